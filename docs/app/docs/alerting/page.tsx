@@ -13,7 +13,7 @@ import { Separator } from "@/components/ui/separator";
 export const metadata: Metadata = {
   title: "Alerting",
   description:
-    "Alert on container deaths, OOM kills, unhealthy health checks, and log patterns. Rate thresholds, per-rule cooldowns, notification channels (generic webhook, ntfy, Gotify, Telegram), and alert history.",
+    "Alert on container deaths, OOM kills, unhealthy health checks, and log patterns. Rate thresholds, per-rule cooldowns, notification channels (generic webhook, ntfy, Gotify, Telegram), and incident history with resolution, acknowledge, and silence.",
   alternates: { canonical: "/docs/alerting" },
 };
 
@@ -36,7 +36,8 @@ export default function AlertingPage() {
         <p className="text-base">
           LogDeck already watches every container&apos;s events and log stream.
           Alert rules put that to use: they match on what LogDeck sees and
-          deliver each fired alert to every enabled notification channel. Rules
+          deliver each fired alert to the notification channels the rule
+          routes it to — by default, every enabled channel. Rules
           and channels are managed under{" "}
           <strong>Settings &rarr; Alerts</strong> in the UI, or with{" "}
           <code>logdeck alerts</code> from the terminal.
@@ -48,7 +49,7 @@ export default function AlertingPage() {
 
         <h3 className="mb-3 mt-8 text-xl font-semibold">Event rules</h3>
         <p className="mb-4 text-base">
-          Event rules watch container lifecycle events. Three are alertable:
+          Event rules watch container lifecycle events. Four are alertable:
         </p>
         <ul className="mb-4 space-y-2">
           <li>
@@ -65,6 +66,15 @@ export default function AlertingPage() {
             transitioned to unhealthy. Recoveries (healthy/starting) do not
             fire.
           </li>
+          <li>
+            <code>restart_loop</code> — the container died and started again
+            threshold times (default 3) within the window, whatever its exit
+            codes. It fires once with the restart count, the last exit codes,
+            and the container&apos;s final log lines as the sample, so a
+            container under <code>restart: always</code> that crashes every
+            few seconds neither floods you with <code>die</code> alerts nor
+            hides behind the cooldown. It must be the only event in its rule.
+          </li>
         </ul>
         <p className="mb-6 text-base">
           An OOM kill usually emits <code>oom</code> immediately followed by a{" "}
@@ -91,9 +101,53 @@ export default function AlertingPage() {
           </li>
         </ul>
 
+        <h3 className="mb-3 mt-8 text-xl font-semibold">Absence rules</h3>
+        <p className="mb-4 text-base">
+          A log rule in <code>absence</code> mode fires when lines{" "}
+          <em>stop</em> arriving: fewer than its threshold (default 1) matching
+          lines from a targeted container within the window, which can be up
+          to 24 hours. Level and pattern filters are optional here; with
+          neither, any line counts. Use it for a cron job that prints
+          &ldquo;job done&rdquo; or a consumer that should never go quiet.
+        </p>
+        <p className="mb-4 text-base">
+          Containers are checked every 15 seconds, and a container is only
+          judged once LogDeck has watched it for a full window. A stopped
+          container sends nothing, so it fires too, as does a container the
+          rule names that no host has at all. The incident resolves, with a
+          recovery notification, as soon as the expected lines arrive again.
+        </p>
+
+        <h3 className="mb-3 mt-8 text-xl font-semibold">Metric rules</h3>
+        <p className="mb-4 text-base">
+          Metric rules watch <code>cpu_percent</code> or{" "}
+          <code>memory_percent</code> for running containers, sampled every 15
+          seconds while at least one metric rule exists. CPU is a percentage of
+          one core, so a busy container on a multi-core host can exceed 100.
+          Memory is a percentage of the container&apos;s memory limit. A rule compares the metric with{" "}
+          <code>&gt;</code> or <code>&lt;</code> and a value over its window:
+        </p>
+        <ul className="mb-6 space-y-2">
+          <li>
+            <strong>Sustained</strong> (default) — every sample must breach for
+            the whole window, e.g. <code>memory_percent &gt; 90 for 5m</code>.
+          </li>
+          <li>
+            <strong>Averaged</strong> — the window&apos;s average must breach,
+            e.g. <code>cpu_percent &gt; 200 averaged over 2m</code>.
+          </li>
+        </ul>
+        <p className="mb-4 text-base">
+          While the condition keeps holding the incident stays open and repeats
+          follow the cooldown; it resolves once the condition has been clear
+          for a window.
+        </p>
+
         <Separator className="my-12" />
 
-        <h2 className="mb-4 text-3xl font-bold tracking-tight">Targeting</h2>
+        <h2 id="targeting" className="mb-4 text-3xl font-bold tracking-tight">
+          Targeting
+        </h2>
         <p className="mb-4 text-base">
           Every rule can be narrowed by <strong>hosts</strong>,{" "}
           <strong>container names</strong> (exact), and{" "}
@@ -132,12 +186,69 @@ export default function AlertingPage() {
 
         <Separator className="my-12" />
 
+        <h2 className="mb-4 text-3xl font-bold tracking-tight">
+          Incidents: firing and resolved
+        </h2>
+        <p className="mb-4 text-base">
+          An alert is an <strong>incident</strong> for one rule and one
+          container. It opens <strong>firing</strong> when the rule first
+          trips, and its channels are notified. While it stays open, later
+          trips that get past the cooldown are folded into the same incident
+          as repeats and re-notify, rather than opening new entries.
+        </p>
+        <p className="mb-4 text-base">
+          The incident <strong>resolves</strong> when the condition clears,
+          and the channels get a resolved notification with how long it
+          lasted:
+        </p>
+        <ul className="mb-6 space-y-2">
+          <li>
+            <strong>Unhealthy</strong> incidents resolve when the container
+            reports <code>health_status: healthy</code> again. An unhealthy
+            container logs nothing further, so quiet alone does not resolve
+            them.
+          </li>
+          <li>
+            Every other incident resolves after a <strong>quiet window</strong>:
+            one full rule window (60 seconds by default) without another trip.
+          </li>
+        </ul>
+        <p className="mb-4 text-base">
+          Two actions quiet an open incident, from the History table in
+          Settings, the CLI, or the API:
+        </p>
+        <ul className="mb-6 space-y-2">
+          <li>
+            <strong>Acknowledge</strong> — someone is on it. Repeats stop
+            notifying, but the resolution is still delivered.{" "}
+            <code>POST /api/v1/alerts/history/&lt;id&gt;/ack</code>, or{" "}
+            <code>logdeck alerts ack &lt;id&gt;</code>.
+          </li>
+          <li>
+            <strong>Silence</strong> — nothing is delivered for the incident,
+            resolution included, either until it resolves or for a set time.{" "}
+            <code>POST /api/v1/alerts/history/&lt;id&gt;/silence</code> with{" "}
+            <code>{`{"durationSeconds": 3600}`}</code> (omit it to silence
+            until resolved), or{" "}
+            <code>logdeck alerts silence &lt;id&gt; --for 1h</code>.
+          </li>
+        </ul>
+        <p className="mb-8 text-base">
+          Incidents still firing when LogDeck stops are closed on the next
+          start with the reason <em>LogDeck restarted</em>, since the state
+          that would resolve them was lost. Editing or deleting a rule closes
+          its open incidents without a notification.
+        </p>
+
+        <Separator className="my-12" />
+
         <h2 className="mb-4 text-3xl font-bold tracking-tight">Channels</h2>
         <p className="mb-4 text-base">
-          A channel is one notification destination. Every fired alert is
-          delivered to <strong>every enabled channel</strong>. Add channels
+          A channel is one notification destination. Unless its rule routes
+          it (see <a href="#routing">Severity and routing</a>), a fired alert
+          is delivered to <strong>every enabled channel</strong>. Add channels
           under <strong>Settings &rarr; Alerts</strong> (or with{" "}
-          <code>logdeck alerts channels add</code>). Four types are supported:
+          <code>logdeck alerts channels add</code>). Eight types are supported:
         </p>
         <ul className="mb-6 space-y-2">
           <li>
@@ -161,6 +272,31 @@ export default function AlertingPage() {
             <code>sendMessage</code> with the alert text, using a bot token and
             a chat id.
           </li>
+          <li>
+            <strong>Email (SMTP)</strong> — sends a plain-text email through an{" "}
+            <code>smtp://host:port</code> server (port 587 by default) to a
+            comma-separated list of recipients, with the alert title as the
+            subject. The connection is always upgraded with{" "}
+            <strong>STARTTLS</strong> before the optional username and password
+            are sent; a server that does not offer it is refused.
+          </li>
+          <li>
+            <strong>PagerDuty</strong> — sends an Events API v2{" "}
+            <code>trigger</code> with the integration&apos;s routing key. The
+            event&apos;s <code>dedup_key</code> is derived from the incident, so
+            LogDeck&apos;s resolve notification sends a <code>resolve</code>{" "}
+            that closes the PagerDuty incident too. An optional URL overrides
+            the endpoint (e.g. for the EU service region).
+          </li>
+          <li>
+            <strong>Matrix</strong> — sends an <code>m.room.message</code> to a
+            room ID (<code>!abc:example.org</code>) through the client-server
+            API of a homeserver, using an access token of the bot account.
+          </li>
+          <li>
+            <strong>Teams</strong> — POSTs an Adaptive Card with the title and
+            text to a Microsoft Teams workflow webhook URL.
+          </li>
         </ul>
         <p className="mb-4 text-base">
           The webhook payload is:
@@ -181,6 +317,7 @@ export default function AlertingPage() {
     "host": "prod",
     "containerId": "...",
     "containerName": "api",
+    "project": "shop",
     "reason": "5 matches (level >= ERROR) within 60s",
     "sample": "level=error msg=\\"upstream timeout\\"",
     "count": 5,
@@ -221,11 +358,28 @@ export default function AlertingPage() {
         </div>
 
         <p className="mb-4 text-base">
-          Each delivery has a 10-second timeout. Network errors and 5xx
-          responses are retried once after 5 seconds; other statuses are treated
-          as permanent. The alert history records one summary result per fired
-          alert: it succeeds only if every enabled channel accepted it, and
-          otherwise names the channel that failed.
+          Notifications are queued in <code>alerts.db</code> before they are
+          sent, so a notification waiting on a failing channel survives a
+          restart. Each attempt has a 10-second timeout. Network errors, 5xx
+          responses, and temporary (4xx) SMTP replies are retried with
+          exponential backoff per channel — 5 seconds, then 10, 20, and so on
+          up to 5 minutes — while other channels keep delivering; other
+          failures are treated as permanent. A notification still undelivered
+          an hour after it fired is dead-lettered: LogDeck gives up on it and
+          records the delivery as failed. Change the deadline under{" "}
+          <strong>Settings &rarr; Alerts</strong>, with{" "}
+          <code>logdeck alerts delivery --deadline 6h</code>, or with{" "}
+          <code>PUT /api/v1/alerts/delivery</code>.
+        </p>
+        <p className="mb-4 text-base">
+          The alert history records one summary result per fired alert:{" "}
+          <code>retrying</code> while a channel is backing off, then{" "}
+          <code>ok</code> if every channel it was routed to accepted it, or{" "}
+          <code>failed</code> naming the channel that did not. Each channel
+          also reports its health — its last success and failure, the last
+          error, consecutive failures, when it is next retried, and how many
+          notifications are queued or dead-lettered — in the channel list and
+          in <code>logdeck alerts channels list</code>.
         </p>
         <p className="mb-8 text-base">
           Use <strong>Test</strong> next to a channel in Settings (or{" "}
@@ -234,22 +388,188 @@ export default function AlertingPage() {
           alerts are simply recorded in history and delivered nowhere.
         </p>
 
+        <h3 className="mb-3 mt-8 text-xl font-semibold">Message templates</h3>
+        <p className="mb-4 text-base">
+          Each channel can replace the default message with Go{" "}
+          <code>text/template</code> templates (<strong>Customize message</strong>{" "}
+          in Settings, or <code>--title-template</code> and{" "}
+          <code>--body-template</code> on <code>channels add</code>). The body
+          template replaces the alert text; for a webhook it renders the{" "}
+          <strong>whole JSON request body</strong>, so it can produce Slack
+          blocks or any shape a receiver expects. The title template applies
+          only to ntfy, Gotify, email (the subject), and Teams (the card heading),
+          which carry a title separately.
+        </p>
+        <p className="mb-4 text-base">
+          Templates see every alert field (<code>.RuleName</code>,{" "}
+          <code>.Type</code>, <code>.Host</code>, <code>.ContainerName</code>,{" "}
+          <code>.Project</code>, <code>.Reason</code>, <code>.Sample</code>,{" "}
+          <code>.FiredAt</code>, <code>.ResolveReason</code>, ...) plus{" "}
+          <code>.Resolved</code>, <code>.SampleLines</code> (the sample split
+          into lines), and <code>.Title</code> and <code>.Text</code> (the
+          default title and message). Helpers: <code>json</code> (encode a value
+          as JSON), <code>upper</code>, <code>lower</code>,{" "}
+          <code>truncate N</code>, and <code>join SEP</code>.
+        </p>
+
+        <div className="not-prose mb-6">
+          <CodeBlock
+            code={`{"blocks": [{"type": "section", "text": {"type": "mrkdwn",
+  "text": {{json (printf "*%s* %s on %s/%s" .RuleName .Reason .Host .ContainerName)}}}}]}`}
+            language="json"
+          />
+        </div>
+
+        <p className="mb-8 text-base">
+          Templates are checked when the channel is saved: they must parse and
+          render against a sample alert, and a webhook body must render valid
+          JSON. <strong>Preview</strong> next to a templated channel (or{" "}
+          <code>logdeck alerts channels test &lt;id&gt; --dry-run</code>) shows
+          the rendered test notification without sending it.
+        </p>
+
+        <Separator className="my-12" />
+
+        <h2
+          id="routing"
+          className="mb-4 text-3xl font-bold tracking-tight"
+        >
+          Severity and routing
+        </h2>
+        <p className="mb-4 text-base">
+          Every rule has a <strong>severity</strong>: <code>info</code>,{" "}
+          <code>warning</code> (the default), or <code>critical</code>. It
+          sets the notification priority where the channel has one:
+        </p>
+        <ul className="mb-6 space-y-2">
+          <li>
+            <strong>ntfy</strong> — <code>urgent</code>, <code>high</code>, or{" "}
+            <code>default</code>.
+          </li>
+          <li>
+            <strong>Gotify</strong> — priority 8, 5, or 2.
+          </li>
+          <li>
+            <strong>PagerDuty</strong> — the event&apos;s{" "}
+            <code>critical</code>, <code>warning</code>, or <code>info</code>{" "}
+            severity.
+          </li>
+        </ul>
+        <p className="mb-4 text-base">
+          Resolved notifications always go out at the low priority. Templates
+          can read the severity as <code>{"{{.Severity}}"}</code>.
+        </p>
+        <p className="mb-4 text-base">
+          A rule chooses where its alerts go in three steps. The first one
+          that applies wins:
+        </p>
+        <ol className="mb-6 list-decimal space-y-2 pl-6">
+          <li>
+            <strong>Routes</strong> — an ordered list of matchers, each with
+            its own channels. The first route whose hosts, container names,
+            and Compose projects match the alert&apos;s container picks the
+            channels. Empty fields match everything, like a rule&apos;s own{" "}
+            <a href="#targeting">targeting</a>.
+          </li>
+          <li>
+            <strong>The rule&apos;s channels</strong> — a list of channel
+            IDs.
+          </li>
+          <li>
+            <strong>Every enabled channel</strong>, when the rule names none.
+          </li>
+        </ol>
+        <p className="mb-4 text-base">
+          For example, one error rule can page PagerDuty about production and
+          send staging&apos;s noise to a low-priority ntfy topic:
+        </p>
+        <CodeBlock
+          code={`logdeck alerts rules create --type log --name errors --min-level ERROR \\
+  --severity critical --channel <pagerduty-id> \\
+  --route 'project=staging -> <ntfy-id>'`}
+          language="bash"
+        />
+        <p className="mb-8 mt-4 text-base">
+          Disabled channels are skipped. An incident keeps the channels it
+          was routed to when it opened, so its repeats and its resolution go
+          to the same places, and PagerDuty gets the resolve for the incident
+          it opened. The history records the chosen channels and the reason,
+          e.g. <em>route 1 (projects: staging)</em>. A channel that a rule
+          names cannot be deleted until the rule stops using it.
+        </p>
+
+        <Separator className="my-12" />
+
+        <h2
+          id="silences"
+          className="mb-4 text-3xl font-bold tracking-tight"
+        >
+          Silences and maintenance windows
+        </h2>
+        <p className="mb-4 text-base">
+          A deploy restarts containers and logs errors on purpose. To keep it
+          from paging anyone, add a <strong>silence</strong> for the hosts,
+          containers, compose projects, or rules involved — any left empty
+          match everything. A silence starts now or at a given time and ends
+          at a set time, and lasts at most 30 days. Add and remove silences under{" "}
+          <strong>Settings &rarr; Alerts</strong>, with{" "}
+          <code>logdeck alerts silences create</code>, through the{" "}
+          <code>create_alert_silence</code> MCP tool, or with{" "}
+          <code>POST /api/v1/alerts/silences</code>.
+        </p>
+        <p className="mb-4 text-base">
+          A <strong>maintenance window</strong> is a silence that recurs. Its
+          schedule is a five-field cron expression —{" "}
+          <code>minute hour day-of-month month day-of-week</code>, supporting{" "}
+          <code>*</code>, lists, ranges, and steps — read in the window&apos;s
+          timezone (UTC unless set). Each time it fires, the window mutes
+          matching alerts for its duration. <code>0 3 * * 1-5</code> with a
+          30-minute duration covers a weekday deploy at 03:00.
+        </p>
+        <p className="mb-8 text-base">
+          A muted alert is not dropped. It is recorded in history as usual,
+          with delivery <code>suppressed-by-silence</code> and the silence or
+          window that muted it. If an incident was never notified, its
+          resolution is not sent either. If it was notified before the silence
+          began, its resolution is still sent, so a PagerDuty incident still
+          closes.
+        </p>
+
         <Separator className="my-12" />
 
         <h2 className="mb-4 text-3xl font-bold tracking-tight">
           Alert history
         </h2>
         <p className="mb-8 text-base">
-          LogDeck keeps the most recent 500 fired alerts, newest first, and
-          mirrors them to <code>alerts-history.json</code> next to the config
-          file — so history survives a restart, provided that directory is a
-          mounted volume. Each entry records the rule, the container and host,
-          the reason, a sample line for log rules, how many matches were
-          suppressed, and the delivery result. Read it under{" "}
-          <strong>Settings &rarr; Alerts</strong>, with{" "}
+          LogDeck records incidents in <code>alerts.db</code>, a SQLite
+          database next to the config file — so history survives a restart,
+          provided that directory is a mounted volume. An existing{" "}
+          <code>alerts-history.json</code> is imported on first start. Each
+          entry records the rule, the container and host, the reason, a sample line for log rules, how many matches were
+          suppressed, its severity, the channels it was routed to and why,
+          and the delivery result, along with its state, when it
+          resolved and why, its duration, and any acknowledgement or silence.
+          Read it under <strong>Settings &rarr; Alerts</strong>, with{" "}
           <code>logdeck alerts history</code>, or from{" "}
-          <code>GET /api/v1/alerts/history</code>. Clearing it is a single
-          action in the UI, or <code>logdeck alerts history clear</code>.
+          <code>GET /api/v1/alerts/history</code>, newest first. Filter by{" "}
+          <code>rule</code>, <code>host</code>, <code>container</code>,{" "}
+          <code>project</code>, <code>state</code> (<code>firing</code> or{" "}
+          <code>resolved</code>), <code>delivery</code> (<code>ok</code>,{" "}
+          <code>failed</code>, <code>retrying</code>,{" "}
+          <code>suppressed-by-silence</code>, or <code>none</code>{" "}
+          for incidents never sent), and a <code>since</code>/<code>until</code>{" "}
+          time range. Pages hold up to 500 incidents; pass the response&apos;s{" "}
+          <code>nextCursor</code> back as <code>cursor</code> for older ones.
+          Clearing it is a single action in the UI, or{" "}
+          <code>logdeck alerts history clear</code>.
+        </p>
+        <p className="mb-8 text-base">
+          Resolved incidents are kept for 90 days, and at most 10,000 are kept
+          in total; the oldest go first. Firing incidents are never pruned.
+          Change either bound under <strong>Settings &rarr; Alerts</strong>,
+          with <code>logdeck alerts history retention --days 30 --max-entries
+          50000</code>, or with <code>PUT /api/v1/alerts/history/retention</code>.
+          The new bounds apply straight away.
         </p>
 
         <Separator className="my-12" />
@@ -289,7 +609,11 @@ logdeck alerts rules create --type log --name upstream-timeouts \\
 # Inspect and manage
 logdeck alerts rules                  # list, with targets and triggers
 logdeck alerts rules disable <id>     # or enable / delete
-logdeck alerts history --limit 20`}
+logdeck alerts history --limit 20
+logdeck alerts history --state firing # open incidents only
+logdeck alerts history --rule <id> --delivery failed --since 7d
+logdeck alerts ack <alert-id>         # stop repeat notifications
+logdeck alerts silence <alert-id> --for 1h`}
         language="bash"
       />
 
@@ -321,7 +645,7 @@ logdeck alerts history --limit 20`}
             <p>
               Alert rules and channels are persisted to{" "}
               <code>config.json</code> (<code>/data/config.json</code> by
-              default), and fired alerts to <code>alerts-history.json</code>{" "}
+              default), and fired alerts to the SQLite <code>alerts.db</code>{" "}
               beside it. Mount <code>/data</code> as a volume, or both are lost
               when the LogDeck container is recreated.
             </p>
@@ -87,8 +87,9 @@ logdeck stop web --host staging`,
   {
     name: "stack",
     summary:
-      "Start, stop, or restart every container of a compose project. Applies to every host that has the project unless --host narrows it.",
-    example: "logdeck stack restart myapp",
+      "Start, stop, or restart every container of a compose project. Applies to every host that has the project unless --host narrows it. pull, up, and down act like docker compose pull / up -d / down on one host, driven from the compose files in the project's labels (the server must be able to read them at that path, so pull and up only work on local-socket hosts); up recreates only changed services and down keeps volumes.",
+    example: `logdeck stack restart myapp
+logdeck stack up myapp --host prod`,
   },
   {
     name: "env",
@@ -101,6 +102,13 @@ logdeck stop web --host staging`,
       "Show or update a container's resource limits and restart policy. Memory accepts human units (512m, 1.5g), CPUs accept fractions.",
     example: `logdeck resources web
 logdeck resources set web --memory 512m --cpus 1.5 --restart on-failure --max-retries 3`,
+  },
+  {
+    name: "updates / update",
+    summary:
+      "updates lists running containers whose image tag now points to a newer image in its registry (--all for every checked container, --check to check now and wait). update pulls a container's image and, if a newer one arrived, recreates the container with its current configuration.",
+    example: `logdeck updates --check
+logdeck update web --host prod`,
   },
   {
     name: "images / volumes / networks",
@@ -113,15 +121,29 @@ logdeck networks`,
   {
     name: "alerts",
     summary:
-      "Manage alerting: rules, notification channels (webhook, ntfy, gotify, telegram), and fired-alert history. Rules match container events (die, oom, unhealthy) or log lines (minimum level and/or regex), and can require a threshold of matches within a window. Every fired alert is delivered to each enabled channel. --host, --container, and --project (repeatable) narrow which containers a rule watches. --window and --cooldown accept durations (60s, 5m) or bare seconds; an omitted cooldown means the server default of 300s.",
+      "Manage alerting: rules, notification channels (webhook, ntfy, gotify, telegram, smtp, pagerduty, matrix, teams), and incident history. Rules match container events (die, oom, unhealthy) or log lines (minimum level and/or regex), and can require a threshold of matches within a window. Every fired alert is delivered to each enabled channel, or to the channels its rule routes it to (--channel, --route 'project=staging -> <id>'), and again when it resolves; --severity info|warning|critical sets the notification priority; ack stops an incident's repeat notifications and silence mutes it entirely; silences and maintenance windows (cron schedule, --timezone) mute matching alerts ahead of time, recording them in history as suppressed-by-silence. --host, --container, and --project (repeatable) narrow which containers a rule watches. --window and --cooldown accept durations (60s, 5m) or bare seconds; an omitted cooldown means the server default of 300s.",
     example: `logdeck alerts rules
 logdeck alerts rules create --type event --name oom-watch --events oom
+logdeck alerts rules create --type event --name crash-loop --events restart_loop --threshold 3 --window 2m
 logdeck alerts rules create --type log --name errors --min-level ERROR --threshold 5 --window 60s
+logdeck alerts rules create --type log --name cron-heartbeat --absent --pattern "job done" --container cron --window 1h
+logdeck alerts rules create --type metric --name high-mem --metric memory_percent --above 90 --window 5m
+logdeck alerts rules create --type metric --name hot-cpu --metric cpu_percent --above 200 --avg --window 2m
+logdeck alerts rules create --type event --name oom-page --events oom --severity critical --channel <pagerduty-id> --route 'project=staging -> <ntfy-id>'
 logdeck alerts rules disable <id>
 logdeck alerts channels add --type webhook --endpoint https://hooks.example.com/logdeck
 logdeck alerts channels add --type telegram --secret "$TELEGRAM_BOT_TOKEN" --target "$CHAT_ID"
+logdeck alerts channels add --type smtp --endpoint smtp://mail.example.com:587 --from logdeck@example.com --target ops@example.com
+logdeck alerts channels add --type pagerduty --secret "$PAGERDUTY_ROUTING_KEY"
+logdeck alerts channels add --type ntfy --endpoint https://ntfy.sh/pager --body-template '{{.ContainerName}}: {{.Reason}}'
 logdeck alerts channels test <id>
-logdeck alerts history --limit 20`,
+logdeck alerts channels test <id> --dry-run
+logdeck alerts history --limit 20
+logdeck alerts history --state firing
+logdeck alerts ack <alert-id>
+logdeck alerts silence <alert-id> --for 1h
+logdeck alerts silences create --project shop --for 30m --comment "deploy v2"
+logdeck alerts maintenance add --name nightly-deploy --schedule "0 3 * * 1-5" --for 30m --timezone Europe/Berlin`,
   },
 ];
 
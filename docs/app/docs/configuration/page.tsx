@@ -52,7 +52,10 @@ export default function ConfigurationPage() {
             <code>logs.db</code> — the SQLite <a href="/docs/log-history">log store</a>
           </li>
           <li>
-            <code>alerts-history.json</code> — recently fired <a href="/docs/alerting">alerts</a>
+            <code>alerts.db</code> — the SQLite <a href="/docs/alerting">alert</a> history
+          </li>
+          <li>
+            <code>stats.db</code> — container CPU, memory, network, and block I/O history
           </li>
         </ul>
         <p className="mb-4 text-base">
@@ -231,6 +234,108 @@ LOG_STORE_TOTAL_MB=1024`} language="bash" />
           </CardContent>
         </Card>
 
+        <Card>
+          <CardHeader>
+            <div className="flex items-start gap-2">
+              <Database className="h-5 w-5 text-primary mt-0.5" />
+              <div>
+                <CardTitle>Stats History (Optional)</CardTitle>
+                <CardDescription>
+                  Stored CPU, memory, network, and block I/O usage per container
+                </CardDescription>
+              </div>
+            </div>
+          </CardHeader>
+          <CardContent className="space-y-4">
+            <p className="text-sm text-muted-foreground">
+              Stats history is <strong>on by default</strong>. Every running container is sampled
+              on an interval; samples are kept for 24 hours, one-minute rollups for 7 days, and
+              one-hour rollups for 90 days. Rollups keep both the average and the peak, so a short
+              spike still shows at hourly resolution. History is keyed by container name, so it
+              spans recreates. These variables override the <code>statsHistory</code> section of the
+              config file, which also sets the retention periods (<code>rawHours</code>,{" "}
+              <code>minuteDays</code>, <code>hourDays</code>).
+            </p>
+
+            <div>
+              <div className="flex items-baseline gap-2 mb-1">
+                <code className="text-sm font-mono bg-muted px-2 py-1 rounded">STATS_HISTORY_ENABLED</code>
+                <span className="text-xs text-muted-foreground">Optional</span>
+              </div>
+              <p className="text-sm text-muted-foreground">
+                <code>false</code> disables stats history: no sampling and no database file.
+                Default: <code>true</code>.
+              </p>
+            </div>
+
+            <div>
+              <div className="flex items-baseline gap-2 mb-1">
+                <code className="text-sm font-mono bg-muted px-2 py-1 rounded">STATS_HISTORY_INTERVAL_SECONDS</code>
+                <span className="text-xs text-muted-foreground">Optional</span>
+              </div>
+              <p className="text-sm text-muted-foreground">
+                Seconds between samples. Default: <code>15</code>.
+              </p>
+            </div>
+
+            <div className="mt-2">
+              <CodeBlock code={`STATS_HISTORY_ENABLED=true
+STATS_HISTORY_INTERVAL_SECONDS=15`} language="bash" />
+            </div>
+          </CardContent>
+        </Card>
+
+        <Card>
+          <CardHeader>
+            <div className="flex items-start gap-2">
+              <CloudCog className="h-5 w-5 text-primary mt-0.5" />
+              <div>
+                <CardTitle>Image Updates (Optional)</CardTitle>
+                <CardDescription>
+                  Periodic registry checks for newer images of running containers
+                </CardDescription>
+              </div>
+            </div>
+          </CardHeader>
+          <CardContent className="space-y-4">
+            <p className="text-sm text-muted-foreground">
+              Image update checks are <strong>off by default</strong>. Once enabled, each running container&apos;s
+              image is compared with the digest its tag has in the registry, and containers with a
+              newer image are badged on the dashboard, where they can be pulled and recreated in one
+              click. Private registries use the credentials in the LogDeck server&apos;s Docker
+              config, which is only sent to local-socket hosts; TCP and SSH hosts check and pull
+              anonymously. Mount <code>~/.docker/config.json</code> at{" "}
+              <code>/root/.docker/config.json</code> read-only. These variables override the{" "}
+              <code>imageUpdates</code> section of the config file.
+            </p>
+
+            <div>
+              <div className="flex items-baseline gap-2 mb-1">
+                <code className="text-sm font-mono bg-muted px-2 py-1 rounded">IMAGE_UPDATES_ENABLED</code>
+                <span className="text-xs text-muted-foreground">Optional</span>
+              </div>
+              <p className="text-sm text-muted-foreground">
+                <code>true</code> turns on the periodic check. Default: <code>false</code>.
+              </p>
+            </div>
+
+            <div>
+              <div className="flex items-baseline gap-2 mb-1">
+                <code className="text-sm font-mono bg-muted px-2 py-1 rounded">IMAGE_UPDATES_INTERVAL_HOURS</code>
+                <span className="text-xs text-muted-foreground">Optional</span>
+              </div>
+              <p className="text-sm text-muted-foreground">
+                Hours between checks. Default: <code>6</code>.
+              </p>
+            </div>
+
+            <div className="mt-2">
+              <CodeBlock code={`IMAGE_UPDATES_ENABLED=true
+IMAGE_UPDATES_INTERVAL_HOURS=6`} language="bash" />
+            </div>
+          </CardContent>
+        </Card>
+
         <Card>
           <CardHeader>
             <div className="flex items-start gap-2">
@@ -275,6 +380,23 @@ LOG_STORE_TOTAL_MB=1024`} language="bash" />
                 </code>
               </div>
             </div>
+
+            <Separator />
+
+            <div>
+              <div className="flex items-baseline gap-2 mb-1">
+                <code className="text-sm font-mono bg-muted px-2 py-1 rounded">METRICS_ENABLED</code>
+                <span className="text-xs text-muted-foreground">Optional</span>
+              </div>
+              <p className="text-sm text-muted-foreground">
+                Set to <code>true</code> to serve a Prometheus <code>/metrics</code> endpoint. It always
+                requires an API token. See <a href="#prometheus-metrics">Prometheus Metrics</a>.
+              </p>
+              <div className="mt-2">
+                <span className="text-xs font-medium">Default:</span>{" "}
+                <code className="text-xs bg-muted px-1.5 py-0.5 rounded">false</code>
+              </div>
+            </div>
           </CardContent>
         </Card>
 
@@ -583,6 +705,62 @@ volumes:
 
         <Separator className="my-12" />
 
+        <h2 id="container-labels" className="mb-4 text-3xl font-bold tracking-tight">Container Labels</h2>
+        <p className="mb-4 text-base">
+          Some behaviour can be set per container with <code>logdeck.*</code> labels, so a
+          service&apos;s policy lives in its compose file next to its definition instead of in the UI:
+        </p>
+        <ul className="mb-4 space-y-2">
+          <li>
+            <code>logdeck.store=false</code> keeps the container out of{" "}
+            <a href="/docs/log-history">log history</a>. Live logs still work.
+          </li>
+          <li>
+            <code>logdeck.retention=7d</code> keeps the container&apos;s stored lines for that long, at every
+            level. It takes days (<code>7d</code>), weeks (<code>2w</code>), or hours and minutes
+            (<code>36h</code>), and overrides the retention rules for that container.
+          </li>
+          <li>
+            <code>logdeck.parser=json</code> or <code>logfmt</code> reads each line as structured: the level
+            and message come from its <code>level</code> and <code>msg</code> keys, and every key is shown
+            as a field.
+          </li>
+          <li>
+            <code>logdeck.multiline=java</code>, <code>python</code>, or <code>go</code> folds that
+            language&apos;s stack traces into the entry they belong to, including trace lines that would
+            otherwise read as errors of their own.
+          </li>
+          <li>
+            <code>logdeck.hide=true</code> leaves the container out of the container list.
+          </li>
+        </ul>
+
+        <div className="not-prose mb-4">
+          <CodeBlock
+            code={`services:
+  payments:
+    image: example/payments
+    labels:
+      - "logdeck.retention=30d"
+      - "logdeck.parser=json"
+      - "logdeck.multiline=java"
+  healthcheck-pinger:
+    image: example/pinger
+    labels:
+      - "logdeck.store=false"
+      - "logdeck.hide=true"`}
+            language="yaml"
+          />
+        </div>
+
+        <p className="mb-8 text-base">
+          Docker fixes a container&apos;s labels when it is created, so a changed label applies once the
+          service is recreated (<code>docker compose up -d</code>). A value LogDeck cannot read is
+          ignored, and the container keeps the default behaviour.
+        </p>
+
+        <Separator className="my-12" />
+
         <h2 id="read-only-mode" className="mb-4 text-3xl font-bold tracking-tight">Read-Only Mode</h2>
         <p className="mb-4 text-base">
           Read-only mode prevents LogDeck from changing anything on your containers. It is useful in
@@ -660,7 +838,69 @@ volumes:
         </ul>
         <p className="mb-8 text-base">
           Tokens only matter when authentication is enabled; on an open instance the API is
-          reachable without them.
+          reachable without them. The one exception is <code>/metrics</code>, which always wants a
+          token.
+        </p>
+
+        <Separator className="my-12" />
+
+        <h2 id="prometheus-metrics" className="mb-4 text-3xl font-bold tracking-tight">Prometheus Metrics</h2>
+        <p className="mb-4 text-base">
+          With <code>METRICS_ENABLED=true</code>, LogDeck serves <code>/metrics</code> in the Prometheus
+          text format, so an existing Prometheus and Grafana can graph LogDeck and the containers it
+          watches. The endpoint is off by default. When it is on, every scrape must carry an API token
+          of either scope, even when login authentication is disabled. Create a <strong>read</strong>{" "}
+          token for it.
+        </p>
+
+        <div className="not-prose mb-4">
+          <CodeBlock
+            code={`scrape_configs:
+  - job_name: logdeck
+    metrics_path: /metrics
+    authorization:
+      credentials: ldk_your_read_token
+    static_configs:
+      - targets: ["logdeck.example.com:8080"]`}
+            language="yaml"
+          />
+        </div>
+
+        <p className="mb-2 text-base">
+          Per-container series carry <code>host</code>, <code>container</code>, and <code>project</code>{" "}
+          (the compose project) labels:
+        </p>
+        <ul className="mb-4 space-y-2">
+          <li>
+            <code>logdeck_host_up</code> and <code>logdeck_container_running</code> show which hosts
+            answered and which containers run
+          </li>
+          <li>
+            <code>logdeck_container_cpu_percent</code>, <code>logdeck_container_memory_percent</code>,{" "}
+            <code>logdeck_container_memory_used_bytes</code>, and{" "}
+            <code>logdeck_container_memory_limit_bytes</code> cover running containers, read from the
+            stats history sampler&apos;s latest pass rather than the engines on every scrape. While
+            stats history is disabled they are read from the engines on each scrape instead
+          </li>
+          <li>
+            <code>logdeck_log_lines_total</code> counts stored lines per container and{" "}
+            <code>level</code>. Graph it with <code>rate()</code>
+          </li>
+          <li>
+            <code>logdeck_store_bytes</code>, <code>logdeck_store_database_bytes</code>,{" "}
+            <code>logdeck_store_committed_lines_total</code>, and{" "}
+            <code>logdeck_store_dropped_lines_total</code> describe the{" "}
+            <a href="/docs/log-history">log store</a>
+          </li>
+          <li>
+            <code>logdeck_alerts_fired_total</code> (by <code>rule_id</code>, <code>rule</code>, and{" "}
+            <code>type</code>), <code>logdeck_alert_deliveries_total</code> (by <code>status</code>), and{" "}
+            <code>logdeck_alert_match_drops_total</code> cover <a href="/docs/alerting">alerting</a>
+          </li>
+        </ul>
+        <p className="mb-8 text-base">
+          The counters start at zero when LogDeck starts. Store series appear only while log history is
+          enabled.
         </p>
 
         <Separator className="my-12" />
@@ -72,7 +72,7 @@ export default function LogHistoryPage() {
             </p>
             <p>
               The same volume also holds <code>config.json</code> (hosts, API
-              tokens, alert rules) and <code>alerts-history.json</code>.
+              tokens, alert rules) and <code>alerts.db</code> (alert history).
             </p>
           </CardContent>
         </Card>
@@ -252,6 +252,161 @@ volumes:
           headroom.
         </p>
 
+        <h3 className="mb-4 mt-8 text-xl font-semibold">Age limits</h3>
+        <p className="mb-4 text-base">
+          Retention rules add age limits on top of the caps. A rule targets
+          containers by host, name, or compose project the way alert rules do,
+          can be narrowed to some log levels, and says how many hours matching
+          lines are kept. For each level of each container the{" "}
+          <strong>first matching rule wins</strong>, so list specific rules
+          before catch-alls. This keeps debug lines for a day, the{" "}
+          <code>payments</code> project for two weeks, and everything else for
+          two days:
+        </p>
+        <div className="not-prose mb-6">
+          <CodeBlock
+            code={`{
+  "logStore": {
+    "retentionRules": [
+      { "levels": ["DEBUG"], "maxAgeHours": 24 },
+      { "projects": ["payments"], "maxAgeHours": 336 },
+      { "maxAgeHours": 48 }
+    ]
+  }
+}`}
+            language="json"
+          />
+        </div>
+        <p className="mb-8 text-base">
+          Lines past their age are deleted by the same sweep, before the caps
+          are applied, and are never archived; archived files are pruned too.
+          Levels no rule reaches are only bound by the caps. A multi-line entry
+          is kept or deleted whole, by the level and age of its first line: its
+          continuation lines go with it rather than following the rules for{" "}
+          <code>UNKNOWN</code>. A container labelled{" "}
+          <code>logdeck.retention</code> (for example <code>7d</code>) keeps its
+          lines that long instead, whatever the rules say; see{" "}
+          <a href="/docs/configuration#container-labels">container labels</a>.
+        </p>
+
+        <h3 className="mb-4 mt-8 text-xl font-semibold">
+          Keeping noise out
+        </h3>
+        <p className="mb-4 text-base">
+          Ingestion rules decide what is stored in the first place. Like
+          retention rules they target containers by host, name, or compose
+          project, and also by label (<code>key</code> or{" "}
+          <code>key=value</code>). Each rule has one action:
+        </p>
+        <ul className="mb-6 space-y-2">
+          <li>
+            <strong>exclude</strong> — store nothing for the container. It is
+            not read from the engine either.
+          </li>
+          <li>
+            <strong>drop</strong> — skip lines whose message matches{" "}
+            <code>pattern</code> (RE2).
+          </li>
+          <li>
+            <strong>sample</strong> — keep <code>samplePercent</code> of the
+            container&apos;s INFO-and-below entries (entries without a level
+            included). Warnings and errors are always kept. A multi-line entry
+            is kept or dropped whole, so an error keeps its stack trace.
+          </li>
+        </ul>
+        <div className="not-prose mb-6">
+          <CodeBlock
+            code={`{
+  "logStore": {
+    "ingestionRules": [
+      { "containers": ["healthcheck"], "action": "exclude" },
+      { "labels": ["traefik.enable=true"], "action": "drop", "pattern": "GET /ping" },
+      { "projects": ["edge"], "action": "sample", "samplePercent": 10 }
+    ]
+  }
+}`}
+            language="json"
+          />
+        </div>
+        <p className="mb-8 text-base">
+          A container can also exclude itself with the{" "}
+          <code>logdeck.store=false</code> label. Every matching rule applies,
+          and the lowest sample rate wins. Rules
+          only affect lines stored from then on, live and backfilled alike; a
+          container whose exclusion is lifted is read from the engine again.
+          Filtered containers are listed with <code>filtered</code> and a{" "}
+          <code>filteredReason</code> naming the rules.
+        </p>
+
+        <h3 className="mb-4 mt-8 text-xl font-semibold">
+          Archiving evicted lines
+        </h3>
+        <p className="mb-4 text-base">
+          With the archive turned on, retention keeps what it evicts: before
+          deleting lines it writes them to zstd-compressed files, one per
+          container generation and day, under{" "}
+          <code>archive/&lt;host&gt;/&lt;name&gt;/&lt;yyyy&gt;/&lt;mm&gt;/&lt;dd&gt;/</code>{" "}
+          next to the database — or to an S3-compatible bucket (AWS S3, MinIO,
+          R2) when one is configured. Queries read the archive transparently:
+          paging past the oldest stored line carries on into the archived ones,
+          with the same filters and the same cursors.
+        </p>
+        <div className="not-prose mb-6">
+          <CodeBlock
+            code={`{
+  "logStore": {
+    "archive": {
+      "enabled": true,
+      "s3": {
+        "endpoint": "http://minio:9000",
+        "bucket": "logdeck",
+        "prefix": "archive",
+        "accessKeyId": "logdeck",
+        "secretAccessKey": "..."
+      }
+    }
+  }
+}`}
+            language="json"
+          />
+        </div>
+        <p className="mb-8 text-base">
+          If a file cannot be written, nothing is evicted and the sweep retries
+          later, so the store may run over its caps while the archive is
+          unreachable. Archived lines do not count against the caps, and level
+          counts and exports cover stored lines only. Turning the archive off
+          stops new archiving but keeps existing files readable; purging a
+          container deletes its archived files too. Each file is itself a
+          history archive, so <code>logdeck history import</code> can load one
+          into any instance.
+        </p>
+
+        <h3 className="mb-4 mt-8 text-xl font-semibold">
+          Exporting and importing
+        </h3>
+        <p className="mb-4 text-base">
+          To keep history past retention, or to move it to another LogDeck
+          instance, export it as a zstd-compressed NDJSON archive and import it
+          elsewhere. An export takes the same container, project, host, and time
+          selection as a query, and writes whole lines, newest first.
+        </p>
+        <div className="not-prose mb-6">
+          <CodeBlock
+            code={`logdeck history export --project shop --since 30d > shop.ndjson.zst
+logdeck history import shop.ndjson.zst`}
+            language="bash"
+          />
+        </div>
+        <p className="mb-8 text-base">
+          Each imported line joins the container it was exported from — the
+          same host and name, under the same container generation — so a
+          rebuilt container&apos;s timeline survives the move. Lines the store
+          already holds are skipped, so importing an archive twice, or one that
+          overlaps the store, stores each line once. Containers the destination
+          has never seen show up as removed, and imported lines count against
+          the retention caps like any other.
+        </p>
+
         <Separator className="my-12" />
 
         <h2 className="mb-4 text-3xl font-bold tracking-tight">
@@ -316,6 +471,81 @@ volumes:
                   positive integer. Default: <code>1024</code>.
                 </p>
               </div>
+              <div>
+                <code className="text-sm bg-muted px-2 py-1 rounded">
+                  LOG_STORE_FULL_TEXT_INDEX
+                </code>
+                <p className="text-sm text-muted-foreground mt-1">
+                  <code>true</code> maintains a full-text index so substring
+                  searches of at least three characters skip straight to
+                  matching lines instead of scanning the store. Existing lines
+                  are indexed in the background after it is turned on; regex
+                  searches always scan. Default: <code>false</code>.
+                </p>
+              </div>
+              <div>
+                <code className="text-sm bg-muted px-2 py-1 rounded">
+                  LOG_STORE_INDEXED_FIELDS
+                </code>
+                <p className="text-sm text-muted-foreground mt-1">
+                  Comma-separated field keys (for example{" "}
+                  <code>status,request_id</code>) to index so field filters on
+                  them skip straight to matching lines. At most 16 keys; each
+                  key is indexed in the background when added, and its index is
+                  dropped when removed. Default: none.
+                </p>
+              </div>
+              <div>
+                <code className="text-sm bg-muted px-2 py-1 rounded">
+                  LOG_STORE_RETENTION_RULES
+                </code>
+                <p className="text-sm text-muted-foreground mt-1">
+                  The retention rules as a JSON array, in the same shape as the
+                  config file. Rules without a positive{" "}
+                  <code>maxAgeHours</code> are dropped, and invalid JSON is
+                  ignored with a warning. Default: none.
+                </p>
+              </div>
+              <div>
+                <code className="text-sm bg-muted px-2 py-1 rounded">
+                  LOG_STORE_INGESTION_RULES
+                </code>
+                <p className="text-sm text-muted-foreground mt-1">
+                  The ingestion rules as a JSON array, in the same shape as the
+                  config file. A rule with an unknown action, an invalid
+                  pattern, or a sample rate outside 0-100 is dropped with a
+                  warning. Default: none.
+                </p>
+              </div>
+              <div>
+                <code className="text-sm bg-muted px-2 py-1 rounded">
+                  LOG_STORE_ARCHIVE_ENABLED
+                </code>
+                <p className="text-sm text-muted-foreground mt-1">
+                  <code>true</code> archives evicted lines instead of
+                  discarding them. Default: <code>false</code>.
+                </p>
+              </div>
+              <div>
+                <code className="text-sm bg-muted px-2 py-1 rounded">
+                  LOG_STORE_ARCHIVE_DIR
+                </code>
+                <p className="text-sm text-muted-foreground mt-1">
+                  Where archived files go when no bucket is configured.
+                  Default: <code>archive</code> next to the database.
+                </p>
+              </div>
+              <div>
+                <code className="text-sm bg-muted px-2 py-1 rounded">
+                  LOG_STORE_ARCHIVE_S3_ENDPOINT, _BUCKET, _REGION, _PREFIX,
+                  _ACCESS_KEY_ID, _SECRET_ACCESS_KEY
+                </code>
+                <p className="text-sm text-muted-foreground mt-1">
+                  An S3-compatible bucket for archived files, addressed
+                  path-style. An endpoint and a bucket are required; the region
+                  defaults to <code>us-east-1</code>.
+                </p>
+              </div>
             </CardContent>
           </Card>
         </div>
@@ -349,6 +579,10 @@ volumes:
             <code>GET /api/v1/history/logs</code> — one page of stored logs.
             Returns <code>503</code> when persistence is disabled.
           </li>
+          <li>
+            <code>GET /api/v1/history/stats</code> — per-level counts in time
+            buckets, for charting error rates without pulling lines.
+          </li>
         </ul>
         <p className="mb-4 text-base">
           <code>/history/logs</code> takes <code>container</code> (required),{" "}
@@ -359,6 +593,17 @@ volumes:
           and <code>cursor</code>. Pages walk backwards through history: follow
           the returned <code>nextCursor</code> for older lines.
         </p>
+        <p className="mb-4 text-base">
+          <code>filter</code> matches structured fields pulled from JSON and{" "}
+          <code>key=value</code> (logfmt) lines, with nested JSON keys joined by
+          dots. Comparisons are <code>=</code>, <code>!=</code>,{" "}
+          <code>&gt;</code>, <code>&gt;=</code>, <code>&lt;</code>,{" "}
+          <code>&lt;=</code>, <code>~</code> (contains) and <code>!~</code>,
+          joined with <code>AND</code>; quote values that contain spaces. Numbers
+          compare numerically, so <code>status&gt;=500 AND path~&quot;/api&quot;</code>{" "}
+          works as expected. The same <code>filter</code> parameter applies to
+          live parsed logs.
+        </p>
 
         <div className="not-prose mb-8">
           <CodeBlock
@@ -367,6 +612,56 @@ volumes:
             language="bash"
           />
         </div>
+
+        <p className="mb-4 text-base">
+          <code>/history/stats</code> takes the same selection and filters, less{" "}
+          <code>limit</code> and <code>cursor</code>, plus{" "}
+          <code>interval</code> (a duration such as <code>1m</code> or{" "}
+          <code>1h</code>). The window defaults to the last 24 hours and the
+          interval to roughly a hundred buckets; at most 1500 buckets are
+          returned. Without <code>search</code> or <code>filter</code> it counts
+          stored lines by level straight from the index — a stack trace&apos;s
+          continuation lines count as <code>UNKNOWN</code>. With either, it
+          counts matching entries, by the level of each entry.
+        </p>
+
+        <div className="not-prose mb-8">
+          <CodeBlock
+            code={`curl -H "Authorization: Bearer ldk_..." \\
+  "http://localhost:8123/api/v1/history/stats?project=api&levels=ERROR&interval=1m&since=2026-07-01T00:00:00Z"`}
+            language="bash"
+          />
+        </div>
+
+        <p className="mb-4 text-base">
+          Two more endpoints back <code>history export</code> and{" "}
+          <code>history import</code>. They move whole histories, so they are
+          denied to <code>read</code>-scoped tokens, and import is blocked in
+          read-only mode:
+        </p>
+        <ul className="mb-6 space-y-2">
+          <li>
+            <code>GET /api/v1/history/export</code> — streams an archive
+            (<code>application/zstd</code>). Takes the selection parameters and{" "}
+            <code>since</code>/<code>until</code>; <code>search</code>,{" "}
+            <code>levels</code>, and <code>filter</code> are rejected.
+          </li>
+          <li>
+            <code>POST /api/v1/history/import</code> — stores the archive sent
+            as the request body and reports how many lines were read, stored,
+            and skipped as duplicates.
+          </li>
+        </ul>
+
+        <div className="not-prose mb-8">
+          <CodeBlock
+            code={`curl -H "Authorization: Bearer ldk_..." -o web.ndjson.zst \\
+  "http://localhost:8123/api/v1/history/export?container=web&host=local"
+curl -H "Authorization: Bearer ldk_..." --data-binary @web.ndjson.zst \\
+  -H "Content-Type: application/zstd" "http://localhost:8123/api/v1/history/import"`}
+            language="bash"
+          />
+        </div>
       </div>
     </div>
   );
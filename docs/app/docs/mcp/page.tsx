@@ -42,9 +42,14 @@ const readTools = [
     summary: "Images, volumes, and networks across hosts.",
   },
   {
-    name: "history_search / history_status / history_containers",
+    name: "list_image_updates",
     summary:
-      "Query the persisted log store: fast, indexed, cursor-paginated, and readable even for containers that no longer exist.",
+      "Running containers whose image tag now points to a newer image, from the server's last periodic check.",
+  },
+  {
+    name: "history_search / history_stats / history_status / history_containers",
+    summary:
+      "Query the persisted log store: fast, indexed, cursor-paginated, and readable even for containers that no longer exist. history_stats counts logs per level over time, so error rates can be charted without pulling lines.",
   },
 ];
 
@@ -63,6 +68,16 @@ const actionTools = [
     summary:
       "Run one non-interactive command in a container and return separate stdout, stderr, and the exit code.",
   },
+  {
+    name: "deploy_stack",
+    summary:
+      "Run compose pull, up -d, or down for a project from the compose files its containers were started with, and return every progress step. up recreates only changed services; down keeps volumes.",
+  },
+  {
+    name: "update_container_image",
+    summary:
+      "Pull a container's image and, if a newer one arrived, recreate the container on it with its current configuration. It restarts with a new ID.",
+  },
   {
     name: "get_env / set_env",
     summary:
@@ -83,6 +98,11 @@ const actionTools = [
     summary:
       "Change authentication and manage API tokens. Disabling auth leaves the server open to anyone who can reach it.",
   },
+  {
+    name: "list_alert_silences / create_alert_silence / delete_alert_silence",
+    summary:
+      "List silences and maintenance windows, and mute alerts for a host, container, project, or rule over a time range, e.g. around a deploy. Muted alerts stay in history as suppressed-by-silence.",
+  },
 ];
 
 export default function McpPage() {
@@ -51,3 +51,32 @@ export function restartContainer(id: string, host: string) {
 export function removeContainer(id: string, host: string) {
 	return performContainerAction(id, "remove", host);
 }
+
+interface UpdateImageResponse {
+	message?: string;
+	recreated: boolean;
+	new_container_id: string;
+}
+
+// Pulls the container's image and, when it changed, recreates the container
+// with its current configuration. The pull can take a while on slow links.
+export async function updateContainerImage(
+	id: string,
+	host: string,
+): Promise<string> {
+	const endpoint = `${BASE_URL}/${encodeURIComponent(id)}/update-image?host=${encodeURIComponent(host)}`;
+	const response = await authenticatedFetch(endpoint, {
+		method: "POST",
+		headers: {
+			"Content-Type": "application/json",
+		},
+	});
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to update container image");
+	}
+
+	const data = (await response.json()) as UpdateImageResponse;
+	return data.message ?? "Container image updated";
+}
@@ -23,6 +23,9 @@ export interface LogEntry {
 	raw?: string;
 	fields?: Record<string, string>;
 	continuationCount?: number;
+	// Set by the server on live lines of a container labelled
+	// logdeck.multiline that belong to the entry before them.
+	continues?: boolean;
 	// Present only on aggregated multi-container streams.
 	containerId?: string;
 	containerName?: string;
@@ -216,10 +219,11 @@ export function groupRelatedLogEntries<TLogEntry extends LogEntry>(
 }
 
 function isContinuationLogEntry(entry: LogEntry, previous: LogEntry): boolean {
-	if (entry.level !== "UNKNOWN") return false;
 	// Aggregate streams interleave containers; never fold a line into another
 	// container's entry.
 	if (entry.containerName !== previous.containerName) return false;
+	if (entry.continues) return true;
+	if (entry.level !== "UNKNOWN") return false;
 
 	const message = (entry.message ?? entry.raw ?? "").trim();
 	const previousMessage = (previous.message ?? previous.raw ?? "").trim();
@@ -25,6 +25,9 @@ export interface StoredContainer {
 	removed: boolean;
 	excluded: boolean;
 	excludedReason?: string;
+	// Ingestion rules exclude the container or skip some of its lines.
+	filtered: boolean;
+	filteredReason?: string;
 }
 
 export interface HistoryLogsPage {
@@ -0,0 +1,40 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { iterateNDJSONStream } from "@/lib/ndjson";
+import { API_BASE_URL } from "@/types/api";
+
+import type { ContainerStats } from "../types";
+
+const STATS_STREAM_ENDPOINT = `${API_BASE_URL}/api/v1/containers/stats/stream`;
+
+/**
+ * One line of the stats stream: a container's newest reading, or with
+ * `removed` set, notice that the container stopped and has no more readings.
+ */
+export interface ContainerStatsEvent extends ContainerStats {
+	removed?: boolean;
+}
+
+export async function* streamContainerStats(
+	signal?: AbortSignal,
+	onOpen?: () => void,
+): AsyncGenerator<ContainerStatsEvent, void, unknown> {
+	const response = await authenticatedFetch(STATS_STREAM_ENDPOINT, {
+		headers: {
+			Accept: "application/x-ndjson",
+		},
+		signal,
+	});
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to subscribe to container stats");
+	}
+
+	if (!response.body) {
+		throw new Error("Streaming is not supported in this environment.");
+	}
+
+	onOpen?.();
+
+	yield* iterateNDJSONStream<ContainerStatsEvent>(response.body, signal);
+}
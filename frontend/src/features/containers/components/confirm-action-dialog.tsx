@@ -28,6 +28,12 @@ const CONFIRM_COPY: Record<
 			"Removing a container will permanently delete it and its resources. This action cannot be undone.",
 		confirmLabel: "Remove Container",
 	},
+	update: {
+		title: "Update container?",
+		description:
+			"Pulls the latest image for this tag and recreates the container with its current configuration. The container restarts and gets a new ID.",
+		confirmLabel: "Update Container",
+	},
 };
 
 interface ConfirmActionDialogProps {
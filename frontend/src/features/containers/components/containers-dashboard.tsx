@@ -118,6 +118,7 @@ export function ContainersDashboard() {
 		stopContainerAction,
 		restartContainerAction,
 		deleteContainerAction,
+		updateContainerAction,
 		composeAction,
 		confirmPendingAction,
 		handleConfirmDialogOpenChange,
@@ -327,6 +328,7 @@ export function ContainersDashboard() {
 					onStop={stopContainerAction}
 					onRestart={restartContainerAction}
 					onDelete={deleteContainerAction}
+					onUpdate={updateContainerAction}
 					onComposeAction={composeAction}
 					onViewLogs={handleViewLogs}
 					onPurgeHistory={(container) =>
@@ -1,6 +1,7 @@
 import { Link } from "@tanstack/react-router";
 import type { LucideIcon } from "lucide-react";
 import {
+	CircleArrowUpIcon,
 	FileTextIcon,
 	PlayIcon,
 	RotateCwIcon,
@@ -119,6 +120,7 @@ interface ContainersTableProps {
 	onStop: (container: ContainerInfo) => void;
 	onRestart: (container: ContainerInfo) => void;
 	onDelete: (container: ContainerInfo) => void;
+	onUpdate: (container: ContainerInfo) => void;
 	onComposeAction: (action: ComposeAction, group: GroupedContainers) => void;
 	onViewLogs: (container: ContainerInfo) => void;
 	onPurgeHistory: (container: RemovedContainerInfo) => void;
@@ -142,6 +144,7 @@ export function ContainersTable({
 	onStart,
 	onStop,
 	onRestart,
+	onUpdate,
 	onDelete,
 	onComposeAction,
 	onViewLogs,
@@ -191,11 +194,22 @@ export function ContainersTable({
 					<TooltipProvider>
 						<Tooltip>
 							<TooltipTrigger asChild>
-								<span className="block cursor-help truncate max-w-[240px]">
-									{formatImageName(container.image)}
+								<span className="flex items-center gap-1.5 cursor-help max-w-[240px]">
+									<span className="truncate">
+										{formatImageName(container.image)}
+									</span>
+									{container.update_available && (
+										<Badge className="bg-sky-500/10 text-sky-700 dark:text-sky-400 border-0 text-[10px] px-1.5 h-4 shrink-0">
+											Update
+										</Badge>
+									)}
 								</span>
 							</TooltipTrigger>
-							<TooltipContent>{container.image}</TooltipContent>
+							<TooltipContent>
+								{container.update_available
+									? `${container.image} (newer image available)`
+									: container.image}
+							</TooltipContent>
 						</Tooltip>
 					</TooltipProvider>
 				</TableCell>
@@ -337,6 +351,17 @@ export function ContainersTable({
 										busy={busy}
 										isReadOnly={isReadOnly}
 									/>
+									{container.update_available && (
+										<ActionButton
+											icon={CircleArrowUpIcon}
+											action="update"
+											containerId={container.id}
+											onClick={() => onUpdate(container)}
+											isPending={isPending}
+											busy={busy}
+											isReadOnly={isReadOnly}
+										/>
+									)}
 									<ActionButton
 										icon={Trash2Icon}
 										action="remove"
@@ -19,6 +19,7 @@ vi.mock("../api/container-actions", () => ({
 	stopContainer: (id: string) => deferredAction(id),
 	restartContainer: (id: string) => deferredAction(id),
 	removeContainer: (id: string) => deferredAction(id),
+	updateContainerImage: (id: string) => deferredAction(id),
 }));
 
 vi.mock("../api/compose-actions", () => ({
@@ -71,4 +72,27 @@ describe("useContainerActions pending state", () => {
 
 		expect(result.current.pendingActions.size).toBe(0);
 	});
+
+	it("asks for confirmation before updating a container image", async () => {
+		const { result } = renderHook(() => useContainerActions(async () => {}));
+
+		act(() => result.current.updateContainerAction(makeContainer("a")));
+
+		expect(result.current.confirmAction?.type).toBe("update");
+		expect(result.current.pendingActions.has("a")).toBe(false);
+
+		let confirmed: Promise<void> | undefined;
+		act(() => {
+			confirmed = result.current.confirmPendingAction();
+		});
+		expect(result.current.pendingActions.get("a")).toBe("update");
+
+		await act(async () => {
+			resolvers.get("a")?.("");
+			await confirmed;
+		});
+
+		expect(result.current.pendingActions.size).toBe(0);
+		expect(result.current.confirmAction).toBeNull();
+	});
 });
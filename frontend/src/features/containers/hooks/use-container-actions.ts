@@ -8,6 +8,7 @@ import {
 	restartContainer,
 	startContainer,
 	stopContainer,
+	updateContainerImage,
 } from "../api/container-actions";
 import type {
 	ContainerActionType,
@@ -16,7 +17,7 @@ import type {
 import type { ContainerInfo } from "../types";
 
 export interface ConfirmableAction {
-	type: Extract<ContainerActionType, "stop" | "remove">;
+	type: Extract<ContainerActionType, "stop" | "remove" | "update">;
 	container: ContainerInfo;
 }
 
@@ -28,11 +29,12 @@ const containerActionFns: Record<
 	stop: stopContainer,
 	restart: restartContainer,
 	remove: removeContainer,
+	update: updateContainerImage,
 };
 
 /**
  * Container and compose lifecycle actions with pending state and a
- * confirmation step for destructive actions (stop/remove).
+ * confirmation step for destructive actions (stop/remove/update).
  *
  * In-flight actions are tracked per key (container id or compose project)
  * so concurrent actions on different rows don't clobber each other's
@@ -155,6 +157,10 @@ export function useContainerActions(refetch: () => Promise<unknown>) {
 		setConfirmAction({ type: "remove", container });
 	};
 
+	const updateContainerAction = (container: ContainerInfo) => {
+		setConfirmAction({ type: "update", container });
+	};
+
 	const composeAction = (action: ComposeAction, group: GroupedContainers) => {
 		void executeComposeAction(action, group);
 	};
@@ -185,6 +191,7 @@ export function useContainerActions(refetch: () => Promise<unknown>) {
 		stopContainerAction,
 		restartContainerAction,
 		deleteContainerAction,
+		updateContainerAction,
 		composeAction,
 		confirmPendingAction,
 		handleConfirmDialogOpenChange,
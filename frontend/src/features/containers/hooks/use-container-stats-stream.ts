@@ -0,0 +1,89 @@
+import { useEffect, useState } from "react";
+
+import { streamContainerStats } from "../api/stream-container-stats";
+import type { ContainerStats } from "../types";
+
+import { useDocumentVisible } from "./use-document-visible";
+
+const INITIAL_RETRY_DELAY_MS = 1_000;
+const MAX_RETRY_DELAY_MS = 30_000;
+// Readings arrive about once a second per container; the table and the
+// sparklines only need a snapshot at the old polling cadence.
+const SNAPSHOT_INTERVAL_MS = 5_000;
+const FIRST_SNAPSHOT_DELAY_MS = 1_000;
+
+/**
+ * Subscribes to the server's stats stream while the page is visible and
+ * publishes a snapshot of every container's latest reading every few seconds.
+ * `stats` stays undefined until the first snapshot after each (re)connect, so
+ * callers can fall back to polling in the meantime.
+ */
+export function useContainerStatsStream() {
+	const [isConnected, setIsConnected] = useState(false);
+	const [stats, setStats] = useState<ContainerStats[] | undefined>();
+	const isVisible = useDocumentVisible();
+
+	useEffect(() => {
+		if (!isVisible) return;
+
+		const abortController = new AbortController();
+		const latest = new Map<string, ContainerStats>();
+		let retryDelay = INITIAL_RETRY_DELAY_MS;
+		let retryTimeout: ReturnType<typeof setTimeout> | null = null;
+		let snapshotInterval: ReturnType<typeof setInterval> | null = null;
+		let firstSnapshot: ReturnType<typeof setTimeout> | null = null;
+
+		const publish = () => setStats(Array.from(latest.values()));
+		const stopSnapshots = () => {
+			if (snapshotInterval) clearInterval(snapshotInterval);
+			if (firstSnapshot) clearTimeout(firstSnapshot);
+			snapshotInterval = null;
+			firstSnapshot = null;
+		};
+
+		const subscribe = async () => {
+			try {
+				const stream = streamContainerStats(abortController.signal, () => {
+					setIsConnected(true);
+					retryDelay = INITIAL_RETRY_DELAY_MS;
+					latest.clear();
+					// The latest known readings arrive in one burst on connect.
+					firstSnapshot = setTimeout(publish, FIRST_SNAPSHOT_DELAY_MS);
+					snapshotInterval = setInterval(publish, SNAPSHOT_INTERVAL_MS);
+				});
+				for await (const event of stream) {
+					const key = `${event.host}/${event.id}`;
+					if (event.removed) {
+						latest.delete(key);
+					} else {
+						latest.set(key, event);
+					}
+				}
+			} catch {}
+
+			stopSnapshots();
+			setIsConnected(false);
+			setStats(undefined);
+			if (abortController.signal.aborted) return;
+
+			retryTimeout = setTimeout(() => {
+				void subscribe();
+			}, retryDelay);
+			retryDelay = Math.min(retryDelay * 2, MAX_RETRY_DELAY_MS);
+		};
+
+		void subscribe();
+
+		return () => {
+			abortController.abort();
+			stopSnapshots();
+			if (retryTimeout) {
+				clearTimeout(retryTimeout);
+			}
+			setIsConnected(false);
+			setStats(undefined);
+		};
+	}, [isVisible]);
+
+	return { stats, isConnected };
+}
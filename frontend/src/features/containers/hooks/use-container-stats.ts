@@ -4,32 +4,43 @@ import { useMemo } from "react";
 import { getContainerStats } from "../api/get-container-stats";
 import type { ContainerStatsMap } from "../types";
 
+import { useContainerStatsStream } from "./use-container-stats-stream";
 import { useDocumentVisible } from "./use-document-visible";
 import { useContainerStatsHistory } from "./use-stats-history";
 
+/**
+ * Live container stats from the server's shared stats stream. Polling only
+ * runs while the stream is down or has not produced its first snapshot yet.
+ */
 export function useContainerStats() {
 	const isVisible = useDocumentVisible();
+	const stream = useContainerStatsStream();
+	const isStreaming = stream.stats !== undefined;
 	const query = useQuery({
 		queryKey: ["containers", "stats"],
 		queryFn: getContainerStats,
-		refetchInterval: isVisible ? 5000 : false,
+		refetchInterval: isVisible && !isStreaming ? 5000 : false,
 		staleTime: 4000,
+		enabled: !isStreaming,
 	});
 
+	const stats = stream.stats ?? query.data?.stats;
+
 	const statsMap = useMemo<ContainerStatsMap>(() => {
-		if (!query.data?.stats) return {};
+		if (!stats) return {};
 
-		return query.data.stats.reduce((acc, stat) => {
+		return stats.reduce((acc, stat) => {
 			acc[stat.id] = stat;
 			return acc;
 		}, {} as ContainerStatsMap);
-	}, [query.data?.stats]);
+	}, [stats]);
 
-	const statsHistory = useContainerStatsHistory(query.data?.stats);
+	const statsHistory = useContainerStatsHistory(stats);
 
 	return {
 		...query,
 		statsMap,
 		statsHistory,
+		isStreaming,
 	};
 }
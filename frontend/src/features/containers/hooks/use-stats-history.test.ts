@@ -12,6 +12,13 @@ function makeStat(id: string, cpu: number, memory: number): ContainerStats {
 		memory_percent: memory,
 		memory_used: 0,
 		memory_limit: 0,
+		memory_cache: 0,
+		memory_rss: 0,
+		network_rx_per_sec: 0,
+		network_tx_per_sec: 0,
+		block_read_per_sec: 0,
+		block_write_per_sec: 0,
+		pids: 0,
 	};
 }
 
@@ -15,6 +15,8 @@ export interface ContainerInfo {
 	health?: string;
 	labels?: Record<string, string>;
 	host: string;
+	// Set when the registry has a newer image for the container's tag.
+	update_available?: boolean;
 }
 
 export interface HostError {
@@ -29,6 +31,14 @@ export interface ContainerStats {
 	memory_percent: number;
 	memory_used: number;
 	memory_limit: number;
+	memory_cache: number;
+	memory_rss: number;
+	// Bytes per second since the previous reading; 0 on the first one.
+	network_rx_per_sec: number;
+	network_tx_per_sec: number;
+	block_read_per_sec: number;
+	block_write_per_sec: number;
+	pids: number;
 }
 
 export type ContainerStatsMap = Record<string, ContainerStats>;
@@ -0,0 +1,20 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+import type { AlertHistoryEntry } from "./get-alert-history";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/history`;
+
+export async function acknowledgeAlert(id: string): Promise<AlertHistoryEntry> {
+	const response = await authenticatedFetch(
+		`${ENDPOINT}/${encodeURIComponent(id)}/ack`,
+		{ method: "POST" },
+	);
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to acknowledge alert");
+	}
+
+	return (await response.json()) as AlertHistoryEntry;
+}
@@ -0,0 +1,36 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+import type { AlertSilence } from "./get-alert-silences";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/silences`;
+
+// The server starts a silence now unless startsAt is given, and ends it at
+// endsAt or durationSeconds after its start.
+export interface AlertSilencePayload {
+	hosts?: string[];
+	containers?: string[];
+	projects?: string[];
+	rules?: string[];
+	startsAt?: string;
+	endsAt?: string;
+	durationSeconds?: number;
+	comment?: string;
+}
+
+export async function createAlertSilence(
+	silence: AlertSilencePayload,
+): Promise<AlertSilence> {
+	const response = await authenticatedFetch(ENDPOINT, {
+		method: "POST",
+		headers: { "Content-Type": "application/json" },
+		body: JSON.stringify(silence),
+	});
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to create alert silence");
+	}
+
+	return (await response.json()) as AlertSilence;
+}
@@ -0,0 +1,28 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+import type { MaintenanceWindow } from "./get-maintenance-windows";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/maintenance`;
+
+export type MaintenanceWindowPayload = Omit<
+	MaintenanceWindow,
+	"id" | "createdAt" | "active" | "activeSince" | "nextStart"
+>;
+
+export async function createMaintenanceWindow(
+	window: MaintenanceWindowPayload,
+): Promise<MaintenanceWindow> {
+	const response = await authenticatedFetch(ENDPOINT, {
+		method: "POST",
+		headers: { "Content-Type": "application/json" },
+		body: JSON.stringify(window),
+	});
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to create maintenance window");
+	}
+
+	return (await response.json()) as MaintenanceWindow;
+}
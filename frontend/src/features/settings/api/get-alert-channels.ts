@@ -3,7 +3,27 @@ import { API_BASE_URL } from "@/types/api";
 
 const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/channels`;
 
-export type AlertChannelType = "webhook" | "ntfy" | "gotify" | "telegram";
+export type AlertChannelType =
+	| "webhook"
+	| "ntfy"
+	| "gotify"
+	| "telegram"
+	| "smtp"
+	| "pagerduty"
+	| "matrix"
+	| "teams";
+
+// A channel's recent delivery record. Timestamps are RFC3339; retryAt is set
+// while the channel is backing off after a failure.
+export interface AlertChannelHealth {
+	lastSuccessAt?: string;
+	lastFailureAt?: string;
+	lastError?: string;
+	consecutiveFailures: number;
+	retryAt?: string;
+	pending: number;
+	deadLettered: number;
+}
 
 export interface AlertChannel {
 	id: string;
@@ -13,6 +33,15 @@ export interface AlertChannel {
 	url?: string;
 	token?: string;
 	target?: string;
+	// SMTP login and sender address.
+	username?: string;
+	from?: string;
+	// Go text/template sources replacing the default title (ntfy, gotify) and
+	// text; a webhook's body template renders the whole JSON body.
+	titleTemplate?: string;
+	bodyTemplate?: string;
+	// Reported by the server once the channel has been attempted.
+	health?: AlertChannelHealth;
 }
 
 export interface AlertChannelsResponse {
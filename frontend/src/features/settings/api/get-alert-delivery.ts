@@ -0,0 +1,21 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/delivery`;
+
+// A notification its channel has not accepted deadlineSeconds after it was
+// queued is given up on and recorded as failed.
+export interface AlertDeliverySettings {
+	deadlineSeconds: number;
+}
+
+export async function getAlertDelivery(): Promise<AlertDeliverySettings> {
+	const response = await authenticatedFetch(ENDPOINT);
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to load alert delivery settings");
+	}
+
+	return (await response.json()) as AlertDeliverySettings;
+}
@@ -0,0 +1,22 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/history/retention`;
+
+// Resolved incidents older than retentionDays, or beyond the newest
+// maxEntries, are pruned. Firing incidents are always kept.
+export interface AlertHistoryRetention {
+	retentionDays: number;
+	maxEntries: number;
+}
+
+export async function getAlertHistoryRetention(): Promise<AlertHistoryRetention> {
+	const response = await authenticatedFetch(ENDPOINT);
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to load alert history retention");
+	}
+
+	return (await response.json()) as AlertHistoryRetention;
+}
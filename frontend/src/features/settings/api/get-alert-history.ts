@@ -1,7 +1,8 @@
 import { authenticatedFetch } from "@/lib/api-client";
 import { API_BASE_URL } from "@/types/api";
 
-import type { AlertRuleType } from "./get-alert-rules";
+import type { AlertChannelType } from "./get-alert-channels";
+import type { AlertRuleType, AlertSeverity } from "./get-alert-rules";
 
 const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/history`;
 
@@ -11,11 +12,17 @@ export interface AlertDelivery {
 	error?: string;
 }
 
+export interface AlertRouting {
+	channels: { id: string; name?: string; type: AlertChannelType }[];
+	reason: string;
+}
+
 export interface AlertHistoryEntry {
 	id: string;
 	ruleId: string;
 	ruleName: string;
 	type: AlertRuleType;
+	severity?: AlertSeverity;
 	host: string;
 	containerId: string;
 	containerName: string;
@@ -25,17 +32,52 @@ export interface AlertHistoryEntry {
 	suppressed: number;
 	firedAt: string;
 	delivery?: AlertDelivery;
+	// Names the silence or maintenance window that muted the incident.
+	suppressedBy?: string;
+	routing?: AlertRouting;
+	state: "firing" | "resolved";
+	lastFiredAt?: string;
+	repeats?: number;
+	resolvedAt?: string;
+	resolveReason?: string;
+	durationSeconds?: number;
+	resolveDelivery?: AlertDelivery;
+	acknowledgedAt?: string;
+	acknowledgedBy?: string;
+	silenced?: boolean;
+	silencedUntil?: string;
 }
 
 export interface AlertHistoryResponse {
 	alerts: AlertHistoryEntry[];
 	count: number;
+	// Cursor for the next (older) page; absent on the last page.
+	nextCursor?: string;
+}
+
+// Filters are applied by the server. Empty values match everything.
+export interface AlertHistoryParams {
+	rule?: string;
+	host?: string;
+	container?: string;
+	project?: string;
+	state?: "firing" | "resolved";
+	// "ok", "failed", "suppressed", or "none" for incidents never sent.
+	delivery?: string;
+	since?: string;
+	until?: string;
+	limit?: number;
+	cursor?: string;
 }
 
 export async function getAlertHistory(
-	limit: number,
+	params: AlertHistoryParams = {},
 ): Promise<AlertHistoryResponse> {
-	const response = await authenticatedFetch(`${ENDPOINT}?limit=${limit}`);
+	const query = new URLSearchParams();
+	for (const [key, value] of Object.entries(params)) {
+		if (value !== undefined && value !== "") query.set(key, String(value));
+	}
+	const response = await authenticatedFetch(`${ENDPOINT}?${query}`);
 
 	if (!response.ok) {
 		const message = await response.text();
@@ -3,8 +3,19 @@ import { API_BASE_URL } from "@/types/api";
 
 const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/rules`;
 
-export type AlertRuleType = "event" | "log";
-export type AlertEventKind = "die" | "oom" | "unhealthy";
+export type AlertRuleType = "event" | "log" | "metric";
+export type AlertEventKind = "die" | "oom" | "unhealthy" | "restart_loop";
+export type AlertMetric = "cpu_percent" | "memory_percent";
+export type AlertSeverity = "info" | "warning" | "critical";
+
+// A route sends a rule's alerts for matching containers to specific
+// channels; the first matching route wins.
+export interface AlertRoute {
+	hosts?: string[];
+	containers?: string[];
+	projects?: string[];
+	channels: string[];
+}
 
 export interface AlertRule {
 	id: string;
@@ -17,9 +28,17 @@ export interface AlertRule {
 	events?: AlertEventKind[];
 	minLevel?: string;
 	pattern?: string;
+	mode?: "absence";
+	metric?: AlertMetric;
+	operator?: ">" | "<";
+	value?: number;
+	aggregate?: "sustained" | "avg";
 	threshold: number;
 	windowSeconds?: number;
 	cooldownSeconds?: number;
+	severity?: AlertSeverity;
+	channels?: string[];
+	routes?: AlertRoute[];
 	createdAt: string;
 }
 
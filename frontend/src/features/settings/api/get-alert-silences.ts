@@ -0,0 +1,36 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/silences`;
+
+// A silence mutes the alerts it matches between startsAt and endsAt: they are
+// recorded in history as suppressed-by-silence but not notified. Empty
+// targeting dimensions match everything.
+export interface AlertSilence {
+	id: string;
+	hosts?: string[];
+	containers?: string[];
+	projects?: string[];
+	rules?: string[];
+	startsAt: string;
+	endsAt: string;
+	comment?: string;
+	createdBy?: string;
+	createdAt: string;
+	active: boolean;
+}
+
+export interface AlertSilencesResponse {
+	silences: AlertSilence[];
+}
+
+export async function getAlertSilences(): Promise<AlertSilencesResponse> {
+	const response = await authenticatedFetch(ENDPOINT);
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to load alert silences");
+	}
+
+	return (await response.json()) as AlertSilencesResponse;
+}
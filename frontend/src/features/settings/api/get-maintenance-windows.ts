@@ -0,0 +1,39 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/maintenance`;
+
+// A maintenance window is a recurring silence: it mutes matching alerts for
+// durationSeconds each time its cron schedule fires, in timezone (UTC when
+// unset). activeSince or nextStart say when it is or will next be in effect.
+export interface MaintenanceWindow {
+	id: string;
+	name: string;
+	enabled: boolean;
+	schedule: string;
+	durationSeconds: number;
+	timezone?: string;
+	hosts?: string[];
+	containers?: string[];
+	projects?: string[];
+	rules?: string[];
+	createdAt: string;
+	active: boolean;
+	activeSince?: string;
+	nextStart?: string;
+}
+
+export interface MaintenanceWindowsResponse {
+	windows: MaintenanceWindow[];
+}
+
+export async function getMaintenanceWindows(): Promise<MaintenanceWindowsResponse> {
+	const response = await authenticatedFetch(ENDPOINT);
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to load maintenance windows");
+	}
+
+	return (await response.json()) as MaintenanceWindowsResponse;
+}
@@ -0,0 +1,29 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+import type { AlertHistoryEntry } from "./get-alert-history";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/history`;
+
+// silenceAlert mutes an incident's notifications for durationSeconds, or
+// until it resolves when durationSeconds is 0.
+export async function silenceAlert(
+	id: string,
+	durationSeconds: number,
+): Promise<AlertHistoryEntry> {
+	const response = await authenticatedFetch(
+		`${ENDPOINT}/${encodeURIComponent(id)}/silence`,
+		{
+			method: "POST",
+			headers: { "Content-Type": "application/json" },
+			body: JSON.stringify({ durationSeconds }),
+		},
+	);
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to silence alert");
+	}
+
+	return (await response.json()) as AlertHistoryEntry;
+}
@@ -3,15 +3,27 @@ import { API_BASE_URL } from "@/types/api";
 
 const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/channels`;
 
+export interface NotificationPreview {
+	title?: string;
+	body: string;
+}
+
 export interface AlertTestResult {
-	status: "ok" | "failed";
+	status: "ok" | "failed" | "skipped";
 	httpStatus?: number;
 	error?: string;
+	preview?: NotificationPreview;
 }
 
-export async function testAlertChannel(id: string): Promise<AlertTestResult> {
+// A dry run renders the test notification through the channel's templates
+// without delivering it (status "skipped").
+export async function testAlertChannel(
+	id: string,
+	dryRun = false,
+): Promise<AlertTestResult> {
+	const query = dryRun ? "?dryRun=true" : "";
 	const response = await authenticatedFetch(
-		`${ENDPOINT}/${encodeURIComponent(id)}/test`,
+		`${ENDPOINT}/${encodeURIComponent(id)}/test${query}`,
 		{ method: "POST" },
 	);
 
@@ -0,0 +1,24 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+import type { AlertDeliverySettings } from "./get-alert-delivery";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/delivery`;
+
+/** A zero deadline restores the default. */
+export async function updateAlertDelivery(
+	settings: AlertDeliverySettings,
+): Promise<AlertDeliverySettings> {
+	const response = await authenticatedFetch(ENDPOINT, {
+		method: "PUT",
+		headers: { "Content-Type": "application/json" },
+		body: JSON.stringify(settings),
+	});
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to update alert delivery settings");
+	}
+
+	return (await response.json()) as AlertDeliverySettings;
+}
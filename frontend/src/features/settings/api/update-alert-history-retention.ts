@@ -0,0 +1,24 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+import type { AlertHistoryRetention } from "./get-alert-history-retention";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/history/retention`;
+
+/** Zero restores the default for that bound. */
+export async function updateAlertHistoryRetention(
+	retention: AlertHistoryRetention,
+): Promise<AlertHistoryRetention> {
+	const response = await authenticatedFetch(ENDPOINT, {
+		method: "PUT",
+		headers: { "Content-Type": "application/json" },
+		body: JSON.stringify(retention),
+	});
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to update alert history retention");
+	}
+
+	return (await response.json()) as AlertHistoryRetention;
+}
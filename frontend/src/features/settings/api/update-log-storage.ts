@@ -1,5 +1,6 @@
 import { authenticatedFetch } from "@/lib/api-client";
 import { API_BASE_URL } from "@/types/api";
+import type { IngestionRule, RetentionRule } from "../types";
 
 const ENDPOINT = `${API_BASE_URL}/api/v1/settings/log-storage`;
 
@@ -8,6 +9,10 @@ export interface UpdateLogStoragePayload {
 	enabled?: boolean;
 	perContainerMB?: number;
 	totalMB?: number;
+	fullTextIndex?: boolean;
+	indexedFields?: string[];
+	retentionRules?: RetentionRule[];
+	ingestionRules?: IngestionRule[];
 }
 
 export async function updateLogStorage(
@@ -0,0 +1,28 @@
+import { authenticatedFetch } from "@/lib/api-client";
+import { API_BASE_URL } from "@/types/api";
+
+import type { MaintenanceWindowPayload } from "./create-maintenance-window";
+import type { MaintenanceWindow } from "./get-maintenance-windows";
+
+const ENDPOINT = `${API_BASE_URL}/api/v1/alerts/maintenance`;
+
+export async function updateMaintenanceWindow(
+	id: string,
+	window: MaintenanceWindowPayload,
+): Promise<MaintenanceWindow> {
+	const response = await authenticatedFetch(
+		`${ENDPOINT}/${encodeURIComponent(id)}`,
+		{
+			method: "PUT",
+			headers: { "Content-Type": "application/json" },
+			body: JSON.stringify(window),
+		},
+	);
+
+	if (!response.ok) {
+		const message = await response.text();
+		throw new Error(message || "Failed to update maintenance window");
+	}
+
+	return (await response.json()) as MaintenanceWindow;
+}
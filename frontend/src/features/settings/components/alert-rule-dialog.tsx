@@ -1,7 +1,8 @@
-import { useQuery } from "@tanstack/react-query";
 import {
 	ArrowLeftIcon,
 	CheckIcon,
+	CpuIcon,
+	GaugeIcon,
 	HeartPulseIcon,
 	type LucideIcon,
 	MemoryStickIcon,
@@ -9,10 +10,11 @@ import {
 	RegexIcon,
 	RotateCcwIcon,
 	SlidersHorizontalIcon,
+	TimerOffIcon,
 	TrendingUpIcon,
 	XIcon,
 } from "lucide-react";
-import { type ReactNode, useMemo, useState } from "react";
+import { type ReactNode, useState } from "react";
 import { toast } from "sonner";
 
 import { Button } from "@/components/ui/button";
@@ -34,16 +36,24 @@ import {
 } from "@/components/ui/select";
 import { Separator } from "@/components/ui/separator";
 import { Spinner } from "@/components/ui/spinner";
-import { getContainers } from "@/features/containers/api/get-containers";
-import {
-	formatContainerName,
-	getComposeProject,
-} from "@/features/containers/components/container-utils";
 import { cn } from "@/lib/utils";
 
 import type { AlertRulePayload } from "../api/create-alert-rule";
-import type { AlertEventKind, AlertRule } from "../api/get-alert-rules";
-import { useCreateAlertRule, useUpdateAlertRule } from "../hooks/use-alerts";
+import type {
+	AlertEventKind,
+	AlertMetric,
+	AlertRoute,
+	AlertRule,
+	AlertRuleType,
+	AlertSeverity,
+} from "../api/get-alert-rules";
+import {
+	useAlertChannels,
+	useCreateAlertRule,
+	useUpdateAlertRule,
+} from "../hooks/use-alerts";
+import { useTargetOptions } from "../hooks/use-target-options";
+import { channelName, describeRoute } from "./channel-utils";
 import { MultiCombobox } from "./multi-combobox";
 
 const LOG_LEVELS = [
@@ -56,14 +66,31 @@ const LOG_LEVELS = [
 	"PANIC",
 ];
 
+const METRIC_LABELS: Record<AlertMetric, string> = {
+	cpu_percent: "CPU %",
+	memory_percent: "Memory %",
+};
+
+const SEVERITY_LABELS: Record<AlertSeverity, string> = {
+	info: "Info",
+	warning: "Warning",
+	critical: "Critical",
+};
+
 interface FormState {
 	name: string;
-	type: "log" | "event";
+	type: AlertRuleType;
 	minLevel: string;
 	pattern: string;
 	die: boolean;
 	oom: boolean;
 	unhealthy: boolean;
+	restartLoop: boolean;
+	metric: AlertMetric;
+	operator: ">" | "<";
+	metricValue: string;
+	aggregate: "sustained" | "avg";
+	absent: boolean;
 	rateEnabled: boolean;
 	threshold: string;
 	windowSeconds: string;
@@ -72,6 +99,10 @@ interface FormState {
 	hosts: string[];
 	cooldownValue: string;
 	cooldownUnit: "minutes" | "seconds";
+	severity: AlertSeverity;
+	channels: string[];
+	// Routes can only be removed here; the CLI and API create them.
+	routes: AlertRoute[];
 }
 
 const EMPTY_FORM: FormState = {
@@ -82,6 +113,12 @@ const EMPTY_FORM: FormState = {
 	die: false,
 	oom: false,
 	unhealthy: false,
+	restartLoop: false,
+	metric: "memory_percent",
+	operator: ">",
+	metricValue: "90",
+	aggregate: "sustained",
+	absent: false,
 	rateEnabled: false,
 	threshold: "1",
 	windowSeconds: "",
@@ -90,6 +127,9 @@ const EMPTY_FORM: FormState = {
 	hosts: [],
 	cooldownValue: "",
 	cooldownUnit: "minutes",
+	severity: "warning",
+	channels: [],
+	routes: [],
 };
 
 interface Preset {
@@ -114,11 +154,11 @@ const PRESETS: Preset[] = [
 		id: "crash-loop",
 		icon: RotateCcwIcon,
 		title: "Crash looping",
-		description: "A container dies 3 times within 2 minutes.",
+		description: "A container restarts 3 times within 2 minutes.",
 		form: {
 			name: "Crash looping",
 			type: "event",
-			die: true,
+			restartLoop: true,
 			rateEnabled: true,
 			threshold: "3",
 			windowSeconds: "120",
@@ -141,6 +181,35 @@ const PRESETS: Preset[] = [
 		form: { name: "Health check failing", type: "event", unhealthy: true },
 		focus: null,
 	},
+	{
+		id: "high-memory",
+		icon: GaugeIcon,
+		title: "High memory",
+		description: "Memory stays above 90% of the limit for 5 minutes.",
+		form: {
+			name: "High memory",
+			type: "metric",
+			metric: "memory_percent",
+			metricValue: "90",
+			windowSeconds: "300",
+		},
+		focus: null,
+	},
+	{
+		id: "high-cpu",
+		icon: CpuIcon,
+		title: "High CPU",
+		description: "CPU averages above 200% over 2 minutes.",
+		form: {
+			name: "High CPU",
+			type: "metric",
+			metric: "cpu_percent",
+			metricValue: "200",
+			aggregate: "avg",
+			windowSeconds: "120",
+		},
+		focus: null,
+	},
 	{
 		id: "error-spike",
 		icon: TrendingUpIcon,
@@ -156,6 +225,20 @@ const PRESETS: Preset[] = [
 		},
 		focus: null,
 	},
+	{
+		id: "heartbeat",
+		icon: TimerOffIcon,
+		title: "Heartbeat missing",
+		description: "A container stops logging an expected line for an hour.",
+		form: {
+			name: "Heartbeat missing",
+			type: "log",
+			absent: true,
+			threshold: "1",
+			windowSeconds: "3600",
+		},
+		focus: "pattern",
+	},
 	{
 		id: "pattern",
 		icon: RegexIcon,
@@ -184,7 +267,16 @@ function ruleToForm(rule: AlertRule): FormState {
 		die: rule.events?.includes("die") ?? false,
 		oom: rule.events?.includes("oom") ?? false,
 		unhealthy: rule.events?.includes("unhealthy") ?? false,
-		rateEnabled: rule.threshold > 1 || Boolean(rule.windowSeconds),
+		restartLoop: rule.events?.includes("restart_loop") ?? false,
+		metric: rule.metric ?? "memory_percent",
+		operator: rule.operator ?? ">",
+		metricValue: rule.value !== undefined ? String(rule.value) : "0",
+		aggregate: rule.aggregate ?? "sustained",
+		absent: rule.mode === "absence",
+		rateEnabled:
+			rule.type !== "metric" &&
+			rule.mode !== "absence" &&
+			(rule.threshold > 1 || Boolean(rule.windowSeconds)),
 		threshold: String(rule.threshold),
 		windowSeconds: rule.windowSeconds ? String(rule.windowSeconds) : "",
 		containers: rule.containers ?? [],
@@ -195,6 +287,9 @@ function ruleToForm(rule: AlertRule): FormState {
 				? String(cooldown % 60 === 0 ? cooldown / 60 : cooldown)
 				: "",
 		cooldownUnit: cooldown > 0 && cooldown % 60 !== 0 ? "seconds" : "minutes",
+		severity: rule.severity ?? "warning",
+		channels: rule.channels ?? [],
+		routes: rule.routes ?? [],
 	};
 }
 
@@ -213,23 +308,39 @@ function buildPayload(
 
 	if (form.type === "log") {
 		const pattern = form.pattern.trim();
-		if (form.minLevel === "any" && !pattern) {
+		// An absence rule with neither filter expects any line at all.
+		if (form.minLevel === "any" && !pattern && !form.absent) {
 			return { error: "Log rules need a minimum level or a pattern" };
 		}
 		if (form.minLevel !== "any") payload.minLevel = form.minLevel;
 		if (pattern) payload.pattern = pattern;
+		if (form.absent) payload.mode = "absence";
+	} else if (form.type === "metric") {
+		const value = Number.parseFloat(form.metricValue);
+		if (Number.isNaN(value) || value < 0) {
+			return { error: "Metric rules need a value of 0 or more" };
+		}
+		payload.metric = form.metric;
+		payload.operator = form.operator;
+		payload.value = value;
+		payload.aggregate = form.aggregate;
+		const windowSeconds = Number.parseInt(form.windowSeconds, 10);
+		if (!Number.isNaN(windowSeconds) && windowSeconds > 0) {
+			payload.windowSeconds = windowSeconds;
+		}
 	} else {
 		const events: AlertEventKind[] = [];
 		if (form.die) events.push("die");
 		if (form.oom) events.push("oom");
 		if (form.unhealthy) events.push("unhealthy");
+		if (form.restartLoop) events.push("restart_loop");
 		if (events.length === 0) {
 			return { error: "Event rules need at least one event" };
 		}
 		payload.events = events;
 	}
 
-	if (form.rateEnabled) {
+	if ((form.rateEnabled || form.absent) && form.type !== "metric") {
 		const threshold = Number.parseInt(form.threshold, 10);
 		if (!Number.isNaN(threshold) && threshold > 0) {
 			payload.threshold = threshold;
@@ -250,6 +361,10 @@ function buildPayload(
 	if (form.projects.length > 0) payload.projects = form.projects;
 	if (form.hosts.length > 0) payload.hosts = form.hosts;
 
+	payload.severity = form.severity;
+	if (form.channels.length > 0) payload.channels = form.channels;
+	if (form.routes.length > 0) payload.routes = form.routes;
+
 	return { payload };
 }
 
@@ -277,13 +392,25 @@ function summarize(form: FormState): string {
 		]
 			.filter(Boolean)
 			.join(" ");
-		if (form.rateEnabled) {
+		if (form.absent) {
+			const expected = Number.parseInt(form.threshold, 10) || 1;
+			condition = `logs ${
+				expected > 1 ? `fewer than ${expected} lines` : "no line"
+			}${qualifiers ? ` ${qualifiers}` : ""} for ${form.windowSeconds || "60"}s`;
+		} else if (form.rateEnabled) {
 			condition = `logs ${form.threshold || "?"} lines${
 				qualifiers ? ` ${qualifiers}` : ""
 			} within ${form.windowSeconds || "?"}s`;
 		} else {
 			condition = `logs a line${qualifiers ? ` ${qualifiers}` : ""}`;
 		}
+	} else if (form.type === "metric") {
+		const comparison = `${form.operator === ">" ? "above" : "below"} ${form.metricValue || "?"}%`;
+		const window = `${form.windowSeconds || "60"}s`;
+		condition =
+			form.aggregate === "avg"
+				? `averages ${METRIC_LABELS[form.metric]} ${comparison} over ${window}`
+				: `keeps ${METRIC_LABELS[form.metric]} ${comparison} for ${window}`;
 	} else {
 		const events =
 			[
@@ -293,9 +420,13 @@ function summarize(form: FormState): string {
 			]
 				.filter(Boolean)
 				.join(" or ") || "…";
-		condition = form.rateEnabled
-			? `${events} ${form.threshold || "?"} times within ${form.windowSeconds || "?"}s`
-			: events;
+		if (form.restartLoop) {
+			condition = `restarts ${form.rateEnabled ? form.threshold || "?" : "3"} times within ${form.windowSeconds || "60"}s`;
+		} else {
+			condition = form.rateEnabled
+				? `${events} ${form.threshold || "?"} times within ${form.windowSeconds || "?"}s`
+				: events;
+		}
 	}
 
 	let quiet: string;
@@ -309,7 +440,7 @@ function summarize(form: FormState): string {
 		quiet = "5 minutes";
 	}
 
-	return `When ${target} ${condition} → alert, then stay quiet for ${quiet}.`;
+	return `When ${target} ${condition} → ${form.severity} alert, then stay quiet for ${quiet}.`;
 }
 
 // Extends the click target above/below the visual bounds so 32-36px controls
@@ -455,41 +586,38 @@ function RuleEditor({
 		null,
 	);
 
+	const channelsQuery = useAlertChannels();
+	const channels = channelsQuery.data?.channels ?? [];
+
 	const createMutation = useCreateAlertRule();
 	const updateMutation = useUpdateAlertRule();
 	const isSaving = createMutation.isPending || updateMutation.isPending;
 
-	const containersQuery = useQuery({
-		queryKey: ["containers"],
-		queryFn: getContainers,
-		staleTime: 30_000,
-	});
-
-	const targetOptions = useMemo(() => {
-		const containers = new Set<string>();
-		const projects = new Set<string>();
-		const hosts = new Set<string>();
-		for (const container of containersQuery.data?.containers ?? []) {
-			const name = formatContainerName(container.names);
-			if (name !== "—") containers.add(name);
-			const project = getComposeProject(container.labels);
-			if (project) projects.add(project);
-			if (container.host) hosts.add(container.host);
-		}
-		for (const host of containersQuery.data?.hosts ?? []) {
-			hosts.add(host.name);
-		}
-		return {
-			containers: [...containers],
-			projects: [...projects],
-			hosts: [...hosts],
-		};
-	}, [containersQuery.data]);
+	const targetOptions = useTargetOptions();
 
 	function set<K extends keyof FormState>(key: K, value: FormState[K]) {
 		setForm((prev) => ({ ...prev, [key]: value }));
 	}
 
+	// A restart loop counts restarts rather than occurrences, so it cannot
+	// share a rule with the other events.
+	function setEvent(
+		key: "die" | "oom" | "unhealthy" | "restartLoop",
+		value: boolean,
+	) {
+		setForm((prev) =>
+			key === "restartLoop"
+				? {
+						...prev,
+						die: false,
+						oom: false,
+						unhealthy: false,
+						restartLoop: value,
+					}
+				: { ...prev, restartLoop: false, [key]: value },
+		);
+	}
+
 	function applyPreset(preset: Preset) {
 		setForm({ ...EMPTY_FORM, ...preset.form });
 		setInitialFocus(preset.focus);
@@ -505,6 +633,25 @@ function RuleEditor({
 		}));
 	}
 
+	function enableAbsence() {
+		setForm((prev) => ({
+			...prev,
+			absent: true,
+			rateEnabled: false,
+			threshold: "1",
+			windowSeconds: prev.windowSeconds || "300",
+		}));
+	}
+
+	function disableAbsence() {
+		setForm((prev) => ({
+			...prev,
+			absent: false,
+			threshold: "1",
+			windowSeconds: "",
+		}));
+	}
+
 	function disableRate() {
 		setForm((prev) => ({
 			...prev,
@@ -596,6 +743,14 @@ function RuleEditor({
 					>
 						Event
 					</Button>
+					<Button
+						type="button"
+						size="sm"
+						variant={form.type === "metric" ? "default" : "outline"}
+						onClick={() => set("type", "metric")}
+					>
+						Metric
+					</Button>
 				</div>
 
 				{form.type === "log" ? (
@@ -641,27 +796,131 @@ function RuleEditor({
 							</div>
 						</div>
 					</div>
+				) : form.type === "metric" ? (
+					<div className="flex flex-wrap items-center gap-x-1.5 gap-y-2 text-sm">
+						<Select
+							value={form.metric}
+							onValueChange={(v) => set("metric", v as AlertMetric)}
+						>
+							<SelectTrigger size="sm" className="w-28" aria-label="Metric">
+								<SelectValue />
+							</SelectTrigger>
+							<SelectContent>
+								{(Object.keys(METRIC_LABELS) as AlertMetric[]).map((metric) => (
+									<SelectItem key={metric} value={metric}>
+										{METRIC_LABELS[metric]}
+									</SelectItem>
+								))}
+							</SelectContent>
+						</Select>
+						<Select
+							value={form.aggregate}
+							onValueChange={(v) => set("aggregate", v as "sustained" | "avg")}
+						>
+							<SelectTrigger size="sm" className="w-32" aria-label="Aggregate">
+								<SelectValue />
+							</SelectTrigger>
+							<SelectContent>
+								<SelectItem value="sustained">stays</SelectItem>
+								<SelectItem value="avg">averages</SelectItem>
+							</SelectContent>
+						</Select>
+						<Select
+							value={form.operator}
+							onValueChange={(v) => set("operator", v as ">" | "<")}
+						>
+							<SelectTrigger size="sm" className="w-24" aria-label="Comparison">
+								<SelectValue />
+							</SelectTrigger>
+							<SelectContent>
+								<SelectItem value=">">above</SelectItem>
+								<SelectItem value="<">below</SelectItem>
+							</SelectContent>
+						</Select>
+						<InlineNumberInput
+							id="alert-rule-metric-value"
+							value={form.metricValue}
+							onChange={(v) => set("metricValue", v)}
+							min={0}
+							max={form.metric === "memory_percent" ? 100 : 10000}
+							ariaLabel="Metric value"
+						/>
+						% {form.aggregate === "avg" ? "over" : "for"}
+						<InlineNumberInput
+							id="alert-rule-metric-window"
+							value={form.windowSeconds}
+							onChange={(v) => set("windowSeconds", v)}
+							min={5}
+							max={3600}
+							placeholder="60"
+							ariaLabel="Window in seconds"
+						/>
+						seconds
+					</div>
 				) : (
 					<div className="flex items-center gap-2">
 						<ToggleChip
 							label="Container died"
 							pressed={form.die}
-							onToggle={() => set("die", !form.die)}
+							onToggle={() => setEvent("die", !form.die)}
 						/>
 						<ToggleChip
 							label="Out of memory"
 							pressed={form.oom}
-							onToggle={() => set("oom", !form.oom)}
+							onToggle={() => setEvent("oom", !form.oom)}
 						/>
 						<ToggleChip
 							label="Unhealthy"
 							pressed={form.unhealthy}
-							onToggle={() => set("unhealthy", !form.unhealthy)}
+							onToggle={() => setEvent("unhealthy", !form.unhealthy)}
+						/>
+						<ToggleChip
+							label="Restart loop"
+							pressed={form.restartLoop}
+							onToggle={() => setEvent("restartLoop", !form.restartLoop)}
 						/>
 					</div>
 				)}
 
-				{form.rateEnabled ? (
+				{form.type === "metric" ? (
+					<p className="text-xs text-muted-foreground">
+						CPU is a percentage of one core, so it can exceed 100 on a
+						multi-core host. Containers are sampled every 15 seconds.
+					</p>
+				) : form.type === "log" && form.absent ? (
+					<div className="flex flex-wrap items-center gap-x-1.5 gap-y-2 text-sm">
+						Alert when fewer than
+						<InlineNumberInput
+							id="alert-rule-expected"
+							value={form.threshold}
+							onChange={(v) => set("threshold", v)}
+							min={1}
+							max={1000}
+							ariaLabel="Expected line count"
+						/>
+						matching lines arrive within
+						<InlineNumberInput
+							id="alert-rule-absence-window"
+							value={form.windowSeconds}
+							onChange={(v) => set("windowSeconds", v)}
+							min={5}
+							max={86400}
+							placeholder="300"
+							ariaLabel="Window in seconds"
+						/>
+						seconds
+						<Button
+							type="button"
+							variant="ghost"
+							size="icon"
+							onClick={disableAbsence}
+							aria-label="Alert on matching lines instead"
+							className="ml-0.5 size-6 text-muted-foreground hover:text-foreground"
+						>
+							<XIcon className="size-3.5" />
+						</Button>
+					</div>
+				) : form.rateEnabled ? (
 					<div className="flex flex-wrap items-center gap-x-1.5 gap-y-2 text-sm">
 						Alert after
 						<InlineNumberInput
@@ -672,7 +931,7 @@ function RuleEditor({
 							max={1000}
 							ariaLabel="Occurrence threshold"
 						/>
-						occurrences within
+						{form.restartLoop ? "restarts" : "occurrences"} within
 						<InlineNumberInput
 							id="alert-rule-window"
 							value={form.windowSeconds}
@@ -696,7 +955,9 @@ function RuleEditor({
 					</div>
 				) : (
 					<p className="text-sm">
-						Alert on the first occurrence.{" "}
+						{form.restartLoop
+							? "Alert after 3 restarts within a minute."
+							: "Alert on the first occurrence."}{" "}
 						<Button
 							type="button"
 							variant="link"
@@ -706,6 +967,20 @@ function RuleEditor({
 						>
 							Add a rate condition
 						</Button>
+						{form.type === "log" && (
+							<>
+								{" · "}
+								<Button
+									type="button"
+									variant="link"
+									size="sm"
+									onClick={enableAbsence}
+									className="h-auto p-0 text-xs"
+								>
+									Alert when lines stop arriving
+								</Button>
+							</>
+						)}
 					</p>
 				)}
 			</div>
@@ -777,6 +1052,95 @@ function RuleEditor({
 				</p>
 			</div>
 
+			<div className="space-y-3">
+				<SectionLabel>Notify</SectionLabel>
+				<div className="flex items-center gap-3">
+					<Label>Severity</Label>
+					<Select
+						value={form.severity}
+						onValueChange={(v) => set("severity", v as AlertSeverity)}
+					>
+						<SelectTrigger size="sm" className="w-32">
+							<SelectValue />
+						</SelectTrigger>
+						<SelectContent>
+							{(Object.keys(SEVERITY_LABELS) as AlertSeverity[]).map(
+								(severity) => (
+									<SelectItem key={severity} value={severity}>
+										{SEVERITY_LABELS[severity]}
+									</SelectItem>
+								),
+							)}
+						</SelectContent>
+					</Select>
+				</div>
+				<div className="space-y-1.5">
+					<Label>Channels</Label>
+					{channels.length === 0 ? (
+						<p className="text-xs text-muted-foreground">
+							No channels configured yet.
+						</p>
+					) : (
+						<div className="flex flex-wrap items-center gap-2">
+							{channels.map((channel) => (
+								<ToggleChip
+									key={channel.id}
+									label={channelName(channel)}
+									pressed={form.channels.includes(channel.id)}
+									onToggle={() =>
+										set(
+											"channels",
+											form.channels.includes(channel.id)
+												? form.channels.filter((id) => id !== channel.id)
+												: [...form.channels, channel.id],
+										)
+									}
+								/>
+							))}
+						</div>
+					)}
+					<p className="text-xs text-muted-foreground">
+						{form.channels.length === 0
+							? "Sends to every enabled channel. Pick channels to narrow it down."
+							: "Sends only to the picked channels that are enabled."}
+					</p>
+				</div>
+				{form.routes.length > 0 && (
+					<div className="space-y-1.5">
+						<Label>Routes</Label>
+						<ul className="space-y-1">
+							{form.routes.map((route, i) => (
+								// biome-ignore lint/suspicious/noArrayIndexKey: routes are ordered and have no id
+								<li key={i} className="flex items-center gap-1.5 text-xs">
+									<span className="text-muted-foreground">{i + 1}.</span>
+									<span className="min-w-0 flex-1 truncate font-mono">
+										{describeRoute(route, channels)}
+									</span>
+									<Button
+										type="button"
+										variant="ghost"
+										size="icon"
+										onClick={() =>
+											set(
+												"routes",
+												form.routes.filter((_, j) => j !== i),
+											)
+										}
+										aria-label={`Remove route ${i + 1}`}
+										className="size-6 text-muted-foreground hover:text-foreground"
+									>
+										<XIcon className="size-3.5" />
+									</Button>
+								</li>
+							))}
+						</ul>
+						<p className="text-xs text-muted-foreground">
+							The first route matching the container picks the channels instead.
+						</p>
+					</div>
+				)}
+			</div>
+
 			<Separator />
 
 			<div className="space-y-4">
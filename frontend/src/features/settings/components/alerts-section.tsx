@@ -41,33 +41,63 @@ import {
 	TableRow,
 } from "@/components/ui/table";
 
-import type { AlertChannel } from "../api/get-alert-channels";
-import type { AlertHistoryEntry } from "../api/get-alert-history";
+import type {
+	AlertChannel,
+	AlertChannelHealth,
+} from "../api/get-alert-channels";
+import type {
+	AlertHistoryEntry,
+	AlertHistoryParams,
+} from "../api/get-alert-history";
 import type { AlertRule } from "../api/get-alert-rules";
 import {
+	useAcknowledgeAlert,
 	useAlertChannels,
+	useAlertDelivery,
 	useAlertHistory,
+	useAlertHistoryRetention,
 	useAlertRules,
 	useClearAlertHistory,
 	useCreateAlertChannel,
 	useDeleteAlertChannel,
 	useDeleteAlertRule,
+	useSilenceAlert,
+	usePreviewAlertChannel,
 	useTestAlertChannel,
 	useUpdateAlertChannel,
+	useUpdateAlertDelivery,
+	useUpdateAlertHistoryRetention,
 	useUpdateAlertRule,
 } from "../hooks/use-alerts";
 import { AlertRuleDialog } from "./alert-rule-dialog";
 import {
+	bodyTemplatePlaceholder,
 	buildChannelPayload,
+	channelHasTitle,
 	CHANNEL_TYPES,
 	type ChannelDraft,
 	channelDestination,
+	channelNames,
 	channelTypeLabel,
+	describeRoute,
 	EMPTY_CHANNEL_DRAFT,
+	routingLabel,
 } from "./channel-utils";
 import { showResultToast } from "./mutation-toast";
+import { SilencesBlock } from "./silences-block";
 
-const HISTORY_LIMIT = 50;
+const HISTORY_PAGE_SIZE = 50;
+
+// Select items cannot have an empty value, so "all" stands for no filter.
+const ANY = "all";
+
+const DELIVERY_FILTERS = [
+	{ value: "ok", label: "Delivered" },
+	{ value: "failed", label: "Failed" },
+	{ value: "retrying", label: "Retrying" },
+	{ value: "suppressed-by-silence", label: "Suppressed" },
+	{ value: "none", label: "Not sent" },
+];
 
 export function AlertsSection() {
 	return (
@@ -77,7 +107,8 @@ export function AlertsSection() {
 				<CardDescription>
 					Get notified when containers die, run out of memory, become unhealthy,
 					or log errors. Alerts are delivered to every enabled channel and
-					recorded in the history below.
+					recorded in the history below; silences and maintenance windows mute
+					them without dropping them from history.
 				</CardDescription>
 			</CardHeader>
 			<CardContent className="space-y-6">
@@ -85,12 +116,54 @@ export function AlertsSection() {
 				<Separator />
 				<RulesBlock />
 				<Separator />
+				<SilencesBlock />
+				<Separator />
 				<HistoryBlock />
 			</CardContent>
 		</Card>
 	);
 }
 
+interface FieldCopy {
+	label: string;
+	placeholder: string;
+}
+
+// The URL, token, and target fields each type uses, with its wording; a type
+// missing from a map does not use that field.
+const URL_FIELD: Partial<Record<ChannelDraft["type"], FieldCopy>> = {
+	webhook: { label: "URL", placeholder: "https://example.com/webhook" },
+	ntfy: { label: "URL", placeholder: "https://ntfy.sh/mytopic" },
+	gotify: { label: "Server URL", placeholder: "https://gotify.example.com" },
+	smtp: { label: "SMTP server", placeholder: "smtp://mail.example.com:587" },
+	pagerduty: {
+		label: "Events API URL (optional)",
+		placeholder: "https://events.pagerduty.com/v2/enqueue",
+	},
+	matrix: { label: "Homeserver URL", placeholder: "https://matrix.example.org" },
+	teams: {
+		label: "Workflow URL",
+		placeholder: "https://prod-00.westus.logic.azure.com/workflows/…",
+	},
+};
+
+const TOKEN_FIELD: Partial<Record<ChannelDraft["type"], FieldCopy>> = {
+	gotify: { label: "App token", placeholder: "App token" },
+	telegram: { label: "Bot token", placeholder: "Bot token" },
+	smtp: { label: "Password", placeholder: "Password (optional)" },
+	pagerduty: { label: "Routing key", placeholder: "Integration routing key" },
+	matrix: { label: "Access token", placeholder: "syt_…" },
+};
+
+const TARGET_FIELD: Partial<Record<ChannelDraft["type"], FieldCopy>> = {
+	telegram: { label: "Chat id", placeholder: "-1001234567890" },
+	smtp: {
+		label: "Recipients",
+		placeholder: "ops@example.com, oncall@example.com",
+	},
+	matrix: { label: "Room ID", placeholder: "!abcdef:example.org" },
+};
+
 function ChannelDraftFields({
 	draft,
 	set,
@@ -98,59 +171,127 @@ function ChannelDraftFields({
 	draft: ChannelDraft;
 	set: <K extends keyof ChannelDraft>(key: K, value: ChannelDraft[K]) => void;
 }) {
-	const needsURL = draft.type !== "telegram";
-	const needsToken = draft.type === "gotify" || draft.type === "telegram";
-	const needsTarget = draft.type === "telegram";
-
-	const urlLabel = draft.type === "gotify" ? "Server URL" : "URL";
-	const urlPlaceholder =
-		draft.type === "ntfy"
-			? "https://ntfy.sh/mytopic"
-			: draft.type === "gotify"
-				? "https://gotify.example.com"
-				: "https://example.com/webhook";
-	const tokenLabel = draft.type === "gotify" ? "App token" : "Bot token";
+	const urlField = URL_FIELD[draft.type];
+	const tokenField = TOKEN_FIELD[draft.type];
+	const targetField = TARGET_FIELD[draft.type];
+	const [showTemplates, setShowTemplates] = useState(
+		Boolean(draft.titleTemplate || draft.bodyTemplate),
+	);
 
 	return (
 		<>
-			{needsURL && (
+			{urlField && (
 				<div className="space-y-1.5">
-					<Label htmlFor="channel-url">{urlLabel}</Label>
+					<Label htmlFor="channel-url">{urlField.label}</Label>
 					<Input
 						id="channel-url"
 						value={draft.url}
 						onChange={(e) => set("url", e.target.value)}
-						placeholder={urlPlaceholder}
+						placeholder={urlField.placeholder}
 						className="h-8"
 					/>
 				</div>
 			)}
-			{needsToken && (
+			{draft.type === "smtp" && (
+				<div className="flex flex-wrap gap-3">
+					<div className="space-y-1.5 flex-1 min-w-40">
+						<Label htmlFor="channel-from">From</Label>
+						<Input
+							id="channel-from"
+							value={draft.from}
+							onChange={(e) => set("from", e.target.value)}
+							placeholder="LogDeck <logdeck@example.com>"
+							className="h-8"
+						/>
+					</div>
+					<div className="space-y-1.5 flex-1 min-w-40">
+						<Label htmlFor="channel-username">
+							Username{" "}
+							<span className="font-normal text-muted-foreground">
+								(optional)
+							</span>
+						</Label>
+						<Input
+							id="channel-username"
+							autoComplete="off"
+							value={draft.username}
+							onChange={(e) => set("username", e.target.value)}
+							placeholder="alerts@example.com"
+							className="h-8"
+						/>
+					</div>
+				</div>
+			)}
+			{tokenField && (
 				<div className="space-y-1.5">
-					<Label htmlFor="channel-token">{tokenLabel}</Label>
+					<Label htmlFor="channel-token">{tokenField.label}</Label>
 					<Input
 						id="channel-token"
 						type="password"
 						autoComplete="new-password"
 						value={draft.token}
 						onChange={(e) => set("token", e.target.value)}
-						placeholder={tokenLabel}
+						placeholder={tokenField.placeholder}
 						className="h-8"
 					/>
 				</div>
 			)}
-			{needsTarget && (
+			{targetField && (
 				<div className="space-y-1.5">
-					<Label htmlFor="channel-target">Chat id</Label>
+					<Label htmlFor="channel-target">{targetField.label}</Label>
 					<Input
 						id="channel-target"
 						value={draft.target}
 						onChange={(e) => set("target", e.target.value)}
-						placeholder="-1001234567890"
+						placeholder={targetField.placeholder}
 						className="h-8"
 					/>
 				</div>
 			)}
+			{showTemplates ? (
+				<div className="space-y-3">
+					<p className="text-xs text-muted-foreground">
+						Go templates over the alert's fields ({"{{.RuleName}}"},{" "}
+						{"{{.Host}}"}, {"{{.ContainerName}}"}, {"{{.Project}}"},{" "}
+						{"{{.Reason}}"}, {"{{.SampleLines}}"}, {"{{.Resolved}}"}). Leave
+						empty for the default message.
+					</p>
+					{channelHasTitle(draft.type) && (
+						<div className="space-y-1.5">
+							<Label htmlFor="channel-title-template">Title template</Label>
+							<Input
+								id="channel-title-template"
+								value={draft.titleTemplate}
+								onChange={(e) => set("titleTemplate", e.target.value)}
+								placeholder="{{if .Resolved}}OK{{else}}DOWN{{end}} {{.ContainerName}}"
+								className="h-8 font-mono text-xs"
+							/>
+						</div>
+					)}
+					<div className="space-y-1.5">
+						<Label htmlFor="channel-body-template">
+							{draft.type === "webhook" ? "JSON body template" : "Message template"}
+						</Label>
+						<textarea
+							id="channel-body-template"
+							value={draft.bodyTemplate}
+							onChange={(e) => set("bodyTemplate", e.target.value)}
+							placeholder={bodyTemplatePlaceholder(draft.type)}
+							rows={4}
+							className="placeholder:text-muted-foreground dark:bg-input/30 border-input w-full rounded-md border bg-transparent px-3 py-2 font-mono text-xs shadow-xs outline-none focus-visible:border-ring focus-visible:ring-ring/50 focus-visible:ring-[3px]"
+						/>
+					</div>
+				</div>
+			) : (
+				<Button
+					variant="link"
+					size="sm"
+					className="h-auto p-0 text-xs"
+					onClick={() => setShowTemplates(true)}
+				>
+					Customize message
+				</Button>
+			)}
 		</>
 	);
 }
@@ -251,6 +392,7 @@ function ChannelsBlock() {
 	const updateMutation = useUpdateAlertChannel();
 	const deleteMutation = useDeleteAlertChannel();
 	const testMutation = useTestAlertChannel();
+	const previewMutation = usePreviewAlertChannel();
 
 	const [isAdding, setIsAdding] = useState(false);
 	const [channelToDelete, setChannelToDelete] = useState<AlertChannel | null>(
@@ -293,6 +435,28 @@ function ChannelsBlock() {
 		});
 	}
 
+	// Renders the test notification through the channel's templates without
+	// sending it.
+	function handlePreview(channel: AlertChannel) {
+		previewMutation.mutate(channel.id, {
+			onSuccess: (result) => {
+				if (!result.preview) {
+					toast.error(`Preview failed: ${result.error ?? "no output"}`);
+					return;
+				}
+				const { title, body } = result.preview;
+				toast.info(title || "Notification preview", {
+					description: (
+						<pre className="whitespace-pre-wrap break-all font-mono text-xs">
+							{body}
+						</pre>
+					),
+				});
+			},
+			onError: (err) => toast.error(err.message),
+		});
+	}
+
 	function handleDelete() {
 		if (!channelToDelete) return;
 		deleteMutation.mutate(channelToDelete.id, showResultToast);
@@ -322,6 +486,7 @@ function ChannelsBlock() {
 						<TableRow>
 							<TableHead>Type</TableHead>
 							<TableHead>Destination</TableHead>
+							<TableHead>Health</TableHead>
 							<TableHead>Enabled</TableHead>
 							<TableHead className="text-right">Actions</TableHead>
 						</TableRow>
@@ -343,6 +508,9 @@ function ChannelsBlock() {
 									<TableCell className="text-xs text-muted-foreground font-mono break-all">
 										{channelDestination(channel)}
 									</TableCell>
+									<TableCell>
+										<ChannelHealthStatus health={channel.health} />
+									</TableCell>
 									<TableCell>
 										<Switch
 											checked={channel.enabled}
@@ -355,6 +523,16 @@ function ChannelsBlock() {
 									</TableCell>
 									<TableCell className="text-right">
 										<div className="flex items-center justify-end gap-1">
+											{(channel.titleTemplate || channel.bodyTemplate) && (
+												<Button
+													variant="ghost"
+													size="sm"
+													disabled={previewMutation.isPending}
+													onClick={() => handlePreview(channel)}
+												>
+													Preview
+												</Button>
+											)}
 											<Button
 												variant="ghost"
 												size="sm"
@@ -396,6 +574,8 @@ function ChannelsBlock() {
 				</Button>
 			)}
 
+			<DeliveryDeadlineForm />
+
 			<AlertDialog
 				open={channelToDelete !== null}
 				onOpenChange={(open) => {
@@ -426,6 +606,96 @@ function ChannelsBlock() {
 	);
 }
 
+// Summarizes a channel's delivery record: healthy, or failing with its
+// backoff and queue. The last error is in the tooltip.
+function ChannelHealthStatus({ health }: { health?: AlertChannelHealth }) {
+	if (!health) {
+		return <span className="text-xs text-muted-foreground">—</span>;
+	}
+	const failing = health.consecutiveFailures > 0;
+	const retryIn = health.retryAt
+		? Math.round((Date.parse(health.retryAt) - Date.now()) / 1000)
+		: 0;
+	const notes = [
+		retryIn > 0 && `retry in ${formatIncidentDuration(retryIn)}`,
+		health.pending > 0 && `${health.pending} queued`,
+		health.deadLettered > 0 && `${health.deadLettered} dead-lettered`,
+	].filter(Boolean);
+	return (
+		<span
+			title={health.lastError}
+			className={`inline-flex flex-wrap items-center gap-1.5 text-xs ${
+				failing
+					? "text-red-600 dark:text-red-400"
+					: "text-green-600 dark:text-green-400"
+			}`}
+		>
+			<span
+				className={`size-1.5 rounded-full ${failing ? "bg-red-500" : "bg-green-500"}`}
+			/>
+			{failing ? `failing ×${health.consecutiveFailures}` : "ok"}
+			{notes.length > 0 && (
+				<span className="text-muted-foreground">· {notes.join(", ")}</span>
+			)}
+		</span>
+	);
+}
+
+// A cleared field saves 0, which restores the server's default deadline.
+function DeliveryDeadlineForm() {
+	const { data } = useAlertDelivery();
+	const mutation = useUpdateAlertDelivery();
+	const [minutes, setMinutes] = useState<string>();
+
+	if (!data) return null;
+
+	const current = String(Math.round(data.deadlineSeconds / 60));
+	const value = minutes ?? current;
+
+	function handleSave() {
+		mutation.mutate(
+			{ deadlineSeconds: Math.round(Number(value) * 60) || 0 },
+			{
+				onSuccess: () => {
+					setMinutes(undefined);
+					toast.success("Delivery deadline updated");
+				},
+				onError: (error) => toast.error(error.message),
+			},
+		);
+	}
+
+	return (
+		<div className="flex flex-wrap items-end gap-3">
+			<div className="space-y-1.5">
+				<Label htmlFor="alert-delivery-deadline" className="text-xs">
+					Retry failed notifications for (minutes)
+				</Label>
+				<Input
+					id="alert-delivery-deadline"
+					type="number"
+					min={1}
+					className="h-8 w-28"
+					value={value}
+					onChange={(e) => setMinutes(e.target.value)}
+				/>
+			</div>
+			<Button
+				size="sm"
+				variant="outline"
+				disabled={value === current || mutation.isPending}
+				onClick={handleSave}
+			>
+				Save
+			</Button>
+			<p className="w-full text-xs text-muted-foreground">
+				A failing channel is retried with backoff; notifications it has not
+				accepted by then are recorded as failed.
+			</p>
+		</div>
+	);
+}
+
 function renderTarget(rule: AlertRule): string {
 	const parts: string[] = [];
 	if (rule.hosts?.length) parts.push(`hosts: ${rule.hosts.join(", ")}`);
@@ -441,12 +711,22 @@ function renderTarget(rule: AlertRule): string {
 function renderTrigger(rule: AlertRule): string {
 	const parts: string[] = [];
 	if (rule.type === "log") {
+		if (rule.mode === "absence") parts.push("absent");
 		if (rule.minLevel) parts.push(`level >= ${rule.minLevel}`);
 		if (rule.pattern) parts.push(`pattern /${rule.pattern}/`);
+	} else if (rule.type === "metric") {
+		const window = rule.windowSeconds ?? 60;
+		parts.push(
+			rule.aggregate === "avg"
+				? `${rule.metric} avg ${rule.operator ?? ">"} ${rule.value ?? 0} over ${window}s`
+				: `${rule.metric} ${rule.operator ?? ">"} ${rule.value ?? 0} for ${window}s`,
+		);
 	} else {
 		parts.push((rule.events ?? []).join(", "));
 	}
-	if (rule.threshold > 1) {
+	if (rule.mode === "absence") {
+		parts.push(`< ${rule.threshold} in ${rule.windowSeconds ?? 60}s`);
+	} else if (rule.threshold > 1) {
 		parts.push(`${rule.threshold} in ${rule.windowSeconds ?? 0}s`);
 	}
 	if (rule.cooldownSeconds) {
@@ -457,8 +737,18 @@ function renderTrigger(rule: AlertRule): string {
 	return parts.filter(Boolean).join(" · ");
 }
 
+function renderNotify(rule: AlertRule, channels: AlertChannel[]): string {
+	const fallback = rule.channels?.length
+		? channelNames(rule.channels, channels)
+		: "all channels";
+	const routes = rule.routes?.length ?? 0;
+	if (routes === 0) return fallback;
+	return `${routes} ${routes === 1 ? "route" : "routes"}, else ${fallback}`;
+}
+
 function RulesBlock() {
 	const { data, isLoading, error } = useAlertRules();
+	const channelsQuery = useAlertChannels();
 	const updateMutation = useUpdateAlertRule();
 	const deleteMutation = useDeleteAlertRule();
 
@@ -467,6 +757,7 @@ function RulesBlock() {
 	const [ruleToDelete, setRuleToDelete] = useState<AlertRule | null>(null);
 
 	const rules = data?.rules ?? [];
+	const channels = channelsQuery.data?.channels ?? [];
 
 	function openCreate() {
 		setEditingRule(null);
@@ -524,6 +815,7 @@ function RulesBlock() {
 							<TableHead>Type</TableHead>
 							<TableHead>Target</TableHead>
 							<TableHead>Trigger</TableHead>
+							<TableHead>Notify</TableHead>
 							<TableHead>Enabled</TableHead>
 							<TableHead className="text-right">Actions</TableHead>
 						</TableRow>
@@ -541,6 +833,17 @@ function RulesBlock() {
 								<TableCell className="text-xs text-muted-foreground font-mono">
 									{renderTrigger(rule)}
 								</TableCell>
+								<TableCell
+									className="text-xs text-muted-foreground"
+									title={rule.routes
+										?.map((route) => describeRoute(route, channels))
+										.join("\n")}
+								>
+									<span className="font-medium">
+										{rule.severity ?? "warning"}
+									</span>{" "}
+									· {renderNotify(rule, channels)}
+								</TableCell>
 								<TableCell>
 									<Switch
 										checked={rule.enabled}
@@ -615,10 +918,80 @@ function RulesBlock() {
 	);
 }
 
+function formatIncidentDuration(seconds: number): string {
+	if (seconds < 60) return `${seconds}s`;
+	if (seconds < 3600) return `${Math.floor(seconds / 60)}m`;
+	if (seconds < 86400) {
+		return `${Math.floor(seconds / 3600)}h ${Math.floor((seconds % 3600) / 60)}m`;
+	}
+	return `${Math.floor(seconds / 86400)}d ${Math.floor((seconds % 86400) / 3600)}h`;
+}
+
+function IncidentState({ entry }: { entry: AlertHistoryEntry }) {
+	const firing = entry.state === "firing";
+	const marks = [
+		entry.acknowledgedAt &&
+			`acknowledged${entry.acknowledgedBy ? ` by ${entry.acknowledgedBy}` : ""}`,
+		firing && entry.silenced && "silenced",
+	].filter(Boolean);
+	const detail = firing
+		? undefined
+		: [
+				entry.resolveReason,
+				entry.durationSeconds !== undefined &&
+					`lasted ${formatIncidentDuration(entry.durationSeconds)}`,
+			]
+				.filter(Boolean)
+				.join(", ");
+	return (
+		<span
+			title={detail || undefined}
+			className={`inline-flex items-center gap-1.5 text-xs whitespace-nowrap ${
+				firing
+					? "text-red-600 dark:text-red-400"
+					: "text-muted-foreground"
+			}`}
+		>
+			<span
+				className={`size-1.5 rounded-full ${firing ? "bg-red-500" : "bg-green-500"}`}
+			/>
+			{firing ? "firing" : "resolved"}
+			{!firing && entry.durationSeconds !== undefined && (
+				<span>({formatIncidentDuration(entry.durationSeconds)})</span>
+			)}
+			{marks.length > 0 && (
+				<span className="text-muted-foreground/70">· {marks.join(", ")}</span>
+			)}
+		</span>
+	);
+}
+
 function DeliveryStatus({ entry }: { entry: AlertHistoryEntry }) {
 	if (!entry.delivery) {
 		return <span className="text-xs text-muted-foreground">—</span>;
 	}
+	if (entry.delivery.status === "suppressed-by-silence") {
+		return (
+			<span
+				title={entry.suppressedBy}
+				className="inline-flex items-center gap-1.5 text-xs text-muted-foreground"
+			>
+				<span className="size-1.5 rounded-full bg-muted-foreground/50" />
+				suppressed
+			</span>
+		);
+	}
+	if (entry.delivery.status === "retrying") {
+		return (
+			<span
+				title={entry.delivery.error}
+				className="inline-flex items-center gap-1.5 text-xs text-amber-600 dark:text-amber-400"
+			>
+				<span className="size-1.5 rounded-full bg-amber-500" />
+				retrying
+			</span>
+		);
+	}
 	const ok = entry.delivery.status === "ok";
 	const detail = ok
 		? undefined
@@ -646,17 +1019,55 @@ function DeliveryStatus({ entry }: { entry: AlertHistoryEntry }) {
 }
 
 function HistoryBlock() {
-	const { data, isLoading, error } = useAlertHistory(HISTORY_LIMIT);
+	const [rule, setRule] = useState(ANY);
+	const [state, setState] = useState(ANY);
+	const [delivery, setDelivery] = useState(ANY);
+	const filters: Omit<AlertHistoryParams, "cursor" | "limit"> = {
+		rule: rule === ANY ? undefined : rule,
+		state: state === ANY ? undefined : (state as AlertHistoryParams["state"]),
+		delivery: delivery === ANY ? undefined : delivery,
+	};
+	const filtered = rule !== ANY || state !== ANY || delivery !== ANY;
+
+	const rulesQuery = useAlertRules();
+	const {
+		data,
+		isLoading,
+		error,
+		hasNextPage,
+		fetchNextPage,
+		isFetchingNextPage,
+	} = useAlertHistory(filters, HISTORY_PAGE_SIZE);
 	const clearMutation = useClearAlertHistory();
+	const ackMutation = useAcknowledgeAlert();
+	const silenceMutation = useSilenceAlert();
 	const [isClearOpen, setIsClearOpen] = useState(false);
 
-	const alerts = data?.alerts ?? [];
+	const alerts = data?.pages.flatMap((page) => page.alerts) ?? [];
 
 	function handleClear() {
 		clearMutation.mutate(undefined, showResultToast);
 		setIsClearOpen(false);
 	}
 
+	function handleAcknowledge(entry: AlertHistoryEntry) {
+		ackMutation.mutate(entry.id, {
+			onSuccess: () => toast.success(`Acknowledged "${entry.ruleName}"`),
+			onError: (error) => toast.error(error.message),
+		});
+	}
+
+	function handleSilence(entry: AlertHistoryEntry) {
+		silenceMutation.mutate(
+			{ id: entry.id, durationSeconds: 0 },
+			{
+				onSuccess: () =>
+					toast.success(`Silenced "${entry.ruleName}" until it resolves`),
+				onError: (error) => toast.error(error.message),
+			},
+		);
+	}
+
 	return (
 		<div className="space-y-3">
 			<div className="flex items-center justify-between">
@@ -673,6 +1084,45 @@ function HistoryBlock() {
 				)}
 			</div>
 
+			<div className="flex flex-wrap gap-2">
+				<Select value={rule} onValueChange={setRule}>
+					<SelectTrigger className="h-8 w-44" aria-label="Rule">
+						<SelectValue />
+					</SelectTrigger>
+					<SelectContent>
+						<SelectItem value={ANY}>All rules</SelectItem>
+						{(rulesQuery.data?.rules ?? []).map((r) => (
+							<SelectItem key={r.id} value={r.id}>
+								{r.name}
+							</SelectItem>
+						))}
+					</SelectContent>
+				</Select>
+				<Select value={state} onValueChange={setState}>
+					<SelectTrigger className="h-8 w-32" aria-label="State">
+						<SelectValue />
+					</SelectTrigger>
+					<SelectContent>
+						<SelectItem value={ANY}>Any state</SelectItem>
+						<SelectItem value="firing">Firing</SelectItem>
+						<SelectItem value="resolved">Resolved</SelectItem>
+					</SelectContent>
+				</Select>
+				<Select value={delivery} onValueChange={setDelivery}>
+					<SelectTrigger className="h-8 w-36" aria-label="Delivery">
+						<SelectValue />
+					</SelectTrigger>
+					<SelectContent>
+						<SelectItem value={ANY}>Any delivery</SelectItem>
+						{DELIVERY_FILTERS.map((f) => (
+							<SelectItem key={f.value} value={f.value}>
+								{f.label}
+							</SelectItem>
+						))}
+					</SelectContent>
+				</Select>
+			</div>
+
 			{isLoading && <Spinner className="size-4" />}
 			{error && (
 				<p className="text-sm text-destructive">
@@ -681,7 +1131,9 @@ function HistoryBlock() {
 			)}
 
 			{!isLoading && !error && alerts.length === 0 && (
-				<p className="text-sm text-muted-foreground">No alerts fired yet.</p>
+				<p className="text-sm text-muted-foreground">
+					{filtered ? "No alerts match these filters." : "No alerts fired yet."}
+				</p>
 			)}
 
 			{alerts.length > 0 && (
@@ -690,10 +1142,13 @@ function HistoryBlock() {
 						<TableHeader>
 							<TableRow>
 								<TableHead>Time</TableHead>
+								<TableHead>State</TableHead>
 								<TableHead>Rule</TableHead>
 								<TableHead>Container</TableHead>
 								<TableHead>Reason</TableHead>
+								<TableHead>Channels</TableHead>
 								<TableHead>Delivery</TableHead>
+								<TableHead />
 							</TableRow>
 						</TableHeader>
 						<TableBody>
@@ -702,8 +1157,16 @@ function HistoryBlock() {
 									<TableCell className="text-xs text-muted-foreground whitespace-nowrap">
 										{new Date(entry.firedAt).toLocaleString()}
 									</TableCell>
+									<TableCell>
+										<IncidentState entry={entry} />
+									</TableCell>
 									<TableCell className="font-medium">
 										{entry.ruleName}
+										{entry.severity && (
+											<span className="ml-1.5 text-xs font-normal text-muted-foreground">
+												{entry.severity}
+											</span>
+										)}
 									</TableCell>
 									<TableCell className="text-xs text-muted-foreground font-mono">
 										{entry.containerName}@{entry.host}
@@ -716,9 +1179,41 @@ function HistoryBlock() {
 											</span>
 										)}
 									</TableCell>
+									<TableCell
+										className="text-xs text-muted-foreground"
+										title={entry.routing?.reason}
+									>
+										{entry.routing ? routingLabel(entry.routing) : "—"}
+									</TableCell>
 									<TableCell>
 										<DeliveryStatus entry={entry} />
 									</TableCell>
+									<TableCell className="text-right whitespace-nowrap">
+										{entry.state === "firing" && (
+											<>
+												{!entry.acknowledgedAt && (
+													<Button
+														variant="ghost"
+														size="sm"
+														disabled={ackMutation.isPending}
+														onClick={() => handleAcknowledge(entry)}
+													>
+														Ack
+													</Button>
+												)}
+												{!entry.silenced && (
+													<Button
+														variant="ghost"
+														size="sm"
+														disabled={silenceMutation.isPending}
+														onClick={() => handleSilence(entry)}
+													>
+														Silence
+													</Button>
+												)}
+											</>
+										)}
+									</TableCell>
 								</TableRow>
 							))}
 						</TableBody>
@@ -726,6 +1221,20 @@ function HistoryBlock() {
 				</div>
 			)}
 
+			{hasNextPage && (
+				<Button
+					variant="outline"
+					size="sm"
+					disabled={isFetchingNextPage}
+					onClick={() => fetchNextPage()}
+				>
+					{isFetchingNextPage && <Spinner className="size-3.5" />}
+					Load older incidents
+				</Button>
+			)}
+
+			<RetentionForm />
+
 			<AlertDialog open={isClearOpen} onOpenChange={setIsClearOpen}>
 				<AlertDialogContent>
 					<AlertDialogHeader>
@@ -748,3 +1257,79 @@ function HistoryBlock() {
 		</div>
 	);
 }
+
+// Edits stay local until saved. A cleared field saves 0, which restores the
+// server default for that bound.
+function RetentionForm() {
+	const { data } = useAlertHistoryRetention();
+	const mutation = useUpdateAlertHistoryRetention();
+	const [days, setDays] = useState<string>();
+	const [maxEntries, setMaxEntries] = useState<string>();
+
+	if (!data) return null;
+
+	const daysValue = days ?? String(data.retentionDays);
+	const maxValue = maxEntries ?? String(data.maxEntries);
+	const dirty =
+		daysValue !== String(data.retentionDays) ||
+		maxValue !== String(data.maxEntries);
+
+	function handleSave() {
+		mutation.mutate(
+			{
+				retentionDays: Number(daysValue) || 0,
+				maxEntries: Number(maxValue) || 0,
+			},
+			{
+				onSuccess: () => {
+					setDays(undefined);
+					setMaxEntries(undefined);
+					toast.success("Alert history retention updated");
+				},
+				onError: (error) => toast.error(error.message),
+			},
+		);
+	}
+
+	return (
+		<div className="flex flex-wrap items-end gap-3">
+			<div className="space-y-1.5">
+				<Label htmlFor="alert-history-days" className="text-xs">
+					Keep resolved incidents (days)
+				</Label>
+				<Input
+					id="alert-history-days"
+					type="number"
+					min={1}
+					className="h-8 w-28"
+					value={daysValue}
+					onChange={(e) => setDays(e.target.value)}
+				/>
+			</div>
+			<div className="space-y-1.5">
+				<Label htmlFor="alert-history-max" className="text-xs">
+					At most (incidents)
+				</Label>
+				<Input
+					id="alert-history-max"
+					type="number"
+					min={100}
+					className="h-8 w-32"
+					value={maxValue}
+					onChange={(e) => setMaxEntries(e.target.value)}
+				/>
+			</div>
+			<Button
+				size="sm"
+				variant="outline"
+				disabled={!dirty || mutation.isPending}
+				onClick={handleSave}
+			>
+				Save
+			</Button>
+			<p className="w-full text-xs text-muted-foreground">
+				Firing incidents are never pruned.
+			</p>
+		</div>
+	);
+}
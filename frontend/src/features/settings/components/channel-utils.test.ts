@@ -4,7 +4,9 @@ import type { AlertChannel } from "../api/get-alert-channels";
 import {
 	buildChannelPayload,
 	channelDestination,
+	describeRoute,
 	EMPTY_CHANNEL_DRAFT,
+	routingLabel,
 } from "./channel-utils";
 
 describe("buildChannelPayload", () => {
@@ -76,6 +78,84 @@ describe("buildChannelPayload", () => {
 			},
 		});
 	});
+
+	it("builds smtp, pagerduty, and matrix payloads", () => {
+		expect(
+			buildChannelPayload({
+				...EMPTY_CHANNEL_DRAFT,
+				type: "smtp",
+				url: "smtp://mail.example.com:587",
+				from: "LogDeck <logdeck@example.com>",
+				target: "ops@example.com, boss@example.com",
+			}),
+		).toEqual({
+			payload: {
+				type: "smtp",
+				enabled: true,
+				url: "smtp://mail.example.com:587",
+				from: "LogDeck <logdeck@example.com>",
+				target: "ops@example.com, boss@example.com",
+			},
+		});
+		expect(
+			buildChannelPayload({
+				...EMPTY_CHANNEL_DRAFT,
+				type: "smtp",
+				url: "smtp://mail.example.com",
+				from: "logdeck@example.com",
+				target: "ops@example.com, not-an-address",
+			}),
+		).toEqual({ error: expect.stringMatching(/recipients/i) });
+
+		expect(
+			buildChannelPayload({
+				...EMPTY_CHANNEL_DRAFT,
+				type: "pagerduty",
+				token: "routing-key",
+			}),
+		).toEqual({
+			payload: { type: "pagerduty", enabled: true, token: "routing-key" },
+		});
+
+		expect(
+			buildChannelPayload({
+				...EMPTY_CHANNEL_DRAFT,
+				type: "matrix",
+				url: "https://matrix.example.org",
+				token: "syt_x",
+				target: "#ops:example.org",
+			}),
+		).toEqual({ error: expect.stringMatching(/room id/i) });
+	});
+
+	it("passes templates through and rejects a title the type cannot carry", () => {
+		expect(
+			buildChannelPayload({
+				...EMPTY_CHANNEL_DRAFT,
+				type: "ntfy",
+				url: "https://ntfy.sh/pager",
+				titleTemplate: " {{.ContainerName}} ",
+				bodyTemplate: "{{.Reason}}\n",
+			}),
+		).toEqual({
+			payload: {
+				type: "ntfy",
+				enabled: true,
+				url: "https://ntfy.sh/pager",
+				titleTemplate: "{{.ContainerName}}",
+				bodyTemplate: "{{.Reason}}\n",
+			},
+		});
+
+		expect(
+			buildChannelPayload({
+				...EMPTY_CHANNEL_DRAFT,
+				type: "webhook",
+				url: "https://example.com/hook",
+				titleTemplate: "{{.RuleName}}",
+			}),
+		).toEqual({ error: expect.stringMatching(/title template/i) });
+	});
 });
 
 describe("channelDestination", () => {
@@ -99,3 +179,40 @@ describe("channelDestination", () => {
 		expect(channelDestination(ch)).toBe("https://example.com/hook");
 	});
 });
+
+describe("describeRoute", () => {
+	const channels: AlertChannel[] = [
+		{ id: "c1", type: "ntfy", name: "Low priority", enabled: true },
+		{ id: "c2", type: "pagerduty", enabled: true },
+	];
+
+	it("names the route's targeting and channels", () => {
+		expect(
+			describeRoute(
+				{ projects: ["staging"], hosts: ["prod"], channels: ["c1", "c2"] },
+				channels,
+			),
+		).toBe("hosts: prod; projects: staging → Low priority, PagerDuty");
+	});
+
+	it("shows a catch-all route and an unknown channel id", () => {
+		expect(describeRoute({ channels: ["gone"] }, channels)).toBe(
+			"all containers → gone",
+		);
+	});
+});
+
+describe("routingLabel", () => {
+	it("lists the routed channels by name", () => {
+		expect(
+			routingLabel({
+				channels: [
+					{ id: "c1", type: "ntfy", name: "Low priority" },
+					{ id: "c2", type: "pagerduty" },
+				],
+				reason: "rule channels",
+			}),
+		).toBe("Low priority, PagerDuty");
+		expect(routingLabel({ channels: [], reason: "route 1" })).toBe("none");
+	});
+});
@@ -1,11 +1,17 @@
 import type { AlertChannelPayload } from "../api/create-alert-channel";
 import type { AlertChannel, AlertChannelType } from "../api/get-alert-channels";
+import type { AlertRouting } from "../api/get-alert-history";
+import type { AlertRoute } from "../api/get-alert-rules";
 
 export const CHANNEL_TYPES: AlertChannelType[] = [
 	"webhook",
 	"ntfy",
 	"gotify",
 	"telegram",
+	"smtp",
+	"pagerduty",
+	"matrix",
+	"teams",
 ];
 
 const TYPE_LABELS: Record<AlertChannelType, string> = {
@@ -13,6 +19,10 @@ const TYPE_LABELS: Record<AlertChannelType, string> = {
 	ntfy: "ntfy",
 	gotify: "Gotify",
 	telegram: "Telegram",
+	smtp: "Email",
+	pagerduty: "PagerDuty",
+	matrix: "Matrix",
+	teams: "Teams",
 };
 
 export function channelTypeLabel(type: AlertChannelType): string {
@@ -20,8 +30,15 @@ export function channelTypeLabel(type: AlertChannelType): string {
 }
 
 export function channelDestination(channel: AlertChannel): string {
-	if (channel.type === "telegram") {
-		return channel.target ? `chat ${channel.target}` : "—";
+	switch (channel.type) {
+		case "telegram":
+			return channel.target ? `chat ${channel.target}` : "—";
+		case "smtp":
+			return channel.target || "—";
+		case "matrix":
+			return channel.target ? `room ${channel.target}` : "—";
+		case "pagerduty":
+			return channel.url || "Events API";
 	}
 	return channel.url || "—";
 }
@@ -32,6 +49,10 @@ export interface ChannelDraft {
 	url: string;
 	token: string;
 	target: string;
+	username: string;
+	from: string;
+	titleTemplate: string;
+	bodyTemplate: string;
 }
 
 export const EMPTY_CHANNEL_DRAFT: ChannelDraft = {
@@ -40,8 +61,36 @@ export const EMPTY_CHANNEL_DRAFT: ChannelDraft = {
 	url: "",
 	token: "",
 	target: "",
+	username: "",
+	from: "",
+	titleTemplate: "",
+	bodyTemplate: "",
 };
 
+// Only these types carry a title separate from the message: the ntfy and
+// Gotify title, the email subject, and the Teams card heading.
+export function channelHasTitle(type: AlertChannelType): boolean {
+	return (
+		type === "ntfy" || type === "gotify" || type === "smtp" || type === "teams"
+	);
+}
+
+const EMAIL_PATTERN = /^[^\s@<>]+@[^\s@<>]+$/;
+
+// Accepts "a@example.com" or "Name <a@example.com>".
+function isEmailAddress(value: string): boolean {
+	const match = /<([^>]+)>\s*$/.exec(value);
+	return EMAIL_PATTERN.test(match ? match[1] : value);
+}
+
+// Example templates offered as placeholders; a webhook body template renders
+// the whole JSON body, so its example is a Slack blocks payload.
+export function bodyTemplatePlaceholder(type: AlertChannelType): string {
+	return type === "webhook"
+		? '{"blocks":[{"type":"section","text":{"type":"mrkdwn","text":{{json .Text}}}}]}'
+		: "{{.RuleName}} on {{.Host}}/{{.ContainerName}}: {{.Reason}}";
+}
+
 function isHttpUrl(value: string): boolean {
 	try {
 		const parsed = new URL(value);
@@ -59,6 +108,10 @@ export function buildChannelPayload(
 	const url = draft.url.trim();
 	const token = draft.token.trim();
 	const target = draft.target.trim();
+	const username = draft.username.trim();
+	const from = draft.from.trim();
+	const titleTemplate = draft.titleTemplate.trim();
+	const bodyTemplate = draft.bodyTemplate.trim() ? draft.bodyTemplate : "";
 
 	const payload: AlertChannelPayload = {
 		type: draft.type,
@@ -68,7 +121,8 @@ export function buildChannelPayload(
 
 	switch (draft.type) {
 		case "webhook":
-		case "ntfy": {
+		case "ntfy":
+		case "teams": {
 			if (!url) return { error: "A destination URL is required" };
 			if (!isHttpUrl(url))
 				return { error: "URL must be a valid http or https URL" };
@@ -91,7 +145,94 @@ export function buildChannelPayload(
 			payload.target = target;
 			break;
 		}
+		case "smtp": {
+			if (!/^smtp:\/\/[^/\s]+\/?$/.test(url))
+				return { error: "The server must be smtp://host or smtp://host:port" };
+			if (!isEmailAddress(from))
+				return { error: "A valid sender address is required" };
+			const recipients = target.split(",").map((r) => r.trim());
+			if (!target || !recipients.every(isEmailAddress))
+				return { error: "Recipients must be comma-separated email addresses" };
+			if (username && !token)
+				return { error: "A password is required with a username" };
+			payload.url = url;
+			payload.from = from;
+			payload.target = target;
+			if (username) payload.username = username;
+			if (token) payload.token = token;
+			break;
+		}
+		case "pagerduty": {
+			if (!token) return { error: "A PagerDuty routing key is required" };
+			if (url && !isHttpUrl(url))
+				return { error: "URL must be a valid http or https URL" };
+			payload.token = token;
+			if (url) payload.url = url;
+			break;
+		}
+		case "matrix": {
+			if (!url) return { error: "The Matrix homeserver URL is required" };
+			if (!isHttpUrl(url))
+				return { error: "URL must be a valid http or https URL" };
+			if (!token) return { error: "A Matrix access token is required" };
+			if (!target.startsWith("!") || !target.includes(":"))
+				return { error: "The room must be a room ID like !abc:example.org" };
+			payload.url = url;
+			payload.token = token;
+			payload.target = target;
+			break;
+		}
+	}
+
+	if (titleTemplate) {
+		if (!channelHasTitle(draft.type))
+			return {
+				error: "A title template only applies to ntfy, Gotify, email, and Teams",
+			};
+		payload.titleTemplate = titleTemplate;
 	}
+	if (bodyTemplate) payload.bodyTemplate = bodyTemplate;
 
 	return { payload };
 }
+
+export function channelName(channel: {
+	name?: string;
+	type: AlertChannelType;
+}): string {
+	return channel.name || TYPE_LABELS[channel.type];
+}
+
+// Summarizes a rule route, e.g. "projects: staging → Low priority". Channel
+// IDs that no longer exist are shown as-is.
+export function describeRoute(
+	route: AlertRoute,
+	channels: AlertChannel[],
+): string {
+	const match = [
+		route.hosts?.length ? `hosts: ${route.hosts.join(", ")}` : "",
+		route.projects?.length ? `projects: ${route.projects.join(", ")}` : "",
+		route.containers?.length
+			? `containers: ${route.containers.join(", ")}`
+			: "",
+	]
+		.filter(Boolean)
+		.join("; ");
+	return `${match || "all containers"} → ${channelNames(route.channels, channels)}`;
+}
+
+export function channelNames(ids: string[], channels: AlertChannel[]): string {
+	return ids
+		.map((id) => {
+			const channel = channels.find((c) => c.id === id);
+			return channel ? channelName(channel) : id;
+		})
+		.join(", ");
+}
+
+// An incident routed by a route whose channels were all disabled was
+// delivered nowhere.
+export function routingLabel(routing: AlertRouting): string {
+	if (routing.channels.length === 0) return "none";
+	return routing.channels.map(channelName).join(", ");
+}
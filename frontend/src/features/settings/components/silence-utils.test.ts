@@ -0,0 +1,71 @@
+import { describe, expect, it } from "vitest";
+
+import type { AlertRule } from "../api/get-alert-rules";
+import type { AlertSilence } from "../api/get-alert-silences";
+import {
+	describeSilenceTargets,
+	formatWindowDuration,
+	ruleIdsForNames,
+	silenceState,
+} from "./silence-utils";
+
+const rules = [
+	{ id: "r1", name: "Error spike" },
+	{ id: "r2", name: "OOM" },
+] as AlertRule[];
+
+describe("describeSilenceTargets", () => {
+	it("names each dimension and resolves rule names", () => {
+		expect(
+			describeSilenceTargets(
+				{ hosts: ["prod"], projects: ["shop"], rules: ["r1", "gone"] },
+				rules,
+			),
+		).toBe("hosts: prod; projects: shop; rules: Error spike, gone");
+	});
+
+	it("describes an unrestricted silence", () => {
+		expect(describeSilenceTargets({ containers: [] }, rules)).toBe(
+			"all alerts",
+		);
+	});
+});
+
+describe("silenceState", () => {
+	const now = new Date("2026-07-10T12:00:00Z");
+	const silence = (endsAt: string, active = false) =>
+		({ startsAt: "2026-07-10T11:00:00Z", endsAt, active }) as AlertSilence;
+
+	it("trusts the server for active silences", () => {
+		expect(silenceState(silence("2026-07-10T13:00:00Z", true), now)).toBe(
+			"active",
+		);
+	});
+
+	it("tells expired from scheduled silences", () => {
+		expect(silenceState(silence("2026-07-10T11:30:00Z"), now)).toBe("expired");
+		expect(silenceState(silence("2026-07-11T00:00:00Z"), now)).toBe("pending");
+	});
+});
+
+describe("ruleIdsForNames", () => {
+	it("maps names to IDs", () => {
+		expect(ruleIdsForNames(["OOM", "Error spike"], rules)).toEqual({
+			ids: ["r2", "r1"],
+		});
+	});
+
+	it("rejects an unknown name", () => {
+		expect(ruleIdsForNames(["Disk"], rules)).toEqual({
+			error: expect.stringMatching(/Disk/),
+		});
+	});
+});
+
+describe("formatWindowDuration", () => {
+	it("renders hours and minutes", () => {
+		expect(formatWindowDuration(1800)).toBe("30m");
+		expect(formatWindowDuration(7200)).toBe("2h");
+		expect(formatWindowDuration(9000)).toBe("2h 30m");
+	});
+});
@@ -0,0 +1,69 @@
+import type { AlertRule } from "../api/get-alert-rules";
+import type { AlertSilence } from "../api/get-alert-silences";
+
+export interface SilenceTargets {
+	hosts?: string[];
+	containers?: string[];
+	projects?: string[];
+	rules?: string[];
+}
+
+// Preset silence lengths offered in the form, in seconds.
+export const SILENCE_DURATIONS: { label: string; seconds: number }[] = [
+	{ label: "30 minutes", seconds: 1800 },
+	{ label: "1 hour", seconds: 3600 },
+	{ label: "2 hours", seconds: 7200 },
+	{ label: "4 hours", seconds: 14400 },
+	{ label: "12 hours", seconds: 43200 },
+	{ label: "1 day", seconds: 86400 },
+];
+
+// Summarizes what a silence or maintenance window mutes, naming rules where
+// they are known, e.g. "projects: shop; rules: Error spike".
+export function describeSilenceTargets(
+	targets: SilenceTargets,
+	rules: AlertRule[],
+): string {
+	const ruleNames = targets.rules?.map(
+		(id) => rules.find((rule) => rule.id === id)?.name ?? id,
+	);
+	const parts = [
+		["hosts", targets.hosts],
+		["projects", targets.projects],
+		["containers", targets.containers],
+		["rules", ruleNames],
+	]
+		.filter((part): part is [string, string[]] => Boolean(part[1]?.length))
+		.map(([label, values]) => `${label}: ${values.join(", ")}`);
+	return parts.length > 0 ? parts.join("; ") : "all alerts";
+}
+
+export type SilenceState = "active" | "pending" | "expired";
+
+export function silenceState(silence: AlertSilence, now: Date): SilenceState {
+	if (silence.active) return "active";
+	return new Date(silence.endsAt) <= now ? "expired" : "pending";
+}
+
+// Maps rule names picked in the form back to rule IDs; a name that matches
+// no rule is reported so the form can reject it.
+export function ruleIdsForNames(
+	names: string[],
+	rules: AlertRule[],
+): { ids: string[] } | { error: string } {
+	const ids: string[] = [];
+	for (const name of names) {
+		const rule = rules.find((r) => r.name === name);
+		if (!rule) return { error: `No alert rule is named "${name}"` };
+		ids.push(rule.id);
+	}
+	return { ids };
+}
+
+// Renders a maintenance window's length, e.g. "30m" or "2h 30m".
+export function formatWindowDuration(seconds: number): string {
+	const hours = Math.floor(seconds / 3600);
+	const minutes = Math.floor((seconds % 3600) / 60);
+	if (hours === 0) return `${minutes}m`;
+	return minutes === 0 ? `${hours}h` : `${hours}h ${minutes}m`;
+}
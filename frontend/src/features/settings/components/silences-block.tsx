@@ -0,0 +1,552 @@
+import { useState } from "react";
+import { toast } from "sonner";
+
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import {
+	Select,
+	SelectContent,
+	SelectItem,
+	SelectTrigger,
+	SelectValue,
+} from "@/components/ui/select";
+import { Spinner } from "@/components/ui/spinner";
+import { Switch } from "@/components/ui/switch";
+import {
+	Table,
+	TableBody,
+	TableCell,
+	TableHead,
+	TableHeader,
+	TableRow,
+} from "@/components/ui/table";
+
+import type { AlertRule } from "../api/get-alert-rules";
+import type { MaintenanceWindow } from "../api/get-maintenance-windows";
+import {
+	useAlertRules,
+	useAlertSilences,
+	useCreateAlertSilence,
+	useCreateMaintenanceWindow,
+	useDeleteAlertSilence,
+	useDeleteMaintenanceWindow,
+	useMaintenanceWindows,
+	useUpdateMaintenanceWindow,
+} from "../hooks/use-alerts";
+import { useTargetOptions } from "../hooks/use-target-options";
+import { MultiCombobox } from "./multi-combobox";
+import { showResultToast } from "./mutation-toast";
+import {
+	describeSilenceTargets,
+	formatWindowDuration,
+	ruleIdsForNames,
+	SILENCE_DURATIONS,
+	type SilenceTargets,
+	silenceState,
+} from "./silence-utils";
+
+interface TargetDraft {
+	hosts: string[];
+	containers: string[];
+	projects: string[];
+	ruleNames: string[];
+}
+
+const EMPTY_TARGETS: TargetDraft = {
+	hosts: [],
+	containers: [],
+	projects: [],
+	ruleNames: [],
+};
+
+// Resolves a draft's rule names to IDs, reporting the first unknown one.
+function buildTargets(
+	draft: TargetDraft,
+	rules: AlertRule[],
+): { targets: SilenceTargets } | { error: string } {
+	const result = ruleIdsForNames(draft.ruleNames, rules);
+	if ("error" in result) return result;
+	return {
+		targets: {
+			hosts: draft.hosts,
+			containers: draft.containers,
+			projects: draft.projects,
+			rules: result.ids,
+		},
+	};
+}
+
+function TargetFields({
+	idPrefix,
+	draft,
+	onChange,
+	rules,
+}: {
+	idPrefix: string;
+	draft: TargetDraft;
+	onChange: (draft: TargetDraft) => void;
+	rules: AlertRule[];
+}) {
+	const options = useTargetOptions();
+	const fields = [
+		["projects", "Compose projects", options.projects],
+		["containers", "Containers", options.containers],
+		["hosts", "Hosts", options.hosts],
+		["ruleNames", "Rules", rules.map((rule) => rule.name)],
+	] as const;
+
+	return (
+		<div className="grid gap-3 sm:grid-cols-2">
+			{fields.map(([key, label, values]) => (
+				<div key={key} className="space-y-1.5">
+					<Label htmlFor={`${idPrefix}-${key}`}>{label}</Label>
+					<MultiCombobox
+						id={`${idPrefix}-${key}`}
+						placeholder="Any"
+						options={values}
+						selected={draft[key]}
+						onChange={(selected) => onChange({ ...draft, [key]: selected })}
+					/>
+				</div>
+			))}
+		</div>
+	);
+}
+
+function AddSilenceForm({
+	rules,
+	onDone,
+}: {
+	rules: AlertRule[];
+	onDone: () => void;
+}) {
+	const createMutation = useCreateAlertSilence();
+	const [targets, setTargets] = useState<TargetDraft>(EMPTY_TARGETS);
+	const [duration, setDuration] = useState(String(SILENCE_DURATIONS[1].seconds));
+	const [comment, setComment] = useState("");
+
+	function handleAdd() {
+		const result = buildTargets(targets, rules);
+		if ("error" in result) {
+			toast.error(result.error);
+			return;
+		}
+		createMutation.mutate(
+			{
+				...result.targets,
+				durationSeconds: Number(duration),
+				comment: comment.trim() || undefined,
+			},
+			{
+				onSuccess: () => {
+					toast.success("Silence created");
+					onDone();
+				},
+				onError: (err) => toast.error(err.message),
+			},
+		);
+	}
+
+	return (
+		<div className="space-y-3 border rounded-md p-3">
+			<TargetFields
+				idPrefix="silence"
+				draft={targets}
+				onChange={setTargets}
+				rules={rules}
+			/>
+			<div className="flex flex-wrap items-end gap-3">
+				<div className="space-y-1.5">
+					<Label htmlFor="silence-duration">Duration</Label>
+					<Select value={duration} onValueChange={setDuration}>
+						<SelectTrigger id="silence-duration" className="h-8 w-32">
+							<SelectValue />
+						</SelectTrigger>
+						<SelectContent>
+							{SILENCE_DURATIONS.map((option) => (
+								<SelectItem key={option.seconds} value={String(option.seconds)}>
+									{option.label}
+								</SelectItem>
+							))}
+						</SelectContent>
+					</Select>
+				</div>
+				<div className="space-y-1.5 flex-1 min-w-40">
+					<Label htmlFor="silence-comment">
+						Comment{" "}
+						<span className="font-normal text-muted-foreground">
+							(optional)
+						</span>
+					</Label>
+					<Input
+						id="silence-comment"
+						value={comment}
+						onChange={(e) => setComment(e.target.value)}
+						placeholder="Deploying v2.3"
+						className="h-8"
+						maxLength={256}
+					/>
+				</div>
+			</div>
+			<div className="flex gap-1">
+				<Button
+					size="sm"
+					disabled={createMutation.isPending}
+					onClick={handleAdd}
+				>
+					{createMutation.isPending ? (
+						<>
+							<Spinner className="size-3" />
+							Adding...
+						</>
+					) : (
+						"Add"
+					)}
+				</Button>
+				<Button size="sm" variant="ghost" onClick={onDone}>
+					Cancel
+				</Button>
+			</div>
+		</div>
+	);
+}
+
+function AddWindowForm({
+	rules,
+	onDone,
+}: {
+	rules: AlertRule[];
+	onDone: () => void;
+}) {
+	const createMutation = useCreateMaintenanceWindow();
+	const [targets, setTargets] = useState<TargetDraft>(EMPTY_TARGETS);
+	const [name, setName] = useState("");
+	const [schedule, setSchedule] = useState("");
+	const [minutes, setMinutes] = useState("30");
+	const [timezone, setTimezone] = useState(
+		() => Intl.DateTimeFormat().resolvedOptions().timeZone,
+	);
+
+	function handleAdd() {
+		const result = buildTargets(targets, rules);
+		if ("error" in result) {
+			toast.error(result.error);
+			return;
+		}
+		createMutation.mutate(
+			{
+				...result.targets,
+				name: name.trim(),
+				enabled: true,
+				schedule: schedule.trim(),
+				durationSeconds: Math.round(Number(minutes) * 60),
+				timezone: timezone.trim() || undefined,
+			},
+			{
+				onSuccess: (created) => {
+					toast.success(`Maintenance window "${created.name}" added`);
+					onDone();
+				},
+				onError: (err) => toast.error(err.message),
+			},
+		);
+	}
+
+	return (
+		<div className="space-y-3 border rounded-md p-3">
+			<div className="flex flex-wrap items-end gap-3">
+				<div className="space-y-1.5 flex-1 min-w-40">
+					<Label htmlFor="window-name">Name</Label>
+					<Input
+						id="window-name"
+						value={name}
+						onChange={(e) => setName(e.target.value)}
+						placeholder="Nightly deploy"
+						className="h-8"
+						maxLength={64}
+					/>
+				</div>
+				<div className="space-y-1.5">
+					<Label htmlFor="window-schedule">Schedule (cron)</Label>
+					<Input
+						id="window-schedule"
+						value={schedule}
+						onChange={(e) => setSchedule(e.target.value)}
+						placeholder="0 3 * * 1-5"
+						className="h-8 w-36 font-mono text-xs"
+						maxLength={128}
+					/>
+				</div>
+				<div className="space-y-1.5">
+					<Label htmlFor="window-minutes">Minutes</Label>
+					<Input
+						id="window-minutes"
+						type="number"
+						min={1}
+						value={minutes}
+						onChange={(e) => setMinutes(e.target.value)}
+						className="h-8 w-20"
+					/>
+				</div>
+				<div className="space-y-1.5">
+					<Label htmlFor="window-timezone">Timezone</Label>
+					<Input
+						id="window-timezone"
+						value={timezone}
+						onChange={(e) => setTimezone(e.target.value)}
+						placeholder="UTC"
+						className="h-8 w-40"
+					/>
+				</div>
+			</div>
+			<TargetFields
+				idPrefix="window"
+				draft={targets}
+				onChange={setTargets}
+				rules={rules}
+			/>
+			<div className="flex gap-1">
+				<Button
+					size="sm"
+					disabled={createMutation.isPending}
+					onClick={handleAdd}
+				>
+					{createMutation.isPending ? (
+						<>
+							<Spinner className="size-3" />
+							Adding...
+						</>
+					) : (
+						"Add"
+					)}
+				</Button>
+				<Button size="sm" variant="ghost" onClick={onDone}>
+					Cancel
+				</Button>
+			</div>
+		</div>
+	);
+}
+
+function windowStatus(window: MaintenanceWindow): string {
+	if (window.activeSince) {
+		return `active since ${new Date(window.activeSince).toLocaleString()}`;
+	}
+	if (window.nextStart) {
+		return `next ${new Date(window.nextStart).toLocaleString()}`;
+	}
+	return "—";
+}
+
+export function SilencesBlock() {
+	const silencesQuery = useAlertSilences();
+	const windowsQuery = useMaintenanceWindows();
+	const rulesQuery = useAlertRules();
+	const deleteSilence = useDeleteAlertSilence();
+	const updateWindow = useUpdateMaintenanceWindow();
+	const deleteWindow = useDeleteMaintenanceWindow();
+
+	const [adding, setAdding] = useState<"silence" | "window" | null>(null);
+
+	const silences = silencesQuery.data?.silences ?? [];
+	const windows = windowsQuery.data?.windows ?? [];
+	const rules = rulesQuery.data?.rules ?? [];
+	const now = new Date();
+
+	function handleToggle(window: MaintenanceWindow, enabled: boolean) {
+		const {
+			id,
+			createdAt: _createdAt,
+			active: _active,
+			activeSince: _activeSince,
+			nextStart: _nextStart,
+			...payload
+		} = window;
+		updateWindow.mutate(
+			{ id, window: { ...payload, enabled } },
+			{
+				onSuccess: (updated) => {
+					toast.success(
+						`Maintenance window "${updated.name}" ${
+							updated.enabled ? "enabled" : "disabled"
+						}`,
+					);
+				},
+				onError: (err) => toast.error(err.message),
+			},
+		);
+	}
+
+	const error = silencesQuery.error ?? windowsQuery.error;
+	const isLoading = silencesQuery.isLoading || windowsQuery.isLoading;
+
+	return (
+		<div className="space-y-3">
+			<h3 className="text-sm font-medium">Silences &amp; maintenance</h3>
+
+			{isLoading && <Spinner className="size-4" />}
+			{error && (
+				<p className="text-sm text-destructive">
+					Failed to load silences: {error.message}
+				</p>
+			)}
+
+			{!isLoading &&
+				!error &&
+				silences.length === 0 &&
+				windows.length === 0 &&
+				adding === null && (
+					<p className="text-sm text-muted-foreground">
+						Nothing is silenced. Matching alerts are still recorded in history
+						while silenced, but not notified.
+					</p>
+				)}
+
+			{silences.length > 0 && (
+				<Table>
+					<TableHeader>
+						<TableRow>
+							<TableHead>State</TableHead>
+							<TableHead>Target</TableHead>
+							<TableHead>Ends</TableHead>
+							<TableHead>Comment</TableHead>
+							<TableHead className="text-right">Actions</TableHead>
+						</TableRow>
+					</TableHeader>
+					<TableBody>
+						{silences.map((silence) => {
+							const state = silenceState(silence, now);
+							return (
+								<TableRow key={silence.id}>
+									<TableCell
+										className={`text-xs ${
+											state === "active"
+												? "text-amber-600 dark:text-amber-400"
+												: "text-muted-foreground"
+										}`}
+										title={`starts ${new Date(silence.startsAt).toLocaleString()}`}
+									>
+										{state}
+									</TableCell>
+									<TableCell className="text-xs text-muted-foreground">
+										{describeSilenceTargets(silence, rules)}
+									</TableCell>
+									<TableCell className="text-xs text-muted-foreground whitespace-nowrap">
+										{new Date(silence.endsAt).toLocaleString()}
+									</TableCell>
+									<TableCell className="text-xs text-muted-foreground">
+										{silence.comment || "—"}
+										{silence.createdBy && (
+											<span className="ml-1.5 text-muted-foreground/70">
+												by {silence.createdBy}
+											</span>
+										)}
+									</TableCell>
+									<TableCell className="text-right">
+										<Button
+											variant="ghost"
+											size="sm"
+											disabled={deleteSilence.isPending}
+											onClick={() =>
+												deleteSilence.mutate(silence.id, showResultToast)
+											}
+											className="text-destructive hover:text-destructive"
+										>
+											Delete
+										</Button>
+									</TableCell>
+								</TableRow>
+							);
+						})}
+					</TableBody>
+				</Table>
+			)}
+
+			{windows.length > 0 && (
+				<Table>
+					<TableHeader>
+						<TableRow>
+							<TableHead>Window</TableHead>
+							<TableHead>Schedule</TableHead>
+							<TableHead>Target</TableHead>
+							<TableHead>Status</TableHead>
+							<TableHead>Enabled</TableHead>
+							<TableHead className="text-right">Actions</TableHead>
+						</TableRow>
+					</TableHeader>
+					<TableBody>
+						{windows.map((window) => (
+							<TableRow key={window.id}>
+								<TableCell className="font-medium">{window.name}</TableCell>
+								<TableCell className="text-xs text-muted-foreground whitespace-nowrap">
+									<span className="font-mono">{window.schedule}</span> for{" "}
+									{formatWindowDuration(window.durationSeconds)}
+									<span className="ml-1.5 text-muted-foreground/70">
+										{window.timezone || "UTC"}
+									</span>
+								</TableCell>
+								<TableCell className="text-xs text-muted-foreground">
+									{describeSilenceTargets(window, rules)}
+								</TableCell>
+								<TableCell
+									className={`text-xs whitespace-nowrap ${
+										window.active
+											? "text-amber-600 dark:text-amber-400"
+											: "text-muted-foreground"
+									}`}
+								>
+									{window.enabled ? windowStatus(window) : "—"}
+								</TableCell>
+								<TableCell>
+									<Switch
+										checked={window.enabled}
+										onCheckedChange={(checked) => handleToggle(window, checked)}
+										disabled={updateWindow.isPending}
+										aria-label={`Toggle maintenance window ${window.name}`}
+									/>
+								</TableCell>
+								<TableCell className="text-right">
+									<Button
+										variant="ghost"
+										size="sm"
+										disabled={deleteWindow.isPending}
+										onClick={() => deleteWindow.mutate(window.id, showResultToast)}
+										className="text-destructive hover:text-destructive"
+									>
+										Delete
+									</Button>
+								</TableCell>
+							</TableRow>
+						))}
+					</TableBody>
+				</Table>
+			)}
+
+			{adding === "silence" && (
+				<AddSilenceForm rules={rules} onDone={() => setAdding(null)} />
+			)}
+			{adding === "window" && (
+				<AddWindowForm rules={rules} onDone={() => setAdding(null)} />
+			)}
+			{adding === null && (
+				<div className="flex gap-2">
+					<Button
+						variant="outline"
+						size="sm"
+						onClick={() => setAdding("silence")}
+					>
+						Add silence
+					</Button>
+					<Button
+						variant="outline"
+						size="sm"
+						onClick={() => setAdding("window")}
+					>
+						Add maintenance window
+					</Button>
+				</div>
+			)}
+		</div>
+	);
+}
@@ -1,5 +1,11 @@
-import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import {
+	useInfiniteQuery,
+	useMutation,
+	useQuery,
+	useQueryClient,
+} from "@tanstack/react-query";
 
+import { acknowledgeAlert } from "../api/acknowledge-alert";
 import { clearAlertHistory } from "../api/clear-alert-history";
 import {
 	type AlertChannelPayload,
@@ -9,18 +15,43 @@ import {
 	type AlertRulePayload,
 	createAlertRule,
 } from "../api/create-alert-rule";
+import {
+	type AlertSilencePayload,
+	createAlertSilence,
+} from "../api/create-alert-silence";
+import {
+	createMaintenanceWindow,
+	type MaintenanceWindowPayload,
+} from "../api/create-maintenance-window";
 import { deleteAlertChannel } from "../api/delete-alert-channel";
 import { deleteAlertRule } from "../api/delete-alert-rule";
+import { deleteAlertSilence } from "../api/delete-alert-silence";
+import { deleteMaintenanceWindow } from "../api/delete-maintenance-window";
 import { getAlertChannels } from "../api/get-alert-channels";
-import { getAlertHistory } from "../api/get-alert-history";
+import { getAlertDelivery } from "../api/get-alert-delivery";
+import {
+	type AlertHistoryParams,
+	getAlertHistory,
+} from "../api/get-alert-history";
+import { getAlertHistoryRetention } from "../api/get-alert-history-retention";
 import { getAlertRules } from "../api/get-alert-rules";
+import { getAlertSilences } from "../api/get-alert-silences";
+import { getMaintenanceWindows } from "../api/get-maintenance-windows";
+import { silenceAlert } from "../api/silence-alert";
 import { testAlertChannel } from "../api/test-alert-channel";
 import { updateAlertChannel } from "../api/update-alert-channel";
+import { updateAlertDelivery } from "../api/update-alert-delivery";
+import { updateAlertHistoryRetention } from "../api/update-alert-history-retention";
 import { updateAlertRule } from "../api/update-alert-rule";
+import { updateMaintenanceWindow } from "../api/update-maintenance-window";
 
 const RULES_KEY = ["alerts", "rules"] as const;
 const CHANNELS_KEY = ["alerts", "channels"] as const;
 const HISTORY_KEY = ["alerts", "history"] as const;
+const RETENTION_KEY = ["alerts", "history-retention"] as const;
+const DELIVERY_KEY = ["alerts", "delivery"] as const;
+const SILENCES_KEY = ["alerts", "silences"] as const;
+const MAINTENANCE_KEY = ["alerts", "maintenance"] as const;
 
 export function useAlertRules() {
 	return useQuery({
@@ -61,11 +92,13 @@ export function useDeleteAlertRule() {
 	});
 }
 
+// Polled so channel health follows retries as they happen.
 export function useAlertChannels() {
 	return useQuery({
 		queryKey: CHANNELS_KEY,
 		queryFn: getAlertChannels,
 		staleTime: 30_000,
+		refetchInterval: 30_000,
 	});
 }
 
@@ -111,15 +144,71 @@ export function useTestAlertChannel() {
 	});
 }
 
-export function useAlertHistory(limit: number) {
-	return useQuery({
-		queryKey: [...HISTORY_KEY, limit],
-		queryFn: () => getAlertHistory(limit),
+export function usePreviewAlertChannel() {
+	return useMutation({
+		mutationFn: (id: string) => testAlertChannel(id, true),
+	});
+}
+
+// Pages arrive newest first; each further page is older. Polling refetches
+// every loaded page, so incidents that resolve further down stay current.
+export function useAlertHistory(
+	filters: Omit<AlertHistoryParams, "cursor" | "limit">,
+	pageSize: number,
+) {
+	return useInfiniteQuery({
+		queryKey: [...HISTORY_KEY, filters, pageSize],
+		queryFn: ({ pageParam }) =>
+			getAlertHistory({
+				...filters,
+				limit: pageSize,
+				cursor: pageParam || undefined,
+			}),
+		initialPageParam: "",
+		getNextPageParam: (lastPage) => lastPage.nextCursor || undefined,
 		refetchInterval: 30_000,
 		refetchOnWindowFocus: true,
 	});
 }
 
+export function useAlertHistoryRetention() {
+	return useQuery({
+		queryKey: RETENTION_KEY,
+		queryFn: getAlertHistoryRetention,
+		staleTime: 30_000,
+	});
+}
+
+// Saving prunes immediately, so the history is refetched too.
+export function useUpdateAlertHistoryRetention() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: updateAlertHistoryRetention,
+		onSuccess: (retention) => {
+			queryClient.setQueryData(RETENTION_KEY, retention);
+			queryClient.invalidateQueries({ queryKey: HISTORY_KEY });
+		},
+	});
+}
+
+export function useAlertDelivery() {
+	return useQuery({
+		queryKey: DELIVERY_KEY,
+		queryFn: getAlertDelivery,
+		staleTime: 30_000,
+	});
+}
+
+export function useUpdateAlertDelivery() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: updateAlertDelivery,
+		onSuccess: (settings) => {
+			queryClient.setQueryData(DELIVERY_KEY, settings);
+		},
+	});
+}
+
 export function useClearAlertHistory() {
 	const queryClient = useQueryClient();
 	return useMutation({
@@ -129,3 +218,104 @@ export function useClearAlertHistory() {
 		},
 	});
 }
+
+export function useAcknowledgeAlert() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: (id: string) => acknowledgeAlert(id),
+		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: HISTORY_KEY });
+		},
+	});
+}
+
+export function useSilenceAlert() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: ({
+			id,
+			durationSeconds,
+		}: {
+			id: string;
+			durationSeconds: number;
+		}) => silenceAlert(id, durationSeconds),
+		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: HISTORY_KEY });
+		},
+	});
+}
+
+// Silences and maintenance windows report whether they are in effect, which
+// changes with time alone, so they are polled like the history.
+export function useAlertSilences() {
+	return useQuery({
+		queryKey: SILENCES_KEY,
+		queryFn: getAlertSilences,
+		refetchInterval: 30_000,
+	});
+}
+
+export function useCreateAlertSilence() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: (silence: AlertSilencePayload) => createAlertSilence(silence),
+		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: SILENCES_KEY });
+		},
+	});
+}
+
+export function useDeleteAlertSilence() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: (id: string) => deleteAlertSilence(id),
+		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: SILENCES_KEY });
+		},
+	});
+}
+
+export function useMaintenanceWindows() {
+	return useQuery({
+		queryKey: MAINTENANCE_KEY,
+		queryFn: getMaintenanceWindows,
+		refetchInterval: 30_000,
+	});
+}
+
+export function useCreateMaintenanceWindow() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: (window: MaintenanceWindowPayload) =>
+			createMaintenanceWindow(window),
+		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: MAINTENANCE_KEY });
+		},
+	});
+}
+
+export function useUpdateMaintenanceWindow() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: ({
+			id,
+			window,
+		}: {
+			id: string;
+			window: MaintenanceWindowPayload;
+		}) => updateMaintenanceWindow(id, window),
+		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: MAINTENANCE_KEY });
+		},
+	});
+}
+
+export function useDeleteMaintenanceWindow() {
+	const queryClient = useQueryClient();
+	return useMutation({
+		mutationFn: (id: string) => deleteMaintenanceWindow(id),
+		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: MAINTENANCE_KEY });
+		},
+	});
+}
@@ -34,6 +34,28 @@ export interface AuthConfig {
 	adminUsername?: string;
 }
 
+/** An age limit for stored lines; the first rule matching a line's container
+ * and level decides how long it is kept. */
+export interface RetentionRule {
+	hosts?: string[];
+	containers?: string[];
+	projects?: string[];
+	levels?: string[];
+	maxAgeHours: number;
+}
+
+/** Keeps lines out of the store: every rule matching a container applies. */
+export interface IngestionRule {
+	hosts?: string[];
+	containers?: string[];
+	projects?: string[];
+	/** "key" or "key=value" */
+	labels?: string[];
+	action: "exclude" | "drop" | "sample";
+	pattern?: string;
+	samplePercent?: number;
+}
+
 // Unlike the other categories, each log store field is overridden
 // independently, so it carries its own source rather than one for the section.
 export interface LogStoreConfig {
@@ -43,6 +65,14 @@ export interface LogStoreConfig {
 	perContainerMBSource: ConfigSource;
 	totalMB: number;
 	totalMBSource: ConfigSource;
+	fullTextIndex: boolean;
+	fullTextIndexSource: ConfigSource;
+	indexedFields: string[];
+	indexedFieldsSource: ConfigSource;
+	retentionRules: RetentionRule[];
+	retentionRulesSource: ConfigSource;
+	ingestionRules: IngestionRule[];
+	ingestionRulesSource: ConfigSource;
 }
 
 export interface SettingsResponse {
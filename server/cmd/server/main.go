@@ -14,9 +14,12 @@ import (
 	"github.com/AmoabaKelvin/logdeck/internal/config"
 	"github.com/AmoabaKelvin/logdeck/internal/coolify"
 	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/imageupdates"
 	"github.com/AmoabaKelvin/logdeck/internal/logstore"
 	"github.com/AmoabaKelvin/logdeck/internal/logstream"
 	"github.com/AmoabaKelvin/logdeck/internal/services"
+	"github.com/AmoabaKelvin/logdeck/internal/statsstore"
+	"github.com/AmoabaKelvin/logdeck/internal/statsstream"
 	"github.com/AmoabaKelvin/logdeck/internal/system"
 )
 
@@ -73,10 +76,19 @@ func main() {
 	registry := services.NewRegistry(multiHostClient, coolifyClient, authService, cfg)
 
 	logHub := logstream.New(registry)
+	statsHub := statsstream.New(registry)
 	alertEngine := alerts.NewEngine(registry, manager, logHub)
 	// logStore is nil when persistence is disabled or its database is
 	// unusable; every consumer must treat that as "no stored logs".
 	logStore := logstore.OpenFromConfig(manager)
+	// statsStore is nil in the same way when stats history is off.
+	statsStore := statsstore.OpenFromConfig(manager)
+	imageUpdates := imageupdates.New(manager.ImageUpdates)
+	if settings := manager.ImageUpdates(); settings.Enabled {
+		log.Printf("Image update checks are ENABLED (every %dh)", settings.IntervalHours)
+	} else {
+		log.Println("Image update checks are DISABLED")
+	}
 
 	manager.OnChange(func(newCfg *config.Config) {
 		registry.UpdateConfig(newCfg)
@@ -96,9 +108,11 @@ func main() {
 			// converge the shared log tails onto the new client.
 			alertEngine.Reconcile()
 			logHub.Reconcile()
+			statsHub.Reconcile()
 		}
 
 		registry.SwapCoolify(coolify.NewMultiClient(newCfg.CoolifyHosts))
+		imageUpdates.Reconcile()
 
 		// Recreate auth service from file config (env-based auth is immutable).
 		fc := manager.FileConfigSnapshot()
@@ -109,7 +123,16 @@ func main() {
 		log.Println("Configuration reloaded successfully")
 	})
 
-	apiRouter := api.NewRouter(registry, manager, alertEngine, logStore, version)
+	apiRouter := api.NewRouter(api.RouterDeps{
+		Registry:     registry,
+		Manager:      manager,
+		Alerts:       alertEngine,
+		LogStore:     logStore,
+		StatsStore:   statsStore,
+		StatsHub:     statsHub,
+		ImageUpdates: imageUpdates,
+		Version:      version,
+	})
 
 	// No WriteTimeout/IdleTimeout: log streaming and terminal WebSockets are
 	// long-lived connections and would be killed by them. ReadTimeout only
@@ -132,10 +155,15 @@ func main() {
 	}()
 
 	go logHub.Run(ctx)
+	go statsHub.Run(ctx)
 	alertEngine.Start(ctx)
 	if logStore != nil {
 		logStore.Start(ctx, logHub, registry)
 	}
+	if statsStore != nil {
+		statsStore.Start(ctx, registry)
+	}
+	imageUpdates.Start(ctx, registry)
 
 	go func() {
 		log.Println("Server starting on :8080")
@@ -153,15 +181,24 @@ func main() {
 		log.Printf("Graceful shutdown failed: %v", err)
 	}
 
-	// Drain the alerting engine and the shared log tails after the server has
-	// stopped accepting requests. The log store drains last: it feeds off the
-	// hub, so its final batch can only be complete once the hub has stopped.
+	// Drain the alerting engine, the shared log tails, and the stats streams
+	// after the server has stopped accepting requests. The log store drains
+	// last: it feeds off the log hub, so its final batch can only be complete
+	// once the hub has stopped.
 	alertEngine.Wait()
 	logHub.Wait()
+	statsHub.Wait()
+	imageUpdates.Wait()
 	if logStore != nil {
 		logStore.Wait()
 		if err := logStore.Close(); err != nil {
 			log.Printf("Closing the log store failed: %v", err)
 		}
 	}
+	if statsStore != nil {
+		statsStore.Wait()
+		if err := statsStore.Close(); err != nil {
+			log.Printf("Closing the stats history failed: %v", err)
+		}
+	}
 }
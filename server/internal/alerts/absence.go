@@ -0,0 +1,123 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// containerState is one container as seen by a container listing, running or
+// not.
+type containerState struct {
+	host          string
+	containerID   string
+	containerName string
+	labels        map[string]string
+	state         string // "running", "exited", ...
+}
+
+// containerStates lists every container, in any state, that selected reports
+// true for. An unreachable host contributes nothing rather than failing the
+// listing.
+func (a dockerEventAdapter) containerStates(ctx context.Context, selected func(host, name string, labels map[string]string) bool) ([]containerState, error) {
+	containersMap, _, err := a.c.ListContainersAllHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var states []containerState
+	for host, containers := range containersMap {
+		for _, c := range containers {
+			if len(c.Names) == 0 {
+				continue
+			}
+			name := strings.TrimPrefix(c.Names[0], "/")
+			if !selected(host, name, c.Labels) {
+				continue
+			}
+			states = append(states, containerState{host: host, containerID: c.ID, containerName: name, labels: c.Labels, state: c.State})
+		}
+	}
+	return states, nil
+}
+
+// absenceTracker counts one absence rule's matches for one (host, container)
+// key. Like ruleWindow it takes explicit now values and is owned by the run
+// loop.
+type absenceTracker struct {
+	window   time.Duration
+	expected int
+
+	// start is when the key was first tracked: nothing is judged missing
+	// until a full window has passed since. times holds the latest match
+	// times inside the window, oldest first; no more than expected are kept,
+	// since more never change the verdict.
+	start time.Time
+	times []time.Time
+}
+
+func newAbsenceTracker(rule *compiledRule, now time.Time) *absenceTracker {
+	return &absenceTracker{window: rule.window, expected: rule.expected, start: now}
+}
+
+// record notes one match at now.
+func (t *absenceTracker) record(now time.Time) {
+	t.times = append(t.times, now)
+	if len(t.times) > t.expected {
+		t.times = t.times[len(t.times)-t.expected:]
+	}
+	t.prune(now)
+}
+
+// count returns the number of matches within the window ending at now,
+// capped at the expected count.
+func (t *absenceTracker) count(now time.Time) int {
+	t.prune(now)
+	return len(t.times)
+}
+
+// missing reports whether fewer than the expected matches arrived in
+// the window ending at now, along with the count. It never holds before the
+// key has been tracked for a full window.
+func (t *absenceTracker) missing(now time.Time) (int, bool) {
+	n := t.count(now)
+	return n, now.Sub(t.start) >= t.window && n < t.expected
+}
+
+func (t *absenceTracker) prune(now time.Time) {
+	drop := 0
+	for drop < len(t.times) && now.Sub(t.times[drop]) > t.window {
+		drop++
+	}
+	t.times = t.times[drop:]
+}
+
+// absenceReason renders the human-readable reason for a fired absence rule,
+// e.g. "no matching lines within 300s (container exited)".
+func absenceReason(rule *compiledRule, count int, state string) string {
+	window := int(rule.window.Seconds())
+	var reason string
+	if count == 0 {
+		reason = fmt.Sprintf("no matching lines within %ds", window)
+	} else {
+		reason = fmt.Sprintf("only %d of %d expected matching lines within %ds", count, rule.expected, window)
+	}
+	var conds []string
+	if rule.minLevel != "" {
+		conds = append(conds, "level >= "+rule.minLevel)
+	}
+	if rule.pattern != nil {
+		conds = append(conds, fmt.Sprintf("pattern %q", rule.pattern.String()))
+	}
+	if len(conds) > 0 {
+		reason += " (" + strings.Join(conds, ", ") + ")"
+	}
+	switch state {
+	case "running":
+	case "":
+		reason += "; container not found"
+	default:
+		reason += "; container " + state
+	}
+	return reason
+}
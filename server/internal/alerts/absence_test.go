@@ -0,0 +1,113 @@
+package alerts
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+func TestAbsenceTrackerMissing(t *testing.T) {
+	rule := &compiledRule{expected: 2, window: time.Minute}
+	base := time.Unix(1_700_000_000, 0)
+	tr := newAbsenceTracker(rule, base)
+
+	if _, missing := tr.missing(base.Add(30 * time.Second)); missing {
+		t.Fatal("missing before the key was tracked for a full window")
+	}
+	tr.record(base.Add(40 * time.Second))
+	if n, missing := tr.missing(base.Add(60 * time.Second)); !missing || n != 1 {
+		t.Fatalf("missing = (%d, %v), want (1, true) with one of two lines", n, missing)
+	}
+	tr.record(base.Add(70 * time.Second))
+	if _, missing := tr.missing(base.Add(80 * time.Second)); missing {
+		t.Fatal("missing with two lines inside the window")
+	}
+	tr.record(base.Add(75 * time.Second))
+	if len(tr.times) != 2 {
+		t.Fatalf("tracker keeps %d times, want at most the expected 2", len(tr.times))
+	}
+	// Only the latest line is left inside the window.
+	if n, missing := tr.missing(base.Add(131 * time.Second)); !missing || n != 1 {
+		t.Fatalf("missing = (%d, %v), want (1, true) once a line aged out", n, missing)
+	}
+}
+
+func TestAbsenceReason(t *testing.T) {
+	rule := &compiledRule{expected: 1, window: 5 * time.Minute, pattern: regexp.MustCompile("job done")}
+	if got, want := absenceReason(rule, 0, "running"), `no matching lines within 300s (pattern "job done")`; got != want {
+		t.Errorf("reason = %q, want %q", got, want)
+	}
+	if got, want := absenceReason(rule, 0, "exited"), `no matching lines within 300s (pattern "job done"); container exited`; got != want {
+		t.Errorf("reason = %q, want %q", got, want)
+	}
+	if got, want := absenceReason(rule, 0, ""), `no matching lines within 300s (pattern "job done"); container not found`; got != want {
+		t.Errorf("reason = %q, want %q", got, want)
+	}
+	rule.expected = 3
+	if got, want := absenceReason(rule, 1, "running"), `only 1 of 3 expected matching lines within 300s (pattern "job done")`; got != want {
+		t.Errorf("reason = %q, want %q", got, want)
+	}
+}
+
+func TestAbsenceRuleFiresAndRecovers(t *testing.T) {
+	rule := config.AlertRule{
+		ID: "a1", Name: "cron heartbeat", Enabled: true, Type: "log", Mode: "absence",
+		Pattern: "done", WindowSeconds: 60, Containers: []string{"web", "cron"}, Hosts: []string{"local"},
+	}
+	te := startTestEngine(t, rule)
+	te.events.setStates(containerState{host: "local", containerID: "abc123", containerName: "web", state: "running"})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+
+	// Let a few listings start tracking before the window elapses.
+	time.Sleep(50 * time.Millisecond)
+	if n := len(te.e.History(0)); n != 0 {
+		t.Fatalf("history has %d entries before a full window passed", n)
+	}
+
+	te.clock.advance(61 * time.Second)
+	waitFor(t, "absence incidents", func() bool { return len(te.e.History(0)) == 2 })
+	byName := make(map[string]models.Alert)
+	for _, a := range te.e.History(0) {
+		byName[a.ContainerName] = a
+	}
+	if a := byName["web"]; a.Reason != `no matching lines within 60s (pattern "done")` || a.Host != "local" {
+		t.Fatalf("web incident = %+v", a)
+	}
+	if a := byName["cron"]; a.Reason != `no matching lines within 60s (pattern "done"); container not found` || a.Sample != "container not found" || a.Host != "local" {
+		t.Fatalf("cron incident = %+v", a)
+	}
+
+	te.hub.emit(record(models.LogLevelInfo, "job done"))
+	waitFor(t, "recovery", func() bool {
+		for _, a := range te.e.History(0) {
+			if a.ContainerName == "web" {
+				return a.State == models.AlertResolved
+			}
+		}
+		return false
+	})
+	for _, a := range te.e.History(0) {
+		if a.ContainerName == "web" && a.ResolveReason != "matching lines resumed" {
+			t.Fatalf("web incident = %+v, want it resolved by the resumed lines", a)
+		}
+		if a.ContainerName == "cron" && a.State != models.AlertFiring {
+			t.Fatalf("cron incident = %+v, want it still firing", a)
+		}
+	}
+}
+
+func TestCompileRulesAbsence(t *testing.T) {
+	rules := compileRules(config.AlertsConfig{Rules: []config.AlertRule{
+		{ID: "a", Enabled: true, Type: "log", Mode: "absence", Pattern: "ok", Threshold: 3, WindowSeconds: 300},
+		{ID: "bad", Enabled: true, Type: "log", Mode: "sometimes", Pattern: "ok"},
+	}})
+	if len(rules) != 1 {
+		t.Fatalf("compiled %d rules, want the unknown mode skipped", len(rules))
+	}
+	if a := rules[0]; !a.absence || a.expected != 3 || a.threshold != 1 || a.window != 5*time.Minute {
+		t.Fatalf("compiled absence rule = %+v", a)
+	}
+}
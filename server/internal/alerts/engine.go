@@ -1,17 +1,29 @@
-// Package alerts contains the alerting engine: it watches container events
-// and log streams, matches them against the configured rules, and delivers
+// Package alerts contains the alerting engine: it watches container events,
+// log streams, and periodic CPU and memory samples, matches them against the
+// configured rules, notices log lines that stop arriving, and delivers
 // notifications to the configured channels.
 //
+// Alerts are incidents: one (rule, host, container) key opens an incident when
+// its window trips, repeats it when the window trips again after the
+// cooldown, and resolves it once the condition clears — a healthy transition
+// for an unhealthy container, otherwise a full window without a trip.
+//
 // Concurrency model: a single run goroutine owns all mutable state (compiled
-// rules, subscription handles, rate/cooldown windows, the event-stream child
-// context). Log-rule sinks run on the hub's delivery goroutines and only push
-// non-blocking match messages onto firedCh. A single dispatcher goroutine
-// consumes fired alerts, appends them to history, delivers them to the
-// configured channels, and records each delivery outcome on the history entry.
-// Helper goroutines exist only for single container-inspect lookups.
+// rules, subscription handles, rate/cooldown windows and the incident each
+// has open, the event-stream child context). Log-rule sinks run on the hub's
+// delivery goroutines and only push non-blocking match messages onto firedCh.
+// A single dispatcher goroutine consumes incident transitions, records them in
+// history, and queues their notifications in the outbox, a table beside the
+// history that survives restarts. A single outbox goroutine delivers queued
+// notifications to their channels, backing off a failing channel, and records
+// each delivery outcome on the history entry. Helper goroutines exist only for
+// single container-inspect lookups, the stats poll, the container listing
+// absence rules are checked against, and concurrent deliveries to separate
+// channels.
 package alerts
 
 import (
+	"cmp"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -19,6 +31,7 @@ import (
 	"log"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -36,15 +49,21 @@ const (
 	inspectBuffer  = 64
 	dispatchBuffer = 128
 
+	// defaultStatsInterval is how often running containers are sampled for
+	// metric rules, and containers listed for absence rules; neither runs
+	// while there are no such rules. statsTimeout bounds one poll or listing
+	// across every host.
+	defaultStatsInterval = 15 * time.Second
+	statsTimeout         = 10 * time.Second
+
 	defaultResyncInterval = 60 * time.Second
-	inspectTimeout        = 5 * time.Second
-	// deliverBudget bounds one delivery cycle (attempt + retry wait +
-	// attempt).
-	deliverBudget = 30 * time.Second
-	// shutdownDrainBudget bounds the entire dispatch-queue drain after the
-	// Start context is cancelled: once it expires, remaining alerts are
-	// recorded as failed ("shutdown") instead of waiting out their delivery
-	// timeouts one by one.
+	// defaultResolveInterval is how often open incidents are checked for a
+	// cleared condition.
+	defaultResolveInterval = 5 * time.Second
+	inspectTimeout         = 5 * time.Second
+	// shutdownDrainBudget bounds how long delivery attempts already in flight
+	// when the Start context is cancelled may take to finish; an attempt cut
+	// off stays queued for the next run.
 	shutdownDrainBudget = 30 * time.Second
 	// oomDieWindow is how long after an "oom" event a "die" for the same
 	// container is treated as part of the same incident by rules watching
@@ -73,6 +92,8 @@ type eventClient interface {
 	streamEvents(ctx context.Context) <-chan docker.EngineEvent
 	inspectExit(ctx context.Context, host, containerID string) (exitCode string, oomKilled bool, err error)
 	inspectHealth(ctx context.Context, host, containerID string) (status string, err error)
+	containerSamples(ctx context.Context, selected func(host, name string, labels map[string]string) bool) ([]containerSample, error)
+	containerStates(ctx context.Context, selected func(host, name string, labels map[string]string) bool) ([]containerState, error)
 }
 
 // dockerEventAdapter adapts *docker.MultiHostClient to eventClient. It is a
@@ -119,6 +140,7 @@ type matchMsg struct {
 	host          string
 	containerID   string
 	containerName string
+	project       string
 	sample        string
 }
 
@@ -127,13 +149,26 @@ type matchMsg struct {
 // back to the run loop.
 type inspectResult struct {
 	ev       docker.EngineEvent
-	action   string // "die" | "unhealthy"
+	action   string // "die" | "unhealthy" | "healthy"
 	exitCode string // die only
 	fire     bool
 }
 
+// statsResult is the outcome of one stats poll posted back to the run loop.
+type statsResult struct {
+	samples []containerSample
+	err     error
+}
+
+// listResult is the outcome of one container listing for absence rules posted
+// back to the run loop.
+type listResult struct {
+	containers []containerState
+	err        error
+}
+
 // Engine evaluates alert rules against engine events and log records and
-// records fired alerts in an in-memory history.
+// records fired alerts in a SQLite history.
 type Engine struct {
 	hub      engineHub
 	source   func() eventClient
@@ -145,22 +180,34 @@ type Engine struct {
 	reconcileCh chan struct{}
 	firedCh     chan matchMsg
 	inspectCh   chan inspectResult
-	dispatchCh  chan models.Alert
+	statsCh     chan statsResult
+	listCh      chan listResult
+	dispatchCh  chan dispatchMsg
 	flushStop   chan struct{}
+	// outboxCh wakes the outbox loop; outboxDone closes once it has exited.
+	outboxCh   chan struct{}
+	outboxDone chan struct{}
 
 	// deliverCtx survives Start-context cancellation so in-flight deliveries
 	// can finish during shutdown, but is cancelled once shutdownDrainBudget
-	// elapses after shutdown begins; skipRetry aborts retry waits once
-	// shutdown begins. Both are set in Start before any goroutine reads them.
+	// elapses after shutdown begins. It is set in Start before any goroutine
+	// reads it.
 	deliverCtx context.Context
-	skipRetry  <-chan struct{}
 
-	resyncInterval time.Duration
-	now            func() time.Time
-	wg             sync.WaitGroup
+	resyncInterval  time.Duration
+	resolveInterval time.Duration
+	statsInterval   time.Duration
+	now             func() time.Time
+	wg              sync.WaitGroup
 
 	matchDrops  atomic.Uint64
 	loggedDrops uint64 // owned by the run loop
+
+	counts fireCounts
+
+	// recent holds the final log lines restart-loop alerts carry as their
+	// sample.
+	recent *recentLines
 }
 
 // NewEngine creates an alerting engine that reads rules through manager,
@@ -174,25 +221,32 @@ func NewEngine(provider DockerProvider, manager *config.Manager, hub *logstream.
 		}
 		return *fc.Alerts
 	}
-	historyPath := filepath.Join(filepath.Dir(manager.ConfigFilePath()), "alerts-history.json")
+	historyPath := filepath.Join(filepath.Dir(manager.ConfigFilePath()), historyFileName)
 	return newEngine(hub, func() eventClient { return dockerEventAdapter{c: provider.Docker()} }, alertsFn, historyPath)
 }
 
 // newEngine is the internal constructor; tests inject fakes here.
 func newEngine(hub engineHub, source func() eventClient, alertsFn func() config.AlertsConfig, historyPath string) *Engine {
 	return &Engine{
-		hub:            hub,
-		source:         source,
-		alertsFn:       alertsFn,
-		hist:           newHistory(historyPath, historyCap),
-		notif:          newNotifier(),
-		reconcileCh:    make(chan struct{}, 1),
-		firedCh:        make(chan matchMsg, firedBuffer),
-		inspectCh:      make(chan inspectResult, inspectBuffer),
-		dispatchCh:     make(chan models.Alert, dispatchBuffer),
-		flushStop:      make(chan struct{}),
-		resyncInterval: defaultResyncInterval,
-		now:            time.Now,
+		hub:             hub,
+		source:          source,
+		alertsFn:        alertsFn,
+		hist:            newHistory(historyPath),
+		notif:           newNotifier(),
+		reconcileCh:     make(chan struct{}, 1),
+		firedCh:         make(chan matchMsg, firedBuffer),
+		inspectCh:       make(chan inspectResult, inspectBuffer),
+		statsCh:         make(chan statsResult, 1),
+		listCh:          make(chan listResult, 1),
+		dispatchCh:      make(chan dispatchMsg, dispatchBuffer),
+		flushStop:       make(chan struct{}),
+		outboxCh:        make(chan struct{}, 1),
+		outboxDone:      make(chan struct{}),
+		resyncInterval:  defaultResyncInterval,
+		resolveInterval: defaultResolveInterval,
+		statsInterval:   defaultStatsInterval,
+		now:             time.Now,
+		recent:          newRecentLines(),
 	}
 }
 
@@ -200,11 +254,10 @@ func newEngine(hub engineHub, source func() eventClient, alertsFn func() config.
 // evaluation) tied to ctx. It returns immediately; use Wait to block until
 // shutdown completes.
 func (e *Engine) Start(ctx context.Context) {
-	e.hist.load()
+	e.hist.closeInterrupted(e.now())
 	drainCtx, drainCancel := context.WithCancel(context.WithoutCancel(ctx))
 	e.deliverCtx = drainCtx
-	e.skipRetry = ctx.Done()
-	e.wg.Add(4)
+	e.wg.Add(5)
 	go func() {
 		defer e.wg.Done()
 		e.run(ctx)
@@ -215,11 +268,15 @@ func (e *Engine) Start(ctx context.Context) {
 	}()
 	go func() {
 		defer e.wg.Done()
-		e.hist.flushLoop(e.flushStop)
+		e.outboxLoop(ctx)
+	}()
+	go func() {
+		defer e.wg.Done()
+		e.hist.retainLoop(e.flushStop, func() config.AlertHistoryConfig { return e.alertsFn().HistoryRetention() }, e.now)
 	}()
-	// The whole post-shutdown dispatch drain shares one budget: deliverCtx is
-	// cancelled shutdownDrainBudget after the Start context, so a queue of
-	// alerts against an unresponsive webhook cannot stall shutdown.
+	// In-flight deliveries share one budget: deliverCtx is cancelled
+	// shutdownDrainBudget after the Start context, so attempts against an
+	// unresponsive channel cannot stall shutdown.
 	go func() {
 		defer e.wg.Done()
 		defer drainCancel()
@@ -251,17 +308,31 @@ func (e *Engine) Reconcile() {
 }
 
 // TestChannel delivers a synthetic alert to a single channel and reports the
-// delivery outcome. The test alert is not recorded in history.
-func (e *Engine) TestChannel(ctx context.Context, channel config.AlertChannel) models.DeliveryResult {
-	alert := models.Alert{
-		ID:       newAlertID(),
-		RuleName: "Test channel",
-		Type:     "test",
-		Reason:   "test notification from LogDeck",
-		Count:    1,
-		FiredAt:  e.now().UTC().Format(time.RFC3339),
+// delivery outcome along with the notification as rendered through the
+// channel's templates. A dry run renders without delivering, reporting status
+// "skipped". The test alert is not recorded in history.
+func (e *Engine) TestChannel(ctx context.Context, channel config.AlertChannel, dryRun bool) models.ChannelTestResult {
+	// The example target and sample lines give templates that read them
+	// something to render.
+	alert := templateSampleAlert(e.now(), models.AlertFiring)
+	alert.ID = newAlertID()
+	alert.RuleID = ""
+	alert.RuleName = "Test channel"
+	alert.Type = "test"
+	alert.Reason = "test notification from LogDeck"
+	alert.Count = 1
+
+	preview, err := previewChannel(channel, alert)
+	if err != nil {
+		return models.ChannelTestResult{DeliveryResult: models.DeliveryResult{Status: "failed", Error: err.Error()}}
 	}
-	return e.notif.deliver(ctx, channel, alert, ctx.Done())
+	result := models.ChannelTestResult{Preview: &preview}
+	if dryRun {
+		result.Status = "skipped"
+		return result
+	}
+	result.DeliveryResult = e.notif.deliver(ctx, channel, alert, ctx.Done())
+	return result
 }
 
 // History returns the most recent fired alerts, newest first, capped at
@@ -270,6 +341,18 @@ func (e *Engine) History(limit int) []models.Alert {
 	return e.hist.list(limit)
 }
 
+// QueryHistory returns the page of fired alerts q selects, newest first. It
+// returns ErrInvalidHistoryCursor for a cursor it did not produce.
+func (e *Engine) QueryHistory(ctx context.Context, q HistoryQuery) (HistoryPage, error) {
+	return e.hist.query(ctx, q)
+}
+
+// RetainHistory applies the history retention bounds now rather than at the
+// next periodic sweep, e.g. after they were lowered. Non-blocking.
+func (e *Engine) RetainHistory() {
+	e.hist.requestRetain()
+}
+
 func (e *Engine) ClearHistory() {
 	e.hist.clear()
 }
@@ -288,10 +371,17 @@ type runState struct {
 	ctx context.Context
 	gen uint64
 
-	subs       map[string]*activeSub // live log-rule subscriptions by rule ID
-	eventRules []*compiledRule
-	windows    map[string]*ruleWindow // by ruleID|host|containerName
-	lastOOM    map[string]time.Time   // last oom event by host|containerID
+	subs        map[string]*activeSub // live log-rule subscriptions by rule ID
+	lineSubs    map[string]func()     // restart-loop rules' recent-line subscriptions by rule ID
+	eventRules  []*compiledRule
+	metricRules []*compiledRule
+	windows     map[string]*ruleWindow     // by ruleID|host|containerName
+	series      map[string]*metricSeries   // metric readings, keyed like windows
+	absence     map[string]*absenceTracker // absence-rule matches, keyed like windows
+	lastOOM     map[string]time.Time       // last oom event by host|containerID
+	restarts    map[string]*restartTracker // die/start correlation by host|containerName
+	polling     bool                       // a stats poll is in flight
+	listing     bool                       // a container listing is in flight
 
 	events       <-chan docker.EngineEvent
 	eventsCancel context.CancelFunc
@@ -302,16 +392,24 @@ type runState struct {
 // state.
 func (e *Engine) run(ctx context.Context) {
 	st := &runState{
-		ctx:     ctx,
-		subs:    make(map[string]*activeSub),
-		windows: make(map[string]*ruleWindow),
-		lastOOM: make(map[string]time.Time),
+		ctx:      ctx,
+		subs:     make(map[string]*activeSub),
+		lineSubs: make(map[string]func()),
+		restarts: make(map[string]*restartTracker),
+		windows:  make(map[string]*ruleWindow),
+		series:   make(map[string]*metricSeries),
+		absence:  make(map[string]*absenceTracker),
+		lastOOM:  make(map[string]time.Time),
 	}
 	e.openEvents(st, e.source())
 	e.reconcile(st)
 
 	ticker := time.NewTicker(e.resyncInterval)
 	defer ticker.Stop()
+	resolveTicker := time.NewTicker(e.resolveInterval)
+	defer resolveTicker.Stop()
+	statsTicker := time.NewTicker(e.statsInterval)
+	defer statsTicker.Stop()
 
 	for {
 		select {
@@ -325,6 +423,17 @@ func (e *Engine) run(ctx context.Context) {
 			e.checkEventClient(st)
 			e.pruneWindows(st)
 			e.logDrops()
+		case <-resolveTicker.C:
+			e.resolveQuiet(st)
+		case <-statsTicker.C:
+			e.spawnStatsPoll(st)
+			e.spawnAbsenceCheck(st)
+		case r := <-e.statsCh:
+			st.polling = false
+			e.handleStats(st, r)
+		case r := <-e.listCh:
+			st.listing = false
+			e.handleAbsence(st, r)
 		case m := <-e.firedCh:
 			e.handleMatch(st, m)
 		case ev, ok := <-st.events:
@@ -364,17 +473,20 @@ func (e *Engine) checkEventClient(st *runState) {
 // reconcile recompiles the rule set and diffs log-rule subscriptions:
 // unchanged rules keep their subscription (and generation); removed or
 // changed rules are unsubscribed and, if still present, resubscribed with a
-// fresh compiled snapshot. Window state for removed or changed rules is
-// dropped.
+// fresh compiled snapshot. Window, metric, and absence state for removed or
+// changed rules is dropped.
 func (e *Engine) reconcile(st *runState) {
 	compiled := compileRules(e.alertsFn())
 
 	newLog := make(map[string]*compiledRule)
-	var newEvent []*compiledRule
+	var newEvent, newMetric []*compiledRule
 	for _, r := range compiled {
-		if r.typ == "log" {
+		switch r.typ {
+		case "log":
 			newLog[r.id] = r
-		} else {
+		case "metric":
+			newMetric = append(newMetric, r)
+		default:
 			newEvent = append(newEvent, r)
 		}
 	}
@@ -394,27 +506,66 @@ func (e *Engine) reconcile(st *runState) {
 		e.subscribeRule(st, r)
 	}
 
-	oldEvent := make(map[string]config.AlertRule, len(st.eventRules))
-	for _, r := range st.eventRules {
-		oldEvent[r.id] = r.src
+	// Event and metric rules hold no subscription: a changed one simply
+	// replaces its predecessor.
+	oldRules := make(map[string]config.AlertRule, len(st.eventRules)+len(st.metricRules))
+	for _, r := range append(slices.Clone(st.eventRules), st.metricRules...) {
+		oldRules[r.id] = r.src
 	}
-	for _, r := range newEvent {
-		if old, ok := oldEvent[r.id]; !ok || !reflect.DeepEqual(old, r.src) {
+	for _, r := range append(slices.Clone(newEvent), newMetric...) {
+		if old, ok := oldRules[r.id]; !ok || !reflect.DeepEqual(old, r.src) {
 			stale[r.id] = true
 		}
-		delete(oldEvent, r.id)
+		delete(oldRules, r.id)
 	}
-	for id := range oldEvent {
+	for id := range oldRules {
 		stale[id] = true
 	}
 	st.eventRules = newEvent
+	st.metricRules = newMetric
+
+	// Restart-loop rules keep their containers' final lines from the hub.
+	loops := make(map[string]*compiledRule)
+	for _, r := range newEvent {
+		if r.hasEvent(eventRestartLoop) {
+			loops[r.id] = r
+		}
+	}
+	for id, unsub := range st.lineSubs {
+		if _, ok := loops[id]; ok && !stale[id] {
+			continue
+		}
+		unsub()
+		delete(st.lineSubs, id)
+		e.recent.drop(id)
+	}
+	for id, r := range loops {
+		if _, ok := st.lineSubs[id]; !ok {
+			e.subscribeLines(st, r)
+		}
+	}
 
 	if len(stale) > 0 {
-		for key := range st.windows {
+		for key, w := range st.windows {
 			if id, _, _ := strings.Cut(key, "|"); stale[id] {
+				// The incident belonged to a rule that no longer exists as it
+				// was, so it closes without a notification.
+				if w.incident != "" {
+					e.resolve(w, "rule changed or removed", false)
+				}
 				delete(st.windows, key)
 			}
 		}
+		for key := range st.series {
+			if id, _, _ := strings.Cut(key, "|"); stale[id] {
+				delete(st.series, key)
+			}
+		}
+		for key := range st.absence {
+			if id, _, _ := strings.Cut(key, "|"); stale[id] {
+				delete(st.absence, key)
+			}
+		}
 	}
 }
 
@@ -437,6 +588,7 @@ func (e *Engine) subscribeRule(st *runState, rule *compiledRule) {
 			host:          rec.Host,
 			containerID:   rec.ContainerID,
 			containerName: rec.ContainerName,
+			project:       models.ComposeProject(rec.Labels),
 			sample:        entrySample(rec.Entry),
 		}
 		select {
@@ -449,18 +601,45 @@ func (e *Engine) subscribeRule(st *runState, rule *compiledRule) {
 	st.subs[rule.id] = &activeSub{rule: rule, gen: gen, unsub: unsub}
 }
 
-// handleMatch applies rate limiting and cooldown to one log-rule match.
+// subscribeLines registers a hub subscription that keeps the final log lines
+// of every container a restart-loop rule targets. Like a log-rule sink it
+// never touches run-loop state.
+func (e *Engine) subscribeLines(st *runState, rule *compiledRule) {
+	opts := models.LogOptions{Timestamps: true, Tail: "0", ShowStdout: true, ShowStderr: true}
+	st.lineSubs[rule.id] = e.hub.Subscribe(rule.spec, opts, func(rec logstream.Record) {
+		e.recent.add(rule.id+"|"+rec.Host+"|"+rec.ContainerName, entrySample(rec.Entry))
+	})
+}
+
+// handleMatch applies rate limiting and cooldown to one log-rule match. For
+// an absence rule the match is counted instead, and resolves the key's open
+// incident once the expected number of lines has arrived again.
 func (e *Engine) handleMatch(st *runState, m matchMsg) {
 	sub, ok := st.subs[m.rule.id]
 	if !ok || sub.gen != m.gen {
 		return // stale: the rule was removed or resubscribed since this match
 	}
 	key := m.rule.id + "|" + m.host + "|" + m.containerName
-	res := e.window(st, key, m.rule).observe(e.now())
+	if m.rule.absence {
+		now := e.now()
+		t, ok := st.absence[key]
+		if !ok {
+			t = newAbsenceTracker(m.rule, now)
+			st.absence[key] = t
+		}
+		t.record(now)
+		if w, ok := st.windows[key]; ok && w.incident != "" && t.count(now) >= t.expected {
+			e.resolve(w, "matching lines resumed", true)
+		}
+		return
+	}
+	w := e.window(st, key, m.rule)
+	e.resolveIfQuiet(w)
+	res := w.observe(e.now())
 	if !res.fire {
 		return
 	}
-	e.emit(m.rule, m.host, m.containerID, m.containerName, logReason(m.rule), m.sample, res.suppressed)
+	e.fire(w, m.rule, m.host, m.containerID, m.containerName, m.project, logReason(m.rule), m.sample, res.suppressed)
 }
 
 // handleEvent routes one engine event. Only die, oom, and unhealthy health
@@ -469,7 +648,10 @@ func (e *Engine) handleMatch(st *runState, m matchMsg) {
 func (e *Engine) handleEvent(st *runState, ev docker.EngineEvent) {
 	base, _, _ := strings.Cut(ev.Action, ": ")
 	switch base {
+	case "start":
+		e.recordStart(st, ev)
 	case "die":
+		e.recordDie(st, ev)
 		switch ev.ExitCode {
 		case "":
 			e.spawnInspect(st, ev)
@@ -481,12 +663,14 @@ func (e *Engine) handleEvent(st *runState, ev docker.EngineEvent) {
 	case "oom":
 		e.recordEventMatch(st, ev, "oom", "")
 	case "health_status":
-		// Only the unhealthy transition is an alert; healthy/starting are
-		// recoveries and never fire.
+		// Only the unhealthy transition is an alert; a healthy one resolves
+		// the incident an unhealthy one opened, and starting does neither.
 		switch ev.HealthStatus {
 		case "unhealthy":
 			// Docker put the state in the action suffix; use it directly.
 			e.recordEventMatch(st, ev, "unhealthy", "")
+		case "healthy":
+			e.recordRecovery(st, ev)
 		case "":
 			// Podman emits a bare "health_status" action and carries the state
 			// only in a top-level field the SDK drops on decode. Resolve it
@@ -528,7 +712,8 @@ func (e *Engine) spawnInspect(st *runState, ev docker.EngineEvent) {
 // "health_status" event (single inspect, bounded timeout) and posts the result
 // back to the run loop. Only a confirmed "unhealthy" fires: unlike a die, an
 // unresolvable state is not fired, since firing on an unknown state would be a
-// false positive on a container that may well be healthy.
+// false positive on a container that may well be healthy. A confirmed
+// "healthy" is posted as a recovery.
 func (e *Engine) spawnHealthInspect(st *runState, ev docker.EngineEvent) {
 	client := st.eventsClient
 	runCtx := st.ctx
@@ -539,6 +724,9 @@ func (e *Engine) spawnHealthInspect(st *runState, ev docker.EngineEvent) {
 		defer cancel()
 		status, err := client.inspectHealth(ictx, ev.Host, ev.ContainerID)
 		res := inspectResult{ev: ev, action: "unhealthy", fire: err == nil && status == "unhealthy"}
+		if err == nil && status == "healthy" {
+			res = inspectResult{ev: ev, action: "healthy", fire: true}
+		}
 		select {
 		case e.inspectCh <- res:
 		case <-runCtx.Done():
@@ -552,6 +740,10 @@ func (e *Engine) handleInspect(st *runState, r inspectResult) {
 	if !r.fire {
 		return
 	}
+	if r.action == "healthy" {
+		e.recordRecovery(st, r.ev)
+		return
+	}
 	e.recordEventMatch(st, r.ev, r.action, r.exitCode)
 }
 
@@ -577,12 +769,263 @@ func (e *Engine) recordEventMatch(st *runState, ev docker.EngineEvent, action, e
 			}
 		}
 		key := rule.id + "|" + ev.Host + "|" + name
-		res := e.window(st, key, rule).observe(e.now())
+		w := e.window(st, key, rule)
+		e.resolveIfQuiet(w)
+		res := w.observe(e.now())
+		if res.tripped {
+			w.awaitHealthy = action == "unhealthy"
+		}
 		if !res.fire {
 			continue
 		}
-		e.emit(rule, ev.Host, ev.ContainerID, name, eventReason(rule, action, exitCode), eventSample(action, exitCode), res.suppressed)
+		e.fire(w, rule, ev.Host, ev.ContainerID, name, models.ComposeProject(ev.Labels), eventReason(rule, action, exitCode), eventSample(action, exitCode), res.suppressed)
+	}
+}
+
+// recordRecovery resolves the incidents an unhealthy transition opened on the
+// container, now that it reports healthy again.
+func (e *Engine) recordRecovery(st *runState, ev docker.EngineEvent) {
+	name := strings.TrimPrefix(ev.ContainerName, "/")
+	for _, rule := range st.eventRules {
+		w, ok := st.windows[rule.id+"|"+ev.Host+"|"+name]
+		if ok && w.incident != "" && w.awaitHealthy {
+			e.resolve(w, "container became healthy", true)
+		}
+	}
+}
+
+// recordDie notes a die for restart-loop detection. Clean exits count too: a
+// container that exits 0 and is restarted straight away is looping all the
+// same.
+func (e *Engine) recordDie(st *runState, ev docker.EngineEvent) {
+	if len(st.lineSubs) == 0 {
+		return // no restart-loop rules
+	}
+	key := ev.Host + "|" + strings.TrimPrefix(ev.ContainerName, "/")
+	t, ok := st.restarts[key]
+	if !ok {
+		t = &restartTracker{}
+		st.restarts[key] = t
+	}
+	t.die(e.now(), ev.ExitCode)
+}
+
+// recordStart runs a start that follows a die through every matching
+// restart-loop rule: one that finds threshold restarts within its window
+// trips the key's window, which applies the cooldown and opens or repeats
+// the incident, so a container crashing every few seconds alerts once rather
+// than on every death. The sample is the container's final log lines.
+func (e *Engine) recordStart(st *runState, ev docker.EngineEvent) {
+	name := strings.TrimPrefix(ev.ContainerName, "/")
+	t, ok := st.restarts[ev.Host+"|"+name]
+	if !ok || !t.start(e.now()) {
+		return
+	}
+	for _, rule := range st.eventRules {
+		if !rule.hasEvent(eventRestartLoop) || !rule.spec.Matches(ev.Host, name, ev.Labels) {
+			continue
+		}
+		n := t.within(rule.window, e.now())
+		if n < rule.restarts {
+			continue
+		}
+		key := rule.id + "|" + ev.Host + "|" + name
+		w := e.window(st, key, rule)
+		e.resolveIfQuiet(w)
+		res := w.observe(e.now())
+		if !res.fire {
+			continue
+		}
+		sample := e.recent.sample(key)
+		if sample == "" {
+			sample = "no log lines captured"
+		}
+		e.fire(w, rule, ev.Host, ev.ContainerID, name, models.ComposeProject(ev.Labels), restartReason(rule, n, t.exits), sample, res.suppressed)
+	}
+}
+
+// spawnStatsPoll samples the running containers any metric rule targets and
+// posts the readings back to the run loop. At most one poll is in flight; a
+// tick that finds one still running is skipped.
+func (e *Engine) spawnStatsPoll(st *runState) {
+	if len(st.metricRules) == 0 || st.polling {
+		return
+	}
+	st.polling = true
+	client := st.eventsClient
+	runCtx := st.ctx
+	rules := st.metricRules
+	selected := func(host, name string, labels map[string]string) bool {
+		for _, rule := range rules {
+			if rule.spec.Matches(host, name, labels) {
+				return true
+			}
+		}
+		return false
+	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		pctx, cancel := context.WithTimeout(runCtx, statsTimeout)
+		defer cancel()
+		samples, err := client.containerSamples(pctx, selected)
+		select {
+		case e.statsCh <- statsResult{samples: samples, err: err}:
+		case <-runCtx.Done():
+		}
+	}()
+}
+
+// handleStats runs one poll's readings through every matching metric rule:
+// each reading extends the key's series, and a reading that finds the
+// condition holding over the window trips the key's window, which applies the
+// cooldown and opens or repeats the incident like any other trip. Series for
+// containers the poll no longer saw are dropped, so a restarted container
+// starts afresh.
+func (e *Engine) handleStats(st *runState, r statsResult) {
+	if r.err != nil {
+		log.Printf("alerts: stats poll failed: %v", r.err)
+		return
+	}
+	now := e.now()
+	seen := make(map[string]bool)
+	for _, rule := range st.metricRules {
+		for _, sample := range r.samples {
+			if !rule.spec.Matches(sample.host, sample.containerName, sample.labels) {
+				continue
+			}
+			key := rule.id + "|" + sample.host + "|" + sample.containerName
+			seen[key] = true
+			series, ok := st.series[key]
+			if !ok {
+				series = newMetricSeries(rule.window)
+				st.series[key] = series
+			}
+			value, holds := series.add(rule, now, sample.value(rule.metric))
+			if !holds {
+				continue
+			}
+			w := e.window(st, key, rule)
+			e.resolveIfQuiet(w)
+			res := w.observe(now)
+			if !res.fire {
+				continue
+			}
+			e.fire(w, rule, sample.host, sample.containerID, sample.containerName, models.ComposeProject(sample.labels), metricReason(rule, value), fmt.Sprintf("%s %.1f", rule.metric, value), res.suppressed)
+		}
+	}
+	for key := range st.series {
+		if !seen[key] {
+			delete(st.series, key)
+		}
+	}
+}
+
+// spawnAbsenceCheck lists the containers any absence rule targets, running or
+// not, and posts them back to the run loop. At most one listing is in flight.
+func (e *Engine) spawnAbsenceCheck(st *runState) {
+	rules := absenceRules(st)
+	if len(rules) == 0 || st.listing {
+		return
+	}
+	st.listing = true
+	client := st.eventsClient
+	runCtx := st.ctx
+	selected := func(host, name string, labels map[string]string) bool {
+		for _, rule := range rules {
+			if rule.spec.Matches(host, name, labels) {
+				return true
+			}
+		}
+		return false
 	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		lctx, cancel := context.WithTimeout(runCtx, statsTimeout)
+		defer cancel()
+		containers, err := client.containerStates(lctx, selected)
+		select {
+		case e.listCh <- listResult{containers: containers, err: err}:
+		case <-runCtx.Done():
+		}
+	}()
+}
+
+// handleAbsence checks every absence rule against one container listing. A
+// targeted container that has sent fewer matching lines than expected over
+// the rule's window trips the key's window, which applies the cooldown and
+// opens or repeats the incident; a stopped container sends nothing, so it
+// trips once it has been tracked for a window. A container the rule names
+// but no host has is tracked too, under the rule's host when it targets
+// exactly one. Counts for containers the listing no longer saw are dropped.
+func (e *Engine) handleAbsence(st *runState, r listResult) {
+	if r.err != nil {
+		log.Printf("alerts: container listing failed: %v", r.err)
+		return
+	}
+	now := e.now()
+	seen := make(map[string]bool)
+	check := func(rule *compiledRule, c containerState) {
+		key := rule.id + "|" + c.host + "|" + c.containerName
+		seen[key] = true
+		t, ok := st.absence[key]
+		if !ok {
+			t = newAbsenceTracker(rule, now)
+			st.absence[key] = t
+		}
+		count, missing := t.missing(now)
+		if !missing {
+			return
+		}
+		w := e.window(st, key, rule)
+		e.resolveIfQuiet(w)
+		res := w.observe(now)
+		if !res.fire {
+			return
+		}
+		sample := fmt.Sprintf("%d matching lines", count)
+		if c.state != "running" {
+			sample = "container " + cmp.Or(c.state, "not found")
+		}
+		e.fire(w, rule, c.host, c.containerID, c.containerName, models.ComposeProject(c.labels), absenceReason(rule, count, c.state), sample, res.suppressed)
+	}
+	for _, rule := range absenceRules(st) {
+		found := make(map[string]bool)
+		for _, c := range r.containers {
+			if !rule.spec.Matches(c.host, c.containerName, c.labels) {
+				continue
+			}
+			found[c.containerName] = true
+			check(rule, c)
+		}
+		for _, name := range rule.spec.Containers {
+			if found[name] {
+				continue
+			}
+			missing := containerState{containerName: name}
+			if len(rule.spec.Hosts) == 1 {
+				missing.host = rule.spec.Hosts[0]
+			}
+			check(rule, missing)
+		}
+	}
+	for key := range st.absence {
+		if !seen[key] {
+			delete(st.absence, key)
+		}
+	}
+}
+
+// absenceRules returns the subscribed log rules in absence mode.
+func absenceRules(st *runState) []*compiledRule {
+	var rules []*compiledRule
+	for _, sub := range st.subs {
+		if sub.rule.absence {
+			rules = append(rules, sub.rule)
+		}
+	}
+	return rules
 }
 
 // window returns the rate/cooldown state for key, creating it from the
@@ -596,36 +1039,91 @@ func (e *Engine) window(st *runState, key string, rule *compiledRule) *ruleWindo
 	return w
 }
 
-// emit builds the alert and hands it to the dispatcher without blocking the
-// run loop; a full dispatch queue drops the alert with a log line.
-func (e *Engine) emit(rule *compiledRule, host, containerID, containerName, reason, sample string, suppressed int) {
+// fire records a window trip that got past the cooldown: it opens an incident
+// for the window's key, or repeats the one already firing. Either is handed to
+// the dispatcher without blocking the run loop.
+func (e *Engine) fire(w *ruleWindow, rule *compiledRule, host, containerID, containerName, project, reason, sample string, suppressed int) {
+	now := e.now().UTC().Format(time.RFC3339)
 	alert := models.Alert{
-		ID:            newAlertID(),
+		ID:            w.incident,
 		RuleID:        rule.id,
 		RuleName:      rule.name,
 		Type:          rule.typ,
+		Severity:      rule.severity,
 		Host:          host,
 		ContainerID:   containerID,
 		ContainerName: containerName,
+		Project:       project,
 		Reason:        reason,
 		Sample:        sample,
 		Count:         rule.threshold,
 		Suppressed:    suppressed,
-		FiredAt:       e.now().UTC().Format(time.RFC3339),
+		FiredAt:       now,
+		State:         models.AlertFiring,
+	}
+	if w.incident != "" {
+		alert.LastFiredAt = now
+		e.dispatch(dispatchMsg{kind: dispatchRepeat, alert: alert})
+		return
+	}
+	alert.ID = newAlertID()
+	if e.dispatch(dispatchMsg{kind: dispatchOpen, alert: alert}) {
+		w.incident = alert.ID
+		w.ruleName = rule.name
+	}
+}
+
+// resolve closes the window's open incident. When the dispatch queue is full
+// the incident stays open, and the next check resolves it.
+func (e *Engine) resolve(w *ruleWindow, reason string, notify bool) {
+	msg := dispatchMsg{
+		kind:   dispatchResolve,
+		alert:  models.Alert{ID: w.incident, RuleName: w.ruleName, ResolveReason: reason},
+		at:     e.now(),
+		silent: !notify,
+	}
+	if e.dispatch(msg) {
+		w.incident = ""
+		w.awaitHealthy = false
+	}
+}
+
+// resolveIfQuiet resolves the window's incident when its condition has been
+// clear for a full window. The match or event about to be observed may trip
+// the window again; that opens a new incident rather than extending one that
+// had already cleared.
+func (e *Engine) resolveIfQuiet(w *ruleWindow) {
+	if w.quiet(e.now()) {
+		e.resolve(w, fmt.Sprintf("clear for %ds", int(w.window.Seconds())), true)
+	}
+}
+
+// resolveQuiet resolves every open incident whose condition has cleared.
+func (e *Engine) resolveQuiet(st *runState) {
+	for _, w := range st.windows {
+		e.resolveIfQuiet(w)
 	}
+}
+
+// dispatch hands msg to the dispatcher without blocking; a full dispatch
+// queue drops it with a log line.
+func (e *Engine) dispatch(msg dispatchMsg) bool {
 	select {
-	case e.dispatchCh <- alert:
+	case e.dispatchCh <- msg:
+		return true
 	default:
-		log.Printf("alerts: dispatch queue full, dropping alert %s (rule %q)", alert.ID, rule.name)
+		log.Printf("alerts: dispatch queue full, dropping %s of alert %s (rule %q)", msg.kind, msg.alert.ID, msg.alert.RuleName)
+		return false
 	}
 }
 
 // pruneWindows drops rate/cooldown state for keys with no match in
-// windowIdleTTL, and oom timestamps too old to suppress a paired die.
+// windowIdleTTL and no open incident, oom timestamps too old to suppress a
+// paired die, and restart history for containers quiet for restartHistoryTTL.
 func (e *Engine) pruneWindows(st *runState) {
 	now := e.now()
 	for key, w := range st.windows {
-		if w.idleSince(now) > windowIdleTTL {
+		if w.incident == "" && w.idleSince(now) > windowIdleTTL {
 			delete(st.windows, key)
 		}
 	}
@@ -634,6 +1132,11 @@ func (e *Engine) pruneWindows(st *runState) {
 			delete(st.lastOOM, key)
 		}
 	}
+	for key, t := range st.restarts {
+		if now.Sub(t.lastSeen) > restartHistoryTTL {
+			delete(st.restarts, key)
+		}
+	}
 }
 
 // logDrops reports sink-side match drops accumulated since the last resync.
@@ -653,6 +1156,10 @@ func (e *Engine) shutdownRun(st *runState) {
 		sub.unsub()
 		delete(st.subs, id)
 	}
+	for id, unsub := range st.lineSubs {
+		unsub()
+		delete(st.lineSubs, id)
+	}
 	st.eventsCancel()
 	for {
 		select {
@@ -660,6 +1167,8 @@ func (e *Engine) shutdownRun(st *runState) {
 			// Discarded: window state is in-memory and dies with the process,
 			// so partial matches at shutdown cannot fire later anyway.
 		case <-e.inspectCh:
+		case <-e.statsCh:
+		case <-e.listCh:
 		default:
 			close(e.dispatchCh)
 			return
@@ -667,34 +1176,93 @@ func (e *Engine) shutdownRun(st *runState) {
 	}
 }
 
-// dispatchLoop consumes fired alerts: each alert is appended to history first
-// (Delivery nil) so a hung channel can never lose it, then delivered to every
-// enabled channel (read live from config; no enabled channels means
-// history-only) and its history entry updated with the summary result. It exits
-// when the run loop closes dispatchCh, then stops the history flusher, which
-// performs the final synchronous flush. Once the shutdown drain budget has
-// expired, remaining alerts are recorded as failed ("shutdown") without a
-// delivery attempt.
+// dispatchLoop consumes incident transitions in order. An opened incident is
+// routed (see routeAlert; config is read live) and appended to history with
+// its routing (Delivery nil) so a hung channel can never lose it; a repeat or
+// resolution updates its entry and goes to the channels the incident was
+// routed to. Each is queued for delivery unless the incident is silenced —
+// or, for a repeat, acknowledged — or has no channels (history-only); the
+// outbox loop updates the entry with the summary result. A fire or repeat
+// matched by an active silence or maintenance window is recorded as
+// suppressed-by-silence instead of queued; the resolution of an incident that
+// was notified still is, so downstream incidents close. It exits when the run
+// loop closes dispatchCh and the outbox loop has stopped, then stops the
+// history retention loop, which closes the database.
 func (e *Engine) dispatchLoop() {
 	defer close(e.flushStop)
-	for alert := range e.dispatchCh {
-		e.hist.append(alert)
-		channels := enabledChannels(e.alertsFn().Channels)
-		if len(channels) == 0 {
-			continue
-		}
-		var result models.DeliveryResult
-		if e.deliverCtx.Err() != nil {
-			result = models.DeliveryResult{Status: "failed", Error: "shutdown"}
-		} else {
-			ctx, cancel := context.WithTimeout(e.deliverCtx, deliverBudget)
-			result = e.notif.deliverAll(ctx, channels, alert, e.skipRetry)
-			cancel()
+	defer func() { <-e.outboxDone }()
+	for msg := range e.dispatchCh {
+		switch msg.kind {
+		case dispatchOpen:
+			cfg := e.alertsFn()
+			channels, routing := routeAlert(cfg, msg.alert)
+			msg.alert.Routing = routing
+			if by := silencedBy(cfg, msg.alert, e.now()); by != "" {
+				msg.alert.SuppressedBy = by
+				msg.alert.Delivery = &models.DeliveryResult{Status: DeliverySuppressed}
+				channels = nil
+			}
+			e.hist.append(msg.alert)
+			e.counts.fire(msg.alert)
+			e.enqueue(outboxFire, msg.alert, channels)
+		case dispatchRepeat:
+			repeat := msg.alert
+			alert, ok := e.hist.update(repeat.ID, func(a *models.Alert) {
+				a.Repeats++
+				a.LastFiredAt = repeat.LastFiredAt
+				a.Reason = repeat.Reason
+				a.Sample = repeat.Sample
+				a.Suppressed += repeat.Suppressed
+			})
+			if !ok {
+				// The history was cleared under the open incident: record the
+				// repeat as the incident's entry again.
+				alert = repeat
+				e.hist.append(alert)
+			}
+			e.counts.fire(alert)
+			if alert.AcknowledgedAt != "" || alert.SilencedAt(e.now()) {
+				continue
+			}
+			if by := silencedBy(e.alertsFn(), alert, e.now()); by != "" {
+				e.hist.update(alert.ID, func(a *models.Alert) {
+					a.SuppressedBy = by
+					if a.Delivery == nil || a.Delivery.Status == DeliverySuppressed {
+						a.Delivery = &models.DeliveryResult{Status: DeliverySuppressed}
+					}
+				})
+				continue
+			}
+			e.enqueue(outboxFire, alert, e.incidentChannels(alert))
+		case dispatchResolve:
+			alert, ok := e.hist.update(msg.alert.ID, func(a *models.Alert) {
+				resolveEntry(a, msg.at, msg.alert.ResolveReason)
+			})
+			if !ok || msg.silent || alert.SilencedAt(msg.at) || neverNotified(alert) {
+				continue
+			}
+			e.enqueue(outboxResolve, alert, e.incidentChannels(alert))
 		}
-		e.hist.setDelivery(alert.ID, result)
 	}
 }
 
+// neverNotified reports whether every fire of an incident was suppressed by a
+// silence or maintenance window, so its resolution has nothing to close.
+func neverNotified(alert models.Alert) bool {
+	return alert.Delivery != nil && alert.Delivery.Status == DeliverySuppressed
+}
+
+// incidentChannels returns the channels a repeat or resolution of alert goes
+// to. An incident opened before routing was recorded (or whose entry was
+// cleared) is routed now, and the routing stored on its entry.
+func (e *Engine) incidentChannels(alert models.Alert) []config.AlertChannel {
+	channels, routing := reroute(e.alertsFn(), alert)
+	if alert.Routing == nil && routing != nil {
+		e.hist.update(alert.ID, func(a *models.Alert) { a.Routing = routing })
+	}
+	return channels
+}
+
 // logReason renders the human-readable reason for a fired log rule, e.g.
 // "5 matches (level >= ERROR) within 60s".
 func logReason(rule *compiledRule) string {
@@ -755,11 +1323,10 @@ func entrySample(entry models.LogEntry) string {
 	return entry.Raw
 }
 
-// newAlertID returns an 8-hex-char random alert ID.
+// newAlertID returns a 128-bit random alert ID as 32 hex chars. Alert IDs key
+// a durable history of thousands of incidents, so they must not collide.
 func newAlertID() string {
-	var b [4]byte
-	if _, err := rand.Read(b[:]); err != nil {
-		return fmt.Sprintf("%08x", uint32(time.Now().UnixNano()))
-	}
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // never fails; it crashes the program instead
 	return hex.EncodeToString(b[:])
 }
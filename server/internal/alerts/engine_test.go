@@ -6,8 +6,8 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -92,6 +92,12 @@ type fakeEvents struct {
 	inspectOOM          bool
 	inspectHealthStatus string
 	inspectErr          error
+
+	// samplesMu guards samples and states, which tests change between
+	// stats polls and container listings.
+	samplesMu sync.Mutex
+	samples   []containerSample
+	states    []containerState
 }
 
 func newFakeEvents() *fakeEvents {
@@ -110,6 +116,42 @@ func (f *fakeEvents) inspectHealth(ctx context.Context, host, containerID string
 	return f.inspectHealthStatus, f.inspectErr
 }
 
+func (f *fakeEvents) containerSamples(ctx context.Context, selected func(host, name string, labels map[string]string) bool) ([]containerSample, error) {
+	f.samplesMu.Lock()
+	defer f.samplesMu.Unlock()
+	var out []containerSample
+	for _, s := range f.samples {
+		if selected(s.host, s.containerName, s.labels) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEvents) setSamples(samples ...containerSample) {
+	f.samplesMu.Lock()
+	f.samples = samples
+	f.samplesMu.Unlock()
+}
+
+func (f *fakeEvents) containerStates(ctx context.Context, selected func(host, name string, labels map[string]string) bool) ([]containerState, error) {
+	f.samplesMu.Lock()
+	defer f.samplesMu.Unlock()
+	var out []containerState
+	for _, s := range f.states {
+		if selected(s.host, s.containerName, s.labels) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEvents) setStates(states ...containerState) {
+	f.samplesMu.Lock()
+	f.states = states
+	f.samplesMu.Unlock()
+}
+
 // fakeConf is a mutable alerts config source.
 type fakeConf struct {
 	mu  sync.Mutex
@@ -163,10 +205,12 @@ func startTestEngine(t *testing.T, rules ...config.AlertRule) *testEngine {
 		events: newFakeEvents(),
 		conf:   &fakeConf{cfg: config.AlertsConfig{Rules: rules}},
 		clock:  &fakeClock{t: t0},
-		path:   filepath.Join(t.TempDir(), "alerts-history.json"),
+		path:   filepath.Join(t.TempDir(), historyFileName),
 	}
 	te.e = newEngine(te.hub, func() eventClient { return te.events }, te.conf.get, te.path)
 	te.e.now = te.clock.now
+	te.e.resolveInterval = 10 * time.Millisecond
+	te.e.statsInterval = 10 * time.Millisecond
 
 	ctx, cancel := context.WithCancel(context.Background())
 	te.cancel = cancel
@@ -195,6 +239,7 @@ func record(level models.LogLevel, msg string) logstream.Record {
 		Host:          "local",
 		ContainerID:   "abc123",
 		ContainerName: "web",
+		Labels:        map[string]string{"com.docker.compose.project": "shop"},
 		Entry:         models.LogEntry{Level: level, Message: msg, Raw: msg},
 	}
 }
@@ -218,7 +263,7 @@ func TestLogRuleFiresOnceWithCountAndFields(t *testing.T) {
 
 	waitFor(t, "alert in history", func() bool { return len(te.e.History(0)) == 1 })
 	a := te.e.History(0)[0]
-	if a.RuleID != "r1" || a.Type != "log" || a.Host != "local" || a.ContainerName != "web" || a.ContainerID != "abc123" {
+	if a.RuleID != "r1" || a.Type != "log" || a.Host != "local" || a.ContainerName != "web" || a.ContainerID != "abc123" || a.Project != "shop" {
 		t.Fatalf("alert identity wrong: %+v", a)
 	}
 	if a.Count != 3 || a.Suppressed != 0 || a.Sample != "boom" {
@@ -227,8 +272,8 @@ func TestLogRuleFiresOnceWithCountAndFields(t *testing.T) {
 	if !strings.Contains(a.Reason, "3 matches (level >= ERROR) within 60s") {
 		t.Fatalf("reason = %q", a.Reason)
 	}
-	if len(a.ID) != 8 {
-		t.Fatalf("alert ID = %q, want 8 hex chars", a.ID)
+	if len(a.ID) != 32 {
+		t.Fatalf("alert ID = %q, want 32 hex chars", a.ID)
 	}
 	if a.Delivery != nil {
 		t.Fatalf("no webhook configured but Delivery = %+v, want nil (history-only)", a.Delivery)
@@ -545,6 +590,14 @@ func TestEngineDeliversToWebhook(t *testing.T) {
 	if len(payloads) != 1 || payloads[0].Alert.RuleID != "r1" {
 		t.Fatalf("webhook payloads = %+v", payloads)
 	}
+
+	waitFor(t, "delivery counted", func() bool { return len(te.e.DeliveryCounts()) == 1 })
+	if got, want := te.e.DeliveryCounts(), []DeliveryCount{{Status: "ok", Deliveries: 1}}; !slices.Equal(got, want) {
+		t.Fatalf("DeliveryCounts = %+v, want %+v", got, want)
+	}
+	if got, want := te.e.FireCounts(), []FireCount{{RuleID: "r1", RuleName: "boom", Type: "log", Fired: 1}}; !slices.Equal(got, want) {
+		t.Fatalf("FireCounts = %+v, want %+v", got, want)
+	}
 }
 
 // webhookChannels returns a one-element enabled webhook channel list for url.
@@ -558,7 +611,7 @@ func TestTestChannelDelivery(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
 	defer srv.Close()
 
-	res := te.e.TestChannel(context.Background(), webhookChannels(srv.URL)[0])
+	res := te.e.TestChannel(context.Background(), webhookChannels(srv.URL)[0], false)
 	if res.Status != "ok" {
 		t.Fatalf("result = %+v, want ok", res)
 	}
@@ -617,13 +670,8 @@ func TestShutdownIsPromptAndFlushesHistory(t *testing.T) {
 	if te.hub.liveCount() != 0 {
 		t.Fatal("hub subscriptions not removed on shutdown")
 	}
-	data, err := os.ReadFile(te.path)
-	if err != nil {
-		t.Fatalf("history not flushed on shutdown: %v", err)
-	}
-	var onDisk []models.Alert
-	if err := json.Unmarshal(data, &onDisk); err != nil || len(onDisk) != 1 {
-		t.Fatalf("flushed history = %q (err %v), want 1 entry", data, err)
+	if got := newHistory(te.path).list(0); len(got) != 1 {
+		t.Fatalf("persisted history = %+v, want 1 entry", got)
 	}
 }
 
@@ -1,168 +1,578 @@
 package alerts
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/AmoabaKelvin/logdeck/internal/config"
 	"github.com/AmoabaKelvin/logdeck/internal/models"
+
+	_ "modernc.org/sqlite" // pure-Go driver: every release build is CGO_ENABLED=0
 )
 
 const (
-	historyCap          = 500
-	historyFlushEvery   = 2 * time.Second
+	historyFileName = "alerts.db"
+	// legacyHistoryFileName is the JSON file history lived in before it moved
+	// to SQLite. It is imported once, then renamed with legacyImportedSuffix.
+	legacyHistoryFileName = "alerts-history.json"
+	legacyImportedSuffix  = ".imported"
+
+	historyRetainEvery  = 10 * time.Minute
 	defaultHistoryLimit = 100
+	// MaxHistoryLimit caps one page of HistoryQuery results.
+	MaxHistoryLimit = 500
+	historyTimeout  = 5 * time.Second
+	// historySchemaVersion is tracked in PRAGMA user_version, as in the log
+	// store. Version 2 added the delivery outbox.
+	historySchemaVersion = 2
 )
 
-// history is a bounded in-memory store of fired alerts, newest first,
-// mirrored to a JSON file next to the config file. Appends mark the store
-// dirty; flushLoop persists at most every historyFlushEvery via an atomic
-// tmp+rename write and flushes synchronously once more on shutdown.
+// DeliveryNone selects incidents no delivery was recorded for in a
+// HistoryQuery: history-only, or still waiting on their channels.
+const DeliveryNone = "none"
+
+// ErrInvalidHistoryCursor is returned when HistoryQuery.Cursor is not a
+// cursor the history produced.
+var ErrInvalidHistoryCursor = errors.New("invalid cursor")
+
+// historySchema holds one row per incident. The incident itself is stored as
+// JSON in alert; the other columns duplicate the fields queries filter on so
+// they can be indexed. seq orders incidents newest first and anchors cursors.
+const historySchema = `
+CREATE TABLE alert_history (
+  seq       INTEGER PRIMARY KEY AUTOINCREMENT,
+  id        TEXT NOT NULL UNIQUE,
+  fired_ms  INTEGER NOT NULL,
+  rule_id   TEXT NOT NULL,
+  host      TEXT NOT NULL,
+  container TEXT NOT NULL,
+  project   TEXT NOT NULL,
+  state     TEXT NOT NULL,
+  delivery  TEXT NOT NULL, -- delivery status, '' until one is recorded
+  alert     TEXT NOT NULL
+);
+CREATE INDEX alert_history_fired ON alert_history(fired_ms);
+CREATE INDEX alert_history_rule ON alert_history(rule_id, seq);
+CREATE INDEX alert_history_container ON alert_history(host, container, seq);
+CREATE INDEX alert_history_project ON alert_history(project, seq);
+CREATE INDEX alert_history_state ON alert_history(state, seq);
+CREATE INDEX alert_history_delivery ON alert_history(delivery, seq);
+`
+
+// historyMigrations[v] upgrades a version-v database to version v+1.
+var historyMigrations = []string{historySchema, outboxSchema}
+
+// HistoryQuery selects incidents from the alert history. Every filter is
+// optional, and they combine with AND.
+type HistoryQuery struct {
+	RuleID    string
+	Host      string
+	Container string // container name
+	Project   string
+	State     string // models.AlertFiring or models.AlertResolved
+	// Delivery is the first fire's delivery status: "ok", "failed",
+	// DeliverySuppressed, or DeliveryNone.
+	Delivery string
+	Since    time.Time // fired at or after
+	Until    time.Time // fired before
+	Limit    int       // clamped to [1, MaxHistoryLimit]; 0 means the default
+	Cursor   string
+}
+
+// HistoryPage is one page of incidents, newest first. Following NextCursor
+// yields successively older pages; it is empty on the last one.
+type HistoryPage struct {
+	Alerts     []models.Alert `json:"alerts"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// history stores fired alerts in a SQLite database next to the config file.
+// Every change is written through at once; mu serializes writers so a
+// read-modify-write update cannot lose a concurrent one. When the database
+// cannot be opened, history falls back to an in-memory one so alerting keeps
+// working, and says so in the log.
 type history struct {
-	path string
-	max  int
+	db *sql.DB
+
+	mu sync.Mutex
+	// retainCh asks the retention loop for an immediate sweep; coalescing.
+	retainCh chan struct{}
+}
+
+// newHistory opens the history database at path, importing the legacy JSON
+// history beside it on first use.
+func newHistory(path string) *history {
+	h := &history{retainCh: make(chan struct{}, 1)}
+	db, err := openHistoryDB(path)
+	if err != nil {
+		log.Printf("alerts: %v; keeping alert history in memory until restart", err)
+		if db, err = openHistoryDB(":memory:"); err != nil {
+			// The in-memory database needs no file system; failing to open it
+			// means the driver itself is broken.
+			panic(fmt.Sprintf("alerts: open in-memory history: %v", err))
+		}
+	}
+	h.db = db
+	if path != ":memory:" {
+		h.importLegacy(filepath.Join(filepath.Dir(path), legacyHistoryFileName))
+	}
+	return h
+}
 
-	mu      sync.Mutex
-	entries []models.Alert // newest first
-	dirty   bool
+func openHistoryDB(path string) (*sql.DB, error) {
+	dsn := "file::memory:"
+	if path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return nil, fmt.Errorf("create alert history directory: %w", err)
+		}
+		dsn = fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)", path)
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open alert history %s: %w", path, err)
+	}
+	// History is small and its writes are serialized by history.mu, so one
+	// connection is plenty — and an in-memory database lives only as long as
+	// its connection.
+	db.SetMaxOpenConns(1)
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	if err := initHistorySchema(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open alert history %s: %w", path, err)
+	}
+	return db, nil
 }
 
-func newHistory(path string, max int) *history {
-	return &history{path: path, max: max}
+// initHistorySchema creates the schema on a fresh database, migrates an
+// older one, and is a no-op on a current one; a newer version is rejected
+// rather than downgraded.
+func initHistorySchema(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	switch {
+	case version == historySchemaVersion:
+		return nil
+	case version > historySchemaVersion:
+		return fmt.Errorf("alert history schema version %d is newer than supported version %d", version, historySchemaVersion)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for v := version; v < historySchemaVersion; v++ {
+		if _, err := tx.ExecContext(ctx, historyMigrations[v]); err != nil {
+			return fmt.Errorf("migrate schema to version %d: %w", v+1, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", historySchemaVersion)); err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+	return tx.Commit()
 }
 
-// load reads the persisted history from disk. A missing file yields an empty
-// history; a corrupt file logs a warning and starts empty. Never fatal.
-func (h *history) load() {
-	data, err := os.ReadFile(h.path)
+// importLegacy moves the JSON history an older release wrote into the
+// database, oldest first so the order is kept, and renames the file so it is
+// imported only once. A missing file is the normal case; a corrupt one is
+// logged and left in place.
+func (h *history) importLegacy(path string) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			log.Printf("alerts: failed to read history file %s: %v", h.path, err)
+			log.Printf("alerts: failed to read legacy history file %s: %v", path, err)
 		}
 		return
 	}
 	var entries []models.Alert
 	if err := json.Unmarshal(data, &entries); err != nil {
-		log.Printf("alerts: history file %s is corrupt, starting empty: %v", h.path, err)
+		log.Printf("alerts: legacy history file %s is corrupt, not importing it: %v", path, err)
 		return
 	}
-	if len(entries) > h.max {
-		entries = entries[:h.max]
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
 	h.mu.Lock()
-	h.entries = entries
-	h.mu.Unlock()
+	defer h.mu.Unlock()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("alerts: failed to import legacy history: %v", err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	// The file is newest first; an ID it repeats keeps its newest entry.
+	seen := make(map[string]bool, len(entries))
+	keep := make([]bool, len(entries))
+	for i, a := range entries {
+		keep[i] = !seen[a.ID]
+		seen[a.ID] = true
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !keep[i] {
+			continue
+		}
+		if err := insertAlert(ctx, tx, entries[i]); err != nil {
+			log.Printf("alerts: failed to import legacy history: %v", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("alerts: failed to import legacy history: %v", err)
+		return
+	}
+	if err := os.Rename(path, path+legacyImportedSuffix); err != nil {
+		log.Printf("alerts: imported legacy history but could not rename %s: %v", path, err)
+		return
+	}
+	log.Printf("alerts: imported %d alerts from %s", len(entries), path)
+}
+
+// execer is the slice of *sql.DB and *sql.Tx that writes use.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertAlert adds a as the newest incident. An ID that is already present is
+// an error rather than a replacement, so a collision never overwrites an
+// unrelated incident; changes to a stored incident go through save.
+func insertAlert(ctx context.Context, db execer, a models.Alert) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("marshal alert: %w", err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO alert_history
+		(id, fired_ms, rule_id, host, container, project, state, delivery, alert)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ID, firedMillis(a), a.RuleID, a.Host, a.ContainerName, a.Project, a.State, deliveryStatus(a), string(data))
+	if err != nil {
+		return fmt.Errorf("insert alert: %w", err)
+	}
+	return nil
+}
+
+// firedMillis is the incident's fire time in Unix milliseconds, 0 when it
+// does not parse.
+func firedMillis(a models.Alert) int64 {
+	fired, err := time.Parse(time.RFC3339, a.FiredAt)
+	if err != nil {
+		return 0
+	}
+	return fired.UnixMilli()
+}
+
+func deliveryStatus(a models.Alert) string {
+	if a.Delivery == nil {
+		return ""
+	}
+	return a.Delivery.Status
 }
 
-// append inserts an alert at the front (newest first), evicting the oldest
-// entry beyond the cap, and marks the store dirty.
+// append records a as the newest incident.
 func (h *history) append(a models.Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.entries = append(h.entries, models.Alert{})
-	copy(h.entries[1:], h.entries)
-	h.entries[0] = a
-	if len(h.entries) > h.max {
-		h.entries = h.entries[:h.max]
+	if err := insertAlert(ctx, h.db, a); err != nil {
+		log.Printf("alerts: failed to record alert %s: %v", a.ID, err)
 	}
-	h.dirty = true
 }
 
-// setDelivery records the delivery result on the entry with the given alert
-// ID and marks the store dirty. An ID that is no longer present (evicted past
-// the cap or cleared) is ignored.
-func (h *history) setDelivery(id string, result models.DeliveryResult) {
+// update applies fn to the entry with the given alert ID, writes it back, and
+// returns the updated entry. It reports false, without calling fn, when the ID
+// is no longer present.
+func (h *history) update(id string, fn func(*models.Alert)) (models.Alert, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	for i := range h.entries {
-		if h.entries[i].ID == id {
-			h.entries[i].Delivery = &result
-			h.dirty = true
-			return
+	a, ok := h.load(ctx, id)
+	if !ok {
+		return models.Alert{}, false
+	}
+	fn(&a)
+	if err := h.save(ctx, a); err != nil {
+		log.Printf("alerts: failed to update alert %s: %v", id, err)
+	}
+	return a, true
+}
+
+// save writes a back over its existing row, keeping its position.
+func (h *history) save(ctx context.Context, a models.Alert) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("marshal alert: %w", err)
+	}
+	_, err = h.db.ExecContext(ctx,
+		`UPDATE alert_history SET state = ?, delivery = ?, alert = ? WHERE id = ?`,
+		a.State, deliveryStatus(a), string(data), a.ID)
+	return err
+}
+
+// load reads one entry; the caller holds mu when it will write it back.
+func (h *history) load(ctx context.Context, id string) (models.Alert, bool) {
+	var data string
+	err := h.db.QueryRowContext(ctx, `SELECT alert FROM alert_history WHERE id = ?`, id).Scan(&data)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("alerts: failed to read alert %s: %v", id, err)
 		}
+		return models.Alert{}, false
+	}
+	var a models.Alert
+	if err := json.Unmarshal([]byte(data), &a); err != nil {
+		log.Printf("alerts: stored alert %s is corrupt: %v", id, err)
+		return models.Alert{}, false
+	}
+	return a, true
+}
+
+// get returns the entry with the given alert ID.
+func (h *history) get(id string) (models.Alert, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	return h.load(ctx, id)
+}
+
+// closeInterrupted resolves the incidents a previous run left firing. The
+// window state that would have resolved them died with that process, so
+// nothing else ever would; a condition that still holds opens a fresh
+// incident. Entries written before alerts had a lifecycle were one-shot
+// notifications and are marked resolved without an end.
+func (h *history) closeInterrupted(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	rows, err := h.db.QueryContext(ctx, `SELECT id FROM alert_history WHERE state IN ('', ?)`, models.AlertFiring)
+	if err != nil {
+		log.Printf("alerts: failed to read interrupted incidents: %v", err)
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	for _, id := range ids {
+		h.update(id, func(a *models.Alert) {
+			if a.State == "" {
+				a.State = models.AlertResolved
+				return
+			}
+			resolveEntry(a, now, "LogDeck restarted")
+		})
+	}
+}
+
+// resolveEntry marks an incident resolved at now for reason.
+func resolveEntry(a *models.Alert, now time.Time, reason string) {
+	a.State = models.AlertResolved
+	a.ResolvedAt = now.UTC().Format(time.RFC3339)
+	a.ResolveReason = reason
+	if fired, err := time.Parse(time.RFC3339, a.FiredAt); err == nil {
+		a.DurationSeconds = int64(now.Sub(fired).Seconds())
 	}
 }
 
 // list returns up to limit entries, newest first. limit <= 0 falls back to
-// defaultHistoryLimit. The result is always a non-nil copy.
+// defaultHistoryLimit. The result is never nil.
 func (h *history) list(limit int) []models.Alert {
+	page, err := h.query(context.Background(), HistoryQuery{Limit: limit})
+	if err != nil {
+		log.Printf("alerts: failed to list alert history: %v", err)
+		return []models.Alert{}
+	}
+	return page.Alerts
+}
+
+// query returns the page of incidents q selects, newest first.
+func (h *history) query(ctx context.Context, q HistoryQuery) (HistoryPage, error) {
+	limit := q.Limit
 	if limit <= 0 {
 		limit = defaultHistoryLimit
 	}
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if limit > len(h.entries) {
-		limit = len(h.entries)
+	limit = min(limit, MaxHistoryLimit)
+
+	var where []string
+	var args []any
+	for _, filter := range []struct{ column, value string }{
+		{"rule_id", q.RuleID},
+		{"host", q.Host},
+		{"container", q.Container},
+		{"project", q.Project},
+		{"state", q.State},
+	} {
+		if filter.value != "" {
+			where = append(where, filter.column+" = ?")
+			args = append(args, filter.value)
+		}
+	}
+	switch q.Delivery {
+	case "":
+	case DeliveryNone:
+		where = append(where, "delivery = ''")
+	default:
+		where = append(where, "delivery = ?")
+		args = append(args, q.Delivery)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "fired_ms >= ?")
+		args = append(args, q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "fired_ms < ?")
+		args = append(args, q.Until.UnixMilli())
+	}
+	if q.Cursor != "" {
+		seq, err := decodeHistoryCursor(q.Cursor)
+		if err != nil {
+			return HistoryPage{}, err
+		}
+		where = append(where, "seq < ?")
+		args = append(args, seq)
+	}
+
+	statement := "SELECT seq, alert FROM alert_history"
+	if len(where) > 0 {
+		statement += " WHERE " + strings.Join(where, " AND ")
+	}
+	// One row past the page says whether another page follows.
+	statement += " ORDER BY seq DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := h.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("query alert history: %w", err)
 	}
-	out := make([]models.Alert, limit)
-	copy(out, h.entries[:limit])
-	return out
+	defer rows.Close()
+	page := HistoryPage{Alerts: []models.Alert{}}
+	var lastSeq int64
+	for rows.Next() {
+		var seq int64
+		var data string
+		if err := rows.Scan(&seq, &data); err != nil {
+			return HistoryPage{}, fmt.Errorf("read alert history: %w", err)
+		}
+		if len(page.Alerts) == limit {
+			page.NextCursor = encodeHistoryCursor(lastSeq)
+			break
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(data), &a); err != nil {
+			log.Printf("alerts: skipping corrupt stored alert %d: %v", seq, err)
+			continue
+		}
+		page.Alerts = append(page.Alerts, a)
+		lastSeq = seq
+	}
+	return page, rows.Err()
 }
 
-// clear empties the history and persists the empty state immediately.
+func encodeHistoryCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString(strconv.AppendInt(nil, seq, 10))
+}
+
+func decodeHistoryCursor(cursor string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidHistoryCursor
+	}
+	seq, err := strconv.ParseInt(string(decoded), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidHistoryCursor
+	}
+	return seq, nil
+}
+
+// clear deletes every entry.
 func (h *history) clear() {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
 	h.mu.Lock()
-	h.entries = nil
-	h.dirty = true
-	h.mu.Unlock()
-	h.flushIfDirty()
-}
-
-// flushIfDirty writes the current entries to disk when there are unpersisted
-// changes. On write failure the store stays dirty so the next flush retries.
-// h.mu is held across the entire write (the payload is at most historyCap
-// small structs) so concurrent flushes cannot interleave on the shared tmp
-// file and a stale snapshot can never win the rename over a newer one.
-func (h *history) flushIfDirty() {
+	defer h.mu.Unlock()
+	if _, err := h.db.ExecContext(ctx, `DELETE FROM alert_history`); err != nil {
+		log.Printf("alerts: failed to clear alert history: %v", err)
+	}
+}
+
+// retain deletes the resolved incidents the bounds no longer keep: those
+// fired before the retention period, and those past the newest MaxEntries.
+// Firing incidents are never deleted. It returns how many rows went.
+func (h *history) retain(bounds config.AlertHistoryConfig, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if !h.dirty {
-		return
+	cutoff := now.Add(-time.Duration(bounds.RetentionDays) * 24 * time.Hour).UnixMilli()
+	aged, err := h.db.ExecContext(ctx,
+		`DELETE FROM alert_history WHERE state != ? AND fired_ms < ?`, models.AlertFiring, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired alerts: %w", err)
 	}
-	if err := writeHistoryFile(h.path, h.entries); err != nil {
-		log.Printf("alerts: failed to persist alert history: %v", err)
-		return
+	// The subquery finds the oldest incident still within MaxEntries; when
+	// there are fewer it is NULL and nothing matches.
+	excess, err := h.db.ExecContext(ctx, `DELETE FROM alert_history WHERE state != ? AND seq <
+		(SELECT seq FROM alert_history ORDER BY seq DESC LIMIT 1 OFFSET ?)`,
+		models.AlertFiring, bounds.MaxEntries-1)
+	if err != nil {
+		return 0, fmt.Errorf("delete excess alerts: %w", err)
 	}
-	h.dirty = false
+	// Dead letters go with the incidents of their age.
+	if _, err := h.db.ExecContext(ctx, `DELETE FROM alert_outbox WHERE state = ? AND created_ms < ?`, outboxDead, cutoff); err != nil {
+		return 0, fmt.Errorf("delete expired dead letters: %w", err)
+	}
+	agedRows, _ := aged.RowsAffected()
+	excessRows, _ := excess.RowsAffected()
+	return agedRows + excessRows, nil
 }
 
-// flushLoop persists dirty state at most every historyFlushEvery until stop
-// closes, then performs a final synchronous flush.
-func (h *history) flushLoop(stop <-chan struct{}) {
-	ticker := time.NewTicker(historyFlushEvery)
+// requestRetain asks retainLoop for a sweep now; requests coalesce.
+func (h *history) requestRetain() {
+	select {
+	case h.retainCh <- struct{}{}:
+	default:
+	}
+}
+
+// retainLoop applies the retention bounds, read live, on start, every
+// historyRetainEvery, and on request, until stop closes. It then closes the
+// database: by then the dispatch loop, its last writer, has exited.
+func (h *history) retainLoop(stop <-chan struct{}, bounds func() config.AlertHistoryConfig, now func() time.Time) {
+	ticker := time.NewTicker(historyRetainEvery)
 	defer ticker.Stop()
+	sweep := func() {
+		if n, err := h.retain(bounds(), now()); err != nil {
+			log.Printf("alerts: history retention failed: %v", err)
+		} else if n > 0 {
+			log.Printf("alerts: history retention removed %d resolved alerts", n)
+		}
+	}
+	sweep()
 	for {
 		select {
 		case <-ticker.C:
-			h.flushIfDirty()
+			sweep()
+		case <-h.retainCh:
+			sweep()
 		case <-stop:
-			h.flushIfDirty()
+			if err := h.db.Close(); err != nil {
+				log.Printf("alerts: failed to close alert history: %v", err)
+			}
 			return
 		}
 	}
 }
-
-// writeHistoryFile writes entries as a JSON array (never null) atomically via
-// a temp file and rename.
-func writeHistoryFile(path string, entries []models.Alert) error {
-	if entries == nil {
-		entries = []models.Alert{}
-	}
-	data, err := json.MarshalIndent(entries, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal alert history: %w", err)
-	}
-	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0600); err != nil {
-		os.Remove(tmp)
-		return fmt.Errorf("failed to write temp history file: %w", err)
-	}
-	if err := os.Rename(tmp, path); err != nil {
-		os.Remove(tmp)
-		return fmt.Errorf("failed to rename history file: %w", err)
-	}
-	return nil
-}
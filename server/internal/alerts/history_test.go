@@ -1,39 +1,35 @@
 package alerts
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
-	"sync"
 	"testing"
+	"time"
 
+	"github.com/AmoabaKelvin/logdeck/internal/config"
 	"github.com/AmoabaKelvin/logdeck/internal/models"
 )
 
 func historyPath(t *testing.T) string {
 	t.Helper()
-	return filepath.Join(t.TempDir(), "alerts-history.json")
+	return filepath.Join(t.TempDir(), historyFileName)
 }
 
-func TestHistoryCapEviction(t *testing.T) {
-	h := newHistory(historyPath(t), 3)
-	for i := 1; i <= 5; i++ {
-		h.append(models.Alert{ID: strconv.Itoa(i)})
-	}
-	got := h.list(0)
-	if len(got) != 3 {
-		t.Fatalf("len = %d, want 3", len(got))
-	}
-	for i, want := range []string{"5", "4", "3"} {
-		if got[i].ID != want {
-			t.Fatalf("entry %d = %s, want %s (newest first)", i, got[i].ID, want)
-		}
+func ids(alerts []models.Alert) []string {
+	out := make([]string, len(alerts))
+	for i, a := range alerts {
+		out[i] = a.ID
 	}
+	return out
 }
 
 func TestHistoryListLimitAndNonNil(t *testing.T) {
-	h := newHistory(historyPath(t), 500)
+	h := newHistory(historyPath(t))
 
 	if got := h.list(0); got == nil || len(got) != 0 {
 		t.Fatalf("empty list = %#v, want non-nil empty slice", got)
@@ -46,115 +42,199 @@ func TestHistoryListLimitAndNonNil(t *testing.T) {
 		h.append(models.Alert{ID: strconv.Itoa(i)})
 	}
 	if got := h.list(2); len(got) != 2 || got[0].ID != "5" {
-		t.Fatalf("list(2) = %#v, want 2 newest entries", got)
+		t.Fatalf("list(2) = %v, want the 2 newest entries", ids(got))
 	}
 	if got := h.list(0); len(got) != 5 {
 		t.Fatalf("list(0) = %d entries, want all 5 (default limit)", len(got))
 	}
 }
 
-func TestHistoryCorruptFileTolerated(t *testing.T) {
+func TestHistoryPersistsAcrossReopen(t *testing.T) {
 	path := historyPath(t)
-	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
-		t.Fatal(err)
+	h := newHistory(path)
+	h.append(models.Alert{ID: "old"})
+	h.append(models.Alert{ID: "new", State: models.AlertFiring})
+	h.update("new", func(a *models.Alert) { a.Delivery = &models.DeliveryResult{Status: "ok"} })
+	h.db.Close()
+
+	reopened := newHistory(path)
+	got := reopened.list(0)
+	if len(got) != 2 || got[0].ID != "new" || got[0].Delivery == nil || got[0].Delivery.Status != "ok" {
+		t.Fatalf("reopened = %+v, want [new old] with new's delivery", got)
 	}
-	h := newHistory(path, 500)
-	h.load()
-	if got := h.list(0); len(got) != 0 {
-		t.Fatalf("corrupt file yielded %d entries, want 0", len(got))
+}
+
+func TestHistoryDuplicateIDDoesNotOverwrite(t *testing.T) {
+	h := newHistory(historyPath(t))
+	h.append(models.Alert{ID: "a1", RuleID: "first"})
+	if err := insertAlert(context.Background(), h.db, models.Alert{ID: "a1", RuleID: "second"}); err == nil {
+		t.Fatal("inserting a duplicate ID succeeded, want an error")
 	}
-	// Store still works after a corrupt load.
-	h.append(models.Alert{ID: "a"})
-	h.flushIfDirty()
-	if got := h.list(0); len(got) != 1 {
-		t.Fatalf("append after corrupt load failed: %d entries", len(got))
+	if got := h.list(0); len(got) != 1 || got[0].RuleID != "first" {
+		t.Fatalf("history = %+v, want the first incident untouched", got)
 	}
 }
 
-func TestHistoryPersistRoundTrip(t *testing.T) {
-	path := historyPath(t)
-	h := newHistory(path, 500)
-	h.append(models.Alert{ID: "old"})
-	h.append(models.Alert{ID: "new"})
-	h.flushIfDirty()
+func TestHistoryImportsLegacyJSON(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, legacyHistoryFileName)
+	data, _ := json.Marshal([]models.Alert{{ID: "new"}, {ID: "old"}, {ID: "new", Reason: "older duplicate"}})
+	if err := os.WriteFile(legacy, data, 0600); err != nil {
+		t.Fatal(err)
+	}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		t.Fatalf("history file not written: %v", err)
+	h := newHistory(filepath.Join(dir, historyFileName))
+	if got := h.list(0); len(got) != 2 || got[0].ID != "new" || got[0].Reason != "" || got[1].ID != "old" {
+		t.Fatalf("imported = %+v, want [new old] with the newest entry of new", got)
 	}
-	var onDisk []models.Alert
-	if err := json.Unmarshal(data, &onDisk); err != nil {
-		t.Fatalf("history file is not a JSON array: %v", err)
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatalf("legacy file still present after import: %v", err)
 	}
-	if len(onDisk) != 2 || onDisk[0].ID != "new" {
-		t.Fatalf("on-disk = %#v, want [new old]", onDisk)
+	if _, err := os.Stat(legacy + legacyImportedSuffix); err != nil {
+		t.Fatalf("imported file not kept: %v", err)
 	}
 
-	reloaded := newHistory(path, 500)
-	reloaded.load()
-	if got := reloaded.list(0); len(got) != 2 || got[0].ID != "new" {
-		t.Fatalf("reload = %#v, want [new old]", got)
+	// A corrupt legacy file is left alone and the store still works.
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, legacyHistoryFileName), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	h = newHistory(filepath.Join(dir, historyFileName))
+	h.append(models.Alert{ID: "a"})
+	if got := h.list(0); len(got) != 1 {
+		t.Fatalf("append after a corrupt import: %d entries, want 1", len(got))
 	}
 }
 
-// TestHistoryClearWithConcurrentFlushNeverPersistsStale hammers clear against
-// concurrent flushes: whatever the interleaving, once clear and every
-// in-flight flush have returned, a stale pre-clear snapshot must never remain
-// on disk (a restart would resurrect cleared alerts).
-func TestHistoryClearWithConcurrentFlushNeverPersistsStale(t *testing.T) {
-	path := historyPath(t)
-	h := newHistory(path, 500)
-
-	for i := 0; i < 100; i++ {
-		h.append(models.Alert{ID: strconv.Itoa(i)})
+func TestHistoryFallsBackToMemory(t *testing.T) {
+	// A directory cannot be opened as a database.
+	h := newHistory(t.TempDir())
+	h.append(models.Alert{ID: "a"})
+	if got := h.list(0); len(got) != 1 {
+		t.Fatalf("in-memory fallback holds %d entries, want 1", len(got))
+	}
+}
 
-		var wg sync.WaitGroup
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			h.flushIfDirty()
-		}()
-		h.clear()
-		wg.Wait()
-		h.flushIfDirty() // no-op unless a flush failed and left the store dirty
-
-		data, err := os.ReadFile(path)
+func TestHistoryQueryFiltersAndPages(t *testing.T) {
+	h := newHistory(historyPath(t))
+	base := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+	for i, a := range []models.Alert{
+		{RuleID: "r1", Host: "prod", ContainerName: "web", Project: "shop", State: models.AlertResolved, Delivery: &models.DeliveryResult{Status: "ok"}},
+		{RuleID: "r2", Host: "prod", ContainerName: "db", Project: "shop", State: models.AlertResolved, Delivery: &models.DeliveryResult{Status: "failed"}},
+		{RuleID: "r1", Host: "local", ContainerName: "web", State: models.AlertResolved},
+		{RuleID: "r1", Host: "prod", ContainerName: "web", Project: "shop", State: models.AlertFiring, Delivery: &models.DeliveryResult{Status: DeliverySuppressed}},
+	} {
+		a.ID = strconv.Itoa(i + 1)
+		a.FiredAt = base.Add(time.Duration(i) * time.Hour).Format(time.RFC3339)
+		h.append(a)
+	}
+
+	for _, tc := range []struct {
+		name string
+		q    HistoryQuery
+		want []string
+	}{
+		{"rule", HistoryQuery{RuleID: "r1"}, []string{"4", "3", "1"}},
+		{"container", HistoryQuery{Host: "prod", Container: "web"}, []string{"4", "1"}},
+		{"project", HistoryQuery{Project: "shop", State: models.AlertResolved}, []string{"2", "1"}},
+		{"delivery", HistoryQuery{Delivery: "failed"}, []string{"2"}},
+		{"suppressed", HistoryQuery{Delivery: DeliverySuppressed}, []string{"4"}},
+		{"undelivered", HistoryQuery{Delivery: DeliveryNone}, []string{"3"}},
+		{"time range", HistoryQuery{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, []string{"3", "2"}},
+	} {
+		page, err := h.query(context.Background(), tc.q)
 		if err != nil {
-			t.Fatalf("iteration %d: read history file: %v", i, err)
+			t.Fatalf("%s: %v", tc.name, err)
 		}
-		var onDisk []models.Alert
-		if err := json.Unmarshal(data, &onDisk); err != nil {
-			t.Fatalf("iteration %d: history file corrupt: %v (content %q)", i, err, data)
+		if got := ids(page.Alerts); !slices.Equal(got, tc.want) || page.NextCursor != "" {
+			t.Errorf("%s: got %v (cursor %q), want %v on one page", tc.name, got, page.NextCursor, tc.want)
 		}
-		if len(onDisk) != 0 {
-			t.Fatalf("iteration %d: stale entries persisted after clear: %+v", i, onDisk)
+	}
+
+	// Following the cursor walks every incident once, newest first.
+	var walked []string
+	q := HistoryQuery{Limit: 3}
+	for {
+		page, err := h.query(context.Background(), q)
+		if err != nil {
+			t.Fatal(err)
 		}
+		walked = append(walked, ids(page.Alerts)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if !slices.Equal(walked, []string{"4", "3", "2", "1"}) {
+		t.Fatalf("paged = %v, want [4 3 2 1]", walked)
+	}
+
+	if _, err := h.query(context.Background(), HistoryQuery{Cursor: "!!"}); !errors.Is(err, ErrInvalidHistoryCursor) {
+		t.Fatalf("bad cursor: err = %v, want ErrInvalidHistoryCursor", err)
+	}
+}
+
+func TestHistoryRetainKeepsFiringIncidents(t *testing.T) {
+	h := newHistory(historyPath(t))
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+	add := func(id string, age time.Duration, state string) {
+		h.append(models.Alert{ID: id, State: state, FiredAt: now.Add(-age).Format(time.RFC3339)})
+	}
+	add("ancient-firing", 100*24*time.Hour, models.AlertFiring)
+	add("ancient", 100*24*time.Hour, models.AlertResolved)
+	add("a", 3*time.Hour, models.AlertResolved)
+	add("b", 2*time.Hour, models.AlertResolved)
+	add("c", time.Hour, models.AlertResolved)
+
+	n, err := h.retain(config.AlertHistoryConfig{RetentionDays: 90, MaxEntries: 2}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ancient is past the retention period; a is past the newest two. The
+	// firing incident is older than both bounds but still open.
+	if got := ids(h.list(0)); n != 2 || !slices.Equal(got, []string{"c", "b", "ancient-firing"}) {
+		t.Fatalf("after retain (%d removed): %v, want [c b ancient-firing]", n, got)
 	}
 }
 
-func TestHistoryClearPersistsEmptyArray(t *testing.T) {
+func TestHistoryClear(t *testing.T) {
 	path := historyPath(t)
-	h := newHistory(path, 500)
+	h := newHistory(path)
 	h.append(models.Alert{ID: "a"})
-	h.flushIfDirty()
-
 	h.clear()
-
 	if got := h.list(0); got == nil || len(got) != 0 {
 		t.Fatalf("after clear list = %#v, want non-nil empty", got)
 	}
-	data, err := os.ReadFile(path)
-	if err != nil {
-		t.Fatal(err)
+	if _, ok := h.update("a", func(*models.Alert) { t.Fatal("update ran on a cleared entry") }); ok {
+		t.Fatal("update reported a cleared entry present")
+	}
+	h.db.Close()
+	if got := newHistory(path).list(0); len(got) != 0 {
+		t.Fatalf("cleared history reopened with %d entries", len(got))
+	}
+}
+
+func TestHistoryCloseInterruptedResolvesFiringIncidents(t *testing.T) {
+	h := newHistory(historyPath(t))
+	fired := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	h.append(models.Alert{ID: "legacy", FiredAt: fired.Format(time.RFC3339)})
+	h.append(models.Alert{ID: "open", State: models.AlertFiring, FiredAt: fired.Format(time.RFC3339)})
+	h.append(models.Alert{ID: "done", State: models.AlertResolved, ResolveReason: "clear for 60s"})
+
+	h.closeInterrupted(fired.Add(2 * time.Minute))
+
+	open, _ := h.get("open")
+	if open.State != models.AlertResolved || open.ResolveReason != "LogDeck restarted" || open.DurationSeconds != 120 {
+		t.Fatalf("open = %+v, want resolved by the restart after 120s", open)
 	}
-	var onDisk []models.Alert
-	if err := json.Unmarshal(data, &onDisk); err != nil {
-		t.Fatalf("cleared file is not valid JSON: %v (content %q)", err, data)
+	if legacy, _ := h.get("legacy"); legacy.State != models.AlertResolved {
+		t.Fatalf("legacy = %+v, want entries without a state read as resolved", legacy)
 	}
-	if len(onDisk) != 0 {
-		t.Fatalf("cleared file has %d entries, want 0", len(onDisk))
+	if done, _ := h.get("done"); done.ResolveReason != "clear for 60s" {
+		t.Fatalf("done = %+v, want an already resolved incident left alone", done)
 	}
-	if string(data) == "null" {
-		t.Fatal("cleared file serialized as null, want []")
+	page, _ := h.query(context.Background(), HistoryQuery{State: models.AlertFiring})
+	if len(page.Alerts) != 0 {
+		t.Fatalf("firing after closeInterrupted = %v, want none", ids(page.Alerts))
 	}
 }
@@ -0,0 +1,87 @@
+package alerts
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+var (
+	// ErrAlertNotFound is returned for an alert ID that is not in history.
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrAlertResolved is returned when silencing an incident that has
+	// already resolved.
+	ErrAlertResolved = errors.New("alert already resolved")
+)
+
+type dispatchKind int
+
+const (
+	dispatchOpen    dispatchKind = iota // an incident started firing
+	dispatchRepeat                      // a firing incident tripped again after the cooldown
+	dispatchResolve                     // an incident's condition cleared
+)
+
+func (k dispatchKind) String() string {
+	switch k {
+	case dispatchOpen:
+		return "firing"
+	case dispatchRepeat:
+		return "repeat"
+	default:
+		return "resolution"
+	}
+}
+
+// dispatchMsg is one incident transition handed from the run loop to the
+// dispatcher.
+type dispatchMsg struct {
+	kind  dispatchKind
+	alert models.Alert
+	// at is when a resolution happened; silent resolves without a
+	// notification.
+	at     time.Time
+	silent bool
+}
+
+// Acknowledge marks an incident acknowledged by by (empty when auth is off).
+// Acknowledging stops the incident's repeat notifications; its resolution is
+// still notified. Acknowledging twice keeps the first acknowledgement.
+func (e *Engine) Acknowledge(id, by string) (models.Alert, error) {
+	now := e.now().UTC().Format(time.RFC3339)
+	alert, ok := e.hist.update(id, func(a *models.Alert) {
+		if a.AcknowledgedAt == "" {
+			a.AcknowledgedAt = now
+			a.AcknowledgedBy = by
+		}
+	})
+	if !ok {
+		return models.Alert{}, ErrAlertNotFound
+	}
+	return alert, nil
+}
+
+// Silence stops every notification of a firing incident, repeats and
+// resolution alike, for d — or until it resolves when d is not positive.
+func (e *Engine) Silence(id string, d time.Duration) (models.Alert, error) {
+	current, ok := e.hist.get(id)
+	if !ok {
+		return models.Alert{}, ErrAlertNotFound
+	}
+	if current.State != models.AlertFiring {
+		return models.Alert{}, ErrAlertResolved
+	}
+	until := ""
+	if d > 0 {
+		until = e.now().Add(d).UTC().Format(time.RFC3339)
+	}
+	alert, ok := e.hist.update(id, func(a *models.Alert) {
+		a.Silenced = true
+		a.SilencedUntil = until
+	})
+	if !ok {
+		return models.Alert{}, ErrAlertNotFound
+	}
+	return alert, nil
+}
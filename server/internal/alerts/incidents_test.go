@@ -0,0 +1,179 @@
+package alerts
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// webhookRecorder is a webhook channel that records the state of every alert
+// it receives.
+type webhookRecorder struct {
+	srv    *httptest.Server
+	mu     sync.Mutex
+	states []string
+}
+
+func newWebhookRecorder(t *testing.T) *webhookRecorder {
+	t.Helper()
+	rec := &webhookRecorder{}
+	rec.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		rec.mu.Lock()
+		rec.states = append(rec.states, p.Alert.State)
+		rec.mu.Unlock()
+	}))
+	t.Cleanup(rec.srv.Close)
+	return rec
+}
+
+func (r *webhookRecorder) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.states...)
+}
+
+// startWithWebhook starts an engine for rules that notifies rec.
+func startWithWebhook(t *testing.T, rec *webhookRecorder, rules ...config.AlertRule) *testEngine {
+	t.Helper()
+	te := startTestEngine(t, rules...)
+	te.conf.set(config.AlertsConfig{Channels: webhookChannels(rec.srv.URL), Rules: rules})
+	return te
+}
+
+func TestIncidentResolvesAfterAQuietWindow(t *testing.T) {
+	rec := newWebhookRecorder(t)
+	te := startWithWebhook(t, rec, config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1, WindowSeconds: 60, CooldownSeconds: 1})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "firing notification", func() bool { return len(rec.received()) == 1 })
+	if a := te.e.History(0)[0]; a.State != models.AlertFiring || a.ResolvedAt != "" {
+		t.Fatalf("incident = %+v, want it firing", a)
+	}
+
+	te.clock.advance(90 * time.Second)
+	waitFor(t, "resolved notification", func() bool { return len(rec.received()) == 2 })
+	if got := rec.received(); got[1] != models.AlertResolved {
+		t.Fatalf("notifications = %q, want firing then resolved", got)
+	}
+	waitFor(t, "resolve delivery recorded", func() bool { return te.e.History(0)[0].ResolveDelivery != nil })
+	a := te.e.History(0)[0]
+	if a.State != models.AlertResolved || a.ResolveReason != "clear for 60s" || a.DurationSeconds != 90 || a.ResolveDelivery.Status != "ok" {
+		t.Fatalf("incident = %+v, want resolved after 90s with the resolution delivered", a)
+	}
+
+	// The next trip opens a new incident.
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "second incident", func() bool { return len(te.e.History(0)) == 2 })
+	if a := te.e.History(0)[0]; a.State != models.AlertFiring {
+		t.Fatalf("newest incident = %+v, want firing", a)
+	}
+}
+
+func TestUnhealthyIncidentWaitsForHealthy(t *testing.T) {
+	te := startTestEngine(t, config.AlertRule{ID: "e1", Name: "unhealthy", Enabled: true, Type: "event", Events: []string{"unhealthy"}, Threshold: 1})
+
+	te.events.ch <- docker.EngineEvent{Host: "local", ContainerID: "c1", ContainerName: "web", Action: "health_status: unhealthy", HealthStatus: "unhealthy"}
+	waitFor(t, "unhealthy incident", func() bool { return len(te.e.History(0)) == 1 })
+
+	// An unhealthy container stays silent, so quiet is not recovery.
+	te.clock.advance(10 * time.Minute)
+	time.Sleep(50 * time.Millisecond)
+	if a := te.e.History(0)[0]; a.State != models.AlertFiring {
+		t.Fatalf("incident = %+v, want it still firing while the container is unhealthy", a)
+	}
+
+	te.events.ch <- docker.EngineEvent{Host: "local", ContainerID: "c1", ContainerName: "web", Action: "health_status: healthy", HealthStatus: "healthy"}
+	waitFor(t, "resolution", func() bool { return te.e.History(0)[0].State == models.AlertResolved })
+	if a := te.e.History(0)[0]; a.ResolveReason != "container became healthy" || a.DurationSeconds != 600 {
+		t.Fatalf("incident = %+v, want resolved by the healthy transition after 600s", a)
+	}
+}
+
+func TestRepeatsFoldIntoTheIncidentAndAcknowledgeQuietsThem(t *testing.T) {
+	rec := newWebhookRecorder(t)
+	te := startWithWebhook(t, rec, config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1, WindowSeconds: 600, CooldownSeconds: 1})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "firing notification", func() bool { return len(rec.received()) == 1 })
+
+	te.clock.advance(2 * time.Second)
+	te.hub.emit(record(models.LogLevelError, "boom again"))
+	waitFor(t, "repeat notification", func() bool { return len(rec.received()) == 2 })
+	h := te.e.History(0)
+	if len(h) != 1 || h[0].Repeats != 1 || h[0].Sample != "boom again" || h[0].LastFiredAt == "" {
+		t.Fatalf("history = %+v, want one incident that repeated once", h)
+	}
+
+	a, err := te.e.Acknowledge(h[0].ID, "admin")
+	if err != nil || a.AcknowledgedBy != "admin" || a.AcknowledgedAt == "" {
+		t.Fatalf("Acknowledge = %+v, %v", a, err)
+	}
+	te.clock.advance(2 * time.Second)
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "second repeat", func() bool { return te.e.History(0)[0].Repeats == 2 })
+	time.Sleep(50 * time.Millisecond)
+	if got := rec.received(); len(got) != 2 {
+		t.Fatalf("notifications = %q, want no repeat after the acknowledgement", got)
+	}
+
+	if _, err := te.e.Acknowledge("missing", ""); !errors.Is(err, ErrAlertNotFound) {
+		t.Fatalf("Acknowledge(missing) err = %v, want ErrAlertNotFound", err)
+	}
+}
+
+func TestSilencedIncidentResolvesWithoutNotifying(t *testing.T) {
+	rec := newWebhookRecorder(t)
+	te := startWithWebhook(t, rec, config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1, WindowSeconds: 60})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "firing notification", func() bool { return len(rec.received()) == 1 })
+	id := te.e.History(0)[0].ID
+	if a, err := te.e.Silence(id, 0); err != nil || !a.Silenced || a.SilencedUntil != "" {
+		t.Fatalf("Silence = %+v, %v; want silenced until resolved", a, err)
+	}
+
+	te.clock.advance(61 * time.Second)
+	waitFor(t, "resolution", func() bool { return te.e.History(0)[0].State == models.AlertResolved })
+	time.Sleep(50 * time.Millisecond)
+	if got := rec.received(); len(got) != 1 {
+		t.Fatalf("notifications = %q, want the silenced resolution kept quiet", got)
+	}
+	if _, err := te.e.Silence(id, time.Hour); !errors.Is(err, ErrAlertResolved) {
+		t.Fatalf("Silence(resolved) err = %v, want ErrAlertResolved", err)
+	}
+}
+
+func TestChangedRuleClosesItsIncidentQuietly(t *testing.T) {
+	rec := newWebhookRecorder(t)
+	rule := config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1}
+	te := startWithWebhook(t, rec, rule)
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "firing notification", func() bool { return len(rec.received()) == 1 })
+
+	rule.Pattern = "bang"
+	te.conf.set(config.AlertsConfig{Channels: webhookChannels(rec.srv.URL), Rules: []config.AlertRule{rule}})
+	te.e.Reconcile()
+	waitFor(t, "resolution", func() bool { return te.e.History(0)[0].State == models.AlertResolved })
+	if a := te.e.History(0)[0]; a.ResolveReason != "rule changed or removed" {
+		t.Fatalf("incident = %+v, want it closed by the rule change", a)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := rec.received(); len(got) != 1 {
+		t.Fatalf("notifications = %q, want no resolution notification", got)
+	}
+}
@@ -0,0 +1,159 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+)
+
+// Metrics a metric rule can watch.
+const (
+	metricCPUPercent    = "cpu_percent"
+	metricMemoryPercent = "memory_percent"
+)
+
+func validMetric(metric string) bool {
+	return metric == metricCPUPercent || metric == metricMemoryPercent
+}
+
+// containerSample is one stats poll's reading for one running container.
+type containerSample struct {
+	host          string
+	containerID   string
+	containerName string
+	labels        map[string]string
+
+	cpuPercent    float64
+	memoryPercent float64
+}
+
+func (s containerSample) value(metric string) float64 {
+	if metric == metricMemoryPercent {
+		return s.memoryPercent
+	}
+	return s.cpuPercent
+}
+
+// containerSamples reads CPU and memory for the running containers selected
+// reports true for. An unreachable host contributes nothing rather than
+// failing the poll, and neither does a container whose stats read fails.
+func (a dockerEventAdapter) containerSamples(ctx context.Context, selected func(host, name string, labels map[string]string) bool) ([]containerSample, error) {
+	containersMap, _, err := a.c.ListContainersAllHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type containerKey struct{ host, id string }
+	running := make(map[containerKey]containerSample)
+	var ids []docker.ContainerIdentifier
+	for host, containers := range containersMap {
+		for _, c := range containers {
+			if c.State != "running" || len(c.Names) == 0 {
+				continue
+			}
+			name := strings.TrimPrefix(c.Names[0], "/")
+			if !selected(host, name, c.Labels) {
+				continue
+			}
+			running[containerKey{host, c.ID}] = containerSample{host: host, containerID: c.ID, containerName: name, labels: c.Labels}
+			ids = append(ids, docker.ContainerIdentifier{Host: host, ID: c.ID})
+		}
+	}
+
+	samples := make([]containerSample, 0, len(ids))
+	for _, stat := range a.c.GetBulkContainerStats(ctx, ids) {
+		sample, ok := running[containerKey{stat.Host, stat.ID}]
+		if !ok {
+			continue
+		}
+		sample.cpuPercent = stat.CPUPercent
+		sample.memoryPercent = stat.MemoryPercent
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// metricPoint is one reading in a metricSeries.
+type metricPoint struct {
+	at    time.Time
+	value float64
+}
+
+// metricSeries is the recent readings of one metric for one (rule, host,
+// container) key, and decides whether the rule's condition holds over its
+// window. Like ruleWindow it takes explicit now values and is owned by the
+// run loop.
+type metricSeries struct {
+	window time.Duration
+
+	// points holds the readings inside the window, oldest first; start is
+	// when the series' first reading was taken, and breachSince when the
+	// current unbroken run of breaching readings began (zero when the last
+	// reading did not breach).
+	points      []metricPoint
+	start       time.Time
+	breachSince time.Time
+}
+
+func newMetricSeries(window time.Duration) *metricSeries {
+	return &metricSeries{window: window}
+}
+
+// add records one reading at now and reports whether the rule's condition
+// holds, along with the value the condition was judged on: the reading itself
+// for a sustained rule, the window average for an averaged one. Neither
+// holds before the series spans a full window.
+func (s *metricSeries) add(rule *compiledRule, now time.Time, value float64) (float64, bool) {
+	if s.start.IsZero() {
+		s.start = now
+	}
+	s.points = append(s.points, metricPoint{at: now, value: value})
+	drop := 0
+	for drop < len(s.points) && now.Sub(s.points[drop].at) > s.window {
+		drop++
+	}
+	s.points = s.points[drop:]
+
+	if !rule.averaged {
+		if !rule.breaches(value) {
+			s.breachSince = time.Time{}
+			return value, false
+		}
+		if s.breachSince.IsZero() {
+			s.breachSince = now
+		}
+		return value, now.Sub(s.breachSince) >= s.window
+	}
+
+	var sum float64
+	for _, p := range s.points {
+		sum += p.value
+	}
+	avg := sum / float64(len(s.points))
+	return avg, now.Sub(s.start) >= s.window && rule.breaches(avg)
+}
+
+// breaches reports whether value is on the alerting side of the rule's limit.
+func (r *compiledRule) breaches(value float64) bool {
+	if r.below {
+		return value < r.limit
+	}
+	return value > r.limit
+}
+
+// metricReason renders the human-readable reason for a fired metric rule,
+// e.g. "memory_percent > 90 for 300s (now 93.2)".
+func metricReason(rule *compiledRule, value float64) string {
+	op := ">"
+	if rule.below {
+		op = "<"
+	}
+	window := int(rule.window.Seconds())
+	if rule.averaged {
+		return fmt.Sprintf("%s averaged %.1f over %ds (%s %g)", rule.metric, value, window, op, rule.limit)
+	}
+	return fmt.Sprintf("%s %s %g for %ds (now %.1f)", rule.metric, op, rule.limit, window, value)
+}
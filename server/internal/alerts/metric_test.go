@@ -0,0 +1,114 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+func TestMetricSeriesSustained(t *testing.T) {
+	rule := &compiledRule{metric: metricMemoryPercent, limit: 90}
+	s := newMetricSeries(time.Minute)
+
+	steps := []struct {
+		after time.Duration
+		value float64
+		holds bool
+	}{
+		{0, 95, false},
+		{30 * time.Second, 92, false},
+		{60 * time.Second, 93, true}, // above 90 for the whole minute
+		{75 * time.Second, 80, false},
+		{90 * time.Second, 95, false}, // the dip restarted the clock
+		{150 * time.Second, 96, true},
+	}
+	for _, step := range steps {
+		value, holds := s.add(rule, t0.Add(step.after), step.value)
+		if holds != step.holds || value != step.value {
+			t.Fatalf("at +%s: add(%v) = %v, %v; want %v, %v", step.after, step.value, value, holds, step.value, step.holds)
+		}
+	}
+}
+
+func TestMetricSeriesAveraged(t *testing.T) {
+	rule := &compiledRule{metric: metricCPUPercent, limit: 200, averaged: true}
+	s := newMetricSeries(2 * time.Minute)
+
+	for i, v := range []float64{300, 100, 250, 150} {
+		if _, holds := s.add(rule, t0.Add(time.Duration(i)*30*time.Second), v); holds {
+			t.Fatalf("reading %d: holds before the series covers the window", i)
+		}
+	}
+	// 100 + 250 + 150 + 330 over the last two minutes averages 207.5; the
+	// first reading has aged out.
+	avg, holds := s.add(rule, t0.Add(150*time.Second), 330)
+	if !holds || avg != 207.5 {
+		t.Fatalf("add = %v, %v; want 207.5, true", avg, holds)
+	}
+}
+
+func TestMetricSeriesBelow(t *testing.T) {
+	rule := &compiledRule{metric: metricCPUPercent, limit: 1, below: true}
+	s := newMetricSeries(10 * time.Second)
+	s.add(rule, t0, 0.5)
+	if _, holds := s.add(rule, t0.Add(10*time.Second), 0.2); !holds {
+		t.Fatal("below rule did not hold")
+	}
+}
+
+func TestMetricReason(t *testing.T) {
+	sustained := &compiledRule{metric: metricMemoryPercent, limit: 90, window: 5 * time.Minute}
+	if got, want := metricReason(sustained, 93.24), "memory_percent > 90 for 300s (now 93.2)"; got != want {
+		t.Errorf("sustained reason = %q, want %q", got, want)
+	}
+	averaged := &compiledRule{metric: metricCPUPercent, limit: 200, window: 2 * time.Minute, averaged: true}
+	if got, want := metricReason(averaged, 215.3), "cpu_percent averaged 215.3 over 120s (> 200)"; got != want {
+		t.Errorf("averaged reason = %q, want %q", got, want)
+	}
+}
+
+func TestMetricRuleFiresAndResolves(t *testing.T) {
+	rule := config.AlertRule{ID: "m1", Name: "memory", Enabled: true, Type: "metric", Metric: "memory_percent", Operator: ">", Value: 90, WindowSeconds: 60, Containers: []string{"web"}}
+	te := startTestEngine(t, rule)
+	web := containerSample{host: "local", containerID: "c1", containerName: "web", memoryPercent: 95, cpuPercent: 300}
+	other := containerSample{host: "local", containerID: "c2", containerName: "db", memoryPercent: 99}
+	te.events.setSamples(web, other)
+
+	// Let a few polls see the breach start before the window elapses.
+	time.Sleep(50 * time.Millisecond)
+	if n := len(te.e.History(0)); n != 0 {
+		t.Fatalf("history has %d entries before the condition held for the window", n)
+	}
+
+	te.clock.advance(61 * time.Second)
+	waitFor(t, "metric incident", func() bool { return len(te.e.History(0)) == 1 })
+	a := te.e.History(0)[0]
+	if a.Type != "metric" || a.ContainerName != "web" || a.Reason != "memory_percent > 90 for 60s (now 95.0)" || a.Sample != "memory_percent 95.0" {
+		t.Fatalf("incident = %+v, want one memory incident for web only", a)
+	}
+
+	web.memoryPercent = 40
+	te.events.setSamples(web, other)
+	time.Sleep(50 * time.Millisecond)
+	te.clock.advance(61 * time.Second)
+	waitFor(t, "resolution", func() bool { return te.e.History(0)[0].State == models.AlertResolved })
+	if a := te.e.History(0)[0]; a.ResolveReason != "clear for 60s" {
+		t.Fatalf("incident = %+v, want it resolved by the quiet window", a)
+	}
+}
+
+func TestCompileRulesMetric(t *testing.T) {
+	rules := compileRules(config.AlertsConfig{Rules: []config.AlertRule{
+		{ID: "m", Enabled: true, Type: "metric", Metric: "cpu_percent", Operator: "<", Value: 5, Aggregate: "avg", Threshold: 9},
+		{ID: "bad", Enabled: true, Type: "metric", Metric: "disk_percent"},
+	}})
+	if len(rules) != 1 {
+		t.Fatalf("compiled %d rules, want the unknown metric skipped", len(rules))
+	}
+	m := rules[0]
+	if m.metric != "cpu_percent" || !m.below || m.limit != 5 || !m.averaged || m.threshold != 1 || m.window != defaultWindow {
+		t.Fatalf("compiled metric rule = %+v", m)
+	}
+}
@@ -0,0 +1,88 @@
+package alerts
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// FireCount is how many alerts one rule has fired since the engine started.
+type FireCount struct {
+	RuleID   string
+	RuleName string
+	Type     string
+	Fired    int64
+}
+
+// DeliveryCount is how many fired alerts ended with one delivery status.
+type DeliveryCount struct {
+	Status     string
+	Deliveries int64
+}
+
+// fireCounts accumulates the counters the metrics endpoint reads. The
+// dispatcher writes them; scrapes read them from request goroutines.
+type fireCounts struct {
+	mu         sync.Mutex
+	fired      map[FireCount]int64 // keyed with Fired left zero
+	deliveries map[string]int64
+}
+
+func (c *fireCounts) fire(alert models.Alert) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fired == nil {
+		c.fired = make(map[FireCount]int64)
+	}
+	c.fired[FireCount{RuleID: alert.RuleID, RuleName: alert.RuleName, Type: alert.Type}]++
+}
+
+func (c *fireCounts) deliver(result models.DeliveryResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deliveries == nil {
+		c.deliveries = make(map[string]int64)
+	}
+	c.deliveries[result.Status]++
+}
+
+// FireCounts reports the alerts fired per rule, ordered by rule ID. A rule
+// renamed while running counts under each name it fired with.
+func (e *Engine) FireCounts() []FireCount {
+	e.counts.mu.Lock()
+	counts := make([]FireCount, 0, len(e.counts.fired))
+	for key, n := range e.counts.fired {
+		key.Fired = n
+		counts = append(counts, key)
+	}
+	e.counts.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].RuleID != counts[j].RuleID {
+			return counts[i].RuleID < counts[j].RuleID
+		}
+		return counts[i].RuleName < counts[j].RuleName
+	})
+	return counts
+}
+
+// DeliveryCounts reports fired alerts per delivery status, ordered by status.
+// Alerts fired while no channel is enabled are not delivered and not counted.
+func (e *Engine) DeliveryCounts() []DeliveryCount {
+	e.counts.mu.Lock()
+	counts := make([]DeliveryCount, 0, len(e.counts.deliveries))
+	for status, n := range e.counts.deliveries {
+		counts = append(counts, DeliveryCount{Status: status, Deliveries: n})
+	}
+	e.counts.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Status < counts[j].Status })
+	return counts
+}
+
+// MatchDrops reports how many rule matches were discarded because the
+// evaluation backlog was full.
+func (e *Engine) MatchDrops() uint64 {
+	return e.matchDrops.Load()
+}
@@ -2,13 +2,15 @@ package alerts
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/AmoabaKelvin/logdeck/internal/config"
@@ -18,11 +20,16 @@ import (
 const (
 	deliverTimeout = 10 * time.Second
 	retryDelay     = 5 * time.Second
-	// gotifyPriority is the default message priority sent to Gotify servers.
-	gotifyPriority = 5
-	// alertTitle is the notification title used by channel types that carry one
-	// out of band (ntfy Title header, Gotify title field).
-	alertTitle = "LogDeck alert"
+	// alertTitle and resolvedTitle are the notification titles used by
+	// channel types that carry one out of band (ntfy Title header, Gotify
+	// title field).
+	alertTitle    = "LogDeck alert"
+	resolvedTitle = "LogDeck alert resolved"
+	// pagerDutyEventsURL is the Events API v2 endpoint a PagerDuty channel
+	// posts to unless it overrides the URL; pagerDutySummaryLen is the API's
+	// limit on an event summary.
+	pagerDutyEventsURL  = "https://events.pagerduty.com/v2/enqueue"
+	pagerDutySummaryLen = 1024
 )
 
 // webhookPayload is the JSON body POSTed to a generic webhook channel. Text and
@@ -50,6 +57,61 @@ type telegramPayload struct {
 	Text   string `json:"text"`
 }
 
+// pagerDutyEvent is the JSON body POSTed to the PagerDuty Events API v2. A
+// resolve carries only the routing and dedup keys; the dedup key is derived
+// from the incident ID, so it closes the PagerDuty incident the trigger opened.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // "trigger" | "resolve"
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// matrixMessage is the m.room.message event body PUT to a Matrix homeserver.
+type matrixMessage struct {
+	MsgType string `json:"msgtype"`
+	Body    string `json:"body"`
+}
+
+// teamsMessage is the JSON body POSTed to a Microsoft Teams workflow webhook:
+// a message with a single Adaptive Card attachment.
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []teamsTextBlock `json:"body"`
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Color  string `json:"color,omitempty"`
+}
+
 // requestSpec is the fully-resolved HTTP request for one channel delivery.
 type requestSpec struct {
 	method  string
@@ -76,6 +138,9 @@ func redactSecret(msg, secret string) string {
 type notifier struct {
 	client     *http.Client
 	retryDelay time.Duration
+	// mailRoots verifies SMTP servers' STARTTLS certificates; nil uses the
+	// system roots.
+	mailRoots *x509.CertPool
 }
 
 func newNotifier() *notifier {
@@ -85,23 +150,66 @@ func newNotifier() *notifier {
 	}
 }
 
-// alertText builds the human-readable summary for an alert.
+// alertText builds the human-readable summary for an alert: what fired, or for
+// a resolved incident, why it cleared and how long it lasted.
 func alertText(a models.Alert) string {
 	target := a.Host
 	if a.ContainerName != "" {
 		target += "/" + a.ContainerName
 	}
+	if a.State == models.AlertResolved {
+		text := fmt.Sprintf("LogDeck resolved: %s: %s", a.RuleName, a.ResolveReason)
+		if target != "" {
+			text += " (" + target + ")"
+		}
+		return text + fmt.Sprintf(" after %s", time.Duration(a.DurationSeconds)*time.Second)
+	}
 	if target != "" {
 		return fmt.Sprintf("LogDeck alert: %s: %s (%s)", a.RuleName, a.Reason, target)
 	}
 	return fmt.Sprintf("LogDeck alert: %s: %s", a.RuleName, a.Reason)
 }
 
-// buildRequestSpec resolves the HTTP request for delivering alert to channel.
-func buildRequestSpec(ch config.AlertChannel, alert models.Alert) (requestSpec, error) {
-	text := alertText(alert)
+// severityPriority maps an alert's severity to the ntfy and Gotify message
+// priorities. A resolution is always sent at the default (low) priority.
+func severityPriority(alert models.Alert) (ntfy string, gotify int) {
+	if alert.State == models.AlertResolved {
+		return "default", 2
+	}
+	switch alert.Severity {
+	case SeverityCritical:
+		return "urgent", 8
+	case SeverityInfo:
+		return "default", 2
+	default:
+		return "high", 5
+	}
+}
+
+// buildRequestSpec resolves the HTTP request for delivering alert to channel,
+// rendering the channel's templates when it has any. key identifies the
+// delivery: every attempt at it passes the same key.
+func buildRequestSpec(ch config.AlertChannel, alert models.Alert, key string) (requestSpec, error) {
+	msg, err := renderMessage(ch, alert)
+	if err != nil {
+		return requestSpec{}, fmt.Errorf("template: %v", err)
+	}
+	text, title := msg.text, msg.title
+	priority, gotify := severityPriority(alert)
+	tags := "warning"
+	if alert.State == models.AlertResolved {
+		tags = "white_check_mark"
+	}
 	switch ch.Type {
 	case "webhook":
+		if msg.raw != nil {
+			return requestSpec{
+				method:  http.MethodPost,
+				url:     ch.URL,
+				headers: map[string]string{"Content-Type": "application/json"},
+				body:    msg.raw,
+			}, nil
+		}
 		body, err := json.Marshal(webhookPayload{
 			Source:  "logdeck",
 			Version: 1,
@@ -123,14 +231,14 @@ func buildRequestSpec(ch config.AlertChannel, alert models.Alert) (requestSpec,
 			method: http.MethodPost,
 			url:    ch.URL,
 			headers: map[string]string{
-				"Title":    alertTitle,
-				"Priority": "high",
-				"Tags":     "warning",
+				"Title":    title,
+				"Priority": priority,
+				"Tags":     tags,
 			},
 			body: []byte(text),
 		}, nil
 	case "gotify":
-		body, err := json.Marshal(gotifyPayload{Title: alertTitle, Message: text, Priority: gotifyPriority})
+		body, err := json.Marshal(gotifyPayload{Title: title, Message: text, Priority: gotify})
 		if err != nil {
 			return requestSpec{}, fmt.Errorf("failed to marshal payload: %v", err)
 		}
@@ -145,6 +253,90 @@ func buildRequestSpec(ch config.AlertChannel, alert models.Alert) (requestSpec,
 			},
 			body: body,
 		}, nil
+	case "pagerduty":
+		event := pagerDutyEvent{
+			RoutingKey:  ch.Token,
+			EventAction: "trigger",
+			DedupKey:    "logdeck-" + alert.ID,
+		}
+		if alert.State == models.AlertResolved {
+			event.EventAction = "resolve"
+		} else {
+			event.Payload = &pagerDutyPayload{
+				Summary:   truncateRunes(text, pagerDutySummaryLen),
+				Source:    cmp.Or(alert.Host, "logdeck"),
+				Severity:  cmp.Or(alert.Severity, SeverityWarning),
+				Timestamp: alert.FiredAt,
+				Component: alert.ContainerName,
+				Group:     alert.Project,
+				Class:     alert.Type,
+				CustomDetails: map[string]string{
+					"rule":   alert.RuleName,
+					"reason": alert.Reason,
+					"sample": alert.Sample,
+				},
+			}
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			return requestSpec{}, fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		return requestSpec{
+			method:  http.MethodPost,
+			url:     cmp.Or(ch.URL, pagerDutyEventsURL),
+			headers: map[string]string{"Content-Type": "application/json"},
+			body:    body,
+		}, nil
+	case "matrix":
+		body, err := json.Marshal(matrixMessage{MsgType: "m.text", Body: text})
+		if err != nil {
+			return requestSpec{}, fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		// The transaction ID makes the send idempotent: a retry of the same
+		// delivery reuses it, so the homeserver posts the message once.
+		txnID := "logdeck-" + key
+		return requestSpec{
+			method: http.MethodPut,
+			url: strings.TrimRight(ch.URL, "/") + "/_matrix/client/v3/rooms/" +
+				url.PathEscape(ch.Target) + "/send/m.room.message/" + txnID,
+			headers: map[string]string{
+				"Content-Type":  "application/json",
+				"Authorization": "Bearer " + ch.Token,
+			},
+			body:   body,
+			secret: ch.Token,
+		}, nil
+	case "teams":
+		color := "attention"
+		if alert.State == models.AlertResolved {
+			color = "good"
+		}
+		body, err := json.Marshal(teamsMessage{
+			Type: "message",
+			Attachments: []teamsAttachment{{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: teamsCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body: []teamsTextBlock{
+						{Type: "TextBlock", Text: title, Wrap: true, Weight: "Bolder", Size: "Medium", Color: color},
+						{Type: "TextBlock", Text: text, Wrap: true},
+					},
+				},
+			}},
+		})
+		if err != nil {
+			return requestSpec{}, fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		// Workflow URLs carry their signature in the query string.
+		return requestSpec{
+			method:  http.MethodPost,
+			url:     ch.URL,
+			headers: map[string]string{"Content-Type": "application/json"},
+			body:    body,
+			secret:  ch.URL,
+		}, nil
 	case "telegram":
 		body, err := json.Marshal(telegramPayload{ChatID: ch.Target, Text: text})
 		if err != nil {
@@ -162,8 +354,10 @@ func buildRequestSpec(ch config.AlertChannel, alert models.Alert) (requestSpec,
 	}
 }
 
+// channelOutcome is one channel's final result for a notification, under the
+// channel's label.
 type channelOutcome struct {
-	channel config.AlertChannel
+	channel string
 	result  models.DeliveryResult
 }
 
@@ -177,28 +371,10 @@ func enabledChannels(channels []config.AlertChannel) []config.AlertChannel {
 	return out
 }
 
-// deliverAll delivers alert to every channel and returns a single summary
-// result: "ok" when all succeeded, otherwise "failed" with an error naming each
-// channel that failed. Channels are delivered concurrently so one slow channel
-// (up to timeout + retry) cannot exhaust the shared delivery budget and starve
-// the rest. Callers pass the already-enabled channels.
-func (n *notifier) deliverAll(ctx context.Context, channels []config.AlertChannel, alert models.Alert, skip <-chan struct{}) models.DeliveryResult {
-	outcomes := make([]channelOutcome, len(channels))
-	var wg sync.WaitGroup
-	for i, ch := range channels {
-		wg.Add(1)
-		go func(i int, ch config.AlertChannel) {
-			defer wg.Done()
-			outcomes[i] = channelOutcome{channel: ch, result: n.deliver(ctx, ch, alert, skip)}
-		}(i, ch)
-	}
-	wg.Wait()
-	return summarizeDeliveries(outcomes)
-}
-
-// summarizeDeliveries collapses per-channel outcomes into one history result.
-// The HTTP status is carried through only when a single channel was attempted,
-// where it is unambiguous.
+// summarizeDeliveries collapses per-channel outcomes into one history result:
+// "ok" when all succeeded, otherwise "failed" with an error naming each
+// channel that failed. The HTTP status is carried through only when a single
+// channel was attempted, where it is unambiguous.
 func summarizeDeliveries(outcomes []channelOutcome) models.DeliveryResult {
 	var failed []string
 	for _, o := range outcomes {
@@ -209,7 +385,7 @@ func summarizeDeliveries(outcomes []channelOutcome) models.DeliveryResult {
 		if detail == "" {
 			detail = o.result.Status
 		}
-		failed = append(failed, channelLabel(o.channel)+": "+detail)
+		failed = append(failed, o.channel+": "+detail)
 	}
 	result := models.DeliveryResult{Status: "ok"}
 	if len(failed) > 0 {
@@ -228,16 +404,18 @@ func channelLabel(ch config.AlertChannel) string {
 	return ch.Type
 }
 
-// deliver sends the alert to one channel. Network errors and 5xx responses are
-// retried once after retryDelay; other statuses are permanent. Closing skip
-// (shutdown) or ctx aborts the retry wait and returns the first result.
+// deliver sends the alert to one channel. Network errors, 5xx responses, and
+// transient (4xx) SMTP replies are retried once after retryDelay; other
+// failures are permanent. Closing skip or ctx aborts the retry wait and
+// returns the first result. Channel tests deliver this way; incidents go
+// through the outbox, which retries with backoff instead.
 func (n *notifier) deliver(ctx context.Context, ch config.AlertChannel, alert models.Alert, skip <-chan struct{}) models.DeliveryResult {
-	spec, err := buildRequestSpec(ch, alert)
+	attempt, err := n.prepare(ch, alert, newAlertID())
 	if err != nil {
 		return models.DeliveryResult{Status: "failed", Error: err.Error()}
 	}
 
-	result, retryable := n.attempt(ctx, spec)
+	result, retryable := attempt(ctx)
 	if !retryable {
 		return result
 	}
@@ -248,10 +426,38 @@ func (n *notifier) deliver(ctx context.Context, ch config.AlertChannel, alert mo
 	case <-ctx.Done():
 		return result
 	}
-	result, _ = n.attempt(ctx, spec)
+	result, _ = attempt(ctx)
 	return result
 }
 
+// attemptOnce makes a single attempt at delivering alert to ch. The bool
+// reports whether a failure is worth retrying. key identifies the delivery
+// across attempts (see buildRequestSpec).
+func (n *notifier) attemptOnce(ctx context.Context, ch config.AlertChannel, alert models.Alert, key string) (models.DeliveryResult, bool) {
+	attempt, err := n.prepare(ch, alert, key)
+	if err != nil {
+		return models.DeliveryResult{Status: "failed", Error: err.Error()}, false
+	}
+	return attempt(ctx)
+}
+
+// prepare resolves the request or email for delivering alert to ch and
+// returns a function performing one attempt at it.
+func (n *notifier) prepare(ch config.AlertChannel, alert models.Alert, key string) (func(context.Context) (models.DeliveryResult, bool), error) {
+	if ch.Type == "smtp" {
+		spec, err := buildMailSpec(ch, alert)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (models.DeliveryResult, bool) { return n.attemptMail(ctx, spec) }, nil
+	}
+	spec, err := buildRequestSpec(ch, alert, key)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) (models.DeliveryResult, bool) { return n.attempt(ctx, spec) }, nil
+}
+
 // attempt performs one request and classifies the outcome. The bool reports
 // whether the failure is retryable (network error or 5xx).
 func (n *notifier) attempt(ctx context.Context, spec requestSpec) (models.DeliveryResult, bool) {
@@ -40,6 +40,7 @@ type capturedRequest struct {
 	priority    string
 	tags        string
 	gotifyKey   string
+	auth        string
 	body        []byte
 }
 
@@ -54,6 +55,7 @@ func newCapturingServer(t *testing.T, captured *capturedRequest) *httptest.Serve
 		captured.priority = r.Header.Get("Priority")
 		captured.tags = r.Header.Get("Tags")
 		captured.gotifyKey = r.Header.Get("X-Gotify-Key")
+		captured.auth = r.Header.Get("Authorization")
 		captured.body, _ = io.ReadAll(r.Body)
 	}))
 }
@@ -118,6 +120,27 @@ func TestDeliverNtfyPlainBodyWithTitle(t *testing.T) {
 	}
 }
 
+func TestDeliverResolvedIncident(t *testing.T) {
+	var cap capturedRequest
+	srv := newCapturingServer(t, &cap)
+	defer srv.Close()
+
+	resolved := testAlert()
+	resolved.State = models.AlertResolved
+	resolved.ResolveReason = "clear for 60s"
+	resolved.DurationSeconds = 330
+	ch := config.AlertChannel{ID: "c1", Type: "ntfy", Enabled: true, URL: srv.URL + "/mytopic"}
+	if res := newNotifier().deliver(context.Background(), ch, resolved, nil); res.Status != "ok" {
+		t.Fatalf("result = %+v, want ok", res)
+	}
+	if cap.title != resolvedTitle || cap.tags != "white_check_mark" || cap.priority != "default" {
+		t.Fatalf("headers = %q/%q/%q, want the resolved title, tag, and default priority", cap.title, cap.tags, cap.priority)
+	}
+	if want := "LogDeck resolved: High error rate: clear for 60s (local/web) after 5m30s"; string(cap.body) != want {
+		t.Fatalf("body = %q, want %q", cap.body, want)
+	}
+}
+
 func TestDeliverGotifyJSONWithToken(t *testing.T) {
 	var cap capturedRequest
 	srv := newCapturingServer(t, &cap)
@@ -147,7 +170,7 @@ func TestDeliverGotifyJSONWithToken(t *testing.T) {
 	if err := json.Unmarshal(cap.body, &payload); err != nil {
 		t.Fatalf("payload is not valid JSON: %v", err)
 	}
-	if payload.Title != alertTitle || payload.Message != alertText(testAlert()) || payload.Priority != gotifyPriority {
+	if payload.Title != alertTitle || payload.Message != alertText(testAlert()) || payload.Priority != 5 {
 		t.Fatalf("gotify payload = %+v", payload)
 	}
 }
@@ -160,7 +183,7 @@ func TestDeliverTelegramSendMessage(t *testing.T) {
 	defer srv.Close()
 
 	ch := config.AlertChannel{ID: "c1", Type: "telegram", Enabled: true, Token: "bot42:secret", Target: "-1001234"}
-	spec, err := buildRequestSpec(ch, testAlert())
+	spec, err := buildRequestSpec(ch, testAlert(), "k")
 	if err != nil {
 		t.Fatalf("buildRequestSpec: %v", err)
 	}
@@ -181,6 +204,98 @@ func TestDeliverTelegramSendMessage(t *testing.T) {
 	}
 }
 
+func TestDeliverPagerDutyTriggerAndResolve(t *testing.T) {
+	var cap capturedRequest
+	srv := newCapturingServer(t, &cap)
+	defer srv.Close()
+
+	ch := config.AlertChannel{ID: "c1", Type: "pagerduty", Enabled: true, URL: srv.URL + "/v2/enqueue", Token: "routing-key"}
+	alert := testAlert()
+	alert.Project = "shop"
+	if res := newNotifier().deliver(context.Background(), ch, alert, nil); res.Status != "ok" {
+		t.Fatalf("result = %+v, want ok", res)
+	}
+	var trigger pagerDutyEvent
+	if err := json.Unmarshal(cap.body, &trigger); err != nil {
+		t.Fatalf("payload is not valid JSON: %v", err)
+	}
+	if cap.path != "/v2/enqueue" || trigger.RoutingKey != "routing-key" || trigger.EventAction != "trigger" || trigger.DedupKey != "logdeck-abcd1234" {
+		t.Fatalf("trigger = %+v at %s", trigger, cap.path)
+	}
+	p := trigger.Payload
+	if p == nil || p.Summary != alertText(alert) || p.Source != "local" || p.Component != "web" || p.Group != "shop" || p.Severity != "warning" {
+		t.Fatalf("trigger payload = %+v", p)
+	}
+
+	// The resolve carries the same dedup key, which closes the incident.
+	alert.State = models.AlertResolved
+	if res := newNotifier().deliver(context.Background(), ch, alert, nil); res.Status != "ok" {
+		t.Fatalf("result = %+v, want ok", res)
+	}
+	var resolve pagerDutyEvent
+	if err := json.Unmarshal(cap.body, &resolve); err != nil {
+		t.Fatalf("payload is not valid JSON: %v", err)
+	}
+	if resolve.EventAction != "resolve" || resolve.DedupKey != trigger.DedupKey || resolve.Payload != nil {
+		t.Fatalf("resolve = %+v", resolve)
+	}
+
+	spec, err := buildRequestSpec(config.AlertChannel{Type: "pagerduty", Token: "k"}, testAlert(), "k")
+	if err != nil || spec.url != pagerDutyEventsURL {
+		t.Fatalf("default url = %q (%v), want %s", spec.url, err, pagerDutyEventsURL)
+	}
+}
+
+func TestDeliverMatrixRoomMessage(t *testing.T) {
+	var cap capturedRequest
+	srv := newCapturingServer(t, &cap)
+	defer srv.Close()
+
+	ch := config.AlertChannel{ID: "c1", Type: "matrix", Enabled: true, URL: srv.URL + "/", Token: "syt_token", Target: "!room:example.org"}
+	if res := newNotifier().deliver(context.Background(), ch, testAlert(), nil); res.Status != "ok" {
+		t.Fatalf("result = %+v, want ok", res)
+	}
+	prefix := "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/logdeck-"
+	if cap.method != http.MethodPut || !strings.HasPrefix(cap.path, prefix) || len(cap.path) == len(prefix) {
+		t.Fatalf("request = %s %s, want PUT %s<txn>", cap.method, cap.path, prefix)
+	}
+	if cap.auth != "Bearer syt_token" {
+		t.Fatalf("Authorization = %q", cap.auth)
+	}
+	var msg matrixMessage
+	if err := json.Unmarshal(cap.body, &msg); err != nil {
+		t.Fatalf("payload is not valid JSON: %v", err)
+	}
+	if msg.MsgType != "m.text" || msg.Body != alertText(testAlert()) {
+		t.Fatalf("message = %+v", msg)
+	}
+}
+
+func TestDeliverTeamsAdaptiveCard(t *testing.T) {
+	var cap capturedRequest
+	srv := newCapturingServer(t, &cap)
+	defer srv.Close()
+
+	ch := config.AlertChannel{ID: "c1", Type: "teams", Enabled: true, URL: srv.URL + "/workflows/run?sig=abc"}
+	if res := newNotifier().deliver(context.Background(), ch, testAlert(), nil); res.Status != "ok" {
+		t.Fatalf("result = %+v, want ok", res)
+	}
+	if cap.rawQuery != "sig=abc" {
+		t.Fatalf("query = %q, want the workflow signature kept", cap.rawQuery)
+	}
+	var msg teamsMessage
+	if err := json.Unmarshal(cap.body, &msg); err != nil {
+		t.Fatalf("payload is not valid JSON: %v", err)
+	}
+	if msg.Type != "message" || len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("message = %+v", msg)
+	}
+	card := msg.Attachments[0].Content
+	if card.Type != "AdaptiveCard" || len(card.Body) != 2 || card.Body[0].Text != alertTitle || card.Body[1].Text != alertText(testAlert()) {
+		t.Fatalf("card = %+v", card)
+	}
+}
+
 // failingRoundTripper fails every request, letting the http.Client wrap the
 // request URL (which for Telegram carries the bot token) in the *url.Error the
 // caller sees.
@@ -208,25 +323,16 @@ func TestDeliveryErrorRedactsTelegramToken(t *testing.T) {
 	}
 }
 
-func TestDeliverAllSummarizesFailures(t *testing.T) {
-	okSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
-	defer okSrv.Close()
-	badSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusBadRequest)
-	}))
-	defer badSrv.Close()
-
-	channels := []config.AlertChannel{
-		{ID: "c1", Type: "webhook", Name: "good", Enabled: true, URL: okSrv.URL},
-		{ID: "c2", Type: "webhook", Name: "bad", Enabled: true, URL: badSrv.URL},
-	}
-	n := newNotifier()
-	res := n.deliverAll(context.Background(), channels, testAlert(), nil)
+func TestSummarizeDeliveriesNamesFailedChannels(t *testing.T) {
+	res := summarizeDeliveries([]channelOutcome{
+		{channel: "good", result: models.DeliveryResult{Status: "ok", HTTPStatus: http.StatusOK}},
+		{channel: "bad", result: models.DeliveryResult{Status: "failed", HTTPStatus: http.StatusBadRequest, Error: "channel returned 400 Bad Request"}},
+	})
 
 	if res.Status != "failed" {
 		t.Fatalf("status = %q, want failed", res.Status)
 	}
-	if !strings.Contains(res.Error, "bad") {
+	if !strings.Contains(res.Error, "bad: channel returned 400") {
 		t.Fatalf("error %q should name the failed channel", res.Error)
 	}
 	if strings.Contains(res.Error, "good:") {
@@ -234,16 +340,9 @@ func TestDeliverAllSummarizesFailures(t *testing.T) {
 	}
 }
 
-func TestDeliverAllAllOK(t *testing.T) {
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
-	defer srv.Close()
-
-	channels := []config.AlertChannel{
-		{ID: "c1", Type: "webhook", Enabled: true, URL: srv.URL},
-		{ID: "c2", Type: "ntfy", Enabled: true, URL: srv.URL + "/t"},
-	}
-	n := newNotifier()
-	res := n.deliverAll(context.Background(), channels, testAlert(), nil)
+func TestSummarizeDeliveriesAllOK(t *testing.T) {
+	ok := models.DeliveryResult{Status: "ok", HTTPStatus: http.StatusOK}
+	res := summarizeDeliveries([]channelOutcome{{channel: "a", result: ok}, {channel: "b", result: ok}})
 	if res.Status != "ok" {
 		t.Fatalf("result = %+v, want ok", res)
 	}
@@ -251,6 +350,9 @@ func TestDeliverAllAllOK(t *testing.T) {
 	if res.HTTPStatus != 0 {
 		t.Fatalf("httpStatus = %d, want 0 for a multi-channel summary", res.HTTPStatus)
 	}
+	if res := summarizeDeliveries([]channelOutcome{{channel: "a", result: ok}}); res.HTTPStatus != http.StatusOK {
+		t.Fatalf("single-channel httpStatus = %d, want 200", res.HTTPStatus)
+	}
 }
 
 func TestDeliverRetriesOnceOn5xx(t *testing.T) {
@@ -0,0 +1,540 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+const (
+	// outboxBaseDelay is how long a channel backs off after a retryable
+	// failure; each further consecutive failure doubles it, up to
+	// outboxMaxDelay.
+	outboxBaseDelay = 5 * time.Second
+	outboxMaxDelay  = 5 * time.Minute
+	// outboxIdleWait is how long the outbox loop sleeps with nothing queued
+	// when it is not woken by a new notification.
+	outboxIdleWait = time.Minute
+)
+
+// Outbox entry kinds name the incident field an entry's result goes to:
+// Delivery for a fire or repeat, ResolveDelivery for a resolution.
+const (
+	outboxFire    = "fire"
+	outboxResolve = "resolve"
+)
+
+// Outbox entry states.
+const (
+	outboxPending = "pending"
+	outboxOK      = "ok"
+	outboxFailed  = "failed"
+	outboxDead    = "dead"
+)
+
+// DeliveryRetrying is the delivery status of an incident whose notification
+// failed on a channel that is backing off, before it is retried.
+const DeliveryRetrying = "retrying"
+
+// outboxSchema holds the notifications waiting on their channels and each
+// channel's delivery record. A notification to several channels is one entry
+// per channel sharing a batch; once every entry has finished, the summary
+// result is written to the incident and the batch deleted, except for dead
+// letters, which are kept until history retention removes them.
+const outboxSchema = `
+CREATE TABLE alert_outbox (
+  seq          INTEGER PRIMARY KEY AUTOINCREMENT,
+  batch        TEXT NOT NULL,
+  kind         TEXT NOT NULL, -- 'fire' | 'resolve'
+  alert_id     TEXT NOT NULL,
+  channel_id   TEXT NOT NULL,
+  channel_name TEXT NOT NULL,
+  alert        TEXT NOT NULL, -- the incident as it is notified
+  created_ms   INTEGER NOT NULL,
+  attempts     INTEGER NOT NULL DEFAULT 0,
+  state        TEXT NOT NULL, -- 'pending' | 'ok' | 'failed' | 'dead'
+  result       TEXT NOT NULL DEFAULT '' -- the last attempt's result
+);
+CREATE INDEX alert_outbox_state ON alert_outbox(state, seq);
+CREATE INDEX alert_outbox_batch ON alert_outbox(batch);
+CREATE TABLE alert_channel_health (
+  channel_id      TEXT PRIMARY KEY,
+  last_success_ms INTEGER NOT NULL DEFAULT 0,
+  last_failure_ms INTEGER NOT NULL DEFAULT 0,
+  last_error      TEXT NOT NULL DEFAULT '',
+  failures        INTEGER NOT NULL DEFAULT 0, -- consecutive
+  retry_ms        INTEGER NOT NULL DEFAULT 0  -- backing off until
+);
+`
+
+// outboxEntry is one queued notification of an incident to one channel.
+type outboxEntry struct {
+	seq       int64
+	batch     string
+	kind      string
+	channelID string
+	channel   string // the channel's label when the entry was queued
+	alert     models.Alert
+	created   time.Time
+	attempts  int
+	result    models.DeliveryResult // the last attempt's
+}
+
+// key identifies the entry's delivery across attempts, so channels that
+// deduplicate (Matrix) post it once.
+func (o outboxEntry) key() string {
+	return o.batch + "-" + o.channelID
+}
+
+// channelBackoff is how long a channel waits after its failures-th
+// consecutive failure.
+func channelBackoff(failures int) time.Duration {
+	d := outboxBaseDelay
+	for i := 1; i < failures && d < outboxMaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, outboxMaxDelay)
+}
+
+// enqueue queues a notification of alert to each channel as one batch.
+func (h *history) enqueue(kind string, alert models.Alert, channels []config.AlertChannel, now time.Time) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("marshal alert: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	batch := newAlertID()
+	for _, ch := range channels {
+		_, err := tx.ExecContext(ctx, `INSERT INTO alert_outbox
+			(batch, kind, alert_id, channel_id, channel_name, alert, created_ms, state)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			batch, kind, alert.ID, ch.ID, channelLabel(ch), string(data), now.UnixMilli(), outboxPending)
+		if err != nil {
+			return fmt.Errorf("queue notification: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// pendingDeliveries returns the queued entries in the order they were queued.
+func (h *history) pendingDeliveries(ctx context.Context) ([]outboxEntry, error) {
+	rows, err := h.db.QueryContext(ctx, `SELECT seq, batch, kind, channel_id, channel_name, alert, created_ms, attempts, result
+		FROM alert_outbox WHERE state = ? ORDER BY seq`, outboxPending)
+	if err != nil {
+		return nil, fmt.Errorf("read delivery outbox: %w", err)
+	}
+	defer rows.Close()
+	var entries []outboxEntry
+	for rows.Next() {
+		var e outboxEntry
+		var alert, result string
+		var created int64
+		if err := rows.Scan(&e.seq, &e.batch, &e.kind, &e.channelID, &e.channel, &alert, &created, &e.attempts, &result); err != nil {
+			return nil, fmt.Errorf("read delivery outbox: %w", err)
+		}
+		if err := json.Unmarshal([]byte(alert), &e.alert); err != nil {
+			log.Printf("alerts: skipping corrupt queued notification %d: %v", e.seq, err)
+			continue
+		}
+		if result != "" {
+			_ = json.Unmarshal([]byte(result), &e.result)
+		}
+		e.created = time.UnixMilli(created)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// channelRetries returns when each backing-off channel may next be attempted.
+func (h *history) channelRetries(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := h.db.QueryContext(ctx, `SELECT channel_id, retry_ms FROM alert_channel_health WHERE retry_ms > 0`)
+	if err != nil {
+		return nil, fmt.Errorf("read channel health: %w", err)
+	}
+	defer rows.Close()
+	retries := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var retry int64
+		if err := rows.Scan(&id, &retry); err != nil {
+			return nil, fmt.Errorf("read channel health: %w", err)
+		}
+		retries[id] = time.UnixMilli(retry)
+	}
+	return retries, rows.Err()
+}
+
+// channelSucceeded records a delivery to the channel at now, ending any
+// failure streak and backoff.
+func (h *history) channelSucceeded(id string, now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.db.ExecContext(ctx, `INSERT INTO alert_channel_health (channel_id, last_success_ms) VALUES (?, ?)
+		ON CONFLICT (channel_id) DO UPDATE SET last_success_ms = excluded.last_success_ms, failures = 0, retry_ms = 0`,
+		id, now.UnixMilli())
+	if err != nil {
+		log.Printf("alerts: failed to record health of channel %s: %v", id, err)
+	}
+}
+
+// channelFailed records a failed delivery to the channel at now. A
+// retryable failure backs the channel off; the time it may be attempted
+// again is returned. Otherwise the zero time is.
+func (h *history) channelFailed(id, errText string, now time.Time, retryable bool) time.Time {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var failures int
+	err := h.db.QueryRowContext(ctx, `INSERT INTO alert_channel_health (channel_id, last_failure_ms, last_error, failures) VALUES (?, ?, ?, 1)
+		ON CONFLICT (channel_id) DO UPDATE SET last_failure_ms = excluded.last_failure_ms, last_error = excluded.last_error, failures = failures + 1
+		RETURNING failures`,
+		id, now.UnixMilli(), errText).Scan(&failures)
+	if err != nil {
+		log.Printf("alerts: failed to record health of channel %s: %v", id, err)
+		failures = 1
+	}
+	if !retryable {
+		return time.Time{}
+	}
+	retryAt := now.Add(channelBackoff(failures))
+	if _, err := h.db.ExecContext(ctx, `UPDATE alert_channel_health SET retry_ms = ? WHERE channel_id = ?`, retryAt.UnixMilli(), id); err != nil {
+		log.Printf("alerts: failed to record health of channel %s: %v", id, err)
+	}
+	return retryAt
+}
+
+// retryLater records a failed attempt at an entry that stays queued, and
+// marks its incident's delivery as retrying.
+func (h *history) retryLater(e outboxEntry, result models.DeliveryResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	data, _ := json.Marshal(result)
+	if _, err := h.db.ExecContext(ctx, `UPDATE alert_outbox SET attempts = ?, result = ? WHERE seq = ?`, e.attempts, string(data), e.seq); err != nil {
+		log.Printf("alerts: failed to record delivery attempt: %v", err)
+	}
+	h.setOutboxResult(ctx, e, models.DeliveryResult{
+		Status:     DeliveryRetrying,
+		HTTPStatus: result.HTTPStatus,
+		Error:      e.channel + ": " + result.Error,
+	})
+}
+
+// finishDelivery records an entry's final state and result. When it was the
+// last of its batch to finish, the batch's summary result is written to the
+// incident and returned with true, and the batch's entries other than dead
+// letters are deleted.
+func (h *history) finishDelivery(e outboxEntry, state string, result models.DeliveryResult) (models.DeliveryResult, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	data, _ := json.Marshal(result)
+	if _, err := h.db.ExecContext(ctx, `UPDATE alert_outbox SET state = ?, attempts = ?, result = ? WHERE seq = ?`,
+		state, e.attempts, string(data), e.seq); err != nil {
+		log.Printf("alerts: failed to record delivery of alert %s: %v", e.alert.ID, err)
+		return models.DeliveryResult{}, false
+	}
+
+	rows, err := h.db.QueryContext(ctx, `SELECT channel_name, state, result FROM alert_outbox WHERE batch = ? ORDER BY seq`, e.batch)
+	if err != nil {
+		log.Printf("alerts: failed to read delivery of alert %s: %v", e.alert.ID, err)
+		return models.DeliveryResult{}, false
+	}
+	var outcomes []channelOutcome
+	pending := false
+	for rows.Next() {
+		var o channelOutcome
+		var entryState, entryResult string
+		if err := rows.Scan(&o.channel, &entryState, &entryResult); err != nil {
+			rows.Close()
+			log.Printf("alerts: failed to read delivery of alert %s: %v", e.alert.ID, err)
+			return models.DeliveryResult{}, false
+		}
+		pending = pending || entryState == outboxPending
+		_ = json.Unmarshal([]byte(entryResult), &o.result)
+		outcomes = append(outcomes, o)
+	}
+	rows.Close()
+	if pending {
+		return models.DeliveryResult{}, false
+	}
+
+	summary := summarizeDeliveries(outcomes)
+	if _, err := h.db.ExecContext(ctx, `DELETE FROM alert_outbox WHERE batch = ? AND state != ?`, e.batch, outboxDead); err != nil {
+		log.Printf("alerts: failed to remove delivered notification: %v", err)
+	}
+	h.setOutboxResult(ctx, e, summary)
+	return summary, true
+}
+
+// setOutboxResult writes result to the delivery field of the entry's incident
+// its kind names. An incident that is no longer present is ignored. The
+// caller holds mu.
+func (h *history) setOutboxResult(ctx context.Context, e outboxEntry, result models.DeliveryResult) {
+	a, ok := h.load(ctx, e.alert.ID)
+	if !ok {
+		return
+	}
+	if e.kind == outboxResolve {
+		a.ResolveDelivery = &result
+	} else {
+		a.Delivery = &result
+	}
+	if err := h.save(ctx, a); err != nil {
+		log.Printf("alerts: failed to update alert %s: %v", a.ID, err)
+	}
+}
+
+// channelHealth returns the delivery record of every channel that has one,
+// keyed by channel ID.
+func (h *history) channelHealth(ctx context.Context, now time.Time) (map[string]models.ChannelHealth, error) {
+	health := make(map[string]models.ChannelHealth)
+	rows, err := h.db.QueryContext(ctx, `SELECT channel_id, last_success_ms, last_failure_ms, last_error, failures, retry_ms FROM alert_channel_health`)
+	if err != nil {
+		return nil, fmt.Errorf("read channel health: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		var success, failure, retry int64
+		var ch models.ChannelHealth
+		if err := rows.Scan(&id, &success, &failure, &ch.LastError, &ch.ConsecutiveFailures, &retry); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("read channel health: %w", err)
+		}
+		ch.LastSuccessAt = formatMillis(success)
+		ch.LastFailureAt = formatMillis(failure)
+		if retry > now.UnixMilli() {
+			ch.RetryAt = formatMillis(retry)
+		}
+		health[id] = ch
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read channel health: %w", err)
+	}
+
+	rows, err = h.db.QueryContext(ctx, `SELECT channel_id, state, COUNT(*) FROM alert_outbox
+		WHERE state IN (?, ?) GROUP BY channel_id, state`, outboxPending, outboxDead)
+	if err != nil {
+		return nil, fmt.Errorf("read delivery outbox: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, state string
+		var n int
+		if err := rows.Scan(&id, &state, &n); err != nil {
+			return nil, fmt.Errorf("read delivery outbox: %w", err)
+		}
+		ch := health[id]
+		if state == outboxPending {
+			ch.Pending = n
+		} else {
+			ch.DeadLettered = n
+		}
+		health[id] = ch
+	}
+	return health, rows.Err()
+}
+
+// formatMillis renders Unix milliseconds as RFC 3339, and 0 as "".
+func formatMillis(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339)
+}
+
+// enqueue queues a notification of alert to channels and wakes the outbox
+// loop. With no channels the incident is history-only and nothing is queued.
+func (e *Engine) enqueue(kind string, alert models.Alert, channels []config.AlertChannel) {
+	if len(channels) == 0 {
+		return
+	}
+	if err := e.hist.enqueue(kind, alert, channels, e.now()); err != nil {
+		log.Printf("alerts: failed to queue notification of alert %s (rule %q): %v", alert.ID, alert.RuleName, err)
+		return
+	}
+	e.pokeOutbox()
+}
+
+// pokeOutbox wakes the outbox loop; pokes coalesce.
+func (e *Engine) pokeOutbox() {
+	select {
+	case e.outboxCh <- struct{}{}:
+	default:
+	}
+}
+
+// ChannelHealth reports each channel's delivery record, keyed by channel ID.
+// Channels never delivered to are absent.
+func (e *Engine) ChannelHealth(ctx context.Context) (map[string]models.ChannelHealth, error) {
+	return e.hist.channelHealth(ctx, e.now())
+}
+
+// outboxLoop delivers queued notifications until ctx is cancelled: on start,
+// whenever a notification is queued, and when a backing-off channel or the
+// delivery deadline falls due. Entries still queued at shutdown stay in the
+// database, and the next run delivers them.
+func (e *Engine) outboxLoop(ctx context.Context) {
+	defer close(e.outboxDone)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-e.outboxCh:
+		}
+		wait := outboxIdleWait
+		if next := e.deliverDue(ctx); !next.IsZero() {
+			wait = max(next.Sub(e.now()), 10*time.Millisecond)
+		}
+		timer.Reset(wait)
+	}
+}
+
+// deliverDue dead-letters the entries past the delivery deadline and attempts
+// every other entry whose channel is not backing off. Channels are delivered
+// concurrently, each channel's entries in order, so one slow or failing
+// channel holds up only its own queue. It returns when the next entry falls
+// due, or the zero time when none is queued.
+func (e *Engine) deliverDue(ctx context.Context) time.Time {
+	readCtx, cancel := context.WithTimeout(ctx, historyTimeout)
+	entries, err := e.hist.pendingDeliveries(readCtx)
+	var retries map[string]time.Time
+	if err == nil {
+		retries, err = e.hist.channelRetries(readCtx)
+	}
+	cancel()
+	if err != nil {
+		log.Printf("alerts: %v", err)
+		return e.now().Add(outboxBaseDelay)
+	}
+
+	cfg := e.alertsFn()
+	deadline := cfg.DeliveryDeadline()
+	now := e.now()
+	queues := make(map[string][]outboxEntry)
+	for _, entry := range entries {
+		if now.Sub(entry.created) >= deadline {
+			e.deadLetter(entry, deadline)
+			continue
+		}
+		queues[entry.channelID] = append(queues[entry.channelID], entry)
+	}
+
+	var (
+		mu   sync.Mutex
+		next time.Time
+		wg   sync.WaitGroup
+	)
+	due := func(t time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	for id, queue := range queues {
+		// The oldest entry is the first to reach the deadline.
+		due(queue[0].created.Add(deadline))
+		if retry := retries[id]; retry.After(now) {
+			due(retry)
+			continue
+		}
+		var channel *config.AlertChannel
+		for i := range cfg.Channels {
+			if cfg.Channels[i].ID == id {
+				channel = &cfg.Channels[i]
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			due(e.deliverQueue(ctx, channel, queue))
+		}()
+	}
+	wg.Wait()
+	return next
+}
+
+// deliverQueue attempts one channel's entries in order; channel is nil when
+// it was deleted. A retryable failure stops it and returns when the channel
+// may be attempted again; otherwise it returns the zero time.
+func (e *Engine) deliverQueue(ctx context.Context, channel *config.AlertChannel, queue []outboxEntry) time.Time {
+	for _, entry := range queue {
+		if ctx.Err() != nil {
+			return time.Time{}
+		}
+		switch {
+		case channel == nil:
+			e.finishDelivery(entry, outboxFailed, models.DeliveryResult{Status: "failed", Error: "channel was deleted"})
+			continue
+		case !channel.Enabled:
+			e.finishDelivery(entry, outboxFailed, models.DeliveryResult{Status: "failed", Error: "channel is disabled"})
+			continue
+		}
+		result, retryable := e.notif.attemptOnce(e.deliverCtx, *channel, entry.alert, entry.key())
+		if e.deliverCtx.Err() != nil {
+			// Cut off by shutdown: the entry stays queued for the next run.
+			return time.Time{}
+		}
+		entry.attempts++
+		now := e.now()
+		if result.Status == "ok" {
+			e.hist.channelSucceeded(channel.ID, now)
+			e.finishDelivery(entry, outboxOK, result)
+			continue
+		}
+		retryAt := e.hist.channelFailed(channel.ID, result.Error, now, retryable)
+		if retryable {
+			e.hist.retryLater(entry, result)
+			return retryAt
+		}
+		e.finishDelivery(entry, outboxFailed, result)
+	}
+	return time.Time{}
+}
+
+// deadLetter gives up on an entry its channel did not accept within the
+// delivery deadline.
+func (e *Engine) deadLetter(entry outboxEntry, deadline time.Duration) {
+	log.Printf("alerts: giving up on delivering alert %s (rule %q) to channel %q after %s", entry.alert.ID, entry.alert.RuleName, entry.channel, deadline)
+	result := models.DeliveryResult{
+		Status:     "failed",
+		HTTPStatus: entry.result.HTTPStatus,
+		Error:      fmt.Sprintf("gave up after %s", deadline),
+	}
+	if entry.result.Error != "" {
+		result.Error += ": " + entry.result.Error
+	}
+	e.finishDelivery(entry, outboxDead, result)
+}
+
+// finishDelivery records an entry's final result; the batch's summary, once
+// complete, is counted for the metrics endpoint.
+func (e *Engine) finishDelivery(entry outboxEntry, state string, result models.DeliveryResult) {
+	if summary, done := e.hist.finishDelivery(entry, state, result); done {
+		e.counts.deliver(summary)
+	}
+}
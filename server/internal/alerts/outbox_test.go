@@ -0,0 +1,245 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// flakyServer answers 503 while down is set and 200 otherwise, counting
+// requests.
+type flakyServer struct {
+	*httptest.Server
+	down atomic.Bool
+	hits atomic.Int32
+}
+
+func newFlakyServer(t *testing.T) *flakyServer {
+	t.Helper()
+	s := &flakyServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		if s.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// restart stops the engine and starts a new one on the same database, as a
+// LogDeck restart would.
+func (te *testEngine) restart(t *testing.T) {
+	t.Helper()
+	te.cancel()
+	te.e.Wait()
+	te.e = newEngine(te.hub, func() eventClient { return te.events }, te.conf.get, te.path)
+	te.e.now = te.clock.now
+	te.e.resolveInterval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	te.cancel = cancel
+	te.e.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		te.e.Wait()
+	})
+}
+
+func (te *testEngine) health(t *testing.T, id string) models.ChannelHealth {
+	t.Helper()
+	health, err := te.e.ChannelHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return health[id]
+}
+
+func lastDelivery(te *testEngine) *models.DeliveryResult {
+	h := te.e.History(0)
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0].Delivery
+}
+
+func TestChannelBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:  5 * time.Second,
+		2:  10 * time.Second,
+		4:  40 * time.Second,
+		7:  outboxMaxDelay,
+		50: outboxMaxDelay,
+	} {
+		if got := channelBackoff(failures); got != want {
+			t.Errorf("channelBackoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestOutboxBacksOffAFailingChannelOnly(t *testing.T) {
+	bad, good := newFlakyServer(t), newFlakyServer(t)
+	bad.down.Store(true)
+
+	te := startTestEngine(t, config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1})
+	te.conf.set(config.AlertsConfig{
+		Channels: []config.AlertChannel{
+			{ID: "c1", Type: "webhook", Name: "bad", Enabled: true, URL: bad.URL},
+			{ID: "c2", Type: "webhook", Name: "good", Enabled: true, URL: good.URL},
+		},
+		Rules: te.conf.get().Rules,
+	})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+	te.hub.emit(record(models.LogLevelError, "boom"))
+
+	waitFor(t, "retrying delivery", func() bool {
+		d := lastDelivery(te)
+		return d != nil && d.Status == DeliveryRetrying
+	})
+	if d := lastDelivery(te); !strings.HasPrefix(d.Error, "bad: channel returned 503") {
+		t.Fatalf("delivery = %+v, want the failing channel's error", d)
+	}
+	waitFor(t, "healthy channel delivered", func() bool {
+		health := te.health(t, "c2")
+		return health.LastSuccessAt != "" && health.Pending == 0
+	})
+	if got := good.hits.Load(); got != 1 {
+		t.Fatalf("healthy channel requests = %d, want 1", got)
+	}
+	health := te.health(t, "c1")
+	if health.ConsecutiveFailures != 1 || health.Pending != 1 || health.RetryAt != t0.Add(5*time.Second).Format(time.RFC3339) {
+		t.Fatalf("bad channel health = %+v, want 1 failure, 1 pending, backing off 5s", health)
+	}
+
+	// A poke before the backoff expires does not retry.
+	te.e.pokeOutbox()
+	time.Sleep(50 * time.Millisecond)
+	if got := bad.hits.Load(); got != 1 {
+		t.Fatalf("attempts during backoff = %d, want 1", got)
+	}
+
+	bad.down.Store(false)
+	te.clock.advance(5 * time.Second)
+	te.e.pokeOutbox()
+	waitFor(t, "delivered after backoff", func() bool { return lastDelivery(te).Status == "ok" })
+	if health := te.health(t, "c1"); health.ConsecutiveFailures != 0 || health.Pending != 0 || health.RetryAt != "" || health.LastError == "" {
+		t.Fatalf("recovered channel health = %+v, want the streak reset and the last error kept", health)
+	}
+	waitFor(t, "delivery counted", func() bool { return len(te.e.DeliveryCounts()) == 1 })
+}
+
+func TestOutboxDeliversAcrossRestart(t *testing.T) {
+	srv := newFlakyServer(t)
+	srv.down.Store(true)
+
+	// A long window keeps the incident firing while the clock moves.
+	te := startTestEngine(t, config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1, WindowSeconds: 3600})
+	te.conf.set(config.AlertsConfig{Channels: webhookChannels(srv.URL), Rules: te.conf.get().Rules})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "retrying delivery", func() bool {
+		d := lastDelivery(te)
+		return d != nil && d.Status == DeliveryRetrying
+	})
+
+	srv.down.Store(false)
+	te.clock.advance(time.Minute)
+	te.restart(t)
+	waitFor(t, "delivered after restart", func() bool { return lastDelivery(te).Status == "ok" })
+	if got := srv.hits.Load(); got != 2 {
+		t.Fatalf("requests = %d, want 2 (one failed, one after the restart)", got)
+	}
+}
+
+func TestOutboxDeadLettersPastTheDeadline(t *testing.T) {
+	srv := newFlakyServer(t)
+	srv.down.Store(true)
+
+	// A long window keeps the incident firing while the clock moves.
+	te := startTestEngine(t, config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1, WindowSeconds: 3600})
+	te.conf.set(config.AlertsConfig{
+		Channels: webhookChannels(srv.URL),
+		Rules:    te.conf.get().Rules,
+		Delivery: &config.AlertDeliveryConfig{DeadlineSeconds: 60},
+	})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "retrying delivery", func() bool {
+		d := lastDelivery(te)
+		return d != nil && d.Status == DeliveryRetrying
+	})
+
+	te.clock.advance(time.Minute)
+	te.e.pokeOutbox()
+	waitFor(t, "dead-lettered", func() bool { return lastDelivery(te).Status == "failed" })
+	if d := lastDelivery(te); d.Error != "webhook: gave up after 1m0s: channel returned 503 Service Unavailable" || d.HTTPStatus != http.StatusServiceUnavailable {
+		t.Fatalf("delivery = %+v, want the dead letter's last error", d)
+	}
+	if health := te.health(t, "c1"); health.Pending != 0 || health.DeadLettered != 1 {
+		t.Fatalf("health = %+v, want nothing pending and one dead letter", health)
+	}
+	if got := srv.hits.Load(); got != 1 {
+		t.Fatalf("requests = %d, want no attempt past the deadline", got)
+	}
+}
+
+func TestOutboxPermanentFailureIsNotRetried(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	te := startTestEngine(t, config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1})
+	te.conf.set(config.AlertsConfig{Channels: webhookChannels(srv.URL), Rules: te.conf.get().Rules})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+	te.hub.emit(record(models.LogLevelError, "boom"))
+
+	waitFor(t, "failed delivery", func() bool {
+		d := lastDelivery(te)
+		return d != nil && d.Status == "failed"
+	})
+	if health := te.health(t, "c1"); health.ConsecutiveFailures != 1 || health.RetryAt != "" || health.Pending != 0 {
+		t.Fatalf("health = %+v, want a failure without backoff", health)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("requests = %d, want 1 (4xx is permanent)", got)
+	}
+}
+
+func TestHistoryMigratesToOutboxSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), historyFileName)
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(historySchema + "PRAGMA user_version = 1;"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO alert_history (id, fired_ms, rule_id, host, container, project, state, delivery, alert)
+		VALUES ('a', 0, '', '', '', '', 'resolved', '', '{"id":"a","state":"resolved"}')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	h := newHistory(path)
+	if got := h.list(0); len(got) != 1 || got[0].ID != "a" {
+		t.Fatalf("history after migration = %+v, want the version 1 entry", got)
+	}
+	if err := h.enqueue(outboxFire, models.Alert{ID: "a"}, webhookChannels("http://x"), t0); err != nil {
+		t.Fatalf("enqueue after migration: %v", err)
+	}
+	var version int
+	if err := h.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil || version != historySchemaVersion {
+		t.Fatalf("schema version = %d (%v), want %d", version, err, historySchemaVersion)
+	}
+}
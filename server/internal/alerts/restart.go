@@ -0,0 +1,127 @@
+package alerts
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// eventRestartLoop is the event a restart-loop rule watches: a container
+	// that dies and starts again threshold times within the window.
+	eventRestartLoop = "restart_loop"
+	// defaultLoopRestarts applies when a restart-loop rule sets no threshold.
+	defaultLoopRestarts = 3
+	// loopExitCodes is how many of the latest exit codes a restart-loop alert
+	// reports, and loopSampleLines how many final log lines it carries.
+	loopExitCodes   = 5
+	loopSampleLines = 5
+	// restartHistoryTTL bounds how long restarts are remembered; no rule
+	// window is longer.
+	restartHistoryTTL = time.Hour
+)
+
+// restartTracker correlates one container's die and start events. Like
+// ruleWindow it takes explicit now values and is owned by the run loop.
+type restartTracker struct {
+	// died is set by a die and cleared by the start that follows it, which
+	// counts as a restart. exits holds the latest exit codes, oldest first,
+	// and restarts the restart times within restartHistoryTTL.
+	died     bool
+	exits    []string
+	restarts []time.Time
+	lastSeen time.Time
+}
+
+// die records one die event at now.
+func (t *restartTracker) die(now time.Time, exitCode string) {
+	t.lastSeen = now
+	t.died = true
+	if exitCode == "" {
+		exitCode = "unknown"
+	}
+	t.exits = append(t.exits, exitCode)
+	if len(t.exits) > loopExitCodes {
+		t.exits = t.exits[len(t.exits)-loopExitCodes:]
+	}
+}
+
+// start records one start event at now and reports whether it was a restart,
+// i.e. followed a die.
+func (t *restartTracker) start(now time.Time) bool {
+	t.lastSeen = now
+	if !t.died {
+		return false
+	}
+	t.died = false
+	t.restarts = append(t.restarts, now)
+	drop := 0
+	for drop < len(t.restarts) && now.Sub(t.restarts[drop]) > restartHistoryTTL {
+		drop++
+	}
+	t.restarts = t.restarts[drop:]
+	return true
+}
+
+// within returns the number of restarts in the window ending at now.
+func (t *restartTracker) within(window time.Duration, now time.Time) int {
+	n := 0
+	for _, at := range t.restarts {
+		if now.Sub(at) <= window {
+			n++
+		}
+	}
+	return n
+}
+
+// restartReason renders the human-readable reason for a fired restart-loop
+// rule, e.g. "restarted 4 times within 60s (last exits 1, 1, 137)".
+func restartReason(rule *compiledRule, restarts int, exits []string) string {
+	reason := fmt.Sprintf("restarted %d times within %ds", restarts, int(rule.window.Seconds()))
+	if len(exits) > 0 {
+		reason += " (last exits " + strings.Join(exits, ", ") + ")"
+	}
+	return reason
+}
+
+// recentLines keeps the final log lines of each container a restart-loop
+// rule watches, so an alert can carry them as its sample. Hub sinks write it
+// from their delivery goroutines and the run loop reads it, hence the lock.
+type recentLines struct {
+	mu    sync.Mutex
+	lines map[string][]string // by ruleID|host|containerName
+}
+
+func newRecentLines() *recentLines {
+	return &recentLines{lines: make(map[string][]string)}
+}
+
+func (r *recentLines) add(key, line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := append(r.lines[key], line)
+	if len(lines) > loopSampleLines {
+		lines = lines[len(lines)-loopSampleLines:]
+	}
+	r.lines[key] = lines
+}
+
+// sample returns the key's final lines joined one per line, "" when none
+// arrived.
+func (r *recentLines) sample(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.lines[key], "\n")
+}
+
+// drop forgets every key of the given rule.
+func (r *recentLines) drop(ruleID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.lines {
+		if id, _, _ := strings.Cut(key, "|"); id == ruleID {
+			delete(r.lines, key)
+		}
+	}
+}
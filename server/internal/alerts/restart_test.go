@@ -0,0 +1,114 @@
+package alerts
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+func TestRestartTracker(t *testing.T) {
+	base := time.Unix(1_700_000_000, 0)
+	var tr restartTracker
+
+	if tr.start(base) {
+		t.Fatal("a start with no die before it counted as a restart")
+	}
+	for i, code := range []string{"1", "2", "3", "4", "5", ""} {
+		at := base.Add(time.Duration(i*10) * time.Second)
+		tr.die(at, code)
+		if !tr.start(at.Add(time.Second)) {
+			t.Fatalf("start after die %d did not count as a restart", i)
+		}
+	}
+	if got, want := tr.exits, []string{"2", "3", "4", "5", "unknown"}; !slices.Equal(got, want) {
+		t.Fatalf("exits = %v, want the latest %d: %v", got, loopExitCodes, want)
+	}
+	if n := tr.within(30*time.Second, base.Add(52*time.Second)); n != 3 {
+		t.Fatalf("within 30s = %d, want 3", n)
+	}
+	if tr.start(base.Add(time.Minute)) {
+		t.Fatal("a second start without a die counted as a restart")
+	}
+}
+
+func TestRecentLinesKeepsTheFinalLines(t *testing.T) {
+	r := newRecentLines()
+	for _, line := range []string{"a", "b", "c", "d", "e", "f"} {
+		r.add("r1|local|web", line)
+	}
+	r.add("r2|local|web", "other")
+	if got, want := r.sample("r1|local|web"), "b\nc\nd\ne\nf"; got != want {
+		t.Fatalf("sample = %q, want %q", got, want)
+	}
+	r.drop("r1")
+	if got := r.sample("r1|local|web"); got != "" {
+		t.Fatalf("sample after drop = %q, want empty", got)
+	}
+	if got := r.sample("r2|local|web"); got != "other" {
+		t.Fatalf("other rule's sample = %q, want it kept", got)
+	}
+}
+
+func TestRestartLoopFiresOnceWithExitsAndFinalLines(t *testing.T) {
+	te := startTestEngine(t,
+		config.AlertRule{
+			ID: "loop", Name: "Restart loop", Enabled: true, Type: "event",
+			Events: []string{"restart_loop"}, Threshold: 3, WindowSeconds: 60, Containers: []string{"web"},
+		},
+		config.AlertRule{
+			ID: "marker", Name: "Marker", Enabled: true, Type: "event",
+			Events: []string{"die"}, Containers: []string{"flush"},
+		},
+	)
+	waitFor(t, "line subscription", func() bool { return te.hub.liveCount() == 1 })
+	te.hub.emit(record(models.LogLevelError, "panic: nil map"))
+	te.hub.emit(record(models.LogLevelInfo, "exiting"))
+
+	restart := func(exitCode string) {
+		te.events.ch <- docker.EngineEvent{Host: "local", ContainerID: "c1", ContainerName: "/web", Action: "die", ExitCode: exitCode}
+		te.events.ch <- docker.EngineEvent{Host: "local", ContainerID: "c1", ContainerName: "/web", Action: "start"}
+	}
+	restart("1")
+	restart("1")
+	restart("137")
+
+	waitFor(t, "restart-loop incident", func() bool { return len(te.e.History(0)) == 1 })
+	a := te.e.History(0)[0]
+	if a.Type != "event" || a.ContainerName != "web" || a.Reason != "restarted 3 times within 60s (last exits 1, 1, 137)" {
+		t.Fatalf("incident = %+v", a)
+	}
+	if a.Sample != "panic: nil map\nexiting" {
+		t.Fatalf("sample = %q, want the final log lines", a.Sample)
+	}
+
+	// Further restarts inside the cooldown are swallowed, not alerted.
+	restart("1")
+	restart("1")
+	te.events.ch <- docker.EngineEvent{Host: "local", ContainerID: "c2", ContainerName: "flush", Action: "die", ExitCode: "1"}
+	waitFor(t, "marker alert", func() bool { return len(te.e.History(0)) == 2 })
+	for _, a := range te.e.History(0) {
+		if a.RuleID == "loop" && (a.Repeats != 0 || a.State != models.AlertFiring) {
+			t.Fatalf("loop incident = %+v, want one firing incident with no repeats", a)
+		}
+	}
+}
+
+func TestCompileRulesRestartLoop(t *testing.T) {
+	rules := compileRules(config.AlertsConfig{Rules: []config.AlertRule{
+		{ID: "a", Enabled: true, Type: "event", Events: []string{"restart_loop"}, Threshold: 5},
+		{ID: "b", Enabled: true, Type: "event", Events: []string{"restart_loop"}},
+	}})
+	if len(rules) != 2 {
+		t.Fatalf("compiled %d rules, want 2", len(rules))
+	}
+	if rules[0].restarts != 5 || rules[0].threshold != 1 {
+		t.Fatalf("compiled rule = %+v, want 5 restarts and threshold 1", rules[0])
+	}
+	if rules[1].restarts != defaultLoopRestarts {
+		t.Fatalf("compiled rule = %+v, want the default restart count", rules[1])
+	}
+}
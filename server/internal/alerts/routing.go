@@ -0,0 +1,128 @@
+package alerts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/logstream"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// Rule severities. An empty configured severity is treated as warning.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// ValidSeverity reports whether s is a severity a rule may configure; ""
+// selects the default.
+func ValidSeverity(s string) bool {
+	switch s {
+	case "", SeverityInfo, SeverityWarning, SeverityCritical:
+		return true
+	}
+	return false
+}
+
+// routeAlert decides which channels an incident opened by alert is sent to.
+// The first of its rule's routes whose targeting matches the alert's
+// container wins; a rule with no matching route uses its own channel list,
+// and one with neither uses every enabled channel. Disabled and deleted
+// channels are dropped from an explicit list. The routing is nil when the
+// default applies and no channel is enabled, leaving the incident
+// history-only as it would be without any routing.
+func routeAlert(cfg config.AlertsConfig, alert models.Alert) ([]config.AlertChannel, *models.AlertRouting) {
+	var rule *config.AlertRule
+	for i := range cfg.Rules {
+		if cfg.Rules[i].ID == alert.RuleID {
+			rule = &cfg.Rules[i]
+			break
+		}
+	}
+	if rule != nil {
+		for i, route := range rule.Routes {
+			spec := logstream.ContainerSpec{Hosts: route.Hosts, Containers: route.Containers, Projects: route.Projects}
+			if spec.MatchesProject(alert.Host, alert.ContainerName, alert.Project) {
+				return routed(cfg.Channels, route.Channels, fmt.Sprintf("route %d (%s)", i+1, describeRoute(route)))
+			}
+		}
+		if len(rule.Channels) > 0 {
+			return routed(cfg.Channels, rule.Channels, "rule channels")
+		}
+	}
+	channels := enabledChannels(cfg.Channels)
+	if len(channels) == 0 {
+		return nil, nil
+	}
+	return channels, newRouting(channels, "all enabled channels")
+}
+
+// reroute returns the live, enabled channels an incident was routed to when
+// it opened, so its repeats and resolution reach the same destinations (a
+// PagerDuty resolve must follow its trigger). An incident without a recorded
+// routing is routed afresh.
+func reroute(cfg config.AlertsConfig, alert models.Alert) ([]config.AlertChannel, *models.AlertRouting) {
+	if alert.Routing == nil {
+		return routeAlert(cfg, alert)
+	}
+	ids := make([]string, len(alert.Routing.Channels))
+	for i, ch := range alert.Routing.Channels {
+		ids[i] = ch.ID
+	}
+	return selectChannels(cfg.Channels, ids), alert.Routing
+}
+
+// routed builds the routing for an explicit channel list.
+func routed(all []config.AlertChannel, ids []string, reason string) ([]config.AlertChannel, *models.AlertRouting) {
+	channels := selectChannels(all, ids)
+	if len(channels) == 0 {
+		reason += ": no enabled channels"
+	}
+	return channels, newRouting(channels, reason)
+}
+
+// selectChannels returns the enabled channels among ids, in ids order.
+func selectChannels(all []config.AlertChannel, ids []string) []config.AlertChannel {
+	out := make([]config.AlertChannel, 0, len(ids))
+	for _, id := range ids {
+		for _, ch := range all {
+			if ch.ID == id && ch.Enabled {
+				out = append(out, ch)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func newRouting(channels []config.AlertChannel, reason string) *models.AlertRouting {
+	routing := &models.AlertRouting{Channels: make([]models.RoutedChannel, len(channels)), Reason: reason}
+	for i, ch := range channels {
+		routing.Channels[i] = models.RoutedChannel{ID: ch.ID, Name: ch.Name, Type: ch.Type}
+	}
+	return routing
+}
+
+// describeRoute summarizes a route's targeting, e.g. "projects: staging;
+// hosts: prod".
+func describeRoute(route config.AlertRoute) string {
+	var parts []string
+	for _, dim := range []struct {
+		label  string
+		values []string
+	}{
+		{"hosts", route.Hosts},
+		{"projects", route.Projects},
+		{"containers", route.Containers},
+	} {
+		if len(dim.values) > 0 {
+			parts = append(parts, dim.label+": "+strings.Join(dim.values, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "all containers"
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,135 @@
+package alerts
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+func routedIDs(channels []config.AlertChannel) []string {
+	ids := make([]string, len(channels))
+	for i, ch := range channels {
+		ids[i] = ch.ID
+	}
+	return ids
+}
+
+func TestRouteAlert(t *testing.T) {
+	channels := []config.AlertChannel{
+		{ID: "c1", Type: "ntfy", Name: "Low", Enabled: true},
+		{ID: "c2", Type: "pagerduty", Name: "Pager", Enabled: true},
+		{ID: "c3", Type: "webhook", Enabled: false},
+	}
+	rule := config.AlertRule{
+		ID:       "r1",
+		Channels: []string{"c1"},
+		Routes: []config.AlertRoute{
+			{Projects: []string{"staging"}, Channels: []string{"c3"}},
+			{Hosts: []string{"prod"}, Projects: []string{"shop"}, Channels: []string{"c2", "gone", "c1"}},
+		},
+	}
+	cfg := config.AlertsConfig{Channels: channels, Rules: []config.AlertRule{rule}}
+
+	cases := []struct {
+		name   string
+		alert  models.Alert
+		want   []string
+		reason string
+	}{
+		{"second route", models.Alert{RuleID: "r1", Host: "prod", Project: "shop"}, []string{"c2", "c1"}, "route 2 (hosts: prod; projects: shop)"},
+		{"route to disabled channel", models.Alert{RuleID: "r1", Host: "prod", Project: "staging"}, []string{}, "route 1 (projects: staging): no enabled channels"},
+		{"rule channels", models.Alert{RuleID: "r1", Host: "dev", Project: "shop"}, []string{"c1"}, "rule channels"},
+		{"unknown rule", models.Alert{RuleID: "r9"}, []string{"c1", "c2"}, "all enabled channels"},
+	}
+	for _, tc := range cases {
+		got, routing := routeAlert(cfg, tc.alert)
+		if ids := routedIDs(got); !slices.Equal(ids, tc.want) {
+			t.Errorf("%s: channels = %v, want %v", tc.name, ids, tc.want)
+		}
+		if routing == nil || routing.Reason != tc.reason || len(routing.Channels) != len(tc.want) {
+			t.Errorf("%s: routing = %+v, want reason %q", tc.name, routing, tc.reason)
+		}
+	}
+
+	if got, routing := routeAlert(config.AlertsConfig{Channels: channels[2:]}, models.Alert{RuleID: "r1"}); len(got) != 0 || routing != nil {
+		t.Fatalf("no enabled channels: got %v, %+v; want history-only", got, routing)
+	}
+}
+
+func TestRerouteFollowsRecordedRouting(t *testing.T) {
+	cfg := config.AlertsConfig{
+		Channels: []config.AlertChannel{
+			{ID: "c1", Type: "ntfy", Enabled: true, URL: "https://ntfy.sh/new-topic"},
+			{ID: "c2", Type: "webhook", Enabled: true},
+		},
+		Rules: []config.AlertRule{{ID: "r1", Channels: []string{"c2"}}},
+	}
+	// The incident opened when the rule still routed to c1: its resolution
+	// goes there too, with the channel's current settings.
+	alert := models.Alert{RuleID: "r1", Routing: &models.AlertRouting{
+		Channels: []models.RoutedChannel{{ID: "c1", Type: "ntfy"}},
+		Reason:   "rule channels",
+	}}
+	got, routing := reroute(cfg, alert)
+	if len(got) != 1 || got[0].URL != "https://ntfy.sh/new-topic" || routing != alert.Routing {
+		t.Fatalf("reroute = %+v, %+v; want the recorded channel", got, routing)
+	}
+
+	alert.Routing = nil
+	if got, _ := reroute(cfg, alert); !slices.Equal(routedIDs(got), []string{"c2"}) {
+		t.Fatalf("reroute without routing = %v, want the rule's channels", routedIDs(got))
+	}
+}
+
+func TestSeverityPriority(t *testing.T) {
+	for _, tc := range []struct {
+		alert  models.Alert
+		ntfy   string
+		gotify int
+	}{
+		{models.Alert{Severity: SeverityCritical}, "urgent", 8},
+		{models.Alert{Severity: SeverityWarning}, "high", 5},
+		{models.Alert{}, "high", 5},
+		{models.Alert{Severity: SeverityInfo}, "default", 2},
+		{models.Alert{Severity: SeverityCritical, State: models.AlertResolved}, "default", 2},
+	} {
+		ntfy, gotify := severityPriority(tc.alert)
+		if ntfy != tc.ntfy || gotify != tc.gotify {
+			t.Errorf("severityPriority(%q, %q) = %q, %d; want %q, %d", tc.alert.Severity, tc.alert.State, ntfy, gotify, tc.ntfy, tc.gotify)
+		}
+	}
+}
+
+func TestEngineRoutesIncidentToRuleChannels(t *testing.T) {
+	quiet, pager := newWebhookRecorder(t), newWebhookRecorder(t)
+	rule := config.AlertRule{
+		ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1,
+		WindowSeconds: 60, CooldownSeconds: 1, Severity: SeverityCritical,
+		Routes: []config.AlertRoute{{Projects: []string{"shop"}, Channels: []string{"pager"}}},
+	}
+	te := startTestEngine(t, rule)
+	te.conf.set(config.AlertsConfig{
+		Channels: []config.AlertChannel{
+			{ID: "quiet", Type: "webhook", Enabled: true, URL: quiet.srv.URL},
+			{ID: "pager", Type: "webhook", Enabled: true, URL: pager.srv.URL},
+		},
+		Rules: []config.AlertRule{rule},
+	})
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "firing notification", func() bool { return len(pager.received()) == 1 })
+	a := te.e.History(0)[0]
+	if a.Severity != SeverityCritical || a.Routing == nil || a.Routing.Reason != "route 1 (projects: shop)" {
+		t.Fatalf("incident = %+v, routing %+v; want critical, routed by route 1", a, a.Routing)
+	}
+
+	te.clock.advance(90 * time.Second)
+	waitFor(t, "resolved notification", func() bool { return len(pager.received()) == 2 })
+	if got := quiet.received(); len(got) != 0 {
+		t.Fatalf("unrouted channel received %q, want nothing", got)
+	}
+}
@@ -1,6 +1,7 @@
 package alerts
 
 import (
+	"cmp"
 	"log"
 	"regexp"
 	"slices"
@@ -26,18 +27,27 @@ const (
 type compiledRule struct {
 	id   string
 	name string
-	typ  string // "event" | "log"
+	typ  string // "event" | "log" | "metric"
+	// severity is the configured severity, "warning" when unset.
+	severity string
 
 	// spec is the rule's container targeting; matching is delegated to
 	// logstream.ContainerSpec.Matches.
 	spec logstream.ContainerSpec
 
-	events []string // event rules: "die" | "oom" | "unhealthy"
+	events   []string // event rules: "die" | "oom" | "unhealthy" | "restart_loop"
+	restarts int      // restart-loop rules: restarts within the window that fire
 
 	minLevel    string // normalized upper-case, "" when unset
 	minSeverity int    // >= 1 when minLevel is set; UNKNOWN (0) never passes
 	pattern     *regexp.Regexp
+	absence     bool // log rules: fire on too few matches, not on matches
+	expected    int  // absence rules: fewer matches than this within the window fire
 
+	metric    string  // metric rules: "cpu_percent" | "memory_percent"
+	below     bool    // metric rules: the condition is value < limit, not >
+	limit     float64 // metric rules: the value compared against
+	averaged  bool    // metric rules: compare the window average, not every sample
 	threshold int
 	window    time.Duration
 	cooldown  time.Duration
@@ -47,9 +57,9 @@ type compiledRule struct {
 	src config.AlertRule
 }
 
-// compileRules turns the configured rules into compiled ones. Disabled rules,
-// rules with an unknown type, and rules with an invalid pattern are skipped
-// (the latter two with a log line). Defaults are normalized here so the rest
+// compileRules turns the configured rules into compiled ones. Disabled rules
+// are skipped, and so are rules with an unknown type, metric, or mode or an
+// invalid pattern (with a log line). Defaults are normalized here so the rest
 // of the engine never re-checks them.
 func compileRules(cfg config.AlertsConfig) []*compiledRule {
 	compiled := make([]*compiledRule, 0, len(cfg.Rules))
@@ -57,25 +67,63 @@ func compileRules(cfg config.AlertsConfig) []*compiledRule {
 		if !rule.Enabled {
 			continue
 		}
-		if rule.Type != "event" && rule.Type != "log" {
+		if rule.Type != "event" && rule.Type != "log" && rule.Type != "metric" {
 			log.Printf("alerts: rule %q (%s): unknown type %q, skipping", rule.Name, rule.ID, rule.Type)
 			continue
 		}
+		if rule.Type == "metric" && !validMetric(rule.Metric) {
+			log.Printf("alerts: rule %q (%s): unknown metric %q, skipping", rule.Name, rule.ID, rule.Metric)
+			continue
+		}
+		if rule.Type == "log" && rule.Mode != "" && rule.Mode != "absence" {
+			log.Printf("alerts: rule %q (%s): unknown mode %q, skipping", rule.Name, rule.ID, rule.Mode)
+			continue
+		}
+		severity := rule.Severity
+		if !ValidSeverity(severity) {
+			log.Printf("alerts: rule %q (%s): unknown severity %q, using %q", rule.Name, rule.ID, severity, SeverityWarning)
+			severity = ""
+		}
 
 		c := &compiledRule{
 			id:        rule.ID,
 			name:      rule.Name,
 			typ:       rule.Type,
+			severity:  cmp.Or(severity, SeverityWarning),
 			spec:      logstream.ContainerSpec{Hosts: rule.Hosts, Containers: rule.Containers, Projects: rule.Projects},
 			events:    rule.Events,
 			threshold: rule.Threshold,
 			window:    time.Duration(rule.WindowSeconds) * time.Second,
 			cooldown:  time.Duration(rule.CooldownSeconds) * time.Second,
+			metric:    rule.Metric,
+			below:     rule.Operator == "<",
+			limit:     rule.Value,
+			averaged:  rule.Aggregate == "avg",
 			src:       rule,
 		}
 		if c.threshold < 1 {
 			c.threshold = 1
 		}
+		// A metric rule's window is a duration the condition must hold, and
+		// an absence rule's threshold is the number of matches it expects, so
+		// every check that finds either condition holding trips the window.
+		if c.typ == "log" && rule.Mode == "absence" {
+			c.absence = true
+			c.expected = c.threshold
+			c.threshold = 1
+		}
+		if c.typ == "metric" {
+			c.threshold = 1
+		}
+		// Likewise a restart-loop rule's threshold counts restarts, which the
+		// engine tallies itself before tripping the window.
+		if c.typ == "event" && c.hasEvent(eventRestartLoop) {
+			c.restarts = rule.Threshold
+			if c.restarts < 1 {
+				c.restarts = defaultLoopRestarts
+			}
+			c.threshold = 1
+		}
 		if c.window <= 0 {
 			c.window = defaultWindow
 		}
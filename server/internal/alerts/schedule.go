@@ -0,0 +1,213 @@
+package alerts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	// Maintenance windows name IANA time zones; the runtime image ships no
+	// zoneinfo database.
+	_ "time/tzdata"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+)
+
+// scheduleLookahead bounds the search for a maintenance window's next start,
+// so a schedule that never fires (e.g. February 30th) terminates.
+const scheduleLookahead = 366 * 24 * time.Hour
+
+// schedule is a parsed five-field cron expression: each field is a bitset of
+// the values it allows.
+type schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record an unrestricted day field: as in cron, when
+	// both day fields are restricted a day matching either one fires.
+	domStar, dowStar bool
+}
+
+var scheduleFields = [5]struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day-of-month", 1, 31},
+	{"month", 1, 12},
+	{"day-of-week", 0, 7},
+}
+
+// parseSchedule parses a cron expression "minute hour day-of-month month
+// day-of-week". A field is "*", a value, or a range "a-b", optionally with a
+// step ("*/15", "1-5/2"), or a comma-separated list of those. Day-of-week
+// counts from Sunday as 0; 7 is Sunday too.
+func parseSchedule(expr string) (schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(scheduleFields) {
+		return schedule{}, fmt.Errorf("schedule must have 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseScheduleField(f, scheduleFields[i].min, scheduleFields[i].max)
+		if err != nil {
+			return schedule{}, fmt.Errorf("schedule %s field %q: %w", scheduleFields[i].name, f, err)
+		}
+		bits[i] = b
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return schedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseScheduleField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+			step = n
+		}
+		start, end := lo, hi
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if start, err = scheduleValue(a, lo, hi); err != nil {
+				return 0, err
+			}
+			if end, err = scheduleValue(b, lo, hi); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("range %q runs backwards", rng)
+			}
+		default:
+			v, err := scheduleValue(rng, lo, hi)
+			if err != nil {
+				return 0, err
+			}
+			start, end = v, v
+			if hasStep {
+				end = hi
+			}
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func scheduleValue(s string, lo, hi int) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < lo || v > hi {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, lo, hi)
+	}
+	return v, nil
+}
+
+// matches reports whether the schedule fires in t's minute.
+func (s schedule) matches(t time.Time) bool {
+	if s.minute&(1<<t.Minute()) == 0 || s.hour&(1<<t.Hour()) == 0 || s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// ValidateMaintenanceWindow checks a window's schedule and time zone.
+func ValidateMaintenanceWindow(w config.MaintenanceWindow) error {
+	if _, err := parseSchedule(w.Schedule); err != nil {
+		return err
+	}
+	if _, err := loadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", w.Timezone)
+	}
+	return nil
+}
+
+// locations caches loaded time zones by name; windows are checked on every
+// fire, and loading parses the embedded zoneinfo each time.
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// maintenanceActive reports whether w is in effect at now, with the start of
+// its current occurrence. It only looks back over one duration, so it is
+// cheap enough for every fire. A disabled or invalid window is never active.
+func maintenanceActive(w config.MaintenanceWindow, now time.Time) (bool, time.Time) {
+	if !w.Enabled {
+		return false, time.Time{}
+	}
+	sched, err := parseSchedule(w.Schedule)
+	if err != nil {
+		return false, time.Time{}
+	}
+	loc, err := loadLocation(w.Timezone)
+	if err != nil {
+		return false, time.Time{}
+	}
+	now = now.In(loc)
+	// Walk back minute by minute over one duration: an occurrence that
+	// started within it is still running.
+	d := time.Duration(w.DurationSeconds) * time.Second
+	for t := now.Truncate(time.Minute); t.After(now.Add(-d)); t = t.Add(-time.Minute) {
+		if sched.matches(t) {
+			return true, t
+		}
+	}
+	return false, time.Time{}
+}
+
+// MaintenanceStatus reports whether w is in effect at now, with the start of
+// its current occurrence; otherwise it returns the next start, or the zero
+// time when the schedule does not fire within a year. A disabled or invalid
+// window is never active. The search for the next start can walk a year of
+// minutes, so it is meant for the API, not for every fire.
+func MaintenanceStatus(w config.MaintenanceWindow, now time.Time) (active bool, start time.Time) {
+	if active, start := maintenanceActive(w, now); active {
+		return true, start
+	}
+	sched, err := parseSchedule(w.Schedule)
+	if err != nil {
+		return false, time.Time{}
+	}
+	loc, err := loadLocation(w.Timezone)
+	if err != nil {
+		return false, time.Time{}
+	}
+	now = now.In(loc)
+	for t := now.Truncate(time.Minute).Add(time.Minute); t.Before(now.Add(scheduleLookahead)); t = t.Add(time.Minute) {
+		if sched.matches(t) {
+			return false, t
+		}
+	}
+	return false, time.Time{}
+}
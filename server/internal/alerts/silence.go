@@ -0,0 +1,57 @@
+package alerts
+
+import (
+	"slices"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/logstream"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// DeliverySuppressed is the delivery status recorded for a fire that an
+// active silence or maintenance window kept from being notified.
+const DeliverySuppressed = "suppressed-by-silence"
+
+// SilenceActive reports whether s is in effect at now.
+func SilenceActive(s config.AlertSilence, now time.Time) bool {
+	starts, err := time.Parse(time.RFC3339, s.StartsAt)
+	if err != nil {
+		return false
+	}
+	ends, err := time.Parse(time.RFC3339, s.EndsAt)
+	return err == nil && !now.Before(starts) && now.Before(ends)
+}
+
+// silencedBy returns what mutes alert at now — "silence <id>: <comment>" or
+// "maintenance window <name>" for the first active silence or window whose
+// targeting matches it — or "" when nothing does.
+func silencedBy(cfg config.AlertsConfig, alert models.Alert, now time.Time) string {
+	for _, s := range cfg.Silences {
+		if SilenceActive(s, now) && silenceMatches(alert, s.Hosts, s.Containers, s.Projects, s.Rules) {
+			if s.Comment != "" {
+				return "silence " + s.ID + ": " + s.Comment
+			}
+			return "silence " + s.ID
+		}
+	}
+	for _, w := range cfg.MaintenanceWindows {
+		if !w.Enabled || !silenceMatches(alert, w.Hosts, w.Containers, w.Projects, w.Rules) {
+			continue
+		}
+		if active, _ := maintenanceActive(w, now); active {
+			return "maintenance window " + w.Name
+		}
+	}
+	return ""
+}
+
+// silenceMatches applies a silence's targeting: the container dimensions as
+// for a rule, and rules to the alert's rule ID.
+func silenceMatches(alert models.Alert, hosts, containers, projects, rules []string) bool {
+	if len(rules) > 0 && !slices.Contains(rules, alert.RuleID) {
+		return false
+	}
+	spec := logstream.ContainerSpec{Hosts: hosts, Containers: containers, Projects: projects}
+	return spec.MatchesProject(alert.Host, alert.ContainerName, alert.Project)
+}
@@ -0,0 +1,145 @@
+package alerts
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, expr := range []string{"* * * * *", "*/15 2-4 1,15 * 1-5", "0 3 * 1-12/3 7", "30 22 * * 0,6"} {
+		if _, err := parseSchedule(expr); err != nil {
+			t.Errorf("parseSchedule(%q) = %v, want valid", expr, err)
+		}
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "x * * * *", "* * * * * *"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("parseSchedule(%q) = nil, want an error", expr)
+		}
+	}
+
+	// Both day fields restricted: either one matching fires.
+	s, _ := parseSchedule("0 0 1 * 1")
+	for _, tc := range []struct {
+		day  time.Time
+		want bool
+	}{
+		{time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), true},  // the 1st, a Wednesday
+		{time.Date(2026, 7, 6, 0, 0, 0, 0, time.UTC), true},  // a Monday
+		{time.Date(2026, 7, 7, 0, 0, 0, 0, time.UTC), false}, // neither
+	} {
+		if got := s.matches(tc.day); got != tc.want {
+			t.Errorf("matches(%s) = %v, want %v", tc.day.Format(time.DateOnly), got, tc.want)
+		}
+	}
+}
+
+func TestMaintenanceStatus(t *testing.T) {
+	// Fridays 13:00 Berlin time (11:00 UTC in July) for two hours; t0 is
+	// Friday 12:00 UTC.
+	w := config.MaintenanceWindow{Enabled: true, Schedule: "0 13 * * 5", DurationSeconds: 7200, Timezone: "Europe/Berlin"}
+	if active, start := MaintenanceStatus(w, t0); !active || !start.Equal(time.Date(2026, 7, 10, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("at t0: active %v, start %s; want active since 11:00 UTC", active, start)
+	}
+	if active, next := MaintenanceStatus(w, t0.Add(90*time.Minute)); active || !next.Equal(time.Date(2026, 7, 17, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("after the window: active %v, next %s; want the next Friday", active, next)
+	}
+
+	// The fire-path check agrees without searching for the next start.
+	if active, start := maintenanceActive(w, t0); !active || !start.Equal(time.Date(2026, 7, 10, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("maintenanceActive at t0: %v, %s; want active since 11:00 UTC", active, start)
+	}
+	if active, start := maintenanceActive(w, t0.Add(90*time.Minute)); active || !start.IsZero() {
+		t.Fatalf("maintenanceActive after the window: %v, %s; want inactive", active, start)
+	}
+
+	w.Enabled = false
+	if active, _ := MaintenanceStatus(w, t0); active {
+		t.Fatal("a disabled window is active")
+	}
+	if active, next := MaintenanceStatus(config.MaintenanceWindow{Enabled: true, Schedule: "0 0 30 2 *", DurationSeconds: 60}, t0); active || !next.IsZero() {
+		t.Fatalf("never-firing schedule: active %v, next %s; want neither", active, next)
+	}
+}
+
+func TestSilencedBy(t *testing.T) {
+	at := func(d time.Duration) string { return t0.Add(d).Format(time.RFC3339) }
+	cfg := config.AlertsConfig{
+		Silences: []config.AlertSilence{
+			{ID: "old", StartsAt: at(-2 * time.Hour), EndsAt: at(-time.Hour)},
+			{ID: "later", StartsAt: at(time.Hour), EndsAt: at(2 * time.Hour)},
+			{ID: "s1", Projects: []string{"shop"}, Rules: []string{"r1"}, StartsAt: at(-time.Minute), EndsAt: at(time.Hour), Comment: "deploy"},
+		},
+		MaintenanceWindows: []config.MaintenanceWindow{
+			{Name: "nightly", Enabled: true, Schedule: "0 12 * * *", DurationSeconds: 600, Hosts: []string{"prod"}},
+		},
+	}
+	for _, tc := range []struct {
+		name  string
+		alert models.Alert
+		want  string
+	}{
+		{"silence", models.Alert{RuleID: "r1", Host: "local", Project: "shop"}, "silence s1: deploy"},
+		{"other rule", models.Alert{RuleID: "r2", Host: "local", Project: "shop"}, ""},
+		{"other project", models.Alert{RuleID: "r1", Host: "local", Project: "blog"}, ""},
+		{"window", models.Alert{RuleID: "r2", Host: "prod"}, "maintenance window nightly"},
+	} {
+		if got := silencedBy(cfg, tc.alert, t0); got != tc.want {
+			t.Errorf("%s: silencedBy = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestEngineSuppressesSilencedFires(t *testing.T) {
+	rec := newWebhookRecorder(t)
+	rule := config.AlertRule{ID: "r1", Name: "boom", Enabled: true, Type: "log", Pattern: "boom", Threshold: 1, WindowSeconds: 60, CooldownSeconds: 1}
+	te := startWithWebhook(t, rec, rule)
+	silence := func(id string, from time.Time) {
+		te.conf.set(config.AlertsConfig{
+			Channels: webhookChannels(rec.srv.URL),
+			Rules:    []config.AlertRule{rule},
+			Silences: []config.AlertSilence{{
+				ID: id, Projects: []string{"shop"}, Comment: "deploy",
+				StartsAt: from.Format(time.RFC3339), EndsAt: from.Add(time.Minute).Format(time.RFC3339),
+			}},
+		})
+	}
+	silence("s1", t0)
+	waitFor(t, "subscription", func() bool { return te.hub.liveCount() == 1 })
+
+	// A fire during the silence is recorded but not notified, and neither
+	// is its resolution.
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "suppressed incident", func() bool { return len(te.e.History(0)) == 1 })
+	if a := te.e.History(0)[0]; a.SuppressedBy != "silence s1: deploy" || a.Delivery == nil || a.Delivery.Status != DeliverySuppressed {
+		t.Fatalf("incident = %+v, want suppressed by s1", a)
+	}
+	te.clock.advance(90 * time.Second)
+	waitFor(t, "resolution", func() bool { return te.e.History(0)[0].State == models.AlertResolved })
+
+	// The silence has ended: the next incident is notified. Dispatch is
+	// ordered, so a stray resolution would have arrived first.
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "firing notification", func() bool { return len(rec.received()) == 1 })
+	if got := rec.received(); got[0] != models.AlertFiring {
+		t.Fatalf("notifications = %q, want only the second incident firing", got)
+	}
+
+	// A repeat under a new silence is suppressed, but the notified
+	// incident's resolution is still sent.
+	silence("s2", te.clock.now())
+	te.clock.advance(2 * time.Second)
+	te.hub.emit(record(models.LogLevelError, "boom"))
+	waitFor(t, "suppressed repeat", func() bool { return te.e.History(0)[0].SuppressedBy == "silence s2: deploy" })
+	if a := te.e.History(0)[0]; a.Repeats != 1 || a.Delivery.Status != "ok" {
+		t.Fatalf("incident = %+v, want one repeat with the firing delivery kept", a)
+	}
+	te.clock.advance(90 * time.Second)
+	waitFor(t, "resolved notification", func() bool { return len(rec.received()) == 2 })
+	if got := rec.received(); !slices.Equal(got, []string{models.AlertFiring, models.AlertResolved}) {
+		t.Fatalf("notifications = %q, want firing then resolved", got)
+	}
+}
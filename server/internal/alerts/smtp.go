@@ -0,0 +1,178 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// defaultSMTPPort is the submission port an smtp:// URL without one uses.
+const defaultSMTPPort = "587"
+
+// mailSpec is the fully-resolved message for one email delivery.
+type mailSpec struct {
+	addr     string // host:port
+	host     string // for STARTTLS verification and PLAIN auth
+	username string
+	password string
+	from     string
+	to       []string
+	msg      []byte
+}
+
+// ParseSMTPURL splits an smtp://host[:port] channel URL into its host and
+// port, defaulting the port to 587.
+func ParseSMTPURL(raw string) (host, port string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "smtp" || u.Hostname() == "" || (u.Path != "" && u.Path != "/") {
+		return "", "", errors.New("url must be smtp://host or smtp://host:port")
+	}
+	port = u.Port()
+	if port == "" {
+		port = defaultSMTPPort
+	}
+	return u.Hostname(), port, nil
+}
+
+// buildMailSpec resolves the email for delivering alert to an smtp channel:
+// the title is the subject and the text the plain-text body.
+func buildMailSpec(ch config.AlertChannel, alert models.Alert) (mailSpec, error) {
+	host, port, err := ParseSMTPURL(ch.URL)
+	if err != nil {
+		return mailSpec{}, err
+	}
+	from, err := mail.ParseAddress(ch.From)
+	if err != nil {
+		return mailSpec{}, fmt.Errorf("invalid from address: %v", err)
+	}
+	to, err := mail.ParseAddressList(ch.Target)
+	if err != nil {
+		return mailSpec{}, fmt.Errorf("invalid recipients: %v", err)
+	}
+	msg, err := renderMessage(ch, alert)
+	if err != nil {
+		return mailSpec{}, fmt.Errorf("template: %v", err)
+	}
+
+	recipients := make([]string, len(to))
+	headerTo := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.Address
+		headerTo[i] = addr.String()
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(headerTo, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(msg.text, "\n", "\r\n"))); err != nil {
+		return mailSpec{}, err
+	}
+	if err := qp.Close(); err != nil {
+		return mailSpec{}, err
+	}
+
+	return mailSpec{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: ch.Username,
+		password: ch.Token,
+		from:     from.Address,
+		to:       recipients,
+		msg:      buf.Bytes(),
+	}, nil
+}
+
+// attemptMail performs one SMTP submission. The connection must be upgraded
+// with STARTTLS before any credentials or content are sent; a server that does
+// not offer it fails permanently. The bool reports whether the failure is
+// retryable: a network error or a transient (4xx) reply.
+func (n *notifier) attemptMail(ctx context.Context, spec mailSpec) (models.DeliveryResult, bool) {
+	dialer := net.Dialer{Timeout: deliverTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", spec.addr)
+	if err != nil {
+		return mailFailure(err, spec.password)
+	}
+	deadline := time.Now().Add(deliverTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	// net/smtp takes no context, so cancellation expires the deadline instead.
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, spec.host)
+	if err != nil {
+		conn.Close()
+		return mailFailure(err, spec.password)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return models.DeliveryResult{Status: "failed", Error: "smtp server does not offer STARTTLS"}, false
+	}
+	if err := c.StartTLS(&tls.Config{ServerName: spec.host, RootCAs: n.mailRoots, MinVersion: tls.VersionTLS12}); err != nil {
+		return mailFailure(err, spec.password)
+	}
+	if spec.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", spec.username, spec.password, spec.host)); err != nil {
+			return mailFailure(err, spec.password)
+		}
+	}
+	if err := c.Mail(spec.from); err != nil {
+		return mailFailure(err, spec.password)
+	}
+	for _, rcpt := range spec.to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return mailFailure(err, spec.password)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return mailFailure(err, spec.password)
+	}
+	if _, err := w.Write(spec.msg); err != nil {
+		return mailFailure(err, spec.password)
+	}
+	if err := w.Close(); err != nil {
+		return mailFailure(err, spec.password)
+	}
+	// The message is accepted once DATA completes; a failed QUIT is not a
+	// failed delivery.
+	_ = c.Quit()
+	return models.DeliveryResult{Status: "ok"}, false
+}
+
+// mailFailure classifies an SMTP error: permanent (5xx) replies are final,
+// transient (4xx) replies and network errors are retryable.
+func mailFailure(err error, secret string) (models.DeliveryResult, bool) {
+	result := models.DeliveryResult{Status: "failed", Error: redactSecret("smtp: "+err.Error(), secret)}
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return result, reply.Code >= 400 && reply.Code < 500
+	}
+	var tlsErr *tls.CertificateVerificationError
+	if errors.As(err, &tlsErr) {
+		return result, false
+	}
+	return result, true
+}
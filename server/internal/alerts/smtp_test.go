@@ -0,0 +1,243 @@
+package alerts
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+)
+
+// fakeSMTP is a minimal SMTP server standing in for a mail relay: it offers
+// STARTTLS (unless plainOnly), accepts AUTH PLAIN, and records each message.
+type fakeSMTP struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	roots     *x509.CertPool
+	plainOnly bool
+	rcptReply string // when set, every RCPT is answered with it
+
+	mu       sync.Mutex
+	sessions int
+	auth     string
+	secured  bool
+	from     string
+	to       []string
+	data     string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	// Borrow httptest's self-signed certificate (valid for 127.0.0.1).
+	certSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	certSrv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(certSrv.Certificate())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln, tlsConfig: &tls.Config{Certificates: certSrv.TLS.Certificates}, roots: roots}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) url() string { return "smtp://" + s.ln.Addr().String() }
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	s.mu.Lock()
+	s.sessions++
+	s.mu.Unlock()
+
+	tp := textproto.NewConn(conn)
+	secured := false
+	_ = tp.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250-fake")
+			if !secured && !s.plainOnly {
+				_ = tp.PrintfLine("250 STARTTLS")
+			} else {
+				_ = tp.PrintfLine("250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, secured = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			s.mu.Lock()
+			s.auth, s.secured = arg, secured
+			s.mu.Unlock()
+			_ = tp.PrintfLine("235 ok")
+		case "MAIL":
+			s.mu.Lock()
+			s.from, s.secured = arg, secured
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			if s.rcptReply != "" {
+				_ = tp.PrintfLine("%s", s.rcptReply)
+				continue
+			}
+			s.mu.Lock()
+			s.to = append(s.to, arg)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data = string(data)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 unsupported")
+		}
+	}
+}
+
+func smtpChannel(s *fakeSMTP) config.AlertChannel {
+	return config.AlertChannel{
+		ID: "c1", Type: "smtp", Enabled: true, URL: s.url(),
+		Username: "alerts", Token: "hunter2",
+		From:   "LogDeck <logdeck@example.com>",
+		Target: "ops@example.com, Manager <boss@example.com>",
+	}
+}
+
+func TestDeliverSMTPOverStartTLS(t *testing.T) {
+	s := newFakeSMTP(t)
+	n := newNotifier()
+	n.mailRoots = s.roots
+
+	ch := smtpChannel(s)
+	ch.TitleTemplate = "[{{upper .Host}}] {{.RuleName}}"
+	res := n.deliver(context.Background(), ch, testAlert(), nil)
+	if res.Status != "ok" {
+		t.Fatalf("result = %+v, want ok", res)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.secured {
+		t.Fatal("credentials and envelope were sent before STARTTLS")
+	}
+	wantAuth := "PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00alerts\x00hunter2"))
+	if s.auth != wantAuth {
+		t.Fatalf("AUTH = %q, want %q", s.auth, wantAuth)
+	}
+	if s.from != "FROM:<logdeck@example.com>" {
+		t.Fatalf("MAIL = %q", s.from)
+	}
+	if fmt.Sprint(s.to) != "[TO:<ops@example.com> TO:<boss@example.com>]" {
+		t.Fatalf("RCPT = %v, want both recipients", s.to)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(s.data))
+	if err != nil {
+		t.Fatalf("message does not parse: %v\n%s", err, s.data)
+	}
+	if got := msg.Header.Get("Subject"); got != "[LOCAL] High error rate" {
+		t.Fatalf("Subject = %q, want the templated title", got)
+	}
+	if got := msg.Header.Get("To"); got != `<ops@example.com>, "Manager" <boss@example.com>` {
+		t.Fatalf("To = %q", got)
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if got := strings.TrimSpace(string(body)); got != alertText(testAlert()) {
+		t.Fatalf("body = %q, want the alert text", got)
+	}
+}
+
+func TestDeliverSMTPRequiresStartTLS(t *testing.T) {
+	s := newFakeSMTP(t)
+	s.plainOnly = true
+	n := newNotifier()
+	n.mailRoots = s.roots
+
+	res := n.deliver(context.Background(), smtpChannel(s), testAlert(), nil)
+	if res.Status != "failed" || !strings.Contains(res.Error, "STARTTLS") {
+		t.Fatalf("result = %+v, want a STARTTLS failure", res)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.auth != "" || s.from != "" || s.sessions != 1 {
+		t.Fatalf("auth %q, from %q, %d sessions: want nothing sent over plaintext and no retry", s.auth, s.from, s.sessions)
+	}
+}
+
+func TestDeliverSMTPRetriesTransientReply(t *testing.T) {
+	s := newFakeSMTP(t)
+	s.rcptReply = "451 try again later"
+	n := newNotifier()
+	n.mailRoots = s.roots
+	n.retryDelay = 0
+
+	res := n.deliver(context.Background(), smtpChannel(s), testAlert(), nil)
+	if res.Status != "failed" || !strings.Contains(res.Error, "451") {
+		t.Fatalf("result = %+v, want the 451 reply", res)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions != 2 {
+		t.Fatalf("sessions = %d, want one retry of the transient failure", s.sessions)
+	}
+	if strings.Contains(res.Error, "hunter2") {
+		t.Fatalf("error %q leaks the password", res.Error)
+	}
+}
+
+func TestParseSMTPURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"smtp://mail.example.com":      "mail.example.com:587",
+		"smtp://mail.example.com:25":   "mail.example.com:25",
+		"smtp://mail.example.com:587/": "mail.example.com:587",
+	} {
+		host, port, err := ParseSMTPURL(raw)
+		if err != nil || net.JoinHostPort(host, port) != want {
+			t.Errorf("ParseSMTPURL(%q) = %q, %q, %v; want %s", raw, host, port, err, want)
+		}
+	}
+	for _, raw := range []string{"", "mail.example.com", "https://mail.example.com", "smtp://", "smtp://h/x"} {
+		if _, _, err := ParseSMTPURL(raw); err == nil {
+			t.Errorf("ParseSMTPURL(%q) accepted", raw)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// maxTemplateLen bounds one channel template, and maxRenderedLen what it may
+// render to.
+const (
+	maxTemplateLen = 4096
+	maxRenderedLen = 16384
+)
+
+// templateData is what a channel's title and body templates render against.
+// Alert is embedded so every field is addressable directly ({{.RuleName}},
+// {{.Host}}, {{.Project}}, {{.Sample}}, ...).
+type templateData struct {
+	models.Alert
+	// Resolved reports a resolution rather than a fire; Title and Text are
+	// the default title and summary LogDeck would otherwise send; SampleLines
+	// is Sample split into lines.
+	Resolved    bool
+	Title       string
+	Text        string
+	SampleLines []string
+}
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, so a webhook template can embed any
+	// field in a JSON body safely: {"text": {{json .Text}}}.
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"join":     func(sep string, s []string) string { return strings.Join(s, sep) },
+	"truncate": func(n int, s string) string { return truncateRunes(s, n) },
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with "…".
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if n < 1 || len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// channelHasTitle reports whether a channel type sends a title apart from
+// the message text: the ntfy Title header, the Gotify title, the email
+// subject, or the Teams card heading.
+func channelHasTitle(typ string) bool {
+	switch typ {
+	case "ntfy", "gotify", "smtp", "teams":
+		return true
+	}
+	return false
+}
+
+func newTemplateData(a models.Alert) templateData {
+	title := alertTitle
+	if a.State == models.AlertResolved {
+		title = resolvedTitle
+	}
+	var lines []string
+	if a.Sample != "" {
+		lines = strings.Split(a.Sample, "\n")
+	}
+	return templateData{
+		Alert:       a,
+		Resolved:    a.State == models.AlertResolved,
+		Title:       title,
+		Text:        alertText(a),
+		SampleLines: lines,
+	}
+}
+
+// renderTemplate parses and executes one channel template. name labels the
+// template in errors ("titleTemplate", "bodyTemplate").
+func renderTemplate(name, src string, data templateData) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	if buf.Len() > maxRenderedLen {
+		return "", fmt.Errorf("%s rendered %d bytes, more than the %d allowed", name, buf.Len(), maxRenderedLen)
+	}
+	return buf.String(), nil
+}
+
+// templateSampleAlert is the alert templates are validated and previewed
+// against: every field a template might read is populated, so a reference
+// to a missing field fails on save rather than on the first real alert.
+func templateSampleAlert(now time.Time, state string) models.Alert {
+	a := models.Alert{
+		ID:            "0a1b2c3d",
+		RuleID:        "r1",
+		RuleName:      "Error spike",
+		Type:          "log",
+		Severity:      SeverityWarning,
+		Host:          "local",
+		ContainerID:   "4f2a9c1e7b3d",
+		ContainerName: "web",
+		Project:       "shop",
+		Reason:        "5 matches (level >= ERROR) within 60s",
+		Sample:        "ERROR upstream timed out\nERROR retrying request",
+		Count:         5,
+		FiredAt:       now.UTC().Format(time.RFC3339),
+		State:         models.AlertFiring,
+	}
+	if state == models.AlertResolved {
+		a.State = models.AlertResolved
+		a.ResolvedAt = now.Add(5 * time.Minute).UTC().Format(time.RFC3339)
+		a.ResolveReason = "clear for 60s"
+		a.DurationSeconds = 300
+	}
+	return a
+}
+
+// ValidateTemplates checks a channel's templates: each must parse, and must
+// render against both a firing and a resolved sample alert. A webhook body
+// template must render valid JSON, since it is sent as the request body.
+func ValidateTemplates(ch config.AlertChannel) error {
+	if ch.TitleTemplate != "" && !channelHasTitle(ch.Type) {
+		return errors.New("titleTemplate only applies to ntfy, gotify, smtp, and teams channels")
+	}
+	for _, t := range []struct{ name, src string }{
+		{"titleTemplate", ch.TitleTemplate},
+		{"bodyTemplate", ch.BodyTemplate},
+	} {
+		if len(t.src) > maxTemplateLen {
+			return fmt.Errorf("%s must be at most %d characters", t.name, maxTemplateLen)
+		}
+	}
+	now := time.Now()
+	for _, state := range []string{models.AlertFiring, models.AlertResolved} {
+		msg, err := renderMessage(ch, templateSampleAlert(now, state))
+		if err != nil {
+			return err
+		}
+		if ch.Type == "webhook" && ch.BodyTemplate != "" && !json.Valid(msg.raw) {
+			return errors.New("bodyTemplate must render valid JSON for a webhook channel")
+		}
+	}
+	return nil
+}
+
+// message is one alert's rendered notification for a channel: the title for
+// channel types that carry one, the text, and for a templated webhook the
+// raw request body.
+type message struct {
+	title string
+	text  string
+	raw   []byte
+}
+
+// renderMessage renders alert for ch, applying the channel's templates over
+// the defaults.
+func renderMessage(ch config.AlertChannel, alert models.Alert) (message, error) {
+	data := newTemplateData(alert)
+	msg := message{title: data.Title, text: data.Text}
+	if ch.TitleTemplate != "" {
+		title, err := renderTemplate("titleTemplate", ch.TitleTemplate, data)
+		if err != nil {
+			return message{}, err
+		}
+		// Titles travel in a header for ntfy and email, which cannot hold
+		// newlines.
+		msg.title = strings.Join(strings.Fields(title), " ")
+	}
+	if ch.BodyTemplate != "" {
+		body, err := renderTemplate("bodyTemplate", ch.BodyTemplate, data)
+		if err != nil {
+			return message{}, err
+		}
+		if ch.Type == "webhook" {
+			msg.raw = []byte(body)
+		} else {
+			msg.text = body
+		}
+	}
+	return msg, nil
+}
+
+// previewChannel renders what ch would send for alert without sending it.
+func previewChannel(ch config.AlertChannel, alert models.Alert) (models.NotificationPreview, error) {
+	var body []byte
+	if ch.Type == "smtp" {
+		if _, err := buildMailSpec(ch, alert); err != nil {
+			return models.NotificationPreview{}, err
+		}
+	} else {
+		spec, err := buildRequestSpec(ch, alert, newAlertID())
+		if err != nil {
+			return models.NotificationPreview{}, err
+		}
+		body = spec.body
+	}
+	msg, err := renderMessage(ch, alert)
+	if err != nil {
+		return models.NotificationPreview{}, err
+	}
+	preview := models.NotificationPreview{Body: msg.text}
+	if channelHasTitle(ch.Type) {
+		preview.Title = msg.title
+	}
+	if ch.Type == "webhook" {
+		preview.Body = string(body)
+	}
+	return preview, nil
+}
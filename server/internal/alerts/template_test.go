@@ -0,0 +1,121 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+func TestDeliverWebhookBodyTemplate(t *testing.T) {
+	var cap capturedRequest
+	srv := newCapturingServer(t, &cap)
+	defer srv.Close()
+
+	ch := webhookChannel(srv.URL)
+	ch.BodyTemplate = `{"blocks":[{"type":"section","text":{"type":"mrkdwn","text":{{json (printf "*%s* on %s/%s (%s)" .RuleName .Host .ContainerName .Project)}}}}],"lines":{{json .SampleLines}}}`
+	alert := testAlert()
+	alert.Project = "shop"
+	alert.Sample = "ERROR a\nERROR b"
+	if res := newNotifier().deliver(context.Background(), ch, alert, nil); res.Status != "ok" {
+		t.Fatalf("result = %+v, want ok", res)
+	}
+	var body struct {
+		Blocks []struct {
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+		} `json:"blocks"`
+		Lines []string `json:"lines"`
+	}
+	if err := json.Unmarshal(cap.body, &body); err != nil {
+		t.Fatalf("body %q is not the templated JSON: %v", cap.body, err)
+	}
+	if len(body.Blocks) != 1 || body.Blocks[0].Text.Text != "*High error rate* on local/web (shop)" {
+		t.Fatalf("blocks = %+v", body.Blocks)
+	}
+	if len(body.Lines) != 2 || body.Lines[1] != "ERROR b" {
+		t.Fatalf("lines = %v, want the sample split into lines", body.Lines)
+	}
+}
+
+func TestDeliverNtfyTemplates(t *testing.T) {
+	var cap capturedRequest
+	srv := newCapturingServer(t, &cap)
+	defer srv.Close()
+
+	ch := config.AlertChannel{
+		ID: "c1", Type: "ntfy", Enabled: true, URL: srv.URL,
+		TitleTemplate: "{{if .Resolved}}OK{{else}}PAGE{{end}}\n{{upper .ContainerName}}",
+		BodyTemplate:  "{{.RuleName}}: {{truncate 8 .Reason}}",
+	}
+	if res := newNotifier().deliver(context.Background(), ch, testAlert(), nil); res.Status != "ok" {
+		t.Fatalf("result = %+v, want ok", res)
+	}
+	if cap.title != "PAGE WEB" {
+		t.Fatalf("Title header = %q, want the template on one line", cap.title)
+	}
+	if got := string(cap.body); got != "High error rate: 5 match…" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestDeliverTemplateErrorFails(t *testing.T) {
+	ch := webhookChannel("http://127.0.0.1:1")
+	ch.BodyTemplate = `{{.Nope}}`
+	res := newNotifier().deliver(context.Background(), ch, testAlert(), nil)
+	if res.Status != "failed" || !strings.HasPrefix(res.Error, "template:") {
+		t.Fatalf("result = %+v, want a template failure", res)
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	cases := []struct {
+		name    string
+		ch      config.AlertChannel
+		wantErr string
+	}{
+		{"no templates", config.AlertChannel{Type: "webhook"}, ""},
+		{"gotify title", config.AlertChannel{Type: "gotify", TitleTemplate: "{{.RuleName}}"}, ""},
+		{"webhook json", config.AlertChannel{Type: "webhook", BodyTemplate: `{"text":{{json .Text}}}`}, ""},
+		{"telegram body", config.AlertChannel{Type: "telegram", BodyTemplate: "{{.Host}}: {{join \", \" .SampleLines}}"}, ""},
+		{"parse error", config.AlertChannel{Type: "ntfy", BodyTemplate: "{{.RuleName"}, "bodyTemplate"},
+		{"unknown field", config.AlertChannel{Type: "ntfy", BodyTemplate: "{{.Priority}}"}, "Priority"},
+		{"title on webhook", config.AlertChannel{Type: "webhook", TitleTemplate: "x"}, "only applies"},
+		{"webhook not json", config.AlertChannel{Type: "webhook", BodyTemplate: "{{.Text}}"}, "valid JSON"},
+		{"too long", config.AlertChannel{Type: "ntfy", BodyTemplate: strings.Repeat("x", maxTemplateLen+1)}, "at most"},
+	}
+	for _, tc := range cases {
+		err := ValidateTemplates(tc.ch)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: error = %v, want it to mention %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestTestChannelDryRunPreview(t *testing.T) {
+	te := startTestEngine(t)
+
+	ch := config.AlertChannel{
+		ID: "c1", Type: "gotify", Enabled: true, URL: "http://127.0.0.1:1", Token: "t",
+		TitleTemplate: "{{.RuleName}}",
+		BodyTemplate:  "{{.Host}}/{{.ContainerName}}: {{.Reason}}",
+	}
+	res := te.e.TestChannel(context.Background(), ch, true)
+	if res.Status != "skipped" {
+		t.Fatalf("result = %+v, want a skipped dry run", res)
+	}
+	want := models.NotificationPreview{Title: "Test channel", Body: "local/web: test notification from LogDeck"}
+	if res.Preview == nil || *res.Preview != want {
+		t.Fatalf("preview = %+v, want %+v", res.Preview, want)
+	}
+}
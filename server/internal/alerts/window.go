@@ -20,11 +20,26 @@ type ruleWindow struct {
 	lastFired  time.Time
 	suppressed int
 	lastSeen   time.Time
+
+	// incident is the ID of the key's open incident, "" when none is firing,
+	// and ruleName the name of the rule that opened it.
+	// lastTrip is the last time the window tripped, fired or suppressed: the
+	// condition has cleared once a full window passes without another trip.
+	// awaitHealthy holds an incident opened by an unhealthy transition open
+	// until the container reports healthy, since an unhealthy container emits
+	// nothing further while it stays unhealthy.
+	incident     string
+	ruleName     string
+	lastTrip     time.Time
+	awaitHealthy bool
 }
 
 // observeResult reports the outcome of one recorded match.
 type observeResult struct {
-	fire bool
+	// tripped reports that the window held threshold matches, whether or
+	// not the cooldown let it fire.
+	tripped bool
+	fire    bool
 	// suppressed is the number of window trips swallowed by the cooldown
 	// since the previous fire; only meaningful when fire is true.
 	suppressed int
@@ -57,12 +72,13 @@ func (w *ruleWindow) observe(now time.Time) observeResult {
 	if w.count < w.threshold || now.Sub(w.times[w.head]) > w.window {
 		return observeResult{}
 	}
+	w.lastTrip = now
 	if w.fired && now.Sub(w.lastFired) < w.cooldown {
 		w.suppressed++
-		return observeResult{}
+		return observeResult{tripped: true}
 	}
 
-	res := observeResult{fire: true, suppressed: w.suppressed}
+	res := observeResult{tripped: true, fire: true, suppressed: w.suppressed}
 	w.head = 0
 	w.count = 0
 	w.fired = true
@@ -71,6 +87,12 @@ func (w *ruleWindow) observe(now time.Time) observeResult {
 	return res
 }
 
+// quiet reports whether the key's open incident has gone a full window
+// without a trip, so its condition has cleared.
+func (w *ruleWindow) quiet(now time.Time) bool {
+	return w.incident != "" && !w.awaitHealthy && now.Sub(w.lastTrip) >= w.window
+}
+
 // idleSince reports how long ago the key last saw a match, used by the run
 // loop to prune long-idle state.
 func (w *ruleWindow) idleSince(now time.Time) time.Duration {
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
 )
 
 // maxAggregateTargets bounds how many container streams one aggregate
@@ -34,6 +35,11 @@ func (ar *APIRouter) GetAggregatedLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if _, err := models.ParseFieldFilter(options.Filter); err != nil {
+		http.Error(w, fmt.Sprintf("invalid filter: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	if options.Follow {
 		stream, err := ar.registry.Docker().StreamAggregatedLogsParsed(r.Context(), targets, options)
 		if err != nil {
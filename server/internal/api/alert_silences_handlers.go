@@ -0,0 +1,446 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/alerts"
+	"github.com/AmoabaKelvin/logdeck/internal/auth"
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxAlertSilences          = 100
+	maxSilenceCommentLen      = 256
+	maxMaintenanceWindows     = 20
+	minMaintenanceWindowSecs  = 60
+	maxMaintenanceWindowSecs  = 7 * 86400
+	maxMaintenanceScheduleLen = 128
+)
+
+var (
+	errAlertSilenceLimit         = fmt.Errorf("maximum of %d active alert silences reached", maxAlertSilences)
+	errAlertSilenceNotFound      = errors.New("alert silence not found")
+	errMaintenanceWindowLimit    = fmt.Errorf("maximum of %d maintenance windows reached", maxMaintenanceWindows)
+	errMaintenanceWindowNotFound = errors.New("maintenance window not found")
+	errAlertRuleUnknown          = errors.New("unknown alert rule")
+)
+
+// alertSilenceRequest is the client-supplied portion of a silence. StartsAt
+// defaults to now; the end is given as EndsAt or DurationSeconds from the
+// start, not both.
+type alertSilenceRequest struct {
+	Hosts           []string `json:"hosts"`
+	Containers      []string `json:"containers"`
+	Projects        []string `json:"projects"`
+	Rules           []string `json:"rules"`
+	StartsAt        string   `json:"startsAt"`
+	EndsAt          string   `json:"endsAt"`
+	DurationSeconds int      `json:"durationSeconds"`
+	Comment         string   `json:"comment"`
+}
+
+// alertSilenceView is a silence as listed, with whether it is in effect.
+type alertSilenceView struct {
+	config.AlertSilence
+	Active bool `json:"active"`
+}
+
+// maintenanceWindowRequest is the client-supplied portion of a maintenance
+// window. Enabled is a pointer so an omitted value can default to true.
+type maintenanceWindowRequest struct {
+	Name            string   `json:"name"`
+	Enabled         *bool    `json:"enabled"`
+	Schedule        string   `json:"schedule"`
+	DurationSeconds int      `json:"durationSeconds"`
+	Timezone        string   `json:"timezone"`
+	Hosts           []string `json:"hosts"`
+	Containers      []string `json:"containers"`
+	Projects        []string `json:"projects"`
+	Rules           []string `json:"rules"`
+}
+
+// maintenanceWindowView is a window as listed: whether it is in effect and
+// since when, or when it next starts.
+type maintenanceWindowView struct {
+	config.MaintenanceWindow
+	Active      bool   `json:"active"`
+	ActiveSince string `json:"activeSince,omitempty"`
+	NextStart   string `json:"nextStart,omitempty"`
+}
+
+func newSilenceView(s config.AlertSilence, now time.Time) alertSilenceView {
+	return alertSilenceView{AlertSilence: s, Active: alerts.SilenceActive(s, now)}
+}
+
+func newMaintenanceWindowView(w config.MaintenanceWindow, now time.Time) maintenanceWindowView {
+	view := maintenanceWindowView{MaintenanceWindow: w}
+	active, start := alerts.MaintenanceStatus(w, now)
+	switch {
+	case active:
+		view.Active = true
+		view.ActiveSince = start.UTC().Format(time.RFC3339)
+	case !start.IsZero():
+		view.NextStart = start.UTC().Format(time.RFC3339)
+	}
+	return view
+}
+
+// normalizeSilenceTargets trims a silence's or window's targeting in place,
+// stripping the Docker API's leading "/" from container names, and rejects
+// empty entries.
+func normalizeSilenceTargets(hosts, containers, projects, rules []string) error {
+	for _, targets := range []struct {
+		field  string
+		values []string
+	}{
+		{"hosts", hosts},
+		{"containers", containers},
+		{"projects", projects},
+		{"rules", rules},
+	} {
+		for i, v := range targets.values {
+			v = strings.TrimSpace(v)
+			if targets.field == "containers" {
+				v = strings.TrimPrefix(v, "/")
+			}
+			if v == "" {
+				return fmt.Errorf("%s must not contain empty entries", targets.field)
+			}
+			targets.values[i] = v
+		}
+	}
+	return nil
+}
+
+// checkSilenceRules verifies that every rule a silence or window names exists.
+func checkSilenceRules(ids []string, rules []config.AlertRule) error {
+	for _, id := range ids {
+		if !slices.ContainsFunc(rules, func(r config.AlertRule) bool { return r.ID == id }) {
+			return fmt.Errorf("%w %q", errAlertRuleUnknown, id)
+		}
+	}
+	return nil
+}
+
+// buildAlertSilence validates a silence request into a config silence. ID,
+// CreatedBy, and CreatedAt are left empty for the caller to fill in.
+func buildAlertSilence(req alertSilenceRequest, now time.Time) (config.AlertSilence, error) {
+	s := config.AlertSilence{
+		Hosts:      req.Hosts,
+		Containers: req.Containers,
+		Projects:   req.Projects,
+		Rules:      req.Rules,
+		Comment:    strings.TrimSpace(req.Comment),
+	}
+	if err := normalizeSilenceTargets(s.Hosts, s.Containers, s.Projects, s.Rules); err != nil {
+		return s, err
+	}
+	if len(s.Comment) > maxSilenceCommentLen {
+		return s, fmt.Errorf("comment must be at most %d characters", maxSilenceCommentLen)
+	}
+
+	starts := now
+	if req.StartsAt != "" {
+		t, err := time.Parse(time.RFC3339, req.StartsAt)
+		if err != nil {
+			return s, errors.New("startsAt must be an RFC 3339 timestamp")
+		}
+		starts = t
+	}
+	var ends time.Time
+	switch {
+	case req.EndsAt != "" && req.DurationSeconds != 0:
+		return s, errors.New("set endsAt or durationSeconds, not both")
+	case req.EndsAt != "":
+		t, err := time.Parse(time.RFC3339, req.EndsAt)
+		if err != nil {
+			return s, errors.New("endsAt must be an RFC 3339 timestamp")
+		}
+		ends = t
+	case req.DurationSeconds > 0:
+		ends = starts.Add(time.Duration(req.DurationSeconds) * time.Second)
+	default:
+		return s, errors.New("endsAt or durationSeconds is required")
+	}
+	if !ends.After(starts) {
+		return s, errors.New("a silence must end after it starts")
+	}
+	if !ends.After(now) {
+		return s, errors.New("a silence must end in the future")
+	}
+	if ends.Sub(starts) > maxAlertSilenceSecs*time.Second {
+		return s, fmt.Errorf("a silence can last at most %d seconds", maxAlertSilenceSecs)
+	}
+	s.StartsAt = starts.UTC().Format(time.RFC3339)
+	s.EndsAt = ends.UTC().Format(time.RFC3339)
+	return s, nil
+}
+
+// ListAlertSilences handles GET /api/v1/alerts/silences.
+func (ar *APIRouter) ListAlertSilences(w http.ResponseWriter, r *http.Request) {
+	fc := ar.manager.FileConfigSnapshot()
+	now := time.Now()
+	silences := []alertSilenceView{}
+	if fc.Alerts != nil {
+		for _, s := range fc.Alerts.Silences {
+			silences = append(silences, newSilenceView(s, now))
+		}
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"silences": silences})
+}
+
+// CreateAlertSilence handles POST /api/v1/alerts/silences. Silences that have
+// already ended are pruned from the config as a new one is added.
+func (ar *APIRouter) CreateAlertSilence(w http.ResponseWriter, r *http.Request) {
+	var req alertSilenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	silence, err := buildAlertSilence(req, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := generateAlertID()
+	if err != nil {
+		http.Error(w, "failed to generate silence id", http.StatusInternalServerError)
+		return
+	}
+	silence.ID = id
+	silence.CreatedAt = now.UTC().Format(time.RFC3339)
+	if user, ok := r.Context().Value(auth.UserContextKey).(models.User); ok {
+		silence.CreatedBy = user.Username
+	}
+
+	err = ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
+		if err := checkSilenceRules(silence.Rules, current.Rules); err != nil {
+			return current, err
+		}
+		kept := current.Silences[:0]
+		for _, s := range current.Silences {
+			if ends, err := time.Parse(time.RFC3339, s.EndsAt); err == nil && ends.After(now) {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) >= maxAlertSilences {
+			return current, errAlertSilenceLimit
+		}
+		current.Silences = append(kept, silence)
+		return current, nil
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errAlertSilenceLimit) || errors.Is(err, errAlertRuleUnknown) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusCreated, newSilenceView(silence, now))
+}
+
+// DeleteAlertSilence handles DELETE /api/v1/alerts/silences/{id}, ending the
+// silence at once.
+func (ar *APIRouter) DeleteAlertSilence(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
+		next := slices.DeleteFunc(current.Silences, func(s config.AlertSilence) bool { return s.ID == id })
+		if len(next) == len(current.Silences) {
+			return current, errAlertSilenceNotFound
+		}
+		current.Silences = next
+		return current, nil
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errAlertSilenceNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "silence deleted"})
+}
+
+// buildMaintenanceWindow validates a window request into a config window. ID
+// and CreatedAt are left empty for the caller to fill in.
+func buildMaintenanceWindow(req maintenanceWindowRequest) (config.MaintenanceWindow, error) {
+	mw := config.MaintenanceWindow{
+		Name:            strings.TrimSpace(req.Name),
+		Enabled:         true,
+		Schedule:        strings.Join(strings.Fields(req.Schedule), " "),
+		DurationSeconds: req.DurationSeconds,
+		Timezone:        strings.TrimSpace(req.Timezone),
+		Hosts:           req.Hosts,
+		Containers:      req.Containers,
+		Projects:        req.Projects,
+		Rules:           req.Rules,
+	}
+	if req.Enabled != nil {
+		mw.Enabled = *req.Enabled
+	}
+
+	if mw.Name == "" {
+		return mw, errors.New("name is required")
+	}
+	if len(mw.Name) > maxAlertRuleNameLen {
+		return mw, fmt.Errorf("name must be at most %d characters", maxAlertRuleNameLen)
+	}
+	if mw.Schedule == "" {
+		return mw, errors.New("schedule is required")
+	}
+	if len(mw.Schedule) > maxMaintenanceScheduleLen {
+		return mw, fmt.Errorf("schedule must be at most %d characters", maxMaintenanceScheduleLen)
+	}
+	if mw.DurationSeconds < minMaintenanceWindowSecs || mw.DurationSeconds > maxMaintenanceWindowSecs {
+		return mw, fmt.Errorf("durationSeconds must be between %d and %d", minMaintenanceWindowSecs, maxMaintenanceWindowSecs)
+	}
+	if err := alerts.ValidateMaintenanceWindow(mw); err != nil {
+		return mw, err
+	}
+	if err := normalizeSilenceTargets(mw.Hosts, mw.Containers, mw.Projects, mw.Rules); err != nil {
+		return mw, err
+	}
+	return mw, nil
+}
+
+// ListMaintenanceWindows handles GET /api/v1/alerts/maintenance.
+func (ar *APIRouter) ListMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	fc := ar.manager.FileConfigSnapshot()
+	now := time.Now()
+	windows := []maintenanceWindowView{}
+	if fc.Alerts != nil {
+		for _, mw := range fc.Alerts.MaintenanceWindows {
+			windows = append(windows, newMaintenanceWindowView(mw, now))
+		}
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"windows": windows})
+}
+
+// CreateMaintenanceWindow handles POST /api/v1/alerts/maintenance.
+func (ar *APIRouter) CreateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	var req maintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	mw, err := buildMaintenanceWindow(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := generateAlertID()
+	if err != nil {
+		http.Error(w, "failed to generate window id", http.StatusInternalServerError)
+		return
+	}
+	mw.ID = id
+	mw.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	err = ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
+		if len(current.MaintenanceWindows) >= maxMaintenanceWindows {
+			return current, errMaintenanceWindowLimit
+		}
+		if err := checkSilenceRules(mw.Rules, current.Rules); err != nil {
+			return current, err
+		}
+		current.MaintenanceWindows = append(current.MaintenanceWindows, mw)
+		return current, nil
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errMaintenanceWindowLimit) || errors.Is(err, errAlertRuleUnknown) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusCreated, newMaintenanceWindowView(mw, time.Now()))
+}
+
+// UpdateMaintenanceWindow handles PUT /api/v1/alerts/maintenance/{id}.
+func (ar *APIRouter) UpdateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req maintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	mw, err := buildMaintenanceWindow(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
+		for i, existing := range current.MaintenanceWindows {
+			if existing.ID == id {
+				if err := checkSilenceRules(mw.Rules, current.Rules); err != nil {
+					return current, err
+				}
+				mw.ID = existing.ID
+				mw.CreatedAt = existing.CreatedAt
+				current.MaintenanceWindows[i] = mw
+				return current, nil
+			}
+		}
+		return current, errMaintenanceWindowNotFound
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errMaintenanceWindowNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, errAlertRuleUnknown):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, newMaintenanceWindowView(mw, time.Now()))
+}
+
+// DeleteMaintenanceWindow handles DELETE /api/v1/alerts/maintenance/{id}.
+func (ar *APIRouter) DeleteMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
+		next := slices.DeleteFunc(current.MaintenanceWindows, func(mw config.MaintenanceWindow) bool { return mw.ID == id })
+		if len(next) == len(current.MaintenanceWindows) {
+			return current, errMaintenanceWindowNotFound
+		}
+		current.MaintenanceWindows = next
+		return current, nil
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errMaintenanceWindowNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "maintenance window deleted"})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlertSilencesCRUD(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+	rule := createAlertRule(t, router, `{"name":"errors","type":"log","minLevel":"ERROR"}`)
+
+	w := doAlertsRequest(t, router, "GET", "/api/v1/alerts/silences", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"silences":[]`) {
+		t.Fatalf("expected an empty list, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := fmt.Sprintf(`{"projects":["shop"],"containers":["/web"],"rules":[%q],"durationSeconds":3600,"comment":" deploy "}`, rule.ID)
+	w = doAlertsRequest(t, router, "POST", "/api/v1/alerts/silences", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating silence, got %d: %s", w.Code, w.Body.String())
+	}
+	var created alertSilenceView
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to parse create response: %v", err)
+	}
+	starts, _ := time.Parse(time.RFC3339, created.StartsAt)
+	ends, _ := time.Parse(time.RFC3339, created.EndsAt)
+	if created.ID == "" || !created.Active || created.Comment != "deploy" || created.Containers[0] != "web" || ends.Sub(starts) != time.Hour {
+		t.Fatalf("silence = %+v, want an active, normalized one-hour silence", created)
+	}
+
+	// A scheduled silence is listed but not yet active.
+	start := time.Now().Add(24 * time.Hour).UTC()
+	body = fmt.Sprintf(`{"hosts":["prod"],"startsAt":%q,"endsAt":%q}`, start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+	if w := doAlertsRequest(t, router, "POST", "/api/v1/alerts/silences", body); w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"active":false`) {
+		t.Fatalf("expected 201 for an inactive scheduled silence, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doAlertsRequest(t, router, "DELETE", "/api/v1/alerts/silences/"+created.ID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 deleting silence, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAlertsRequest(t, router, "DELETE", "/api/v1/alerts/silences/"+created.ID, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting it again, got %d", w.Code)
+	}
+	var list struct {
+		Silences []alertSilenceView `json:"silences"`
+	}
+	w = doAlertsRequest(t, router, "GET", "/api/v1/alerts/silences", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Silences) != 1 || list.Silences[0].Hosts[0] != "prod" {
+		t.Fatalf("silences = %s, want only the scheduled one", w.Body.String())
+	}
+}
+
+func TestAlertSilenceValidation(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	for _, tc := range []struct {
+		name, body, want string
+	}{
+		{"no end", `{"projects":["shop"]}`, "endsAt or durationSeconds is required"},
+		{"both ends", fmt.Sprintf(`{"endsAt":%q,"durationSeconds":60}`, future), "not both"},
+		{"bad timestamp", `{"endsAt":"tomorrow"}`, "RFC 3339"},
+		{"ended", fmt.Sprintf(`{"startsAt":%q,"endsAt":%q}`, past, past), "end after it starts"},
+		{"in the past", fmt.Sprintf(`{"startsAt":%q,"durationSeconds":60}`, past), "end in the future"},
+		{"too long", fmt.Sprintf(`{"durationSeconds":%d}`, maxAlertSilenceSecs+1), "at most"},
+		{"empty target", `{"hosts":[" "],"durationSeconds":60}`, "hosts must not contain empty entries"},
+		{"unknown rule", `{"rules":["nope"],"durationSeconds":60}`, "unknown alert rule"},
+		{"long comment", fmt.Sprintf(`{"durationSeconds":60,"comment":%q}`, strings.Repeat("x", maxSilenceCommentLen+1)), "comment"},
+	} {
+		w := doAlertsRequest(t, router, "POST", "/api/v1/alerts/silences", tc.body)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: expected 400 containing %q, got %d: %s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMaintenanceWindowsCRUD(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+
+	w := doAlertsRequest(t, router, "POST", "/api/v1/alerts/maintenance",
+		`{"name":" nightly deploy ","schedule":" 0  3 * * 1-5 ","durationSeconds":1800,"timezone":"Europe/Berlin","projects":["shop"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating window, got %d: %s", w.Code, w.Body.String())
+	}
+	var created maintenanceWindowView
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to parse create response: %v", err)
+	}
+	if created.Name != "nightly deploy" || created.Schedule != "0 3 * * 1-5" || !created.Enabled || (created.NextStart == "" && created.ActiveSince == "") {
+		t.Fatalf("window = %+v, want a normalized, enabled window with its next start", created)
+	}
+
+	w = doAlertsRequest(t, router, "PUT", "/api/v1/alerts/maintenance/"+created.ID,
+		`{"name":"nightly deploy","enabled":false,"schedule":"0 3 * * *","durationSeconds":600}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"enabled":false`) || !strings.Contains(w.Body.String(), created.CreatedAt) {
+		t.Fatalf("expected 200 updating window, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAlertsRequest(t, router, "PUT", "/api/v1/alerts/maintenance/nope", `{"name":"x","schedule":"0 3 * * *","durationSeconds":600}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 updating an unknown window, got %d", w.Code)
+	}
+
+	for _, tc := range []struct {
+		name, body, want string
+	}{
+		{"no name", `{"schedule":"0 3 * * *","durationSeconds":600}`, "name is required"},
+		{"no schedule", `{"name":"x","durationSeconds":600}`, "schedule is required"},
+		{"bad schedule", `{"name":"x","schedule":"0 25 * * *","durationSeconds":600}`, "hour"},
+		{"short", `{"name":"x","schedule":"0 3 * * *","durationSeconds":30}`, "durationSeconds"},
+		{"bad timezone", `{"name":"x","schedule":"0 3 * * *","durationSeconds":600,"timezone":"Mars/Base"}`, "unknown timezone"},
+		{"unknown rule", `{"name":"x","schedule":"0 3 * * *","durationSeconds":600,"rules":["nope"]}`, "unknown alert rule"},
+	} {
+		w := doAlertsRequest(t, router, "POST", "/api/v1/alerts/maintenance", tc.body)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: expected 400 containing %q, got %d: %s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	if w := doAlertsRequest(t, router, "DELETE", "/api/v1/alerts/maintenance/"+created.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 deleting window, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doAlertsRequest(t, router, "GET", "/api/v1/alerts/maintenance", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"windows":[]`) {
+		t.Fatalf("expected an empty list after delete, got %d: %s", w.Code, w.Body.String())
+	}
+}
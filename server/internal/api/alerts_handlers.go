@@ -6,13 +6,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/mail"
 	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/AmoabaKelvin/logdeck/internal/alerts"
+	"github.com/AmoabaKelvin/logdeck/internal/auth"
 	"github.com/AmoabaKelvin/logdeck/internal/config"
 	"github.com/AmoabaKelvin/logdeck/internal/models"
 	"github.com/go-chi/chi/v5"
@@ -24,19 +30,36 @@ const (
 	maxAlertChannels       = 20
 	maxAlertChannelNameLen = 64
 	maxAlertThreshold      = 1000
-	minAlertWindowSecs     = 5
-	maxAlertWindowSecs     = 3600
-	maxAlertCooldownSecs   = 86400
+	maxAlertRoutes         = 20
+	// defaultLoopRestarts is the restart count a restart_loop rule fires on
+	// when it sets no threshold; the engine applies the same default.
+	defaultLoopRestarts = 3
+	minAlertWindowSecs  = 5
+	maxAlertWindowSecs  = 3600
+	// maxAbsenceWindowSecs lets an absence rule watch a daily job.
+	maxAbsenceWindowSecs = 86400
+	maxAlertCooldownSecs = 86400
 
 	defaultAlertHistoryLimit = 100
-	maxAlertHistoryLimit     = 500
+	maxAlertHistoryLimit     = alerts.MaxHistoryLimit
+	maxAlertHistoryDays      = 3650
+	minAlertHistoryEntries   = 100
+	maxAlertHistoryEntries   = 1000000
+	maxAlertSilenceSecs      = 30 * 86400
+	minAlertDeliverySecs     = 60
+	maxAlertDeliverySecs     = 7 * 86400
 )
 
+// alertDeliveryFilters are the values the history's delivery filter takes.
+var alertDeliveryFilters = []string{"ok", "failed", alerts.DeliveryRetrying, alerts.DeliverySuppressed, alerts.DeliveryNone}
+
 var (
 	errAlertRuleLimit       = fmt.Errorf("maximum of %d alert rules reached", maxAlertRules)
 	errAlertRuleNotFound    = errors.New("alert rule not found")
 	errAlertChannelLimit    = fmt.Errorf("maximum of %d alert channels reached", maxAlertChannels)
 	errAlertChannelNotFound = errors.New("alert channel not found")
+	errAlertChannelUnknown  = errors.New("unknown alert channel")
+	errAlertChannelInUse    = errors.New("alert channel is in use")
 )
 
 // alertRuleRequest is the client-supplied portion of an alert rule. Enabled is
@@ -51,9 +74,18 @@ type alertRuleRequest struct {
 	Events          []string `json:"events"`
 	MinLevel        string   `json:"minLevel"`
 	Pattern         string   `json:"pattern"`
+	Mode            string   `json:"mode"`
+	Metric          string   `json:"metric"`
+	Operator        string   `json:"operator"`
+	Value           float64  `json:"value"`
+	Aggregate       string   `json:"aggregate"`
 	Threshold       int      `json:"threshold"`
 	WindowSeconds   int      `json:"windowSeconds"`
 	CooldownSeconds int      `json:"cooldownSeconds"`
+
+	Severity string              `json:"severity"`
+	Channels []string            `json:"channels"`
+	Routes   []config.AlertRoute `json:"routes"`
 }
 
 // validAlertMinLevels are the log levels accepted for a log rule's minLevel.
@@ -70,9 +102,16 @@ var validAlertMinLevels = map[string]bool{
 }
 
 var validAlertEvents = map[string]bool{
-	"die":       true,
-	"oom":       true,
-	"unhealthy": true,
+	"die":          true,
+	"oom":          true,
+	"unhealthy":    true,
+	"restart_loop": true,
+}
+
+// validAlertMetrics are the container stats a metric rule can watch.
+var validAlertMetrics = map[string]bool{
+	"cpu_percent":    true,
+	"memory_percent": true,
 }
 
 // buildAlertRule validates and normalizes a rule request into a config rule.
@@ -88,9 +127,17 @@ func buildAlertRule(req alertRuleRequest) (config.AlertRule, error) {
 		Events:          req.Events,
 		MinLevel:        strings.ToUpper(strings.TrimSpace(req.MinLevel)),
 		Pattern:         strings.TrimSpace(req.Pattern),
+		Mode:            strings.TrimSpace(req.Mode),
+		Metric:          strings.TrimSpace(req.Metric),
+		Operator:        strings.TrimSpace(req.Operator),
+		Value:           req.Value,
+		Aggregate:       strings.TrimSpace(req.Aggregate),
 		Threshold:       req.Threshold,
 		WindowSeconds:   req.WindowSeconds,
 		CooldownSeconds: req.CooldownSeconds,
+		Severity:        strings.ToLower(strings.TrimSpace(req.Severity)),
+		Channels:        req.Channels,
+		Routes:          req.Routes,
 	}
 	if req.Enabled != nil {
 		rule.Enabled = *req.Enabled
@@ -124,6 +171,14 @@ func buildAlertRule(req alertRuleRequest) (config.AlertRule, error) {
 		}
 	}
 
+	if rule.Type != "metric" && (rule.Metric != "" || rule.Operator != "" || rule.Value != 0 || rule.Aggregate != "") {
+		return rule, errors.New("metric, operator, value, and aggregate only apply to a metric rule")
+	}
+
+	if rule.Type != "log" && rule.Mode != "" {
+		return rule, errors.New("mode only applies to a log rule")
+	}
+
 	switch rule.Type {
 	case "event":
 		if len(rule.Events) == 0 {
@@ -131,7 +186,17 @@ func buildAlertRule(req alertRuleRequest) (config.AlertRule, error) {
 		}
 		for _, ev := range rule.Events {
 			if !validAlertEvents[ev] {
-				return rule, fmt.Errorf("events contains invalid value %q (must be \"die\", \"oom\", or \"unhealthy\")", ev)
+				return rule, fmt.Errorf("events contains invalid value %q (must be \"die\", \"oom\", \"unhealthy\", or \"restart_loop\")", ev)
+			}
+		}
+		// A restart-loop rule's threshold counts restarts, so it cannot share
+		// a rule with events whose threshold counts occurrences.
+		if slices.Contains(rule.Events, "restart_loop") {
+			if len(rule.Events) > 1 {
+				return rule, errors.New("restart_loop must be the only event in its rule")
+			}
+			if rule.Threshold == 0 {
+				rule.Threshold = defaultLoopRestarts
 			}
 		}
 		if rule.MinLevel != "" {
@@ -144,8 +209,15 @@ func buildAlertRule(req alertRuleRequest) (config.AlertRule, error) {
 		if len(rule.Events) > 0 {
 			return rule, errors.New("events must be empty for a log rule")
 		}
-		if rule.MinLevel == "" && rule.Pattern == "" {
-			return rule, errors.New("a log rule requires at least one of minLevel or pattern")
+		switch rule.Mode {
+		case "":
+			if rule.MinLevel == "" && rule.Pattern == "" {
+				return rule, errors.New("a log rule requires at least one of minLevel or pattern")
+			}
+		case "absence":
+			// With neither filter an absence rule expects any line at all.
+		default:
+			return rule, fmt.Errorf("mode %q is invalid (must be empty or \"absence\")", rule.Mode)
 		}
 		if rule.MinLevel != "" && !validAlertMinLevels[rule.MinLevel] {
 			return rule, fmt.Errorf("minLevel %q is not a valid log level", rule.MinLevel)
@@ -155,10 +227,42 @@ func buildAlertRule(req alertRuleRequest) (config.AlertRule, error) {
 				return rule, fmt.Errorf("pattern is not a valid regular expression: %v", err)
 			}
 		}
+	case "metric":
+		if len(rule.Events) > 0 || rule.MinLevel != "" || rule.Pattern != "" {
+			return rule, errors.New("events, minLevel, and pattern must be empty for a metric rule")
+		}
+		if !validAlertMetrics[rule.Metric] {
+			return rule, errors.New("metric is required for a metric rule (must be \"cpu_percent\" or \"memory_percent\")")
+		}
+		switch rule.Operator {
+		case "":
+			rule.Operator = ">"
+		case ">", "<":
+		default:
+			return rule, fmt.Errorf("operator %q is invalid (must be \">\" or \"<\")", rule.Operator)
+		}
+		if rule.Value < 0 || math.IsNaN(rule.Value) || math.IsInf(rule.Value, 0) {
+			return rule, errors.New("value must be a non-negative number")
+		}
+		if rule.Metric == "memory_percent" && rule.Value > 100 {
+			return rule, errors.New("value must be at most 100 for memory_percent")
+		}
+		switch rule.Aggregate {
+		case "":
+			rule.Aggregate = "sustained"
+		case "sustained", "avg":
+		default:
+			return rule, fmt.Errorf("aggregate %q is invalid (must be \"sustained\" or \"avg\")", rule.Aggregate)
+		}
+		// The window is how long the condition must hold, not a match
+		// count, so a threshold means nothing here.
+		if rule.Threshold > 1 {
+			return rule, errors.New("threshold does not apply to a metric rule; use windowSeconds")
+		}
 	case "":
 		return rule, errors.New("type is required")
 	default:
-		return rule, fmt.Errorf("type %q is invalid (must be \"event\" or \"log\")", rule.Type)
+		return rule, fmt.Errorf("type %q is invalid (must be \"event\", \"log\", or \"metric\")", rule.Type)
 	}
 
 	if rule.Threshold < 0 {
@@ -174,10 +278,14 @@ func buildAlertRule(req alertRuleRequest) (config.AlertRule, error) {
 	if rule.WindowSeconds < 0 {
 		return rule, errors.New("windowSeconds must not be negative")
 	}
+	maxWindow := maxAlertWindowSecs
+	if rule.Mode == "absence" {
+		maxWindow = maxAbsenceWindowSecs
+	}
 	if rule.WindowSeconds == 0 {
 		rule.WindowSeconds = 60
-	} else if rule.WindowSeconds < minAlertWindowSecs || rule.WindowSeconds > maxAlertWindowSecs {
-		return rule, fmt.Errorf("windowSeconds must be between %d and %d", minAlertWindowSecs, maxAlertWindowSecs)
+	} else if rule.WindowSeconds < minAlertWindowSecs || rule.WindowSeconds > maxWindow {
+		return rule, fmt.Errorf("windowSeconds must be between %d and %d", minAlertWindowSecs, maxWindow)
 	}
 
 	if rule.CooldownSeconds < 0 {
@@ -187,9 +295,85 @@ func buildAlertRule(req alertRuleRequest) (config.AlertRule, error) {
 		return rule, fmt.Errorf("cooldownSeconds must be at most %d", maxAlertCooldownSecs)
 	}
 
+	if !alerts.ValidSeverity(rule.Severity) {
+		return rule, fmt.Errorf("severity %q is invalid (must be \"info\", \"warning\", or \"critical\")", rule.Severity)
+	}
+	var err error
+	if rule.Channels, err = normalizeChannelIDs("channels", rule.Channels); err != nil {
+		return rule, err
+	}
+	if len(rule.Routes) > maxAlertRoutes {
+		return rule, fmt.Errorf("routes must have at most %d entries", maxAlertRoutes)
+	}
+	for i := range rule.Routes {
+		route := &rule.Routes[i]
+		field := fmt.Sprintf("routes[%d]", i)
+		for j, c := range route.Containers {
+			route.Containers[j] = strings.TrimPrefix(strings.TrimSpace(c), "/")
+		}
+		for _, targets := range [][]string{route.Hosts, route.Containers, route.Projects} {
+			for _, v := range targets {
+				if strings.TrimSpace(v) == "" {
+					return rule, fmt.Errorf("%s must not contain empty targets", field)
+				}
+			}
+		}
+		if route.Channels, err = normalizeChannelIDs(field+".channels", route.Channels); err != nil {
+			return rule, err
+		}
+		if len(route.Channels) == 0 {
+			return rule, fmt.Errorf("%s.channels is required", field)
+		}
+	}
+
 	return rule, nil
 }
 
+// normalizeChannelIDs trims and de-duplicates a rule's channel ID list,
+// keeping the first occurrence of each.
+func normalizeChannelIDs(field string, ids []string) ([]string, error) {
+	var out []string
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return nil, fmt.Errorf("%s must not contain empty entries", field)
+		}
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// checkRuleChannels verifies that every channel a rule routes to exists.
+func checkRuleChannels(rule config.AlertRule, channels []config.AlertChannel) error {
+	ids := slices.Clone(rule.Channels)
+	for _, route := range rule.Routes {
+		ids = append(ids, route.Channels...)
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(channels, func(ch config.AlertChannel) bool { return ch.ID == id }) {
+			return fmt.Errorf("%w %q", errAlertChannelUnknown, id)
+		}
+	}
+	return nil
+}
+
+// ruleUsingChannel returns the first rule that routes to the channel id.
+func ruleUsingChannel(rules []config.AlertRule, id string) (config.AlertRule, bool) {
+	for _, rule := range rules {
+		if slices.Contains(rule.Channels, id) {
+			return rule, true
+		}
+		for _, route := range rule.Routes {
+			if slices.Contains(route.Channels, id) {
+				return rule, true
+			}
+		}
+	}
+	return config.AlertRule{}, false
+}
+
 // generateAlertID returns a random 8-character hex identifier for a rule or
 // channel.
 func generateAlertID() (string, error) {
@@ -244,12 +428,15 @@ func (ar *APIRouter) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
 		if len(current.Rules) >= maxAlertRules {
 			return current, errAlertRuleLimit
 		}
+		if err := checkRuleChannels(rule, current.Channels); err != nil {
+			return current, err
+		}
 		current.Rules = append(current.Rules, rule)
 		return current, nil
 	})
 	if err != nil {
 		status := http.StatusInternalServerError
-		if errors.Is(err, errAlertRuleLimit) {
+		if errors.Is(err, errAlertRuleLimit) || errors.Is(err, errAlertChannelUnknown) {
 			status = http.StatusBadRequest
 		}
 		http.Error(w, err.Error(), status)
@@ -279,6 +466,9 @@ func (ar *APIRouter) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
 	err = ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
 		for i, existing := range current.Rules {
 			if existing.ID == id {
+				if err := checkRuleChannels(rule, current.Channels); err != nil {
+					return current, err
+				}
 				rule.ID = existing.ID
 				rule.CreatedAt = existing.CreatedAt
 				current.Rules[i] = rule
@@ -289,8 +479,11 @@ func (ar *APIRouter) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
 	})
 	if err != nil {
 		status := http.StatusInternalServerError
-		if errors.Is(err, errAlertRuleNotFound) {
+		switch {
+		case errors.Is(err, errAlertRuleNotFound):
 			status = http.StatusNotFound
+		case errors.Is(err, errAlertChannelUnknown):
+			status = http.StatusBadRequest
 		}
 		http.Error(w, err.Error(), status)
 		return
@@ -339,13 +532,23 @@ type alertChannelRequest struct {
 	URL     string `json:"url"`
 	Token   string `json:"token"`
 	Target  string `json:"target"`
+
+	Username string `json:"username"`
+	From     string `json:"from"`
+
+	TitleTemplate string `json:"titleTemplate"`
+	BodyTemplate  string `json:"bodyTemplate"`
 }
 
 var validAlertChannelTypes = map[string]bool{
-	"webhook":  true,
-	"ntfy":     true,
-	"gotify":   true,
-	"telegram": true,
+	"webhook":   true,
+	"ntfy":      true,
+	"gotify":    true,
+	"telegram":  true,
+	"smtp":      true,
+	"pagerduty": true,
+	"matrix":    true,
+	"teams":     true,
 }
 
 // validateHTTPURL rejects empty or non-http(s) URLs with a field-named error.
@@ -371,20 +574,33 @@ func buildAlertChannel(req alertChannelRequest) (config.AlertChannel, error) {
 		URL:     strings.TrimSpace(req.URL),
 		Token:   strings.TrimSpace(req.Token),
 		Target:  strings.TrimSpace(req.Target),
+
+		Username: strings.TrimSpace(req.Username),
+		From:     strings.TrimSpace(req.From),
+
+		TitleTemplate: strings.TrimSpace(req.TitleTemplate),
+		BodyTemplate:  req.BodyTemplate,
+	}
+	if strings.TrimSpace(ch.BodyTemplate) == "" {
+		ch.BodyTemplate = ""
 	}
 	if req.Enabled != nil {
 		ch.Enabled = *req.Enabled
 	}
 
 	if !validAlertChannelTypes[ch.Type] {
-		return ch, fmt.Errorf("type %q is invalid (must be \"webhook\", \"ntfy\", \"gotify\", or \"telegram\")", ch.Type)
+		return ch, fmt.Errorf("type %q is invalid (must be \"webhook\", \"ntfy\", \"gotify\", \"telegram\", \"smtp\", \"pagerduty\", \"matrix\", or \"teams\")", ch.Type)
 	}
 	if len(ch.Name) > maxAlertChannelNameLen {
 		return ch, fmt.Errorf("name must be at most %d characters", maxAlertChannelNameLen)
 	}
 
+	if ch.Type != "smtp" {
+		ch.Username = ""
+		ch.From = ""
+	}
 	switch ch.Type {
-	case "webhook", "ntfy":
+	case "webhook", "ntfy", "teams":
 		if err := validateHTTPURL(ch.URL); err != nil {
 			return ch, err
 		}
@@ -406,17 +622,79 @@ func buildAlertChannel(req alertChannelRequest) (config.AlertChannel, error) {
 			return ch, errors.New("target is required for a telegram channel (the chat id)")
 		}
 		ch.URL = ""
+	case "smtp":
+		if _, _, err := alerts.ParseSMTPURL(ch.URL); err != nil {
+			return ch, err
+		}
+		if _, err := mail.ParseAddress(ch.From); err != nil {
+			return ch, errors.New("from must be a valid email address for an smtp channel")
+		}
+		if ch.Target == "" {
+			return ch, errors.New("target is required for an smtp channel (the recipients)")
+		}
+		if _, err := mail.ParseAddressList(ch.Target); err != nil {
+			return ch, errors.New("target must be a comma-separated list of email addresses")
+		}
+		if ch.Username != "" && ch.Token == "" {
+			return ch, errors.New("token is required with a username (the smtp password)")
+		}
+	case "pagerduty":
+		if ch.Token == "" {
+			return ch, errors.New("token is required for a pagerduty channel (the integration routing key)")
+		}
+		// The URL is optional: it overrides the Events API endpoint, e.g. for
+		// PagerDuty's EU service region.
+		if ch.URL != "" {
+			if err := validateHTTPURL(ch.URL); err != nil {
+				return ch, err
+			}
+		}
+		ch.Target = ""
+	case "matrix":
+		if err := validateHTTPURL(ch.URL); err != nil {
+			return ch, err
+		}
+		if ch.Token == "" {
+			return ch, errors.New("token is required for a matrix channel (the access token)")
+		}
+		if !strings.HasPrefix(ch.Target, "!") || !strings.Contains(ch.Target, ":") {
+			return ch, errors.New("target must be a matrix room ID (!room:server) for a matrix channel")
+		}
 	}
 
+	if err := alerts.ValidateTemplates(ch); err != nil {
+		return ch, err
+	}
 	return ch, nil
 }
 
+// alertChannelView is a configured channel with its delivery health, absent
+// for a channel that has never been attempted.
+type alertChannelView struct {
+	config.AlertChannel
+	Health *models.ChannelHealth `json:"health,omitempty"`
+}
+
 // ListAlertChannels handles GET /api/v1/alerts/channels.
 func (ar *APIRouter) ListAlertChannels(w http.ResponseWriter, r *http.Request) {
 	fc := ar.manager.FileConfigSnapshot()
-	channels := []config.AlertChannel{}
+	var health map[string]models.ChannelHealth
+	if ar.engine != nil {
+		var err error
+		if health, err = ar.engine.ChannelHealth(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	channels := []alertChannelView{}
 	if fc.Alerts != nil {
-		channels = append(channels, fc.Alerts.Channels...)
+		for _, ch := range fc.Alerts.Channels {
+			view := alertChannelView{AlertChannel: ch}
+			if h, ok := health[ch.ID]; ok {
+				view.Health = &h
+			}
+			channels = append(channels, view)
+		}
 	}
 	WriteJsonResponse(w, http.StatusOK, map[string]any{"channels": channels})
 }
@@ -506,6 +784,11 @@ func (ar *APIRouter) DeleteAlertChannel(w http.ResponseWriter, r *http.Request)
 	id := chi.URLParam(r, "id")
 
 	err := ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
+		// A rule routing to the channel would silently lose the route, so it
+		// must be edited first.
+		if rule, ok := ruleUsingChannel(current.Rules, id); ok {
+			return current, fmt.Errorf("%w by rule %q", errAlertChannelInUse, rule.Name)
+		}
 		next := current.Channels[:0]
 		for _, ch := range current.Channels {
 			if ch.ID != id {
@@ -520,8 +803,11 @@ func (ar *APIRouter) DeleteAlertChannel(w http.ResponseWriter, r *http.Request)
 	})
 	if err != nil {
 		status := http.StatusInternalServerError
-		if errors.Is(err, errAlertChannelNotFound) {
+		switch {
+		case errors.Is(err, errAlertChannelNotFound):
 			status = http.StatusNotFound
+		case errors.Is(err, errAlertChannelInUse):
+			status = http.StatusConflict
 		}
 		http.Error(w, err.Error(), status)
 		return
@@ -532,9 +818,10 @@ func (ar *APIRouter) DeleteAlertChannel(w http.ResponseWriter, r *http.Request)
 }
 
 // TestAlertChannel handles POST /api/v1/alerts/channels/{id}/test. It delivers
-// a synthetic alert to the one channel and returns the delivery result with a
-// 200 whether or not the delivery itself succeeded; the result's status field
-// carries the outcome.
+// a synthetic alert to the one channel and returns the delivery result, with
+// the notification as the channel's templates render it, with a 200 whether or
+// not the delivery itself succeeded; the result's status field carries the
+// outcome. ?dryRun=true renders the preview without delivering.
 func (ar *APIRouter) TestAlertChannel(w http.ResponseWriter, r *http.Request) {
 	if ar.engine == nil {
 		http.Error(w, "alerting engine not available", http.StatusInternalServerError)
@@ -559,41 +846,169 @@ func (ar *APIRouter) TestAlertChannel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := ar.engine.TestChannel(r.Context(), channel)
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	result := ar.engine.TestChannel(r.Context(), channel, dryRun)
 	WriteJsonResponse(w, http.StatusOK, result)
 }
 
-// GetAlertHistory handles GET /api/v1/alerts/history.
+// GetAlertHistory handles GET /api/v1/alerts/history. Every filter is
+// optional: rule (a rule ID), host, container, project, state, delivery,
+// since/until (RFC 3339, on the fire time), limit, and the cursor of the
+// previous page.
 func (ar *APIRouter) GetAlertHistory(w http.ResponseWriter, r *http.Request) {
 	if ar.engine == nil {
 		http.Error(w, "alerting engine not available", http.StatusInternalServerError)
 		return
 	}
 
-	limit := defaultAlertHistoryLimit
-	if raw := r.URL.Query().Get("limit"); raw != "" {
+	params := r.URL.Query()
+	query := alerts.HistoryQuery{
+		RuleID:    params.Get("rule"),
+		Host:      params.Get("host"),
+		Container: strings.TrimPrefix(params.Get("container"), "/"),
+		Project:   params.Get("project"),
+		State:     params.Get("state"),
+		Delivery:  params.Get("delivery"),
+		Limit:     defaultAlertHistoryLimit,
+		Cursor:    params.Get("cursor"),
+	}
+	if raw := params.Get("limit"); raw != "" {
 		parsed, err := strconv.Atoi(raw)
 		if err != nil {
 			http.Error(w, "limit must be an integer", http.StatusBadRequest)
 			return
 		}
-		limit = parsed
+		query.Limit = min(max(parsed, 1), maxAlertHistoryLimit)
 	}
-	if limit < 1 {
-		limit = 1
+	if query.State != "" && query.State != models.AlertFiring && query.State != models.AlertResolved {
+		http.Error(w, "state must be firing or resolved", http.StatusBadRequest)
+		return
 	}
-	if limit > maxAlertHistoryLimit {
-		limit = maxAlertHistoryLimit
+	if query.Delivery != "" && !slices.Contains(alertDeliveryFilters, query.Delivery) {
+		http.Error(w, "delivery must be one of "+strings.Join(alertDeliveryFilters, ", "), http.StatusBadRequest)
+		return
+	}
+	for _, param := range []struct {
+		name string
+		dest *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		value := params.Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: expected an RFC3339 timestamp", param.name), http.StatusBadRequest)
+			return
+		}
+		*param.dest = parsed
 	}
 
-	alerts := ar.engine.History(limit)
-	if alerts == nil {
-		alerts = []models.Alert{}
+	page, err := ar.engine.QueryHistory(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, alerts.ErrInvalidHistoryCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
-		"alerts": alerts,
-		"count":  len(alerts),
+		"alerts":     page.Alerts,
+		"count":      len(page.Alerts),
+		"nextCursor": page.NextCursor,
+	})
+}
+
+// GetAlertHistoryRetention handles GET /api/v1/alerts/history/retention,
+// reporting the effective bounds.
+func (ar *APIRouter) GetAlertHistoryRetention(w http.ResponseWriter, r *http.Request) {
+	fc := ar.manager.FileConfigSnapshot()
+	var current config.AlertsConfig
+	if fc.Alerts != nil {
+		current = *fc.Alerts
+	}
+	WriteJsonResponse(w, http.StatusOK, current.HistoryRetention())
+}
+
+// UpdateAlertHistoryRetention handles PUT /api/v1/alerts/history/retention.
+// A zero bound restores its default. Lowered bounds are applied at once.
+func (ar *APIRouter) UpdateAlertHistoryRetention(w http.ResponseWriter, r *http.Request) {
+	var req config.AlertHistoryConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.RetentionDays < 0 || req.RetentionDays > maxAlertHistoryDays {
+		http.Error(w, fmt.Sprintf("retentionDays must be between 1 and %d, or 0 for the default", maxAlertHistoryDays), http.StatusBadRequest)
+		return
+	}
+	if req.MaxEntries != 0 && (req.MaxEntries < minAlertHistoryEntries || req.MaxEntries > maxAlertHistoryEntries) {
+		http.Error(w, fmt.Sprintf("maxEntries must be between %d and %d, or 0 for the default", minAlertHistoryEntries, maxAlertHistoryEntries), http.StatusBadRequest)
+		return
+	}
+
+	var updated config.AlertsConfig
+	err := ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
+		current.History = &req
+		if req == (config.AlertHistoryConfig{}) {
+			current.History = nil
+		}
+		updated = current
+		return current, nil
 	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ar.engine != nil {
+		ar.engine.RetainHistory()
+	}
+	WriteJsonResponse(w, http.StatusOK, updated.HistoryRetention())
+}
+
+// GetAlertDelivery handles GET /api/v1/alerts/delivery, reporting the
+// effective delivery deadline.
+func (ar *APIRouter) GetAlertDelivery(w http.ResponseWriter, r *http.Request) {
+	fc := ar.manager.FileConfigSnapshot()
+	var current config.AlertsConfig
+	if fc.Alerts != nil {
+		current = *fc.Alerts
+	}
+	WriteJsonResponse(w, http.StatusOK, alertDeliveryResponse(current))
+}
+
+// UpdateAlertDelivery handles PUT /api/v1/alerts/delivery. A zero deadline
+// restores the default. The deadline applies to notifications already queued.
+func (ar *APIRouter) UpdateAlertDelivery(w http.ResponseWriter, r *http.Request) {
+	var req config.AlertDeliveryConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DeadlineSeconds != 0 && (req.DeadlineSeconds < minAlertDeliverySecs || req.DeadlineSeconds > maxAlertDeliverySecs) {
+		http.Error(w, fmt.Sprintf("deadlineSeconds must be between %d and %d, or 0 for the default", minAlertDeliverySecs, maxAlertDeliverySecs), http.StatusBadRequest)
+		return
+	}
+
+	var updated config.AlertsConfig
+	err := ar.manager.UpdateAlerts(func(current config.AlertsConfig) (config.AlertsConfig, error) {
+		current.Delivery = &req
+		if req == (config.AlertDeliveryConfig{}) {
+			current.Delivery = nil
+		}
+		updated = current
+		return current, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, alertDeliveryResponse(updated))
+}
+
+func alertDeliveryResponse(c config.AlertsConfig) config.AlertDeliveryConfig {
+	return config.AlertDeliveryConfig{DeadlineSeconds: int(c.DeliveryDeadline() / time.Second)}
 }
 
 // ClearAlertHistory handles DELETE /api/v1/alerts/history.
@@ -605,3 +1020,64 @@ func (ar *APIRouter) ClearAlertHistory(w http.ResponseWriter, r *http.Request) {
 	ar.engine.ClearHistory()
 	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "history cleared"})
 }
+
+// AcknowledgeAlert handles POST /api/v1/alerts/history/{id}/ack. An
+// acknowledged incident stays open but stops re-notifying; it still notifies
+// when it resolves.
+func (ar *APIRouter) AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
+	if ar.engine == nil {
+		http.Error(w, "alerting engine not available", http.StatusInternalServerError)
+		return
+	}
+
+	by := ""
+	if user, ok := r.Context().Value(auth.UserContextKey).(models.User); ok {
+		by = user.Username
+	}
+	alert, err := ar.engine.Acknowledge(chi.URLParam(r, "id"), by)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, alert)
+}
+
+// SilenceAlert handles POST /api/v1/alerts/history/{id}/silence. The body's
+// durationSeconds bounds the silence; 0 or an empty body silences the
+// incident until it resolves.
+func (ar *APIRouter) SilenceAlert(w http.ResponseWriter, r *http.Request) {
+	if ar.engine == nil {
+		http.Error(w, "alerting engine not available", http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		DurationSeconds int `json:"durationSeconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DurationSeconds < 0 || req.DurationSeconds > maxAlertSilenceSecs {
+		http.Error(w, fmt.Sprintf("durationSeconds must be between 0 and %d", maxAlertSilenceSecs), http.StatusBadRequest)
+		return
+	}
+
+	alert, err := ar.engine.Silence(chi.URLParam(r, "id"), time.Duration(req.DurationSeconds)*time.Second)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, alert)
+}
+
+func writeIncidentError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, alerts.ErrAlertNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, alerts.ErrAlertResolved):
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
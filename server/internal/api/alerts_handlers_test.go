@@ -37,7 +37,7 @@ func newAlertsTestRouter(t *testing.T, authSvc *auth.Service) (http.Handler, str
 	manager := config.NewManager()
 	registry := services.NewRegistry(nil, nil, authSvc, manager.Config())
 	engine := alerts.NewEngine(registry, manager, nil)
-	return NewRouter(registry, manager, engine, nil, "test"), configPath
+	return NewRouter(RouterDeps{Registry: registry, Manager: manager, Alerts: engine, Version: "test"}), configPath
 }
 
 func doAlertsRequest(t *testing.T, router http.Handler, method, path, body string) *httptest.ResponseRecorder {
@@ -169,13 +169,52 @@ func TestCreateAlertRuleAcceptsUnhealthyEvent(t *testing.T) {
 	}
 }
 
+func TestCreateRestartLoopAlertRule(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+	created := createAlertRule(t, router, `{"name":"loop","type":"event","events":["restart_loop"],"windowSeconds":300}`)
+	if created.Threshold != 3 || created.WindowSeconds != 300 {
+		t.Fatalf("unexpected restart_loop rule: %+v", created)
+	}
+	created = createAlertRule(t, router, `{"name":"loop5","type":"event","events":["restart_loop"],"threshold":5}`)
+	if created.Threshold != 5 {
+		t.Fatalf("unexpected restart_loop rule: %+v", created)
+	}
+}
+
+func TestCreateMetricAlertRule(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+	created := createAlertRule(t, router, `{"name":"memory","type":"metric","metric":"memory_percent","value":90,"windowSeconds":300}`)
+	if created.Metric != "memory_percent" || created.Operator != ">" || created.Value != 90 || created.Aggregate != "sustained" || created.WindowSeconds != 300 || created.Threshold != 1 {
+		t.Fatalf("unexpected metric rule: %+v", created)
+	}
+
+	created = createAlertRule(t, router, `{"name":"cpu","type":"metric","metric":"cpu_percent","operator":"<","value":0.5,"aggregate":"avg","windowSeconds":120}`)
+	if created.Operator != "<" || created.Value != 0.5 || created.Aggregate != "avg" {
+		t.Fatalf("unexpected metric rule: %+v", created)
+	}
+}
+
+func TestCreateAbsenceAlertRule(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+	created := createAlertRule(t, router, `{"name":"backup ran","type":"log","mode":"absence","pattern":"backup complete","containers":["backup"],"windowSeconds":86400}`)
+	if created.Mode != "absence" || created.Pattern != "backup complete" || created.WindowSeconds != 86400 || created.Threshold != 1 {
+		t.Fatalf("unexpected absence rule: %+v", created)
+	}
+
+	// With no filters an absence rule expects any line at all.
+	created = createAlertRule(t, router, `{"name":"consumer quiet","type":"log","mode":"absence","containers":["consumer"],"threshold":10,"windowSeconds":300}`)
+	if created.Mode != "absence" || created.Threshold != 10 {
+		t.Fatalf("unexpected absence rule: %+v", created)
+	}
+}
+
 var alertRuleValidationCases = []struct{ name, body string }{
 	{"invalid json", `{`},
 	{"missing name", `{"type":"log","minLevel":"ERROR"}`},
 	{"whitespace name", `{"name":"   ","type":"log","minLevel":"ERROR"}`},
 	{"name too long", fmt.Sprintf(`{"name":%q,"type":"log","minLevel":"ERROR"}`, strings.Repeat("a", 65))},
 	{"missing type", `{"name":"r"}`},
-	{"invalid type", `{"name":"r","type":"metric"}`},
+	{"invalid type", `{"name":"r","type":"disk"}`},
 	{"event missing events", `{"name":"r","type":"event"}`},
 	{"event empty events", `{"name":"r","type":"event","events":[]}`},
 	{"event invalid event", `{"name":"r","type":"event","events":["start"]}`},
@@ -186,6 +225,19 @@ var alertRuleValidationCases = []struct{ name, body string }{
 	{"log minLevel UNKNOWN", `{"name":"r","type":"log","minLevel":"UNKNOWN"}`},
 	{"log invalid pattern", `{"name":"r","type":"log","pattern":"("}`},
 	{"log with events", `{"name":"r","type":"log","minLevel":"ERROR","events":["die"]}`},
+	{"log invalid mode", `{"name":"r","type":"log","minLevel":"ERROR","mode":"never"}`},
+	{"restart_loop with other events", `{"name":"r","type":"event","events":["die","restart_loop"]}`},
+	{"event with mode", `{"name":"r","type":"event","events":["die"],"mode":"absence"}`},
+	{"absence windowSeconds too large", `{"name":"r","type":"log","mode":"absence","windowSeconds":86401}`},
+	{"log with metric", `{"name":"r","type":"log","minLevel":"ERROR","metric":"cpu_percent"}`},
+	{"metric missing metric", `{"name":"r","type":"metric","value":90}`},
+	{"metric invalid metric", `{"name":"r","type":"metric","metric":"disk_percent","value":90}`},
+	{"metric invalid operator", `{"name":"r","type":"metric","metric":"cpu_percent","operator":">=","value":90}`},
+	{"metric negative value", `{"name":"r","type":"metric","metric":"cpu_percent","value":-1}`},
+	{"metric memory over 100", `{"name":"r","type":"metric","metric":"memory_percent","value":101}`},
+	{"metric invalid aggregate", `{"name":"r","type":"metric","metric":"cpu_percent","value":90,"aggregate":"max"}`},
+	{"metric with threshold", `{"name":"r","type":"metric","metric":"cpu_percent","value":90,"threshold":3}`},
+	{"metric with events", `{"name":"r","type":"metric","metric":"cpu_percent","value":90,"events":["die"]}`},
 	{"negative threshold", `{"name":"r","type":"log","minLevel":"ERROR","threshold":-1}`},
 	{"threshold too high", `{"name":"r","type":"log","minLevel":"ERROR","threshold":1001}`},
 	{"negative windowSeconds", `{"name":"r","type":"log","minLevel":"ERROR","windowSeconds":-1}`},
@@ -197,6 +249,10 @@ var alertRuleValidationCases = []struct{ name, body string }{
 	{"blank containers entry", `{"name":"r","type":"log","minLevel":"ERROR","containers":[" "]}`},
 	{"slash-only containers entry", `{"name":"r","type":"log","minLevel":"ERROR","containers":["/"]}`},
 	{"empty projects entry", `{"name":"r","type":"log","minLevel":"ERROR","projects":[""]}`},
+	{"invalid severity", `{"name":"r","type":"log","minLevel":"ERROR","severity":"page"}`},
+	{"empty channels entry", `{"name":"r","type":"log","minLevel":"ERROR","channels":[" "]}`},
+	{"route without channels", `{"name":"r","type":"log","minLevel":"ERROR","routes":[{"projects":["staging"]}]}`},
+	{"route empty target", `{"name":"r","type":"log","minLevel":"ERROR","routes":[{"hosts":[""],"channels":["c1"]}]}`},
 }
 
 func TestCreateAlertRuleValidation(t *testing.T) {
@@ -254,6 +310,59 @@ func TestCreateAlertRuleNormalization(t *testing.T) {
 	}
 }
 
+func TestAlertRuleRouting(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+
+	createChannel := func(body string) string {
+		t.Helper()
+		w := doAlertsRequest(t, router, "POST", "/api/v1/alerts/channels", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 creating channel, got %d: %s", w.Code, w.Body.String())
+		}
+		var ch config.AlertChannel
+		_ = json.Unmarshal(w.Body.Bytes(), &ch)
+		return ch.ID
+	}
+	low := createChannel(`{"type":"ntfy","url":"https://ntfy.sh/staging"}`)
+	pager := createChannel(`{"type":"pagerduty","token":"routing-key"}`)
+
+	created := createAlertRule(t, router, fmt.Sprintf(
+		`{"name":"errors","type":"log","minLevel":"ERROR","severity":" Critical ","channels":[%q,%q],"routes":[{"projects":["staging"],"containers":["/web"],"channels":[%q]}]}`,
+		pager, pager, low))
+	if created.Severity != "critical" || len(created.Channels) != 1 || created.Channels[0] != pager {
+		t.Fatalf("rule = %+v, want critical with its channel list de-duplicated", created)
+	}
+	if len(created.Routes) != 1 || created.Routes[0].Containers[0] != "web" || created.Routes[0].Channels[0] != low {
+		t.Fatalf("routes = %+v, want the staging route normalized", created.Routes)
+	}
+
+	// Every channel a rule names must exist.
+	for _, body := range []string{
+		`{"name":"r","type":"log","minLevel":"ERROR","channels":["nope"]}`,
+		`{"name":"r","type":"log","minLevel":"ERROR","routes":[{"channels":["nope"]}]}`,
+	} {
+		if w := doAlertsRequest(t, router, "POST", "/api/v1/alerts/rules", body); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "nope") {
+			t.Errorf("create %s: expected 400 naming the channel, got %d: %s", body, w.Code, w.Body.String())
+		}
+		if w := doAlertsRequest(t, router, "PUT", "/api/v1/alerts/rules/"+created.ID, body); w.Code != http.StatusBadRequest {
+			t.Errorf("update %s: expected 400, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	// A channel a rule routes to cannot be deleted out from under it.
+	w := doAlertsRequest(t, router, "DELETE", "/api/v1/alerts/channels/"+low, "")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "errors") {
+		t.Fatalf("expected 409 naming the rule, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doAlertsRequest(t, router, "PUT", "/api/v1/alerts/rules/"+created.ID, `{"name":"errors","type":"log","minLevel":"ERROR"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 updating rule, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAlertsRequest(t, router, "DELETE", "/api/v1/alerts/channels/"+low, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 deleting the unused channel, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func TestCreateAlertRuleExplicitEnabledFalse(t *testing.T) {
 	router, _ := newAlertsTestRouter(t, nil)
 	created := createAlertRule(t, router, `{"name":"paused","type":"log","minLevel":"WARN","enabled":false}`)
@@ -339,16 +448,30 @@ func TestAlertChannelsCRUD(t *testing.T) {
 	create(`{"type":"ntfy","url":"https://ntfy.sh/mytopic"}`)
 	create(`{"type":"gotify","url":"https://gotify.example.com","token":"apptoken"}`)
 	tg := create(`{"type":"telegram","token":"bot42:secret","target":"-1001234"}`)
+	mail := create(`{"type":"smtp","url":"smtp://mail.example.com","username":"alerts","token":"pw","from":"LogDeck <logdeck@example.com>","target":"ops@example.com, boss@example.com"}`)
+	pd := create(`{"type":"pagerduty","token":"routing-key","target":"ignored","from":"ignored@example.com"}`)
+	create(`{"type":"matrix","url":"https://matrix.example.org","token":"syt_x","target":"!room:example.org"}`)
+	create(`{"type":"teams","url":"https://prod.westus.logic.azure.com/workflows/1?sig=x"}`)
 
-	_, channels := listAlertChannelsRaw(t, router)
-	if len(channels) != 4 {
-		t.Fatalf("expected 4 channels, got %d", len(channels))
+	raw, channels := listAlertChannelsRaw(t, router)
+	if len(channels) != 8 {
+		t.Fatalf("expected 8 channels, got %d", len(channels))
+	}
+	// Health is omitted until a channel has been attempted.
+	if strings.Contains(string(raw), `"health"`) {
+		t.Errorf("unattempted channels report health: %s", raw)
 	}
 
 	// Irrelevant fields are cleared for the chosen type.
 	if tg.URL != "" {
 		t.Errorf("telegram channel should not store a url, got %q", tg.URL)
 	}
+	if pd.Target != "" || pd.From != "" || pd.URL != "" {
+		t.Errorf("pagerduty channel should store only its routing key, got %+v", pd)
+	}
+	if mail.Username != "alerts" || mail.From != "LogDeck <logdeck@example.com>" {
+		t.Errorf("smtp channel = %+v, want its login and sender kept", mail)
+	}
 
 	// Update: disable the webhook channel.
 	w := doAlertsRequest(t, router, "PUT", "/api/v1/alerts/channels/"+hook.ID,
@@ -369,8 +492,8 @@ func TestAlertChannelsCRUD(t *testing.T) {
 		t.Fatalf("expected 200 deleting channel, got %d: %s", w.Code, w.Body.String())
 	}
 	_, channels = listAlertChannelsRaw(t, router)
-	if len(channels) != 3 {
-		t.Fatalf("expected 3 channels after delete, got %d", len(channels))
+	if len(channels) != 7 {
+		t.Fatalf("expected 7 channels after delete, got %d", len(channels))
 	}
 
 	// Update / delete of an unknown id are 404s.
@@ -401,6 +524,20 @@ func TestAlertChannelValidation(t *testing.T) {
 		{"gotify no url", `{"type":"gotify","token":"t"}`},
 		{"telegram no token", `{"type":"telegram","target":"123"}`},
 		{"telegram no target", `{"type":"telegram","token":"bot:secret"}`},
+		{"smtp bad scheme", `{"type":"smtp","url":"https://mail.example.com","from":"a@example.com","target":"b@example.com"}`},
+		{"smtp no from", `{"type":"smtp","url":"smtp://mail.example.com","target":"b@example.com"}`},
+		{"smtp no recipients", `{"type":"smtp","url":"smtp://mail.example.com","from":"a@example.com"}`},
+		{"smtp bad recipients", `{"type":"smtp","url":"smtp://mail.example.com","from":"a@example.com","target":"not an address"}`},
+		{"smtp username without password", `{"type":"smtp","url":"smtp://mail.example.com","from":"a@example.com","target":"b@example.com","username":"u"}`},
+		{"pagerduty no routing key", `{"type":"pagerduty"}`},
+		{"pagerduty bad url", `{"type":"pagerduty","token":"k","url":"events.pagerduty.com"}`},
+		{"matrix no token", `{"type":"matrix","url":"https://matrix.example.org","target":"!r:example.org"}`},
+		{"matrix room alias", `{"type":"matrix","url":"https://matrix.example.org","token":"t","target":"#ops:example.org"}`},
+		{"teams no url", `{"type":"teams"}`},
+		{"template parse error", `{"type":"ntfy","url":"https://ntfy.sh/x","bodyTemplate":"{{.RuleName"}`},
+		{"template unknown field", `{"type":"ntfy","url":"https://ntfy.sh/x","bodyTemplate":"{{.Nope}}"}`},
+		{"webhook template not json", `{"type":"webhook","url":"https://x.com","bodyTemplate":"{{.Text}}"}`},
+		{"title template on telegram", `{"type":"telegram","token":"t","target":"1","titleTemplate":"x"}`},
 		{"malformed json", `{`},
 	} {
 		w := doAlertsRequest(t, router, "POST", "/api/v1/alerts/channels", tc.body)
@@ -452,6 +589,27 @@ func TestAlertChannelTestEndpoint(t *testing.T) {
 		t.Fatalf("webhook received %d deliveries, want 1", delivered)
 	}
 
+	// A dry run renders the templated body without delivering it.
+	w = doAlertsRequest(t, router, "PUT", "/api/v1/alerts/channels/"+ch.ID,
+		fmt.Sprintf(`{"type":"webhook","url":%q,"bodyTemplate":"{\"text\":{{json .RuleName}}}"}`, hook.URL))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 updating channel, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doAlertsRequest(t, router, "POST", "/api/v1/alerts/channels/"+ch.ID+"/test?dryRun=true", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from dry run, got %d: %s", w.Code, w.Body.String())
+	}
+	var dry models.ChannelTestResult
+	if err := json.Unmarshal(w.Body.Bytes(), &dry); err != nil {
+		t.Fatalf("failed to parse dry-run result: %v", err)
+	}
+	if dry.Status != "skipped" || dry.Preview == nil || dry.Preview.Body != `{"text":"Test channel"}` {
+		t.Fatalf("dry run = %+v (preview %+v), want skipped with the templated body", dry, dry.Preview)
+	}
+	if atomic.LoadInt32(&delivered) != 1 {
+		t.Fatalf("dry run delivered: webhook received %d deliveries, want 1", delivered)
+	}
+
 	// Testing an unknown channel is a 404.
 	w = doAlertsRequest(t, router, "POST", "/api/v1/alerts/channels/nope/test", "")
 	if w.Code != http.StatusNotFound {
@@ -492,6 +650,30 @@ func TestAlertHistory(t *testing.T) {
 		}
 	}
 
+	// The state filter takes the two incident states only.
+	for _, state := range []string{"firing", "resolved"} {
+		w = doAlertsRequest(t, router, "GET", "/api/v1/alerts/history?state="+state, "")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"alerts":[]`) {
+			t.Errorf("state=%s: expected 200 with no alerts, got %d: %s", state, w.Code, w.Body.String())
+		}
+	}
+	w = doAlertsRequest(t, router, "GET", "/api/v1/alerts/history?state=open", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown state, got %d", w.Code)
+	}
+
+	// The other filters are validated before the store is read.
+	w = doAlertsRequest(t, router, "GET", "/api/v1/alerts/history?rule=r1&host=prod&container=/web&project=shop&delivery=suppressed-by-silence&since=2026-07-01T00:00:00Z&until=2026-07-02T00:00:00Z", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"alerts":[]`) {
+		t.Errorf("expected 200 for a filtered query, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, query := range []string{"delivery=sent", "since=yesterday", "until=2026-07-02", "cursor=!!"} {
+		w = doAlertsRequest(t, router, "GET", "/api/v1/alerts/history?"+query, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+
 	w = doAlertsRequest(t, router, "DELETE", "/api/v1/alerts/history", "")
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected 200 clearing history, got %d: %s", w.Code, w.Body.String())
@@ -501,6 +683,87 @@ func TestAlertHistory(t *testing.T) {
 	}
 }
 
+func TestAlertHistoryRetention(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+
+	w := doAlertsRequest(t, router, "GET", "/api/v1/alerts/history/retention", "")
+	if w.Code != http.StatusOK || w.Body.String() != `{"retentionDays":90,"maxEntries":10000}` {
+		t.Fatalf("expected the defaults, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doAlertsRequest(t, router, "PUT", "/api/v1/alerts/history/retention", `{"retentionDays":30}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"retentionDays":30,"maxEntries":10000`) {
+		t.Fatalf("expected 200 with the default entry cap kept, got %d: %s", w.Code, w.Body.String())
+	}
+	// The reloaded manager reads the same CONFIG_PATH.
+	fc := config.NewManager().FileConfigSnapshot()
+	if fc.Alerts == nil || fc.Alerts.History == nil || fc.Alerts.History.RetentionDays != 30 {
+		t.Fatalf("retention not persisted: %+v", fc.Alerts)
+	}
+
+	for _, body := range []string{`{"retentionDays":-1}`, `{"retentionDays":99999}`, `{"maxEntries":5}`, `not json`} {
+		w = doAlertsRequest(t, router, "PUT", "/api/v1/alerts/history/retention", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAlertDeliveryDeadline(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+
+	w := doAlertsRequest(t, router, "GET", "/api/v1/alerts/delivery", "")
+	if w.Code != http.StatusOK || w.Body.String() != `{"deadlineSeconds":3600}` {
+		t.Fatalf("expected the default deadline, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doAlertsRequest(t, router, "PUT", "/api/v1/alerts/delivery", `{"deadlineSeconds":600}`)
+	if w.Code != http.StatusOK || w.Body.String() != `{"deadlineSeconds":600}` {
+		t.Fatalf("expected 200 with the new deadline, got %d: %s", w.Code, w.Body.String())
+	}
+	fc := config.NewManager().FileConfigSnapshot()
+	if fc.Alerts == nil || fc.Alerts.DeliveryDeadline() != 10*time.Minute {
+		t.Fatalf("deadline not persisted: %+v", fc.Alerts)
+	}
+
+	w = doAlertsRequest(t, router, "PUT", "/api/v1/alerts/delivery", `{"deadlineSeconds":0}`)
+	if w.Code != http.StatusOK || w.Body.String() != `{"deadlineSeconds":3600}` {
+		t.Fatalf("expected zero to restore the default, got %d: %s", w.Code, w.Body.String())
+	}
+	if fc := config.NewManager().FileConfigSnapshot(); fc.Alerts.Delivery != nil {
+		t.Fatalf("default deadline stored as %+v, want it omitted", fc.Alerts.Delivery)
+	}
+
+	for _, body := range []string{`{"deadlineSeconds":-1}`, `{"deadlineSeconds":30}`, `{"deadlineSeconds":9999999}`, `not json`} {
+		w = doAlertsRequest(t, router, "PUT", "/api/v1/alerts/delivery", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAlertIncidentActionsValidation(t *testing.T) {
+	router, _ := newAlertsTestRouter(t, nil)
+
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/api/v1/alerts/history/nope/ack", "", http.StatusNotFound},
+		{"/api/v1/alerts/history/nope/silence", "", http.StatusNotFound},
+		{"/api/v1/alerts/history/nope/silence", `{"durationSeconds":3600}`, http.StatusNotFound},
+		{"/api/v1/alerts/history/nope/silence", `{"durationSeconds":-1}`, http.StatusBadRequest},
+		{"/api/v1/alerts/history/nope/silence", `{"durationSeconds":99999999}`, http.StatusBadRequest},
+		{"/api/v1/alerts/history/nope/silence", `not json`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		w := doAlertsRequest(t, router, "POST", c.path, c.body)
+		if w.Code != c.want {
+			t.Errorf("POST %s %s: expected %d, got %d: %s", c.path, c.body, c.want, w.Code, w.Body.String())
+		}
+	}
+}
+
 func TestAlertRulesPersistAcrossManagerReload(t *testing.T) {
 	router, _ := newAlertsTestRouter(t, nil)
 
@@ -543,6 +806,19 @@ func TestAlertRoutesRequireAuthWhenEnabled(t *testing.T) {
 		{"POST", "/api/v1/alerts/channels/deadbeef/test"},
 		{"GET", "/api/v1/alerts/history"},
 		{"DELETE", "/api/v1/alerts/history"},
+		{"GET", "/api/v1/alerts/history/retention"},
+		{"PUT", "/api/v1/alerts/history/retention"},
+		{"GET", "/api/v1/alerts/delivery"},
+		{"PUT", "/api/v1/alerts/delivery"},
+		{"POST", "/api/v1/alerts/history/deadbeef/ack"},
+		{"POST", "/api/v1/alerts/history/deadbeef/silence"},
+		{"GET", "/api/v1/alerts/silences"},
+		{"POST", "/api/v1/alerts/silences"},
+		{"DELETE", "/api/v1/alerts/silences/deadbeef"},
+		{"GET", "/api/v1/alerts/maintenance"},
+		{"POST", "/api/v1/alerts/maintenance"},
+		{"PUT", "/api/v1/alerts/maintenance/deadbeef"},
+		{"DELETE", "/api/v1/alerts/maintenance/deadbeef"},
 	}
 	for _, e := range endpoints {
 		w := httptest.NewRecorder()
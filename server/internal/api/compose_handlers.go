@@ -1,9 +1,15 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 
 	"github.com/AmoabaKelvin/logdeck/internal/api/middleware"
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -26,7 +32,8 @@ func (ar *APIRouter) registerComposeRoutes(r chi.Router) {
 // ComposeAction applies start/stop/restart to every container in a compose
 // project on one host. Responds 200 when all containers succeed, 500 with the
 // per-container failures in the body when any fail, and 404 when the project
-// has no containers on the host.
+// has no containers on the host. pull, up, and down stream their progress
+// instead; see composeDeploy.
 func (ar *APIRouter) ComposeAction(w http.ResponseWriter, r *http.Request) {
 	project := chi.URLParam(r, "project")
 	action := chi.URLParam(r, "action")
@@ -37,8 +44,13 @@ func (ar *APIRouter) ComposeAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if slices.Contains(docker.ComposeDeployActions, action) {
+		ar.composeDeploy(w, r, host, project, action)
+		return
+	}
+
 	if !validComposeActions[action] {
-		http.Error(w, "invalid action: must be one of start, stop, restart", http.StatusBadRequest)
+		http.Error(w, "invalid action: must be one of start, stop, restart, pull, up, down", http.StatusBadRequest)
 		return
 	}
 
@@ -59,3 +71,60 @@ func (ar *APIRouter) ComposeAction(w http.ResponseWriter, r *http.Request) {
 	}
 	WriteJsonResponse(w, status, result)
 }
+
+// composeDeploy runs a pull, up, or down from the project's compose files and
+// streams each step as NDJSON, ending with a "done" or "error" line. Problems
+// found before anything changes get a plain HTTP error instead: 404 for an
+// unknown project, 422 when its compose files cannot be read (or live on a
+// remote host), and 409 while another pull, up, or down of it is running.
+// Once started, the deploy runs to completion even if the client goes away, so
+// it never stops half-done; only the deploy timeout cuts it short, ending the
+// stream with an "error" line that says so.
+func (ar *APIRouter) composeDeploy(w http.ResponseWriter, r *http.Request, host, project, action string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	deploy, err := ar.registry.Docker().PlanComposeDeploy(r.Context(), host, project, action)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, docker.ErrComposeProjectNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, docker.ErrComposeFiles), errors.Is(err, docker.ErrComposeRemoteHost):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, docker.ErrComposeDeployBusy):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	clientGone := false
+	emit := func(event models.ComposeDeployEvent) {
+		if clientGone {
+			return
+		}
+		if err := encoder.Encode(event); err != nil {
+			clientGone = true
+			return
+		}
+		flusher.Flush()
+	}
+
+	final := models.ComposeDeployEvent{Status: "done"}
+	if err := deploy.Run(context.WithoutCancel(r.Context()), emit); err != nil {
+		final = models.ComposeDeployEvent{Status: "error", Error: err.Error()}
+	}
+	final.Detail = action + " " + project
+	emit(final)
+}
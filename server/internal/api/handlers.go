@@ -64,10 +64,18 @@ func (ar *APIRouter) GetContainers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A container labelled logdeck.hide=true is left out unless the caller
+	// asks for hidden ones too.
+	showHidden := r.URL.Query().Get("hidden") == "true"
 	allContainers := []models.ContainerInfo{}
 	for _, containers := range containersMap {
-		allContainers = append(allContainers, containers...)
+		for _, container := range containers {
+			if showHidden || !models.PolicyFromLabels(container.Labels).Hidden {
+				allContainers = append(allContainers, container)
+			}
+		}
 	}
+	ar.imageUpdates.Annotate(allContainers)
 
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
 		"containers":        allContainers,
@@ -208,6 +216,11 @@ func (ar *APIRouter) GetContainerLogsParsed(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	if _, err := models.ParseFieldFilter(options.Filter); err != nil {
+		http.Error(w, fmt.Sprintf("invalid filter: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	if options.Follow {
 		ar.streamParsedLogs(w, r, host, id, options)
 		return
@@ -314,6 +327,10 @@ func parseLogOptions(r *http.Request) models.LogOptions {
 		options.Level = level
 	}
 
+	if filter := query.Get("filter"); filter != "" {
+		options.Filter = filter
+	}
+
 	return options
 }
 
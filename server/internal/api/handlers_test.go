@@ -112,6 +112,21 @@ func TestRunCommandValidation(t *testing.T) {
 	}
 }
 
+// TestGetContainerLogsParsedInvalidFilter proves a malformed field filter is
+// rejected with a 400 before the request reaches Docker.
+func TestGetContainerLogsParsedInvalidFilter(t *testing.T) {
+	ar := &APIRouter{}
+	w := httptest.NewRecorder()
+	ar.GetContainerLogsParsed(w, httptest.NewRequest(http.MethodGet,
+		"/api/v1/containers/abc/logs/parsed?host=prod&filter=status%3E%3D", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad filter, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "invalid filter") {
+		t.Fatalf("expected an invalid-filter message, got %q", w.Body.String())
+	}
+}
+
 // TestGetContainerLogsParsedInvalidSearch proves an uncompilable search regex
 // is rejected with a 400 before the request reaches Docker.
 func TestGetContainerLogsParsedInvalidSearch(t *testing.T) {
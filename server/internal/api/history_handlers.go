@@ -119,8 +119,10 @@ func (ar *APIRouter) GetHistoryContainers(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// GetHistoryLogs returns one page of stored logs for a logical container.
-// Pages walk backwards through history: follow nextCursor for older lines.
+// GetHistoryLogs returns one page of stored logs for a logical container, or —
+// without a container — for every stored container matching the optional
+// host, project, and containers filters, merged by timestamp. Pages walk
+// backwards through history: follow nextCursor for older lines.
 func (ar *APIRouter) GetHistoryLogs(w http.ResponseWriter, r *http.Request) {
 	if ar.logStore == nil {
 		WriteJsonResponse(w, http.StatusServiceUnavailable, map[string]string{
@@ -136,10 +138,10 @@ func (ar *APIRouter) GetHistoryLogs(w http.ResponseWriter, r *http.Request) {
 
 	page, err := ar.logStore.Query(r.Context(), query)
 	if err != nil {
-		// A bad cursor or an uncompilable pattern is the caller's to fix, so it is
-		// reported verbatim. Anything else is ours: it would leak driver errors and
+		// A bad cursor, an uncompilable pattern, or a malformed filter is the
+		// caller's to fix, so it is reported verbatim. Anything else is ours: it would leak driver errors and
 		// file paths, so it is logged and answered generically.
-		if errors.Is(err, logstore.ErrInvalidCursor) || isInvalidSearchPattern(err) {
+		if errors.Is(err, logstore.ErrInvalidCursor) || errors.Is(err, logstore.ErrInvalidFilter) || isInvalidSearchPattern(err) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -163,22 +165,182 @@ func (ar *APIRouter) GetHistoryLogs(w http.ResponseWriter, r *http.Request) {
 	WriteJsonResponse(w, http.StatusOK, response)
 }
 
-// parseHistoryQuery validates the query parameters and writes the 400 itself
-// when one is bad, reporting ok=false.
+// GetHistoryStats returns bucketed per-level counts of stored logs over a time
+// window, selected with the same parameters as GetHistoryLogs (less limit and
+// cursor) plus an interval. Without search or filter it counts stored lines by
+// level straight from the index; with either it counts matching entries.
+func (ar *APIRouter) GetHistoryStats(w http.ResponseWriter, r *http.Request) {
+	if ar.logStore == nil {
+		WriteJsonResponse(w, http.StatusServiceUnavailable, map[string]string{
+			"error": "log persistence is disabled",
+		})
+		return
+	}
+
+	selection, ok := parseHistorySelection(w, r)
+	if !ok {
+		return
+	}
+	query := logstore.StatsQuery{LogQuery: selection}
+	if interval := r.URL.Query().Get("interval"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid interval: expected a duration such as 1m or 1h", http.StatusBadRequest)
+			return
+		}
+		query.Interval = parsed
+	}
+
+	stats, err := ar.logStore.Stats(r.Context(), query)
+	if err != nil {
+		// As for GetHistoryLogs: the caller's mistakes are reported verbatim,
+		// anything else is logged and answered generically.
+		if errors.Is(err, logstore.ErrInvalidStats) || errors.Is(err, logstore.ErrInvalidFilter) || isInvalidSearchPattern(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("history: counting stored logs failed: %v", err)
+		http.Error(w, "failed to count stored logs", http.StatusInternalServerError)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"since":           stats.Since,
+		"until":           stats.Until,
+		"intervalSeconds": stats.Interval.Seconds(),
+		"counting":        stats.Counting,
+		"total":           stats.Total,
+		"levels":          stats.Levels,
+		"buckets":         stats.Buckets,
+	})
+}
+
+// ExportHistory streams every stored line of the selected containers and window
+// as a zstd-compressed NDJSON archive (see logstore.Store.Export). It takes the
+// selection parameters of GetHistoryLogs; the entry filters are refused rather
+// than ignored, since an archive holds whole lines. The archive can hold every
+// line of every container, so the route is denied to read-scoped API tokens.
+func (ar *APIRouter) ExportHistory(w http.ResponseWriter, r *http.Request) {
+	if ar.logStore == nil {
+		WriteJsonResponse(w, http.StatusServiceUnavailable, map[string]string{
+			"error": "log persistence is disabled",
+		})
+		return
+	}
+
+	query, ok := parseHistorySelection(w, r)
+	if !ok {
+		return
+	}
+	if query.Search != "" || query.Filter != "" || len(query.Levels) > 0 {
+		http.Error(w, "export copies whole lines: search, levels, and filter are not supported", http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("logdeck-history-%s.ndjson.zst", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zstd")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// The archive streams as it is read, so once the first bytes are out a
+	// failure can only be logged: the client sees a truncated archive, which
+	// import rejects.
+	if _, err := ar.logStore.Export(r.Context(), query, w); err != nil {
+		log.Printf("history: exporting stored logs failed: %v", err)
+	}
+}
+
+// ImportHistory stores the lines of an uploaded history archive, sent as the
+// raw request body. It writes stored logs, so it is blocked in read-only mode
+// and denied to read-scoped API tokens like the purge route.
+func (ar *APIRouter) ImportHistory(w http.ResponseWriter, r *http.Request) {
+	if ar.logStore == nil {
+		WriteJsonResponse(w, http.StatusServiceUnavailable, map[string]string{
+			"error": "log persistence is disabled",
+		})
+		return
+	}
+
+	result, err := ar.logStore.Import(r.Context(), r.Body)
+	if err != nil {
+		// Batches before a malformed record are already stored, so the counts go
+		// back with the error.
+		if errors.Is(err, logstore.ErrInvalidArchive) {
+			WriteJsonResponse(w, http.StatusBadRequest, map[string]any{
+				"error":  err.Error(),
+				"result": result,
+			})
+			return
+		}
+		log.Printf("history: importing stored logs failed: %v", err)
+		http.Error(w, "failed to import stored logs", http.StatusInternalServerError)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"message": "archive imported",
+		"result":  result,
+	})
+}
+
+// parseHistoryQuery validates the query parameters of /history/logs and writes
+// the 400 itself when one is bad, reporting ok=false.
 func parseHistoryQuery(w http.ResponseWriter, r *http.Request) (logstore.LogQuery, bool) {
+	query, ok := parseHistorySelection(w, r)
+	if !ok {
+		return logstore.LogQuery{}, false
+	}
 	params := r.URL.Query()
+	query.Cursor = params.Get("cursor")
+
+	if limit := params.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, "invalid limit: expected an integer", http.StatusBadRequest)
+			return logstore.LogQuery{}, false
+		}
+		query.Limit = parsed
+	}
 
+	return query, true
+}
+
+// parseHistorySelection validates the parameters every history read shares —
+// which containers, which window, and which filters — and writes the 400
+// itself when one is bad, reporting ok=false.
+func parseHistorySelection(w http.ResponseWriter, r *http.Request) (logstore.LogQuery, bool) {
+	params := r.URL.Query()
+
+	// An absent container makes the query store-wide; a blank one is a mistake.
 	container := strings.TrimSpace(params.Get("container"))
-	if container == "" {
-		http.Error(w, "container is required", http.StatusBadRequest)
+	if params.Has("container") && container == "" {
+		http.Error(w, "container must not be blank", http.StatusBadRequest)
 		return logstore.LogQuery{}, false
 	}
 
 	query := logstore.LogQuery{
 		Host:      params.Get("host"),
 		Container: container,
+		Project:   strings.TrimSpace(params.Get("project")),
 		Search:    params.Get("search"),
-		Cursor:    params.Get("cursor"),
+		Filter:    params.Get("filter"),
+	}
+
+	if names := params.Get("containers"); names != "" {
+		// Project and name filters narrow a store-wide query; alongside a single
+		// container they would be silently ignored, so they are refused instead.
+		if container != "" {
+			http.Error(w, "container and containers are mutually exclusive", http.StatusBadRequest)
+			return logstore.LogQuery{}, false
+		}
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				query.Containers = append(query.Containers, name)
+			}
+		}
+	}
+	if container != "" && query.Project != "" {
+		http.Error(w, "container and project are mutually exclusive", http.StatusBadRequest)
+		return logstore.LogQuery{}, false
 	}
 
 	for _, param := range []struct {
@@ -220,15 +382,6 @@ func parseHistoryQuery(w http.ResponseWriter, r *http.Request) (logstore.LogQuer
 		query.Regex = parsed
 	}
 
-	if limit := params.Get("limit"); limit != "" {
-		parsed, err := strconv.Atoi(limit)
-		if err != nil {
-			http.Error(w, "invalid limit: expected an integer", http.StatusBadRequest)
-			return logstore.LogQuery{}, false
-		}
-		query.Limit = parsed
-	}
-
 	return query, true
 }
 
@@ -1,11 +1,13 @@
 package api
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -43,7 +45,7 @@ func newHistoryTestRouterWithAuth(t *testing.T, store *logstore.Store, authSvc *
 	manager := config.NewManager()
 	registry := services.NewRegistry(nil, nil, authSvc, manager.Config())
 	engine := alerts.NewEngine(registry, manager, nil)
-	return NewRouter(registry, manager, engine, store, "test")
+	return NewRouter(RouterDeps{Registry: registry, Manager: manager, Alerts: engine, LogStore: store, Version: "test"})
 }
 
 // newHistoryStore opens a real store over a temp database and returns it with
@@ -261,8 +263,9 @@ func TestHistoryLogsValidation(t *testing.T) {
 		name string
 		path string
 	}{
-		{"missing container", "/api/v1/history/logs"},
 		{"blank container", "/api/v1/history/logs?container=%20"},
+		{"container and containers", "/api/v1/history/logs?container=web&containers=web,db"},
+		{"container and project", "/api/v1/history/logs?container=web&project=shop"},
 		{"unknown level", "/api/v1/history/logs?container=web&levels=ERROR,BOGUS"},
 		{"invalid regex", "/api/v1/history/logs?container=web&search=%5B&regex=true"},
 		{"non-integer limit", "/api/v1/history/logs?container=web&limit=lots"},
@@ -270,6 +273,7 @@ func TestHistoryLogsValidation(t *testing.T) {
 		{"invalid since", "/api/v1/history/logs?container=web&since=yesterday"},
 		{"invalid until", "/api/v1/history/logs?container=web&until=yesterday"},
 		{"invalid regex flag", "/api/v1/history/logs?container=web&regex=maybe"},
+		{"invalid filter", "/api/v1/history/logs?container=web&filter=status%3E%3D"},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			w := doHistoryRequest(t, router, tt.path)
@@ -302,6 +306,7 @@ func TestHistoryLogsQuery(t *testing.T) {
 	seed("local", "abc123", "web", historyBase.Add(time.Second), "ERROR database is down")
 	seed("local", "abc123", "web", historyBase.Add(2*time.Second), "recovered")
 	seed("local", "xyz789", "db", historyBase, "db line")
+	seed("local", "xyz789", "db", historyBase.Add(time.Second), `slow query table=orders ms=1250`)
 	router := newHistoryTestRouter(t, store)
 
 	t.Run("returns entries for the container only", func(t *testing.T) {
@@ -347,6 +352,13 @@ func TestHistoryLogsQuery(t *testing.T) {
 		}
 	})
 
+	t.Run("field filter", func(t *testing.T) {
+		body := historyLogs(t, router, "/api/v1/history/logs?filter="+url.QueryEscape(`ms>=1000 AND table="orders"`))
+		if len(body.Logs) != 1 || body.Logs[0].ContainerName != "db" {
+			t.Fatalf("expected the one slow query, got %+v", body.Logs)
+		}
+	})
+
 	t.Run("since and until", func(t *testing.T) {
 		path := fmt.Sprintf("/api/v1/history/logs?container=web&since=%s&until=%s",
 			historyBase.Add(time.Second).Format(time.RFC3339),
@@ -377,6 +389,134 @@ func TestHistoryLogsQuery(t *testing.T) {
 	})
 }
 
+func TestHistoryLogsStoreWide(t *testing.T) {
+	store, seed := newHistoryStore(t)
+	seed("local", "abc123", "web", historyBase, "web served req-42")
+	seed("local", "xyz789", "db", historyBase.Add(time.Second), "db saw req-42")
+	seed("remote", "def456", "web", historyBase.Add(2*time.Second), "remote web served req-42")
+	seed("local", "abc123", "web", historyBase.Add(3*time.Second), "unrelated")
+	router := newHistoryTestRouter(t, store)
+
+	t.Run("without a container every stored container is searched", func(t *testing.T) {
+		body := historyLogs(t, router, "/api/v1/history/logs?search=req-42")
+		if body.Count != 3 {
+			t.Fatalf("expected 3 matches, got %d: %+v", body.Count, body.Logs)
+		}
+		want := []struct{ host, name string }{{"local", "web"}, {"local", "db"}, {"remote", "web"}}
+		for i, entry := range body.Logs {
+			if entry.Host != want[i].host || entry.ContainerName != want[i].name {
+				t.Fatalf("entry %d came from %s/%s, want %s/%s (merged by timestamp)",
+					i, entry.Host, entry.ContainerName, want[i].host, want[i].name)
+			}
+		}
+	})
+
+	t.Run("host narrows the search", func(t *testing.T) {
+		body := historyLogs(t, router, "/api/v1/history/logs?search=req-42&host=remote")
+		if body.Count != 1 || body.Logs[0].Message != "remote web served req-42" {
+			t.Fatalf("expected the remote line only, got %+v", body.Logs)
+		}
+	})
+
+	t.Run("containers narrows the search", func(t *testing.T) {
+		body := historyLogs(t, router, "/api/v1/history/logs?search=req-42&containers=db,%20missing")
+		if body.Count != 1 || body.Logs[0].ContainerName != "db" {
+			t.Fatalf("expected the db line only, got %+v", body.Logs)
+		}
+	})
+
+	t.Run("cursor pages across containers", func(t *testing.T) {
+		first := historyLogs(t, router, "/api/v1/history/logs?limit=2")
+		if len(first.Logs) != 2 || first.NextCursor == "" {
+			t.Fatalf("expected a full page and a cursor, got %d logs, cursor %q", len(first.Logs), first.NextCursor)
+		}
+		second := historyLogs(t, router, "/api/v1/history/logs?limit=2&cursor="+first.NextCursor)
+		if len(second.Logs) != 2 || second.Logs[0].Message != "web served req-42" || second.NextCursor != "" {
+			t.Fatalf("expected the two oldest lines and no cursor, got %+v, cursor %q", second.Logs, second.NextCursor)
+		}
+	})
+}
+
+func TestHistoryStats(t *testing.T) {
+	store, seed := newHistoryStore(t)
+	seed("local", "abc123", "web", historyBase.Add(5*time.Second), "ERROR upstream timeout")
+	seed("local", "abc123", "web", historyBase.Add(10*time.Second), "INFO served")
+	seed("local", "abc123", "web", historyBase.Add(70*time.Second), "ERROR disk full")
+	seed("local", "xyz789", "db", historyBase.Add(20*time.Second), "ERROR checkpoint timeout")
+	router := newHistoryTestRouter(t, store)
+
+	window := "&since=" + historyBase.Format(time.RFC3339) +
+		"&until=" + historyBase.Add(119*time.Second).Format(time.RFC3339)
+
+	var body struct {
+		IntervalSeconds float64          `json:"intervalSeconds"`
+		Counting        string           `json:"counting"`
+		Total           int64            `json:"total"`
+		Levels          map[string]int64 `json:"levels"`
+		Buckets         []struct {
+			Start  time.Time        `json:"start"`
+			Total  int64            `json:"total"`
+			Levels map[string]int64 `json:"levels"`
+		} `json:"buckets"`
+	}
+	stats := func(t *testing.T, path string) {
+		t.Helper()
+		w := doHistoryRequest(t, router, path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		body.Levels, body.Buckets = nil, nil
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("parse response: %v", err)
+		}
+	}
+
+	t.Run("lines per level for one container", func(t *testing.T) {
+		stats(t, "/api/v1/history/stats?container=web&interval=1m"+window)
+		if body.IntervalSeconds != 60 || body.Counting != "lines" || len(body.Buckets) != 2 {
+			t.Fatalf("unexpected shape: %+v", body)
+		}
+		if !body.Buckets[0].Start.Equal(historyBase) || body.Buckets[0].Levels["ERROR"] != 1 || body.Buckets[0].Levels["INFO"] != 1 {
+			t.Fatalf("unexpected first bucket: %+v", body.Buckets[0])
+		}
+		if body.Buckets[1].Total != 1 || body.Levels["ERROR"] != 2 {
+			t.Fatalf("unexpected counts: %+v", body)
+		}
+	})
+
+	t.Run("matching entries store-wide", func(t *testing.T) {
+		stats(t, "/api/v1/history/stats?search=timeout&levels=ERROR&interval=1m"+window)
+		if body.Counting != "entries" || body.Total != 2 || body.Buckets[0].Levels["ERROR"] != 2 {
+			t.Fatalf("expected two matching errors in the first minute, got %+v", body)
+		}
+	})
+
+	for _, tt := range []struct {
+		name string
+		path string
+	}{
+		{"invalid interval", "/api/v1/history/stats?interval=often"},
+		{"negative interval", "/api/v1/history/stats?interval=-1m"},
+		{"too many buckets", "/api/v1/history/stats?interval=1s"},
+		{"until before since", "/api/v1/history/stats?since=" + historyBase.Format(time.RFC3339) + "&until=" + historyBase.Add(-time.Hour).Format(time.RFC3339)},
+		{"invalid filter", "/api/v1/history/stats?filter=status%3E"},
+		{"container and project", "/api/v1/history/stats?container=web&project=shop"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doHistoryRequest(t, router, tt.path); w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		w := doHistoryRequest(t, newHistoryTestRouter(t, nil), "/api/v1/history/stats")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
 func TestHistoryRequiresAuth(t *testing.T) {
 	store, _ := newHistoryStore(t)
 	router := newHistoryTestRouterWithAuth(t, store, newTestAuthService(t))
@@ -574,7 +714,7 @@ func TestDeleteHistoryContainerReadOnlyMode(t *testing.T) {
 
 	manager := config.NewManager()
 	registry := services.NewRegistry(nil, nil, nil, manager.Config())
-	router := NewRouter(registry, manager, alerts.NewEngine(registry, manager, nil), store, "test")
+	router := NewRouter(RouterDeps{Registry: registry, Manager: manager, Alerts: alerts.NewEngine(registry, manager, nil), LogStore: store, Version: "test"})
 
 	w := doHistoryDelete(t, router, "/api/v1/history/containers/web?host=local", "")
 	if w.Code != http.StatusForbidden {
@@ -650,3 +790,180 @@ func TestAlertRoutesDenyReadScope(t *testing.T) {
 		t.Fatalf("expected 200 on channels with an admin session, got %d: %s", w.Code, w.Body.String())
 	}
 }
+
+func doHistoryImport(t *testing.T, router http.Handler, body []byte, bearer string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/api/v1/history/import", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/zstd")
+	if bearer != "" {
+		r.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestHistoryExportImport(t *testing.T) {
+	src, seed := newHistoryStore(t)
+	seed("local", "abc123", "web", historyBase, "INFO started")
+	seed("local", "abc123", "web", historyBase.Add(time.Second), "ERROR upstream timeout")
+	seed("local", "xyz789", "db", historyBase, "INFO checkpoint")
+	srcRouter := newHistoryTestRouter(t, src)
+
+	w := doHistoryRequest(t, srcRouter, "/api/v1/history/export?container=web")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zstd" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, ".ndjson.zst") {
+		t.Fatalf("unexpected content disposition %q", cd)
+	}
+	archive := w.Body.Bytes()
+
+	dst, _ := newHistoryStore(t)
+	dstRouter := newHistoryTestRouter(t, dst)
+	var body struct {
+		Message string                `json:"message"`
+		Result  logstore.ImportResult `json:"result"`
+	}
+	for i, want := range []logstore.ImportResult{
+		{Lines: 2, Imported: 2, Generations: 1},
+		{Lines: 2, Duplicates: 2}, // importing again stores nothing
+	} {
+		w = doHistoryImport(t, dstRouter, archive, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("import %d: expected 200, got %d: %s", i+1, w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("parse response: %v", err)
+		}
+		if body.Result != want {
+			t.Fatalf("import %d: result = %+v, want %+v", i+1, body.Result, want)
+		}
+	}
+
+	logs := historyLogs(t, dstRouter, "/api/v1/history/logs?container=web")
+	if logs.Count != 2 || logs.Logs[1].Message != "ERROR upstream timeout" {
+		t.Fatalf("unexpected imported logs: %+v", logs.Logs)
+	}
+	if logs := historyLogs(t, dstRouter, "/api/v1/history/logs?container=db"); logs.Count != 0 {
+		t.Fatalf("db was not exported, but %d lines were imported", logs.Count)
+	}
+
+	t.Run("export rejects filters and bad parameters", func(t *testing.T) {
+		for _, path := range []string{
+			"/api/v1/history/export?search=timeout",
+			"/api/v1/history/export?levels=ERROR",
+			"/api/v1/history/export?filter=status%3E%3D500",
+			"/api/v1/history/export?since=yesterday",
+		} {
+			if w := doHistoryRequest(t, srcRouter, path); w.Code != http.StatusBadRequest {
+				t.Fatalf("GET %s: expected 400, got %d: %s", path, w.Code, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("invalid archive", func(t *testing.T) {
+		w := doHistoryImport(t, dstRouter, []byte("not an archive"), "")
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+		}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || !strings.Contains(body.Error, "invalid archive") {
+			t.Fatalf("unexpected error body: %s", w.Body.String())
+		}
+	})
+
+	t.Run("persistence disabled", func(t *testing.T) {
+		router := newHistoryTestRouter(t, nil)
+		if w := doHistoryRequest(t, router, "/api/v1/history/export"); w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("export: expected 503, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := doHistoryImport(t, router, archive, ""); w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("import: expected 503, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+// TestHistoryExportImportDeniesReadScope proves export and import are closed to
+// read-scoped API tokens: an archive can hold every stored line.
+func TestHistoryExportImportDeniesReadScope(t *testing.T) {
+	store, seed := newHistoryStore(t)
+	seed("local", "abc123", "web", historyBase, "hello")
+
+	svc := newTestAuthService(t)
+	router := newHistoryTestRouterWithAuth(t, store, svc)
+
+	jwt, err := svc.GenerateToken("admin")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/api/v1/settings/api-tokens",
+		strings.NewReader(`{"name":"agent","scope":"read"}`))
+	r.Header.Set("Authorization", "Bearer "+jwt)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating read token, got %d: %s", w.Code, w.Body.String())
+	}
+	var readToken createdTokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &readToken); err != nil {
+		t.Fatalf("parse create response: %v", err)
+	}
+
+	export := func(bearer string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/v1/history/export", nil)
+		r.Header.Set("Authorization", "Bearer "+bearer)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := export(readToken.Token); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 exporting with a read token, got %d: %s", w.Code, w.Body.String())
+	}
+	w = export(jwt)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 exporting with an admin session, got %d: %s", w.Code, w.Body.String())
+	}
+	archive := w.Body.Bytes()
+
+	if w := doHistoryImport(t, router, archive, readToken.Token); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 importing with a read token, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doHistoryImport(t, router, archive, jwt); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 importing with an admin session, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestHistoryImportReadOnlyMode covers the global read-only switch: import
+// writes stored logs and is blocked, while export only reads them.
+func TestHistoryImportReadOnlyMode(t *testing.T) {
+	store, seed := newHistoryStore(t)
+	seed("local", "abc123", "web", historyBase, "hello")
+
+	for _, key := range []string{
+		"JWT_SECRET", "ADMIN_USERNAME", "ADMIN_PASSWORD", "ADMIN_PASSWORD_SALT",
+		"DOCKER_HOSTS", "COOLIFY_CONFIGS", "CORS_ALLOWED_ORIGINS",
+	} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("READONLY_MODE", "true")
+	t.Setenv("CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+
+	manager := config.NewManager()
+	registry := services.NewRegistry(nil, nil, nil, manager.Config())
+	router := NewRouter(RouterDeps{Registry: registry, Manager: manager, Alerts: alerts.NewEngine(registry, manager, nil), LogStore: store, Version: "test"})
+
+	w := doHistoryRequest(t, router, "/api/v1/history/export")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected export to work in read-only mode, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doHistoryImport(t, router, w.Body.Bytes(), ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 importing in read-only mode, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AmoabaKelvin/logdeck/internal/api/middleware"
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/go-chi/chi/v5"
+)
+
+func (ar *APIRouter) registerImageUpdateRoutes(r chi.Router) {
+	r.Get("/images/updates", ar.GetImageUpdates)
+
+	// A check asks every registry about every image, so it is treated as
+	// mutating: blocked in read-only mode and, being a POST, denied to
+	// read-scoped API tokens.
+	r.Group(func(mutating chi.Router) {
+		mutating.Use(middleware.ReadOnly(func() bool {
+			return ar.registry.Config().ReadOnly
+		}))
+		mutating.Post("/images/updates/check", ar.CheckImageUpdates)
+	})
+}
+
+// GetImageUpdates returns the latest image update check: for every running
+// container, whether its tag now points to a newer image in the registry.
+func (ar *APIRouter) GetImageUpdates(w http.ResponseWriter, r *http.Request) {
+	WriteJsonResponse(w, http.StatusOK, ar.imageUpdates.Snapshot())
+}
+
+// CheckImageUpdates starts an image update check now instead of waiting for
+// the next scheduled one. It returns straight away; poll GetImageUpdates for
+// the result.
+func (ar *APIRouter) CheckImageUpdates(w http.ResponseWriter, r *http.Request) {
+	if !ar.imageUpdates.Snapshot().Enabled {
+		http.Error(w, "image update checks are disabled", http.StatusConflict)
+		return
+	}
+	ar.imageUpdates.CheckNow()
+	WriteJsonResponse(w, http.StatusAccepted, map[string]any{
+		"message": "Image update check started",
+	})
+}
+
+// UpdateContainerImage pulls the tag a container was created from and, if a
+// newer image arrived, recreates the container on it with its current
+// configuration. The container gets a new ID when it is recreated.
+func (ar *APIRouter) UpdateContainerImage(w http.ResponseWriter, r *http.Request) {
+	host, id, ok := containerParams(w, r)
+	if !ok {
+		return
+	}
+
+	newID, recreated, err := ar.registry.Docker().PullAndRecreateContainer(r.Context(), host, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, docker.ErrImagePinned) {
+			status = http.StatusUnprocessableEntity
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	message := "Container is already running the latest image"
+	if recreated {
+		ar.imageUpdates.Forget(host, id)
+		message = "Container updated to the latest image"
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"message":          message,
+		"recreated":        recreated,
+		"new_container_id": newID,
+	})
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/imageupdates"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+func newImageUpdatesTestRouter(t *testing.T, enabled string) http.Handler {
+	t.Helper()
+	deps := newTestDeps(t, map[string]string{"IMAGE_UPDATES_ENABLED": enabled})
+	deps.ImageUpdates = imageupdates.New(deps.Manager.ImageUpdates)
+	return NewRouter(deps)
+}
+
+// fakeImageEngine serves the Docker Engine API calls a container listing, a
+// stats read and an image update check make: one running web container on
+// nginx:latest using 64 of its 128 MiB, whose local image was pulled at
+// oldDigest while the registry has newDigest.
+func fakeImageEngine(t *testing.T, oldDigest, newDigest string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if rest, ok := strings.CutPrefix(path, "/v1."); ok {
+			path = rest[strings.Index(rest, "/"):]
+		}
+		w.Header().Set("Api-Version", "1.45")
+		var body any
+		switch {
+		case path == "/_ping":
+			_, _ = w.Write([]byte("OK"))
+			return
+		case path == "/containers/json":
+			body = []map[string]any{{
+				"Id": "web", "Names": []string{"/web"}, "Image": "nginx:latest",
+				"ImageID": "sha256:local", "State": "running", "Status": "Up 1 hour",
+			}}
+		case path == "/containers/web/stats":
+			body = map[string]any{"memory_stats": map[string]any{"usage": 64 << 20, "limit": 128 << 20}}
+		case strings.HasPrefix(path, "/distribution/"):
+			body = map[string]any{"Descriptor": map[string]any{
+				"mediaType": "application/vnd.oci.image.index.v1+json", "digest": newDigest, "size": 1,
+			}}
+		case strings.HasPrefix(path, "/images/"):
+			body = map[string]any{"Id": "sha256:local", "RepoDigests": []string{"nginx@" + oldDigest}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestImageUpdatesEndpoints(t *testing.T) {
+	router := newImageUpdatesTestRouter(t, "true")
+
+	w := doHistoryRequest(t, router, "/api/v1/images/updates")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var body models.ImageUpdatesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if !body.Enabled || body.LastCheck != 0 || body.Updates == nil {
+		t.Errorf("body = %+v, want enabled, not yet checked, and an empty list", body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/images/updates/check", nil))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("check: status = %d, want 202: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImageUpdateCheckFlagsContainers(t *testing.T) {
+	oldDigest := "sha256:" + strings.Repeat("a", 64)
+	newDigest := "sha256:" + strings.Repeat("b", 64)
+	engine := fakeImageEngine(t, oldDigest, newDigest)
+	t.Setenv("DOCKER_CONFIG", t.TempDir()) // no registry credentials
+
+	deps := newTestDeps(t, map[string]string{"IMAGE_UPDATES_ENABLED": "true"},
+		config.DockerHost{Name: "local", Host: "tcp://" + engine.Listener.Addr().String()})
+	checker := imageupdates.New(deps.Manager.ImageUpdates)
+	deps.ImageUpdates = checker
+	router := NewRouter(deps)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	checker.Start(ctx, deps.Registry)
+	t.Cleanup(func() {
+		cancel()
+		checker.Wait()
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/images/updates/check", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("check: status = %d, want 202: %s", w.Code, w.Body.String())
+	}
+	var updates models.ImageUpdatesResponse
+	for deadline := time.Now().Add(5 * time.Second); updates.LastCheck == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the check did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := json.Unmarshal(doHistoryRequest(t, router, "/api/v1/images/updates").Body.Bytes(), &updates); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(updates.Updates) != 1 || !updates.Updates[0].UpdateAvailable || updates.Updates[0].RemoteDigest != newDigest {
+		t.Fatalf("updates = %+v, want web behind %s", updates.Updates, newDigest)
+	}
+
+	w = doHistoryRequest(t, router, "/api/v1/containers")
+	var list struct {
+		Containers []models.ContainerInfo `json:"containers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("containers: %v: %s", err, w.Body.String())
+	}
+	if len(list.Containers) != 1 || !list.Containers[0].UpdateAvailable {
+		t.Errorf("containers = %+v, want web flagged update_available", list.Containers)
+	}
+}
+
+func TestImageUpdatesCheckWhenDisabled(t *testing.T) {
+	// Off unless enabled.
+	router := newImageUpdatesTestRouter(t, "")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/images/updates/check", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("check: status = %d, want 409: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImageUpdatesCheckInReadOnlyMode(t *testing.T) {
+	deps := newTestDeps(t, map[string]string{"IMAGE_UPDATES_ENABLED": "true", "READONLY_MODE": "true"})
+	deps.ImageUpdates = imageupdates.New(deps.Manager.ImageUpdates)
+	router := NewRouter(deps)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/images/updates/check", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("check: status = %d, want 403: %s", w.Code, w.Body.String())
+	}
+	if w := doHistoryRequest(t, router, "/api/v1/images/updates"); w.Code != http.StatusOK {
+		t.Errorf("results: status = %d, want 200", w.Code)
+	}
+}
+
+func TestUpdateContainerImageRequiresHost(t *testing.T) {
+	router := newImageUpdatesTestRouter(t, "")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/containers/web/update-image", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", w.Code, w.Body.String())
+	}
+}
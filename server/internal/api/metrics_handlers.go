@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/metrics"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// metricsEnabled reports whether the Prometheus endpoint is served, from the
+// METRICS_ENABLED env var. It is off by default: the endpoint describes every
+// container on every host, so exposing it is an explicit choice.
+func metricsEnabled() bool {
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv("METRICS_ENABLED")))
+	return enabled
+}
+
+// GetMetrics serves LogDeck's own counters and the watched containers' state
+// in the Prometheus text format. Container state is listed on each scrape; an
+// unreachable host reports logdeck_host_up 0 and contributes no containers
+// rather than failing the scrape. Resource usage is the stats history
+// sampler's latest pass when history is on, so a scrape need not ask every
+// container for its stats; without it the stats are read on each scrape.
+func (ar *APIRouter) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var buf bytes.Buffer
+	out := metrics.NewWriter(&buf)
+
+	out.Family("logdeck_build_info", metrics.Gauge, "LogDeck build information; always 1.")
+	out.Sample("logdeck_build_info", metrics.Labels{"version": ar.version}, 1)
+
+	ar.writeContainerMetrics(ctx, out)
+	if ar.logStore != nil {
+		if err := ar.writeStoreMetrics(ctx, out); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if ar.engine != nil {
+		ar.writeAlertMetrics(out)
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// containerLabels identifies one container in every per-container series.
+func containerLabels(host, name, project string) metrics.Labels {
+	return metrics.Labels{"host": host, "container": name, "project": project}
+}
+
+func (ar *APIRouter) writeContainerMetrics(ctx context.Context, out *metrics.Writer) {
+	dockerClient := ar.registry.Docker()
+	if dockerClient == nil {
+		return
+	}
+	containersMap, hostErrors, err := dockerClient.ListContainersAllHosts(ctx)
+	if err != nil {
+		return
+	}
+	down := make(map[string]bool, len(hostErrors))
+	for _, hostErr := range hostErrors {
+		down[hostErr.HostName] = true
+	}
+
+	out.Family("logdeck_host_up", metrics.Gauge, "Whether the last container listing on the host succeeded.")
+	for _, host := range dockerClient.GetHosts() {
+		up := 1.0
+		if down[host.Name] {
+			up = 0
+		}
+		out.Sample("logdeck_host_up", metrics.Labels{"host": host.Name}, up)
+	}
+
+	type containerKey struct{ host, id string }
+	labels := make(map[containerKey]metrics.Labels)
+	out.Family("logdeck_container_running", metrics.Gauge, "Whether the container is running.")
+	for _, host := range dockerClient.GetHosts() {
+		for _, container := range containersMap[host.Name] {
+			name := ""
+			if len(container.Names) > 0 {
+				name = strings.TrimPrefix(container.Names[0], "/")
+			}
+			series := containerLabels(host.Name, name, models.ComposeProject(container.Labels))
+			labels[containerKey{host.Name, container.ID}] = series
+			running := 0.0
+			if container.State == "running" {
+				running = 1
+			}
+			out.Sample("logdeck_container_running", series, running)
+		}
+	}
+
+	// Without stats history there is no sampler to read from, so the scrape
+	// reads the running containers' stats itself.
+	var stats []models.ContainerStats
+	if ar.statsStore != nil {
+		stats = ar.statsStore.Latest()
+	} else if stats, err = dockerClient.GetAllRunningContainerStats(ctx); err != nil {
+		return
+	}
+	known := make([]models.ContainerStats, 0, len(stats))
+	for _, stat := range stats {
+		if _, ok := labels[containerKey{stat.Host, stat.ID}]; ok {
+			known = append(known, stat)
+		}
+	}
+	gauges := []struct {
+		name, help string
+		value      func(models.ContainerStats) float64
+	}{
+		{"logdeck_container_cpu_percent", "CPU use of the running container, as a percentage of one CPU.",
+			func(s models.ContainerStats) float64 { return s.CPUPercent }},
+		{"logdeck_container_memory_percent", "Memory use of the running container, as a percentage of its limit.",
+			func(s models.ContainerStats) float64 { return s.MemoryPercent }},
+		{"logdeck_container_memory_used_bytes", "Memory used by the running container.",
+			func(s models.ContainerStats) float64 { return float64(s.MemoryUsed) }},
+		{"logdeck_container_memory_limit_bytes", "Memory limit of the running container.",
+			func(s models.ContainerStats) float64 { return float64(s.MemoryLimit) }},
+	}
+	for _, gauge := range gauges {
+		out.Family(gauge.name, metrics.Gauge, gauge.help)
+		for _, stat := range known {
+			out.Sample(gauge.name, labels[containerKey{stat.Host, stat.ID}], gauge.value(stat))
+		}
+	}
+}
+
+func (ar *APIRouter) writeStoreMetrics(ctx context.Context, out *metrics.Writer) error {
+	stored, err := ar.logStore.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+	out.Family("logdeck_store_bytes", metrics.Gauge, "Bytes of log lines stored for the container.")
+	for _, container := range stored {
+		out.Sample("logdeck_store_bytes", containerLabels(container.Host, container.Name, container.ComposeProject), float64(container.StoredBytes))
+	}
+
+	if size, err := ar.logStore.DBSize(); err == nil {
+		out.Family("logdeck_store_database_bytes", metrics.Gauge, "Size of the log store database on disk, WAL included.")
+		out.Sample("logdeck_store_database_bytes", nil, float64(size))
+	}
+
+	out.Family("logdeck_store_committed_lines_total", metrics.Counter, "Log lines written to the store.")
+	out.Sample("logdeck_store_committed_lines_total", nil, float64(ar.logStore.Committed()))
+	out.Family("logdeck_store_dropped_lines_total", metrics.Counter, "Live log lines the store's ingest buffer dropped because the writer fell behind; they are re-read from the engine.")
+	out.Sample("logdeck_store_dropped_lines_total", nil, float64(ar.logStore.Drops()))
+
+	out.Family("logdeck_log_lines_total", metrics.Counter, "Log lines written to the store, by container and level.")
+	for _, count := range ar.logStore.LineCounts() {
+		series := containerLabels(count.Host, count.Container, count.Project)
+		series["level"] = count.Level
+		out.Sample("logdeck_log_lines_total", series, float64(count.Lines))
+	}
+	return nil
+}
+
+func (ar *APIRouter) writeAlertMetrics(out *metrics.Writer) {
+	out.Family("logdeck_alerts_fired_total", metrics.Counter, "Alerts fired, by rule.")
+	for _, count := range ar.engine.FireCounts() {
+		out.Sample("logdeck_alerts_fired_total", metrics.Labels{
+			"rule_id": count.RuleID,
+			"rule":    count.RuleName,
+			"type":    count.Type,
+		}, float64(count.Fired))
+	}
+
+	out.Family("logdeck_alert_deliveries_total", metrics.Counter, "Fired alerts delivered to their channels, by outcome.")
+	for _, count := range ar.engine.DeliveryCounts() {
+		out.Sample("logdeck_alert_deliveries_total", metrics.Labels{"status": count.Status}, float64(count.Deliveries))
+	}
+
+	out.Family("logdeck_alert_match_drops_total", metrics.Counter, "Rule matches discarded because alert evaluation fell behind.")
+	out.Sample("logdeck_alert_match_drops_total", nil, float64(ar.engine.MatchDrops()))
+}
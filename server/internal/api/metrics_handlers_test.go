@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+)
+
+func doMetricsRequest(router http.Handler, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestMetricsRequiresAnAPIToken(t *testing.T) {
+	t.Setenv("METRICS_ENABLED", "true")
+	store, seed := newHistoryStore(t)
+	seed("local", "aaa", "web", historyBase, "INFO ready")
+	router := newHistoryTestRouter(t, store)
+	token := createToken(t, router, "", "prometheus").Token
+
+	// Login auth is off, yet the endpoint still wants a token.
+	if w := doMetricsRequest(router, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("status without a token = %d, want 401", w.Code)
+	}
+
+	w := doMetricsRequest(router, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type = %q, want the Prometheus text format", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`logdeck_build_info{version="test"} 1`,
+		"# TYPE logdeck_store_bytes gauge",
+		`logdeck_store_bytes{container="web",host="local"} `,
+		"logdeck_store_dropped_lines_total 0",
+		"# TYPE logdeck_alerts_fired_total counter",
+		"logdeck_alert_match_drops_total 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsAreOptIn(t *testing.T) {
+	t.Setenv("METRICS_ENABLED", "")
+	router := newHistoryTestRouter(t, nil)
+	token := createToken(t, router, "", "prometheus").Token
+
+	if w := doMetricsRequest(router, token); strings.Contains(w.Body.String(), "logdeck_build_info") {
+		t.Fatalf("metrics served without METRICS_ENABLED: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMetricsReadStatsWithoutStatsHistory(t *testing.T) {
+	engine := fakeImageEngine(t, "sha256:old", "sha256:new")
+	deps := newTestDeps(t, map[string]string{"METRICS_ENABLED": "true"},
+		config.DockerHost{Name: "local", Host: "tcp://" + engine.Listener.Addr().String()})
+	router := NewRouter(deps)
+	token := createToken(t, router, "", "prometheus").Token
+
+	w := doMetricsRequest(router, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`logdeck_container_running{container="web",host="local"} 1`,
+		`logdeck_container_memory_percent{container="web",host="local"} 50`,
+		`logdeck_container_memory_limit_bytes{container="web",host="local"} 1.34217728e+08`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
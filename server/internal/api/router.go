@@ -9,9 +9,12 @@ import (
 	"github.com/AmoabaKelvin/logdeck/internal/api/middleware"
 	"github.com/AmoabaKelvin/logdeck/internal/auth"
 	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/imageupdates"
 	"github.com/AmoabaKelvin/logdeck/internal/logstore"
 	"github.com/AmoabaKelvin/logdeck/internal/services"
 	"github.com/AmoabaKelvin/logdeck/internal/static"
+	"github.com/AmoabaKelvin/logdeck/internal/statsstore"
+	"github.com/AmoabaKelvin/logdeck/internal/statsstream"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -24,17 +27,40 @@ type APIRouter struct {
 	engine   *alerts.Engine
 	// logStore is nil when log persistence is disabled or unusable.
 	logStore *logstore.Store
-	version  string
+	// statsStore is nil when stats history is disabled or unusable.
+	statsStore *statsstore.Store
+	statsHub   *statsstream.Hub
+	// imageUpdates is nil only in tests; its methods tolerate that.
+	imageUpdates *imageupdates.Checker
+	version      string
 }
 
-func NewRouter(registry *services.Registry, manager *config.Manager, engine *alerts.Engine, logStore *logstore.Store, version string) *chi.Mux {
+// RouterDeps is what the API serves from. Registry, Manager, and Version are
+// required; the rest may be nil when their feature is off.
+type RouterDeps struct {
+	Registry *services.Registry
+	Manager  *config.Manager
+	Alerts   *alerts.Engine
+	// LogStore is nil when log persistence is disabled or unusable.
+	LogStore *logstore.Store
+	// StatsStore is nil when stats history is disabled or unusable.
+	StatsStore   *statsstore.Store
+	StatsHub     *statsstream.Hub
+	ImageUpdates *imageupdates.Checker
+	Version      string
+}
+
+func NewRouter(deps RouterDeps) *chi.Mux {
 	r := &APIRouter{
-		router:   chi.NewRouter(),
-		registry: registry,
-		manager:  manager,
-		engine:   engine,
-		logStore: logStore,
-		version:  version,
+		router:       chi.NewRouter(),
+		registry:     deps.Registry,
+		manager:      deps.Manager,
+		engine:       deps.Alerts,
+		logStore:     deps.LogStore,
+		statsStore:   deps.StatsStore,
+		statsHub:     deps.StatsHub,
+		imageUpdates: deps.ImageUpdates,
+		version:      deps.Version,
 	}
 
 	return r.Routes()
@@ -97,11 +123,18 @@ func (ar *APIRouter) Routes() *chi.Mux {
 			protected.Get("/networks", ar.GetNetworks)
 			protected.Get("/hosts/stats", ar.GetHostsStats)
 			ar.registerContainerRoutes(protected)
+			ar.registerImageUpdateRoutes(protected)
 			ar.registerComposeRoutes(protected)
 			ar.registerHistoryRoutes(protected)
 		})
 	})
 
+	// Prometheus metrics - opt-in, and always behind an API token, even when
+	// login auth is disabled
+	if metricsEnabled() {
+		ar.router.With(auth.RequireAPIToken(ar.lookupAPIToken)).Get("/metrics", ar.GetMetrics)
+	}
+
 	staticFS, err := static.GetFileSystem()
 	if err != nil {
 		log.Printf("Warning: Could not load embedded frontend files: %v", err)
@@ -117,6 +150,7 @@ func (ar *APIRouter) Routes() *chi.Mux {
 func (ar *APIRouter) registerContainerRoutes(r chi.Router) {
 	r.Get("/containers", ar.GetContainers)
 	r.Get("/containers/stats", ar.GetContainerStats)
+	r.Get("/containers/stats/stream", ar.StreamContainerStats)
 	r.Get("/logs/aggregate", ar.GetAggregatedLogs)
 	r.Route("/containers/{id}", func(r chi.Router) {
 		// Read-only routes (always available)
@@ -125,6 +159,7 @@ func (ar *APIRouter) registerContainerRoutes(r chi.Router) {
 		// Env vars expose secrets, so read-scoped tokens are denied
 		r.With(auth.DenyReadScope).Get("/env", ar.GetEnvVariables)
 		r.Get("/resources", ar.GetContainerResources)
+		r.Get("/stats/history", ar.GetContainerStatsHistory)
 
 		// Mutating routes (blocked in read-only mode)
 		r.Group(func(mutating chi.Router) {
@@ -137,6 +172,7 @@ func (ar *APIRouter) registerContainerRoutes(r chi.Router) {
 			mutating.Post("/remove", ar.RemoveContainer)
 			mutating.Put("/env", ar.UpdateEnvVariables)
 			mutating.Put("/resources", ar.UpdateContainerResources)
+			mutating.Post("/update-image", ar.UpdateContainerImage)
 			// Exec is GET (websocket upgrade) but spawns a shell, so
 			// read-scoped tokens are denied explicitly
 			mutating.With(auth.DenyReadScope).Get("/exec", ar.HandleTerminal)
@@ -151,16 +187,25 @@ func (ar *APIRouter) registerContainerRoutes(r chi.Router) {
 // sit in the plain protected group alongside the live log routes, while purging
 // a container's stored logs is destructive and irreversible, so it is treated
 // exactly like container removal — blocked in read-only mode and denied to
-// read-scoped API tokens.
+// read-scoped API tokens. Export and import are admin-only too.
 func (ar *APIRouter) registerHistoryRoutes(r chi.Router) {
 	r.Get("/history/status", ar.GetHistoryStatus)
 	r.Get("/history/containers", ar.GetHistoryContainers)
 	r.Get("/history/logs", ar.GetHistoryLogs)
+	r.Get("/history/stats", ar.GetHistoryStats)
 
 	r.With(
 		middleware.ReadOnly(func() bool { return ar.registry.Config().ReadOnly }),
 		auth.DenyReadScope,
 	).Delete("/history/containers/{name}", ar.DeleteHistoryContainer)
+
+	// Export and import move whole histories in bulk, so both are admin-only;
+	// import writes stored logs and is blocked in read-only mode as well.
+	r.With(auth.DenyReadScope).Get("/history/export", ar.ExportHistory)
+	r.With(
+		middleware.ReadOnly(func() bool { return ar.registry.Config().ReadOnly }),
+		auth.DenyReadScope,
+	).Post("/history/import", ar.ImportHistory)
 }
 
 func (ar *APIRouter) registerSettingsRoutes(r chi.Router) {
@@ -210,6 +255,19 @@ func (ar *APIRouter) registerAlertRoutes(r chi.Router) {
 		r.Post("/channels/{id}/test", ar.TestAlertChannel)
 		r.Get("/history", ar.GetAlertHistory)
 		r.Delete("/history", ar.ClearAlertHistory)
+		r.Get("/history/retention", ar.GetAlertHistoryRetention)
+		r.Put("/history/retention", ar.UpdateAlertHistoryRetention)
+		r.Get("/delivery", ar.GetAlertDelivery)
+		r.Put("/delivery", ar.UpdateAlertDelivery)
+		r.Post("/history/{id}/ack", ar.AcknowledgeAlert)
+		r.Post("/history/{id}/silence", ar.SilenceAlert)
+		r.Get("/silences", ar.ListAlertSilences)
+		r.Post("/silences", ar.CreateAlertSilence)
+		r.Delete("/silences/{id}", ar.DeleteAlertSilence)
+		r.Get("/maintenance", ar.ListMaintenanceWindows)
+		r.Post("/maintenance", ar.CreateMaintenanceWindow)
+		r.Put("/maintenance/{id}", ar.UpdateMaintenanceWindow)
+		r.Delete("/maintenance/{id}", ar.DeleteMaintenanceWindow)
 	})
 }
 
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/services"
+)
+
+// newTestDeps builds the dependencies a test router starts from: a fresh
+// config file, no auth, and Docker clients for hosts (none by default). env is
+// set after the environment is cleared and before the config is read. Tests
+// add the optional stores and services they exercise.
+func newTestDeps(t *testing.T, env map[string]string, hosts ...config.DockerHost) RouterDeps {
+	t.Helper()
+	for _, key := range []string{
+		"JWT_SECRET", "ADMIN_USERNAME", "ADMIN_PASSWORD", "ADMIN_PASSWORD_SALT",
+		"DOCKER_HOSTS", "COOLIFY_CONFIGS", "READONLY_MODE", "CORS_ALLOWED_ORIGINS",
+	} {
+		t.Setenv(key, "")
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	t.Setenv("CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+
+	manager := config.NewManager()
+	dockerClient, err := docker.NewMultiHostClient(hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := services.NewRegistry(dockerClient, nil, nil, manager.Config())
+	return RouterDeps{Registry: registry, Manager: manager, Version: "test"}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,6 +16,8 @@ import (
 	"github.com/AmoabaKelvin/logdeck/internal/config"
 	"github.com/AmoabaKelvin/logdeck/internal/coolify"
 	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/logstore"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
 )
 
 const secretMask = "••••••••"
@@ -85,6 +88,14 @@ func (ar *APIRouter) GetSettings(w http.ResponseWriter, r *http.Request) {
 			"perContainerMBSource": logStoreSources.PerContainerMB,
 			"totalMB":              logStore.TotalMB,
 			"totalMBSource":        logStoreSources.TotalMB,
+			"fullTextIndex":        logStore.FullTextIndex,
+			"fullTextIndexSource":  logStoreSources.FullTextIndex,
+			"indexedFields":        logStore.IndexedFields,
+			"indexedFieldsSource":  logStoreSources.IndexedFields,
+			"retentionRules":       logStore.RetentionRules,
+			"retentionRulesSource": logStoreSources.RetentionRules,
+			"ingestionRules":       logStore.IngestionRules,
+			"ingestionRulesSource": logStoreSources.IngestionRules,
 		},
 		"coolifyHosts": map[string]any{
 			"source": sources.CoolifyHosts,
@@ -230,12 +241,18 @@ const maxLogStoreMB = 1024 * 1024
 
 // UpdateLogStorage handles PUT /api/v1/settings/log-storage. Every field is
 // optional; only the ones provided change. The janitor re-reads the caps on
-// each pass, so a new cap takes effect without a restart.
+// each pass, so a new cap takes effect without a restart; the writer likewise
+// builds or drops the full-text index shortly after it is toggled, and indexes
+// or forgets each field key shortly after it is added or removed.
 func (ar *APIRouter) UpdateLogStorage(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Enabled        *bool `json:"enabled"`
-		PerContainerMB *int  `json:"perContainerMB"`
-		TotalMB        *int  `json:"totalMB"`
+		Enabled        *bool                   `json:"enabled"`
+		PerContainerMB *int                    `json:"perContainerMB"`
+		TotalMB        *int                    `json:"totalMB"`
+		FullTextIndex  *bool                   `json:"fullTextIndex"`
+		IndexedFields  *[]string               `json:"indexedFields"`
+		RetentionRules *[]config.RetentionRule `json:"retentionRules"`
+		IngestionRules *[]config.IngestionRule `json:"ingestionRules"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
@@ -253,6 +270,10 @@ func (ar *APIRouter) UpdateLogStorage(w http.ResponseWriter, r *http.Request) {
 		{req.Enabled != nil, sources.Enabled, "enabled is set via the LOG_STORE_ENABLED environment variable and cannot be changed from the UI"},
 		{req.PerContainerMB != nil, sources.PerContainerMB, "perContainerMB is set via the LOG_STORE_PER_CONTAINER_MB environment variable and cannot be changed from the UI"},
 		{req.TotalMB != nil, sources.TotalMB, "totalMB is set via the LOG_STORE_TOTAL_MB environment variable and cannot be changed from the UI"},
+		{req.FullTextIndex != nil, sources.FullTextIndex, "fullTextIndex is set via the LOG_STORE_FULL_TEXT_INDEX environment variable and cannot be changed from the UI"},
+		{req.IndexedFields != nil, sources.IndexedFields, "indexedFields is set via the LOG_STORE_INDEXED_FIELDS environment variable and cannot be changed from the UI"},
+		{req.RetentionRules != nil, sources.RetentionRules, "retentionRules is set via the LOG_STORE_RETENTION_RULES environment variable and cannot be changed from the UI"},
+		{req.IngestionRules != nil, sources.IngestionRules, "ingestionRules is set via the LOG_STORE_INGESTION_RULES environment variable and cannot be changed from the UI"},
 	}
 	for _, f := range pinned {
 		if f.provided && f.source == config.SourceEnv {
@@ -274,6 +295,30 @@ func (ar *APIRouter) UpdateLogStorage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	var indexedFields []string
+	if req.IndexedFields != nil {
+		var err error
+		if indexedFields, err = cleanIndexedFields(*req.IndexedFields); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var retentionRules []config.RetentionRule
+	if req.RetentionRules != nil {
+		var err error
+		if retentionRules, err = cleanRetentionRules(*req.RetentionRules); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var ingestionRules []config.IngestionRule
+	if req.IngestionRules != nil {
+		var err error
+		if ingestionRules, err = cleanIngestionRules(*req.IngestionRules); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	err := ar.manager.UpdateLogStore(func(current config.LogStoreConfig) (config.LogStoreConfig, error) {
 		if req.Enabled != nil {
@@ -285,6 +330,18 @@ func (ar *APIRouter) UpdateLogStorage(w http.ResponseWriter, r *http.Request) {
 		if req.TotalMB != nil {
 			current.TotalMB = req.TotalMB
 		}
+		if req.FullTextIndex != nil {
+			current.FullTextIndex = req.FullTextIndex
+		}
+		if req.IndexedFields != nil {
+			current.IndexedFields = indexedFields
+		}
+		if req.RetentionRules != nil {
+			current.RetentionRules = retentionRules
+		}
+		if req.IngestionRules != nil {
+			current.IngestionRules = ingestionRules
+		}
 		return current, nil
 	})
 	if err != nil {
@@ -295,6 +352,95 @@ func (ar *APIRouter) UpdateLogStorage(w http.ResponseWriter, r *http.Request) {
 	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "log storage settings updated"})
 }
 
+// cleanIndexedFields trims and deduplicates the requested field keys, and
+// rejects a key no filter could name or a list longer than the store indexes.
+func cleanIndexedFields(requested []string) ([]string, error) {
+	fields := make([]string, 0, len(requested))
+	for _, key := range requested {
+		key = strings.TrimSpace(key)
+		if key == "" || slices.Contains(fields, key) {
+			continue
+		}
+		if !models.ValidFieldKey(key) {
+			return nil, fmt.Errorf("invalid indexed field %q: expected a letter or underscore followed by letters, digits, '_', '.', or '-'", key)
+		}
+		fields = append(fields, key)
+	}
+	if len(fields) > config.MaxIndexedFields {
+		return nil, fmt.Errorf("at most %d indexed fields are allowed", config.MaxIndexedFields)
+	}
+	return fields, nil
+}
+
+// maxRetentionHours bounds a retention rule's age at ten years.
+const maxRetentionHours = 10 * 365 * 24
+
+// cleanRetentionRules trims the requested rules' targets and levels, and
+// rejects a rule without a usable age or naming a level lines are never
+// classified as. Rule order is kept: the first matching rule wins.
+func cleanRetentionRules(requested []config.RetentionRule) ([]config.RetentionRule, error) {
+	rules := make([]config.RetentionRule, 0, len(requested))
+	for i, rule := range requested {
+		if rule.MaxAgeHours < 1 || rule.MaxAgeHours > maxRetentionHours {
+			return nil, fmt.Errorf("retention rule %d: maxAgeHours must be between 1 and %d", i+1, maxRetentionHours)
+		}
+		clean := config.RetentionRule{
+			Hosts:       cleanList(rule.Hosts),
+			Containers:  cleanList(rule.Containers),
+			Projects:    cleanList(rule.Projects),
+			Levels:      cleanList(rule.Levels),
+			MaxAgeHours: rule.MaxAgeHours,
+		}
+		for j, level := range clean.Levels {
+			if !logstore.KnownLevel(level) {
+				return nil, fmt.Errorf("retention rule %d: unknown level %q", i+1, level)
+			}
+			clean.Levels[j] = strings.ToUpper(level)
+		}
+		rules = append(rules, clean)
+	}
+	return rules, nil
+}
+
+// cleanIngestionRules trims the requested rules' targets and action, and
+// rejects a rule the store could not apply as written: an unknown action, a
+// drop rule without a valid pattern, or a sample rate outside 0-100.
+func cleanIngestionRules(requested []config.IngestionRule) ([]config.IngestionRule, error) {
+	rules := make([]config.IngestionRule, 0, len(requested))
+	for i, rule := range requested {
+		clean := config.IngestionRule{
+			Hosts:      cleanList(rule.Hosts),
+			Containers: cleanList(rule.Containers),
+			Projects:   cleanList(rule.Projects),
+			Labels:     cleanList(rule.Labels),
+			Action:     strings.ToLower(strings.TrimSpace(rule.Action)),
+		}
+		switch clean.Action {
+		case config.IngestionDrop:
+			clean.Pattern = rule.Pattern
+		case config.IngestionSample:
+			clean.SamplePercent = rule.SamplePercent
+		}
+		if err := config.CheckIngestionRule(clean); err != nil {
+			return nil, fmt.Errorf("ingestion rule %d: %w", i+1, err)
+		}
+		rules = append(rules, clean)
+	}
+	return rules, nil
+}
+
+// cleanList trims each value and drops blanks and duplicates.
+func cleanList(values []string) []string {
+	var list []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
 // validateLogStoreCaps rejects caps that make retention nonsense: a non-positive
 // cap would evict the whole store on the next janitor pass, and a per-container
 // cap above the total cap can never be reached.
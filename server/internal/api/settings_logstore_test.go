@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -21,6 +23,8 @@ func newLogStoreTestRouter(t *testing.T, authSvc *auth.Service) (http.Handler, *
 		"JWT_SECRET", "ADMIN_USERNAME", "ADMIN_PASSWORD", "ADMIN_PASSWORD_SALT",
 		"DOCKER_HOSTS", "COOLIFY_CONFIGS", "READONLY_MODE", "CORS_ALLOWED_ORIGINS",
 		"LOG_STORE_ENABLED", "LOG_STORE_PER_CONTAINER_MB", "LOG_STORE_TOTAL_MB",
+		"LOG_STORE_FULL_TEXT_INDEX", "LOG_STORE_INDEXED_FIELDS", "LOG_STORE_RETENTION_RULES",
+		"LOG_STORE_INGESTION_RULES",
 	} {
 		t.Setenv(key, "")
 	}
@@ -28,7 +32,7 @@ func newLogStoreTestRouter(t *testing.T, authSvc *auth.Service) (http.Handler, *
 
 	manager := config.NewManager()
 	registry := services.NewRegistry(nil, nil, authSvc, manager.Config())
-	return NewRouter(registry, manager, nil, nil, "test"), manager
+	return NewRouter(RouterDeps{Registry: registry, Manager: manager, Version: "test"}), manager
 }
 
 func putLogStorage(t *testing.T, router http.Handler, body string) *httptest.ResponseRecorder {
@@ -71,12 +75,18 @@ func TestGetSettingsReportsLogStoreDefaultsAsFileSourced(t *testing.T) {
 		"perContainerMBSource": "file",
 		"totalMB":              float64(config.DefaultLogStoreTotalMB),
 		"totalMBSource":        "file",
+		"fullTextIndex":        false,
+		"fullTextIndexSource":  "file",
+		"indexedFieldsSource":  "file",
 	}
 	for key, expected := range want {
 		if block[key] != expected {
 			t.Errorf("logStore[%q] = %v, want %v", key, block[key], expected)
 		}
 	}
+	if fields, ok := block["indexedFields"].([]any); !ok || len(fields) != 0 {
+		t.Errorf("logStore[\"indexedFields\"] = %v, want an empty list", block["indexedFields"])
+	}
 }
 
 func TestGetSettingsMarksEnvPinnedCapsAsEnvSourced(t *testing.T) {
@@ -114,6 +124,29 @@ func TestUpdateLogStoragePersistsAndIsVisible(t *testing.T) {
 	}
 }
 
+func TestUpdateLogStorageTogglesFullTextIndex(t *testing.T) {
+	router, manager := newLogStoreTestRouter(t, nil)
+
+	if w := putLogStorage(t, router, `{"fullTextIndex":true}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if !manager.LogStore().FullTextIndex {
+		t.Fatal("FullTextIndex = false after enabling it")
+	}
+	if block := getLogStoreBlock(t, router); block["fullTextIndex"] != true {
+		t.Fatalf("GET /settings did not reflect the update: %v", block)
+	}
+
+	t.Setenv("LOG_STORE_FULL_TEXT_INDEX", "false")
+	w := putLogStorage(t, router, `{"fullTextIndex":true}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("PUT = %d, want 409 for an env-pinned index switch: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "LOG_STORE_FULL_TEXT_INDEX") {
+		t.Errorf("message %q does not name the environment variable", strings.TrimSpace(w.Body.String()))
+	}
+}
+
 // Only the provided fields change; the others keep their current values.
 func TestUpdateLogStorageAppliesOnlyProvidedFields(t *testing.T) {
 	router, manager := newLogStoreTestRouter(t, nil)
@@ -206,7 +239,7 @@ func TestUpdateLogStorageRejectedInReadOnlyMode(t *testing.T) {
 
 	manager := config.NewManager()
 	registry := services.NewRegistry(nil, nil, nil, manager.Config())
-	router := NewRouter(registry, manager, nil, nil, "test")
+	router := NewRouter(RouterDeps{Registry: registry, Manager: manager, Version: "test"})
 
 	// Lowering a cap evicts stored logs, so it is blocked like any other
 	// destructive route.
@@ -258,3 +291,122 @@ func TestUpdateLogStorageRejectsUnauthenticated(t *testing.T) {
 		t.Fatalf("PUT without a token = %d, want 401: %s", w.Code, w.Body.String())
 	}
 }
+
+func TestUpdateLogStorageSetsIndexedFields(t *testing.T) {
+	router, manager := newLogStoreTestRouter(t, nil)
+
+	if w := putLogStorage(t, router, `{"indexedFields":[" status ","request_id","status",""]}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if got := manager.LogStore().IndexedFields; strings.Join(got, ",") != "status,request_id" {
+		t.Fatalf("IndexedFields = %q, want trimmed and deduplicated keys", got)
+	}
+
+	tooMany := make([]string, config.MaxIndexedFields+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%q", fmt.Sprintf("key%d", i))
+	}
+	for _, body := range []string{
+		`{"indexedFields":["http status"]}`,
+		`{"indexedFields":[` + strings.Join(tooMany, ",") + `]}`,
+	} {
+		if w := putLogStorage(t, router, body); w.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s = %d, want 400: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	t.Setenv("LOG_STORE_INDEXED_FIELDS", "status")
+	if w := putLogStorage(t, router, `{"indexedFields":[]}`); w.Code != http.StatusConflict {
+		t.Fatalf("PUT = %d, want 409 for env-pinned indexed fields: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateLogStorageSetsRetentionRules(t *testing.T) {
+	router, manager := newLogStoreTestRouter(t, nil)
+
+	body := `{"retentionRules":[
+		{"levels":[" debug ","debug"],"maxAgeHours":24},
+		{"projects":["payments"],"maxAgeHours":336},
+		{"maxAgeHours":48}
+	]}`
+	if w := putLogStorage(t, router, body); w.Code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	want := []config.RetentionRule{
+		{Levels: []string{"DEBUG"}, MaxAgeHours: 24},
+		{Projects: []string{"payments"}, MaxAgeHours: 336},
+		{MaxAgeHours: 48},
+	}
+	if got := manager.LogStore().RetentionRules; !reflect.DeepEqual(got, want) {
+		t.Fatalf("RetentionRules = %+v, want %+v", got, want)
+	}
+
+	block := getLogStoreBlock(t, router)
+	if rules, ok := block["retentionRules"].([]any); !ok || len(rules) != 3 || block["retentionRulesSource"] != "file" {
+		t.Fatalf("GET /settings did not reflect the rules: %v", block)
+	}
+
+	for _, body := range []string{
+		`{"retentionRules":[{"containers":["web"]}]}`,
+		`{"retentionRules":[{"maxAgeHours":-1}]}`,
+		`{"retentionRules":[{"levels":["VERBOSE"],"maxAgeHours":1}]}`,
+	} {
+		if w := putLogStorage(t, router, body); w.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s = %d, want 400: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	// An empty list removes every rule.
+	if w := putLogStorage(t, router, `{"retentionRules":[]}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if got := manager.LogStore().RetentionRules; len(got) != 0 {
+		t.Fatalf("RetentionRules = %+v, want none", got)
+	}
+
+	t.Setenv("LOG_STORE_RETENTION_RULES", `[{"maxAgeHours":12}]`)
+	if w := putLogStorage(t, router, `{"retentionRules":[]}`); w.Code != http.StatusConflict {
+		t.Fatalf("PUT = %d, want 409 for env-pinned retention rules: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateLogStorageSetsIngestionRules(t *testing.T) {
+	router, manager := newLogStoreTestRouter(t, nil)
+
+	body := `{"ingestionRules":[
+		{"containers":[" pinger "],"action":"Exclude"},
+		{"labels":["traefik.enable=true"],"action":"drop","pattern":"GET /ping","samplePercent":5},
+		{"projects":["edge"],"action":"sample","samplePercent":10}
+	]}`
+	if w := putLogStorage(t, router, body); w.Code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	want := []config.IngestionRule{
+		{Containers: []string{"pinger"}, Action: config.IngestionExclude},
+		{Labels: []string{"traefik.enable=true"}, Action: config.IngestionDrop, Pattern: "GET /ping"},
+		{Projects: []string{"edge"}, Action: config.IngestionSample, SamplePercent: 10},
+	}
+	if got := manager.LogStore().IngestionRules; !reflect.DeepEqual(got, want) {
+		t.Fatalf("IngestionRules = %+v, want %+v", got, want)
+	}
+	block := getLogStoreBlock(t, router)
+	if rules, ok := block["ingestionRules"].([]any); !ok || len(rules) != 3 || block["ingestionRulesSource"] != "file" {
+		t.Fatalf("GET /settings did not reflect the rules: %v", block)
+	}
+
+	for _, body := range []string{
+		`{"ingestionRules":[{"action":"mute"}]}`,
+		`{"ingestionRules":[{"action":"drop"}]}`,
+		`{"ingestionRules":[{"action":"drop","pattern":"("}]}`,
+		`{"ingestionRules":[{"action":"sample","samplePercent":150}]}`,
+	} {
+		if w := putLogStorage(t, router, body); w.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s = %d, want 400: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	t.Setenv("LOG_STORE_INGESTION_RULES", `[{"action":"exclude"}]`)
+	if w := putLogStorage(t, router, `{"ingestionRules":[]}`); w.Code != http.StatusConflict {
+		t.Fatalf("PUT = %d, want 409 for env-pinned ingestion rules: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+	"github.com/AmoabaKelvin/logdeck/internal/statsstore"
+)
+
+// GetContainerStatsHistory returns a container's stored CPU, memory, network,
+// and block I/O usage. History is keyed by container name, so it spans
+// recreates; {id} may be an ID or a name, and a container that no longer
+// exists is looked up by the name given.
+func (ar *APIRouter) GetContainerStatsHistory(w http.ResponseWriter, r *http.Request) {
+	host, id, ok := containerParams(w, r)
+	if !ok {
+		return
+	}
+	if ar.statsStore == nil {
+		WriteJsonResponse(w, http.StatusServiceUnavailable, map[string]string{
+			"error": "stats history is disabled",
+		})
+		return
+	}
+
+	params := r.URL.Query()
+	query := statsstore.Query{
+		Host:       host,
+		Container:  strings.TrimPrefix(id, "/"),
+		Resolution: params.Get("resolution"),
+	}
+	for _, param := range []struct {
+		name string
+		dest *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		value := params.Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: expected an RFC3339 timestamp", param.name), http.StatusBadRequest)
+			return
+		}
+		*param.dest = parsed
+	}
+	if query.Since.IsZero() {
+		query.Since = time.Now().Add(-time.Hour)
+	}
+	if !query.Until.IsZero() && !query.Until.After(query.Since) {
+		http.Error(w, "until must be after since", http.StatusBadRequest)
+		return
+	}
+
+	if inspect, err := ar.registry.Docker().GetContainer(r.Context(), host, id); err == nil {
+		query.Container = strings.TrimPrefix(inspect.Name, "/")
+	}
+
+	history, err := ar.statsStore.History(r.Context(), query)
+	if errors.Is(err, statsstore.ErrInvalidResolution) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, models.ContainerStatsHistoryResponse{
+		Host:       host,
+		Container:  query.Container,
+		Resolution: history.Resolution,
+		Points:     history.Points,
+	})
+}
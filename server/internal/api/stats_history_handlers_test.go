@@ -0,0 +1,116 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+	"github.com/AmoabaKelvin/logdeck/internal/statsstore"
+)
+
+// newStatsHistoryTestRouter builds a router over a real stats store with no
+// Docker hosts, so containers are looked up by the name in the path. The
+// sampler is unexported, so samples are seeded through a second connection.
+func newStatsHistoryTestRouter(t *testing.T, enabled bool) (http.Handler, func(name string, ts time.Time, cpu float64)) {
+	t.Helper()
+	deps := newTestDeps(t, nil)
+	if !enabled {
+		return NewRouter(deps), nil
+	}
+
+	path := filepath.Join(t.TempDir(), "stats.db")
+	store, err := statsstore.Open(path, func() config.ResolvedStatsHistoryConfig {
+		return config.ResolvedStatsHistoryConfig{Enabled: true, IntervalSeconds: 15, RawHours: 24, MinuteDays: 7, HourDays: 90}
+	})
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("open seed connection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	seed := func(name string, ts time.Time, cpu float64) {
+		t.Helper()
+		if _, err := db.Exec(`INSERT INTO samples
+			(resolution, host, name, ts_ms, samples, cpu_avg, cpu_max, mem_avg, mem_max, mem_limit, net_rx, net_tx, blk_read, blk_write)
+			VALUES (0, 'local', ?, ?, 1, ?, ?, 100, 100, 1000, 0, 0, 0, 0)`,
+			name, ts.UnixMilli(), cpu, cpu); err != nil {
+			t.Fatalf("insert sample: %v", err)
+		}
+	}
+	deps.StatsStore = store
+	return NewRouter(deps), seed
+}
+
+func TestContainerStatsHistory(t *testing.T) {
+	router, seed := newStatsHistoryTestRouter(t, true)
+	now := time.Now().Truncate(time.Second)
+	seed("web", now.Add(-20*time.Minute), 10)
+	seed("web", now.Add(-10*time.Minute), 30)
+	seed("db", now.Add(-10*time.Minute), 99)
+
+	since := now.Add(-30 * time.Minute).UTC().Format(time.RFC3339)
+	w := doHistoryRequest(t, router, "/api/v1/containers/web/stats/history?host=local&since="+since)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var body models.ContainerStatsHistoryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Container != "web" || body.Resolution != statsstore.ResolutionRaw || len(body.Points) != 2 {
+		t.Fatalf("response = %+v, want web's 2 raw samples", body)
+	}
+	if body.Points[0].CPUPercent != 10 || body.Points[1].CPUPercent != 30 {
+		t.Errorf("points = %+v, want oldest first", body.Points)
+	}
+
+	// A container with no samples has an empty list, not null.
+	w = doHistoryRequest(t, router, "/api/v1/containers/gone/stats/history?host=local")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unknown container: status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var empty map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &empty); err != nil {
+		t.Fatal(err)
+	}
+	if points, ok := empty["points"].([]any); !ok || len(points) != 0 {
+		t.Errorf("points = %v, want []", empty["points"])
+	}
+}
+
+func TestContainerStatsHistoryValidation(t *testing.T) {
+	router, _ := newStatsHistoryTestRouter(t, true)
+	for _, tc := range []struct {
+		name, query string
+	}{
+		{"missing host", ""},
+		{"bad since", "host=local&since=yesterday"},
+		{"until before since", "host=local&since=2026-07-10T12:00:00Z&until=2026-07-10T11:00:00Z"},
+		{"bad resolution", "host=local&resolution=5m"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := doHistoryRequest(t, router, "/api/v1/containers/web/stats/history?"+tc.query)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestContainerStatsHistoryDisabled(t *testing.T) {
+	router, _ := newStatsHistoryTestRouter(t, false)
+	w := doHistoryRequest(t, router, "/api/v1/containers/web/stats/history?host=local")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", w.Code)
+	}
+}
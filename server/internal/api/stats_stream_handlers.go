@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AmoabaKelvin/logdeck/internal/statsstream"
+)
+
+// StreamContainerStats streams container stats as NDJSON from the shared stats
+// hub: first the latest reading of every selected container, then each fresh
+// reading (about once a second), and a line with "removed": true when a
+// container stops. host and id optionally narrow the stream. A slow client
+// skips readings rather than falling behind.
+func (ar *APIRouter) StreamContainerStats(w http.ResponseWriter, r *http.Request) {
+	if ar.statsHub == nil {
+		WriteJsonResponse(w, http.StatusServiceUnavailable, map[string]string{
+			"error": "stats streaming is unavailable",
+		})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	sub := ar.statsHub.Subscribe(statsstream.Filter{
+		Host:        query.Get("host"),
+		ContainerID: query.Get("id"),
+	})
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done():
+			return
+		case <-sub.Ready():
+			for _, event := range sub.Take() {
+				if err := encoder.Encode(event); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
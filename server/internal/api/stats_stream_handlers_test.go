@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/statsstream"
+)
+
+func newStatsStreamTestRouter(t *testing.T, withHub bool) http.Handler {
+	t.Helper()
+	deps := newTestDeps(t, nil)
+	if !withHub {
+		return NewRouter(deps)
+	}
+
+	hub := statsstream.New(deps.Registry)
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		hub.Wait()
+	})
+	deps.StatsHub = hub
+	return NewRouter(deps)
+}
+
+func TestStreamContainerStatsUnavailable(t *testing.T) {
+	router := newStatsStreamTestRouter(t, false)
+	w := doHistoryRequest(t, router, "/api/v1/containers/stats/stream")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", w.Code)
+	}
+}
+
+func TestStreamContainerStatsOpensAndEndsWithTheClient(t *testing.T) {
+	server := httptest.NewServer(newStatsStreamTestRouter(t, true))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/containers/stats/stream?host=local", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d, content type %q; want a 200 NDJSON stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	// Hanging up must end the handler, and with it the subscription; the
+	// server's Close would block on a handler that kept running.
+	cancel()
+}
@@ -114,3 +114,30 @@ func DenyReadScope(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequireAPIToken creates a middleware that admits only requests bearing a
+// valid API token of either scope, whether or not login auth is enabled. It
+// guards machine endpoints such as /metrics, which a scraper reaches with a
+// long-lived token rather than a session. The token must come in the
+// Authorization header, so it never lands in a URL or an access log.
+func RequireAPIToken(lookupAPIToken APITokenLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !strings.HasPrefix(tokenString, APITokenPrefix) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "An API token is required", http.StatusUnauthorized)
+				return
+			}
+			name, scope, ok := lookupAPIToken(tokenString)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+			user := models.User{Username: "token:" + name, Role: NormalizeAPITokenScope(scope)}
+			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
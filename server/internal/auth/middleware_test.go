@@ -229,3 +229,49 @@ func TestDynamicMiddlewarePassesThroughWhenAuthDisabled(t *testing.T) {
 		t.Errorf("expected 200 with auth disabled, got %d", w.Code)
 	}
 }
+
+func TestRequireAPITokenAdmitsOnlyAPITokens(t *testing.T) {
+	token, hash, _, err := GenerateAPIToken()
+	if err != nil {
+		t.Fatalf("GenerateAPIToken failed: %v", err)
+	}
+	lookup := func(presented string) (string, string, bool) {
+		if HashAPIToken(presented) == hash {
+			return "prometheus", APITokenScopeRead, true
+		}
+		return "", "", false
+	}
+	jwt, err := testService(t).GenerateToken("admin")
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+
+	var gotUser models.User
+	handler := RequireAPIToken(lookup)(echoUserHandler(&gotUser))
+	cases := []struct {
+		name   string
+		header string
+		query  string
+		want   int
+	}{
+		{"api token", "Bearer " + token, "", http.StatusOK},
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"unknown api token", "Bearer " + APITokenPrefix + "nope", "", http.StatusUnauthorized},
+		{"session jwt", "Bearer " + jwt, "", http.StatusUnauthorized},
+		{"token in the query", "", "?token=" + token, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/metrics"+tc.query, nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		handler.ServeHTTP(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, w.Code, tc.want)
+		}
+	}
+	if gotUser.Username != "token:prometheus" || gotUser.Role != APITokenScopeRead {
+		t.Fatalf("user = %+v, want the read-scoped prometheus token", gotUser)
+	}
+}
@@ -52,18 +52,41 @@ func newActionCmd(a *app, name, endpoint, pastTense string) *cobra.Command {
 
 var validStackActions = map[string]bool{"start": true, "stop": true, "restart": true}
 
+// stackDeployActions run from the project's compose files on the server and
+// stream their progress.
+var stackDeployActions = map[string]bool{"pull": true, "up": true, "down": true}
+
+const stackLong = `Act on a whole compose project.
+
+start, stop, and restart act on the project's existing containers, on every
+host that has the project unless --host narrows it down.
+
+pull, up, and down work like their docker compose counterparts, driven by the
+server through the engine API from the compose files the project's containers
+were started with (their config_files and working_dir labels). The server must
+be able to read those files at the same path. pull pulls every service's
+image; up creates missing networks, volumes, and containers and recreates
+those whose configuration or image changed; down stops and removes the
+containers and the project's networks, keeping volumes. Progress is printed as
+it happens; with -o json each step is one NDJSON line.`
+
 func newStackCmd(a *app) *cobra.Command {
 	var host string
 
 	cmd := &cobra.Command{
-		Use:   "stack <start|stop|restart> <project>",
-		Short: "Start, stop, or restart a whole compose project",
+		Use:   "stack <start|stop|restart|pull|up|down> <project>",
+		Short: "Start, stop, restart, pull, up, or down a whole compose project",
+		Long:  stackLong,
+		Example: `  logdeck stack restart shop
+  logdeck stack pull shop --host prod
+  logdeck stack up shop --host prod
+  logdeck stack down shop -o json`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 2 {
 				return fmt.Errorf("expected an action and a project (e.g. logdeck stack restart myapp)")
 			}
-			if !validStackActions[args[0]] {
-				return fmt.Errorf("invalid action %q: must be one of start, stop, restart", args[0])
+			if !validStackActions[args[0]] && !stackDeployActions[args[0]] {
+				return fmt.Errorf("invalid action %q: must be one of start, stop, restart, pull, up, down", args[0])
 			}
 			return nil
 		},
@@ -71,23 +94,17 @@ func newStackCmd(a *app) *cobra.Command {
 			ctx := cmd.Context()
 			action, project := args[0], args[1]
 
-			hosts := []string{host}
-			if host == "" {
-				resp, err := a.fetchContainers(ctx)
+			if stackDeployActions[action] {
+				deployHost, err := a.stackDeployHost(ctx, project, host)
 				if err != nil {
 					return err
 				}
-				seen := map[string]bool{}
-				hosts = nil
-				for _, c := range resp.Containers {
-					if composeProject(c.Labels) == project && !seen[c.Host] {
-						seen[c.Host] = true
-						hosts = append(hosts, c.Host)
-					}
-				}
-				if len(hosts) == 0 {
-					return fmt.Errorf("no containers found for stack %q", project)
-				}
+				return a.stackDeploy(ctx, project, action, deployHost)
+			}
+
+			hosts, err := a.stackHosts(ctx, project, host)
+			if err != nil {
+				return err
 			}
 
 			var results []composeResult
@@ -121,10 +138,112 @@ func newStackCmd(a *app) *cobra.Command {
 		}),
 	}
 
-	cmd.Flags().StringVar(&host, "host", "", "apply only on this host (default: every host that has the project)")
+	cmd.Flags().StringVar(&host, "host", "", "apply only on this host (default: every host that has the project; pull, up, and down need exactly one)")
 	return cmd
 }
 
+// stackHosts returns the hosts a stack action applies to: host when set,
+// otherwise every host with a container in the project.
+func (a *app) stackHosts(ctx context.Context, project, host string) ([]string, error) {
+	if host != "" {
+		return []string{host}, nil
+	}
+	resp, err := a.fetchContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var hosts []string
+	for _, c := range resp.Containers {
+		if composeProject(c.Labels) == project && !seen[c.Host] {
+			seen[c.Host] = true
+			hosts = append(hosts, c.Host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no containers found for stack %q", project)
+	}
+	return hosts, nil
+}
+
+// stackDeployHost is stackHosts for pull, up, and down, which run against
+// one host's copy of the compose files.
+func (a *app) stackDeployHost(ctx context.Context, project, host string) (string, error) {
+	hosts, err := a.stackHosts(ctx, project, host)
+	if err != nil {
+		return "", err
+	}
+	if len(hosts) > 1 {
+		return "", fmt.Errorf("stack %q exists on several hosts (%s); pick one with --host", project, strings.Join(hosts, ", "))
+	}
+	return hosts[0], nil
+}
+
+// stackDeploy runs a compose pull/up/down on the server and prints its
+// progress. The stream ends with a "done" or "error" line; an error, or a
+// stream that ends without either, fails the command.
+func (a *app) stackDeploy(ctx context.Context, project, action, host string) error {
+	body, err := a.client.streamPost(ctx, "/compose/"+project+"/"+action, url.Values{"host": {host}})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var final *composeDeployEvent
+	err = a.scanNDJSON(ctx, body, func(line []byte) error {
+		var event composeDeployEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil // skip malformed lines
+		}
+		if a.jsonOutput() {
+			fmt.Println(string(line))
+		}
+		if event.Status == "done" || event.Status == "error" {
+			final = &event
+			return nil
+		}
+		if a.jsonOutput() {
+			return nil
+		}
+		if event.Error != "" {
+			fmt.Fprintf(os.Stderr, "  failed: %s: %s\n", event.Target, event.Error)
+			return nil
+		}
+		fmt.Println(formatDeployEvent(event))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case final == nil:
+		return fmt.Errorf("host %s: the %s of %s ended without a result; check the stack's state", host, action, project)
+	case final.Status == "error":
+		return fmt.Errorf("host %s: %s", host, final.Error)
+	}
+	if !a.jsonOutput() {
+		fmt.Printf("host %s: %s %s done\n", host, action, project)
+	}
+	return nil
+}
+
+// formatDeployEvent renders one progress step, e.g.
+// "web: shop-web-1 recreated (configuration changed)".
+func formatDeployEvent(event composeDeployEvent) string {
+	line := event.Status
+	if event.Target != "" {
+		line = event.Target + " " + line
+	}
+	if event.Service != "" {
+		line = event.Service + ": " + line
+	}
+	if event.Detail != "" {
+		line += " (" + event.Detail + ")"
+	}
+	return line
+}
+
 // composeAction posts a compose project action. The server returns the result
 // body with HTTP 500 when some containers fail, so decode it in both cases.
 func (a *app) composeAction(ctx context.Context, project, action, host string) (composeResult, error) {
@@ -0,0 +1,343 @@
+package cli
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// alertSilence doubles as the create request body: omitempty keeps fields the
+// user didn't set out of the payload.
+type alertSilence struct {
+	ID              string   `json:"id,omitempty"`
+	Hosts           []string `json:"hosts,omitempty"`
+	Containers      []string `json:"containers,omitempty"`
+	Projects        []string `json:"projects,omitempty"`
+	Rules           []string `json:"rules,omitempty"`
+	StartsAt        string   `json:"startsAt,omitempty"`
+	EndsAt          string   `json:"endsAt,omitempty"`
+	DurationSeconds int      `json:"durationSeconds,omitempty"`
+	Comment         string   `json:"comment,omitempty"`
+	CreatedBy       string   `json:"createdBy,omitempty"`
+	Active          bool     `json:"active,omitempty"`
+}
+
+// maintenanceWindow doubles as the create request body.
+type maintenanceWindow struct {
+	ID              string   `json:"id,omitempty"`
+	Name            string   `json:"name"`
+	Enabled         bool     `json:"enabled"`
+	Schedule        string   `json:"schedule"`
+	DurationSeconds int      `json:"durationSeconds"`
+	Timezone        string   `json:"timezone,omitempty"`
+	Hosts           []string `json:"hosts,omitempty"`
+	Containers      []string `json:"containers,omitempty"`
+	Projects        []string `json:"projects,omitempty"`
+	Rules           []string `json:"rules,omitempty"`
+	Active          bool     `json:"active,omitempty"`
+	ActiveSince     string   `json:"activeSince,omitempty"`
+	NextStart       string   `json:"nextStart,omitempty"`
+}
+
+// silenceTargets summarizes what a silence or window mutes ("all" when
+// unrestricted), e.g. "projects=shop rules=r1".
+func silenceTargets(hosts, containers, projects, rules []string) string {
+	targets := ruleTargets(alertRule{Hosts: hosts, Containers: containers, Projects: projects})
+	if len(rules) == 0 {
+		return targets
+	}
+	if targets == "all" {
+		return "rules=" + strings.Join(rules, ",")
+	}
+	return targets + " rules=" + strings.Join(rules, ",")
+}
+
+// silenceState renders whether a silence is active, pending, or expired.
+func silenceState(s alertSilence, now time.Time) string {
+	if s.Active {
+		return "active"
+	}
+	if ends, err := time.Parse(time.RFC3339, s.EndsAt); err == nil && !ends.After(now) {
+		return "expired"
+	}
+	return "pending"
+}
+
+// listJSON fetches a list endpoint and, in JSON mode, prints it under key
+// with an empty list for a missing one. It reports whether it printed.
+func listJSON(a *app, cmd *cobra.Command, path, key string, into any) (bool, error) {
+	raw := map[string]json.RawMessage{}
+	if err := a.client.get(cmd.Context(), path, nil, &raw); err != nil {
+		return false, err
+	}
+	if a.jsonOutput() {
+		var items any
+		if len(raw[key]) > 0 {
+			if err := json.Unmarshal(raw[key], &items); err != nil {
+				return false, err
+			}
+		}
+		if items == nil {
+			items = []any{}
+		}
+		return true, a.printJSON(map[string]any{key: items})
+	}
+	if len(raw[key]) > 0 {
+		if err := json.Unmarshal(raw[key], into); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func newAlertSilencesCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "silences",
+		Short: "List silences that mute matching alerts for a time range",
+		Args:  cobra.NoArgs,
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var silences []alertSilence
+			if printed, err := listJSON(a, cmd, "/alerts/silences", "silences", &silences); printed || err != nil {
+				return err
+			}
+			now := time.Now()
+			rows := make([][]string, 0, len(silences))
+			for _, s := range silences {
+				rows = append(rows, []string{
+					s.ID,
+					silenceState(s, now),
+					silenceTargets(s.Hosts, s.Containers, s.Projects, s.Rules),
+					s.StartsAt,
+					s.EndsAt,
+					cmp.Or(s.CreatedBy, "-"),
+					cmp.Or(s.Comment, "-"),
+				})
+			}
+			renderTable(os.Stdout, []string{"ID", "STATE", "TARGETS", "STARTS", "ENDS", "BY", "COMMENT"}, rows)
+			return nil
+		}),
+	}
+	cmd.AddCommand(newAlertSilenceCreateCmd(a), newAlertSilenceDeleteCmd(a))
+	return cmd
+}
+
+func newAlertSilenceCreateCmd(a *app) *cobra.Command {
+	var (
+		duration, start, until, comment    string
+		hosts, containers, projects, rules []string
+		req                                alertSilence
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create (--for <duration> | --until <time>)",
+		Short: "Silence matching alerts, e.g. during a deploy",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if (duration == "") == (until == "") {
+				return fmt.Errorf("exactly one of --for or --until is required")
+			}
+			for _, t := range []struct{ flag, value string }{{"start", start}, {"until", until}} {
+				if t.value == "" {
+					continue
+				}
+				if _, err := time.Parse(time.RFC3339, t.value); err != nil {
+					return fmt.Errorf("invalid --%s %q (want an RFC 3339 time, e.g. 2026-07-10T22:00:00Z)", t.flag, t.value)
+				}
+			}
+			seconds, err := parseSecondsFlag("for", duration)
+			if err != nil {
+				return err
+			}
+			if duration != "" && seconds == 0 {
+				return fmt.Errorf("--for must be positive")
+			}
+			req = alertSilence{
+				Hosts:           hosts,
+				Containers:      containers,
+				Projects:        projects,
+				Rules:           rules,
+				StartsAt:        start,
+				EndsAt:          until,
+				DurationSeconds: seconds,
+				Comment:         comment,
+			}
+			return nil
+		},
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var created alertSilence
+			if err := a.client.post(cmd.Context(), "/alerts/silences", nil, req, &created); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(created)
+			}
+			fmt.Printf("created silence %q (%s) until %s\n", created.ID, silenceTargets(created.Hosts, created.Containers, created.Projects, created.Rules), created.EndsAt)
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringSliceVar(&hosts, "host", nil, "silence only these hosts (repeatable)")
+	cmd.Flags().StringSliceVar(&containers, "container", nil, "silence only these container names (repeatable)")
+	cmd.Flags().StringSliceVar(&projects, "project", nil, "silence only these compose projects (repeatable)")
+	cmd.Flags().StringSliceVar(&rules, "rule", nil, "silence only these rule IDs (repeatable)")
+	cmd.Flags().StringVar(&duration, "for", "", "how long the silence lasts from its start (e.g. 30m, 2h, 1d)")
+	cmd.Flags().StringVar(&until, "until", "", "when the silence ends, as an RFC 3339 time")
+	cmd.Flags().StringVar(&start, "start", "", "when the silence starts, as an RFC 3339 time (default: now)")
+	cmd.Flags().StringVar(&comment, "comment", "", "why the alerts are silenced, shown in history")
+	return cmd
+}
+
+func newAlertSilenceDeleteCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a silence, ending it at once",
+		Args:  cobra.ExactArgs(1),
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			if err := a.client.do(cmd.Context(), http.MethodDelete, "/alerts/silences/"+args[0], nil, nil, nil); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(map[string]string{"message": "silence deleted", "id": args[0]})
+			}
+			fmt.Printf("deleted silence %q\n", args[0])
+			return nil
+		}),
+	}
+}
+
+// maintenanceStatus renders when a window is or will next be in effect.
+func maintenanceStatus(w maintenanceWindow) string {
+	switch {
+	case w.Active:
+		return "active since " + w.ActiveSince
+	case !w.Enabled:
+		return "disabled"
+	case w.NextStart != "":
+		return "next " + w.NextStart
+	}
+	return "never"
+}
+
+func newAlertMaintenanceCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "List recurring maintenance windows that mute matching alerts",
+		Args:  cobra.NoArgs,
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var windows []maintenanceWindow
+			if printed, err := listJSON(a, cmd, "/alerts/maintenance", "windows", &windows); printed || err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(windows))
+			for _, w := range windows {
+				rows = append(rows, []string{
+					w.ID,
+					w.Name,
+					strconv.FormatBool(w.Enabled),
+					w.Schedule,
+					cmp.Or(w.Timezone, "-"),
+					(time.Duration(w.DurationSeconds) * time.Second).String(),
+					silenceTargets(w.Hosts, w.Containers, w.Projects, w.Rules),
+					maintenanceStatus(w),
+				})
+			}
+			renderTable(os.Stdout, []string{"ID", "NAME", "ENABLED", "SCHEDULE", "TIMEZONE", "DURATION", "TARGETS", "STATUS"}, rows)
+			return nil
+		}),
+	}
+	cmd.AddCommand(newAlertMaintenanceAddCmd(a), newAlertMaintenanceDeleteCmd(a))
+	return cmd
+}
+
+func newAlertMaintenanceAddCmd(a *app) *cobra.Command {
+	var (
+		name, schedule, duration, timezone string
+		hosts, containers, projects, rules []string
+		disabled                           bool
+		req                                maintenanceWindow
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add --name <name> --schedule <cron> --for <duration>",
+		Short: "Add a recurring maintenance window",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return fmt.Errorf("--name is required")
+			}
+			if len(strings.Fields(schedule)) != 5 {
+				return fmt.Errorf("--schedule must be a 5-field cron expression, e.g. '0 3 * * 1-5'")
+			}
+			seconds, err := parseSecondsFlag("for", duration)
+			if err != nil {
+				return err
+			}
+			if seconds == 0 {
+				return fmt.Errorf("--for is required (e.g. 30m)")
+			}
+			req = maintenanceWindow{
+				Name:            name,
+				Enabled:         !disabled,
+				Schedule:        schedule,
+				DurationSeconds: seconds,
+				Timezone:        timezone,
+				Hosts:           hosts,
+				Containers:      containers,
+				Projects:        projects,
+				Rules:           rules,
+			}
+			return nil
+		},
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var created maintenanceWindow
+			if err := a.client.post(cmd.Context(), "/alerts/maintenance", nil, req, &created); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(created)
+			}
+			fmt.Printf("created maintenance window %q (%s), %s\n", name, created.ID, maintenanceStatus(created))
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "window name, shown in history for the alerts it mutes")
+	cmd.Flags().StringVar(&schedule, "schedule", "", "when the window starts, as cron 'minute hour day-of-month month day-of-week'")
+	cmd.Flags().StringVar(&duration, "for", "", "how long each occurrence lasts (e.g. 30m, 2h)")
+	cmd.Flags().StringVar(&timezone, "timezone", "", "IANA time zone the schedule is in, e.g. Europe/Berlin (default UTC)")
+	cmd.Flags().StringSliceVar(&hosts, "host", nil, "mute only these hosts (repeatable)")
+	cmd.Flags().StringSliceVar(&containers, "container", nil, "mute only these container names (repeatable)")
+	cmd.Flags().StringSliceVar(&projects, "project", nil, "mute only these compose projects (repeatable)")
+	cmd.Flags().StringSliceVar(&rules, "rule", nil, "mute only these rule IDs (repeatable)")
+	cmd.Flags().BoolVar(&disabled, "disabled", false, "create the window disabled")
+	return cmd
+}
+
+func newAlertMaintenanceDeleteCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a maintenance window",
+		Args:  cobra.ExactArgs(1),
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			if err := a.client.do(cmd.Context(), http.MethodDelete, "/alerts/maintenance/"+args[0], nil, nil, nil); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(map[string]string{"message": "maintenance window deleted", "id": args[0]})
+			}
+			fmt.Printf("deleted maintenance window %q\n", args[0])
+			return nil
+		}),
+	}
+}
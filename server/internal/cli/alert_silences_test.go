@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAlertSilenceCreatePayload(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/alerts/silences" {
+			t.Errorf("request = %s %s, want POST /api/v1/alerts/silences", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"s1","projects":["shop"],"endsAt":"2026-07-10T13:00:00Z","active":true}`)
+	}))
+	defer server.Close()
+
+	var code int
+	out := captureStdout(t, func() {
+		code = execute(context.Background(), "test", []string{
+			"alerts", "silences", "create", "--project", "shop", "--rule", "r1,r2",
+			"--for", "2h", "--comment", "deploy", "--url", server.URL,
+		})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	if got := fmt.Sprint(body); got != "map[comment:deploy durationSeconds:7200 projects:[shop] rules:[r1 r2]]" {
+		t.Errorf("body = %s", got)
+	}
+	if !strings.Contains(out, `created silence "s1" (projects=shop) until 2026-07-10T13:00:00Z`) {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestAlertSilenceUsageErrorsBeforeHTTP(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("server must not be called on a usage error: %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	cases := [][]string{
+		{"alerts", "silences", "create", "--project", "shop", "--url", server.URL},                                          // no end
+		{"alerts", "silences", "create", "--for", "1h", "--until", "2026-07-10T13:00:00Z", "--url", server.URL},             // both ends
+		{"alerts", "silences", "create", "--until", "tomorrow", "--url", server.URL},                                        // bad time
+		{"alerts", "silences", "create", "--for", "0", "--url", server.URL},                                                 // zero duration
+		{"alerts", "maintenance", "add", "--name", "nightly", "--schedule", "0 3 * *", "--for", "30m", "--url", server.URL}, // 4 fields
+		{"alerts", "maintenance", "add", "--name", "nightly", "--schedule", "0 3 * * *", "--url", server.URL},               // no duration
+		{"alerts", "maintenance", "add", "--schedule", "0 3 * * *", "--for", "30m", "--url", server.URL},                    // no name
+	}
+	for _, args := range cases {
+		var code int
+		captureStdout(t, func() {
+			captureStderr(t, func() {
+				code = execute(context.Background(), "test", args)
+			})
+		})
+		if code != 2 {
+			t.Errorf("args %v: exit code = %d, want 2 (usage error)", args, code)
+		}
+	}
+}
+
+func TestAlertSilencesAndMaintenanceTables(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/alerts/silences":
+			fmt.Fprint(w, `{"silences":[
+				{"id":"s1","projects":["shop"],"rules":["r1"],"startsAt":"2026-07-10T12:00:00Z","endsAt":"2999-01-01T00:00:00Z","comment":"deploy","createdBy":"admin","active":true},
+				{"id":"s2","hosts":["prod"],"startsAt":"2020-01-01T00:00:00Z","endsAt":"2020-01-01T01:00:00Z"}]}`)
+		case "/api/v1/alerts/maintenance":
+			fmt.Fprint(w, `{"windows":[{"id":"m1","name":"nightly","enabled":true,"schedule":"0 3 * * 1-5","durationSeconds":1800,"timezone":"Europe/Berlin","nextStart":"2026-07-13T01:00:00Z"}]}`)
+		}
+	}))
+	defer server.Close()
+
+	out := captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"alerts", "silences", "--url", server.URL}); code != 0 {
+			t.Errorf("silences: exit code = %d, want 0", code)
+		}
+	})
+	for _, want := range []string{"projects=shop rules=r1", "active", "admin", "deploy", "hosts=prod", "expired"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("silences table missing %q:\n%s", want, out)
+		}
+	}
+
+	out = captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"alerts", "maintenance", "--url", server.URL}); code != 0 {
+			t.Errorf("maintenance: exit code = %d, want 0", code)
+		}
+	})
+	for _, want := range []string{"nightly", "0 3 * * 1-5", "Europe/Berlin", "30m0s", "all", "next 2026-07-13T01:00:00Z"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("maintenance table missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestIncidentDeliveryNamesSilence(t *testing.T) {
+	al := alertInfo{Delivery: alertDelivery{Status: "suppressed-by-silence"}, SuppressedBy: "maintenance window nightly"}
+	if got := incidentDelivery(al); got != "suppressed-by-silence (maintenance window nightly)" {
+		t.Errorf("incidentDelivery = %q", got)
+	}
+	// A later notified repeat keeps SuppressedBy but shows its own delivery.
+	al.Delivery = alertDelivery{Status: "ok", HTTPStatus: 200}
+	if got := incidentDelivery(al); got != "ok (HTTP 200)" {
+		t.Errorf("incidentDelivery = %q", got)
+	}
+}
@@ -1,11 +1,13 @@
 package cli
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -30,9 +32,27 @@ type alertRule struct {
 	Events          []string `json:"events,omitempty"`
 	MinLevel        string   `json:"minLevel,omitempty"`
 	Pattern         string   `json:"pattern,omitempty"`
+	Mode            string   `json:"mode,omitempty"`
+	Metric          string   `json:"metric,omitempty"`
+	Operator        string   `json:"operator,omitempty"`
+	Value           float64  `json:"value,omitempty"`
+	Aggregate       string   `json:"aggregate,omitempty"`
 	Threshold       int      `json:"threshold,omitempty"`
 	WindowSeconds   int      `json:"windowSeconds,omitempty"`
 	CooldownSeconds int      `json:"cooldownSeconds,omitempty"`
+
+	Severity string       `json:"severity,omitempty"`
+	Channels []string     `json:"channels,omitempty"`
+	Routes   []alertRoute `json:"routes,omitempty"`
+}
+
+// alertRoute sends a rule's alerts for matching containers to specific
+// channels.
+type alertRoute struct {
+	Hosts      []string `json:"hosts,omitempty"`
+	Containers []string `json:"containers,omitempty"`
+	Projects   []string `json:"projects,omitempty"`
+	Channels   []string `json:"channels"`
 }
 
 type alertDelivery struct {
@@ -41,6 +61,16 @@ type alertDelivery struct {
 	Error      string `json:"error"`
 }
 
+// channelTestResult is a channel test's delivery outcome and the notification
+// as the channel's templates rendered it.
+type channelTestResult struct {
+	alertDelivery
+	Preview *struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	} `json:"preview"`
+}
+
 type alertInfo struct {
 	ID            string        `json:"id"`
 	RuleID        string        `json:"ruleId"`
@@ -55,6 +85,24 @@ type alertInfo struct {
 	Suppressed    int           `json:"suppressed"`
 	FiredAt       time.Time     `json:"firedAt"`
 	Delivery      alertDelivery `json:"delivery"`
+	SuppressedBy  string        `json:"suppressedBy"`
+	Severity      string        `json:"severity"`
+	Routing       *struct {
+		Channels []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"channels"`
+		Reason string `json:"reason"`
+	} `json:"routing"`
+
+	State           string `json:"state"`
+	Repeats         int    `json:"repeats"`
+	ResolveReason   string `json:"resolveReason"`
+	DurationSeconds int64  `json:"durationSeconds"`
+	AcknowledgedBy  string `json:"acknowledgedBy"`
+	AcknowledgedAt  string `json:"acknowledgedAt"`
+	Silenced        bool   `json:"silenced"`
 }
 
 // alertChannel doubles as the create request body: omitempty keeps fields the
@@ -67,17 +115,42 @@ type alertChannel struct {
 	URL     string `json:"url,omitempty"`
 	Token   string `json:"token,omitempty"`
 	Target  string `json:"target,omitempty"`
+
+	Username string `json:"username,omitempty"`
+	From     string `json:"from,omitempty"`
+
+	TitleTemplate string `json:"titleTemplate,omitempty"`
+	BodyTemplate  string `json:"bodyTemplate,omitempty"`
+
+	// Health is reported by the server and never sent.
+	Health *channelHealth `json:"health,omitempty"`
+}
+
+// channelHealth is a channel's recent delivery record.
+type channelHealth struct {
+	LastSuccessAt       string `json:"lastSuccessAt"`
+	LastFailureAt       string `json:"lastFailureAt"`
+	LastError           string `json:"lastError"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	RetryAt             string `json:"retryAt"`
+	Pending             int    `json:"pending"`
+	DeadLettered        int    `json:"deadLettered"`
 }
 
 func newAlertsCmd(a *app) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "alerts",
-		Short: "Manage alert rules, notification channels, and fired-alert history",
+		Short: "Manage alert rules, notification channels, silences, and fired-alert history",
 	}
 	cmd.AddCommand(
 		newAlertRulesCmd(a),
 		newAlertHistoryCmd(a),
 		newAlertChannelsCmd(a),
+		newAlertAckCmd(a),
+		newAlertSilenceCmd(a),
+		newAlertSilencesCmd(a),
+		newAlertMaintenanceCmd(a),
+		newAlertDeliveryCmd(a),
 	)
 	return cmd
 }
@@ -121,11 +194,13 @@ func newAlertRulesCmd(a *app) *cobra.Command {
 					r.Name,
 					r.Type,
 					strconv.FormatBool(r.Enabled),
+					cmp.Or(r.Severity, "warning"),
 					ruleTargets(r),
 					ruleTrigger(r),
+					ruleChannels(r),
 				})
 			}
-			renderTable(os.Stdout, []string{"ID", "NAME", "TYPE", "ENABLED", "TARGETS", "TRIGGER"}, rows)
+			renderTable(os.Stdout, []string{"ID", "NAME", "TYPE", "ENABLED", "SEVERITY", "TARGETS", "TRIGGER", "CHANNELS"}, rows)
 			return nil
 		}),
 	}
@@ -156,15 +231,90 @@ func ruleTargets(r alertRule) string {
 	return strings.Join(parts, " ")
 }
 
+// ruleChannels summarizes where a rule's alerts go: its channel IDs, or "all"
+// enabled channels, after any routes ("2 routes, else c1,c2").
+func ruleChannels(r alertRule) string {
+	channels := "all"
+	if len(r.Channels) > 0 {
+		channels = strings.Join(r.Channels, ",")
+	}
+	switch len(r.Routes) {
+	case 0:
+		return channels
+	case 1:
+		return "1 route, else " + channels
+	default:
+		return fmt.Sprintf("%d routes, else %s", len(r.Routes), channels)
+	}
+}
+
+// parseRouteFlag parses a --route value, "MATCHERS -> CHANNELS": matchers are
+// comma-separated host=, container=, and project= pairs (none matches every
+// container) and channels are comma-separated channel IDs, e.g.
+// "project=staging -> c1,c2".
+func parseRouteFlag(value string) (alertRoute, error) {
+	var route alertRoute
+	matchers, channels, ok := strings.Cut(value, "->")
+	if !ok {
+		return route, fmt.Errorf("invalid --route %q (want MATCHERS -> CHANNELS, e.g. project=staging -> c1)", value)
+	}
+	for _, m := range strings.Split(matchers, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		key, v, _ := strings.Cut(m, "=")
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return route, fmt.Errorf("invalid --route matcher %q (want host=, container=, or project=)", m)
+		}
+		switch strings.TrimSpace(key) {
+		case "host":
+			route.Hosts = append(route.Hosts, v)
+		case "container":
+			route.Containers = append(route.Containers, v)
+		case "project":
+			route.Projects = append(route.Projects, v)
+		default:
+			return route, fmt.Errorf("invalid --route matcher %q (want host=, container=, or project=)", m)
+		}
+	}
+	for _, id := range strings.Split(channels, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			route.Channels = append(route.Channels, id)
+		}
+	}
+	if len(route.Channels) == 0 {
+		return route, fmt.Errorf("invalid --route %q: no channels after ->", value)
+	}
+	return route, nil
+}
+
 // ruleTrigger summarizes what fires a rule and how often it may deliver
-// ("die,oom 3x/120s cooldown 60s", "level>=ERROR cooldown 300s (default)").
+// ("die,oom 3x/120s cooldown 60s", "level>=ERROR cooldown 300s (default)",
+// "memory_percent>90 for 300s cooldown 300s (default)").
 func ruleTrigger(r alertRule) string {
 	var parts []string
-	if r.Type == "event" {
+	switch r.Type {
+	case "event":
 		if len(r.Events) > 0 {
 			parts = append(parts, strings.Join(r.Events, ","))
 		}
-	} else {
+	case "metric":
+		op := r.Operator
+		if op == "" {
+			op = ">"
+		}
+		value := strconv.FormatFloat(r.Value, 'g', -1, 64)
+		if r.Aggregate == "avg" {
+			parts = append(parts, fmt.Sprintf("%s avg%s%s over %ds", r.Metric, op, value, r.WindowSeconds))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s%s%s for %ds", r.Metric, op, value, r.WindowSeconds))
+		}
+	default:
+		if r.Mode == "absence" {
+			parts = append(parts, "absent")
+		}
 		if r.MinLevel != "" {
 			parts = append(parts, "level>="+r.MinLevel)
 		}
@@ -172,7 +322,11 @@ func ruleTrigger(r alertRule) string {
 			parts = append(parts, "pattern="+r.Pattern)
 		}
 	}
-	if r.Threshold > 0 {
+	switch {
+	case r.Mode == "absence":
+		// Fires on fewer than Threshold lines, so render it as a bound.
+		parts = append(parts, fmt.Sprintf("<%d/%ds", max(r.Threshold, 1), r.WindowSeconds))
+	case r.Threshold > 0 && r.Type != "metric":
 		parts = append(parts, fmt.Sprintf("%dx/%ds", r.Threshold, r.WindowSeconds))
 	}
 	if r.CooldownSeconds > 0 {
@@ -205,15 +359,17 @@ func parseSecondsFlag(flag, value string) (int, error) {
 
 func newAlertRuleCreateCmd(a *app) *cobra.Command {
 	var (
-		ruleType, name, minLevel, pattern, window, cooldown string
-		hosts, containers, projects, events                 []string
-		threshold                                           int
-		disabled                                            bool
-		req                                                 alertRule
+		ruleType, name, minLevel, pattern, window, cooldown, metric string
+		severity                                                    string
+		hosts, containers, projects, events, channels, routes       []string
+		threshold                                                   int
+		above, below                                                float64
+		disabled, avg, absent                                       bool
+		req                                                         alertRule
 	)
 
 	cmd := &cobra.Command{
-		Use:   "create --type <log|event> --name <name>",
+		Use:   "create --type <log|event|metric> --name <name>",
 		Short: "Create an alert rule",
 		Args:  cobra.NoArgs,
 		// Flag validation runs in PreRunE so bad combinations are usage
@@ -222,6 +378,13 @@ func newAlertRuleCreateCmd(a *app) *cobra.Command {
 			if name == "" {
 				return fmt.Errorf("--name is required")
 			}
+			metricFlags := metric != "" || cmd.Flags().Changed("above") || cmd.Flags().Changed("below") || avg
+			if ruleType != "metric" && metricFlags {
+				return fmt.Errorf("--metric, --above, --below, and --avg only apply to --type metric")
+			}
+			if absent && ruleType != "log" {
+				return fmt.Errorf("--absent only applies to --type log")
+			}
 			switch ruleType {
 			case "log":
 				if len(events) > 0 {
@@ -229,23 +392,39 @@ func newAlertRuleCreateCmd(a *app) *cobra.Command {
 				}
 			case "event":
 				if len(events) == 0 {
-					return fmt.Errorf("--events is required for --type event (die, oom, unhealthy)")
+					return fmt.Errorf("--events is required for --type event (die, oom, unhealthy, restart_loop)")
 				}
 				for _, e := range events {
-					if e != "die" && e != "oom" && e != "unhealthy" {
-						return fmt.Errorf("invalid event %q (must be die, oom, or unhealthy)", e)
+					if e != "die" && e != "oom" && e != "unhealthy" && e != "restart_loop" {
+						return fmt.Errorf("invalid event %q (must be die, oom, unhealthy, or restart_loop)", e)
 					}
 				}
+				if slices.Contains(events, "restart_loop") && len(events) > 1 {
+					return fmt.Errorf("restart_loop must be the only event in its rule")
+				}
 				if minLevel != "" {
 					return fmt.Errorf("--min-level only applies to --type log")
 				}
 				if pattern != "" {
 					return fmt.Errorf("--pattern only applies to --type log")
 				}
+			case "metric":
+				if metric != "cpu_percent" && metric != "memory_percent" {
+					return fmt.Errorf("--metric is required for --type metric (cpu_percent or memory_percent)")
+				}
+				if cmd.Flags().Changed("above") == cmd.Flags().Changed("below") {
+					return fmt.Errorf("--type metric takes exactly one of --above or --below")
+				}
+				if len(events) > 0 || minLevel != "" || pattern != "" {
+					return fmt.Errorf("--events, --min-level, and --pattern do not apply to --type metric")
+				}
+				if cmd.Flags().Changed("threshold") {
+					return fmt.Errorf("--threshold does not apply to --type metric; --window sets how long the condition must hold")
+				}
 			case "":
-				return fmt.Errorf("--type is required (log or event)")
+				return fmt.Errorf("--type is required (log, event, or metric)")
 			default:
-				return fmt.Errorf("invalid --type %q (must be log or event)", ruleType)
+				return fmt.Errorf("invalid --type %q (must be log, event, or metric)", ruleType)
 			}
 
 			req = alertRule{
@@ -258,6 +437,31 @@ func newAlertRuleCreateCmd(a *app) *cobra.Command {
 				Events:     events,
 				MinLevel:   minLevel,
 				Pattern:    pattern,
+				Severity:   severity,
+				Channels:   channels,
+			}
+			if severity != "" && severity != "info" && severity != "warning" && severity != "critical" {
+				return fmt.Errorf("invalid --severity %q (must be info, warning, or critical)", severity)
+			}
+			for _, value := range routes {
+				route, err := parseRouteFlag(value)
+				if err != nil {
+					return err
+				}
+				req.Routes = append(req.Routes, route)
+			}
+			if absent {
+				req.Mode = "absence"
+			}
+			if ruleType == "metric" {
+				req.Metric = metric
+				req.Operator, req.Value = ">", above
+				if cmd.Flags().Changed("below") {
+					req.Operator, req.Value = "<", below
+				}
+				if avg {
+					req.Aggregate = "avg"
+				}
 			}
 			if cmd.Flags().Changed("threshold") {
 				if threshold < 1 {
@@ -289,17 +493,25 @@ func newAlertRuleCreateCmd(a *app) *cobra.Command {
 		}),
 	}
 
-	cmd.Flags().StringVar(&ruleType, "type", "", "rule type: log or event")
+	cmd.Flags().StringVar(&ruleType, "type", "", "rule type: log, event, or metric")
 	cmd.Flags().StringVar(&name, "name", "", "rule name")
 	cmd.Flags().StringSliceVar(&hosts, "host", nil, "limit to these hosts (repeatable)")
 	cmd.Flags().StringSliceVar(&containers, "container", nil, "limit to these container names (repeatable)")
 	cmd.Flags().StringSliceVar(&projects, "project", nil, "limit to these compose projects (repeatable)")
-	cmd.Flags().StringSliceVar(&events, "events", nil, "events to match: die, oom, unhealthy (only with --type event)")
+	cmd.Flags().StringSliceVar(&events, "events", nil, "events to match: die, oom, unhealthy, or restart_loop alone (only with --type event)")
 	cmd.Flags().StringVar(&minLevel, "min-level", "", "minimum log level to match, e.g. ERROR (only with --type log)")
 	cmd.Flags().StringVar(&pattern, "pattern", "", "regex the log message must match (only with --type log)")
-	cmd.Flags().IntVar(&threshold, "threshold", 0, "fire only after this many matches within --window")
-	cmd.Flags().StringVar(&window, "window", "", "threshold window (e.g. 60s, 5m, or bare seconds)")
+	cmd.Flags().BoolVar(&absent, "absent", false, "fire when fewer than --threshold matching lines (default 1) arrive within --window (only with --type log)")
+	cmd.Flags().StringVar(&metric, "metric", "", "container metric to watch: cpu_percent or memory_percent (only with --type metric)")
+	cmd.Flags().Float64Var(&above, "above", 0, "fire when the metric stays above this value for --window (only with --type metric)")
+	cmd.Flags().Float64Var(&below, "below", 0, "fire when the metric stays below this value for --window (only with --type metric)")
+	cmd.Flags().BoolVar(&avg, "avg", false, "compare the metric's average over --window instead of every sample (only with --type metric)")
+	cmd.Flags().IntVar(&threshold, "threshold", 0, "fire only after this many matches within --window (restart_loop: restarts, default 3)")
+	cmd.Flags().StringVar(&window, "window", "", "threshold window, how long a metric condition must hold, or how long an --absent rule waits for lines (e.g. 60s, 5m, or bare seconds)")
 	cmd.Flags().StringVar(&cooldown, "cooldown", "", "minimum time between deliveries (e.g. 5m); 0 or omitted uses the server default of 300s")
+	cmd.Flags().StringVar(&severity, "severity", "", "info, warning (default), or critical; sets the notification priority")
+	cmd.Flags().StringSliceVar(&channels, "channel", nil, "send alerts only to these channel IDs (repeatable; default: every enabled channel)")
+	cmd.Flags().StringArrayVar(&routes, "route", nil, "send alerts for matching containers to other channels, e.g. 'project=staging -> c1' (repeatable; the first match wins)")
 	cmd.Flags().BoolVar(&disabled, "disabled", false, "create the rule disabled")
 	return cmd
 }
@@ -361,19 +573,60 @@ func newAlertRuleToggleCmd(a *app, verb, short string, enabled bool) *cobra.Comm
 	}
 }
 
+// alertDeliveryFilters are the values --delivery takes.
+var alertDeliveryFilters = []string{"ok", "failed", "retrying", "suppressed-by-silence", "none"}
+
 func newAlertHistoryCmd(a *app) *cobra.Command {
-	var limit int
+	var (
+		limit                          int
+		state, delivery                string
+		rule, host, container, project string
+		since, until, cursor           string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "history",
-		Short: "List recently fired alerts, newest first",
-		Args:  cobra.NoArgs,
+		Short: "List alert incidents, newest first",
+		Long: `List alert incidents, newest first. Every filter is optional and they
+combine; --since and --until bound when an incident fired. A full page ends
+with a cursor on stderr that fetches the next, older one.`,
+		Example: `  logdeck alerts history --state firing
+  logdeck alerts history --rule <rule-id> --since 7d
+  logdeck alerts history --project shop --delivery failed`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if state != "" && state != "firing" && state != "resolved" {
+				return fmt.Errorf("invalid --state %q (must be firing or resolved)", state)
+			}
+			if delivery != "" && !slices.Contains(alertDeliveryFilters, delivery) {
+				return fmt.Errorf("invalid --delivery %q (must be one of %s)", delivery, strings.Join(alertDeliveryFilters, ", "))
+			}
+			for _, value := range []string{since, until} {
+				if _, err := parseTimeArg(value, time.Now()); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
 		RunE: a.run(func(cmd *cobra.Command, args []string) error {
 			var resp struct {
-				Alerts json.RawMessage `json:"alerts"`
-				Count  int             `json:"count"`
+				Alerts     json.RawMessage `json:"alerts"`
+				Count      int             `json:"count"`
+				NextCursor string          `json:"nextCursor,omitempty"`
 			}
 			query := url.Values{"limit": {strconv.Itoa(limit)}}
+			now := time.Now()
+			sinceArg, _ := parseTimeArg(since, now)
+			untilArg, _ := parseTimeArg(until, now)
+			for key, value := range map[string]string{
+				"state": state, "delivery": delivery, "rule": rule, "host": host,
+				"container": container, "project": project, "since": sinceArg,
+				"until": untilArg, "cursor": cursor,
+			} {
+				if value != "" {
+					query.Set(key, value)
+				}
+			}
 			if err := a.client.get(cmd.Context(), "/alerts/history", query, &resp); err != nil {
 				return err
 			}
@@ -388,7 +641,11 @@ func newAlertHistoryCmd(a *app) *cobra.Command {
 				if alerts == nil {
 					alerts = []any{}
 				}
-				return a.printJSON(map[string]any{"alerts": alerts, "count": resp.Count})
+				out := map[string]any{"alerts": alerts, "count": resp.Count}
+				if resp.NextCursor != "" {
+					out["nextCursor"] = resp.NextCursor
+				}
+				return a.printJSON(out)
 			}
 
 			var alerts []alertInfo
@@ -398,28 +655,202 @@ func newAlertHistoryCmd(a *app) *cobra.Command {
 				}
 			}
 			sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].FiredAt.After(alerts[j].FiredAt) })
-			now := time.Now()
 			rows := make([][]string, 0, len(alerts))
 			for _, al := range alerts {
 				rows = append(rows, []string{
+					al.ID,
 					humanAge(al.FiredAt, now),
+					incidentState(al),
+					incidentDuration(al, now),
 					al.RuleName,
 					al.ContainerName + "@" + al.Host,
 					al.Reason,
 					strconv.Itoa(al.Suppressed),
-					deliverySummary(al.Delivery),
+					routingSummary(al),
+					incidentDelivery(al),
 				})
 			}
-			renderTable(os.Stdout, []string{"TIME", "RULE", "CONTAINER@HOST", "REASON", "SUPPRESSED", "DELIVERY"}, rows)
+			renderTable(os.Stdout, []string{"ID", "TIME", "STATE", "DURATION", "RULE", "CONTAINER@HOST", "REASON", "SUPPRESSED", "CHANNELS", "DELIVERY"}, rows)
+			if resp.NextCursor != "" {
+				fmt.Fprintf(os.Stderr, "older incidents: --cursor %s\n", resp.NextCursor)
+			}
 			return nil
 		}),
 	}
 
-	cmd.Flags().IntVar(&limit, "limit", 50, "maximum number of alerts to return")
+	cmd.Flags().IntVar(&limit, "limit", 50, "maximum number of alerts per page (max 500)")
+	cmd.Flags().StringVar(&state, "state", "", "only list firing or resolved incidents")
+	cmd.Flags().StringVar(&rule, "rule", "", "only incidents of this rule ID")
+	cmd.Flags().StringVar(&host, "host", "", "only incidents on this host")
+	cmd.Flags().StringVar(&container, "container", "", "only incidents of this container name")
+	cmd.Flags().StringVar(&project, "project", "", "only incidents of this compose project")
+	cmd.Flags().StringVar(&delivery, "delivery", "", "only incidents whose delivery was ok, failed, retrying, suppressed-by-silence, or none")
+	cmd.Flags().StringVar(&since, "since", "", "only incidents fired after this time (RFC3339 or relative: 30m, 2h, 7d)")
+	cmd.Flags().StringVar(&until, "until", "", "only incidents fired before this time (RFC3339 or relative: 30m, 2h, 7d)")
+	cmd.Flags().StringVar(&cursor, "cursor", "", "continue from a previous page's cursor (older incidents)")
 	cmd.AddCommand(newAlertHistoryClearCmd(a))
+	cmd.AddCommand(newAlertHistoryRetentionCmd(a))
+	return cmd
+}
+
+// historyRetention mirrors the server's alert history bounds.
+type historyRetention struct {
+	RetentionDays int `json:"retentionDays"`
+	MaxEntries    int `json:"maxEntries"`
+}
+
+func newAlertHistoryRetentionCmd(a *app) *cobra.Command {
+	var days, maxEntries int
+
+	cmd := &cobra.Command{
+		Use:   "retention",
+		Short: "Show or change how long resolved incidents are kept",
+		Long: `Show how long resolved incidents are kept, or change it with --days and
+--max-entries. Resolved incidents older than --days, or beyond the newest
+--max-entries, are deleted; firing incidents are always kept. 0 restores a
+bound's default.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if days < 0 || maxEntries < 0 {
+				return fmt.Errorf("--days and --max-entries must not be negative")
+			}
+			return nil
+		},
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var current historyRetention
+			if err := a.client.get(cmd.Context(), "/alerts/history/retention", nil, &current); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("days") || cmd.Flags().Changed("max-entries") {
+				if cmd.Flags().Changed("days") {
+					current.RetentionDays = days
+				}
+				if cmd.Flags().Changed("max-entries") {
+					current.MaxEntries = maxEntries
+				}
+				if err := a.client.put(cmd.Context(), "/alerts/history/retention", nil, current, &current); err != nil {
+					return err
+				}
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(current)
+			}
+			fmt.Printf("resolved incidents are kept for %d days, at most %d in total\n", current.RetentionDays, current.MaxEntries)
+			return nil
+		}),
+	}
+
+	cmd.Flags().IntVar(&days, "days", 0, "delete resolved incidents older than this many days (0: default of 90)")
+	cmd.Flags().IntVar(&maxEntries, "max-entries", 0, "keep at most this many incidents (0: default of 10000)")
+	return cmd
+}
+
+func newAlertDeliveryCmd(a *app) *cobra.Command {
+	var (
+		deadline string
+		seconds  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delivery",
+		Short: "Show or change how long failed notifications are retried",
+		Long: `Show how long LogDeck keeps retrying a notification its channel rejects,
+or change it with --deadline. A notification still undelivered after the
+deadline is given up on and its incident's delivery recorded as failed.
+0 restores the default of 1h.`,
+		Example: `  logdeck alerts delivery
+  logdeck alerts delivery --deadline 6h`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			seconds, err = parseSecondsFlag("deadline", deadline)
+			return err
+		},
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var current struct {
+				DeadlineSeconds int `json:"deadlineSeconds"`
+			}
+			if err := a.client.get(cmd.Context(), "/alerts/delivery", nil, &current); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("deadline") {
+				current.DeadlineSeconds = seconds
+				if err := a.client.put(cmd.Context(), "/alerts/delivery", nil, current, &current); err != nil {
+					return err
+				}
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(current)
+			}
+			fmt.Printf("failed notifications are retried for %s\n", time.Duration(current.DeadlineSeconds)*time.Second)
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringVar(&deadline, "deadline", "", "give up on a notification this long after it was queued (e.g. 30m, 6h, 1d; 0: default of 1h)")
 	return cmd
 }
 
+// incidentState renders an incident's state with the actions taken on it,
+// e.g. "firing (acked, silenced)".
+func incidentState(al alertInfo) string {
+	state := al.State
+	if state == "" {
+		state = "resolved"
+	}
+	var marks []string
+	if al.AcknowledgedAt != "" {
+		marks = append(marks, "acked")
+	}
+	if al.Silenced && al.State == "firing" {
+		marks = append(marks, "silenced")
+	}
+	if len(marks) > 0 {
+		state += " (" + strings.Join(marks, ", ") + ")"
+	}
+	return state
+}
+
+// incidentDuration renders how long an incident lasted, or has lasted so far
+// while it is still firing.
+func incidentDuration(al alertInfo, now time.Time) string {
+	d := time.Duration(al.DurationSeconds) * time.Second
+	if al.State == "firing" && !al.FiredAt.IsZero() {
+		d = now.Sub(al.FiredAt).Truncate(time.Second)
+	}
+	if d <= 0 {
+		return "-"
+	}
+	return d.String()
+}
+
+// routingSummary renders the channels an incident was routed to and why, e.g.
+// "Pager, ntfy via route 1 (projects: shop)".
+func routingSummary(al alertInfo) string {
+	if al.Routing == nil {
+		return "-"
+	}
+	names := make([]string, 0, len(al.Routing.Channels))
+	for _, ch := range al.Routing.Channels {
+		names = append(names, cmp.Or(ch.Name, ch.Type))
+	}
+	if len(names) == 0 {
+		return "none via " + al.Routing.Reason
+	}
+	return strings.Join(names, ", ") + " via " + al.Routing.Reason
+}
+
+// incidentDelivery renders an incident's delivery, naming the silence or
+// maintenance window that suppressed it.
+func incidentDelivery(al alertInfo) string {
+	if al.SuppressedBy != "" && al.Delivery.Status == "suppressed-by-silence" {
+		return al.Delivery.Status + " (" + al.SuppressedBy + ")"
+	}
+	return deliverySummary(al.Delivery)
+}
+
 // deliverySummary renders a delivery result on one line ("ok (HTTP 200)",
 // "failed (HTTP 500): timeout").
 func deliverySummary(d alertDelivery) string {
@@ -455,6 +886,64 @@ func newAlertHistoryClearCmd(a *app) *cobra.Command {
 	}
 }
 
+func newAlertAckCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ack <id>",
+		Short: "Acknowledge a firing incident, stopping its repeat notifications",
+		Args:  cobra.ExactArgs(1),
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var alert map[string]any
+			if err := a.client.post(cmd.Context(), "/alerts/history/"+args[0]+"/ack", nil, nil, &alert); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(alert)
+			}
+			fmt.Printf("acknowledged alert %q\n", args[0])
+			return nil
+		}),
+	}
+}
+
+func newAlertSilenceCmd(a *app) *cobra.Command {
+	var (
+		duration string
+		seconds  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "silence <id>",
+		Short: "Silence a firing incident's notifications, resolution included",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			seconds, err = parseSecondsFlag("for", duration)
+			return err
+		},
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var alert map[string]any
+			body := map[string]int{"durationSeconds": seconds}
+			if err := a.client.post(cmd.Context(), "/alerts/history/"+args[0]+"/silence", nil, body, &alert); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(alert)
+			}
+			if seconds == 0 {
+				fmt.Printf("silenced alert %q until it resolves\n", args[0])
+			} else {
+				fmt.Printf("silenced alert %q for %s\n", args[0], time.Duration(seconds)*time.Second)
+			}
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringVar(&duration, "for", "", "how long to silence (e.g. 30m, 2h, 1d); omitted silences until the incident resolves")
+	return cmd
+}
+
 func newAlertChannelsCmd(a *app) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "channels",
@@ -470,11 +959,26 @@ func newAlertChannelsCmd(a *app) *cobra.Command {
 }
 
 func channelDest(c alertChannel) string {
-	if c.Type == "telegram" {
+	switch c.Type {
+	case "telegram":
 		if c.Target == "" {
 			return "-"
 		}
 		return "chat " + c.Target
+	case "smtp":
+		if c.Target == "" {
+			return "-"
+		}
+		return "mail " + c.Target
+	case "matrix":
+		if c.Target == "" {
+			return "-"
+		}
+		return "room " + c.Target
+	case "pagerduty":
+		if c.URL == "" {
+			return "events API"
+		}
 	}
 	if c.URL == "" {
 		return "-"
@@ -482,6 +986,28 @@ func channelDest(c alertChannel) string {
 	return c.URL
 }
 
+// healthSummary renders a channel's delivery health on one line, e.g.
+// "failing x3, retry in 40s, 2 queued".
+func healthSummary(h *channelHealth, now time.Time) string {
+	if h == nil {
+		return "-"
+	}
+	parts := []string{"ok"}
+	if h.ConsecutiveFailures > 0 {
+		parts[0] = fmt.Sprintf("failing x%d", h.ConsecutiveFailures)
+	}
+	if retry, err := time.Parse(time.RFC3339, h.RetryAt); err == nil && retry.After(now) {
+		parts = append(parts, "retry in "+retry.Sub(now).Round(time.Second).String())
+	}
+	if h.Pending > 0 {
+		parts = append(parts, fmt.Sprintf("%d queued", h.Pending))
+	}
+	if h.DeadLettered > 0 {
+		parts = append(parts, fmt.Sprintf("%d dead-lettered", h.DeadLettered))
+	}
+	return strings.Join(parts, ", ")
+}
+
 func newAlertChannelsListCmd(a *app) *cobra.Command {
 	return &cobra.Command{
 		Use:   "list",
@@ -514,6 +1040,7 @@ func newAlertChannelsListCmd(a *app) *cobra.Command {
 					return err
 				}
 			}
+			now := time.Now()
 			rows := make([][]string, 0, len(channels))
 			for _, c := range channels {
 				rows = append(rows, []string{
@@ -522,9 +1049,10 @@ func newAlertChannelsListCmd(a *app) *cobra.Command {
 					c.Name,
 					strconv.FormatBool(c.Enabled),
 					channelDest(c),
+					healthSummary(c.Health, now),
 				})
 			}
-			renderTable(os.Stdout, []string{"ID", "TYPE", "NAME", "ENABLED", "DESTINATION"}, rows)
+			renderTable(os.Stdout, []string{"ID", "TYPE", "NAME", "ENABLED", "DESTINATION", "HEALTH"}, rows)
 			return nil
 		}),
 	}
@@ -533,19 +1061,21 @@ func newAlertChannelsListCmd(a *app) *cobra.Command {
 func newAlertChannelAddCmd(a *app) *cobra.Command {
 	var (
 		channelType, name, channelURL, token, target string
+		username, from                               string
+		titleTemplate, bodyTemplate                  string
 		disabled                                     bool
 		req                                          alertChannel
 	)
 
 	cmd := &cobra.Command{
-		Use:   "add --type <webhook|ntfy|gotify|telegram>",
+		Use:   "add --type <webhook|ntfy|gotify|telegram|smtp|pagerduty|matrix|teams>",
 		Short: "Add a notification channel",
 		Args:  cobra.NoArgs,
 		// Flag validation runs in PreRunE so bad combinations are usage errors
 		// (exit 2) and never reach the server.
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			switch channelType {
-			case "webhook", "ntfy":
+			case "webhook", "ntfy", "teams":
 				if channelURL == "" {
 					return fmt.Errorf("--endpoint is required for --type %s", channelType)
 				}
@@ -563,10 +1093,43 @@ func newAlertChannelAddCmd(a *app) *cobra.Command {
 				if target == "" {
 					return fmt.Errorf("--target is required for --type telegram (the chat id)")
 				}
+			case "smtp":
+				if channelURL == "" {
+					return fmt.Errorf("--endpoint is required for --type smtp (smtp://host:port)")
+				}
+				if from == "" {
+					return fmt.Errorf("--from is required for --type smtp")
+				}
+				if target == "" {
+					return fmt.Errorf("--target is required for --type smtp (the recipients, comma-separated)")
+				}
+				if username != "" && token == "" {
+					return fmt.Errorf("--secret is required with --username (the smtp password)")
+				}
+			case "pagerduty":
+				if token == "" {
+					return fmt.Errorf("--secret is required for --type pagerduty (the integration routing key)")
+				}
+			case "matrix":
+				if channelURL == "" {
+					return fmt.Errorf("--endpoint is required for --type matrix (the homeserver base URL)")
+				}
+				if token == "" {
+					return fmt.Errorf("--secret is required for --type matrix (the access token)")
+				}
+				if target == "" {
+					return fmt.Errorf("--target is required for --type matrix (the room ID)")
+				}
 			case "":
-				return fmt.Errorf("--type is required (webhook, ntfy, gotify, or telegram)")
+				return fmt.Errorf("--type is required (webhook, ntfy, gotify, telegram, smtp, pagerduty, matrix, or teams)")
 			default:
-				return fmt.Errorf("invalid --type %q (must be webhook, ntfy, gotify, or telegram)", channelType)
+				return fmt.Errorf("invalid --type %q (must be webhook, ntfy, gotify, telegram, smtp, pagerduty, matrix, or teams)", channelType)
+			}
+			if (username != "" || from != "") && channelType != "smtp" {
+				return fmt.Errorf("--username and --from only apply to smtp channels")
+			}
+			if titleTemplate != "" && !slices.Contains([]string{"ntfy", "gotify", "smtp", "teams"}, channelType) {
+				return fmt.Errorf("--title-template only applies to ntfy, gotify, smtp, and teams channels")
 			}
 
 			req = alertChannel{
@@ -576,6 +1139,12 @@ func newAlertChannelAddCmd(a *app) *cobra.Command {
 				URL:     channelURL,
 				Token:   token,
 				Target:  target,
+
+				Username: username,
+				From:     from,
+
+				TitleTemplate: titleTemplate,
+				BodyTemplate:  bodyTemplate,
 			}
 			return nil
 		},
@@ -594,23 +1163,32 @@ func newAlertChannelAddCmd(a *app) *cobra.Command {
 		}),
 	}
 
-	cmd.Flags().StringVar(&channelType, "type", "", "channel type: webhook, ntfy, gotify, or telegram")
+	cmd.Flags().StringVar(&channelType, "type", "", "channel type: webhook, ntfy, gotify, telegram, smtp, pagerduty, matrix, or teams")
 	cmd.Flags().StringVar(&name, "name", "", "display name for the channel")
-	cmd.Flags().StringVar(&channelURL, "endpoint", "", "webhook/ntfy: full destination URL; gotify: server base URL")
-	cmd.Flags().StringVar(&token, "secret", "", "gotify app token or telegram bot token")
-	cmd.Flags().StringVar(&target, "target", "", "telegram chat id")
+	cmd.Flags().StringVar(&channelURL, "endpoint", "", "webhook/ntfy/teams: full destination URL; gotify/matrix: server base URL; smtp: smtp://host:port; pagerduty: optional Events API URL")
+	cmd.Flags().StringVar(&token, "secret", "", "gotify app token, telegram bot token, smtp password, pagerduty routing key, or matrix access token")
+	cmd.Flags().StringVar(&target, "target", "", "telegram chat id, matrix room ID, or smtp recipients (comma-separated)")
+	cmd.Flags().StringVar(&username, "username", "", "smtp: login user name")
+	cmd.Flags().StringVar(&from, "from", "", "smtp: sender address")
+	cmd.Flags().StringVar(&titleTemplate, "title-template", "", "ntfy/gotify/smtp/teams: Go template for the notification title (the email subject)")
+	cmd.Flags().StringVar(&bodyTemplate, "body-template", "", "Go template for the notification text (webhook: the whole JSON body)")
 	cmd.Flags().BoolVar(&disabled, "disabled", false, "add the channel disabled")
 	return cmd
 }
 
 func newAlertChannelTestCmd(a *app) *cobra.Command {
-	return &cobra.Command{
+	var dryRun bool
+	cmd := &cobra.Command{
 		Use:   "test <id>",
 		Short: "Send a test alert to a channel",
 		Args:  cobra.ExactArgs(1),
 		RunE: a.run(func(cmd *cobra.Command, args []string) error {
-			var result alertDelivery
-			if err := a.client.post(cmd.Context(), "/alerts/channels/"+args[0]+"/test", nil, nil, &result); err != nil {
+			var query url.Values
+			if dryRun {
+				query = url.Values{"dryRun": {"true"}}
+			}
+			var result channelTestResult
+			if err := a.client.post(cmd.Context(), "/alerts/channels/"+args[0]+"/test", query, nil, &result); err != nil {
 				return err
 			}
 
@@ -619,9 +1197,15 @@ func newAlertChannelTestCmd(a *app) *cobra.Command {
 					return err
 				}
 			} else {
-				fmt.Println("delivery " + deliverySummary(result))
+				if p := result.Preview; p != nil {
+					if p.Title != "" {
+						fmt.Println("title: " + p.Title)
+					}
+					fmt.Println(p.Body)
+				}
+				fmt.Println("delivery " + deliverySummary(result.alertDelivery))
 			}
-			if result.Status != "ok" {
+			if result.Status != "ok" && result.Status != "skipped" {
 				msg := "channel test failed"
 				if result.Error != "" {
 					msg += ": " + result.Error
@@ -631,6 +1215,8 @@ func newAlertChannelTestCmd(a *app) *cobra.Command {
 			return nil
 		}),
 	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "render the test notification through the channel's templates without sending it")
+	return cmd
 }
 
 func newAlertChannelDeleteCmd(a *app) *cobra.Command {
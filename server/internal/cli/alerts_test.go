@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -126,6 +127,104 @@ func TestAlertRuleCreateEventPayload(t *testing.T) {
 	}
 }
 
+func TestAlertRuleCreateMetricPayload(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"r3"}`)
+	}))
+	defer server.Close()
+
+	captureStdout(t, func() {
+		code := execute(context.Background(), "test", []string{
+			"alerts", "rules", "create", "--type", "metric", "--name", "hot",
+			"--metric", "cpu_percent", "--above", "200", "--avg", "--window", "2m",
+			"--url", server.URL,
+		})
+		if code != 0 {
+			t.Fatalf("exit code = %d, want 0", code)
+		}
+	})
+
+	want := map[string]any{"metric": "cpu_percent", "operator": ">", "value": float64(200), "aggregate": "avg", "windowSeconds": float64(120)}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("body[%s] = %v, want %v", key, body[key], value)
+		}
+	}
+	if _, ok := body["threshold"]; ok {
+		t.Errorf("body should not contain threshold, got %v", body["threshold"])
+	}
+}
+
+func TestAlertRuleCreateAbsentPayload(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"r4"}`)
+	}))
+	defer server.Close()
+
+	captureStdout(t, func() {
+		code := execute(context.Background(), "test", []string{
+			"alerts", "rules", "create", "--type", "log", "--name", "cron", "--absent",
+			"--pattern", "job done", "--container", "cron", "--window", "1h",
+			"--url", server.URL,
+		})
+		if code != 0 {
+			t.Fatalf("exit code = %d, want 0", code)
+		}
+	})
+
+	want := map[string]any{"type": "log", "mode": "absence", "pattern": "job done", "windowSeconds": float64(3600)}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("body[%s] = %v, want %v", key, body[key], value)
+		}
+	}
+}
+
+func TestAlertRuleCreateRoutingPayload(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var body alertRule
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"r1"}`)
+	}))
+	defer server.Close()
+
+	code := execute(context.Background(), "test", []string{
+		"alerts", "rules", "create", "--type", "event", "--name", "oom",
+		"--events", "oom", "--severity", "critical", "--channel", "pager",
+		"--route", "project=staging, host=prod -> low,chat", "--route", "container=web->low",
+		"--url", server.URL,
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+
+	if body.Severity != "critical" || fmt.Sprint(body.Channels) != "[pager]" {
+		t.Errorf("severity/channels = %q/%v, want critical/[pager]", body.Severity, body.Channels)
+	}
+	if got := fmt.Sprintf("%+v", body.Routes); got != "[{Hosts:[prod] Containers:[] Projects:[staging] Channels:[low chat]} {Hosts:[] Containers:[web] Projects:[] Channels:[low]}]" {
+		t.Errorf("routes = %s", got)
+	}
+}
+
 func TestParseSecondsFlag(t *testing.T) {
 	tests := []struct {
 		value   string
@@ -170,6 +269,18 @@ func TestAlertRuleCreateUsageErrorsBeforeHTTP(t *testing.T) {
 		{"missing type", []string{"--name", "x"}},
 		{"invalid type", []string{"--type", "bogus", "--name", "x"}},
 		{"invalid window", []string{"--type", "log", "--name", "x", "--window", "abc"}},
+		{"metric flag on log", []string{"--type", "log", "--name", "x", "--min-level", "ERROR", "--above", "90"}},
+		{"missing metric", []string{"--type", "metric", "--name", "x", "--above", "90"}},
+		{"unknown metric", []string{"--type", "metric", "--name", "x", "--metric", "disk_percent", "--above", "90"}},
+		{"metric without bound", []string{"--type", "metric", "--name", "x", "--metric", "cpu_percent"}},
+		{"metric with both bounds", []string{"--type", "metric", "--name", "x", "--metric", "cpu_percent", "--above", "90", "--below", "5"}},
+		{"threshold on metric", []string{"--type", "metric", "--name", "x", "--metric", "cpu_percent", "--above", "90", "--threshold", "3"}},
+		{"restart_loop with die", []string{"--type", "event", "--name", "x", "--events", "die,restart_loop"}},
+		{"absent on event", []string{"--type", "event", "--name", "x", "--events", "die", "--absent"}},
+		{"invalid severity", []string{"--type", "log", "--name", "x", "--min-level", "ERROR", "--severity", "page"}},
+		{"route without arrow", []string{"--type", "log", "--name", "x", "--min-level", "ERROR", "--route", "project=staging"}},
+		{"route without channels", []string{"--type", "log", "--name", "x", "--min-level", "ERROR", "--route", "project=staging ->"}},
+		{"route unknown matcher", []string{"--type", "log", "--name", "x", "--min-level", "ERROR", "--route", "label=x -> c1"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -269,8 +380,8 @@ func TestAlertHistoryTable(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gotLimit = r.URL.Query().Get("limit")
 		fmt.Fprintf(w, `{"alerts":[
-			{"id":"a1","ruleName":"older-rule","host":"prod","containerName":"web","reason":"exited","suppressed":0,"firedAt":%q,"delivery":{"status":"ok","httpStatus":200,"error":""}},
-			{"id":"a2","ruleName":"newer-rule","host":"prod","containerName":"api","reason":"oom","suppressed":3,"firedAt":%q,"delivery":{"status":"failed","httpStatus":500,"error":"boom"}}
+			{"id":"a1","ruleName":"older-rule","host":"prod","containerName":"web","reason":"exited","suppressed":0,"firedAt":%q,"delivery":{"status":"ok","httpStatus":200,"error":""},"state":"resolved","durationSeconds":330},
+			{"id":"a2","ruleName":"newer-rule","host":"prod","containerName":"api","reason":"oom","suppressed":3,"firedAt":%q,"delivery":{"status":"failed","httpStatus":500,"error":"boom"},"state":"firing","acknowledgedAt":"2026-01-01T00:00:00Z","routing":{"channels":[{"id":"c2","name":"Pager","type":"pagerduty"},{"id":"c1","type":"ntfy"}],"reason":"route 1 (projects: shop)"}}
 		],"count":2}`, older, newer)
 	}))
 	defer server.Close()
@@ -292,7 +403,7 @@ func TestAlertHistoryTable(t *testing.T) {
 	if len(lines) != 3 {
 		t.Fatalf("expected header + 2 rows, got %d lines:\n%s", len(lines), stdout)
 	}
-	for _, column := range []string{"TIME", "RULE", "CONTAINER@HOST", "REASON", "SUPPRESSED", "DELIVERY"} {
+	for _, column := range []string{"ID", "TIME", "STATE", "DURATION", "RULE", "CONTAINER@HOST", "REASON", "SUPPRESSED", "CHANNELS", "DELIVERY"} {
 		if !strings.Contains(lines[0], column) {
 			t.Errorf("header missing %q: %q", column, lines[0])
 		}
@@ -303,9 +414,170 @@ func TestAlertHistoryTable(t *testing.T) {
 	if !strings.Contains(lines[1], "api@prod") || !strings.Contains(lines[1], "failed (HTTP 500): boom") {
 		t.Errorf("newest row missing container@host or delivery summary: %q", lines[1])
 	}
+	if !strings.Contains(lines[1], "Pager, ntfy via route 1 (projects: shop)") {
+		t.Errorf("newest row missing its routing: %q", lines[1])
+	}
+	if !strings.Contains(lines[1], "firing (acked)") || !strings.Contains(lines[1], "3m0s") {
+		t.Errorf("newest row should be firing and acknowledged for 3m so far: %q", lines[1])
+	}
 	if !strings.Contains(lines[2], "older-rule") || !strings.Contains(lines[2], "ok (HTTP 200)") {
 		t.Errorf("second row should be the older alert: %q", lines[2])
 	}
+	if !strings.Contains(lines[2], "resolved") || !strings.Contains(lines[2], "5m30s") {
+		t.Errorf("older row should be resolved after 5m30s: %q", lines[2])
+	}
+}
+
+func TestAlertHistoryStateFilter(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var gotState string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotState = r.URL.Query().Get("state")
+		fmt.Fprint(w, `{"alerts":[],"count":0}`)
+	}))
+	defer server.Close()
+
+	captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"alerts", "history", "--state", "firing", "--url", server.URL}); code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	})
+	if gotState != "firing" {
+		t.Errorf("state query = %q, want firing", gotState)
+	}
+
+	gotState = "unset"
+	if code := execute(context.Background(), "test", []string{"alerts", "history", "--state", "open", "--url", server.URL}); code != 2 {
+		t.Errorf("exit code = %d, want 2 (usage error)", code)
+	}
+	if gotState != "unset" {
+		t.Error("usage error should not reach the server")
+	}
+}
+
+func TestAlertHistoryFiltersAndCursor(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var got url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		fmt.Fprint(w, `{"alerts":[],"count":0,"nextCursor":"MTI"}`)
+	}))
+	defer server.Close()
+
+	var code int
+	stderr := captureStderr(t, func() {
+		captureStdout(t, func() {
+			code = execute(context.Background(), "test", []string{
+				"alerts", "history", "--rule", "r1", "--host", "prod", "--container", "web", "--project", "shop",
+				"--delivery", "failed", "--since", "2026-07-01T00:00:00Z", "--cursor", "MjA", "--url", server.URL,
+			})
+		})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	for key, want := range map[string]string{
+		"rule": "r1", "host": "prod", "container": "web", "project": "shop",
+		"delivery": "failed", "since": "2026-07-01T00:00:00Z", "cursor": "MjA",
+	} {
+		if got.Get(key) != want {
+			t.Errorf("%s = %q, want %q", key, got.Get(key), want)
+		}
+	}
+	if got.Has("until") || got.Has("state") {
+		t.Errorf("unset filters were sent: %v", got)
+	}
+	if !strings.Contains(stderr, "--cursor MTI") {
+		t.Errorf("stderr = %q, want the next page's cursor", stderr)
+	}
+
+	for _, args := range [][]string{{"--delivery", "sent"}, {"--since", "yesterday"}} {
+		got = nil
+		captureStderr(t, func() {
+			code = execute(context.Background(), "test", append([]string{"alerts", "history", "--url", server.URL}, args...))
+		})
+		if code != 2 || got != nil {
+			t.Errorf("%v: exit code = %d (server called: %v), want a usage error", args, code, got != nil)
+		}
+	}
+}
+
+func TestAlertHistoryRetention(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var put map[string]int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&put); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+			fmt.Fprintf(w, `{"retentionDays":%d,"maxEntries":%d}`, put["retentionDays"], put["maxEntries"])
+			return
+		}
+		fmt.Fprint(w, `{"retentionDays":90,"maxEntries":10000}`)
+	}))
+	defer server.Close()
+
+	out := captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"alerts", "history", "retention", "--url", server.URL}); code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	})
+	if put != nil || !strings.Contains(out, "kept for 90 days, at most 10000") {
+		t.Fatalf("show: put %v, output %q", put, out)
+	}
+
+	// Only the changed bound is replaced.
+	out = captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"alerts", "history", "retention", "--days", "30", "--url", server.URL}); code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	})
+	if put["retentionDays"] != 30 || put["maxEntries"] != 10000 || !strings.Contains(out, "kept for 30 days") {
+		t.Fatalf("update: put %v, output %q", put, out)
+	}
+}
+
+func TestAlertAckAndSilence(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotBody = r.Method+" "+r.URL.Path, string(body)
+		fmt.Fprint(w, `{"id":"a1","state":"firing"}`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		args               []string
+		wantPath, wantBody string
+	}{
+		{[]string{"alerts", "ack", "a1"}, "POST /api/v1/alerts/history/a1/ack", ""},
+		{[]string{"alerts", "silence", "a1"}, "POST /api/v1/alerts/history/a1/silence", `{"durationSeconds":0}`},
+		{[]string{"alerts", "silence", "a1", "--for", "2h"}, "POST /api/v1/alerts/history/a1/silence", `{"durationSeconds":7200}`},
+	}
+	for _, tt := range tests {
+		gotPath, gotBody = "", ""
+		captureStdout(t, func() {
+			if code := execute(context.Background(), "test", append(tt.args, "--url", server.URL)); code != 0 {
+				t.Errorf("%v: exit code = %d, want 0", tt.args, code)
+			}
+		})
+		if gotPath != tt.wantPath || strings.TrimSpace(gotBody) != tt.wantBody {
+			t.Errorf("%v: request = %s %q, want %s %q", tt.args, gotPath, gotBody, tt.wantPath, tt.wantBody)
+		}
+	}
+
+	gotPath = ""
+	if code := execute(context.Background(), "test", []string{"alerts", "silence", "a1", "--for", "soon", "--url", server.URL}); code != 2 {
+		t.Errorf("exit code = %d, want 2 (usage error)", code)
+	}
+	if gotPath != "" {
+		t.Error("usage error should not reach the server")
+	}
 }
 
 func TestAlertTestExitCodes(t *testing.T) {
@@ -344,13 +616,38 @@ func TestAlertTestExitCodes(t *testing.T) {
 	}
 }
 
+func TestAlertChannelTestDryRunPrintsPreview(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("dryRun") != "true" {
+			t.Errorf("query = %q, want dryRun=true", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"status":"skipped","preview":{"title":"PAGE web","body":"web: boom"}}`)
+	}))
+	defer server.Close()
+
+	var code int
+	stdout := captureStdout(t, func() {
+		code = execute(context.Background(), "test", []string{
+			"alerts", "channels", "test", "c1", "--dry-run", "--url", server.URL,
+		})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0 for a skipped dry run", code)
+	}
+	if want := "title: PAGE web\nweb: boom\ndelivery skipped\n"; stdout != want {
+		t.Fatalf("stdout = %q, want %q", stdout, want)
+	}
+}
+
 func TestAlertRulesTableCooldown(t *testing.T) {
 	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, `{"rules":[
 			{"id":"r1","name":"default-cd","enabled":true,"type":"event","events":["die"]},
-			{"id":"r2","name":"explicit-cd","enabled":true,"type":"log","minLevel":"ERROR","threshold":3,"windowSeconds":60,"cooldownSeconds":120}
+			{"id":"r2","name":"explicit-cd","enabled":true,"type":"log","minLevel":"ERROR","threshold":3,"windowSeconds":60,"cooldownSeconds":120,"severity":"critical","channels":["c1"],"routes":[{"projects":["staging"],"channels":["c2"]}]}
 		]}`)
 	}))
 	defer server.Close()
@@ -377,6 +674,31 @@ func TestAlertRulesTableCooldown(t *testing.T) {
 	if !strings.Contains(lines[2], "cooldown 120s") || strings.Contains(lines[2], "default") {
 		t.Errorf("explicit-cooldown row wrong: %q", lines[2])
 	}
+	if !strings.Contains(lines[1], "warning") || !strings.HasSuffix(lines[1], "all") {
+		t.Errorf("unrouted row should be warning to all channels: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "critical") || !strings.HasSuffix(lines[2], "1 route, else c1") {
+		t.Errorf("routed row wrong: %q", lines[2])
+	}
+}
+
+func TestRuleTriggerMetricAndAbsence(t *testing.T) {
+	tests := []struct {
+		rule alertRule
+		want string
+	}{
+		{alertRule{Type: "metric", Metric: "memory_percent", Operator: ">", Value: 90, Aggregate: "sustained", Threshold: 1, WindowSeconds: 300},
+			"memory_percent>90 for 300s cooldown 300s (default)"},
+		{alertRule{Type: "metric", Metric: "cpu_percent", Operator: "<", Value: 0.5, Aggregate: "avg", Threshold: 1, WindowSeconds: 120, CooldownSeconds: 60},
+			"cpu_percent avg<0.5 over 120s cooldown 60s"},
+		{alertRule{Type: "log", Mode: "absence", Pattern: "job done", Threshold: 1, WindowSeconds: 3600},
+			"absent pattern=job done <1/3600s cooldown 300s (default)"},
+	}
+	for _, tt := range tests {
+		if got := ruleTrigger(tt.rule); got != tt.want {
+			t.Errorf("ruleTrigger(%+v) = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
 }
 
 func TestAlertRulesJSONPassthrough(t *testing.T) {
@@ -420,7 +742,7 @@ func TestAlertChannelsListTable(t *testing.T) {
 			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
 		}
 		fmt.Fprint(w, `{"channels":[
-			{"id":"c1","type":"webhook","name":"Slack","enabled":true,"url":"https://example.com/hook"},
+			{"id":"c1","type":"webhook","name":"Slack","enabled":true,"url":"https://example.com/hook","health":{"consecutiveFailures":3,"pending":2,"deadLettered":0}},
 			{"id":"c2","type":"telegram","enabled":false,"token":"bot:secret","target":"-1001"}
 		]}`)
 	}))
@@ -443,12 +765,108 @@ func TestAlertChannelsListTable(t *testing.T) {
 	if !strings.Contains(lines[1], "webhook") || !strings.Contains(lines[1], "https://example.com/hook") {
 		t.Errorf("webhook row wrong: %q", lines[1])
 	}
+	if !strings.Contains(lines[1], "failing x3, 2 queued") {
+		t.Errorf("webhook row should summarize its health: %q", lines[1])
+	}
 	// A telegram channel's destination is summarized as its chat id.
 	if !strings.Contains(lines[2], "chat -1001") {
 		t.Errorf("telegram row should summarize the chat id: %q", lines[2])
 	}
 }
 
+func TestHealthSummary(t *testing.T) {
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		health *channelHealth
+		want   string
+	}{
+		{nil, "-"},
+		{&channelHealth{LastSuccessAt: "2026-07-10T11:00:00Z"}, "ok"},
+		{&channelHealth{ConsecutiveFailures: 2, RetryAt: "2026-07-10T12:00:40Z", Pending: 1}, "failing x2, retry in 40s, 1 queued"},
+		// A passed retry time is not shown.
+		{&channelHealth{ConsecutiveFailures: 1, RetryAt: "2026-07-10T11:59:00Z", DeadLettered: 4}, "failing x1, 4 dead-lettered"},
+	}
+	for _, tt := range tests {
+		if got := healthSummary(tt.health, now); got != tt.want {
+			t.Errorf("healthSummary(%+v) = %q, want %q", tt.health, got, tt.want)
+		}
+	}
+}
+
+func TestAlertDelivery(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var put map[string]int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/alerts/delivery" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&put); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+			fmt.Fprintf(w, `{"deadlineSeconds":%d}`, put["deadlineSeconds"])
+			return
+		}
+		fmt.Fprint(w, `{"deadlineSeconds":3600}`)
+	}))
+	defer server.Close()
+
+	out := captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"alerts", "delivery", "--url", server.URL}); code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	})
+	if put != nil || !strings.Contains(out, "retried for 1h0m0s") {
+		t.Fatalf("show: put %v, output %q", put, out)
+	}
+
+	out = captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"alerts", "delivery", "--deadline", "6h", "--url", server.URL}); code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	})
+	if put["deadlineSeconds"] != 6*3600 || !strings.Contains(out, "retried for 6h0m0s") {
+		t.Fatalf("update: put %v, output %q", put, out)
+	}
+}
+
+func TestAlertChannelAddSMTPPayload(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/alerts/channels" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"id":"c9","type":"smtp"}`)
+	}))
+	defer server.Close()
+
+	var code int
+	stdout := captureStdout(t, func() {
+		code = execute(context.Background(), "test", []string{
+			"alerts", "channels", "add", "--type", "smtp", "--endpoint", "smtp://mail.example.com:587",
+			"--username", "alerts", "--secret", "pw", "--from", "logdeck@example.com",
+			"--target", "ops@example.com,boss@example.com", "--url", server.URL,
+		})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	want := map[string]any{
+		"type": "smtp", "enabled": true, "url": "smtp://mail.example.com:587", "token": "pw",
+		"target": "ops@example.com,boss@example.com", "username": "alerts", "from": "logdeck@example.com",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("payload = %v, want %v", got, want)
+	}
+	if stdout != "added smtp channel (c9)\n" {
+		t.Fatalf("stdout = %q", stdout)
+	}
+}
+
 func TestAlertChannelAddUsageErrorsBeforeHTTP(t *testing.T) {
 	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
@@ -462,6 +880,11 @@ func TestAlertChannelAddUsageErrorsBeforeHTTP(t *testing.T) {
 		{"alerts", "channels", "add", "--type", "gotify", "--endpoint", "http://x", "--url", server.URL},   // missing secret
 		{"alerts", "channels", "add", "--type", "telegram", "--secret", "t", "--url", server.URL},    // missing target
 		{"alerts", "channels", "add", "--type", "pushover", "--endpoint", "http://x", "--url", server.URL}, // invalid type
+		{"alerts", "channels", "add", "--type", "webhook", "--endpoint", "http://x", "--title-template", "x", "--url", server.URL}, // title on webhook
+		{"alerts", "channels", "add", "--type", "smtp", "--endpoint", "smtp://mail.example.com", "--target", "a@example.com", "--url", server.URL}, // missing from
+		{"alerts", "channels", "add", "--type", "pagerduty", "--url", server.URL}, // missing routing key
+		{"alerts", "channels", "add", "--type", "matrix", "--endpoint", "http://x", "--secret", "t", "--url", server.URL}, // missing room
+		{"alerts", "channels", "add", "--type", "teams", "--endpoint", "http://x", "--from", "a@example.com", "--url", server.URL}, // from on teams
 	}
 
 	for _, args := range cases {
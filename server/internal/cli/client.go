@@ -14,6 +14,9 @@ import (
 
 const requestTimeout = 30 * time.Second
 
+// slowRequestTimeout bounds requests that pull images on the server.
+const slowRequestTimeout = 15 * time.Minute
+
 const authHint = "authenticate with an API token created in LogDeck Settings, via --token or LOGDECK_TOKEN"
 
 // client is a thin HTTP client for the LogDeck API (/api/v1).
@@ -58,7 +61,11 @@ func (c *client) newRequest(ctx context.Context, method, path string, query url.
 // do performs a one-shot request and decodes the JSON response into out
 // (which may be nil to discard the body).
 func (c *client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
-	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	return c.doTimeout(ctx, requestTimeout, method, path, query, body, out)
+}
+
+func (c *client) doTimeout(ctx context.Context, timeout time.Duration, method, path string, query url.Values, body, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	req, err := c.newRequest(ctx, method, path, query, body)
@@ -90,6 +97,12 @@ func (c *client) post(ctx context.Context, path string, query url.Values, body,
 	return c.do(ctx, http.MethodPost, path, query, body, out)
 }
 
+// postSlow is post for requests that legitimately outlast requestTimeout,
+// such as pulling an image.
+func (c *client) postSlow(ctx context.Context, path string, query url.Values, body, out any) error {
+	return c.doTimeout(ctx, slowRequestTimeout, http.MethodPost, path, query, body, out)
+}
+
 func (c *client) put(ctx context.Context, path string, query url.Values, body, out any) error {
 	return c.do(ctx, http.MethodPut, path, query, body, out)
 }
@@ -120,7 +133,17 @@ func (c *client) postRaw(ctx context.Context, path string, query url.Values) (in
 // stream opens a streaming GET request (no client-side timeout) and returns
 // the response body. The caller must close it.
 func (c *client) stream(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, path, query, nil)
+	return c.openStream(ctx, http.MethodGet, path, query)
+}
+
+// streamPost is stream for POST endpoints that report progress while they
+// work (compose pull/up/down).
+func (c *client) streamPost(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	return c.openStream(ctx, http.MethodPost, path, query)
+}
+
+func (c *client) openStream(ctx context.Context, method, path string, query url.Values) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, method, path, query, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +158,29 @@ func (c *client) stream(ctx context.Context, path string, query url.Values) (io.
 	return resp.Body, nil
 }
 
+// upload POSTs body as-is with the given content type and decodes the JSON
+// response into out. Like stream it has no client-side timeout, since the body
+// can be arbitrarily large.
+func (c *client) upload(ctx context.Context, path, contentType string, body io.Reader, out any) error {
+	req, err := c.newRequest(ctx, http.MethodPost, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(body)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach LogDeck server at %s: %v", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
 // responseError turns a non-2xx response into an error carrying the server's
 // message. 401 responses get a hint about API token authentication.
 func responseError(resp *http.Response) error {
@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -64,6 +65,31 @@ func TestClientSurfacesServerMessage(t *testing.T) {
 	}
 }
 
+func TestClientUploadSendsRawBody(t *testing.T) {
+	var gotAuth, gotType, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotType = r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		fmt.Fprint(w, `{"result":{"imported":3}}`)
+	}))
+	defer server.Close()
+
+	var out struct {
+		Result importResult `json:"result"`
+	}
+	err := newClient(server.URL, "secret123").upload(context.Background(), "/history/import", "application/zstd", strings.NewReader("archive bytes"), &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAuth != "Bearer secret123" || gotType != "application/zstd" || gotBody != "archive bytes" {
+		t.Errorf("server saw auth %q, type %q, body %q", gotAuth, gotType, gotBody)
+	}
+	if out.Result.Imported != 3 {
+		t.Errorf("imported = %d, want 3", out.Result.Imported)
+	}
+}
+
 func TestClientUnreachable(t *testing.T) {
 	err := newClient("http://127.0.0.1:1", "").get(context.Background(), "/healthz", nil, nil)
 	if err == nil {
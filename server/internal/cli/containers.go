@@ -44,7 +44,11 @@ func newContainersCmd(a *app) *cobra.Command {
 			warnHostErrors(resp.HostErrors)
 			rows := make([][]string, 0, len(filtered))
 			for _, c := range filtered {
-				rows = append(rows, []string{containerName(c), c.State, c.Image, c.Host, c.Status})
+				image := c.Image
+				if c.UpdateAvailable {
+					image += " (update available)"
+				}
+				rows = append(rows, []string{containerName(c), c.State, image, c.Host, c.Status})
 			}
 			renderTable(os.Stdout, []string{"NAME", "STATE", "IMAGE", "HOST", "UPTIME"}, rows)
 			return nil
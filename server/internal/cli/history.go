@@ -0,0 +1,479 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// storedContainer mirrors logstore.StoredContainer.
+type storedContainer struct {
+	Host           string    `json:"host"`
+	Name           string    `json:"name"`
+	ComposeProject string    `json:"composeProject,omitempty"`
+	Image          string    `json:"image,omitempty"`
+	StoredBytes    int64     `json:"storedBytes"`
+	OldestTs       time.Time `json:"oldestTs"`
+	NewestTs       time.Time `json:"newestTs"`
+	Removed        bool      `json:"removed"`
+	Excluded       bool      `json:"excluded"`
+	ExcludedReason string    `json:"excludedReason,omitempty"`
+	Filtered       bool      `json:"filtered"`
+	FilteredReason string    `json:"filteredReason,omitempty"`
+}
+
+// historyFlags select stored lines: one logical container, or a store-wide
+// query narrowed by names, project, and host.
+type historyFlags struct {
+	container  string
+	containers []string
+	project    string
+	host       string
+	search     string
+	regex      bool
+	filter     string
+	levels     string
+	since      string
+	until      string
+	limit      int
+	cursor     string
+}
+
+func (f *historyFlags) register(cmd *cobra.Command) {
+	f.registerSelection(cmd)
+	cmd.Flags().IntVar(&f.limit, "limit", 100, "max entries per page (max 1000)")
+	cmd.Flags().StringVar(&f.cursor, "cursor", "", "continue from a previous page's cursor (older lines)")
+}
+
+// registerSelection registers the flags every history read shares: which
+// containers, which window, and which filters.
+func (f *historyFlags) registerSelection(cmd *cobra.Command) {
+	f.registerScope(cmd)
+	cmd.Flags().StringVar(&f.search, "search", "", "substring to match (a regex with --regex)")
+	cmd.Flags().BoolVar(&f.regex, "regex", false, "treat --search as a regular expression")
+	cmd.Flags().StringVar(&f.filter, "filter", "", `filter by structured fields, e.g. 'status>=500 AND path~"/api"'`)
+	cmd.Flags().StringVar(&f.levels, "level", "", "comma-separated levels to keep (e.g. ERROR,WARN)")
+}
+
+// registerScope registers the flags that pick containers and a window, without
+// the entry filters.
+func (f *historyFlags) registerScope(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&f.containers, "containers", nil, "search only these container names (repeatable or comma-separated)")
+	cmd.Flags().StringVar(&f.project, "project", "", "search only containers of this compose project")
+	cmd.Flags().StringVar(&f.host, "host", "", "search only this host")
+	cmd.Flags().StringVar(&f.since, "since", "", "only logs after this time (RFC3339 or relative: 30s, 15m, 2h, 1d)")
+	cmd.Flags().StringVar(&f.until, "until", "", "only logs before this time (RFC3339 or relative: 30s, 15m, 2h, 1d)")
+}
+
+// query converts flags to /history/logs query params, resolving relative times.
+func (f *historyFlags) query(now time.Time) (url.Values, error) {
+	query := url.Values{}
+	if f.container != "" {
+		query.Set("container", f.container)
+	}
+	if names := compactList(f.containers); len(names) > 0 {
+		query.Set("containers", strings.Join(names, ","))
+	}
+	if f.project != "" {
+		query.Set("project", f.project)
+	}
+	if f.host != "" {
+		query.Set("host", f.host)
+	}
+	if f.search != "" {
+		query.Set("search", f.search)
+	}
+	if f.regex {
+		query.Set("regex", "true")
+	}
+	if f.filter != "" {
+		query.Set("filter", f.filter)
+	}
+	if f.levels != "" {
+		query.Set("levels", strings.ToUpper(f.levels))
+	}
+	since, err := parseTimeArg(f.since, now)
+	if err != nil {
+		return nil, err
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+	until, err := parseTimeArg(f.until, now)
+	if err != nil {
+		return nil, err
+	}
+	if until != "" {
+		query.Set("until", until)
+	}
+	if f.limit > 0 {
+		query.Set("limit", strconv.Itoa(f.limit))
+	}
+	if f.cursor != "" {
+		query.Set("cursor", f.cursor)
+	}
+	return query, nil
+}
+
+// statsFlags select stored lines like historyFlags and add the bucket width.
+type statsFlags struct {
+	historyFlags
+	interval string
+}
+
+// query converts flags to /history/stats query params.
+func (f *statsFlags) query(now time.Time) (url.Values, error) {
+	query, err := f.historyFlags.query(now)
+	if err != nil {
+		return nil, err
+	}
+	if f.interval != "" {
+		d, err := parseDuration(f.interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval %q: use a duration like 30s, 1m, 1h, 1d", f.interval)
+		}
+		query.Set("interval", d.String())
+	}
+	return query, nil
+}
+
+// historyStats mirrors the /history/stats response.
+type historyStats struct {
+	Since           time.Time        `json:"since"`
+	Until           time.Time        `json:"until"`
+	IntervalSeconds float64          `json:"intervalSeconds"`
+	Counting        string           `json:"counting"`
+	Total           int64            `json:"total"`
+	Levels          map[string]int64 `json:"levels"`
+	Buckets         []struct {
+		Start  time.Time        `json:"start"`
+		Total  int64            `json:"total"`
+		Levels map[string]int64 `json:"levels"`
+	} `json:"buckets"`
+}
+
+// statsLevelOrder is the column order of `history stats`; only levels with a
+// count in the window get a column.
+var statsLevelOrder = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL", "PANIC", "UNKNOWN"}
+
+func newHistoryCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Read logs persisted by the server's log store",
+	}
+	cmd.AddCommand(
+		newHistoryLogsCmd(a),
+		newHistoryStatsCmd(a),
+		newHistoryContainersCmd(a),
+		newHistoryExportCmd(a),
+		newHistoryImportCmd(a),
+	)
+	return cmd
+}
+
+func newHistoryLogsCmd(a *app) *cobra.Command {
+	var flags historyFlags
+
+	cmd := &cobra.Command{
+		Use:   "logs [<name>]",
+		Short: "Read stored logs of one container, or search across every stored container",
+		Long: `Read stored logs of one logical container, or omit the name to search every
+stored container at once, merged by timestamp. --containers, --project, and
+--host narrow a store-wide search. Pages walk backwards through history: the
+cursor printed after a full page fetches the next, older one.`,
+		Args: cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				if len(flags.containers) > 0 || flags.project != "" {
+					return fmt.Errorf("--containers and --project search across containers; drop the container name to use them")
+				}
+				flags.container = args[0]
+			}
+			return nil
+		},
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			query, err := flags.query(time.Now())
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				Logs       []logEntry `json:"logs"`
+				Count      int        `json:"count"`
+				NextCursor string     `json:"nextCursor,omitempty"`
+			}
+			if err := a.client.get(cmd.Context(), "/history/logs", query, &resp); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				if resp.Logs == nil {
+					resp.Logs = []logEntry{}
+				}
+				return a.printJSON(resp)
+			}
+			if err := a.printLogs(resp.Logs, flags.container == ""); err != nil {
+				return err
+			}
+			if resp.NextCursor != "" {
+				fmt.Fprintf(os.Stderr, "older lines: --cursor %s\n", resp.NextCursor)
+			}
+			return nil
+		}),
+	}
+
+	flags.register(cmd)
+	return cmd
+}
+
+func newHistoryStatsCmd(a *app) *cobra.Command {
+	var flags statsFlags
+
+	cmd := &cobra.Command{
+		Use:   "stats [<name>]",
+		Short: "Count stored logs per level over time",
+		Long: `Count stored logs per level in fixed-width time buckets, for one container or
+across stored containers (narrowed like "history logs"). Without --search or
+--filter every stored line is counted by its level; with either, matching
+entries are counted instead. The window defaults to the last 24 hours.
+
+  logdeck history stats --project api --level error --interval 1m --since 1d`,
+		Args: cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				if len(flags.containers) > 0 || flags.project != "" {
+					return fmt.Errorf("--containers and --project count across containers; drop the container name to use them")
+				}
+				flags.container = args[0]
+			}
+			return nil
+		},
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			query, err := flags.query(time.Now())
+			if err != nil {
+				return err
+			}
+
+			var resp historyStats
+			if err := a.client.get(cmd.Context(), "/history/stats", query, &resp); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(resp)
+			}
+
+			var levels []string
+			for _, level := range statsLevelOrder {
+				if resp.Levels[level] > 0 {
+					levels = append(levels, level)
+				}
+			}
+			headers := append([]string{"BUCKET", "TOTAL"}, levels...)
+			rows := make([][]string, 0, len(resp.Buckets))
+			for _, bucket := range resp.Buckets {
+				row := []string{formatLogTimestamp(bucket.Start), strconv.FormatInt(bucket.Total, 10)}
+				for _, level := range levels {
+					row = append(row, strconv.FormatInt(bucket.Levels[level], 10))
+				}
+				rows = append(rows, row)
+			}
+			renderTable(os.Stdout, headers, rows)
+			fmt.Fprintf(os.Stderr, "%d %s in %s buckets\n", resp.Total, resp.Counting,
+				time.Duration(resp.IntervalSeconds*float64(time.Second)))
+			return nil
+		}),
+	}
+
+	flags.registerSelection(cmd)
+	cmd.Flags().StringVar(&flags.interval, "interval", "", "bucket width (e.g. 1m, 1h, 1d; default picks about 100 buckets)")
+	return cmd
+}
+
+func newHistoryContainersCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "containers",
+		Short: "List every logical container with stored logs",
+		Args:  cobra.NoArgs,
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var resp struct {
+				Containers []storedContainer `json:"containers"`
+			}
+			if err := a.client.get(cmd.Context(), "/history/containers", nil, &resp); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				if resp.Containers == nil {
+					resp.Containers = []storedContainer{}
+				}
+				return a.printJSON(resp)
+			}
+
+			now := time.Now()
+			rows := make([][]string, 0, len(resp.Containers))
+			for _, c := range resp.Containers {
+				state := ""
+				switch {
+				case c.Excluded:
+					state = "excluded"
+				case c.Removed:
+					state = "removed"
+				case c.Filtered:
+					state = "filtered"
+				}
+				rows = append(rows, []string{
+					c.Name,
+					c.Host,
+					c.ComposeProject,
+					humanBytes(uint64(max(c.StoredBytes, 0))),
+					humanAge(c.OldestTs, now),
+					humanAge(c.NewestTs, now),
+					state,
+				})
+			}
+			renderTable(os.Stdout, []string{"NAME", "HOST", "PROJECT", "STORED", "OLDEST", "NEWEST", "STATE"}, rows)
+			return nil
+		}),
+	}
+}
+
+// importResult mirrors logstore.ImportResult.
+type importResult struct {
+	Lines       int64 `json:"lines"`
+	Imported    int64 `json:"imported"`
+	Duplicates  int64 `json:"duplicates"`
+	Generations int   `json:"generations"`
+}
+
+func newHistoryExportCmd(a *app) *cobra.Command {
+	var (
+		flags  historyFlags
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export [<name>]",
+		Short: "Export stored logs as a compressed archive",
+		Long: `Export the stored lines of one container, or of every stored container
+(narrowed like "history logs"), as a zstd-compressed NDJSON archive that
+"history import" reads on this or another LogDeck server. The archive is
+written to --output, or to stdout when that is not a terminal.
+
+  logdeck history export --project shop --since 7d > shop.ndjson.zst`,
+		Args: cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				if len(flags.containers) > 0 || flags.project != "" {
+					return fmt.Errorf("--containers and --project export several containers; drop the container name to use them")
+				}
+				flags.container = args[0]
+			}
+			if output == "" && isTerminal(os.Stdout) {
+				return fmt.Errorf("refusing to write a compressed archive to a terminal; redirect stdout or use --output")
+			}
+			return nil
+		},
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			query, err := flags.query(time.Now())
+			if err != nil {
+				return err
+			}
+
+			body, err := a.client.stream(cmd.Context(), "/history/export", query)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+
+			if output == "" {
+				_, err = io.Copy(os.Stdout, body)
+				return err
+			}
+			// A failed export leaves no partial archive behind.
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			written, err := io.Copy(file, body)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(output)
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "wrote %s to %s\n", humanBytes(uint64(written)), output)
+			return nil
+		}),
+	}
+
+	flags.registerScope(cmd)
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the archive to this file instead of stdout")
+	return cmd
+}
+
+func newHistoryImportCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import an archive written by history export",
+		Long: `Import an archive written by "history export" ("-" reads stdin). Every line
+joins the container it was exported from, and lines the store already holds
+are skipped, so importing the same archive twice stores it once.`,
+		Args: cobra.ExactArgs(1),
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = os.Stdin
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				in = file
+			}
+
+			var resp struct {
+				Message string       `json:"message"`
+				Result  importResult `json:"result"`
+			}
+			if err := a.client.upload(cmd.Context(), "/history/import", "application/zstd", in, &resp); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(resp.Result)
+			}
+			fmt.Printf("imported %d of %d lines (%d already stored), %d new containers\n",
+				resp.Result.Imported, resp.Result.Lines, resp.Result.Duplicates, resp.Result.Generations)
+			return nil
+		}),
+	}
+}
+
+// isTerminal reports whether f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// splitList splits a comma-separated list, trimming blanks.
+func splitList(value string) []string {
+	return compactList(strings.Split(value, ","))
+}
+
+// compactList trims every item and drops the blank ones.
+func compactList(items []string) []string {
+	var compact []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			compact = append(compact, item)
+		}
+	}
+	return compact
+}
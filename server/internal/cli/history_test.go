@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryFlagsQuery(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Run("store-wide filters", func(t *testing.T) {
+		f := historyFlags{
+			containers: []string{"api", " ", "worker"},
+			project:    "shop",
+			host:       "prod",
+			search:     "req-42",
+			filter:     "status>=500",
+			levels:     "error,warn",
+			since:      "1d",
+			limit:      50,
+		}
+		q, err := f.query(now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := q["container"]; ok {
+			t.Errorf("a store-wide query must not send container, got %q", q.Get("container"))
+		}
+		for key, want := range map[string]string{
+			"containers": "api,worker",
+			"project":    "shop",
+			"host":       "prod",
+			"search":     "req-42",
+			"filter":     "status>=500",
+			"levels":     "ERROR,WARN",
+			"since":      "2026-01-01T12:00:00Z",
+			"limit":      "50",
+		} {
+			if got := q.Get(key); got != want {
+				t.Errorf("%s = %q, want %q", key, got, want)
+			}
+		}
+	})
+
+	t.Run("single container", func(t *testing.T) {
+		f := historyFlags{container: "web", regex: true, search: "time(out)?", cursor: "abc"}
+		q, err := f.query(now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if q.Get("container") != "web" || q.Get("regex") != "true" || q.Get("cursor") != "abc" {
+			t.Errorf("unexpected query: %v", q)
+		}
+		for _, key := range []string{"containers", "project", "host", "filter", "levels", "since", "until", "limit"} {
+			if _, ok := q[key]; ok {
+				t.Errorf("expected %q to be omitted, got %q", key, q.Get(key))
+			}
+		}
+	})
+
+	t.Run("bad until is an error", func(t *testing.T) {
+		f := historyFlags{until: "later"}
+		if _, err := f.query(now); err == nil {
+			t.Fatal("expected an error for an unparseable --until")
+		}
+	})
+}
+
+func TestHistoryLogsRejectsNameWithStoreWideFilters(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if code := execute(t.Context(), "test", []string{"history", "logs", "web", "--project", "shop"}); code != 2 {
+		t.Fatalf("exit code = %d, want 2 (usage error)", code)
+	}
+}
+
+func TestHistoryExportRejectsNameWithStoreWideFilters(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if code := execute(t.Context(), "test", []string{"history", "export", "web", "--containers", "db", "-o", filepath.Join(t.TempDir(), "out.zst")}); code != 2 {
+		t.Fatalf("exit code = %d, want 2 (usage error)", code)
+	}
+}
+
+func TestStatsFlagsQuery(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	f := statsFlags{historyFlags: historyFlags{project: "api", levels: "error", since: "1d"}, interval: "1d"}
+	q, err := f.query(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for key, want := range map[string]string{
+		"project":  "api",
+		"levels":   "ERROR",
+		"since":    "2026-01-01T12:00:00Z",
+		"interval": "24h0m0s",
+	} {
+		if got := q.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	for _, key := range []string{"limit", "cursor"} {
+		if _, ok := q[key]; ok {
+			t.Errorf("stats must not send %q, got %q", key, q.Get(key))
+		}
+	}
+
+	for _, interval := range []string{"often", "0", "-1m"} {
+		f := statsFlags{interval: interval}
+		if _, err := f.query(now); err == nil {
+			t.Errorf("interval %q: expected an error", interval)
+		}
+	}
+}
@@ -25,6 +25,7 @@ type logFlags struct {
 	tail   int
 	level  string
 	search string
+	filter string
 	since  string
 	until  string
 	follow bool
@@ -34,6 +35,7 @@ func (f *logFlags) register(cmd *cobra.Command) {
 	cmd.Flags().IntVar(&f.tail, "tail", 100, "number of lines from the end of the logs (max 10000)")
 	cmd.Flags().StringVar(&f.level, "level", "", "filter by log level (TRACE, DEBUG, INFO, WARN, ERROR, FATAL, PANIC)")
 	cmd.Flags().StringVar(&f.search, "search", "", "filter by regex")
+	cmd.Flags().StringVar(&f.filter, "filter", "", `filter by structured fields, e.g. 'status>=500 AND path~"/api"'`)
 	cmd.Flags().StringVar(&f.since, "since", "", "only logs after this time (RFC3339 or relative: 30s, 15m, 2h, 1d)")
 	cmd.Flags().StringVar(&f.until, "until", "", "only logs before this time (RFC3339 or relative: 30s, 15m, 2h, 1d)")
 	cmd.Flags().BoolVarP(&f.follow, "follow", "f", false, "stream new logs continuously")
@@ -63,6 +65,9 @@ func (f *logFlags) query(now time.Time) (url.Values, error) {
 	if f.search != "" {
 		query.Set("search", f.search)
 	}
+	if f.filter != "" {
+		query.Set("filter", f.filter)
+	}
 	return query, nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -100,6 +101,7 @@ func registerMCPTools(s *mcp.Server, a *app) []string {
 		Tail      int    `json:"tail,omitempty" jsonschema:"lines from the end of the logs (default 100, max 500)"`
 		Level     string `json:"level,omitempty" jsonschema:"filter by level: TRACE, DEBUG, INFO, WARN, ERROR, FATAL, PANIC"`
 		Search    string `json:"search,omitempty" jsonschema:"filter by regular expression"`
+		Filter    string `json:"filter,omitempty" jsonschema:"filter by JSON/logfmt fields: key op value joined by AND, ops = != > >= < <= ~ (contains) !~, e.g. status>=500 AND path~\"/api\""`
 		Since     string `json:"since,omitempty" jsonschema:"only logs after this time (RFC3339 or relative: 30s, 15m, 2h, 1d)"`
 		Until     string `json:"until,omitempty" jsonschema:"only logs before this time (RFC3339 or relative)"`
 	}
@@ -109,7 +111,7 @@ func registerMCPTools(s *mcp.Server, a *app) []string {
 		if err != nil {
 			return nil, nil, err
 		}
-		f := logFlags{tail: clampTail(in.Tail), level: in.Level, search: in.Search, since: in.Since, until: in.Until}
+		f := logFlags{tail: clampTail(in.Tail), level: in.Level, search: in.Search, filter: in.Filter, since: in.Since, until: in.Until}
 		query, err := f.query(time.Now())
 		if err != nil {
 			return nil, nil, err
@@ -235,7 +237,7 @@ func registerMCPTools(s *mcp.Server, a *app) []string {
 		Container string `json:"container,omitempty" jsonschema:"limit to one container (name or ID); omit for all"`
 		Host      string `json:"host,omitempty" jsonschema:"host name (disambiguates duplicate names)"`
 	}
-	tool = &mcp.Tool{Name: "container_stats", Description: "Show CPU and memory usage for running containers.", Annotations: readOnlyAnnot()}
+	tool = &mcp.Tool{Name: "container_stats", Description: "Show CPU, memory (with cache/RSS breakdown), network and block I/O rates in bytes/sec, and PIDs for running containers.", Annotations: readOnlyAnnot()}
 	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in containerStatsInput) (*mcp.CallToolResult, any, error) {
 		var resp struct {
 			Stats []containerStats `json:"stats"`
@@ -263,6 +265,30 @@ func registerMCPTools(s *mcp.Server, a *app) []string {
 	})
 	register(tool)
 
+	type containerStatsHistoryInput struct {
+		Container  string `json:"container" jsonschema:"container name or ID; a removed container needs host"`
+		Host       string `json:"host,omitempty" jsonschema:"host name (disambiguates duplicate names)"`
+		Since      string `json:"since,omitempty" jsonschema:"window start (RFC3339 or relative, default 1h ago)"`
+		Until      string `json:"until,omitempty" jsonschema:"window end (RFC3339 or relative, default now)"`
+		Resolution string `json:"resolution,omitempty" jsonschema:"raw, 1m, or 1h (default picks one for the window)"`
+	}
+	tool = &mcp.Tool{Name: "container_stats_history", Description: "Show one container's stored CPU, memory, network, and block I/O usage over a window (e.g. memory growth over the last week). Points carry averages and peaks.", Annotations: readOnlyAnnot()}
+	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in containerStatsHistoryInput) (*mcp.CallToolResult, any, error) {
+		if in.Resolution != "" && !slices.Contains(statsResolutions, in.Resolution) {
+			return nil, nil, fmt.Errorf("invalid resolution %q (must be raw, 1m, or 1h)", in.Resolution)
+		}
+		containers, err := a.fetchContainers(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := a.fetchStatsHistory(ctx, containers.Containers, in.Container, in.Host, in.Since, in.Until, in.Resolution)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mcpJSON(resp)
+	})
+	register(tool)
+
 	tool = &mcp.Tool{Name: "host_stats", Description: "Show engine-level info for every configured Docker host.", Annotations: readOnlyAnnot()}
 	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
 		var resp struct {
@@ -280,52 +306,83 @@ func registerMCPTools(s *mcp.Server, a *app) []string {
 	registerListTool(s, a, register, "list_networks", "List networks across all hosts.", "/networks", "networks")
 
 	type historySearchInput struct {
-		Container string `json:"container" jsonschema:"logical container name"`
-		Host      string `json:"host,omitempty" jsonschema:"host name"`
-		Search    string `json:"search,omitempty" jsonschema:"text or regex to match"`
-		Regex     bool   `json:"regex,omitempty" jsonschema:"treat search as a regular expression"`
-		Levels    string `json:"levels,omitempty" jsonschema:"comma-separated levels (e.g. ERROR,WARN)"`
-		Since     string `json:"since,omitempty" jsonschema:"only logs after this time (RFC3339 or relative)"`
-		Until     string `json:"until,omitempty" jsonschema:"only logs before this time (RFC3339 or relative)"`
-		Limit     int    `json:"limit,omitempty" jsonschema:"max lines per page (default 100, max 500)"`
-		Cursor    string `json:"cursor,omitempty" jsonschema:"nextCursor from a previous page to fetch older lines"`
+		Container  string `json:"container,omitempty" jsonschema:"logical container name; omit to search every stored container"`
+		Containers string `json:"containers,omitempty" jsonschema:"comma-separated container names to search together (without container)"`
+		Project    string `json:"project,omitempty" jsonschema:"only containers of this compose project (without container)"`
+		Host       string `json:"host,omitempty" jsonschema:"host name"`
+		Search     string `json:"search,omitempty" jsonschema:"text or regex to match"`
+		Regex      bool   `json:"regex,omitempty" jsonschema:"treat search as a regular expression"`
+		Filter     string `json:"filter,omitempty" jsonschema:"filter by JSON/logfmt fields: key op value joined by AND, ops = != > >= < <= ~ (contains) !~, e.g. status>=500 AND path~\"/api\""`
+		Levels     string `json:"levels,omitempty" jsonschema:"comma-separated levels (e.g. ERROR,WARN)"`
+		Since      string `json:"since,omitempty" jsonschema:"only logs after this time (RFC3339 or relative)"`
+		Until      string `json:"until,omitempty" jsonschema:"only logs before this time (RFC3339 or relative)"`
+		Limit      int    `json:"limit,omitempty" jsonschema:"max lines per page (default 100, max 500)"`
+		Cursor     string `json:"cursor,omitempty" jsonschema:"nextCursor from a previous page to fetch older lines"`
 	}
-	tool = &mcp.Tool{Name: "history_search", Description: "Search persisted (stored) logs for a container. Pages backwards; follow nextCursor for older lines.", Annotations: readOnlyAnnot()}
+	tool = &mcp.Tool{Name: "history_search", Description: "Search persisted (stored) logs of one container, or across every stored container (optionally narrowed by host, project, or names) merged by timestamp. Pages backwards; follow nextCursor for older lines.", Annotations: readOnlyAnnot()}
 	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in historySearchInput) (*mcp.CallToolResult, any, error) {
-		query := url.Values{}
-		query.Set("container", in.Container)
-		if in.Host != "" {
-			query.Set("host", in.Host)
+		f := historyFlags{
+			container:  in.Container,
+			containers: splitList(in.Containers),
+			project:    in.Project,
+			host:       in.Host,
+			search:     in.Search,
+			regex:      in.Regex,
+			filter:     in.Filter,
+			levels:     in.Levels,
+			since:      in.Since,
+			until:      in.Until,
+			limit:      clampTail(in.Limit),
+			cursor:     in.Cursor,
 		}
-		if in.Search != "" {
-			query.Set("search", in.Search)
-		}
-		if in.Regex {
-			query.Set("regex", "true")
-		}
-		if in.Levels != "" {
-			query.Set("levels", strings.ToUpper(in.Levels))
-		}
-		since, err := parseTimeArg(in.Since, time.Now())
+		query, err := f.query(time.Now())
 		if err != nil {
 			return nil, nil, err
 		}
-		if since != "" {
-			query.Set("since", since)
-		}
-		until, err := parseTimeArg(in.Until, time.Now())
-		if err != nil {
+		var resp map[string]any
+		if err := a.client.get(ctx, "/history/logs", query, &resp); err != nil {
 			return nil, nil, err
 		}
-		if until != "" {
-			query.Set("until", until)
+		return mcpJSON(resp)
+	})
+	register(tool)
+
+	type historyStatsInput struct {
+		Container  string `json:"container,omitempty" jsonschema:"logical container name; omit to count across every stored container"`
+		Containers string `json:"containers,omitempty" jsonschema:"comma-separated container names to count together (without container)"`
+		Project    string `json:"project,omitempty" jsonschema:"only containers of this compose project (without container)"`
+		Host       string `json:"host,omitempty" jsonschema:"host name"`
+		Search     string `json:"search,omitempty" jsonschema:"count only entries matching this text or regex"`
+		Regex      bool   `json:"regex,omitempty" jsonschema:"treat search as a regular expression"`
+		Filter     string `json:"filter,omitempty" jsonschema:"count only entries matching these JSON/logfmt field predicates, e.g. status>=500"`
+		Levels     string `json:"levels,omitempty" jsonschema:"comma-separated levels to count (e.g. ERROR,FATAL)"`
+		Since      string `json:"since,omitempty" jsonschema:"window start (RFC3339 or relative, default 24h ago)"`
+		Until      string `json:"until,omitempty" jsonschema:"window end (RFC3339 or relative, default now)"`
+		Interval   string `json:"interval,omitempty" jsonschema:"bucket width, e.g. 1m, 1h, 1d (default picks about 100 buckets)"`
+	}
+	tool = &mcp.Tool{Name: "history_stats", Description: "Count persisted logs per level in time buckets (e.g. errors per minute for a project over 24h) without fetching lines. Without search or filter it counts stored lines; with either, matching entries.", Annotations: readOnlyAnnot()}
+	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in historyStatsInput) (*mcp.CallToolResult, any, error) {
+		f := statsFlags{
+			historyFlags: historyFlags{
+				container:  in.Container,
+				containers: splitList(in.Containers),
+				project:    in.Project,
+				host:       in.Host,
+				search:     in.Search,
+				regex:      in.Regex,
+				filter:     in.Filter,
+				levels:     in.Levels,
+				since:      in.Since,
+				until:      in.Until,
+			},
+			interval: in.Interval,
 		}
-		query.Set("limit", strconv.Itoa(clampTail(in.Limit)))
-		if in.Cursor != "" {
-			query.Set("cursor", in.Cursor)
+		query, err := f.query(time.Now())
+		if err != nil {
+			return nil, nil, err
 		}
 		var resp map[string]any
-		if err := a.client.get(ctx, "/history/logs", query, &resp); err != nil {
+		if err := a.client.get(ctx, "/history/stats", query, &resp); err != nil {
 			return nil, nil, err
 		}
 		return mcpJSON(resp)
@@ -359,8 +416,11 @@ func registerMCPTools(s *mcp.Server, a *app) []string {
 	registerAction(s, a, register, "restart_container", "restart", "restarted", "Restart a container.", lifecycleAnnot())
 	registerAction(s, a, register, "remove_container", "remove", "removed", "Remove a container. This is irreversible.", destructiveAnnot())
 	registerRunCommand(s, a, register)
+	registerStackDeploy(s, a, register)
+	registerImageUpdateTools(s, a, register)
 	registerEnvTools(s, a, register)
 	registerSettingsTools(s, a, register)
+	registerSilenceTools(s, a, register)
 
 	return names
 }
@@ -427,12 +487,32 @@ func registerSettingsTools(s *mcp.Server, a *app, register func(*mcp.Tool)) {
 	})
 	register(tool)
 
+	type retentionRule struct {
+		Hosts       []string `json:"hosts,omitempty" jsonschema:"hosts the rule applies to; empty means every host"`
+		Containers  []string `json:"containers,omitempty" jsonschema:"container names the rule applies to"`
+		Projects    []string `json:"projects,omitempty" jsonschema:"compose projects the rule applies to; with no containers or projects the rule covers every container"`
+		Levels      []string `json:"levels,omitempty" jsonschema:"log levels the rule limits (TRACE, DEBUG, INFO, WARN, ERROR, FATAL, PANIC, UNKNOWN); empty means every level"`
+		MaxAgeHours int      `json:"maxAgeHours" jsonschema:"how many hours matching lines are kept"`
+	}
+	type ingestionRule struct {
+		Hosts         []string `json:"hosts,omitempty" jsonschema:"hosts the rule applies to; empty means every host"`
+		Containers    []string `json:"containers,omitempty" jsonschema:"container names the rule applies to"`
+		Projects      []string `json:"projects,omitempty" jsonschema:"compose projects the rule applies to"`
+		Labels        []string `json:"labels,omitempty" jsonschema:"container labels the rule applies to, as key or key=value; with no containers, projects, or labels the rule covers every container"`
+		Action        string   `json:"action" jsonschema:"exclude (store nothing), drop (skip lines matching pattern), or sample (keep samplePercent of INFO-and-below entries)"`
+		Pattern       string   `json:"pattern,omitempty" jsonschema:"drop: RE2 pattern matched against the message"`
+		SamplePercent int      `json:"samplePercent,omitempty" jsonschema:"sample: percentage of INFO-and-below entries to keep, 0-100"`
+	}
 	type logStorageInput struct {
-		Enabled        *bool `json:"enabled,omitempty" jsonschema:"turn log persistence on or off"`
-		PerContainerMB *int  `json:"perContainerMB,omitempty" jsonschema:"per-container retention cap in MB"`
-		TotalMB        *int  `json:"totalMB,omitempty" jsonschema:"total retention cap in MB across all containers"`
+		Enabled        *bool            `json:"enabled,omitempty" jsonschema:"turn log persistence on or off"`
+		PerContainerMB *int             `json:"perContainerMB,omitempty" jsonschema:"per-container retention cap in MB"`
+		TotalMB        *int             `json:"totalMB,omitempty" jsonschema:"total retention cap in MB across all containers"`
+		FullTextIndex  *bool            `json:"fullTextIndex,omitempty" jsonschema:"maintain a full-text index that speeds up substring search of stored logs"`
+		IndexedFields  *[]string        `json:"indexedFields,omitempty" jsonschema:"the complete list of JSON/logfmt field keys to index for field filters (e.g. status, request_id); it replaces the current list, and [] indexes none"`
+		RetentionRules *[]retentionRule `json:"retentionRules,omitempty" jsonschema:"the complete ordered list of age-based retention rules; for each level the first matching rule wins, so put specific rules before catch-alls. It replaces the current list, and [] removes every rule"`
+		IngestionRules *[]ingestionRule `json:"ingestionRules,omitempty" jsonschema:"the complete list of ingestion rules that keep lines out of the store; every matching rule applies. It replaces the current list, and [] removes every rule"`
 	}
-	tool = &mcp.Tool{Name: "set_log_storage", Description: "Update log persistence: enable or disable it, or change the retention caps. Omitted fields are left unchanged. Lowering a cap makes the next sweep evict stored logs.", Annotations: destructiveAnnot()}
+	tool = &mcp.Tool{Name: "set_log_storage", Description: "Update log persistence: enable or disable it, change the retention caps, age-based retention rules, or ingestion rules, toggle the full-text search index, or choose the indexed fields. Omitted fields are left unchanged. Lowering a cap makes the next sweep evict stored logs.", Annotations: destructiveAnnot()}
 	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in logStorageInput) (*mcp.CallToolResult, any, error) {
 		body := map[string]any{}
 		if in.Enabled != nil {
@@ -444,8 +524,20 @@ func registerSettingsTools(s *mcp.Server, a *app, register func(*mcp.Tool)) {
 		if in.TotalMB != nil {
 			body["totalMB"] = *in.TotalMB
 		}
+		if in.FullTextIndex != nil {
+			body["fullTextIndex"] = *in.FullTextIndex
+		}
+		if in.IndexedFields != nil {
+			body["indexedFields"] = *in.IndexedFields
+		}
+		if in.RetentionRules != nil {
+			body["retentionRules"] = *in.RetentionRules
+		}
+		if in.IngestionRules != nil {
+			body["ingestionRules"] = *in.IngestionRules
+		}
 		if len(body) == 0 {
-			return nil, nil, fmt.Errorf("set at least one of enabled, perContainerMB, or totalMB")
+			return nil, nil, fmt.Errorf("set at least one of enabled, perContainerMB, totalMB, fullTextIndex, indexedFields, retentionRules, or ingestionRules")
 		}
 		return putJSON(ctx, a, "/settings/log-storage", body)
 	})
@@ -536,6 +628,61 @@ func registerSettingsTools(s *mcp.Server, a *app, register func(*mcp.Tool)) {
 	register(tool)
 }
 
+// registerSilenceTools registers alert silences, so an agent running a deploy
+// can mute the alerts it is about to cause. Silenced alerts are still recorded
+// in history. The server denies alerts to read-scoped tokens.
+func registerSilenceTools(s *mcp.Server, a *app, register func(*mcp.Tool)) {
+	tool := &mcp.Tool{Name: "list_alert_silences", Description: "List alert silences (active, scheduled, and recently ended) and recurring maintenance windows.", Annotations: readOnlyAnnot()}
+	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+		var silences, windows map[string]any
+		if err := a.client.get(ctx, "/alerts/silences", nil, &silences); err != nil {
+			return nil, nil, err
+		}
+		if err := a.client.get(ctx, "/alerts/maintenance", nil, &windows); err != nil {
+			return nil, nil, err
+		}
+		return mcpJSON(map[string]any{"silences": silences["silences"], "maintenanceWindows": windows["windows"]})
+	})
+	register(tool)
+
+	type createSilenceInput struct {
+		Hosts           []string `json:"hosts,omitempty" jsonschema:"silence only these hosts"`
+		Containers      []string `json:"containers,omitempty" jsonschema:"silence only these container names"`
+		Projects        []string `json:"projects,omitempty" jsonschema:"silence only these compose projects"`
+		Rules           []string `json:"rules,omitempty" jsonschema:"silence only these alert rule IDs"`
+		DurationSeconds int      `json:"durationSeconds" jsonschema:"how long the silence lasts from its start"`
+		StartsAt        string   `json:"startsAt,omitempty" jsonschema:"RFC 3339 start time; defaults to now"`
+		Comment         string   `json:"comment,omitempty" jsonschema:"why the alerts are silenced, e.g. the deploy being run"`
+	}
+	tool = &mcp.Tool{Name: "create_alert_silence", Description: "Silence alerts matching hosts, containers, compose projects, and rules (all optional; empty matches everything) for a time range. Matching alerts are recorded in history but not notified.", Annotations: lifecycleAnnot()}
+	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in createSilenceInput) (*mcp.CallToolResult, any, error) {
+		if in.DurationSeconds <= 0 {
+			return nil, nil, fmt.Errorf("durationSeconds must be positive")
+		}
+		var resp map[string]any
+		if err := a.client.post(ctx, "/alerts/silences", nil, in, &resp); err != nil {
+			return nil, nil, err
+		}
+		return mcpJSON(resp)
+	})
+	register(tool)
+
+	type deleteSilenceInput struct {
+		ID string `json:"id" jsonschema:"the silence's ID, as shown by list_alert_silences"`
+	}
+	tool = &mcp.Tool{Name: "delete_alert_silence", Description: "Delete an alert silence, ending it at once.", Annotations: lifecycleAnnot()}
+	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in deleteSilenceInput) (*mcp.CallToolResult, any, error) {
+		if strings.TrimSpace(in.ID) == "" {
+			return nil, nil, fmt.Errorf("id is required")
+		}
+		if err := a.client.do(ctx, http.MethodDelete, "/alerts/silences/"+url.PathEscape(in.ID), nil, nil, nil); err != nil {
+			return nil, nil, err
+		}
+		return mcpJSON(map[string]string{"message": "silence deleted", "id": in.ID})
+	})
+	register(tool)
+}
+
 // getJSON and putJSON decode an endpoint's JSON body straight into a tool
 // result, for the settings tools whose shapes the CLI does not model.
 func getJSON(ctx context.Context, a *app, path string, query url.Values) (*mcp.CallToolResult, any, error) {
@@ -649,6 +796,114 @@ func registerRunCommand(s *mcp.Server, a *app, register func(*mcp.Tool)) {
 	register(tool)
 }
 
+// stackDeployResult is the structured result of a deploy_stack call: every
+// progress step, then how the run ended.
+type stackDeployResult struct {
+	Project string               `json:"project"`
+	Host    string               `json:"host"`
+	Action  string               `json:"action"`
+	Status  string               `json:"status"`
+	Error   string               `json:"error,omitempty"`
+	Events  []composeDeployEvent `json:"events"`
+}
+
+func registerStackDeploy(s *mcp.Server, a *app, register func(*mcp.Tool)) {
+	type deployStackInput struct {
+		Project string `json:"project" jsonschema:"compose project name"`
+		Action  string `json:"action" jsonschema:"pull, up, or down"`
+		Host    string `json:"host,omitempty" jsonschema:"host name (required when the project runs on several hosts)"`
+	}
+	tool := &mcp.Tool{Name: "deploy_stack", Description: "Run docker compose pull, up -d, or down for a compose project from the compose files its containers were started with. up recreates only services whose configuration or image changed; down removes containers and networks but keeps volumes. Returns every progress step.", Annotations: destructiveAnnot()}
+	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in deployStackInput) (*mcp.CallToolResult, any, error) {
+		if !stackDeployActions[in.Action] {
+			return nil, nil, fmt.Errorf("invalid action %q: must be one of pull, up, down", in.Action)
+		}
+		host, err := a.stackDeployHost(ctx, in.Project, in.Host)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := a.client.streamPost(ctx, "/compose/"+in.Project+"/"+in.Action, url.Values{"host": {host}})
+		if err != nil {
+			return nil, nil, err
+		}
+		defer body.Close()
+
+		result := stackDeployResult{Project: in.Project, Host: host, Action: in.Action, Events: []composeDeployEvent{}}
+		err = a.scanNDJSON(ctx, body, func(line []byte) error {
+			var event composeDeployEvent
+			if err := json.Unmarshal(line, &event); err != nil {
+				return nil
+			}
+			if event.Status == "done" || event.Status == "error" {
+				result.Status, result.Error = event.Status, event.Error
+				return nil
+			}
+			result.Events = append(result.Events, event)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if result.Status == "" {
+			result.Status, result.Error = "error", "the stream ended without a result; check the stack's state"
+		}
+		res, _, err := mcpJSON(result)
+		if res != nil {
+			res.IsError = result.Status == "error"
+		}
+		return res, nil, err
+	})
+	register(tool)
+}
+
+// registerImageUpdateTools registers the image update check (a read) and the
+// pull-and-recreate that applies one, which restarts the container.
+func registerImageUpdateTools(s *mcp.Server, a *app, register func(*mcp.Tool)) {
+	type listImageUpdatesInput struct {
+		Host string `json:"host,omitempty" jsonschema:"filter by host name"`
+		All  bool   `json:"all,omitempty" jsonschema:"include up-to-date and uncheckable containers, not only those with an update"`
+	}
+	tool := &mcp.Tool{Name: "list_image_updates", Description: "List running containers whose image tag now points to a newer image in its registry, from the server's last periodic check.", Annotations: readOnlyAnnot()}
+	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in listImageUpdatesInput) (*mcp.CallToolResult, any, error) {
+		var resp imageUpdatesResponse
+		if err := a.client.get(ctx, "/images/updates", nil, &resp); err != nil {
+			return nil, nil, err
+		}
+		filtered := make([]imageUpdateStatus, 0, len(resp.Updates))
+		for _, u := range resp.Updates {
+			if (in.Host == "" || u.Host == in.Host) && (in.All || u.UpdateAvailable) {
+				filtered = append(filtered, u)
+			}
+		}
+		return mcpJSON(map[string]any{"enabled": resp.Enabled, "lastCheck": resp.LastCheck, "updates": filtered})
+	})
+	register(tool)
+
+	tool = &mcp.Tool{Name: "update_container_image", Description: "Pull the tag a container was created from and, if a newer image arrived, recreate the container on it with its current configuration. The container restarts and gets a new ID.", Annotations: destructiveAnnot()}
+	mcp.AddTool(s, tool, func(ctx context.Context, _ *mcp.CallToolRequest, in containerRef) (*mcp.CallToolResult, any, error) {
+		container, err := a.resolve(ctx, in.Container, in.Host)
+		if err != nil {
+			return nil, nil, err
+		}
+		var resp struct {
+			Message        string `json:"message"`
+			Recreated      bool   `json:"recreated"`
+			NewContainerID string `json:"new_container_id"`
+		}
+		if err := a.client.postSlow(ctx, "/containers/"+container.ID+"/update-image", url.Values{"host": {container.Host}}, nil, &resp); err != nil {
+			return nil, nil, err
+		}
+		return mcpJSON(map[string]any{
+			"message":   resp.Message,
+			"recreated": resp.Recreated,
+			"name":      containerName(container),
+			"id":        resp.NewContainerID,
+			"host":      container.Host,
+		})
+	})
+	register(tool)
+}
+
 // mcpJSON packs a value as pretty-printed JSON text content.
 func mcpJSON(v any) (*mcp.CallToolResult, any, error) {
 	data, err := json.MarshalIndent(v, "", "  ")
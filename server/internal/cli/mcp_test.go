@@ -12,18 +12,24 @@ import (
 var allTools = []string{
 	// reads
 	"list_containers", "get_logs", "search_logs", "inspect_container",
-	"list_events", "container_stats", "host_stats",
+	"list_events", "container_stats", "container_stats_history", "host_stats",
 	"list_images", "list_volumes", "list_networks",
-	"history_search", "history_status", "history_containers",
+	"history_search", "history_stats", "history_status", "history_containers",
 	// container actions
 	"start_container", "stop_container", "restart_container",
 	"remove_container", "run_command",
+	// stacks
+	"deploy_stack",
+	// image updates
+	"list_image_updates", "update_container_image",
 	// env
 	"get_env", "set_env",
 	// settings
 	"get_settings", "set_read_only", "set_log_storage",
 	"set_docker_hosts", "set_coolify_hosts", "set_auth",
 	"list_api_tokens", "create_api_token", "delete_api_token",
+	// alert silences
+	"list_alert_silences", "create_alert_silence", "delete_alert_silence",
 }
 
 func registeredTools(t *testing.T) []string {
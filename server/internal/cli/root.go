@@ -146,6 +146,7 @@ func newRootCmd(a *app) *cobra.Command {
 		newInspectCmd(a),
 		newLogsCmd(a),
 		newGrepCmd(a),
+		newHistoryCmd(a),
 		newStatsCmd(a),
 		newEventsCmd(a),
 		newActionCmd(a, "start", "start", "started"),
@@ -155,6 +156,8 @@ func newRootCmd(a *app) *cobra.Command {
 		newStackCmd(a),
 		newEnvCmd(a),
 		newResourcesCmd(a),
+		newUpdatesCmd(a),
+		newUpdateCmd(a),
 		newImagesCmd(a),
 		newVolumesCmd(a),
 		newNetworksCmd(a),
@@ -1,7 +1,12 @@
 package cli
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -52,3 +57,80 @@ func TestBuildTargets(t *testing.T) {
 		t.Errorf("unexpected targets: %v", targets)
 	}
 }
+
+func TestStackUpStreamsProgress(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var method, host string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/containers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"containers":[{"id":"abc123","names":["/shop-web-1"],"state":"running","host":"prod",
+			"labels":{"com.docker.compose.project":"shop"}}],"hostErrors":[]}`)
+	})
+	mux.HandleFunc("/api/v1/compose/shop/up", func(w http.ResponseWriter, r *http.Request) {
+		method, host = r.Method, r.URL.Query().Get("host")
+		fmt.Fprintln(w, `{"service":"web","target":"shop-web-1","status":"recreated","detail":"configuration changed"}`)
+		fmt.Fprintln(w, `{"service":"db","target":"shop-db-1","status":"up-to-date"}`)
+		fmt.Fprintln(w, `{"status":"done","detail":"up shop"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var code int
+	out := captureStdout(t, func() {
+		code = execute(context.Background(), "test", []string{"stack", "up", "shop", "--url", server.URL})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	if method != http.MethodPost || host != "prod" {
+		t.Errorf("request = %s host=%q, want POST on prod", method, host)
+	}
+	for _, want := range []string{"web: shop-web-1 recreated (configuration changed)", "db: shop-db-1 up-to-date", "up shop done"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestStackDeployErrors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/containers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"containers":[
+			{"id":"a","names":["/shop-web-1"],"state":"running","host":"prod","labels":{"com.docker.compose.project":"shop"}},
+			{"id":"b","names":["/shop-web-1"],"state":"running","host":"staging","labels":{"com.docker.compose.project":"shop"}}],"hostErrors":[]}`)
+	})
+	mux.HandleFunc("/api/v1/compose/shop/pull", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"service":"web","target":"nginx:1.27","status":"failed","error":"manifest unknown"}`)
+		fmt.Fprintln(w, `{"status":"error","error":"pull web: manifest unknown"}`)
+	})
+	mux.HandleFunc("/api/v1/compose/shop/down", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"service":"web","target":"shop-web-1","status":"stopped"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"stack", "up", "shop"}, "pick one with --host"},
+		{[]string{"stack", "pull", "shop", "--host", "prod"}, "pull web: manifest unknown"},
+		{[]string{"stack", "down", "shop", "--host", "prod"}, "ended without a result"},
+	} {
+		var code int
+		stderr := captureStderr(t, func() {
+			captureStdout(t, func() {
+				code = execute(context.Background(), "test", append(tc.args, "--url", server.URL))
+			})
+		})
+		if code != 1 {
+			t.Errorf("%v: exit code = %d, want 1", tc.args, code)
+		}
+		if !strings.Contains(stderr, tc.want) {
+			t.Errorf("%v: stderr missing %q:\n%s", tc.args, tc.want, stderr)
+		}
+	}
+}
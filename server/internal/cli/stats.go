@@ -1,19 +1,71 @@
 package cli
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// statsResolutions are the resolutions /containers/{id}/stats/history accepts.
+var statsResolutions = []string{"raw", "1m", "1h"}
+
 func newStatsCmd(a *app) *cobra.Command {
-	var host string
+	var (
+		host, since, until, resolution string
+		watch                          bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "stats [<name|id>]",
-		Short: "Show CPU and memory usage for running containers",
-		Args:  cobra.MaximumNArgs(1),
+		Short: "Show CPU, memory, network, and block I/O usage for running containers",
+		Long: `Show CPU, memory, network, and block I/O usage for running containers.
+Network and block I/O are rates since the server's previous reading of the
+container, so the first reading after a while shows 0.
+
+With --watch, keep the table open and refresh it as the server streams new
+readings (about once a second) until interrupted.
+
+With --since, show one container's stored history instead: CPU, memory,
+network, and block I/O over the window, from the server's stats history.`,
+		Example: `  logdeck stats
+  logdeck stats --watch
+  logdeck stats web --since 6h
+  logdeck stats web --since 7d --resolution 1h`,
+		Args: cobra.MaximumNArgs(1),
+		// Flag validation runs in PreRunE so bad combinations are usage
+		// errors (exit 2) and never reach the server.
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if watch && since != "" {
+				return fmt.Errorf("--watch and --since are mutually exclusive")
+			}
+			if since == "" {
+				if until != "" || resolution != "" {
+					return fmt.Errorf("--until and --resolution require --since")
+				}
+				return nil
+			}
+			if len(args) != 1 {
+				return fmt.Errorf("--since needs a container name or ID")
+			}
+			if resolution != "" && !slices.Contains(statsResolutions, resolution) {
+				return fmt.Errorf("invalid --resolution %q (must be raw, 1m, or 1h)", resolution)
+			}
+			for _, value := range []string{since, until} {
+				if _, err := parseTimeArg(value, time.Now()); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
 		RunE: a.run(func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -22,11 +74,8 @@ func newStatsCmd(a *app) *cobra.Command {
 				return err
 			}
 
-			var statsResp struct {
-				Stats []containerStats `json:"stats"`
-			}
-			if err := a.client.get(ctx, "/containers/stats", nil, &statsResp); err != nil {
-				return err
+			if since != "" {
+				return a.printStatsHistory(ctx, containers.Containers, args[0], host, since, until, resolution)
 			}
 
 			names := map[string]string{}
@@ -34,6 +83,29 @@ func newStatsCmd(a *app) *cobra.Command {
 				names[c.Host+"/"+c.ID] = containerName(c)
 			}
 
+			if watch {
+				query := url.Values{}
+				if host != "" {
+					query.Set("host", host)
+				}
+				if len(args) == 1 {
+					container, err := resolveContainer(containers.Containers, args[0], host)
+					if err != nil {
+						return err
+					}
+					query.Set("host", container.Host)
+					query.Set("id", container.ID)
+				}
+				return a.watchStats(ctx, query, names)
+			}
+
+			var statsResp struct {
+				Stats []containerStats `json:"stats"`
+			}
+			if err := a.client.get(ctx, "/containers/stats", nil, &statsResp); err != nil {
+				return err
+			}
+
 			stats := statsResp.Stats
 			if len(args) == 1 {
 				container, err := resolveContainer(containers.Containers, args[0], host)
@@ -58,26 +130,166 @@ func newStatsCmd(a *app) *cobra.Command {
 				return a.printJSON(map[string]any{"stats": stats})
 			}
 
-			rows := make([][]string, 0, len(stats))
-			for _, s := range stats {
-				name := names[s.Host+"/"+s.ID]
-				if name == "" {
-					name = shortID(s.ID)
-				}
-				rows = append(rows, []string{
-					name,
-					s.Host,
-					fmt.Sprintf("%.1f%%", s.CPUPercent),
-					fmt.Sprintf("%.1f%%", s.MemoryPercent),
-					humanBytes(s.MemoryUsed),
-					humanBytes(s.MemoryLimit),
-				})
-			}
-			renderTable(os.Stdout, []string{"NAME", "HOST", "CPU%", "MEM%", "MEM USED", "MEM LIMIT"}, rows)
+			renderStats(os.Stdout, stats, names)
 			return nil
 		}),
 	}
 
 	cmd.Flags().StringVar(&host, "host", "", "host name (disambiguates duplicate container names)")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "refresh as the server streams new readings, until interrupted")
+	cmd.Flags().StringVar(&since, "since", "", "show stored history from this time (RFC3339 or relative: 30m, 6h, 7d)")
+	cmd.Flags().StringVar(&until, "until", "", "end the history at this time (RFC3339 or relative)")
+	cmd.Flags().StringVar(&resolution, "resolution", "", "history resolution: raw, 1m, or 1h (default picks one for the window)")
 	return cmd
 }
+
+// renderStats prints the stats table. names maps host/ID to container names;
+// a container missing from it is shown by its short ID.
+func renderStats(w io.Writer, stats []containerStats, names map[string]string) {
+	rows := make([][]string, 0, len(stats))
+	for _, s := range stats {
+		name := names[s.Host+"/"+s.ID]
+		if name == "" {
+			name = shortID(s.ID)
+		}
+		rows = append(rows, []string{
+			name,
+			s.Host,
+			fmt.Sprintf("%.1f%%", s.CPUPercent),
+			fmt.Sprintf("%.1f%%", s.MemoryPercent),
+			humanBytes(s.MemoryUsed),
+			humanBytes(s.MemoryLimit),
+			humanRate(s.NetworkRxPerSec),
+			humanRate(s.NetworkTxPerSec),
+			humanRate(s.BlockReadPerSec),
+			humanRate(s.BlockWritePerSec),
+			strconv.FormatUint(s.PIDs, 10),
+		})
+	}
+	renderTable(w, []string{"NAME", "HOST", "CPU%", "MEM%", "MEM USED", "MEM LIMIT", "NET RX", "NET TX", "BLK READ", "BLK WRITE", "PIDS"}, rows)
+}
+
+// watchRedrawInterval throttles table redraws: readings of different
+// containers arrive spread across each second.
+const watchRedrawInterval = time.Second
+
+// watchStats follows /containers/stats/stream. JSON output passes every event
+// through as an NDJSON line; table output redraws the whole table, clearing
+// the screen first on a terminal.
+func (a *app) watchStats(ctx context.Context, query url.Values, names map[string]string) error {
+	body, err := a.client.stream(ctx, "/containers/stats/stream", query)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	latest := map[string]containerStats{}
+	clearScreen := isTerminal(os.Stdout)
+	var lastDraw time.Time
+	dirty := false
+	draw := func() {
+		redraw := !lastDraw.IsZero()
+		lastDraw, dirty = time.Now(), false
+
+		stats := make([]containerStats, 0, len(latest))
+		for _, s := range latest {
+			stats = append(stats, s)
+		}
+		slices.SortFunc(stats, func(x, y containerStats) int {
+			return cmp.Or(cmp.Compare(names[x.Host+"/"+x.ID], names[y.Host+"/"+y.ID]), cmp.Compare(x.Host, y.Host), cmp.Compare(x.ID, y.ID))
+		})
+		if clearScreen {
+			fmt.Print("\033[H\033[2J")
+		} else if redraw {
+			fmt.Println()
+		}
+		renderStats(os.Stdout, stats, names)
+	}
+	err = a.scanNDJSON(ctx, body, func(line []byte) error {
+		if a.jsonOutput() {
+			fmt.Println(string(line))
+			return nil
+		}
+		var event containerStatsEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil // skip malformed lines
+		}
+		key := event.Host + "/" + event.ID
+		if event.Removed {
+			delete(latest, key)
+		} else {
+			latest[key] = event.containerStats
+		}
+		dirty = true
+		if time.Since(lastDraw) >= watchRedrawInterval {
+			draw()
+		}
+		return nil
+	})
+	// The server ended the stream: show what arrived since the last redraw.
+	if err == nil && dirty {
+		draw()
+	}
+	return err
+}
+
+// fetchStatsHistory reads one container's stored stats. History outlives the
+// container, so a removed one is looked up by name, which needs host to say
+// where it ran.
+func (a *app) fetchStatsHistory(ctx context.Context, containers []containerInfo, ref, host, since, until, resolution string) (containerStatsHistory, error) {
+	name := ref
+	if container, err := resolveContainer(containers, ref, host); err == nil {
+		host, name = container.Host, containerName(container)
+	} else if host == "" {
+		return containerStatsHistory{}, err
+	}
+
+	now := time.Now()
+	query := url.Values{"host": {host}}
+	for _, param := range []struct{ name, value string }{{"since", since}, {"until", until}} {
+		value, err := parseTimeArg(param.value, now)
+		if err != nil {
+			return containerStatsHistory{}, err
+		}
+		if value != "" {
+			query.Set(param.name, value)
+		}
+	}
+	if resolution != "" {
+		query.Set("resolution", resolution)
+	}
+
+	var resp containerStatsHistory
+	err := a.client.get(ctx, "/containers/"+url.PathEscape(name)+"/stats/history", query, &resp)
+	return resp, err
+}
+
+// printStatsHistory prints one container's stored stats, oldest first.
+func (a *app) printStatsHistory(ctx context.Context, containers []containerInfo, ref, host, since, until, resolution string) error {
+	resp, err := a.fetchStatsHistory(ctx, containers, ref, host, since, until, resolution)
+	if err != nil {
+		return err
+	}
+	if a.jsonOutput() {
+		return a.printJSON(resp)
+	}
+
+	rows := make([][]string, 0, len(resp.Points))
+	for _, p := range resp.Points {
+		rows = append(rows, []string{
+			time.UnixMilli(p.Timestamp).UTC().Format(time.RFC3339),
+			fmt.Sprintf("%.1f%%", p.CPUPercent),
+			fmt.Sprintf("%.1f%%", p.CPUPercentMax),
+			humanBytes(p.MemoryUsed),
+			humanBytes(p.MemoryUsedMax),
+			humanRate(p.NetworkRxPerSec),
+			humanRate(p.NetworkTxPerSec),
+			humanRate(p.BlockReadPerSec),
+			humanRate(p.BlockWritePerSec),
+		})
+	}
+	renderTable(os.Stdout, []string{"TIME", "CPU%", "CPU% MAX", "MEM", "MEM MAX", "NET RX", "NET TX", "BLK READ", "BLK WRITE"}, rows)
+	fmt.Fprintf(os.Stderr, "%d points for %s on %s at %s resolution\n",
+		len(resp.Points), resp.Container, resp.Host, resp.Resolution)
+	return nil
+}
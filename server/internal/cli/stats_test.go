@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestStatsHistoryRequest(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var query url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/containers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"containers":[{"id":"abc123","names":["/web"],"state":"running","host":"prod"}],"hostErrors":[]}`)
+	})
+	mux.HandleFunc("/api/v1/containers/web/stats/history", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, `{"host":"prod","container":"web","resolution":"1m","points":[
+			{"timestamp":1783684800000,"samples":4,"cpu_percent":12.5,"cpu_percent_max":40,
+			 "memory_used":1048576,"memory_used_max":2097152,"memory_limit":4194304,
+			 "network_rx_per_sec":2048,"network_tx_per_sec":0,"block_read_per_sec":0,"block_write_per_sec":512}]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var code int
+	out := captureStdout(t, func() {
+		code = execute(context.Background(), "test", []string{"stats", "abc", "--since", "6h", "--resolution", "1m", "--url", server.URL})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	if query.Get("host") != "prod" || query.Get("resolution") != "1m" || query.Get("since") == "" || query.Has("until") {
+		t.Errorf("query = %v, want host prod, resolution 1m, and a since", query)
+	}
+	for _, want := range []string{"2026-07-10T12:00:00Z", "12.5%", "40.0%", "2.0KiB/s", "512B/s"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestStatsHistoryFlagErrors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	for _, args := range [][]string{
+		{"stats", "--since", "1h"},
+		{"stats", "web", "--until", "1h"},
+		{"stats", "web", "--since", "1h", "--resolution", "5m"},
+		{"stats", "web", "--since", "yesterday"},
+		{"stats", "web", "--watch", "--since", "1h"},
+	} {
+		var code int
+		captureStderr(t, func() {
+			code = execute(context.Background(), "test", append(args, "--url", "http://127.0.0.1:1"))
+		})
+		if code != 2 {
+			t.Errorf("%v: exit code = %d, want 2 (usage error)", args, code)
+		}
+	}
+}
+
+func TestStatsShowsIORates(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/containers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"containers":[{"id":"abc123","names":["/web"],"state":"running","host":"prod"}],"hostErrors":[]}`)
+	})
+	mux.HandleFunc("/api/v1/containers/stats", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"stats":[{"id":"abc123","host":"prod","cpu_percent":5,"memory_percent":10,
+			"memory_used":1048576,"memory_limit":10485760,"network_rx_per_sec":3072,"network_tx_per_sec":100,
+			"block_read_per_sec":0,"block_write_per_sec":1048576,"pids":12}]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var code int
+	out := captureStdout(t, func() {
+		code = execute(context.Background(), "test", []string{"stats", "web", "--url", server.URL})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	for _, want := range []string{"NET RX", "PIDS", "3.0KiB/s", "100B/s", "1.0MiB/s", "12"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestStatsWatch(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var query url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/containers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"containers":[{"id":"abc123","names":["/web"],"state":"running","host":"prod"},
+			{"id":"def456","names":["/db"],"state":"running","host":"prod"}],"hostErrors":[]}`)
+	})
+	mux.HandleFunc("/api/v1/containers/stats/stream", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"id":"abc123","host":"prod","cpu_percent":5,"memory_used":1048576,"pids":3}`)
+		fmt.Fprintln(w, `{"id":"def456","host":"prod","cpu_percent":7.5,"memory_used":2097152,"pids":9}`)
+		fmt.Fprintln(w, `{"id":"abc123","host":"prod","removed":true}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var code int
+	out := captureStdout(t, func() {
+		code = execute(context.Background(), "test", []string{"stats", "--watch", "--host", "prod", "--url", server.URL})
+	})
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	if query.Get("host") != "prod" || query.Has("id") {
+		t.Errorf("query = %v, want host prod and no id", query)
+	}
+	// The stream ending forces a final redraw: db is shown, web is gone.
+	tables := strings.Split(strings.TrimSpace(out), "\n\n")
+	last := tables[len(tables)-1]
+	if !strings.Contains(last, "db") || !strings.Contains(last, "7.5%") || strings.Contains(last, "web") {
+		t.Errorf("final table should list only db:\n%s", out)
+	}
+
+	out = captureStdout(t, func() {
+		code = execute(context.Background(), "test", []string{"stats", "web", "--watch", "-o", "json", "--url", server.URL})
+	})
+	if code != 0 {
+		t.Fatalf("json exit code = %d, want 0", code)
+	}
+	if query.Get("host") != "prod" || query.Get("id") != "abc123" {
+		t.Errorf("query = %v, want host prod and id abc123", query)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || !strings.Contains(lines[2], `"removed":true`) {
+		t.Errorf("JSON output should pass stream lines through, got:\n%s", out)
+	}
+}
@@ -43,6 +43,11 @@ func humanBytes(n uint64) string {
 	return fmt.Sprintf("%.1fPiB", value/unit)
 }
 
+// humanRate renders a bytes-per-second rate.
+func humanRate(bytesPerSec float64) string {
+	return humanBytes(uint64(bytesPerSec)) + "/s"
+}
+
 // humanAge renders how long ago a time was, coarsely ("3d ago", "5m ago").
 func humanAge(t time.Time, now time.Time) string {
 	if t.IsZero() {
@@ -15,6 +15,9 @@ type containerInfo struct {
 	Status  string            `json:"status"`
 	Labels  map[string]string `json:"labels,omitempty"`
 	Host    string            `json:"host"`
+	// UpdateAvailable is set when the server's last image update check found
+	// a newer image behind the container's tag.
+	UpdateAvailable bool `json:"update_available,omitempty"`
 }
 
 type hostError struct {
@@ -29,12 +32,44 @@ type containersResponse struct {
 }
 
 type containerStats struct {
-	ID            string  `json:"id"`
-	Host          string  `json:"host"`
-	CPUPercent    float64 `json:"cpu_percent"`
-	MemoryPercent float64 `json:"memory_percent"`
-	MemoryUsed    uint64  `json:"memory_used"`
-	MemoryLimit   uint64  `json:"memory_limit"`
+	ID               string  `json:"id"`
+	Host             string  `json:"host"`
+	CPUPercent       float64 `json:"cpu_percent"`
+	MemoryPercent    float64 `json:"memory_percent"`
+	MemoryUsed       uint64  `json:"memory_used"`
+	MemoryLimit      uint64  `json:"memory_limit"`
+	MemoryCache      uint64  `json:"memory_cache"`
+	MemoryRSS        uint64  `json:"memory_rss"`
+	NetworkRxPerSec  float64 `json:"network_rx_per_sec"`
+	NetworkTxPerSec  float64 `json:"network_tx_per_sec"`
+	BlockReadPerSec  float64 `json:"block_read_per_sec"`
+	BlockWritePerSec float64 `json:"block_write_per_sec"`
+	PIDs             uint64  `json:"pids"`
+}
+
+// containerStatsEvent is one line of the /containers/stats/stream NDJSON.
+type containerStatsEvent struct {
+	containerStats
+	Removed bool `json:"removed,omitempty"`
+}
+
+type containerStatsHistory struct {
+	Host       string `json:"host"`
+	Container  string `json:"container"`
+	Resolution string `json:"resolution"`
+	Points     []struct {
+		Timestamp        int64   `json:"timestamp"`
+		Samples          int     `json:"samples"`
+		CPUPercent       float64 `json:"cpu_percent"`
+		CPUPercentMax    float64 `json:"cpu_percent_max"`
+		MemoryUsed       uint64  `json:"memory_used"`
+		MemoryUsedMax    uint64  `json:"memory_used_max"`
+		MemoryLimit      uint64  `json:"memory_limit"`
+		NetworkRxPerSec  float64 `json:"network_rx_per_sec"`
+		NetworkTxPerSec  float64 `json:"network_tx_per_sec"`
+		BlockReadPerSec  float64 `json:"block_read_per_sec"`
+		BlockWritePerSec float64 `json:"block_write_per_sec"`
+	} `json:"points"`
 }
 
 type hostInfo struct {
@@ -63,6 +98,7 @@ type logEntry struct {
 	ContinuationCount int               `json:"continuationCount,omitempty"`
 	ContainerID       string            `json:"containerId,omitempty"`
 	ContainerName     string            `json:"containerName,omitempty"`
+	Host              string            `json:"host,omitempty"`
 }
 
 type containerEvent struct {
@@ -81,6 +117,26 @@ type imageInfo struct {
 	Host     string   `json:"host"`
 }
 
+// imageUpdateStatus is one running container's result in an image update check.
+type imageUpdateStatus struct {
+	Host            string `json:"host"`
+	ContainerID     string `json:"container_id"`
+	ContainerName   string `json:"container_name"`
+	Image           string `json:"image"`
+	ImageID         string `json:"image_id"`
+	RemoteDigest    string `json:"remote_digest,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+	Error           string `json:"error,omitempty"`
+	CheckedAt       int64  `json:"checked_at"`
+}
+
+type imageUpdatesResponse struct {
+	Enabled   bool                `json:"enabled"`
+	LastCheck int64               `json:"last_check"`
+	Checking  bool                `json:"checking"`
+	Updates   []imageUpdateStatus `json:"updates"`
+}
+
 type volumeInfo struct {
 	Name       string            `json:"name"`
 	Driver     string            `json:"driver"`
@@ -129,3 +185,12 @@ type composeResult struct {
 	Succeeded int              `json:"succeeded"`
 	Failed    []composeFailure `json:"failed"`
 }
+
+// composeDeployEvent is one progress line of a stack pull/up/down.
+type composeDeployEvent struct {
+	Service string `json:"service,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Status  string `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
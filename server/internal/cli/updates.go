@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// updateCheckPoll is how often `updates --check` polls for the result.
+var updateCheckPoll = 2 * time.Second
+
+func newUpdatesCmd(a *app) *cobra.Command {
+	var host string
+	var all, check bool
+
+	cmd := &cobra.Command{
+		Use:   "updates",
+		Short: "List running containers whose image has a newer version upstream",
+		Long: `List running containers whose image tag now points to a newer image in its
+registry (a new nginx:latest, say). The server checks every few hours; --check
+starts a check now and waits for it. Use "logdeck update <container>" to pull
+the new image and recreate the container on it.`,
+		Example: `  logdeck updates
+  logdeck updates --check
+  logdeck updates --all --host prod`,
+		Args: cobra.NoArgs,
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			var resp imageUpdatesResponse
+			if err := a.client.get(ctx, "/images/updates", nil, &resp); err != nil {
+				return err
+			}
+			if check {
+				var err error
+				if resp, err = a.checkImageUpdates(ctx, resp.LastCheck); err != nil {
+					return err
+				}
+			}
+
+			filtered := make([]imageUpdateStatus, 0, len(resp.Updates))
+			for _, u := range resp.Updates {
+				if host != "" && u.Host != host {
+					continue
+				}
+				if !all && !u.UpdateAvailable {
+					continue
+				}
+				filtered = append(filtered, u)
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(map[string]any{
+					"enabled":   resp.Enabled,
+					"lastCheck": resp.LastCheck,
+					"updates":   filtered,
+				})
+			}
+
+			if !resp.Enabled {
+				fmt.Fprintln(os.Stderr, "warning: image update checks are disabled on the server")
+			} else if resp.LastCheck == 0 {
+				fmt.Fprintln(os.Stderr, "no check has finished yet; run logdeck updates --check")
+			}
+			now := time.Now()
+			rows := make([][]string, 0, len(filtered))
+			for _, u := range filtered {
+				status := "up to date"
+				switch {
+				case u.Error != "":
+					status = "unknown: " + u.Error
+				case u.UpdateAvailable:
+					status = "update available"
+				}
+				rows = append(rows, []string{u.ContainerName, u.Image, u.Host, status, humanAge(time.UnixMilli(u.CheckedAt), now)})
+			}
+			renderTable(os.Stdout, []string{"NAME", "IMAGE", "HOST", "STATUS", "CHECKED"}, rows)
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringVar(&host, "host", "", "filter by host name")
+	cmd.Flags().BoolVar(&all, "all", false, "show every checked container, not only those with an update")
+	cmd.Flags().BoolVar(&check, "check", false, "check the registries now and wait for the result")
+	return cmd
+}
+
+// checkImageUpdates starts a check on the server and polls until one that
+// finished after previous (the last check before this one) is reported.
+func (a *app) checkImageUpdates(ctx context.Context, previous int64) (imageUpdatesResponse, error) {
+	if err := a.client.post(ctx, "/images/updates/check", nil, nil, nil); err != nil {
+		return imageUpdatesResponse{}, err
+	}
+	ticker := time.NewTicker(updateCheckPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return imageUpdatesResponse{}, ctx.Err()
+		case <-ticker.C:
+		}
+		var resp imageUpdatesResponse
+		if err := a.client.get(ctx, "/images/updates", nil, &resp); err != nil {
+			return imageUpdatesResponse{}, err
+		}
+		if !resp.Checking && resp.LastCheck > previous {
+			return resp, nil
+		}
+	}
+}
+
+func newUpdateCmd(a *app) *cobra.Command {
+	var host string
+
+	cmd := &cobra.Command{
+		Use:   "update <name|id>",
+		Short: "Pull a container's image and recreate it if a newer one arrived",
+		Long: `Pull the tag a container was created from and, if that brought a newer image,
+recreate the container on it with its current configuration. The container
+restarts and gets a new ID; if it was stopped it stays stopped. A container
+already on the latest image is left alone. Containers created from a digest
+or an image ID cannot be updated this way.`,
+		Args: cobra.ExactArgs(1),
+		RunE: a.run(func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			container, err := a.resolve(ctx, args[0], host)
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				Message        string `json:"message"`
+				Recreated      bool   `json:"recreated"`
+				NewContainerID string `json:"new_container_id"`
+			}
+			query := url.Values{"host": {container.Host}}
+			if err := a.client.postSlow(ctx, "/containers/"+container.ID+"/update-image", query, nil, &resp); err != nil {
+				return err
+			}
+
+			if a.jsonOutput() {
+				return a.printJSON(map[string]any{
+					"message":   resp.Message,
+					"recreated": resp.Recreated,
+					"name":      containerName(container),
+					"id":        resp.NewContainerID,
+					"host":      container.Host,
+				})
+			}
+			if !resp.Recreated {
+				fmt.Printf("%s is already running the latest image (host %s)\n", containerName(container), container.Host)
+				return nil
+			}
+			fmt.Printf("updated %s (host %s), new ID %s\n", containerName(container), container.Host, shortID(resp.NewContainerID))
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringVar(&host, "host", "", "host name (disambiguates duplicate container names)")
+	return cmd
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const updatesJSON = `{"enabled":true,"last_check":%d,"checking":%t,"updates":[
+	{"host":"prod","container_id":"abc","container_name":"web","image":"nginx:latest","update_available":true,"checked_at":%[1]d},
+	{"host":"prod","container_id":"def","container_name":"db","image":"postgres:16","update_available":false,"checked_at":%[1]d},
+	{"host":"prod","container_id":"ghi","container_name":"app","image":"myapp:dev","update_available":false,"error":"the local image has no registry digest (built locally?)","checked_at":%[1]d}]}`
+
+func TestUpdatesListsAvailableUpdates(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/images/updates", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, updatesJSON, time.Now().UnixMilli(), false)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	out := captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"updates", "--url", server.URL}); code != 0 {
+			t.Fatalf("exit code = %d, want 0", code)
+		}
+	})
+	if !strings.Contains(out, "web") || !strings.Contains(out, "update available") || strings.Contains(out, "db") {
+		t.Errorf("output should list only web:\n%s", out)
+	}
+
+	out = captureStdout(t, func() {
+		execute(context.Background(), "test", []string{"updates", "--all", "--url", server.URL})
+	})
+	for _, want := range []string{"db", "up to date", "unknown: the local image has no registry digest"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("--all output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestUpdatesCheckWaitsForNewResult(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	orig := updateCheckPoll
+	updateCheckPoll = 10 * time.Millisecond
+	t.Cleanup(func() { updateCheckPoll = orig })
+
+	var started, polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/images/updates/check", func(w http.ResponseWriter, r *http.Request) {
+		started.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"message":"Image update check started"}`)
+	})
+	mux.HandleFunc("GET /api/v1/images/updates", func(w http.ResponseWriter, r *http.Request) {
+		// The old result, then a check in progress, then the new result.
+		switch n := polls.Add(1); {
+		case n == 1:
+			fmt.Fprintf(w, updatesJSON, 1000, false)
+		case n == 2:
+			fmt.Fprintf(w, updatesJSON, 1000, true)
+		default:
+			fmt.Fprintf(w, updatesJSON, 2000, false)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	out := captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"updates", "--check", "-o", "json", "--url", server.URL}); code != 0 {
+			t.Fatalf("exit code = %d, want 0", code)
+		}
+	})
+	if started.Load() != 1 || polls.Load() < 3 || !strings.Contains(out, `"lastCheck": 2000`) {
+		t.Errorf("check started %d times, %d polls, output:\n%s", started.Load(), polls.Load(), out)
+	}
+}
+
+func TestUpdateRecreatesContainer(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var host string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/containers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"containers":[{"id":"abc123","names":["/web"],"state":"running","host":"prod"}],"hostErrors":[]}`)
+	})
+	mux.HandleFunc("POST /api/v1/containers/abc123/update-image", func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Query().Get("host")
+		fmt.Fprint(w, `{"message":"Container updated to the latest image","recreated":true,"new_container_id":"fedcba9876543210"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	out := captureStdout(t, func() {
+		if code := execute(context.Background(), "test", []string{"update", "web", "--url", server.URL}); code != 0 {
+			t.Fatalf("exit code = %d, want 0", code)
+		}
+	})
+	if host != "prod" || !strings.Contains(out, "updated web (host prod), new ID fedcba987654") {
+		t.Errorf("host = %q, output:\n%s", host, out)
+	}
+}
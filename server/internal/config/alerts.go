@@ -8,7 +8,8 @@ import (
 )
 
 // AlertsConfig holds the alerting settings persisted in the config file:
-// the notification channels and the list of alert rules.
+// the notification channels, the list of alert rules, and the silences and
+// maintenance windows that mute them.
 type AlertsConfig struct {
 	// WebhookURL is the pre-channels single-webhook field. It is migrated into
 	// a webhook channel on load and cleared; it remains only so old configs
@@ -16,25 +17,104 @@ type AlertsConfig struct {
 	WebhookURL string         `json:"webhookUrl,omitempty"`
 	Channels   []AlertChannel `json:"channels,omitempty"`
 	Rules      []AlertRule    `json:"rules,omitempty"`
+
+	Silences           []AlertSilence      `json:"silences,omitempty"`
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// History bounds how long resolved incidents are kept; nil keeps the
+	// defaults.
+	History *AlertHistoryConfig `json:"history,omitempty"`
+	// Delivery bounds how long a failing notification is retried; nil keeps
+	// the default.
+	Delivery *AlertDeliveryConfig `json:"delivery,omitempty"`
+}
+
+// Alert history retention defaults, applied when AlertHistoryConfig leaves a
+// bound at zero.
+const (
+	DefaultAlertHistoryRetentionDays = 90
+	DefaultAlertHistoryMaxEntries    = 10000
+)
+
+// AlertHistoryConfig bounds the alert history. Resolved incidents older than
+// RetentionDays, or beyond the newest MaxEntries, are deleted; firing
+// incidents are always kept. Zero means the default.
+type AlertHistoryConfig struct {
+	RetentionDays int `json:"retentionDays,omitempty"`
+	MaxEntries    int `json:"maxEntries,omitempty"`
+}
+
+// HistoryRetention returns the effective history bounds, with defaults
+// applied.
+func (c AlertsConfig) HistoryRetention() AlertHistoryConfig {
+	resolved := AlertHistoryConfig{
+		RetentionDays: DefaultAlertHistoryRetentionDays,
+		MaxEntries:    DefaultAlertHistoryMaxEntries,
+	}
+	if c.History != nil {
+		if c.History.RetentionDays > 0 {
+			resolved.RetentionDays = c.History.RetentionDays
+		}
+		if c.History.MaxEntries > 0 {
+			resolved.MaxEntries = c.History.MaxEntries
+		}
+	}
+	return resolved
+}
+
+// DefaultAlertDeliveryDeadlineSeconds is how long a notification is retried
+// when AlertDeliveryConfig leaves the deadline at zero.
+const DefaultAlertDeliveryDeadlineSeconds = 3600
+
+// AlertDeliveryConfig bounds notification retries. A notification its channel
+// has not accepted DeadlineSeconds after it was queued is dead-lettered: given
+// up on and recorded as failed. Zero means the default.
+type AlertDeliveryConfig struct {
+	DeadlineSeconds int `json:"deadlineSeconds,omitempty"`
+}
+
+// DeliveryDeadline returns the effective delivery deadline.
+func (c AlertsConfig) DeliveryDeadline() time.Duration {
+	if c.Delivery != nil && c.Delivery.DeadlineSeconds > 0 {
+		return time.Duration(c.Delivery.DeadlineSeconds) * time.Second
+	}
+	return DefaultAlertDeliveryDeadlineSeconds * time.Second
 }
 
 // AlertChannel is one notification destination. A fired alert is delivered to
-// every enabled channel.
+// the enabled channels its rule routes it to, or to every enabled channel when
+// the rule names none.
 type AlertChannel struct {
 	ID      string `json:"id"`
-	Type    string `json:"type"` // "webhook" | "ntfy" | "gotify" | "telegram"
+	Type    string `json:"type"` // "webhook" | "ntfy" | "gotify" | "telegram" | "smtp" | "pagerduty" | "matrix" | "teams"
 	Name    string `json:"name,omitempty"`
 	Enabled bool   `json:"enabled"`
-	URL     string `json:"url,omitempty"`    // webhook: full URL; ntfy: topic URL; gotify: server base URL
-	Token   string `json:"token,omitempty"`  // gotify: app token; telegram: bot token
-	Target  string `json:"target,omitempty"` // telegram: chat_id
+	// URL is the webhook or Teams workflow URL, the ntfy topic URL, the
+	// Gotify server or Matrix homeserver base URL, smtp://host[:port] for
+	// email, or an optional Events API override for PagerDuty.
+	URL string `json:"url,omitempty"`
+	// Token is the Gotify app token, Telegram bot token, SMTP password,
+	// PagerDuty routing key, or Matrix access token.
+	Token string `json:"token,omitempty"`
+	// Target is the Telegram chat_id, the Matrix room ID, or the email
+	// recipients, comma-separated.
+	Target string `json:"target,omitempty"`
+	// Username and From are the SMTP login and sender address.
+	Username string `json:"username,omitempty"`
+	From     string `json:"from,omitempty"`
+
+	// TitleTemplate and BodyTemplate are optional Go text/template sources
+	// that replace the default notification title (ntfy, gotify) and text.
+	// A webhook's body template renders the whole JSON request body.
+	TitleTemplate string `json:"titleTemplate,omitempty"`
+	BodyTemplate  string `json:"bodyTemplate,omitempty"`
 }
 
 type AlertRule struct {
 	ID      string `json:"id"`
 	Name    string `json:"name"`
 	Enabled bool   `json:"enabled"`
-	Type    string `json:"type"` // "event" | "log"
+	Type    string `json:"type"` // "event" | "log" | "metric"
 
 	// Targeting: all optional, empty = match-all in that dimension, ANDed.
 	Hosts      []string `json:"hosts,omitempty"`
@@ -42,17 +122,82 @@ type AlertRule struct {
 	Projects   []string `json:"projects,omitempty"`   // compose projects
 
 	// Event rules.
-	Events []string `json:"events,omitempty"` // "die" | "oom" | "unhealthy"
+	// "restart_loop" fires when a container restarts Threshold times (default
+	// 3) within the window, and must be a rule's only event.
+	Events []string `json:"events,omitempty"` // "die" | "oom" | "unhealthy" | "restart_loop"
 
-	// Log rules.
+	// Log rules. Mode "absence" inverts a log rule: it fires when fewer than
+	// Threshold matching lines arrive from a container within the window,
+	// rather than when Threshold of them do.
 	MinLevel string `json:"minLevel,omitempty"`
 	Pattern  string `json:"pattern,omitempty"` // RE2
+	Mode     string `json:"mode,omitempty"`    // "" | "absence"
+
+	// Metric rules: Metric compared against Value with Operator, either on
+	// every sample for the whole window ("sustained") or on the window's
+	// average ("avg").
+	Metric    string  `json:"metric,omitempty"`   // "cpu_percent" | "memory_percent"
+	Operator  string  `json:"operator,omitempty"` // ">" | "<"
+	Value     float64 `json:"value,omitempty"`
+	Aggregate string  `json:"aggregate,omitempty"` // "sustained" | "avg"
+
+	// Rate + cooldown (all rule types; metric rules ignore Threshold).
+	Threshold       int `json:"threshold"`
+	WindowSeconds   int `json:"windowSeconds,omitempty"`
+	CooldownSeconds int `json:"cooldownSeconds,omitempty"`
+
+	// Routing. Severity ("" means "warning") sets the notification priority.
+	// An alert goes to the channels of the first route matching its
+	// container, else to Channels, else to every enabled channel.
+	Severity  string       `json:"severity,omitempty"` // "info" | "warning" | "critical"
+	Channels  []string     `json:"channels,omitempty"` // channel IDs
+	Routes    []AlertRoute `json:"routes,omitempty"`
+	CreatedAt string       `json:"createdAt"`
+}
+
+// AlertRoute sends a rule's alerts for matching containers to specific
+// channels. Its targeting works like a rule's: empty dimensions match all,
+// and they are ANDed.
+type AlertRoute struct {
+	Hosts      []string `json:"hosts,omitempty"`
+	Containers []string `json:"containers,omitempty"`
+	Projects   []string `json:"projects,omitempty"`
+	Channels   []string `json:"channels"` // channel IDs
+}
+
+// AlertSilence mutes the alerts it matches from StartsAt until EndsAt: they
+// are still recorded in history, but not notified. Its targeting works like a
+// rule's (empty dimensions match all, ANDed), with Rules narrowing it to
+// specific rule IDs.
+type AlertSilence struct {
+	ID         string   `json:"id"`
+	Hosts      []string `json:"hosts,omitempty"`
+	Containers []string `json:"containers,omitempty"`
+	Projects   []string `json:"projects,omitempty"`
+	Rules      []string `json:"rules,omitempty"` // rule IDs
+	StartsAt   string   `json:"startsAt"`        // RFC 3339
+	EndsAt     string   `json:"endsAt"`          // RFC 3339
+	Comment    string   `json:"comment,omitempty"`
+	CreatedBy  string   `json:"createdBy,omitempty"`
+	CreatedAt  string   `json:"createdAt"`
+}
 
-	// Rate + cooldown (both rule types).
-	Threshold       int    `json:"threshold"`
-	WindowSeconds   int    `json:"windowSeconds,omitempty"`
-	CooldownSeconds int    `json:"cooldownSeconds,omitempty"`
-	CreatedAt       string `json:"createdAt"`
+// MaintenanceWindow is a recurring silence: it mutes the alerts it matches
+// for DurationSeconds from every time its cron Schedule ("minute hour
+// day-of-month month day-of-week") fires, evaluated in Timezone (UTC when
+// empty). Its targeting works like a silence's.
+type MaintenanceWindow struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Enabled         bool     `json:"enabled"`
+	Schedule        string   `json:"schedule"`
+	DurationSeconds int      `json:"durationSeconds"`
+	Timezone        string   `json:"timezone,omitempty"` // IANA name, e.g. "Europe/Berlin"
+	Hosts           []string `json:"hosts,omitempty"`
+	Containers      []string `json:"containers,omitempty"`
+	Projects        []string `json:"projects,omitempty"`
+	Rules           []string `json:"rules,omitempty"` // rule IDs
+	CreatedAt       string   `json:"createdAt"`
 }
 
 // migrateAlertChannels folds a legacy single WebhookURL into a webhook channel
@@ -97,6 +242,18 @@ func (m *Manager) UpdateAlerts(mutate func(current AlertsConfig) (AlertsConfig,
 		copy(current.Channels, m.fileConfig.Alerts.Channels)
 		current.Rules = make([]AlertRule, len(m.fileConfig.Alerts.Rules))
 		copy(current.Rules, m.fileConfig.Alerts.Rules)
+		current.Silences = make([]AlertSilence, len(m.fileConfig.Alerts.Silences))
+		copy(current.Silences, m.fileConfig.Alerts.Silences)
+		current.MaintenanceWindows = make([]MaintenanceWindow, len(m.fileConfig.Alerts.MaintenanceWindows))
+		copy(current.MaintenanceWindows, m.fileConfig.Alerts.MaintenanceWindows)
+		if m.fileConfig.Alerts.History != nil {
+			history := *m.fileConfig.Alerts.History
+			current.History = &history
+		}
+		if m.fileConfig.Alerts.Delivery != nil {
+			delivery := *m.fileConfig.Alerts.Delivery
+			current.Delivery = &delivery
+		}
 	}
 
 	updated, err := mutate(current)
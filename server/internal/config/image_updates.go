@@ -0,0 +1,61 @@
+package config
+
+import "log"
+
+// DefaultImageUpdatesIntervalHours is how often running containers' images are
+// checked against their registries. Each check is one manifest request per
+// distinct image, so a few times a day stays well inside registry rate limits.
+const DefaultImageUpdatesIntervalHours = 6
+
+// ImageUpdatesConfig holds the image update check settings. Persisted in the
+// config file under "imageUpdates"; both fields can be overridden by an
+// environment variable.
+type ImageUpdatesConfig struct {
+	Enabled       *bool `json:"enabled,omitempty"`
+	IntervalHours *int  `json:"intervalHours,omitempty"`
+}
+
+// ResolvedImageUpdatesConfig is the effective image update check
+// configuration after the env-over-file merge, with defaults applied.
+type ResolvedImageUpdatesConfig struct {
+	Enabled       bool `json:"enabled"`
+	IntervalHours int  `json:"intervalHours"`
+}
+
+// ImageUpdates returns the effective image update check settings:
+// environment variables win over the config file, which wins over the
+// defaults (disabled, every 6 hours). Checks are opt-in: each one asks every
+// image's registry for its tag, which an operator should choose to do.
+func (m *Manager) ImageUpdates() ResolvedImageUpdatesConfig {
+	m.mu.RLock()
+	file := m.fileConfig.ImageUpdates
+	m.mu.RUnlock()
+
+	resolved := ResolvedImageUpdatesConfig{
+		Enabled:       false,
+		IntervalHours: DefaultImageUpdatesIntervalHours,
+	}
+
+	if file != nil {
+		if file.Enabled != nil {
+			resolved.Enabled = *file.Enabled
+		}
+		if file.IntervalHours != nil {
+			// Zero would check in a tight loop, so it falls back to the default.
+			if *file.IntervalHours <= 0 {
+				log.Printf("Warning: ignoring imageUpdates.intervalHours=%d (expected a positive integer), using %d", *file.IntervalHours, resolved.IntervalHours)
+			} else {
+				resolved.IntervalHours = *file.IntervalHours
+			}
+		}
+	}
+
+	if v, ok := envBool("IMAGE_UPDATES_ENABLED"); ok {
+		resolved.Enabled = v
+	}
+	if v, ok := envPositiveInt("IMAGE_UPDATES_INTERVAL_HOURS"); ok {
+		resolved.IntervalHours = v
+	}
+
+	return resolved
+}
@@ -0,0 +1,36 @@
+package config
+
+import "testing"
+
+func TestImageUpdatesDefaults(t *testing.T) {
+	t.Setenv("IMAGE_UPDATES_ENABLED", "")
+	t.Setenv("IMAGE_UPDATES_INTERVAL_HOURS", "")
+	manager := writeLogStoreConfig(t, FileConfig{})
+
+	got := manager.ImageUpdates()
+	want := ResolvedImageUpdatesConfig{Enabled: false, IntervalHours: DefaultImageUpdatesIntervalHours}
+	if got != want {
+		t.Fatalf("ImageUpdates() = %+v, want %+v", got, want)
+	}
+}
+
+func TestImageUpdatesFileConfigAndEnv(t *testing.T) {
+	t.Setenv("IMAGE_UPDATES_ENABLED", "")
+	t.Setenv("IMAGE_UPDATES_INTERVAL_HOURS", "")
+	enabled, zero := true, 0
+	manager := writeLogStoreConfig(t, FileConfig{
+		ImageUpdates: &ImageUpdatesConfig{Enabled: &enabled, IntervalHours: &zero},
+	})
+
+	got := manager.ImageUpdates()
+	if !got.Enabled || got.IntervalHours != DefaultImageUpdatesIntervalHours {
+		t.Fatalf("ImageUpdates() = %+v, want the file's enabled and the default interval", got)
+	}
+
+	t.Setenv("IMAGE_UPDATES_ENABLED", "false")
+	t.Setenv("IMAGE_UPDATES_INTERVAL_HOURS", "24")
+	got = manager.ImageUpdates()
+	if got.Enabled || got.IntervalHours != 24 {
+		t.Fatalf("ImageUpdates() = %+v, want the env's disabled and 24h", got)
+	}
+}
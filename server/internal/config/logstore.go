@@ -1,9 +1,15 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 // Default log store limits. Retention is generous enough to be useful out of
@@ -11,6 +17,10 @@ import (
 const (
 	DefaultLogStorePerContainerMB = 50
 	DefaultLogStoreTotalMB        = 1024
+	// MaxIndexedFields caps logStore.indexedFields. Every indexed key adds a row
+	// per line that carries it, so the list is meant for a handful of
+	// high-value attributes rather than every key a service logs.
+	MaxIndexedFields = 16
 )
 
 // LogStoreConfig holds the log persistence settings. Persisted in the config
@@ -20,19 +30,112 @@ type LogStoreConfig struct {
 	Enabled        *bool `json:"enabled,omitempty"`
 	PerContainerMB *int  `json:"perContainerMB,omitempty"`
 	TotalMB        *int  `json:"totalMB,omitempty"`
+	FullTextIndex  *bool `json:"fullTextIndex,omitempty"`
+	// IndexedFields names the structured (JSON or logfmt) keys the store
+	// indexes for field filters, e.g. "status" or "http.path".
+	IndexedFields []string `json:"indexedFields,omitempty"`
+	// Archive configures the cold tier evicted lines are written to.
+	Archive *LogStoreArchiveConfig `json:"archive,omitempty"`
+	// RetentionRules age stored lines out by container and level, on top of
+	// the size caps.
+	RetentionRules []RetentionRule `json:"retentionRules,omitempty"`
+	// IngestionRules keep lines out of the store before they are written.
+	IngestionRules []IngestionRule `json:"ingestionRules,omitempty"`
+}
+
+// RetentionRule bounds how long the stored lines of the containers it targets
+// are kept. Rules are ordered and the first one matching a line decides its
+// age limit, so a narrow rule ("payments: 14 days") goes before the catch-all
+// it overrides ("everything: 2 days"). A rule with Levels only matches lines
+// of those levels, which is how debug output gets a shorter life than the
+// rest of its container.
+type RetentionRule struct {
+	// Targeting, as alert rules do it: all optional, empty = match-all in that
+	// dimension; hosts are ANDed with names-or-projects.
+	Hosts      []string `json:"hosts,omitempty"`
+	Containers []string `json:"containers,omitempty"` // exact container names
+	Projects   []string `json:"projects,omitempty"`   // compose projects
+
+	Levels      []string `json:"levels,omitempty"` // e.g. "DEBUG"; empty = every level
+	MaxAgeHours int      `json:"maxAgeHours"`
+}
+
+// Ingestion rule actions.
+const (
+	IngestionExclude = "exclude" // store nothing for the container
+	IngestionDrop    = "drop"    // skip lines whose message matches Pattern
+	IngestionSample  = "sample"  // keep SamplePercent of INFO-and-below entries
+)
+
+// IngestionRule filters what the store records for the containers it targets.
+// Every matching rule applies: a container is excluded if any rule excludes
+// it, a line is dropped if any pattern matches it, and the lowest sample rate
+// wins. Lines above INFO are never sampled away.
+type IngestionRule struct {
+	// Targeting: all optional, empty = match-all in that dimension. Hosts are
+	// ANDed with the rest, which is an OR between names, projects, and labels.
+	Hosts      []string `json:"hosts,omitempty"`
+	Containers []string `json:"containers,omitempty"` // exact container names
+	Projects   []string `json:"projects,omitempty"`   // compose projects
+	Labels     []string `json:"labels,omitempty"`     // "key" or "key=value"
+
+	Action        string `json:"action"`                  // "exclude" | "drop" | "sample"
+	Pattern       string `json:"pattern,omitempty"`       // drop: RE2 matched against the message
+	SamplePercent int    `json:"samplePercent,omitempty"` // sample: 0-100
+}
+
+// LogStoreArchiveConfig configures the cold tier: when enabled, retention writes
+// the lines it evicts to compressed files instead of discarding them. Files go
+// to Dir, or to an S3-compatible bucket when S3 names an endpoint and a bucket.
+type LogStoreArchiveConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	// Dir defaults to an "archive" directory next to logs.db.
+	Dir string           `json:"dir,omitempty"`
+	S3  *S3ArchiveConfig `json:"s3,omitempty"`
+}
+
+// S3ArchiveConfig locates an S3-compatible bucket (AWS S3, MinIO, ...).
+// Requests are path-style, so any endpoint that serves /bucket/key works.
+type S3ArchiveConfig struct {
+	Endpoint        string `json:"endpoint,omitempty"` // e.g. http://minio:9000
+	Bucket          string `json:"bucket,omitempty"`
+	Region          string `json:"region,omitempty"` // default us-east-1
+	Prefix          string `json:"prefix,omitempty"` // key prefix inside the bucket
+	AccessKeyID     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+}
+
+// Configured reports whether the bucket is usable: an endpoint and a bucket.
+func (c S3ArchiveConfig) Configured() bool {
+	return c.Endpoint != "" && c.Bucket != ""
 }
 
 // ResolvedLogStoreConfig is the effective log store configuration after the
 // env-over-file merge, with defaults applied.
 type ResolvedLogStoreConfig struct {
-	Enabled        bool `json:"enabled"`
-	PerContainerMB int  `json:"perContainerMB"`
-	TotalMB        int  `json:"totalMB"`
+	Enabled        bool            `json:"enabled"`
+	PerContainerMB int             `json:"perContainerMB"`
+	TotalMB        int             `json:"totalMB"`
+	FullTextIndex  bool            `json:"fullTextIndex"`
+	IndexedFields  []string        `json:"indexedFields"`
+	RetentionRules []RetentionRule `json:"retentionRules"`
+	IngestionRules []IngestionRule `json:"ingestionRules"`
+	// Archive carries the bucket credentials, so it is never serialized.
+	Archive ResolvedLogStoreArchive `json:"-"`
+}
+
+// ResolvedLogStoreArchive is the effective cold tier configuration. Enabled
+// only governs writing: archived files stay readable after it is turned off.
+type ResolvedLogStoreArchive struct {
+	Enabled bool
+	Dir     string // empty means the default, next to logs.db
+	S3      S3ArchiveConfig
 }
 
 // LogStore returns the effective log store settings: environment variables
 // win over the config file, which wins over the defaults (enabled, 50 MB per
-// container, 1024 MB total).
+// container, 1024 MB total, no full-text index, no indexed fields, no
+// retention or ingestion rules).
 func (m *Manager) LogStore() ResolvedLogStoreConfig {
 	m.mu.RLock()
 	file := m.fileConfig.LogStore
@@ -42,6 +145,9 @@ func (m *Manager) LogStore() ResolvedLogStoreConfig {
 		Enabled:        true,
 		PerContainerMB: DefaultLogStorePerContainerMB,
 		TotalMB:        DefaultLogStoreTotalMB,
+		IndexedFields:  []string{},
+		RetentionRules: []RetentionRule{},
+		IngestionRules: []IngestionRule{},
 	}
 
 	if file != nil {
@@ -55,6 +161,27 @@ func (m *Manager) LogStore() ResolvedLogStoreConfig {
 		if file.TotalMB != nil {
 			resolved.TotalMB = positiveMB("logStore.totalMB", *file.TotalMB, DefaultLogStoreTotalMB)
 		}
+		if file.FullTextIndex != nil {
+			resolved.FullTextIndex = *file.FullTextIndex
+		}
+		if file.IndexedFields != nil {
+			resolved.IndexedFields = indexedFieldList("logStore.indexedFields", file.IndexedFields)
+		}
+		if file.RetentionRules != nil {
+			resolved.RetentionRules = retentionRuleList("logStore.retentionRules", file.RetentionRules)
+		}
+		if file.IngestionRules != nil {
+			resolved.IngestionRules = ingestionRuleList("logStore.ingestionRules", file.IngestionRules)
+		}
+		if archive := file.Archive; archive != nil {
+			if archive.Enabled != nil {
+				resolved.Archive.Enabled = *archive.Enabled
+			}
+			resolved.Archive.Dir = archive.Dir
+			if archive.S3 != nil {
+				resolved.Archive.S3 = *archive.S3
+			}
+		}
 	}
 
 	if v, ok := envBool("LOG_STORE_ENABLED"); ok {
@@ -66,17 +193,49 @@ func (m *Manager) LogStore() ResolvedLogStoreConfig {
 	if v, ok := envPositiveInt("LOG_STORE_TOTAL_MB"); ok {
 		resolved.TotalMB = v
 	}
+	if v, ok := envBool("LOG_STORE_FULL_TEXT_INDEX"); ok {
+		resolved.FullTextIndex = v
+	}
+	if v, ok := envList("LOG_STORE_INDEXED_FIELDS"); ok {
+		resolved.IndexedFields = indexedFieldList("LOG_STORE_INDEXED_FIELDS", v)
+	}
+	if v, ok := envRules[RetentionRule]("LOG_STORE_RETENTION_RULES"); ok {
+		resolved.RetentionRules = retentionRuleList("LOG_STORE_RETENTION_RULES", v)
+	}
+	if v, ok := envRules[IngestionRule]("LOG_STORE_INGESTION_RULES"); ok {
+		resolved.IngestionRules = ingestionRuleList("LOG_STORE_INGESTION_RULES", v)
+	}
+	if v, ok := envBool("LOG_STORE_ARCHIVE_ENABLED"); ok {
+		resolved.Archive.Enabled = v
+	}
+	for key, field := range map[string]*string{
+		"LOG_STORE_ARCHIVE_DIR":                  &resolved.Archive.Dir,
+		"LOG_STORE_ARCHIVE_S3_ENDPOINT":          &resolved.Archive.S3.Endpoint,
+		"LOG_STORE_ARCHIVE_S3_BUCKET":            &resolved.Archive.S3.Bucket,
+		"LOG_STORE_ARCHIVE_S3_REGION":            &resolved.Archive.S3.Region,
+		"LOG_STORE_ARCHIVE_S3_PREFIX":            &resolved.Archive.S3.Prefix,
+		"LOG_STORE_ARCHIVE_S3_ACCESS_KEY_ID":     &resolved.Archive.S3.AccessKeyID,
+		"LOG_STORE_ARCHIVE_S3_SECRET_ACCESS_KEY": &resolved.Archive.S3.SecretAccessKey,
+	} {
+		if v := os.Getenv(key); v != "" {
+			*field = v
+		}
+	}
 
 	return resolved
 }
 
 // LogStoreSources tracks where each effective log store value came from. The
-// three fields are overridden independently, so unlike the other categories
+// fields are overridden independently, so unlike the other categories
 // they carry a source each rather than one for the whole section.
 type LogStoreSources struct {
 	Enabled        Source `json:"enabled"`
 	PerContainerMB Source `json:"perContainerMB"`
 	TotalMB        Source `json:"totalMB"`
+	FullTextIndex  Source `json:"fullTextIndex"`
+	IndexedFields  Source `json:"indexedFields"`
+	RetentionRules Source `json:"retentionRules"`
+	IngestionRules Source `json:"ingestionRules"`
 }
 
 // LogStoreSources reports which log store values are pinned by an environment
@@ -89,6 +248,10 @@ func (m *Manager) LogStoreSources() LogStoreSources {
 		Enabled:        SourceFile,
 		PerContainerMB: SourceFile,
 		TotalMB:        SourceFile,
+		FullTextIndex:  SourceFile,
+		IndexedFields:  SourceFile,
+		RetentionRules: SourceFile,
+		IngestionRules: SourceFile,
 	}
 	if _, ok := envBool("LOG_STORE_ENABLED"); ok {
 		sources.Enabled = SourceEnv
@@ -99,6 +262,18 @@ func (m *Manager) LogStoreSources() LogStoreSources {
 	if _, ok := envPositiveInt("LOG_STORE_TOTAL_MB"); ok {
 		sources.TotalMB = SourceEnv
 	}
+	if _, ok := envBool("LOG_STORE_FULL_TEXT_INDEX"); ok {
+		sources.FullTextIndex = SourceEnv
+	}
+	if _, ok := envList("LOG_STORE_INDEXED_FIELDS"); ok {
+		sources.IndexedFields = SourceEnv
+	}
+	if _, ok := envRules[RetentionRule]("LOG_STORE_RETENTION_RULES"); ok {
+		sources.RetentionRules = SourceEnv
+	}
+	if _, ok := envRules[IngestionRule]("LOG_STORE_INGESTION_RULES"); ok {
+		sources.IngestionRules = SourceEnv
+	}
 	return sources
 }
 
@@ -139,6 +314,121 @@ func positiveMB(field string, value, fallback int) int {
 	return value
 }
 
+// indexedFieldList trims the configured field keys and drops blanks and
+// duplicates, keeping the first MaxIndexedFields. Key syntax is checked where a
+// user edits the list (the settings API); a key no filter can name is harmless
+// here, it is simply never looked up.
+func indexedFieldList(field string, keys []string) []string {
+	list := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key != "" && !slices.Contains(list, key) {
+			list = append(list, key)
+		}
+	}
+	if len(list) > MaxIndexedFields {
+		log.Printf("Warning: %s lists %d keys, indexing only the first %d", field, len(list), MaxIndexedFields)
+		list = list[:MaxIndexedFields]
+	}
+	return list
+}
+
+// retentionRuleList normalizes the configured retention rules: names and
+// levels are trimmed, levels upper-cased, and a rule without a positive age is
+// dropped — an age of zero would evict every line it matches on the next pass.
+// Level names are checked where a user edits the rules (the settings API); the
+// store skips one it does not know.
+func retentionRuleList(field string, rules []RetentionRule) []RetentionRule {
+	list := make([]RetentionRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.MaxAgeHours <= 0 {
+			log.Printf("Warning: ignoring %s[%d] (maxAgeHours must be a positive integer)", field, i)
+			continue
+		}
+		rule.Hosts = trimmedList(rule.Hosts)
+		rule.Containers = trimmedList(rule.Containers)
+		rule.Projects = trimmedList(rule.Projects)
+		rule.Levels = trimmedList(rule.Levels)
+		for j, level := range rule.Levels {
+			rule.Levels[j] = strings.ToUpper(level)
+		}
+		list = append(list, rule)
+	}
+	return list
+}
+
+// ingestionRuleList normalizes the configured ingestion rules like
+// retentionRuleList does, and drops a rule that cannot be applied as written:
+// an unknown action, a drop rule whose pattern does not compile, or a sample
+// rate outside 0-100. Guessing at any of them could discard lines the user
+// meant to keep.
+func ingestionRuleList(field string, rules []IngestionRule) []IngestionRule {
+	list := make([]IngestionRule, 0, len(rules))
+	for i, rule := range rules {
+		rule.Action = strings.ToLower(strings.TrimSpace(rule.Action))
+		if err := CheckIngestionRule(rule); err != nil {
+			log.Printf("Warning: ignoring %s[%d]: %v", field, i, err)
+			continue
+		}
+		rule.Hosts = trimmedList(rule.Hosts)
+		rule.Containers = trimmedList(rule.Containers)
+		rule.Projects = trimmedList(rule.Projects)
+		rule.Labels = trimmedList(rule.Labels)
+		list = append(list, rule)
+	}
+	return list
+}
+
+// CheckIngestionRule reports why a rule with a normalized action cannot be
+// applied, or nil when it can.
+func CheckIngestionRule(rule IngestionRule) error {
+	switch rule.Action {
+	case IngestionExclude:
+	case IngestionDrop:
+		if rule.Pattern == "" {
+			return errors.New("a drop rule needs a pattern")
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case IngestionSample:
+		if rule.SamplePercent < 0 || rule.SamplePercent > 100 {
+			return fmt.Errorf("samplePercent %d is outside 0-100", rule.SamplePercent)
+		}
+	default:
+		return fmt.Errorf("unknown action %q (expected exclude, drop, or sample)", rule.Action)
+	}
+	return nil
+}
+
+// trimmedList trims each value and drops blanks and duplicates. A nil or empty
+// list stays nil, so an unset dimension keeps matching everything.
+func trimmedList(values []string) []string {
+	var list []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// envRules reads a variable holding a JSON array of rules in the config
+// file's shape, such as LOG_STORE_RETENTION_RULES.
+func envRules[T any](key string) ([]T, bool) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil, false
+	}
+	var rules []T
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		log.Printf("Warning: ignoring %s (expected a JSON array of rules): %v", key, err)
+		return nil, false
+	}
+	return rules, true
+}
+
 func envBool(key string) (bool, bool) {
 	raw := os.Getenv(key)
 	if raw == "" {
@@ -164,3 +454,11 @@ func envPositiveInt(key string) (int, bool) {
 	}
 	return value, true
 }
+
+func envList(key string) ([]string, bool) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil, false
+	}
+	return strings.Split(raw, ","), true
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -29,8 +32,11 @@ func TestLogStoreDefaults(t *testing.T) {
 		Enabled:        true,
 		PerContainerMB: DefaultLogStorePerContainerMB,
 		TotalMB:        DefaultLogStoreTotalMB,
+		IndexedFields:  []string{},
+		RetentionRules: []RetentionRule{},
+		IngestionRules: []IngestionRule{},
 	}
-	if got != want {
+	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("LogStore() = %+v, want %+v", got, want)
 	}
 }
@@ -46,6 +52,9 @@ func TestLogStoreFileConfig(t *testing.T) {
 	if got.Enabled {
 		t.Fatal("Enabled = true, want the file's false")
 	}
+	if got.FullTextIndex {
+		t.Fatal("FullTextIndex = true, want the default false")
+	}
 	if got.PerContainerMB != 10 {
 		t.Fatalf("PerContainerMB = %d, want 10", got.PerContainerMB)
 	}
@@ -83,14 +92,64 @@ func TestLogStoreEnvOverridesFile(t *testing.T) {
 	t.Setenv("LOG_STORE_ENABLED", "false")
 	t.Setenv("LOG_STORE_PER_CONTAINER_MB", "5")
 	t.Setenv("LOG_STORE_TOTAL_MB", "500")
+	t.Setenv("LOG_STORE_FULL_TEXT_INDEX", "true")
+	t.Setenv("LOG_STORE_INDEXED_FIELDS", "status, request_id")
 
 	got := manager.LogStore()
-	want := ResolvedLogStoreConfig{Enabled: false, PerContainerMB: 5, TotalMB: 500}
-	if got != want {
+	want := ResolvedLogStoreConfig{
+		Enabled:        false,
+		PerContainerMB: 5,
+		TotalMB:        500,
+		FullTextIndex:  true,
+		IndexedFields:  []string{"status", "request_id"},
+		RetentionRules: []RetentionRule{},
+		IngestionRules: []IngestionRule{},
+	}
+	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("LogStore() = %+v, want the env values %+v", got, want)
 	}
 }
 
+func TestLogStoreArchiveConfig(t *testing.T) {
+	enabled := true
+	manager := writeLogStoreConfig(t, FileConfig{
+		LogStore: &LogStoreConfig{Archive: &LogStoreArchiveConfig{
+			Enabled: &enabled,
+			Dir:     "/srv/archive",
+			S3:      &S3ArchiveConfig{Endpoint: "http://minio:9000", Bucket: "logs", AccessKeyID: "file-key"},
+		}},
+	})
+
+	want := ResolvedLogStoreArchive{
+		Enabled: true,
+		Dir:     "/srv/archive",
+		S3:      S3ArchiveConfig{Endpoint: "http://minio:9000", Bucket: "logs", AccessKeyID: "file-key"},
+	}
+	if got := manager.LogStore().Archive; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Archive = %+v, want the file's %+v", got, want)
+	}
+
+	t.Setenv("LOG_STORE_ARCHIVE_ENABLED", "false")
+	t.Setenv("LOG_STORE_ARCHIVE_S3_BUCKET", "other")
+	t.Setenv("LOG_STORE_ARCHIVE_S3_ACCESS_KEY_ID", "env-key")
+	t.Setenv("LOG_STORE_ARCHIVE_S3_SECRET_ACCESS_KEY", "env-secret")
+	want.Enabled = false
+	want.S3.Bucket, want.S3.AccessKeyID, want.S3.SecretAccessKey = "other", "env-key", "env-secret"
+	got := manager.LogStore()
+	if !reflect.DeepEqual(got.Archive, want) {
+		t.Fatalf("Archive = %+v, want the env values %+v", got.Archive, want)
+	}
+
+	// The credentials never leave the server through the resolved settings.
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(data), "env-secret") {
+		t.Fatalf("the resolved settings serialize the bucket secret: %s", data)
+	}
+}
+
 func TestLogStoreIgnoresInvalidEnv(t *testing.T) {
 	manager := writeLogStoreConfig(t, FileConfig{})
 
@@ -103,6 +162,94 @@ func TestLogStoreIgnoresInvalidEnv(t *testing.T) {
 	}
 }
 
+func TestLogStoreCleansIndexedFields(t *testing.T) {
+	keys := []string{" status ", "", "status", "request_id"}
+	for i := range MaxIndexedFields {
+		keys = append(keys, fmt.Sprintf("extra%d", i))
+	}
+	manager := writeLogStoreConfig(t, FileConfig{
+		LogStore: &LogStoreConfig{IndexedFields: keys},
+	})
+
+	got := manager.LogStore().IndexedFields
+	if len(got) != MaxIndexedFields || got[0] != "status" || got[1] != "request_id" {
+		t.Fatalf("IndexedFields = %q, want trimmed, deduplicated, and capped at %d", got, MaxIndexedFields)
+	}
+}
+
+func TestLogStoreRetentionRules(t *testing.T) {
+	manager := writeLogStoreConfig(t, FileConfig{
+		LogStore: &LogStoreConfig{RetentionRules: []RetentionRule{
+			{Levels: []string{" debug ", "trace", "debug"}, MaxAgeHours: 24},
+			{Projects: []string{"payments", ""}, MaxAgeHours: 336},
+			{Containers: []string{"web"}}, // no age: dropped
+			{MaxAgeHours: 48},
+		}},
+	})
+
+	want := []RetentionRule{
+		{Levels: []string{"DEBUG", "TRACE"}, MaxAgeHours: 24},
+		{Projects: []string{"payments"}, MaxAgeHours: 336},
+		{MaxAgeHours: 48},
+	}
+	if got := manager.LogStore().RetentionRules; !reflect.DeepEqual(got, want) {
+		t.Fatalf("RetentionRules = %+v, want %+v", got, want)
+	}
+	if got := manager.LogStoreSources().RetentionRules; got != SourceFile {
+		t.Fatalf("RetentionRules source = %q, want file", got)
+	}
+
+	t.Setenv("LOG_STORE_RETENTION_RULES", `[{"hosts":["prod"],"maxAgeHours":12}]`)
+	want = []RetentionRule{{Hosts: []string{"prod"}, MaxAgeHours: 12}}
+	if got := manager.LogStore().RetentionRules; !reflect.DeepEqual(got, want) {
+		t.Fatalf("RetentionRules = %+v, want the env rules %+v", got, want)
+	}
+	if got := manager.LogStoreSources().RetentionRules; got != SourceEnv {
+		t.Fatalf("RetentionRules source = %q, want env", got)
+	}
+
+	// Malformed JSON is ignored, and so is not an env source.
+	t.Setenv("LOG_STORE_RETENTION_RULES", `{"maxAgeHours":12}`)
+	if got := manager.LogStore().RetentionRules; len(got) != 3 {
+		t.Fatalf("RetentionRules = %+v, want the file's rules", got)
+	}
+	if got := manager.LogStoreSources().RetentionRules; got != SourceFile {
+		t.Fatalf("RetentionRules source = %q, want file", got)
+	}
+}
+
+func TestLogStoreIngestionRules(t *testing.T) {
+	manager := writeLogStoreConfig(t, FileConfig{
+		LogStore: &LogStoreConfig{IngestionRules: []IngestionRule{
+			{Containers: []string{" healthcheck ", ""}, Action: " Exclude "},
+			{Labels: []string{"traefik.enable=true"}, Action: "drop", Pattern: `GET /ping`},
+			{Action: "drop"},                       // no pattern: dropped
+			{Action: "drop", Pattern: "("},         // bad pattern: dropped
+			{Action: "sample", SamplePercent: 101}, // out of range: dropped
+			{Action: "mute"},                       // unknown action: dropped
+			{Projects: []string{"edge"}, Action: "sample", SamplePercent: 10},
+		}},
+	})
+
+	want := []IngestionRule{
+		{Containers: []string{"healthcheck"}, Action: IngestionExclude},
+		{Labels: []string{"traefik.enable=true"}, Action: IngestionDrop, Pattern: `GET /ping`},
+		{Projects: []string{"edge"}, Action: IngestionSample, SamplePercent: 10},
+	}
+	if got := manager.LogStore().IngestionRules; !reflect.DeepEqual(got, want) {
+		t.Fatalf("IngestionRules = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("LOG_STORE_INGESTION_RULES", `[{"hosts":["prod"],"action":"exclude"}]`)
+	want = []IngestionRule{{Hosts: []string{"prod"}, Action: IngestionExclude}}
+	if got := manager.LogStore().IngestionRules; !reflect.DeepEqual(got, want) {
+		t.Fatalf("IngestionRules = %+v, want the env rules %+v", got, want)
+	}
+	if got := manager.LogStoreSources().IngestionRules; got != SourceEnv {
+		t.Fatalf("IngestionRules source = %q, want env", got)
+	}
+}
+
 func TestUpdateLogStorePersists(t *testing.T) {
 	manager := writeLogStoreConfig(t, FileConfig{})
 
@@ -132,12 +279,17 @@ func TestLogStoreSources(t *testing.T) {
 
 	t.Setenv("LOG_STORE_TOTAL_MB", "500")
 	t.Setenv("LOG_STORE_ENABLED", "not a bool") // ignored, so it is not an env source
+	t.Setenv("LOG_STORE_FULL_TEXT_INDEX", "false")
 
 	got := manager.LogStoreSources()
 	want := LogStoreSources{
 		Enabled:        SourceFile,
 		PerContainerMB: SourceFile,
 		TotalMB:        SourceEnv,
+		FullTextIndex:  SourceEnv,
+		IndexedFields:  SourceFile,
+		RetentionRules: SourceFile,
+		IngestionRules: SourceFile,
 	}
 	if got != want {
 		t.Fatalf("LogStoreSources() = %+v, want %+v", got, want)
@@ -18,6 +18,8 @@ type FileConfig struct {
 	APITokens    []APIToken          `json:"apiTokens,omitempty"`
 	Alerts       *AlertsConfig       `json:"alerts,omitempty"`
 	LogStore     *LogStoreConfig     `json:"logStore,omitempty"`
+	StatsHistory *StatsHistoryConfig `json:"statsHistory,omitempty"`
+	ImageUpdates *ImageUpdatesConfig `json:"imageUpdates,omitempty"`
 }
 
 // APIToken represents a stored API access token. Only the SHA256 hash of the
@@ -0,0 +1,89 @@
+package config
+
+import "log"
+
+// Default stats history settings: a sample every 15 seconds, raw samples for a
+// day, one-minute rollups for a week, and one-hour rollups for 90 days. At 100
+// containers that is well under a million rows.
+const (
+	DefaultStatsHistoryIntervalSeconds = 15
+	DefaultStatsHistoryRawHours        = 24
+	DefaultStatsHistoryMinuteDays      = 7
+	DefaultStatsHistoryHourDays        = 90
+)
+
+// StatsHistoryConfig holds the container metrics history settings. Persisted in
+// the config file under "statsHistory"; Enabled and IntervalSeconds can be
+// overridden by an environment variable.
+type StatsHistoryConfig struct {
+	Enabled         *bool `json:"enabled,omitempty"`
+	IntervalSeconds *int  `json:"intervalSeconds,omitempty"`
+	// How long each resolution is kept.
+	RawHours   *int `json:"rawHours,omitempty"`
+	MinuteDays *int `json:"minuteDays,omitempty"`
+	HourDays   *int `json:"hourDays,omitempty"`
+}
+
+// ResolvedStatsHistoryConfig is the effective stats history configuration
+// after the env-over-file merge, with defaults applied.
+type ResolvedStatsHistoryConfig struct {
+	Enabled         bool `json:"enabled"`
+	IntervalSeconds int  `json:"intervalSeconds"`
+	RawHours        int  `json:"rawHours"`
+	MinuteDays      int  `json:"minuteDays"`
+	HourDays        int  `json:"hourDays"`
+}
+
+// StatsHistory returns the effective stats history settings: environment
+// variables win over the config file, which wins over the defaults (enabled,
+// 15-second samples kept for 24 hours, minute rollups for 7 days, hour rollups
+// for 90 days).
+func (m *Manager) StatsHistory() ResolvedStatsHistoryConfig {
+	m.mu.RLock()
+	file := m.fileConfig.StatsHistory
+	m.mu.RUnlock()
+
+	resolved := ResolvedStatsHistoryConfig{
+		Enabled:         true,
+		IntervalSeconds: DefaultStatsHistoryIntervalSeconds,
+		RawHours:        DefaultStatsHistoryRawHours,
+		MinuteDays:      DefaultStatsHistoryMinuteDays,
+		HourDays:        DefaultStatsHistoryHourDays,
+	}
+
+	if file != nil {
+		if file.Enabled != nil {
+			resolved.Enabled = *file.Enabled
+		}
+		for _, field := range []struct {
+			name     string
+			value    *int
+			resolved *int
+		}{
+			{"statsHistory.intervalSeconds", file.IntervalSeconds, &resolved.IntervalSeconds},
+			{"statsHistory.rawHours", file.RawHours, &resolved.RawHours},
+			{"statsHistory.minuteDays", file.MinuteDays, &resolved.MinuteDays},
+			{"statsHistory.hourDays", file.HourDays, &resolved.HourDays},
+		} {
+			if field.value == nil {
+				continue
+			}
+			// Zero would sample continuously or delete every sample on the
+			// next pass, so it falls back to the default.
+			if *field.value <= 0 {
+				log.Printf("Warning: ignoring %s=%d (expected a positive integer), using %d", field.name, *field.value, *field.resolved)
+				continue
+			}
+			*field.resolved = *field.value
+		}
+	}
+
+	if v, ok := envBool("STATS_HISTORY_ENABLED"); ok {
+		resolved.Enabled = v
+	}
+	if v, ok := envPositiveInt("STATS_HISTORY_INTERVAL_SECONDS"); ok {
+		resolved.IntervalSeconds = v
+	}
+
+	return resolved
+}
@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestStatsHistoryDefaults(t *testing.T) {
+	t.Setenv("STATS_HISTORY_ENABLED", "")
+	t.Setenv("STATS_HISTORY_INTERVAL_SECONDS", "")
+	manager := writeLogStoreConfig(t, FileConfig{})
+
+	got := manager.StatsHistory()
+	want := ResolvedStatsHistoryConfig{
+		Enabled:         true,
+		IntervalSeconds: DefaultStatsHistoryIntervalSeconds,
+		RawHours:        DefaultStatsHistoryRawHours,
+		MinuteDays:      DefaultStatsHistoryMinuteDays,
+		HourDays:        DefaultStatsHistoryHourDays,
+	}
+	if got != want {
+		t.Fatalf("StatsHistory() = %+v, want %+v", got, want)
+	}
+}
+
+// A retention of zero would delete every sample on the next pass, and an
+// interval of zero would sample in a tight loop; both fall back to the default.
+func TestStatsHistoryFileConfigAndEnv(t *testing.T) {
+	t.Setenv("STATS_HISTORY_ENABLED", "")
+	t.Setenv("STATS_HISTORY_INTERVAL_SECONDS", "")
+	enabled, interval, zero, hourDays := true, 30, 0, 365
+	manager := writeLogStoreConfig(t, FileConfig{
+		StatsHistory: &StatsHistoryConfig{Enabled: &enabled, IntervalSeconds: &interval, RawHours: &zero, HourDays: &hourDays},
+	})
+
+	got := manager.StatsHistory()
+	if got.IntervalSeconds != 30 || got.HourDays != 365 || got.RawHours != DefaultStatsHistoryRawHours {
+		t.Fatalf("StatsHistory() = %+v, want the file's interval and hour retention, default raw retention", got)
+	}
+
+	t.Setenv("STATS_HISTORY_ENABLED", "false")
+	t.Setenv("STATS_HISTORY_INTERVAL_SECONDS", "60")
+	got = manager.StatsHistory()
+	if got.Enabled || got.IntervalSeconds != 60 {
+		t.Fatalf("StatsHistory() = %+v, want the env's disabled and 60s", got)
+	}
+}
@@ -152,6 +152,17 @@ func (c *MultiHostClient) GetHosts() []config.DockerHost {
 	return c.hosts
 }
 
+// hostAddress returns a configured host's engine address, or "" for an
+// unknown host.
+func (c *MultiHostClient) hostAddress(hostName string) string {
+	for _, h := range c.hosts {
+		if h.Name == hostName {
+			return h.Host
+		}
+	}
+	return ""
+}
+
 // EngineInfo identifies the container engine behind a host. Podman serves the
 // Docker-compatible API and reports itself as a "Podman Engine" component in
 // the version response; anything else is treated as Docker.
@@ -10,15 +10,8 @@ import (
 	"github.com/docker/docker/api/types/container"
 )
 
-// Docker Compose and recent podman-compose both set the com.docker label;
-// older podman-compose releases only set the io.podman one.
-var composeProjectLabels = []string{
-	"com.docker.compose.project",
-	"io.podman.compose.project",
-}
-
 func inComposeProject(labels map[string]string, project string) bool {
-	for _, label := range composeProjectLabels {
+	for _, label := range models.ComposeProjectLabels {
 		if labels[label] == project {
 			return true
 		}
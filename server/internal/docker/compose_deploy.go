@@ -0,0 +1,507 @@
+package docker
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+	"github.com/compose-spec/compose-go/v2/types"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+var (
+	// ErrComposeProjectNotFound reports that no container on the host belongs
+	// to the project.
+	ErrComposeProjectNotFound = errors.New("compose project not found")
+	// ErrComposeDeployBusy reports that the project is already being pulled,
+	// brought up, or brought down on that host.
+	ErrComposeDeployBusy = errors.New("a pull, up, or down of this project is already running")
+	// ErrComposeRemoteHost reports a pull or up on a host whose engine is not
+	// on the LogDeck server, where the project's compose files cannot be read.
+	ErrComposeRemoteHost = errors.New("pull and up need the compose files, which LogDeck can only read for a local engine")
+)
+
+// composeDeployTimeout bounds one deploy, so an engine call that never returns
+// cannot hold the project's lock forever. It is generous: pulls of large
+// images over a slow link take a while.
+const composeDeployTimeout = 30 * time.Minute
+
+// ComposeDeployActions are the actions PlanComposeDeploy accepts.
+var ComposeDeployActions = []string{"pull", "up", "down"}
+
+// composeDeployAPI is the subset of the Docker client a compose deploy uses.
+type composeDeployAPI interface {
+	containerRecreateAPI
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error)
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	NetworkList(ctx context.Context, options network.ListOptions) ([]network.Summary, error)
+	NetworkCreate(ctx context.Context, name string, options network.CreateOptions) (network.CreateResponse, error)
+	NetworkRemove(ctx context.Context, networkID string) error
+	VolumeInspect(ctx context.Context, volumeID string) (volume.Volume, error)
+	VolumeCreate(ctx context.Context, options volume.CreateOptions) (volume.Volume, error)
+}
+
+// composeDeployLocks keeps two deploys of one project on one host from
+// interleaving, keyed by host+project. Package-level so locks survive client
+// hot-swaps.
+var composeDeployLocks sync.Map
+
+// ComposeDeploy is one pull, up, or down of a compose project on one host.
+// PlanComposeDeploy checks everything that can fail before any progress is
+// streamed; Run then carries it out and must be called exactly once.
+type ComposeDeploy struct {
+	api        composeDeployAPI
+	action     string
+	name       string
+	containers []container.Summary
+	source     composeSource
+	project    *types.Project // nil for down, which needs no compose files
+	timeout    time.Duration
+	unlock     func()
+}
+
+// PlanComposeDeploy prepares a pull, up, or down of a compose project. Pull and
+// up read the project's compose files from the paths its containers' labels
+// record, on the LogDeck server, so they are refused for a remote host; down
+// only needs the containers and networks the project labelled.
+func (c *MultiHostClient) PlanComposeDeploy(ctx context.Context, hostName, project, action string) (*ComposeDeploy, error) {
+	apiClient, err := c.GetClient(hostName)
+	if err != nil {
+		return nil, err
+	}
+	host := config.DockerHost{Name: hostName, Host: c.hostAddress(hostName)}
+	return planComposeDeploy(ctx, apiClient, host, project, action)
+}
+
+func planComposeDeploy(ctx context.Context, api composeDeployAPI, host config.DockerHost, project, action string) (*ComposeDeploy, error) {
+	hostName := host.Name
+	if !slices.Contains(ComposeDeployActions, action) {
+		return nil, fmt.Errorf("unsupported compose deploy action: %s", action)
+	}
+	if action != "down" && !isLocalEngine(host.Host) {
+		return nil, fmt.Errorf("%w: host %s is %s", ErrComposeRemoteHost, hostName, host.Host)
+	}
+
+	all, err := api.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	d := &ComposeDeploy{api: api, action: action, name: project, timeout: composeDeployTimeout}
+	for _, ctr := range all {
+		if inComposeProject(ctr.Labels, project) && ctr.Labels[composeOneoffLabel] != "True" {
+			d.containers = append(d.containers, ctr)
+		}
+	}
+	if len(d.containers) == 0 {
+		return nil, fmt.Errorf("%w: %s has no containers on host %s", ErrComposeProjectNotFound, project, hostName)
+	}
+
+	if action != "down" {
+		// Every container of a project records the same files; take the
+		// first that records any.
+		var srcErr error
+		for _, ctr := range d.containers {
+			if d.source, srcErr = composeSourceFromLabels(ctr.Labels); srcErr == nil {
+				break
+			}
+		}
+		if srcErr != nil {
+			return nil, srcErr
+		}
+		if d.project, err = loadComposeProject(ctx, project, d.source); err != nil {
+			return nil, err
+		}
+	}
+
+	lock, _ := composeDeployLocks.LoadOrStore(hostName+"/"+project, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, ErrComposeDeployBusy
+	}
+	d.unlock = mu.Unlock
+	return d, nil
+}
+
+// isLocalEngine reports whether a host address reaches an engine on this
+// machine: a unix socket or a Windows named pipe. TCP and SSH hosts count as
+// remote even when they point at localhost.
+func isLocalEngine(address string) bool {
+	return strings.HasPrefix(address, "unix://") || strings.HasPrefix(address, "npipe://")
+}
+
+// Run carries out the deploy, reporting each step through emit. It stops at
+// the first failure of pull or up (later services may depend on the failed
+// one); down keeps going and reports every failure. A deploy still running
+// after composeDeployTimeout is abandoned with an error saying so, releasing
+// the project for the next one.
+func (d *ComposeDeploy) Run(ctx context.Context, emit func(models.ComposeDeployEvent)) error {
+	defer d.unlock()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	var err error
+	switch d.action {
+	case "pull":
+		err = d.pull(ctx, emit)
+	case "up":
+		err = d.up(ctx, emit)
+	default:
+		err = d.down(ctx, emit)
+	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s of %s timed out after %s: %w", d.action, d.name, d.timeout, err)
+	}
+	return err
+}
+
+// sortedServices returns the project's services by name, for stable output.
+func (d *ComposeDeploy) sortedServices() []types.ServiceConfig {
+	services := slices.Collect(maps.Values(d.project.Services))
+	slices.SortFunc(services, func(a, b types.ServiceConfig) int { return cmp.Compare(a.Name, b.Name) })
+	return services
+}
+
+func (d *ComposeDeploy) pull(ctx context.Context, emit func(models.ComposeDeployEvent)) error {
+	for _, s := range d.sortedServices() {
+		if s.Build != nil || s.PullPolicy == types.PullPolicyBuild {
+			emit(models.ComposeDeployEvent{Service: s.Name, Target: serviceImage(d.project, s), Status: "skipped", Detail: "built locally, not pulled"})
+			continue
+		}
+		if err := d.pullImage(ctx, s, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pullImage pulls a service's image, forwarding the engine's per-layer
+// progress. Only status changes are forwarded, not every byte-count tick.
+func (d *ComposeDeploy) pullImage(ctx context.Context, s types.ServiceConfig, emit func(models.ComposeDeployEvent)) error {
+	ref := serviceImage(d.project, s)
+	emit(models.ComposeDeployEvent{Service: s.Name, Target: ref, Status: "pulling"})
+
+	body, err := d.api.ImagePull(ctx, ref, image.PullOptions{Platform: s.Platform, RegistryAuth: registryAuth(ref)})
+	if err != nil {
+		return fmt.Errorf("pull %s: %w", ref, err)
+	}
+	defer body.Close()
+
+	last := map[string]string{}
+	err = readPullProgress(body, func(id, status string) {
+		if last[id] == status {
+			return
+		}
+		last[id] = status
+		emit(models.ComposeDeployEvent{Service: s.Name, Target: ref, Status: "pulling", Detail: strings.TrimSpace(id + " " + status)})
+	})
+	if err != nil {
+		return fmt.Errorf("pull %s: %w", ref, err)
+	}
+	emit(models.ComposeDeployEvent{Service: s.Name, Target: ref, Status: "pulled"})
+	return nil
+}
+
+// up brings the project to what its compose files describe: networks and
+// volumes are created if missing, and each service, dependencies first, gets
+// its configured number of containers. A container whose configuration or
+// image changed is recreated; an unchanged one is only started if stopped.
+// depends_on conditions are honored for order only, not waited on.
+func (d *ComposeDeploy) up(ctx context.Context, emit func(models.ComposeDeployEvent)) error {
+	if err := d.ensureNetworks(ctx, emit); err != nil {
+		return err
+	}
+	if err := d.ensureVolumes(ctx, emit); err != nil {
+		return err
+	}
+
+	byService := map[string][]container.Summary{}
+	for _, ctr := range d.containers {
+		service := ctr.Labels[composeServiceLabel]
+		byService[service] = append(byService[service], ctr)
+	}
+	for service, ctrs := range byService {
+		if _, ok := d.project.Services[service]; !ok {
+			for _, ctr := range ctrs {
+				emit(models.ComposeDeployEvent{Service: service, Target: summaryName(ctr), Status: "orphaned", Detail: "service is no longer in the compose files; left as is"})
+			}
+		}
+	}
+
+	// IDs of each service's first container, for network_mode: service:<name>.
+	deployed := map[string]string{}
+	containerID := func(service string) (string, error) {
+		if id, ok := deployed[service]; ok {
+			return id, nil
+		}
+		return "", fmt.Errorf("service %s has no container", service)
+	}
+
+	return d.project.ForEachService(d.project.ServiceNames(), func(name string, s *types.ServiceConfig) error {
+		imageID, err := d.serviceImageID(ctx, *s, emit)
+		if err != nil {
+			return err
+		}
+		hash, err := serviceConfigHash(*s, containerID)
+		if err != nil {
+			return err
+		}
+
+		existing := byService[name]
+		slices.SortFunc(existing, func(a, b container.Summary) int {
+			return cmp.Compare(containerNumber(a), containerNumber(b))
+		})
+		scale := s.GetScale()
+		for i, ctr := range existing {
+			if i >= scale {
+				if err := d.removeContainer(ctx, ctr); err != nil {
+					return fmt.Errorf("remove %s: %w", summaryName(ctr), err)
+				}
+				emit(models.ComposeDeployEvent{Service: name, Target: summaryName(ctr), Status: "removed", Detail: "scaled down"})
+				continue
+			}
+			id, err := d.converge(ctx, *s, ctr, i+1, hash, imageID, containerID, emit)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				deployed[name] = id
+			}
+		}
+		for number := len(existing) + 1; number <= scale; number++ {
+			spec, err := serviceContainerSpec(d.project, *s, number, hash, imageID, d.source, containerID)
+			if err != nil {
+				return err
+			}
+			resp, err := d.api.ContainerCreate(ctx, spec.Config, spec.HostConfig, spec.Networking, nil, spec.Name)
+			if err != nil {
+				return fmt.Errorf("create %s: %w", spec.Name, err)
+			}
+			if err := d.api.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+				return fmt.Errorf("start %s: %w", spec.Name, err)
+			}
+			emit(models.ComposeDeployEvent{Service: name, Target: spec.Name, Status: "created"})
+			if number == 1 {
+				deployed[name] = resp.ID
+			}
+		}
+		return nil
+	}, types.IncludeDependencies)
+}
+
+// converge brings one existing container in line with its service and returns
+// the ID it ends up with.
+func (d *ComposeDeploy) converge(ctx context.Context, s types.ServiceConfig, ctr container.Summary, number int, hash, imageID string, containerID func(string) (string, error), emit func(models.ComposeDeployEvent)) (string, error) {
+	name := summaryName(ctr)
+	var reason string
+	switch {
+	case ctr.Labels[composeHashLabel] != hash:
+		reason = "configuration changed"
+	// Podman's compat API may report image IDs without the digest prefix.
+	case strings.TrimPrefix(ctr.ImageID, "sha256:") != strings.TrimPrefix(imageID, "sha256:"):
+		reason = "image changed"
+	}
+
+	if reason == "" {
+		if ctr.State == container.StateRunning {
+			emit(models.ComposeDeployEvent{Service: s.Name, Target: name, Status: "up-to-date"})
+			return ctr.ID, nil
+		}
+		if err := d.api.ContainerStart(ctx, ctr.ID, container.StartOptions{}); err != nil {
+			return "", fmt.Errorf("start %s: %w", name, err)
+		}
+		emit(models.ComposeDeployEvent{Service: s.Name, Target: name, Status: "started"})
+		return ctr.ID, nil
+	}
+
+	spec, err := serviceContainerSpec(d.project, s, number, hash, imageID, d.source, containerID)
+	if err != nil {
+		return "", err
+	}
+	inspect, err := d.api.ContainerInspect(ctx, ctr.ID)
+	if err != nil {
+		return "", fmt.Errorf("inspect %s: %w", name, err)
+	}
+	emit(models.ComposeDeployEvent{Service: s.Name, Target: name, Status: "recreating", Detail: reason})
+	id, err := replaceContainer(ctx, d.api, inspect, spec, true)
+	if err != nil {
+		return "", fmt.Errorf("recreate %s: %w", name, err)
+	}
+	emit(models.ComposeDeployEvent{Service: s.Name, Target: spec.Name, Status: "recreated", Detail: reason})
+	return id, nil
+}
+
+// serviceImageID resolves the local image a service runs, pulling it first if
+// it is missing, as docker compose up does.
+func (d *ComposeDeploy) serviceImageID(ctx context.Context, s types.ServiceConfig, emit func(models.ComposeDeployEvent)) (string, error) {
+	ref := serviceImage(d.project, s)
+	inspect, err := d.api.ImageInspect(ctx, ref)
+	if err == nil {
+		return inspect.ID, nil
+	}
+	if !cerrdefs.IsNotFound(err) {
+		return "", fmt.Errorf("inspect image %s: %w", ref, err)
+	}
+	if s.Build != nil || s.PullPolicy == types.PullPolicyBuild {
+		return "", fmt.Errorf("image %s for service %s has not been built; LogDeck cannot build images, run docker compose build first", ref, s.Name)
+	}
+	if err := d.pullImage(ctx, s, emit); err != nil {
+		return "", err
+	}
+	if inspect, err = d.api.ImageInspect(ctx, ref); err != nil {
+		return "", fmt.Errorf("inspect image %s: %w", ref, err)
+	}
+	return inspect.ID, nil
+}
+
+func (d *ComposeDeploy) ensureNetworks(ctx context.Context, emit func(models.ComposeDeployEvent)) error {
+	existing, err := d.api.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for _, n := range existing {
+		names[n.Name] = true
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(d.project.Networks)) {
+		cfg := d.project.Networks[key]
+		if names[cfg.Name] {
+			continue
+		}
+		if cfg.External {
+			return fmt.Errorf("external network %s does not exist", cfg.Name)
+		}
+		labels := map[string]string{}
+		maps.Copy(labels, cfg.Labels)
+		maps.Copy(labels, cfg.CustomLabels)
+		labels[composeProjectLabel] = d.name
+		labels[composeNetworkLabel] = key
+		options := network.CreateOptions{
+			Driver:     cfg.Driver,
+			Options:    cfg.DriverOpts,
+			Internal:   cfg.Internal,
+			Attachable: cfg.Attachable,
+			EnableIPv4: cfg.EnableIPv4,
+			EnableIPv6: cfg.EnableIPv6,
+			Labels:     labels,
+		}
+		if cfg.Ipam.Driver != "" || len(cfg.Ipam.Config) > 0 {
+			options.IPAM = &network.IPAM{Driver: cfg.Ipam.Driver, Options: cfg.Ipam.Options}
+			for _, pool := range cfg.Ipam.Config {
+				options.IPAM.Config = append(options.IPAM.Config, network.IPAMConfig{
+					Subnet:     pool.Subnet,
+					IPRange:    pool.IPRange,
+					Gateway:    pool.Gateway,
+					AuxAddress: pool.AuxiliaryAddresses,
+				})
+			}
+		}
+		if _, err := d.api.NetworkCreate(ctx, cfg.Name, options); err != nil {
+			return fmt.Errorf("create network %s: %w", cfg.Name, err)
+		}
+		emit(models.ComposeDeployEvent{Target: cfg.Name, Status: "created", Detail: "network"})
+	}
+	return nil
+}
+
+func (d *ComposeDeploy) ensureVolumes(ctx context.Context, emit func(models.ComposeDeployEvent)) error {
+	for _, key := range slices.Sorted(maps.Keys(d.project.Volumes)) {
+		cfg := d.project.Volumes[key]
+		_, err := d.api.VolumeInspect(ctx, cfg.Name)
+		if err == nil {
+			continue
+		}
+		if !cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("inspect volume %s: %w", cfg.Name, err)
+		}
+		if cfg.External {
+			return fmt.Errorf("external volume %s does not exist", cfg.Name)
+		}
+		labels := map[string]string{}
+		maps.Copy(labels, cfg.Labels)
+		maps.Copy(labels, cfg.CustomLabels)
+		labels[composeProjectLabel] = d.name
+		labels[composeVolumeLabel] = key
+		if _, err := d.api.VolumeCreate(ctx, volume.CreateOptions{Name: cfg.Name, Driver: cfg.Driver, DriverOpts: cfg.DriverOpts, Labels: labels}); err != nil {
+			return fmt.Errorf("create volume %s: %w", cfg.Name, err)
+		}
+		emit(models.ComposeDeployEvent{Target: cfg.Name, Status: "created", Detail: "volume"})
+	}
+	return nil
+}
+
+// down stops and removes the project's containers, then the networks it
+// created. Volumes are kept, as with docker compose down.
+func (d *ComposeDeploy) down(ctx context.Context, emit func(models.ComposeDeployEvent)) error {
+	failures := 0
+	containers := slices.Clone(d.containers)
+	slices.SortFunc(containers, func(a, b container.Summary) int { return cmp.Compare(summaryName(a), summaryName(b)) })
+	for _, ctr := range containers {
+		event := models.ComposeDeployEvent{Service: ctr.Labels[composeServiceLabel], Target: summaryName(ctr), Status: "removed"}
+		if err := d.removeContainer(ctx, ctr); err != nil {
+			event.Status, event.Error = "failed", err.Error()
+			failures++
+		}
+		emit(event)
+	}
+
+	networks, err := d.api.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(networks, func(a, b network.Summary) int { return cmp.Compare(a.Name, b.Name) })
+	for _, n := range networks {
+		if n.Labels[composeProjectLabel] != d.name {
+			continue
+		}
+		event := models.ComposeDeployEvent{Target: n.Name, Status: "removed", Detail: "network"}
+		if err := d.api.NetworkRemove(ctx, n.ID); err != nil {
+			event.Status, event.Error = "failed", err.Error()
+			failures++
+		}
+		emit(event)
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d step(s) failed", failures)
+	}
+	return nil
+}
+
+func (d *ComposeDeploy) removeContainer(ctx context.Context, ctr container.Summary) error {
+	if ctr.State == container.StateRunning || ctr.State == container.StateRestarting || ctr.State == container.StatePaused {
+		if err := d.api.ContainerStop(ctx, ctr.ID, container.StopOptions{}); err != nil {
+			return err
+		}
+	}
+	return d.api.ContainerRemove(ctx, ctr.ID, container.RemoveOptions{})
+}
+
+func summaryName(ctr container.Summary) string {
+	if len(ctr.Names) > 0 {
+		return strings.TrimPrefix(ctr.Names[0], "/")
+	}
+	return ctr.ID
+}
+
+func containerNumber(ctr container.Summary) int {
+	n, _ := strconv.Atoi(ctr.Labels[composeNumberLabel])
+	return n
+}
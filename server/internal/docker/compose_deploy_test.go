@@ -0,0 +1,422 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeComposeAPI is a scripted engine for compose deploys. Lifecycle calls are
+// recorded by the embedded fakeRecreateAPI.
+type fakeComposeAPI struct {
+	fakeRecreateAPI
+	containers []container.Summary
+	images     map[string]string // ref -> image ID; pulls add to it
+	pullBody   string
+	pullHangs  bool // pulls stream nothing until the context ends
+	networks   []network.Summary
+	volumes    map[string]bool
+	created    map[string]*container.Config // container name -> create config
+}
+
+func newFakeComposeAPI(containers ...container.Summary) *fakeComposeAPI {
+	return &fakeComposeAPI{
+		containers: containers,
+		images:     map[string]string{},
+		volumes:    map[string]bool{},
+		created:    map[string]*container.Config{},
+	}
+}
+
+func (f *fakeComposeAPI) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.created[containerName] = config
+	return f.fakeRecreateAPI.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+func (f *fakeComposeAPI) ContainerList(context.Context, container.ListOptions) ([]container.Summary, error) {
+	return f.containers, nil
+}
+
+func (f *fakeComposeAPI) ContainerInspect(_ context.Context, id string) (container.InspectResponse, error) {
+	for _, ctr := range f.containers {
+		if ctr.ID == id {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    ctr.ID,
+					Name:  ctr.Names[0],
+					State: &container.State{Running: ctr.State == container.StateRunning},
+				},
+				Config: &container.Config{Labels: ctr.Labels},
+			}, nil
+		}
+	}
+	return container.InspectResponse{}, cerrdefs.ErrNotFound
+}
+
+func (f *fakeComposeAPI) ImagePull(ctx context.Context, ref string, _ image.PullOptions) (io.ReadCloser, error) {
+	f.calls = append(f.calls, "pull "+ref)
+	f.images[ref] = "sha256:pulled-" + ref
+	if f.pullHangs {
+		return io.NopCloser(hangingReader{ctx}), nil
+	}
+	return io.NopCloser(strings.NewReader(f.pullBody)), nil
+}
+
+// hangingReader is a pull progress stream that stalls until its request's
+// context ends, as the engine's does.
+type hangingReader struct{ ctx context.Context }
+
+func (r hangingReader) Read([]byte) (int, error) {
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func (f *fakeComposeAPI) ImageInspect(_ context.Context, ref string, _ ...client.ImageInspectOption) (image.InspectResponse, error) {
+	if id, ok := f.images[ref]; ok {
+		return image.InspectResponse{ID: id}, nil
+	}
+	return image.InspectResponse{}, cerrdefs.ErrNotFound
+}
+
+func (f *fakeComposeAPI) NetworkList(context.Context, network.ListOptions) ([]network.Summary, error) {
+	return f.networks, nil
+}
+
+func (f *fakeComposeAPI) NetworkCreate(_ context.Context, name string, options network.CreateOptions) (network.CreateResponse, error) {
+	f.calls = append(f.calls, "create network "+name)
+	f.networks = append(f.networks, network.Summary{ID: name, Name: name, Labels: options.Labels})
+	return network.CreateResponse{ID: name}, nil
+}
+
+func (f *fakeComposeAPI) NetworkRemove(_ context.Context, id string) error {
+	f.calls = append(f.calls, "remove network "+id)
+	return nil
+}
+
+func (f *fakeComposeAPI) VolumeInspect(_ context.Context, name string) (volume.Volume, error) {
+	if f.volumes[name] {
+		return volume.Volume{Name: name}, nil
+	}
+	return volume.Volume{}, cerrdefs.ErrNotFound
+}
+
+func (f *fakeComposeAPI) VolumeCreate(_ context.Context, options volume.CreateOptions) (volume.Volume, error) {
+	f.calls = append(f.calls, "create volume "+options.Name)
+	f.volumes[options.Name] = true
+	return volume.Volume{Name: options.Name}, nil
+}
+
+var localHost = config.DockerHost{Name: "local", Host: "unix:///var/run/docker.sock"}
+
+const testComposeFile = `services:
+  web:
+    image: nginx:${NGINX_TAG}
+    depends_on: [db]
+    ports: ["8080:80"]
+    environment:
+      MODE: prod
+    volumes:
+      - ./site:/usr/share/nginx/html:ro
+  db:
+    image: postgres:16
+    restart: unless-stopped
+    volumes:
+      - data:/var/lib/postgresql/data
+volumes:
+  data: {}
+`
+
+// writeComposeProject writes a compose file and .env into a temp dir and
+// returns the labels docker compose would have put on its containers.
+func writeComposeProject(t *testing.T, compose string) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "compose.yaml")
+	if err := os.WriteFile(file, []byte(compose), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("NGINX_TAG=1.27\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{
+		composeProjectLabel:     "shop",
+		composeConfigFilesLabel: file,
+		composeWorkingDirLabel:  dir,
+	}
+}
+
+func composeContainer(id, service string, state container.ContainerState, labels map[string]string, extra map[string]string) container.Summary {
+	all := map[string]string{composeServiceLabel: service, composeNumberLabel: "1"}
+	for k, v := range labels {
+		all[k] = v
+	}
+	for k, v := range extra {
+		all[k] = v
+	}
+	return container.Summary{ID: id, Names: []string{"/shop-" + service + "-1"}, State: state, Labels: all}
+}
+
+func runDeploy(t *testing.T, api *fakeComposeAPI, action string) ([]models.ComposeDeployEvent, error) {
+	t.Helper()
+	d, err := planComposeDeploy(context.Background(), api, localHost, "shop", action)
+	if err != nil {
+		t.Fatalf("plan %s: %v", action, err)
+	}
+	var events []models.ComposeDeployEvent
+	err = d.Run(context.Background(), func(ev models.ComposeDeployEvent) { events = append(events, ev) })
+	return events, err
+}
+
+func findEvent(events []models.ComposeDeployEvent, target, status string) bool {
+	return slices.ContainsFunc(events, func(ev models.ComposeDeployEvent) bool {
+		return ev.Target == target && ev.Status == status
+	})
+}
+
+func TestComposeUpRecreatesOnlyChangedServices(t *testing.T) {
+	labels := writeComposeProject(t, testComposeFile)
+	project, err := loadComposeProject(context.Background(), "shop", composeSource{ConfigFiles: []string{labels[composeConfigFilesLabel]}, WorkingDir: labels[composeWorkingDirLabel]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbHash, err := serviceConfigHash(project.Services["db"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := newFakeComposeAPI(
+		composeContainer("db1", "db", container.StateRunning, labels, map[string]string{composeHashLabel: dbHash}),
+		composeContainer("web1", "web", container.StateRunning, labels, map[string]string{composeHashLabel: "stale"}),
+		composeContainer("old1", "worker", container.StateExited, labels, nil),
+	)
+	api.containers[0].ImageID = "sha256:pg"
+	api.containers[1].ImageID = "sha256:nginx"
+	api.images["postgres:16"] = "sha256:pg"
+	api.images["nginx:1.27"] = "sha256:nginx"
+
+	events, err := runDeploy(t, api, "up")
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	for _, want := range []struct{ target, status string }{
+		{"shop_default", "created"},
+		{"shop_data", "created"},
+		{"shop-db-1", "up-to-date"},
+		{"shop-web-1", "recreated"},
+		{"shop-worker-1", "orphaned"},
+	} {
+		if !findEvent(events, want.target, want.status) {
+			t.Errorf("missing %s %s in %+v", want.target, want.status, events)
+		}
+	}
+	if slices.Contains(api.calls, "stop db1") {
+		t.Error("unchanged db was restarted")
+	}
+
+	cfg := api.created["shop-web-1"]
+	if cfg == nil {
+		t.Fatalf("web was not recreated; calls: %v", api.calls)
+	}
+	if cfg.Image != "nginx:1.27" || !slices.Contains(cfg.Env, "MODE=prod") {
+		t.Errorf("web config = image %q env %v, want the interpolated image and env", cfg.Image, cfg.Env)
+	}
+	if cfg.Labels[composeProjectLabel] != "shop" || cfg.Labels[composeServiceLabel] != "web" || cfg.Labels[composeHashLabel] == "stale" {
+		t.Errorf("web labels = %v, want compose labels with a fresh hash", cfg.Labels)
+	}
+	if cfg.Labels[composeConfigFilesLabel] != labels[composeConfigFilesLabel] {
+		t.Errorf("config_files label = %q, want %q carried over", cfg.Labels[composeConfigFilesLabel], labels[composeConfigFilesLabel])
+	}
+}
+
+func TestComposeUpCreatesMissingContainersAndPullsImages(t *testing.T) {
+	labels := writeComposeProject(t, testComposeFile)
+	// Only db exists, stopped, with an outdated image.
+	api := newFakeComposeAPI(composeContainer("db1", "db", container.StateExited, labels, nil))
+	api.pullBody = `{"status":"Pulling from library/nginx","id":"1.27"}
+{"status":"Downloading","id":"abc","progressDetail":{"current":1,"total":2}}
+{"status":"Downloading","id":"abc","progressDetail":{"current":2,"total":2}}
+{"status":"Pull complete","id":"abc"}
+`
+	api.images["postgres:16"] = "sha256:pg"
+
+	events, err := runDeploy(t, api, "up")
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if !slices.Contains(api.calls, "pull nginx:1.27") {
+		t.Errorf("missing image was not pulled; calls: %v", api.calls)
+	}
+	pulling := 0
+	for _, ev := range events {
+		if ev.Status == "pulling" && ev.Detail == "abc Downloading" {
+			pulling++
+		}
+	}
+	if pulling != 1 {
+		t.Errorf("got %d Downloading events, want progress ticks collapsed into 1", pulling)
+	}
+	if !findEvent(events, "shop-web-1", "created") || !findEvent(events, "shop-db-1", "recreated") {
+		t.Errorf("events = %+v, want web created and db recreated", events)
+	}
+	// db is a dependency of web, so it must be deployed first.
+	dbAt := slices.IndexFunc(events, func(ev models.ComposeDeployEvent) bool { return ev.Target == "shop-db-1" })
+	webAt := slices.IndexFunc(events, func(ev models.ComposeDeployEvent) bool { return ev.Target == "shop-web-1" })
+	if dbAt > webAt {
+		t.Errorf("web deployed before its dependency db: %+v", events)
+	}
+}
+
+func TestComposeUpStopsOnPullError(t *testing.T) {
+	labels := writeComposeProject(t, testComposeFile)
+	api := newFakeComposeAPI(composeContainer("db1", "db", container.StateRunning, labels, nil))
+	api.pullBody = `{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`
+
+	_, err := runDeploy(t, api, "up")
+	if err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Fatalf("up error = %v, want the pull failure", err)
+	}
+}
+
+func TestComposePullSkipsBuiltServices(t *testing.T) {
+	labels := writeComposeProject(t, `services:
+  app:
+    build: .
+  cache:
+    image: redis:7
+`)
+	api := newFakeComposeAPI(composeContainer("app1", "app", container.StateRunning, labels, nil))
+
+	events, err := runDeploy(t, api, "pull")
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if !findEvent(events, "shop-app", "skipped") || !findEvent(events, "redis:7", "pulled") {
+		t.Errorf("events = %+v, want app skipped and redis pulled", events)
+	}
+	if slices.Contains(api.calls, "pull shop-app") {
+		t.Error("a build-only service was pulled")
+	}
+}
+
+func TestComposeDownRemovesContainersAndProjectNetworks(t *testing.T) {
+	api := newFakeComposeAPI(
+		composeContainer("web1", "web", container.StateRunning, map[string]string{composeProjectLabel: "shop"}, nil),
+		composeContainer("db1", "db", container.StateExited, map[string]string{composeProjectLabel: "shop"}, nil),
+	)
+	api.networks = []network.Summary{
+		{ID: "n1", Name: "shop_default", Labels: map[string]string{composeProjectLabel: "shop"}},
+		{ID: "n2", Name: "other_default", Labels: map[string]string{composeProjectLabel: "other"}},
+	}
+
+	// down needs no compose files: these containers have no config_files label.
+	events, err := runDeploy(t, api, "down")
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	for _, want := range []string{"stop web1", "remove web1 force=false", "remove db1 force=false", "remove network n1"} {
+		if !slices.Contains(api.calls, want) {
+			t.Errorf("missing call %q in %v", want, api.calls)
+		}
+	}
+	if slices.Contains(api.calls, "stop db1") || slices.Contains(api.calls, "remove network n2") {
+		t.Errorf("unexpected calls: %v", api.calls)
+	}
+	if !findEvent(events, "shop_default", "removed") {
+		t.Errorf("events = %+v, want the network removal reported", events)
+	}
+}
+
+func TestPlanComposeDeployErrors(t *testing.T) {
+	ctx := context.Background()
+	busyHost := config.DockerHost{Name: "busyhost", Host: "unix:///var/run/docker.sock"}
+
+	if _, err := planComposeDeploy(ctx, newFakeComposeAPI(), localHost, "shop", "up"); !errors.Is(err, ErrComposeProjectNotFound) {
+		t.Errorf("empty host: err = %v, want ErrComposeProjectNotFound", err)
+	}
+
+	unlabelled := newFakeComposeAPI(composeContainer("web1", "web", container.StateRunning, map[string]string{composeProjectLabel: "shop"}, nil))
+	if _, err := planComposeDeploy(ctx, unlabelled, localHost, "shop", "pull"); !errors.Is(err, ErrComposeFiles) {
+		t.Errorf("no config_files label: err = %v, want ErrComposeFiles", err)
+	}
+
+	missing := newFakeComposeAPI(composeContainer("web1", "web", container.StateRunning, map[string]string{
+		composeProjectLabel:     "shop",
+		composeConfigFilesLabel: filepath.Join(t.TempDir(), "compose.yaml"),
+	}, nil))
+	if _, err := planComposeDeploy(ctx, missing, localHost, "shop", "up"); !errors.Is(err, ErrComposeFiles) {
+		t.Errorf("unreadable compose file: err = %v, want ErrComposeFiles", err)
+	}
+
+	first, err := planComposeDeploy(ctx, unlabelled, busyHost, "shop", "down")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := planComposeDeploy(ctx, unlabelled, busyHost, "shop", "down"); !errors.Is(err, ErrComposeDeployBusy) {
+		t.Errorf("concurrent deploy: err = %v, want ErrComposeDeployBusy", err)
+	}
+	_ = first.Run(ctx, func(models.ComposeDeployEvent) {})
+	if second, err := planComposeDeploy(ctx, unlabelled, busyHost, "shop", "down"); err != nil {
+		t.Errorf("deploy after the first finished: %v", err)
+	} else {
+		_ = second.Run(ctx, func(models.ComposeDeployEvent) {})
+	}
+}
+
+func TestPlanComposeDeployRefusesRemoteHosts(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeComposeAPI(composeContainer("web1", "web", container.StateRunning, writeComposeProject(t, testComposeFile), nil))
+
+	for _, address := range []string{"ssh://root@203.0.113.7", "tcp://203.0.113.7:2375"} {
+		remote := config.DockerHost{Name: "remote", Host: address}
+		for _, action := range []string{"pull", "up"} {
+			if _, err := planComposeDeploy(ctx, api, remote, "shop", action); !errors.Is(err, ErrComposeRemoteHost) {
+				t.Errorf("%s on %s: err = %v, want ErrComposeRemoteHost", action, address, err)
+			}
+		}
+	}
+
+	// down reads no compose files, so it works anywhere.
+	d, err := planComposeDeploy(ctx, api, config.DockerHost{Name: "remote", Host: "ssh://root@203.0.113.7"}, "shop", "down")
+	if err != nil {
+		t.Fatalf("down on a remote host: %v", err)
+	}
+	_ = d.Run(ctx, func(models.ComposeDeployEvent) {})
+}
+
+func TestComposeDeployTimesOutAndReleasesTheProject(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeComposeAPI(composeContainer("web1", "web", container.StateRunning, writeComposeProject(t, testComposeFile), nil))
+	api.pullHangs = true
+
+	d, err := planComposeDeploy(ctx, api, localHost, "shop", "pull")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.timeout = 20 * time.Millisecond
+	if err := d.Run(ctx, func(models.ComposeDeployEvent) {}); err == nil || !strings.Contains(err.Error(), "timed out after 20ms") {
+		t.Fatalf("hung pull: err = %v, want a timeout", err)
+	}
+
+	next, err := planComposeDeploy(ctx, api, localHost, "shop", "down")
+	if err != nil {
+		t.Fatalf("deploy after a timeout: %v", err)
+	}
+	_ = next.Run(ctx, func(models.ComposeDeployEvent) {})
+}
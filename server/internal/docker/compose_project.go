@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/cli"
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// Labels docker compose (and podman-compose) put on every container, recording
+// where the project's compose files live.
+const (
+	composeConfigFilesLabel = "com.docker.compose.project.config_files"
+	composeWorkingDirLabel  = "com.docker.compose.project.working_dir"
+	composeEnvFileLabel     = "com.docker.compose.project.environment_file"
+)
+
+// ErrComposeFiles reports that a project's compose files could not be found
+// or loaded, so pull and up cannot know what the project should look like.
+var ErrComposeFiles = errors.New("compose files unavailable")
+
+// composeSource is where a project was deployed from, read from the labels of
+// one of its containers.
+type composeSource struct {
+	ConfigFiles []string
+	WorkingDir  string
+	EnvFiles    []string
+}
+
+func composeSourceFromLabels(labels map[string]string) (composeSource, error) {
+	src := composeSource{
+		ConfigFiles: splitLabelList(labels[composeConfigFilesLabel]),
+		WorkingDir:  labels[composeWorkingDirLabel],
+		EnvFiles:    splitLabelList(labels[composeEnvFileLabel]),
+	}
+	if len(src.ConfigFiles) == 0 {
+		return src, fmt.Errorf("%w: containers carry no %s label (was the project started with docker compose?)", ErrComposeFiles, composeConfigFilesLabel)
+	}
+	return src, nil
+}
+
+func splitLabelList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// loadComposeProject reads and resolves a project's compose files the way
+// docker compose would from the project's working directory: .env (or the
+// recorded env files) feed interpolation, but LogDeck's own process
+// environment deliberately does not. The paths are read on the LogDeck
+// server, so the project directory must be visible there at the same path.
+func loadComposeProject(ctx context.Context, name string, src composeSource) (*types.Project, error) {
+	options, err := cli.NewProjectOptions(src.ConfigFiles,
+		cli.WithName(name),
+		cli.WithWorkingDirectory(src.WorkingDir),
+		cli.WithEnvFiles(src.EnvFiles...),
+		cli.WithDotEnv,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrComposeFiles, err)
+	}
+	project, err := options.LoadProject(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrComposeFiles, err)
+	}
+	return project, nil
+}
@@ -0,0 +1,503 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+)
+
+// Labels docker compose sets on the containers, networks, and volumes it
+// creates. LogDeck sets the same ones so `docker compose` keeps recognizing a
+// project LogDeck has deployed, and the other way round.
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	composeNumberLabel  = "com.docker.compose.container-number"
+	composeOneoffLabel  = "com.docker.compose.oneoff"
+	composeHashLabel    = "com.docker.compose.config-hash"
+	composeImageLabel   = "com.docker.compose.image"
+	composeNetworkLabel = "com.docker.compose.network"
+	composeVolumeLabel  = "com.docker.compose.volume"
+)
+
+// containerSpec is everything ContainerCreate needs for one container.
+type containerSpec struct {
+	Name       string
+	Config     *container.Config
+	HostConfig *container.HostConfig
+	Networking *network.NetworkingConfig
+}
+
+// serviceConfigHash fingerprints the parts of a service that end up in its
+// containers, the same way docker compose does: fields that only steer
+// building, pulling, scaling, or start order are left out, so changing them
+// does not recreate anything. Like compose, it hashes `network_mode:
+// service:<name>` as the container it resolves to, so a service follows the
+// container whose network it shares. The hash covers compose-go's JSON
+// encoding of the service, field order included, so go.mod keeps compose-go
+// on the release docker compose itself is built with; the hash test fails
+// when the two drift apart.
+func serviceConfigHash(s types.ServiceConfig, containerID func(service string) (string, error)) (string, error) {
+	if mode, ok := strings.CutPrefix(s.NetworkMode, "service:"); ok && containerID != nil {
+		if id, err := containerID(mode); err == nil {
+			s.NetworkMode = "container:" + id
+		}
+	}
+	s.Build = nil
+	s.PullPolicy = ""
+	s.Scale = nil
+	if s.Deploy != nil {
+		deploy := *s.Deploy
+		deploy.Replicas = nil
+		s.Deploy = &deploy
+	}
+	s.DependsOn = nil
+	s.Profiles = nil
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// serviceImage is the image a service's containers run. A service that only
+// has a build section runs the image compose would have built for it.
+func serviceImage(project *types.Project, s types.ServiceConfig) string {
+	if s.Image != "" {
+		return s.Image
+	}
+	return project.Name + "-" + s.Name
+}
+
+// serviceContainerName is the name of the number-th container of a service.
+func serviceContainerName(project *types.Project, s types.ServiceConfig, number int) string {
+	if s.ContainerName != "" {
+		return s.ContainerName
+	}
+	return fmt.Sprintf("%s-%s-%d", project.Name, s.Name, number)
+}
+
+// unsupportedServiceKeys lists the compose keys of s that LogDeck cannot
+// reproduce through the engine API. Deploying such a service would quietly
+// drop them, so up refuses it instead.
+func unsupportedServiceKeys(project *types.Project, s types.ServiceConfig) []string {
+	var keys []string
+	if len(s.VolumesFrom) > 0 {
+		keys = append(keys, "volumes_from")
+	}
+	if len(s.Gpus) > 0 || (s.Deploy != nil && s.Deploy.Resources.Reservations != nil && len(s.Deploy.Resources.Reservations.Devices) > 0) {
+		keys = append(keys, "gpus")
+	}
+	if s.BlkioConfig != nil {
+		keys = append(keys, "blkio_config")
+	}
+	if s.CredentialSpec != nil {
+		keys = append(keys, "credential_spec")
+	}
+	if s.Provider != nil {
+		keys = append(keys, "provider")
+	}
+	if len(s.Models) > 0 {
+		keys = append(keys, "models")
+	}
+	if len(s.PreStart) > 0 || len(s.PostStart) > 0 || len(s.PreStop) > 0 {
+		keys = append(keys, "lifecycle hooks")
+	}
+	if s.UseAPISocket {
+		keys = append(keys, "use_api_socket")
+	}
+	for _, link := range s.Links {
+		if strings.Contains(link, ":") {
+			keys = append(keys, "links with aliases")
+			break
+		}
+	}
+	for _, secret := range s.Secrets {
+		if project.Secrets[secret.Source].File == "" {
+			keys = append(keys, "secrets not backed by a file")
+			break
+		}
+	}
+	for _, config := range s.Configs {
+		if project.Configs[config.Source].File == "" {
+			keys = append(keys, "configs not backed by a file")
+			break
+		}
+	}
+	for _, v := range s.Volumes {
+		switch v.Type {
+		case types.VolumeTypeBind, types.VolumeTypeVolume, types.VolumeTypeTmpfs:
+		default:
+			keys = append(keys, v.Type+" volumes")
+		}
+	}
+	return keys
+}
+
+// serviceContainerSpec translates one container of a compose service into an
+// engine create request. containerID resolves `network_mode: service:<name>`
+// to a running container of that service.
+func serviceContainerSpec(project *types.Project, s types.ServiceConfig, number int, hash, imageID string, src composeSource, containerID func(service string) (string, error)) (containerSpec, error) {
+	if keys := unsupportedServiceKeys(project, s); len(keys) > 0 {
+		return containerSpec{}, fmt.Errorf("service %s uses %s, which LogDeck cannot deploy; use docker compose for this project", s.Name, strings.Join(keys, ", "))
+	}
+
+	labels := map[string]string{}
+	maps.Copy(labels, s.Labels)
+	maps.Copy(labels, s.CustomLabels)
+	maps.Copy(labels, map[string]string{
+		composeProjectLabel:     project.Name,
+		composeServiceLabel:     s.Name,
+		composeNumberLabel:      strconv.Itoa(number),
+		composeOneoffLabel:      "False",
+		composeHashLabel:        hash,
+		composeImageLabel:       imageID,
+		composeConfigFilesLabel: strings.Join(src.ConfigFiles, ","),
+		composeWorkingDirLabel:  src.WorkingDir,
+	})
+	if len(src.EnvFiles) > 0 {
+		labels[composeEnvFileLabel] = strings.Join(src.EnvFiles, ",")
+	}
+
+	config := &container.Config{
+		Image:        serviceImage(project, s),
+		Cmd:          []string(s.Command),
+		Entrypoint:   []string(s.Entrypoint),
+		Env:          serviceEnv(s.Environment),
+		Labels:       labels,
+		User:         s.User,
+		WorkingDir:   s.WorkingDir,
+		Hostname:     s.Hostname,
+		Domainname:   s.DomainName,
+		Tty:          s.Tty,
+		OpenStdin:    s.StdinOpen,
+		StopSignal:   s.StopSignal,
+		ExposedPorts: nat.PortSet{},
+		Healthcheck:  healthConfig(s.HealthCheck),
+	}
+	if s.StopGracePeriod != nil {
+		seconds := int(time.Duration(*s.StopGracePeriod).Seconds())
+		config.StopTimeout = &seconds
+	}
+
+	host := &container.HostConfig{
+		PortBindings:   nat.PortMap{},
+		RestartPolicy:  restartPolicy(s),
+		CapAdd:         s.CapAdd,
+		CapDrop:        s.CapDrop,
+		Privileged:     s.Privileged,
+		ExtraHosts:     s.ExtraHosts.AsList(":"),
+		DNS:            s.DNS,
+		DNSSearch:      s.DNSSearch,
+		DNSOptions:     s.DNSOpts,
+		GroupAdd:       s.GroupAdd,
+		IpcMode:        container.IpcMode(s.Ipc),
+		PidMode:        container.PidMode(s.Pid),
+		UTSMode:        container.UTSMode(s.Uts),
+		UsernsMode:     container.UsernsMode(s.UserNSMode),
+		CgroupnsMode:   container.CgroupnsMode(s.Cgroup),
+		Init:           s.Init,
+		ReadonlyRootfs: s.ReadOnly,
+		SecurityOpt:    s.SecurityOpt,
+		ShmSize:        int64(s.ShmSize),
+		Sysctls:        s.Sysctls,
+		StorageOpt:     s.StorageOpt,
+		Runtime:        s.Runtime,
+		Isolation:      container.Isolation(s.Isolation),
+		OomScoreAdj:    int(s.OomScoreAdj),
+		Annotations:    s.Annotations,
+		LogConfig:      logConfig(s),
+		Resources:      serviceResources(s),
+	}
+
+	for _, port := range s.Expose {
+		proto := "tcp"
+		if p, q, ok := strings.Cut(port, "/"); ok {
+			port, proto = p, q
+		}
+		config.ExposedPorts[nat.Port(port+"/"+proto)] = struct{}{}
+	}
+	for _, port := range s.Ports {
+		proto := port.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+		key := nat.Port(fmt.Sprintf("%d/%s", port.Target, proto))
+		config.ExposedPorts[key] = struct{}{}
+		host.PortBindings[key] = append(host.PortBindings[key], nat.PortBinding{HostIP: port.HostIP, HostPort: port.Published})
+	}
+
+	for _, entry := range s.Tmpfs {
+		target, options, _ := strings.Cut(entry, ":")
+		if host.Tmpfs == nil {
+			host.Tmpfs = map[string]string{}
+		}
+		host.Tmpfs[target] = options
+	}
+
+	for _, v := range s.Volumes {
+		switch v.Type {
+		case types.VolumeTypeBind:
+			host.Binds = append(host.Binds, bindSpec(v))
+		case types.VolumeTypeVolume:
+			host.Mounts = append(host.Mounts, volumeMount(project, v))
+		case types.VolumeTypeTmpfs:
+			m := mount.Mount{Type: mount.TypeTmpfs, Target: v.Target, ReadOnly: v.ReadOnly}
+			if v.Tmpfs != nil {
+				m.TmpfsOptions = &mount.TmpfsOptions{SizeBytes: int64(v.Tmpfs.Size), Mode: os.FileMode(v.Tmpfs.Mode)}
+			}
+			host.Mounts = append(host.Mounts, m)
+		}
+	}
+	// File-backed secrets and configs are read-only bind mounts, which is
+	// what docker compose does outside swarm too.
+	for _, secret := range s.Secrets {
+		target := secret.Target
+		if target == "" {
+			target = secret.Source
+		}
+		if !path.IsAbs(target) {
+			target = "/run/secrets/" + target
+		}
+		host.Binds = append(host.Binds, project.Secrets[secret.Source].File+":"+target+":ro")
+	}
+	for _, cfg := range s.Configs {
+		target := cfg.Target
+		if target == "" {
+			target = "/" + cfg.Source
+		}
+		host.Binds = append(host.Binds, project.Configs[cfg.Source].File+":"+target+":ro")
+	}
+
+	spec := containerSpec{
+		Name:       serviceContainerName(project, s, number),
+		Config:     config,
+		HostConfig: host,
+	}
+
+	if mode, ok := strings.CutPrefix(s.NetworkMode, "service:"); ok {
+		id, err := containerID(mode)
+		if err != nil {
+			return containerSpec{}, fmt.Errorf("service %s shares the network of %s: %w", s.Name, mode, err)
+		}
+		host.NetworkMode = container.NetworkMode("container:" + id)
+		return spec, nil
+	}
+	if s.NetworkMode != "" {
+		host.NetworkMode = container.NetworkMode(s.NetworkMode)
+		return spec, nil
+	}
+
+	endpoints := map[string]*network.EndpointSettings{}
+	for i, key := range s.NetworksByPriority() {
+		netName := project.Networks[key].Name
+		endpoint := &network.EndpointSettings{Aliases: []string{s.Name}}
+		if cfg := s.Networks[key]; cfg != nil {
+			endpoint.Aliases = append(endpoint.Aliases, cfg.Aliases...)
+			endpoint.DriverOpts = cfg.DriverOpts
+			endpoint.GwPriority = cfg.GatewayPriority
+			endpoint.MacAddress = cfg.MacAddress
+			if cfg.Ipv4Address != "" || cfg.Ipv6Address != "" || len(cfg.LinkLocalIPs) > 0 {
+				endpoint.IPAMConfig = &network.EndpointIPAMConfig{
+					IPv4Address:  cfg.Ipv4Address,
+					IPv6Address:  cfg.Ipv6Address,
+					LinkLocalIPs: cfg.LinkLocalIPs,
+				}
+			}
+		}
+		if i == 0 {
+			host.NetworkMode = container.NetworkMode(netName)
+			if endpoint.MacAddress == "" {
+				endpoint.MacAddress = s.MacAddress
+			}
+		}
+		endpoints[netName] = endpoint
+	}
+	spec.Networking = &network.NetworkingConfig{EndpointsConfig: endpoints}
+	return spec, nil
+}
+
+// serviceEnv renders a service's environment, sorted so the same file always
+// yields the same container config. Variables compose left unresolved (no
+// value anywhere) are omitted, as docker compose does.
+func serviceEnv(env types.MappingWithEquals) []string {
+	out := make([]string, 0, len(env))
+	for key, value := range env {
+		if value != nil {
+			out = append(out, key+"="+*value)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+func healthConfig(hc *types.HealthCheckConfig) *container.HealthConfig {
+	if hc == nil {
+		return nil
+	}
+	if hc.Disable {
+		return &container.HealthConfig{Test: []string{"NONE"}}
+	}
+	out := &container.HealthConfig{Test: hc.Test}
+	if hc.Interval != nil {
+		out.Interval = time.Duration(*hc.Interval)
+	}
+	if hc.Timeout != nil {
+		out.Timeout = time.Duration(*hc.Timeout)
+	}
+	if hc.StartPeriod != nil {
+		out.StartPeriod = time.Duration(*hc.StartPeriod)
+	}
+	if hc.StartInterval != nil {
+		out.StartInterval = time.Duration(*hc.StartInterval)
+	}
+	if hc.Retries != nil {
+		out.Retries = int(*hc.Retries)
+	}
+	return out
+}
+
+// restartPolicy maps `restart`, falling back to deploy.restart_policy.
+func restartPolicy(s types.ServiceConfig) container.RestartPolicy {
+	if s.Restart == "" && s.Deploy != nil && s.Deploy.RestartPolicy != nil {
+		policy := container.RestartPolicy{Name: container.RestartPolicyAlways}
+		switch s.Deploy.RestartPolicy.Condition {
+		case "none":
+			policy.Name = container.RestartPolicyDisabled
+		case "on-failure":
+			policy.Name = container.RestartPolicyOnFailure
+			if s.Deploy.RestartPolicy.MaxAttempts != nil {
+				policy.MaximumRetryCount = int(*s.Deploy.RestartPolicy.MaxAttempts)
+			}
+		}
+		return policy
+	}
+	name, retries, _ := strings.Cut(s.Restart, ":")
+	policy := container.RestartPolicy{Name: container.RestartPolicyMode(name)}
+	if name == "" {
+		policy.Name = container.RestartPolicyDisabled
+	}
+	if n, err := strconv.Atoi(retries); err == nil {
+		policy.MaximumRetryCount = n
+	}
+	return policy
+}
+
+func logConfig(s types.ServiceConfig) container.LogConfig {
+	if s.Logging != nil {
+		return container.LogConfig{Type: s.Logging.Driver, Config: s.Logging.Options}
+	}
+	return container.LogConfig{Type: s.LogDriver, Config: s.LogOpt}
+}
+
+// serviceResources merges the top-level limits with deploy.resources, which
+// docker compose also applies outside swarm.
+func serviceResources(s types.ServiceConfig) container.Resources {
+	r := container.Resources{
+		Memory:             int64(s.MemLimit),
+		MemoryReservation:  int64(s.MemReservation),
+		MemorySwap:         int64(s.MemSwapLimit),
+		NanoCPUs:           int64(s.CPUS * 1e9),
+		CPUShares:          s.CPUShares,
+		CPUPeriod:          s.CPUPeriod,
+		CPUQuota:           s.CPUQuota,
+		CPURealtimePeriod:  s.CPURTPeriod,
+		CPURealtimeRuntime: s.CPURTRuntime,
+		CpusetCpus:         s.CPUSet,
+		CgroupParent:       s.CgroupParent,
+		DeviceCgroupRules:  s.DeviceCgroupRules,
+	}
+	if s.MemSwappiness != 0 {
+		swappiness := int64(s.MemSwappiness)
+		r.MemorySwappiness = &swappiness
+	}
+	if s.OomKillDisable {
+		disable := true
+		r.OomKillDisable = &disable
+	}
+	if s.PidsLimit != 0 {
+		limit := s.PidsLimit
+		r.PidsLimit = &limit
+	}
+	if s.Deploy != nil {
+		if limits := s.Deploy.Resources.Limits; limits != nil {
+			if limits.MemoryBytes > 0 {
+				r.Memory = int64(limits.MemoryBytes)
+			}
+			if limits.NanoCPUs > 0 {
+				r.NanoCPUs = int64(float64(limits.NanoCPUs) * 1e9)
+			}
+			if limits.Pids > 0 {
+				pids := limits.Pids
+				r.PidsLimit = &pids
+			}
+		}
+		if res := s.Deploy.Resources.Reservations; res != nil && res.MemoryBytes > 0 {
+			r.MemoryReservation = int64(res.MemoryBytes)
+		}
+	}
+	for name, u := range s.Ulimits {
+		soft, hard := int64(u.Soft), int64(u.Hard)
+		if u.Single != 0 {
+			soft, hard = int64(u.Single), int64(u.Single)
+		}
+		r.Ulimits = append(r.Ulimits, &container.Ulimit{Name: name, Soft: soft, Hard: hard})
+	}
+	slices.SortFunc(r.Ulimits, func(a, b *container.Ulimit) int { return strings.Compare(a.Name, b.Name) })
+	for _, d := range s.Devices {
+		permissions := d.Permissions
+		if permissions == "" {
+			permissions = "rwm"
+		}
+		r.Devices = append(r.Devices, container.DeviceMapping{PathOnHost: d.Source, PathInContainer: d.Target, CgroupPermissions: permissions})
+	}
+	return r
+}
+
+// bindSpec renders a bind volume in the `-v` form, which, unlike a mount,
+// creates a missing host directory: compose's create_host_path default.
+func bindSpec(v types.ServiceVolumeConfig) string {
+	mode := "rw"
+	if v.ReadOnly {
+		mode = "ro"
+	}
+	options := []string{mode}
+	if v.Bind != nil {
+		if v.Bind.SELinux != "" {
+			options = append(options, v.Bind.SELinux)
+		}
+		if v.Bind.Propagation != "" {
+			options = append(options, v.Bind.Propagation)
+		}
+	}
+	return v.Source + ":" + v.Target + ":" + strings.Join(options, ",")
+}
+
+// volumeMount mounts a named or anonymous volume. Named ones are looked up in
+// the project so `data` mounts the `<project>_data` volume up creates.
+func volumeMount(project *types.Project, v types.ServiceVolumeConfig) mount.Mount {
+	source := v.Source
+	if vol, ok := project.Volumes[v.Source]; ok && vol.Name != "" {
+		source = vol.Name
+	}
+	m := mount.Mount{Type: mount.TypeVolume, Source: source, Target: v.Target, ReadOnly: v.ReadOnly}
+	if v.Volume != nil {
+		m.VolumeOptions = &mount.VolumeOptions{NoCopy: v.Volume.NoCopy, Subpath: v.Volume.Subpath, Labels: v.Volume.Labels}
+	}
+	return m
+}
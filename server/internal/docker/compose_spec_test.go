@@ -0,0 +1,185 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestServiceContainerSpec(t *testing.T) {
+	labels := writeComposeProject(t, `services:
+  web:
+    image: nginx:${NGINX_TAG}
+    ports: ["127.0.0.1:8080:80"]
+    restart: on-failure:3
+    mem_limit: 256m
+    volumes:
+      - ./site:/srv:ro
+      - data:/data
+    networks:
+      front:
+        aliases: [www]
+    secrets: [token]
+volumes:
+  data: {}
+networks:
+  front: {}
+secrets:
+  token:
+    file: ./token.txt
+`)
+	src := composeSource{ConfigFiles: []string{labels[composeConfigFilesLabel]}, WorkingDir: labels[composeWorkingDirLabel]}
+	project, err := loadComposeProject(context.Background(), "shop", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := serviceContainerSpec(project, project.Services["web"], 1, "hash", "sha256:img", src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Name != "shop-web-1" || spec.Config.Image != "nginx:1.27" {
+		t.Errorf("name %q image %q, want shop-web-1 running nginx:1.27", spec.Name, spec.Config.Image)
+	}
+	if got := spec.HostConfig.PortBindings[nat.Port("80/tcp")]; len(got) != 1 || got[0].HostIP != "127.0.0.1" || got[0].HostPort != "8080" {
+		t.Errorf("port bindings = %v, want 127.0.0.1:8080 -> 80/tcp", spec.HostConfig.PortBindings)
+	}
+	if p := spec.HostConfig.RestartPolicy; p.Name != container.RestartPolicyOnFailure || p.MaximumRetryCount != 3 {
+		t.Errorf("restart policy = %+v, want on-failure with 3 retries", p)
+	}
+	if spec.HostConfig.Memory != 256<<20 {
+		t.Errorf("memory = %d, want 256MiB", spec.HostConfig.Memory)
+	}
+
+	dir := labels[composeWorkingDirLabel]
+	for _, want := range []string{
+		filepath.Join(dir, "site") + ":/srv:ro",
+		filepath.Join(dir, "token.txt") + ":/run/secrets/token:ro",
+	} {
+		if !slices.Contains(spec.HostConfig.Binds, want) {
+			t.Errorf("binds = %v, want %q", spec.HostConfig.Binds, want)
+		}
+	}
+	if !slices.ContainsFunc(spec.HostConfig.Mounts, func(m mount.Mount) bool {
+		return m.Type == mount.TypeVolume && m.Source == "shop_data" && m.Target == "/data"
+	}) {
+		t.Errorf("mounts = %+v, want the project's shop_data volume", spec.HostConfig.Mounts)
+	}
+
+	if spec.HostConfig.NetworkMode != "shop_front" {
+		t.Errorf("network mode = %q, want shop_front", spec.HostConfig.NetworkMode)
+	}
+	endpoint := spec.Networking.EndpointsConfig["shop_front"]
+	if endpoint == nil || !slices.Equal(endpoint.Aliases, []string{"web", "www"}) {
+		t.Errorf("endpoints = %+v, want shop_front with aliases web and www", spec.Networking.EndpointsConfig)
+	}
+}
+
+func TestServiceContainerSpecRejectsUnsupportedKeys(t *testing.T) {
+	labels := writeComposeProject(t, `services:
+  base:
+    image: busybox
+  web:
+    image: nginx
+    volumes_from: [base]
+`)
+	src := composeSource{ConfigFiles: []string{labels[composeConfigFilesLabel]}, WorkingDir: labels[composeWorkingDirLabel]}
+	project, err := loadComposeProject(context.Background(), "shop", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = serviceContainerSpec(project, project.Services["web"], 1, "hash", "sha256:img", src, nil)
+	if err == nil || !strings.Contains(err.Error(), "volumes_from") {
+		t.Fatalf("err = %v, want volumes_from reported as unsupported", err)
+	}
+}
+
+func TestServiceConfigHashIgnoresScaleAndOrder(t *testing.T) {
+	labels := writeComposeProject(t, testComposeFile)
+	src := composeSource{ConfigFiles: []string{labels[composeConfigFilesLabel]}, WorkingDir: labels[composeWorkingDirLabel]}
+	project, err := loadComposeProject(context.Background(), "shop", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := project.Services["web"]
+	base, _ := serviceConfigHash(web, nil)
+
+	scaled := web
+	scaled.SetScale(3)
+	scaled.DependsOn = nil
+	if h, _ := serviceConfigHash(scaled, nil); h != base {
+		t.Error("scale or depends_on changed the hash")
+	}
+	changed := web
+	changed.Image = "nginx:1.28"
+	if h, _ := serviceConfigHash(changed, nil); h == base {
+		t.Error("a new image did not change the hash")
+	}
+}
+
+// TestServiceConfigHashMatchesDockerCompose pins the config-hash labels docker
+// compose v5.5.1 (built with compose-go v2.15.0) writes for this file, so
+// containers either tool created are left alone by the other. metrics shares
+// the network of web's container.
+func TestServiceConfigHashMatchesDockerCompose(t *testing.T) {
+	labels := writeComposeProject(t, `services:
+  web:
+    image: nginx:1.27
+    depends_on: [db]
+    ports: ["8080:80"]
+    environment:
+      MODE: prod
+    labels:
+      tier: frontend
+    command: ["nginx", "-g", "daemon off;"]
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost"]
+      interval: 30s
+    deploy:
+      replicas: 2
+  db:
+    image: postgres:16
+    restart: unless-stopped
+    volumes:
+      - data:/var/lib/postgresql/data
+  metrics:
+    image: prom/node-exporter
+    network_mode: service:web
+volumes:
+  data: {}
+`)
+	src := composeSource{ConfigFiles: []string{labels[composeConfigFilesLabel]}, WorkingDir: labels[composeWorkingDirLabel]}
+	project, err := loadComposeProject(context.Background(), "shop", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webID := strings.Repeat("0123456789abcdef", 4)
+	containerID := func(service string) (string, error) {
+		if service != "web" {
+			return "", fmt.Errorf("service %s has no container", service)
+		}
+		return webID, nil
+	}
+
+	want := map[string]string{
+		"web":     "5b2227be4b0613f409f4503d6bb4b37b8cdb78c0bb8f51ebe6f28e0524f0bd78",
+		"db":      "a5e39bab89bd0e1595cb9ef00d9b67783fb9b5ab1572402f8d5b31ccc6da394b",
+		"metrics": "0909574c8f61fa33fc9a16b42317693dd47236f4065130ec86e5dc37b0432817",
+	}
+	for name, hash := range want {
+		got, err := serviceConfigHash(project.Services[name], containerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != hash {
+			t.Errorf("%s hashes to %s, docker compose labels it %s", name, got, hash)
+		}
+	}
+}
@@ -157,9 +157,49 @@ func (c *MultiHostClient) SetEnvVariables(ctx context.Context, hostName, id stri
 }
 
 // recreateContainerWithEnv replaces a container with an identical one whose
-// env is envs. The original is renamed aside (not removed) until the
-// replacement is running, so any failure rolls back to the original.
+// env is envs, leaving it stopped if it was stopped.
 func recreateContainerWithEnv(ctx context.Context, apiClient containerRecreateAPI, inspect container.InspectResponse, envs []string) (string, error) {
+	// Copy the config so we don't mutate the shared InspectResponse in place.
+	newConfig := *inspect.Config
+	newConfig.Env = envs
+
+	var networking *network.NetworkingConfig
+	if inspect.NetworkSettings != nil {
+		networking = &network.NetworkingConfig{
+			EndpointsConfig: inspect.NetworkSettings.Networks,
+		}
+	}
+
+	// Podman reports a CPU limit as both NanoCpus and CpuQuota/CpuPeriod, but
+	// rejects a create that carries both ("NanoCpus conflicts with CpuPeriod and
+	// CpuQuota"). They express the same limit, and the quota/period pair is what
+	// the resource-update path writes on Podman, so NanoCpus is the one to drop.
+	// Copy the host config rather than mutate the shared InspectResponse.
+	hostConfig := inspect.HostConfig
+	if hostConfig != nil {
+		clone := *hostConfig
+		if clone.NanoCPUs > 0 && clone.CPUQuota > 0 {
+			clone.NanoCPUs = 0
+		}
+		hostConfig = &clone
+	}
+
+	spec := containerSpec{
+		Name:       strings.TrimPrefix(inspect.Name, "/"),
+		Config:     &newConfig,
+		HostConfig: hostConfig,
+		Networking: networking,
+	}
+	// Only start the replacement if the original was running, so editing
+	// env vars on a stopped container leaves it stopped.
+	wasRunning := inspect.State != nil && inspect.State.Running
+	return replaceContainer(ctx, apiClient, inspect, spec, wasRunning)
+}
+
+// replaceContainer swaps a container for one created from spec, starting it
+// if start is set. The original is renamed aside (not removed) until the
+// replacement is running, so any failure rolls back to the original.
+func replaceContainer(ctx context.Context, apiClient containerRecreateAPI, inspect container.InspectResponse, spec containerSpec, start bool) (string, error) {
 	containerName := strings.TrimPrefix(inspect.Name, "/")
 	wasRunning := inspect.State != nil && inspect.State.Running
 
@@ -196,47 +236,20 @@ func recreateContainerWithEnv(ctx context.Context, apiClient containerRecreateAP
 		return "", err
 	}
 
-	// Copy the config so we don't mutate the shared InspectResponse in place.
-	newConfig := *inspect.Config
-	newConfig.Env = envs
-
-	var networking *network.NetworkingConfig
-	if inspect.NetworkSettings != nil {
-		networking = &network.NetworkingConfig{
-			EndpointsConfig: inspect.NetworkSettings.Networks,
-		}
-	}
-
-	// Podman reports a CPU limit as both NanoCpus and CpuQuota/CpuPeriod, but
-	// rejects a create that carries both ("NanoCpus conflicts with CpuPeriod and
-	// CpuQuota"). They express the same limit, and the quota/period pair is what
-	// the resource-update path writes on Podman, so NanoCpus is the one to drop.
-	// Copy the host config rather than mutate the shared InspectResponse.
-	hostConfig := inspect.HostConfig
-	if hostConfig != nil {
-		clone := *hostConfig
-		if clone.NanoCPUs > 0 && clone.CPUQuota > 0 {
-			clone.NanoCPUs = 0
-		}
-		hostConfig = &clone
-	}
-
 	resp, err := apiClient.ContainerCreate(
 		ctx,
-		&newConfig,
-		hostConfig,
-		networking,
+		spec.Config,
+		spec.HostConfig,
+		spec.Networking,
 		nil,
-		containerName,
+		spec.Name,
 	)
 	if err != nil {
 		rollback("")
 		return "", err
 	}
 
-	// Only start the replacement if the original was running, so editing
-	// env vars on a stopped container leaves it stopped.
-	if wasRunning {
+	if start {
 		if err := apiClient.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
 			rollback(resp.ID)
 			return "", err
@@ -245,7 +258,7 @@ func recreateContainerWithEnv(ctx context.Context, apiClient containerRecreateAP
 
 	// Replacement is running; removing the renamed original is best-effort.
 	if err := apiClient.ContainerRemove(cleanupCtx, inspect.ID, container.RemoveOptions{}); err != nil {
-		log.Printf("Warning: failed to remove old container %s after env update: %v", tempName, err)
+		log.Printf("Warning: failed to remove old container %s after recreating it: %v", tempName, err)
 	}
 
 	return resp.ID, nil
@@ -0,0 +1,344 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-connections/nat"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+)
+
+// ErrImagePinned is returned when a container's image cannot be updated by
+// pulling its tag: it was created from a digest or an image ID.
+var ErrImagePinned = errors.New("container image is pinned to a digest or image ID")
+
+// imageUpdateAPI is the subset of the Docker client an image update check uses.
+type imageUpdateAPI interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	DistributionInspect(ctx context.Context, imageRef, encodedRegistryAuth string) (registry.DistributionInspect, error)
+}
+
+// imagePullRecreateAPI is the subset of the Docker client a pull-and-recreate uses.
+type imagePullRecreateAPI interface {
+	containerRecreateAPI
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error)
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+}
+
+// CheckImageUpdates compares the image of every running container on a host
+// with what its tag points to in the registry now. The registry is asked
+// through the host's engine, once per distinct image, with registry
+// credentials only if the engine is local (see hostRegistryAuth).
+func (c *MultiHostClient) CheckImageUpdates(ctx context.Context, hostName string) ([]models.ImageUpdateStatus, error) {
+	apiClient, err := c.GetClient(hostName)
+	if err != nil {
+		return nil, err
+	}
+	return checkImageUpdates(ctx, apiClient, hostName, c.hostRegistryAuth(hostName))
+}
+
+func checkImageUpdates(ctx context.Context, api imageUpdateAPI, hostName string, auth func(ref string) string) ([]models.ImageUpdateStatus, error) {
+	containers, err := api.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	type remote struct {
+		digest string
+		err    error
+	}
+	remotes := map[string]remote{}
+
+	statuses := make([]models.ImageUpdateStatus, 0, len(containers))
+	for _, ctr := range containers {
+		status := models.ImageUpdateStatus{
+			Host:          hostName,
+			ContainerID:   ctr.ID,
+			ContainerName: summaryName(ctr),
+			Image:         ctr.Image,
+			ImageID:       ctr.ImageID,
+		}
+
+		ref, err := containerImageRef(ctx, api, ctr)
+		if err == nil {
+			status.Image = ref
+			ref, err = updatableRef(ref)
+		}
+		if err == nil {
+			r, ok := remotes[ref]
+			if !ok {
+				var dist registry.DistributionInspect
+				dist, r.err = api.DistributionInspect(ctx, ref, auth(ref))
+				r.digest = dist.Descriptor.Digest.String()
+				remotes[ref] = r
+			}
+			status.RemoteDigest = r.digest
+			err = r.err
+		}
+		if err == nil {
+			status.UpdateAvailable, err = imageOutdated(ctx, api, ctr.ImageID, status.RemoteDigest)
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		status.CheckedAt = time.Now().UnixMilli()
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// containerImageRef returns the image reference a container was created from.
+// The list reports the image ID instead once the tag has moved on to another
+// image (after a pull, say), so the reference is then read from the
+// container's config.
+func containerImageRef(ctx context.Context, api imageUpdateAPI, ctr container.Summary) (string, error) {
+	if !looksLikeImageID(ctr.Image) {
+		return ctr.Image, nil
+	}
+	inspect, err := api.ContainerInspect(ctx, ctr.ID)
+	if err != nil {
+		return "", err
+	}
+	if inspect.Config == nil {
+		return ctr.Image, nil
+	}
+	return inspect.Config.Image, nil
+}
+
+// looksLikeImageID reports whether ref is an image ID, full or short, rather
+// than a name.
+func looksLikeImageID(ref string) bool {
+	id := strings.TrimPrefix(ref, "sha256:")
+	if id != ref {
+		return true
+	}
+	if len(id) < 12 || len(id) > 64 {
+		return false
+	}
+	return strings.Trim(id, "0123456789abcdef") == ""
+}
+
+// updatableRef normalizes a container's image reference to the tag to check,
+// e.g. nginx to docker.io/library/nginx:latest. Containers created from a
+// digest or an image ID can never move, so they are rejected.
+func updatableRef(ref string) (string, error) {
+	if looksLikeImageID(ref) {
+		return "", ErrImagePinned
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("parse image reference %q: %w", ref, err)
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		return "", ErrImagePinned
+	}
+	return reference.TagNameOnly(named).String(), nil
+}
+
+// imageOutdated reports whether the local image differs from the one behind
+// remoteDigest. An image pulled by tag records the registry digest in
+// RepoDigests (or, with the containerd image store, is identified by it); an
+// image without any was built locally and cannot be compared.
+func imageOutdated(ctx context.Context, api imageUpdateAPI, imageID, remoteDigest string) (bool, error) {
+	local, err := api.ImageInspect(ctx, imageID)
+	if err != nil {
+		return false, err
+	}
+	if local.ID == remoteDigest {
+		return false, nil
+	}
+	for _, repoDigest := range local.RepoDigests {
+		if strings.HasSuffix(repoDigest, "@"+remoteDigest) {
+			return false, nil
+		}
+	}
+	if len(local.RepoDigests) == 0 {
+		return false, errors.New("the local image has no registry digest (built locally?)")
+	}
+	return true, nil
+}
+
+// PullAndRecreateContainer pulls the tag a container was created from and, if
+// that brought a newer image, recreates the container on it with its current
+// configuration. Returns the container's ID afterwards and whether it was
+// recreated; an up-to-date container is left alone.
+func (c *MultiHostClient) PullAndRecreateContainer(ctx context.Context, hostName, id string) (string, bool, error) {
+	apiClient, err := c.GetClient(hostName)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Same lock as env edits: both replace the container.
+	lock := envEditLock(hostName, id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return pullAndRecreate(ctx, apiClient, id, c.hostRegistryAuth(hostName))
+}
+
+func pullAndRecreate(ctx context.Context, api imagePullRecreateAPI, id string, auth func(ref string) string) (string, bool, error) {
+	inspect, err := api.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", false, err
+	}
+	if inspect.Config == nil {
+		return "", false, fmt.Errorf("container %s has no config", id)
+	}
+	ref, err := updatableRef(inspect.Config.Image)
+	if err != nil {
+		return "", false, err
+	}
+
+	// The old image is inspected before the pull, while the tag still names
+	// it: its defaults are stripped from the container's config below, and
+	// its platform is the one to pull.
+	old, err := api.ImageInspect(ctx, inspect.Image)
+	if err != nil {
+		return "", false, err
+	}
+
+	body, err := api.ImagePull(ctx, ref, image.PullOptions{RegistryAuth: auth(ref), Platform: imagePlatform(old)})
+	if err != nil {
+		return "", false, fmt.Errorf("pull %s: %w", ref, err)
+	}
+	err = readPullProgress(body, nil)
+	body.Close()
+	if err != nil {
+		return "", false, fmt.Errorf("pull %s: %w", ref, err)
+	}
+
+	pulled, err := api.ImageInspect(ctx, ref)
+	if err != nil {
+		return "", false, err
+	}
+	if pulled.ID == inspect.Image {
+		return inspect.ID, false, nil
+	}
+
+	cfg := withoutImageDefaults(*inspect.Config, old.Config)
+	recreate := inspect
+	recreate.Config = &cfg
+	newID, err := recreateContainerWithEnv(ctx, api, recreate, cfg.Env)
+	if err != nil {
+		return "", false, err
+	}
+	return newID, true, nil
+}
+
+// readPullProgress drains an image pull's JSON progress stream, calling
+// onStatus (if set) for each message that carries a status. The engine
+// reports a failed pull inside the stream, so that becomes the error.
+func readPullProgress(body io.Reader, onStatus func(id, status string)) error {
+	decoder := json.NewDecoder(body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return errors.New(msg.Error.Message)
+		}
+		if onStatus != nil && msg.Status != "" {
+			onStatus(msg.ID, msg.Status)
+		}
+	}
+}
+
+// imagePlatform formats an image's platform for a pull, e.g. linux/arm64/v8.
+func imagePlatform(img image.InspectResponse) string {
+	if img.Os == "" || img.Architecture == "" {
+		return ""
+	}
+	platform := img.Os + "/" + img.Architecture
+	if img.Variant != "" {
+		platform += "/" + img.Variant
+	}
+	return platform
+}
+
+// withoutImageDefaults returns cfg without the settings it inherited from the
+// image it was created from, so the replacement takes the new image's values
+// rather than carrying the old image's forward (an image's version env var,
+// for example). Anything the container set differently is kept.
+func withoutImageDefaults(cfg container.Config, img *dockerspec.DockerOCIImageConfig) container.Config {
+	if img == nil {
+		return cfg
+	}
+
+	cfg.Env = slices.DeleteFunc(slices.Clone(cfg.Env), func(env string) bool {
+		return slices.Contains(img.Env, env)
+	})
+	if cfg.Labels != nil {
+		labels := maps.Clone(cfg.Labels)
+		maps.DeleteFunc(labels, func(key, value string) bool {
+			imageValue, ok := img.Labels[key]
+			return ok && imageValue == value
+		})
+		cfg.Labels = labels
+	}
+	// Setting an entrypoint on create discards the image's cmd, so the cmd can
+	// only be left to the image when the entrypoint is too.
+	if slices.Equal(cfg.Entrypoint, img.Entrypoint) {
+		cfg.Entrypoint = nil
+		if slices.Equal(cfg.Cmd, img.Cmd) {
+			cfg.Cmd = nil
+		}
+	}
+	if cfg.WorkingDir == img.WorkingDir {
+		cfg.WorkingDir = ""
+	}
+	if cfg.User == img.User {
+		cfg.User = ""
+	}
+	if cfg.StopSignal == img.StopSignal {
+		cfg.StopSignal = ""
+	}
+	if cfg.ExposedPorts != nil {
+		ports := nat.PortSet{}
+		for port := range cfg.ExposedPorts {
+			if _, ok := img.ExposedPorts[string(port)]; !ok {
+				ports[port] = struct{}{}
+			}
+		}
+		cfg.ExposedPorts = ports
+	}
+	if cfg.Volumes != nil {
+		volumes := map[string]struct{}{}
+		for path := range cfg.Volumes {
+			if _, ok := img.Volumes[path]; !ok {
+				volumes[path] = struct{}{}
+			}
+		}
+		cfg.Volumes = volumes
+	}
+	if hc := cfg.Healthcheck; hc != nil && img.Healthcheck != nil &&
+		slices.Equal(hc.Test, img.Healthcheck.Test) &&
+		hc.Interval == img.Healthcheck.Interval &&
+		hc.Timeout == img.Healthcheck.Timeout &&
+		hc.StartPeriod == img.Healthcheck.StartPeriod &&
+		hc.StartInterval == img.Healthcheck.StartInterval &&
+		hc.Retries == img.Healthcheck.Retries {
+		cfg.Healthcheck = nil
+	}
+	return cfg
+}
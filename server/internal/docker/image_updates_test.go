@@ -0,0 +1,278 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type fakeImageAPI struct {
+	fakeRecreateAPI
+	containers []container.Summary
+	inspects   map[string]container.InspectResponse // container ID -> inspect
+	images     map[string]image.InspectResponse     // image ID or ref -> image
+	remote     map[string]string                    // ref -> registry digest
+	pulls      map[string]image.InspectResponse     // ref -> image the pull tags it to
+	distCalls  int
+	pullOpts   image.PullOptions
+	created    *container.Config
+}
+
+func (f *fakeImageAPI) ContainerList(context.Context, container.ListOptions) ([]container.Summary, error) {
+	return f.containers, nil
+}
+
+func (f *fakeImageAPI) ContainerInspect(_ context.Context, id string) (container.InspectResponse, error) {
+	inspect, ok := f.inspects[id]
+	if !ok {
+		return container.InspectResponse{}, errors.New("no such container")
+	}
+	return inspect, nil
+}
+
+func (f *fakeImageAPI) ImageInspect(_ context.Context, ref string, _ ...client.ImageInspectOption) (image.InspectResponse, error) {
+	img, ok := f.images[ref]
+	if !ok {
+		return image.InspectResponse{}, errors.New("no such image: " + ref)
+	}
+	return img, nil
+}
+
+func (f *fakeImageAPI) DistributionInspect(_ context.Context, ref, _ string) (registry.DistributionInspect, error) {
+	f.distCalls++
+	d, ok := f.remote[ref]
+	if !ok {
+		return registry.DistributionInspect{}, errors.New("unauthorized")
+	}
+	return registry.DistributionInspect{Descriptor: ocispec.Descriptor{Digest: digest.Digest(d)}}, nil
+}
+
+func (f *fakeImageAPI) ImagePull(_ context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	f.pullOpts = options
+	if img, ok := f.pulls[ref]; ok {
+		f.images[ref] = img
+	}
+	return io.NopCloser(strings.NewReader(`{"status":"Pulling from library/nginx"}` + "\n")), nil
+}
+
+func (f *fakeImageAPI) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.created = config
+	return f.fakeRecreateAPI.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+func noAuth(string) string { return "" }
+
+func TestCheckImageUpdates(t *testing.T) {
+	const (
+		oldDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		newDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	api := &fakeImageAPI{
+		containers: []container.Summary{
+			{ID: "c1", Names: []string{"/web-1"}, Image: "nginx", ImageID: "sha256:nginx-old"},
+			{ID: "c2", Names: []string{"/web-2"}, Image: "nginx:latest", ImageID: "sha256:nginx-old"},
+			// The tag has moved on locally, so the list shows the image ID.
+			{ID: "c3", Names: []string{"/web-3"}, Image: "0123456789ab", ImageID: "sha256:nginx-old"},
+			{ID: "c4", Names: []string{"/cache"}, Image: "redis:7", ImageID: "sha256:redis"},
+			{ID: "c5", Names: []string{"/pinned"}, Image: "alpine@" + oldDigest, ImageID: "sha256:alpine"},
+			{ID: "c6", Names: []string{"/app"}, Image: "myapp:dev", ImageID: "sha256:myapp"},
+		},
+		inspects: map[string]container.InspectResponse{
+			"c3": {Config: &container.Config{Image: "nginx:latest"}},
+		},
+		images: map[string]image.InspectResponse{
+			"sha256:nginx-old": {ID: "sha256:nginx-old", RepoDigests: []string{"nginx@" + oldDigest}},
+			"sha256:redis":     {ID: "sha256:redis", RepoDigests: []string{"redis@" + newDigest}},
+			"sha256:myapp":     {ID: "sha256:myapp"},
+		},
+		remote: map[string]string{
+			"docker.io/library/nginx:latest": newDigest,
+			"docker.io/library/redis:7":      newDigest,
+			"docker.io/library/myapp:dev":    newDigest,
+		},
+	}
+
+	statuses, err := checkImageUpdates(context.Background(), api, "prod", noAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 6 {
+		t.Fatalf("got %d statuses, want 6: %+v", len(statuses), statuses)
+	}
+	for i, want := range []struct {
+		available bool
+		err       string
+	}{
+		{available: true},
+		{available: true},
+		{available: true},
+		{available: false},
+		{err: "pinned"},
+		{err: "no registry digest"},
+	} {
+		got := statuses[i]
+		if got.UpdateAvailable != want.available || (want.err == "") != (got.Error == "") || !strings.Contains(got.Error, want.err) {
+			t.Errorf("%s: available=%t error=%q, want available=%t error containing %q", got.ContainerName, got.UpdateAvailable, got.Error, want.available, want.err)
+		}
+	}
+	if statuses[2].Image != "nginx:latest" {
+		t.Errorf("image = %q, want the container's configured nginx:latest", statuses[2].Image)
+	}
+	// nginx, redis, and myapp: each image is looked up once.
+	if api.distCalls != 3 {
+		t.Errorf("registry lookups = %d, want 3", api.distCalls)
+	}
+}
+
+func nginxContainer() container.InspectResponse {
+	inspect := testInspectResponse(true)
+	inspect.Image = "sha256:nginx-old"
+	inspect.Config = &container.Config{
+		Image:      "nginx:latest",
+		Env:        []string{"PATH=/usr/bin", "NGINX_VERSION=1.27.0", "APP_MODE=prod"},
+		Labels:     map[string]string{"maintainer": "NGINX", "com.docker.compose.project": "shop"},
+		Entrypoint: []string{"/docker-entrypoint.sh"},
+		Cmd:        []string{"nginx", "-g", "daemon off;"},
+		WorkingDir: "/srv",
+	}
+	return inspect
+}
+
+func TestPullAndRecreateStripsOldImageDefaults(t *testing.T) {
+	oldImage := image.InspectResponse{
+		ID: "sha256:nginx-old", Os: "linux", Architecture: "arm64", Variant: "v8",
+		Config: &dockerspec.DockerOCIImageConfig{ImageConfig: ocispec.ImageConfig{
+			Env:        []string{"PATH=/usr/bin", "NGINX_VERSION=1.27.0"},
+			Labels:     map[string]string{"maintainer": "NGINX"},
+			Entrypoint: []string{"/docker-entrypoint.sh"},
+			Cmd:        []string{"nginx", "-g", "daemon off;"},
+		}},
+	}
+	api := &fakeImageAPI{
+		inspects: map[string]container.InspectResponse{"abcdef1234567890": nginxContainer()},
+		images:   map[string]image.InspectResponse{"sha256:nginx-old": oldImage},
+		pulls: map[string]image.InspectResponse{
+			"docker.io/library/nginx:latest": {ID: "sha256:nginx-new"},
+		},
+	}
+
+	id, recreated, err := pullAndRecreate(context.Background(), api, "abcdef1234567890", func(string) string { return "token" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "new-container-id" || !recreated {
+		t.Fatalf("got id %q recreated=%t, want the new container", id, recreated)
+	}
+	if api.pullOpts.RegistryAuth != "token" || api.pullOpts.Platform != "linux/arm64/v8" {
+		t.Errorf("pull options = %+v, want the registry auth and the old image's platform", api.pullOpts)
+	}
+	cfg := api.created
+	if !slices.Equal(cfg.Env, []string{"APP_MODE=prod"}) {
+		t.Errorf("env = %v, want only the container's own APP_MODE", cfg.Env)
+	}
+	if len(cfg.Labels) != 1 || cfg.Labels["com.docker.compose.project"] != "shop" {
+		t.Errorf("labels = %v, want only the compose label", cfg.Labels)
+	}
+	if cfg.Entrypoint != nil || cfg.Cmd != nil || cfg.WorkingDir != "/srv" {
+		t.Errorf("entrypoint %v cmd %v workdir %q, want the image's entrypoint and cmd and the container's /srv", cfg.Entrypoint, cfg.Cmd, cfg.WorkingDir)
+	}
+	if cfg.Image != "nginx:latest" {
+		t.Errorf("image = %q, want the tag kept", cfg.Image)
+	}
+}
+
+func TestPullAndRecreateLeavesUpToDateContainer(t *testing.T) {
+	api := &fakeImageAPI{
+		inspects: map[string]container.InspectResponse{"abcdef1234567890": nginxContainer()},
+		images:   map[string]image.InspectResponse{"sha256:nginx-old": {ID: "sha256:nginx-old"}},
+		pulls: map[string]image.InspectResponse{
+			"docker.io/library/nginx:latest": {ID: "sha256:nginx-old"},
+		},
+	}
+
+	id, recreated, err := pullAndRecreate(context.Background(), api, "abcdef1234567890", noAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "abcdef1234567890" || recreated || len(api.calls) != 0 {
+		t.Errorf("got id %q recreated=%t calls %v, want the container left alone", id, recreated, api.calls)
+	}
+}
+
+func TestPullAndRecreateRejectsPinnedImage(t *testing.T) {
+	inspect := nginxContainer()
+	inspect.Config.Image = "nginx@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	api := &fakeImageAPI{inspects: map[string]container.InspectResponse{"abcdef1234567890": inspect}}
+
+	if _, _, err := pullAndRecreate(context.Background(), api, "abcdef1234567890", noAuth); !errors.Is(err, ErrImagePinned) {
+		t.Fatalf("err = %v, want ErrImagePinned", err)
+	}
+}
+
+func TestRegistryAuthFromDockerConfig(t *testing.T) {
+	dir := t.TempDir()
+	auth := base64.StdEncoding.EncodeToString([]byte("alice:secret"))
+	config := `{"auths":{"https://index.docker.io/v1/":{"auth":"` + auth + `"},"ghcr.io":{"auth":"` + auth + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	orig := dockerConfigDir
+	dockerConfigDir = func() string { return dir }
+	t.Cleanup(func() { dockerConfigDir = orig })
+
+	for _, ref := range []string{"nginx:latest", "ghcr.io/acme/api:1"} {
+		decoded, err := registry.DecodeAuthConfig(registryAuth(ref))
+		if err != nil || decoded.Username != "alice" || decoded.Password != "secret" {
+			t.Errorf("%s: auth = %+v (%v), want alice's credentials", ref, decoded, err)
+		}
+	}
+	if got := registryAuth("quay.io/acme/api"); got != "" {
+		t.Errorf("quay.io: auth = %q, want none", got)
+	}
+}
+
+func TestRemoteHostsGetNoRegistryAuth(t *testing.T) {
+	dir := t.TempDir()
+	auth := base64.StdEncoding.EncodeToString([]byte("alice:secret"))
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"auths":{"https://index.docker.io/v1/":{"auth":"`+auth+`"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	orig := dockerConfigDir
+	dockerConfigDir = func() string { return dir }
+	t.Cleanup(func() { dockerConfigDir = orig })
+
+	c := &MultiHostClient{hosts: []config.DockerHost{
+		{Name: "local", Host: "unix:///var/run/docker.sock"},
+		{Name: "prod", Host: "ssh://root@203.0.113.7"},
+		{Name: "edge", Host: "tcp://203.0.113.8:2376"},
+	}}
+	for host, wantAuth := range map[string]bool{"local": true, "prod": false, "edge": false} {
+		api := &fakeImageAPI{
+			inspects: map[string]container.InspectResponse{"abcdef1234567890": nginxContainer()},
+			images:   map[string]image.InspectResponse{"sha256:nginx-old": {ID: "sha256:nginx-old"}},
+			pulls:    map[string]image.InspectResponse{"docker.io/library/nginx:latest": {ID: "sha256:nginx-old"}},
+		}
+		if _, _, err := pullAndRecreate(context.Background(), api, "abcdef1234567890", c.hostRegistryAuth(host)); err != nil {
+			t.Fatal(err)
+		}
+		if got := api.pullOpts.RegistryAuth != ""; got != wantAuth {
+			t.Errorf("%s: registry auth sent = %t, want %t", host, got, wantAuth)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/AmoabaKelvin/logdeck/internal/models"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 )
 
@@ -28,13 +29,15 @@ const (
 	maxPingFailures = 2
 )
 
-// parseDockerLogs parses the Docker log stream into structured entries,
-// optionally filtering by level and/or search regex.
-func parseDockerLogs(reader io.Reader, levelFilter string, searchRegex *regexp.Regexp) ([]models.LogEntry, error) {
+// parseDockerLogs parses the Docker log stream into structured entries, read
+// and grouped the way the container's format says, optionally filtering by
+// level, search regex, and/or field predicates. Fields are matched on grouped
+// entries, so a "key: value" continuation line counts towards its parent.
+func parseDockerLogs(reader io.Reader, format models.LineFormat, levelFilter string, searchRegex *regexp.Regexp, fieldFilter models.FieldFilter) ([]models.LogEntry, error) {
 	var entries []models.LogEntry
 
-	stdout := &logWriter{stream: "stdout", entries: &entries}
-	stderr := &logWriter{stream: "stderr", entries: &entries}
+	stdout := &logWriter{stream: "stdout", format: format, entries: &entries}
+	stderr := &logWriter{stream: "stderr", format: format, entries: &entries}
 
 	_, err := stdcopy.StdCopy(stdout, stderr, reader)
 	if err != nil && err != io.EOF {
@@ -43,9 +46,9 @@ func parseDockerLogs(reader io.Reader, levelFilter string, searchRegex *regexp.R
 
 	stdout.Flush()
 	stderr.Flush()
-	entries = models.GroupRelatedLogEntries(entries)
+	entries = format.Group(entries)
 
-	if levelFilter == "" && searchRegex == nil {
+	if levelFilter == "" && searchRegex == nil && fieldFilter.Empty() {
 		return entries, nil
 	}
 
@@ -57,6 +60,9 @@ func parseDockerLogs(reader io.Reader, levelFilter string, searchRegex *regexp.R
 		if searchRegex != nil && !searchRegex.MatchString(e.Message) && !searchRegex.MatchString(e.Raw) {
 			continue
 		}
+		if !fieldFilter.MatchesEntry(e) {
+			continue
+		}
 		filtered = append(filtered, e)
 	}
 	return filtered, nil
@@ -70,6 +76,7 @@ const maxLineBufferSize = 1 << 20 // 1 MiB
 // logWriter implements io.Writer and parses log lines
 type logWriter struct {
 	stream  string
+	format  models.LineFormat
 	entries *[]models.LogEntry
 	buffer  []byte
 }
@@ -88,7 +95,7 @@ func (w *logWriter) Write(p []byte) (n int, err error) {
 
 		if line != "" {
 			line = strings.TrimSuffix(line, "\r")
-			entry := models.ParseLogLine(line, w.stream)
+			entry := w.format.Parse(line, w.stream)
 			*w.entries = append(*w.entries, entry)
 		}
 	}
@@ -106,7 +113,7 @@ func (w *logWriter) Flush() {
 	}
 	line := strings.TrimSuffix(string(w.buffer), "\r")
 	if line != "" {
-		entry := models.ParseLogLine(line, w.stream)
+		entry := w.format.Parse(line, w.stream)
 		*w.entries = append(*w.entries, entry)
 	}
 	w.buffer = nil
@@ -114,12 +121,15 @@ func (w *logWriter) Flush() {
 
 type streamingLogWriter struct {
 	stream      string
+	format      models.LineFormat
+	open        models.LogEntry // the entry the last line belonged to, for Continues
 	buffer      []byte
 	encoder     *json.Encoder
 	encoderMu   *sync.Mutex
 	pipeWriter  *io.PipeWriter
 	levelFilter string
 	searchRegex *regexp.Regexp
+	fieldFilter models.FieldFilter
 	wroteEntry  *atomic.Bool // set on each encoded entry; monitor clears it per tick
 }
 
@@ -166,7 +176,16 @@ func (w *streamingLogWriter) Flush() {
 }
 
 func (w *streamingLogWriter) emit(line string) error {
-	entry := models.ParseLogLine(line, w.stream)
+	entry := w.format.Parse(line, w.stream)
+	if w.format.Multiline != "" {
+		// Only a labelled container is marked; the client's own grouping
+		// already covers what an unlabelled one would be.
+		if w.format.Fold(&w.open, entry) {
+			entry.Continues = true
+		} else {
+			w.open = entry
+		}
+	}
 
 	if w.levelFilter != "" && !strings.EqualFold(string(entry.Level), w.levelFilter) {
 		return nil
@@ -176,6 +195,10 @@ func (w *streamingLogWriter) emit(line string) error {
 		return nil
 	}
 
+	if !w.fieldFilter.MatchesEntry(entry) {
+		return nil
+	}
+
 	w.encoderMu.Lock()
 	// Set before Encode (which blocks on the pipe) so a monitor tick during
 	// the write already counts it as activity.
@@ -209,6 +232,7 @@ func (c *MultiHostClient) GetContainerLogsParsed(ctx context.Context, hostName,
 		return nil, err
 	}
 
+	format := lineFormat(ctx, apiClient, id)
 	logs, err := apiClient.ContainerLogs(ctx, id, buildLogsOptions(options, false, true))
 	if err != nil {
 		return nil, err
@@ -219,8 +243,20 @@ func (c *MultiHostClient) GetContainerLogsParsed(ctx context.Context, hostName,
 	if options.Search != "" {
 		searchRegex, _ = regexp.Compile(options.Search) // already validated by handler
 	}
+	fieldFilter, _ := models.ParseFieldFilter(options.Filter) // already validated by handler
 
-	return parseDockerLogs(logs, options.Level, searchRegex)
+	return parseDockerLogs(logs, format, options.Level, searchRegex, fieldFilter)
+}
+
+// lineFormat reads the container's logdeck.parser and logdeck.multiline
+// labels. A container that cannot be inspected is read with the default
+// format; the logs request that follows reports the real problem.
+func lineFormat(ctx context.Context, apiClient *client.Client, id string) models.LineFormat {
+	inspect, err := apiClient.ContainerInspect(ctx, id)
+	if err != nil || inspect.Config == nil {
+		return models.LineFormat{}
+	}
+	return models.PolicyFromLabels(inspect.Config.Labels).Format
 }
 
 // StreamContainerLogsParsed streams parsed logs. The Docker log stream is tied
@@ -231,6 +267,7 @@ func (c *MultiHostClient) StreamContainerLogsParsed(ctx context.Context, hostNam
 		return nil, err
 	}
 
+	format := lineFormat(ctx, apiClient, id)
 	logs, err := apiClient.ContainerLogs(ctx, id, buildLogsOptions(options, options.Follow, true))
 	if err != nil {
 		return nil, err
@@ -240,21 +277,23 @@ func (c *MultiHostClient) StreamContainerLogsParsed(ctx context.Context, hostNam
 		_, err := apiClient.Ping(ctx)
 		return err
 	}
-	return newParsedLogStream(ctx, logs, options, ping, nil), nil
+	return newParsedLogStream(ctx, logs, options, format, ping, nil), nil
 }
 
-// newParsedLogStream parses the raw Docker log stream into NDJSON on a pipe.
+// newParsedLogStream parses the raw Docker log stream into NDJSON on a pipe,
+// reading lines the way format says.
 // When following, a monitor goroutine keeps the stream honest: heartbeats on
 // quiet intervals and teardown when the daemon stops answering pings. tick
 // overrides the monitor cadence in tests; nil means a real time.Ticker at
 // monitorInterval.
-func newParsedLogStream(ctx context.Context, logs io.ReadCloser, options models.LogOptions, ping func(context.Context) error, tick <-chan time.Time) io.ReadCloser {
+func newParsedLogStream(ctx context.Context, logs io.ReadCloser, options models.LogOptions, format models.LineFormat, ping func(context.Context) error, tick <-chan time.Time) io.ReadCloser {
 	pipeReader, pipeWriter := io.Pipe()
 
 	var searchRegex *regexp.Regexp
 	if options.Search != "" {
 		searchRegex, _ = regexp.Compile(options.Search) // already validated by handler
 	}
+	fieldFilter, _ := models.ParseFieldFilter(options.Filter) // already validated by handler
 
 	encoder := json.NewEncoder(pipeWriter)
 	var mu sync.Mutex
@@ -262,20 +301,24 @@ func newParsedLogStream(ctx context.Context, logs io.ReadCloser, options models.
 
 	stdout := &streamingLogWriter{
 		stream:      "stdout",
+		format:      format,
 		encoder:     encoder,
 		encoderMu:   &mu,
 		pipeWriter:  pipeWriter,
 		levelFilter: options.Level,
 		searchRegex: searchRegex,
+		fieldFilter: fieldFilter,
 		wroteEntry:  &wroteEntry,
 	}
 	stderr := &streamingLogWriter{
 		stream:      "stderr",
+		format:      format,
 		encoder:     encoder,
 		encoderMu:   &mu,
 		pipeWriter:  pipeWriter,
 		levelFilter: options.Level,
 		searchRegex: searchRegex,
+		fieldFilter: fieldFilter,
 		wroteEntry:  &wroteEntry,
 	}
 
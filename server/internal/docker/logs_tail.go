@@ -15,6 +15,7 @@ import (
 // CR trimming, oversized-line flush), but emits instead of accumulating.
 type callbackLogWriter struct {
 	stream string
+	format models.LineFormat
 	buffer []byte
 	emit   func(models.LogEntry)
 }
@@ -33,7 +34,7 @@ func (w *callbackLogWriter) Write(p []byte) (n int, err error) {
 
 		if line != "" {
 			line = strings.TrimSuffix(line, "\r")
-			w.emit(models.ParseLogLine(line, w.stream))
+			w.emit(w.format.Parse(line, w.stream))
 		}
 	}
 
@@ -50,13 +51,13 @@ func (w *callbackLogWriter) Flush() {
 	}
 	line := strings.TrimSuffix(string(w.buffer), "\r")
 	if line != "" {
-		w.emit(models.ParseLogLine(line, w.stream))
+		w.emit(w.format.Parse(line, w.stream))
 	}
 	w.buffer = nil
 }
 
 // TailContainerLogs streams one container's logs and hands each parsed entry
-// to emit. It honors opts (Since, Tail, Follow, ShowStdout/ShowStderr,
+// to emit, parsed the way the container's logdeck.parser label says. It honors opts (Since, Tail, Follow, ShowStdout/ShowStderr,
 // Timestamps) and returns once ctx is cancelled or the log stream ends. emit
 // is called sequentially from a single goroutine, and no calls happen after
 // TailContainerLogs returns.
@@ -73,30 +74,34 @@ func (c *MultiHostClient) TailContainerLogs(ctx context.Context, host, container
 		return err
 	}
 	tty := inspect.Config != nil && inspect.Config.Tty
+	var format models.LineFormat
+	if inspect.Config != nil {
+		format = models.PolicyFromLabels(inspect.Config.Labels).Format
+	}
 
 	logs, err := apiClient.ContainerLogs(ctx, containerID, buildLogsOptions(opts, opts.Follow, opts.Timestamps))
 	if err != nil {
 		return err
 	}
 
-	return tailLogStream(ctx, logs, tty, emit)
+	return tailLogStream(ctx, logs, tty, format, emit)
 }
 
 // tailLogStream parses the raw Docker log stream and emits entries until the
 // stream ends or ctx is cancelled. On cancellation it closes logs to unblock
 // the reader and waits for it to exit, so no goroutine outlives the call.
-func tailLogStream(ctx context.Context, logs io.ReadCloser, tty bool, emit func(models.LogEntry)) error {
+func tailLogStream(ctx context.Context, logs io.ReadCloser, tty bool, format models.LineFormat, emit func(models.LogEntry)) error {
 	done := make(chan error, 1)
 	go func() {
 		var err error
 		if tty {
 			// TTY streams carry no stdcopy framing; everything is stdout.
-			w := &callbackLogWriter{stream: "stdout", emit: emit}
+			w := &callbackLogWriter{stream: "stdout", format: format, emit: emit}
 			_, err = io.Copy(w, logs)
 			w.Flush()
 		} else {
-			stdout := &callbackLogWriter{stream: "stdout", emit: emit}
-			stderr := &callbackLogWriter{stream: "stderr", emit: emit}
+			stdout := &callbackLogWriter{stream: "stdout", format: format, emit: emit}
+			stderr := &callbackLogWriter{stream: "stderr", format: format, emit: emit}
 			_, err = stdcopy.StdCopy(stdout, stderr, logs)
 			stdout.Flush()
 			stderr.Flush()
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -30,7 +31,7 @@ func TestParseDockerLogsGroupsStructuredContinuationLines(t *testing.T) {
 		t.Fatalf("failed to write docker log stream: %v", err)
 	}
 
-	entries, err := parseDockerLogs(&stream, "", nil)
+	entries, err := parseDockerLogs(&stream, models.LineFormat{}, "", nil, models.FieldFilter{})
 	if err != nil {
 		t.Fatalf("failed to parse docker logs: %v", err)
 	}
@@ -49,6 +50,83 @@ func TestParseDockerLogsGroupsStructuredContinuationLines(t *testing.T) {
 	}
 }
 
+func TestStreamMarksContinuationsOfALabelledContainer(t *testing.T) {
+	var raw bytes.Buffer
+	stderr := stdcopy.NewStdWriter(&raw, stdcopy.Stderr)
+	if _, err := stderr.Write([]byte(strings.Join([]string{
+		"2026-05-28T05:00:38.367Z ERROR charge failed",
+		"2026-05-28T05:00:38.367Z Caused by: java.io.IOException: gateway failed",
+		"2026-05-28T05:00:38.367Z at com.pay.Gateway.call(Gateway.java:31)",
+		"2026-05-28T05:00:38.368Z INFO retrying",
+	}, "\n") + "\n")); err != nil {
+		t.Fatalf("failed to write docker log stream: %v", err)
+	}
+
+	stream := newParsedLogStream(context.Background(), io.NopCloser(&raw), models.LogOptions{},
+		models.LineFormat{Multiline: models.MultilineJava}, nil, nil)
+	defer stream.Close()
+
+	var continues []bool
+	decoder := json.NewDecoder(stream)
+	for {
+		var entry models.LogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if err != io.EOF {
+				t.Fatalf("decode: %v", err)
+			}
+			break
+		}
+		continues = append(continues, entry.Continues)
+	}
+	if want := []bool{false, true, true, false}; !slices.Equal(continues, want) {
+		t.Fatalf("continues = %v, want %v", continues, want)
+	}
+}
+
+func TestParseDockerLogsFiltersByFields(t *testing.T) {
+	var stream bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&stream, stdcopy.Stdout)
+	_, err := stdout.Write([]byte(strings.Join([]string{
+		"2026-05-28T05:00:38.367Z [2026-05-28 05:00:38.367 +0000] INFO: Request received",
+		"2026-05-28T05:00:38.367Z service: \"api\"",
+		"2026-05-28T05:00:38.367Z requestId: \"e675e98a\"",
+		`2026-05-28T05:00:38.368Z {"level":"error","msg":"upstream failed","status":502,"http":{"path":"/api/orders"}}`,
+		"2026-05-28T05:00:38.369Z level=info msg=done status=200 path=/api/orders",
+	}, "\n") + "\n"))
+	if err != nil {
+		t.Fatalf("failed to write docker log stream: %v", err)
+	}
+	raw := stream.Bytes()
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{`requestId=e675e98a AND service=api`, []string{"Request received"}},
+		{`status>=500`, []string{"upstream failed"}},
+		{`path~/API`, []string{"done"}},
+		{`http.path="/api/orders" AND status!=200`, []string{"upstream failed"}},
+	}
+	for _, tt := range tests {
+		filter, err := models.ParseFieldFilter(tt.filter)
+		if err != nil {
+			t.Fatalf("ParseFieldFilter(%q): %v", tt.filter, err)
+		}
+		entries, err := parseDockerLogs(bytes.NewReader(raw), models.LineFormat{}, "", nil, filter)
+		if err != nil {
+			t.Fatalf("failed to parse docker logs: %v", err)
+		}
+		if len(entries) != len(tt.want) {
+			t.Fatalf("%q kept %d entries, want %d", tt.filter, len(entries), len(tt.want))
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(entries[i].Message, want) {
+				t.Errorf("%q entry %d = %q, want it to contain %q", tt.filter, i, entries[i].Message, want)
+			}
+		}
+	}
+}
+
 func TestLogWriterLineBufferCap(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -157,7 +235,7 @@ func TestFollowMonitorTearsDownStreamWhenDaemonDies(t *testing.T) {
 	rawReader, _ := io.Pipe()
 	tick := make(chan time.Time)
 
-	stream := newParsedLogStream(context.Background(), rawReader, models.LogOptions{Follow: true},
+	stream := newParsedLogStream(context.Background(), rawReader, models.LogOptions{Follow: true}, models.LineFormat{},
 		func(context.Context) error { return errors.New("daemon down") }, tick)
 
 	type result struct {
@@ -190,7 +268,7 @@ func TestFollowMonitorHeartbeatsOnlyWhenIdleAndStopsAfterClose(t *testing.T) {
 	rawReader, rawWriter := io.Pipe()
 	tick := make(chan time.Time)
 
-	stream := newParsedLogStream(context.Background(), rawReader, models.LogOptions{Follow: true},
+	stream := newParsedLogStream(context.Background(), rawReader, models.LogOptions{Follow: true}, models.LineFormat{},
 		func(context.Context) error { return nil }, tick)
 	reader := bufio.NewReader(stream)
 
@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"log"
+
+	"github.com/distribution/reference"
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/credentials"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// dockerHubAuthKey is the key the docker CLI stores Docker Hub credentials
+// under, rather than the docker.io domain.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// dockerConfigDir locates the docker CLI config; tests point it elsewhere.
+var dockerConfigDir = dockerconfig.Dir
+
+// registryAuth returns the encoded X-Registry-Auth value for pulling or
+// inspecting ref, taken from the docker CLI config on the LogDeck server
+// ($DOCKER_CONFIG/config.json, by default ~/.docker/config.json), including
+// any credential helper it names. It is read on every call so a `docker login`
+// takes effect without a restart. Without matching credentials it returns ""
+// and the engine tries the registry anonymously.
+//
+// Only a local engine is given these: they are the LogDeck server's secrets,
+// and hostRegistryAuth keeps them from being sent to other machines.
+func registryAuth(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ""
+	}
+	key := reference.Domain(named)
+	if key == "docker.io" {
+		key = dockerHubAuthKey
+	}
+
+	cfg, err := dockerconfig.Load(dockerConfigDir())
+	if err != nil {
+		log.Printf("Warning: reading the docker config for registry credentials: %v", err)
+		return ""
+	}
+	if !cfg.ContainsAuth() {
+		cfg.CredentialsStore = credentials.DetectDefaultStore(cfg.CredentialsStore)
+	}
+	auth, err := cfg.GetAuthConfig(key)
+	if err != nil {
+		log.Printf("Warning: reading registry credentials for %s: %v", key, err)
+		return ""
+	}
+	if auth.Username == "" && auth.Password == "" && auth.Auth == "" && auth.IdentityToken == "" && auth.RegistryToken == "" {
+		return ""
+	}
+
+	encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		Auth:          auth.Auth,
+		ServerAddress: auth.ServerAddress,
+		IdentityToken: auth.IdentityToken,
+		RegistryToken: auth.RegistryToken,
+	})
+	if err != nil {
+		return ""
+	}
+	return encoded
+}
+
+// noRegistryAuth leaves the engine to try the registry anonymously.
+func noRegistryAuth(string) string { return "" }
+
+// hostRegistryAuth returns the credential source for pulls and registry checks
+// on a host: the LogDeck server's docker config for a local engine, nothing
+// for a remote one, so images from private registries cannot be checked or
+// pulled there.
+func (c *MultiHostClient) hostRegistryAuth(hostName string) func(ref string) string {
+	if isLocalEngine(c.hostAddress(hostName)) {
+		return registryAuth
+	}
+	return noRegistryAuth
+}
@@ -3,7 +3,9 @@ package docker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
@@ -123,6 +125,73 @@ func currentCPUPercent(key string, stats *container.StatsResponse) float64 {
 	return calculateCPUPercent(stats)
 }
 
+// ioSample is one reading of a container's cumulative network and block I/O
+// counters, kept per container so consecutive polls yield rates the way
+// cpuSample does for CPU.
+type ioSample struct {
+	netRx, netTx      uint64
+	blkRead, blkWrite uint64
+	readAt            time.Time
+}
+
+var (
+	prevIOMu      sync.Mutex
+	prevIOSamples = map[string]ioSample{}
+)
+
+// swapPrevIOSample stores the current reading and returns the previous one.
+func swapPrevIOSample(key string, current ioSample) (ioSample, bool) {
+	prevIOMu.Lock()
+	defer prevIOMu.Unlock()
+
+	prev, ok := prevIOSamples[key]
+	prevIOSamples[key] = current
+
+	if len(prevIOSamples) > 256 {
+		for k, s := range prevIOSamples {
+			if time.Since(s.readAt) > cpuSampleMaxAge {
+				delete(prevIOSamples, k)
+			}
+		}
+	}
+	return prev, ok
+}
+
+// setIORates fills in the network and block I/O rates against the previous
+// reading of the same container. Unlike CPU there is no engine-side window
+// to fall back on, so the first reading, a stale previous one, and a counter
+// that went backwards (the container restarted) all report 0.
+func setIORates(key string, v *container.StatsResponse, stats *models.ContainerStats) {
+	current := ioSample{readAt: v.Read}
+	if current.readAt.IsZero() {
+		current.readAt = time.Now()
+	}
+	current.netRx, current.netTx = networkTotals(v)
+	current.blkRead, current.blkWrite = blockIOTotals(v)
+
+	prev, ok := swapPrevIOSample(key, current)
+	if !ok || current.readAt.Sub(prev.readAt) > cpuSampleMaxAge {
+		return
+	}
+	elapsed := current.readAt.Sub(prev.readAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	stats.NetworkRxPerSec = CounterRate(prev.netRx, current.netRx, elapsed)
+	stats.NetworkTxPerSec = CounterRate(prev.netTx, current.netTx, elapsed)
+	stats.BlockReadPerSec = CounterRate(prev.blkRead, current.blkRead, elapsed)
+	stats.BlockWritePerSec = CounterRate(prev.blkWrite, current.blkWrite, elapsed)
+}
+
+// CounterRate is the per-second rate between two readings of a cumulative
+// counter, or 0 when the counter was reset (the container restarted).
+func CounterRate(prev, current uint64, elapsedSeconds float64) float64 {
+	if current < prev {
+		return 0
+	}
+	return float64(current-prev) / elapsedSeconds
+}
+
 type ContainerIdentifier struct {
 	ID   string
 	Host string
@@ -135,6 +204,82 @@ func (c *MultiHostClient) GetContainerStats(ctx context.Context, hostName, conta
 		return stats, nil
 	}
 
+	v, err := c.readStats(ctx, hostName, containerID)
+	if err != nil {
+		return nil, err
+	}
+	stats := containerStats(hostName, containerID, cacheKey, v)
+
+	setCachedStats(cacheKey, stats)
+	return stats, nil
+}
+
+// StatsSample is one uncached stats reading together with the cumulative
+// network and block I/O counters, which only mean something as a difference
+// between two samples.
+type StatsSample struct {
+	models.ContainerStats
+	NetRxBytes      uint64
+	NetTxBytes      uint64
+	BlockReadBytes  uint64
+	BlockWriteBytes uint64
+	ReadAt          time.Time
+}
+
+// SampleContainerStats reads a container's stats for the stats history. It
+// bypasses the cache: a sample must be a fresh reading to be diffed against
+// the previous one.
+func (c *MultiHostClient) SampleContainerStats(ctx context.Context, hostName, containerID string) (*StatsSample, error) {
+	v, err := c.readStats(ctx, hostName, containerID)
+	if err != nil {
+		return nil, err
+	}
+	sample := &StatsSample{
+		ContainerStats: *containerStats(hostName, containerID, fmt.Sprintf("%s:%s", hostName, containerID), v),
+		ReadAt:         v.Read,
+	}
+	if sample.ReadAt.IsZero() {
+		sample.ReadAt = time.Now()
+	}
+	sample.NetRxBytes, sample.NetTxBytes = networkTotals(v)
+	sample.BlockReadBytes, sample.BlockWriteBytes = blockIOTotals(v)
+	return sample, nil
+}
+
+// StreamContainerStats follows a container's stats stream, calling emit with
+// every reading (about one a second) until ctx is cancelled or the container
+// stops. Each reading also refreshes the stats cache, so one-shot requests
+// for a streamed container are served from it.
+func (c *MultiHostClient) StreamContainerStats(ctx context.Context, hostName, containerID string, emit func(models.ContainerStats)) error {
+	apiClient, err := c.GetClient(hostName)
+	if err != nil {
+		return err
+	}
+
+	statsResp, err := apiClient.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		return err
+	}
+	defer statsResp.Body.Close()
+
+	cacheKey := fmt.Sprintf("%s:%s", hostName, containerID)
+	decoder := json.NewDecoder(statsResp.Body)
+	for {
+		var v container.StatsResponse
+		if err := decoder.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		stats := containerStats(hostName, containerID, cacheKey, &v)
+		setCachedStats(cacheKey, stats)
+		emit(*stats)
+	}
+}
+
+// readStats takes one one-shot stats reading.
+func (c *MultiHostClient) readStats(ctx context.Context, hostName, containerID string) (*container.StatsResponse, error) {
 	apiClient, err := c.GetClient(hostName)
 	if err != nil {
 		return nil, err
@@ -150,20 +295,25 @@ func (c *MultiHostClient) GetContainerStats(ctx context.Context, hostName, conta
 	if err := json.NewDecoder(statsResp.Body).Decode(&v); err != nil {
 		return nil, err
 	}
+	return &v, nil
+}
 
-	memPercent, memUsed, memLimit := calculateMemoryStats(&v)
-
+func containerStats(hostName, containerID, cpuKey string, v *container.StatsResponse) *models.ContainerStats {
+	memPercent, memUsed, memLimit := calculateMemoryStats(v)
+	memCache, memRSS := memoryBreakdown(v)
 	stats := &models.ContainerStats{
 		ID:            containerID,
 		Host:          hostName,
-		CPUPercent:    currentCPUPercent(cacheKey, &v),
+		CPUPercent:    currentCPUPercent(cpuKey, v),
 		MemoryPercent: memPercent,
 		MemoryUsed:    memUsed,
 		MemoryLimit:   memLimit,
+		MemoryCache:   memCache,
+		MemoryRSS:     memRSS,
+		PIDs:          v.PidsStats.Current,
 	}
-
-	setCachedStats(cacheKey, stats)
-	return stats, nil
+	setIORates(cpuKey, v, stats)
+	return stats
 }
 
 func (c *MultiHostClient) GetBulkContainerStats(ctx context.Context, containers []ContainerIdentifier) []models.ContainerStats {
@@ -256,3 +406,47 @@ func calculateMemoryStats(stats *container.StatsResponse) (percent float64, usag
 
 	return
 }
+
+// memoryBreakdown splits memory usage into page cache and anonymous memory.
+// cgroup v1 reports them as total_cache and total_rss (or cache and rss
+// outside a hierarchy), cgroup v2 as file and anon.
+func memoryBreakdown(stats *container.StatsResponse) (cache, rss uint64) {
+	m := stats.MemoryStats.Stats
+	for _, key := range []string{"total_cache", "cache", "file"} {
+		if v, ok := m[key]; ok {
+			cache = v
+			break
+		}
+	}
+	for _, key := range []string{"total_rss", "rss", "anon"} {
+		if v, ok := m[key]; ok {
+			rss = v
+			break
+		}
+	}
+	return cache, rss
+}
+
+// networkTotals sums the bytes received and sent across every interface.
+func networkTotals(stats *container.StatsResponse) (rx, tx uint64) {
+	for _, n := range stats.Networks {
+		rx += n.RxBytes
+		tx += n.TxBytes
+	}
+	return rx, tx
+}
+
+// blockIOTotals sums the bytes read and written across every block device.
+// cgroup v1 names the operations "Read" and "Write", cgroup v2 "read" and
+// "write".
+func blockIOTotals(stats *container.StatsResponse) (read, write uint64) {
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "Read", "read":
+			read += entry.Value
+		case "Write", "write":
+			write += entry.Value
+		}
+	}
+	return read, write
+}
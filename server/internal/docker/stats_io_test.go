@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestIOTotalsSumInterfacesAndDevices(t *testing.T) {
+	stats := container.StatsResponse{
+		Networks: map[string]container.NetworkStats{
+			"eth0": {RxBytes: 100, TxBytes: 10},
+			"eth1": {RxBytes: 50, TxBytes: 5},
+		},
+	}
+	// cgroup v1 capitalizes the operations, cgroup v2 does not; other
+	// operations are not I/O volume.
+	stats.BlkioStats.IoServiceBytesRecursive = []container.BlkioStatEntry{
+		{Op: "Read", Value: 1000},
+		{Op: "read", Value: 200},
+		{Op: "Write", Value: 300},
+		{Op: "write", Value: 40},
+		{Op: "Total", Value: 1540},
+	}
+
+	if rx, tx := networkTotals(&stats); rx != 150 || tx != 15 {
+		t.Errorf("networkTotals = %d, %d; want 150, 15", rx, tx)
+	}
+	if read, write := blockIOTotals(&stats); read != 1200 || write != 340 {
+		t.Errorf("blockIOTotals = %d, %d; want 1200, 340", read, write)
+	}
+}
+
+func ioStatsResponse(rx, written uint64, at time.Time) *container.StatsResponse {
+	resp := &container.StatsResponse{}
+	resp.Read = at
+	resp.Networks = map[string]container.NetworkStats{"eth0": {RxBytes: rx}}
+	resp.BlkioStats.IoServiceBytesRecursive = []container.BlkioStatEntry{{Op: "write", Value: written}}
+	return resp
+}
+
+func TestSetIORatesDiffsConsecutiveReadings(t *testing.T) {
+	key := "test-host:io-rate-container"
+	t0 := time.Now()
+
+	var first models.ContainerStats
+	setIORates(key, ioStatsResponse(1000, 500, t0), &first)
+	if first.NetworkRxPerSec != 0 || first.BlockWritePerSec != 0 {
+		t.Errorf("first reading = %+v, want no rates without a previous sample", first)
+	}
+
+	var second models.ContainerStats
+	setIORates(key, ioStatsResponse(5000, 2500, t0.Add(2*time.Second)), &second)
+	if second.NetworkRxPerSec != 2000 || second.BlockWritePerSec != 1000 {
+		t.Errorf("second reading = %+v, want 2000 B/s received and 1000 B/s written", second)
+	}
+
+	// Counters went backwards (container restarted): no rate rather than a
+	// wrapped-around one.
+	var reset models.ContainerStats
+	setIORates(key, ioStatsResponse(10, 10, t0.Add(4*time.Second)), &reset)
+	if reset.NetworkRxPerSec != 0 || reset.BlockWritePerSec != 0 {
+		t.Errorf("post-reset reading = %+v, want no rates", reset)
+	}
+}
+
+func TestMemoryBreakdown(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		stats      map[string]uint64
+		cache, rss uint64
+	}{
+		{"cgroup v1", map[string]uint64{"total_cache": 30, "cache": 10, "total_rss": 60, "rss": 20}, 30, 60},
+		{"cgroup v2", map[string]uint64{"file": 40, "anon": 50}, 40, 50},
+		{"none", nil, 0, 0},
+	} {
+		var stats container.StatsResponse
+		stats.MemoryStats.Stats = tc.stats
+		if cache, rss := memoryBreakdown(&stats); cache != tc.cache || rss != tc.rss {
+			t.Errorf("%s: memoryBreakdown = %d, %d; want %d, %d", tc.name, cache, rss, tc.cache, tc.rss)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Package imageupdates periodically checks whether the images of running
+// containers have moved on in their registries: the tag a container was
+// created from (nginx:latest, say) now points to a different image than the
+// one it runs. The latest answer per container is kept in memory so the
+// container list can flag it; nothing is pulled or changed by a check.
+package imageupdates
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+const (
+	// startupDelay holds the first check back so it does not compete with
+	// everything else a fresh start does.
+	startupDelay = time.Minute
+	// hostTimeout bounds one host's check; an unreachable host or registry
+	// must not stall the others.
+	hostTimeout = 5 * time.Minute
+)
+
+// Engine is the slice of *docker.MultiHostClient the checker needs.
+type Engine interface {
+	GetHosts() []config.DockerHost
+	CheckImageUpdates(ctx context.Context, hostName string) ([]models.ImageUpdateStatus, error)
+}
+
+// DockerProvider yields the current Docker client set; reading through it on
+// every check keeps the checker correct across hot-swapped config.
+type DockerProvider interface {
+	Docker() *docker.MultiHostClient
+}
+
+// Settings supplies whether checks run and how often, re-read after every
+// check and on Reconcile so config changes take effect without a restart.
+type Settings func() config.ResolvedImageUpdatesConfig
+
+type containerKey struct {
+	host, id string
+}
+
+// Checker runs the periodic check and holds its latest results.
+type Checker struct {
+	settings     Settings
+	now          func() time.Time
+	startupDelay time.Duration
+	trigger      chan struct{}
+	reconcile    chan struct{}
+
+	mu        sync.RWMutex
+	statuses  map[containerKey]models.ImageUpdateStatus
+	lastCheck time.Time
+	checking  bool
+
+	workers sync.WaitGroup
+}
+
+// New creates a checker; Start begins checking.
+func New(settings Settings) *Checker {
+	return &Checker{
+		settings:     settings,
+		now:          time.Now,
+		startupDelay: startupDelay,
+		trigger:      make(chan struct{}, 1),
+		reconcile:    make(chan struct{}, 1),
+		statuses:     make(map[containerKey]models.ImageUpdateStatus),
+	}
+}
+
+// Start runs checks in the background until ctx is canceled.
+func (c *Checker) Start(ctx context.Context, provider DockerProvider) {
+	c.start(ctx, func() Engine { return provider.Docker() })
+}
+
+// start is the injectable form of Start; tests supply fakes.
+func (c *Checker) start(ctx context.Context, source func() Engine) {
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		c.loop(ctx, source)
+	}()
+}
+
+// loop checks once after startupDelay and then an interval after the last
+// check, or sooner when CheckNow asks for it. While checks are disabled it
+// sleeps until Reconcile says the settings changed; enabling them then checks
+// straight away if a check is overdue.
+func (c *Checker) loop(ctx context.Context, source func() Engine) {
+	first := c.now().Add(c.startupDelay)
+	for {
+		// A nil timer channel never fires: disabled, only a poke wakes it.
+		var timer *time.Timer
+		var due <-chan time.Time
+		if settings := c.settings(); settings.Enabled {
+			c.mu.RLock()
+			next := c.lastCheck.Add(time.Duration(settings.IntervalHours) * time.Hour)
+			if c.lastCheck.IsZero() {
+				next = first
+			}
+			c.mu.RUnlock()
+			timer = time.NewTimer(max(next.Sub(c.now()), 0))
+			due = timer.C
+		}
+
+		check := true
+		select {
+		case <-ctx.Done():
+			check = false
+		case <-c.reconcile:
+			check = false
+		case <-due:
+		case <-c.trigger:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if check && c.settings().Enabled {
+			c.checkAll(ctx, source())
+		}
+	}
+}
+
+// CheckNow asks for a check to start straight away, or right after the one
+// in progress. It does not wait for the result.
+func (c *Checker) CheckNow() {
+	select {
+	case c.trigger <- struct{}{}:
+	default: // one is already pending
+	}
+}
+
+// Reconcile pokes the loop to re-read its settings. Non-blocking; pokes
+// coalesce. Called on config reloads.
+func (c *Checker) Reconcile() {
+	select {
+	case c.reconcile <- struct{}{}:
+	default:
+	}
+}
+
+// checkAll checks every host in parallel. A host that fails keeps its
+// previous results, since its containers are no more up to date for it; a
+// host no longer configured is dropped.
+func (c *Checker) checkAll(ctx context.Context, engine Engine) {
+	c.mu.Lock()
+	c.checking = true
+	c.mu.Unlock()
+
+	hosts := engine.GetHosts()
+	results := make([][]models.ImageUpdateStatus, len(hosts))
+	failed := make([]bool, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hostCtx, cancel := context.WithTimeout(ctx, hostTimeout)
+			defer cancel()
+			statuses, err := engine.CheckImageUpdates(hostCtx, host.Name)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("imageupdates: checking host %s failed: %v", host.Name, err)
+				}
+				failed[i] = true
+				return
+			}
+			results[i] = statuses
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checking = false
+	if ctx.Err() != nil {
+		return
+	}
+	next := make(map[containerKey]models.ImageUpdateStatus)
+	for i, host := range hosts {
+		if failed[i] {
+			for key, status := range c.statuses {
+				if key.host == host.Name {
+					next[key] = status
+				}
+			}
+			continue
+		}
+		for _, status := range results[i] {
+			next[containerKey{status.Host, status.ContainerID}] = status
+		}
+	}
+	c.statuses = next
+	c.lastCheck = c.now()
+}
+
+// Annotate sets UpdateAvailable on each container the last check found
+// behind its tag. A container whose image changed since then is left
+// unflagged until the next check.
+func (c *Checker) Annotate(containers []models.ContainerInfo) {
+	if c == nil {
+		return
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for i := range containers {
+		status, ok := c.statuses[containerKey{containers[i].Host, containers[i].ID}]
+		containers[i].UpdateAvailable = ok && status.UpdateAvailable && status.ImageID == containers[i].ImageID
+	}
+}
+
+// Forget drops a container's result, e.g. once it has been recreated on the
+// new image.
+func (c *Checker) Forget(host, id string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.statuses, containerKey{host, id})
+}
+
+// Snapshot returns the latest results, ordered by host and container name.
+func (c *Checker) Snapshot() models.ImageUpdatesResponse {
+	resp := models.ImageUpdatesResponse{Updates: []models.ImageUpdateStatus{}}
+	if c == nil {
+		return resp
+	}
+	resp.Enabled = c.settings().Enabled
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	resp.Checking = c.checking
+	if !c.lastCheck.IsZero() {
+		resp.LastCheck = c.lastCheck.UnixMilli()
+	}
+	for _, status := range c.statuses {
+		resp.Updates = append(resp.Updates, status)
+	}
+	slices.SortFunc(resp.Updates, func(a, b models.ImageUpdateStatus) int {
+		return cmp.Or(cmp.Compare(a.Host, b.Host), cmp.Compare(a.ContainerName, b.ContainerName))
+	})
+	return resp
+}
+
+// Wait blocks until the checker has stopped.
+func (c *Checker) Wait() {
+	c.workers.Wait()
+}
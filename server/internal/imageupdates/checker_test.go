@@ -0,0 +1,164 @@
+package imageupdates
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+type fakeEngine struct {
+	mu      sync.Mutex
+	hosts   []config.DockerHost
+	results map[string][]models.ImageUpdateStatus
+	errs    map[string]error
+	checks  chan string
+}
+
+func (f *fakeEngine) GetHosts() []config.DockerHost { return f.hosts }
+
+func (f *fakeEngine) CheckImageUpdates(_ context.Context, host string) ([]models.ImageUpdateStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.checks != nil {
+		f.checks <- host
+	}
+	return f.results[host], f.errs[host]
+}
+
+func enabled(on bool) Settings {
+	return func() config.ResolvedImageUpdatesConfig {
+		return config.ResolvedImageUpdatesConfig{Enabled: on, IntervalHours: 6}
+	}
+}
+
+func TestCheckAllAnnotatesAndKeepsFailedHosts(t *testing.T) {
+	engine := &fakeEngine{
+		hosts: []config.DockerHost{{Name: "prod"}, {Name: "staging"}},
+		results: map[string][]models.ImageUpdateStatus{
+			"prod": {
+				{Host: "prod", ContainerID: "web", ContainerName: "web", ImageID: "sha256:old", UpdateAvailable: true},
+				{Host: "prod", ContainerID: "db", ContainerName: "db", ImageID: "sha256:pg"},
+			},
+			"staging": {
+				{Host: "staging", ContainerID: "api", ContainerName: "api", ImageID: "sha256:api", UpdateAvailable: true},
+			},
+		},
+	}
+	c := New(enabled(true))
+	c.checkAll(context.Background(), engine)
+
+	// staging fails next time: its previous results stand.
+	engine.errs = map[string]error{"staging": errors.New("unreachable")}
+	engine.results["staging"] = nil
+	c.checkAll(context.Background(), engine)
+
+	containers := []models.ContainerInfo{
+		{Host: "prod", ID: "web", ImageID: "sha256:old"},
+		{Host: "prod", ID: "db", ImageID: "sha256:pg"},
+		{Host: "staging", ID: "api", ImageID: "sha256:api"},
+		// Recreated on a new image since the check: not flagged.
+		{Host: "staging", ID: "api", ImageID: "sha256:api-new"},
+	}
+	c.Annotate(containers)
+	for i, want := range []bool{true, false, true, false} {
+		if containers[i].UpdateAvailable != want {
+			t.Errorf("%s/%s (%s): update_available = %t, want %t", containers[i].Host, containers[i].ID, containers[i].ImageID, containers[i].UpdateAvailable, want)
+		}
+	}
+
+	snapshot := c.Snapshot()
+	if !snapshot.Enabled || snapshot.LastCheck == 0 || len(snapshot.Updates) != 3 {
+		t.Fatalf("snapshot = %+v, want enabled, a last check, and 3 results", snapshot)
+	}
+	if snapshot.Updates[0].ContainerName != "db" || snapshot.Updates[2].Host != "staging" {
+		t.Errorf("snapshot order = %+v, want by host then name", snapshot.Updates)
+	}
+
+	// A host removed from the config is dropped, and Forget clears one entry.
+	engine.hosts = engine.hosts[:1]
+	c.checkAll(context.Background(), engine)
+	c.Forget("prod", "web")
+	if got := c.Snapshot().Updates; len(got) != 1 || got[0].ContainerID != "db" {
+		t.Errorf("updates = %+v, want only prod's db", got)
+	}
+}
+
+func TestCheckNowTriggersCheck(t *testing.T) {
+	engine := &fakeEngine{hosts: []config.DockerHost{{Name: "prod"}}, checks: make(chan string, 1)}
+	c := New(enabled(true))
+	c.startupDelay = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.start(ctx, func() Engine { return engine })
+	c.CheckNow()
+
+	select {
+	case host := <-engine.checks:
+		if host != "prod" {
+			t.Errorf("checked %q, want prod", host)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("CheckNow did not start a check")
+	}
+	cancel()
+	c.Wait()
+}
+
+func TestEnablingAtRuntimeChecksWithoutWaiting(t *testing.T) {
+	engine := &fakeEngine{hosts: []config.DockerHost{{Name: "prod"}}, checks: make(chan string, 1)}
+	var on atomic.Bool
+	c := New(func() config.ResolvedImageUpdatesConfig {
+		return config.ResolvedImageUpdatesConfig{Enabled: on.Load(), IntervalHours: 6}
+	})
+	c.startupDelay = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.start(ctx, func() Engine { return engine })
+	defer func() {
+		cancel()
+		c.Wait()
+	}()
+
+	select {
+	case <-engine.checks:
+		t.Fatal("a disabled checker checked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Never checked, so the first check is overdue as soon as it is enabled.
+	on.Store(true)
+	c.Reconcile()
+	select {
+	case <-engine.checks:
+	case <-time.After(5 * time.Second):
+		t.Fatal("enabling the checker did not start a check")
+	}
+}
+
+func TestDisabledCheckerDoesNotCheck(t *testing.T) {
+	engine := &fakeEngine{hosts: []config.DockerHost{{Name: "prod"}}, checks: make(chan string, 1)}
+	c := New(enabled(false))
+	c.startupDelay = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.start(ctx, func() Engine { return engine })
+	c.CheckNow()
+
+	select {
+	case <-engine.checks:
+		t.Fatal("a disabled checker checked")
+	case <-time.After(100 * time.Millisecond):
+	}
+	cancel()
+	c.Wait()
+
+	if c.Snapshot().Enabled {
+		t.Error("snapshot reports enabled")
+	}
+}
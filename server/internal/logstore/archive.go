@@ -0,0 +1,419 @@
+package logstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+	"github.com/klauspost/compress/zstd"
+)
+
+// A history archive is zstd-compressed NDJSON: one header record, then one
+// record per stored line carrying the generation it belongs to. Lines are
+// stored verbatim, so an imported line parses, groups, and filters exactly as
+// it did where it was exported, and every line names its engine container ID as
+// well as its (host, name), so import files it under the same generation.
+
+const (
+	archiveFormat  = "logdeck-history"
+	archiveVersion = 1
+	// exportChunk is how many rows one export read fetches.
+	exportChunk = 1000
+)
+
+// importBatch bounds one import transaction. Tests lower it to cover several
+// batches with a small archive.
+var importBatch = 1000
+
+// ErrInvalidArchive wraps the reason an import stream is not a history archive
+// this store can read.
+var ErrInvalidArchive = errors.New("invalid archive")
+
+type archiveHeader struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// archiveLine is one stored line and the generation it belongs to.
+type archiveLine struct {
+	Host        string `json:"host"`
+	ContainerID string `json:"containerId"`
+	Name        string `json:"name"`
+	Project     string `json:"project,omitempty"`
+	Image       string `json:"image,omitempty"`
+	TsNS        int64  `json:"tsNs"`
+	Stream      string `json:"stream"`
+	Raw         string `json:"raw"`
+	// Rowid is set in cold-tier segments only: the line's position in the store
+	// that evicted it, which keeps its place in the keyset order. Import ignores
+	// it.
+	Rowid int64 `json:"rowid,omitempty"`
+}
+
+// archiveWriter encodes a history archive.
+type archiveWriter struct {
+	zw  *zstd.Encoder
+	out *json.Encoder
+}
+
+// newArchiveWriter starts an archive on w by writing its header.
+func newArchiveWriter(w io.Writer) (*archiveWriter, error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	out := json.NewEncoder(zw)
+	if err := out.Encode(archiveHeader{Format: archiveFormat, Version: archiveVersion, ExportedAt: time.Now().UTC()}); err != nil {
+		zw.Close()
+		return nil, err
+	}
+	return &archiveWriter{zw: zw, out: out}, nil
+}
+
+func (a *archiveWriter) write(line archiveLine) error { return a.out.Encode(line) }
+
+// close flushes the archive. It must be called even after a failed write, to
+// release the encoder.
+func (a *archiveWriter) close() error { return a.zw.Close() }
+
+// archiveReader decodes a history archive record by record. Every error it
+// returns, other than io.EOF at the end, wraps ErrInvalidArchive.
+type archiveReader struct {
+	zr   *zstd.Decoder
+	in   *json.Decoder
+	read int64 // records read so far, for error messages
+}
+
+// openArchive checks the header of the archive on r.
+func openArchive(r io.Reader) (*archiveReader, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	in := json.NewDecoder(zr)
+
+	var header archiveHeader
+	if err := in.Decode(&header); err != nil {
+		zr.Close()
+		return nil, fmt.Errorf("%w: read header: %w", ErrInvalidArchive, err)
+	}
+	if header.Format != archiveFormat || header.Version != archiveVersion {
+		zr.Close()
+		return nil, fmt.Errorf("%w: not a %s archive of version %d", ErrInvalidArchive, archiveFormat, archiveVersion)
+	}
+	return &archiveReader{zr: zr, in: in}, nil
+}
+
+// next returns the next valid record, or io.EOF after the last one.
+func (a *archiveReader) next() (archiveLine, error) {
+	var record archiveLine
+	err := a.in.Decode(&record)
+	if errors.Is(err, io.EOF) {
+		return archiveLine{}, io.EOF
+	}
+	a.read++
+	if err == nil {
+		err = record.validate()
+	}
+	if err != nil {
+		return archiveLine{}, fmt.Errorf("%w: line %d: %w", ErrInvalidArchive, a.read, err)
+	}
+	return record, nil
+}
+
+func (a *archiveReader) close() { a.zr.Close() }
+
+// ImportResult reports what an import stored.
+type ImportResult struct {
+	Lines       int64 `json:"lines"`       // lines read from the archive
+	Imported    int64 `json:"imported"`    // lines stored
+	Duplicates  int64 `json:"duplicates"`  // lines the store already held
+	Generations int   `json:"generations"` // generation rows the import created
+}
+
+// Export writes every stored line the query selects to w as a history archive,
+// in Query's order: newest first, walking back through history. Only the
+// selection (Host, Container, Containers, Project) and the Since/Until window
+// apply; an archive holds whole lines, so the entry filters are ignored. It
+// returns the number of lines written.
+func (s *Store) Export(ctx context.Context, q LogQuery, w io.Writer) (int64, error) {
+	generations, err := s.generations(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	refs := generationRefs(generations)
+	byRef := make(map[int64]generation, len(generations))
+	for _, gen := range generations {
+		byRef[gen.ref] = gen
+	}
+
+	archive, err := newArchiveWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		written int64
+		from    cursorPos
+		hasPos  bool
+	)
+	for len(refs) > 0 {
+		rows, err := s.readMergedRaw(ctx, refs, exportChunk, newerThan, func(refs []int64) (string, []any) {
+			return buildSelect(refs, q, from, hasPos, exportChunk)
+		})
+		if err != nil {
+			archive.close()
+			return written, err
+		}
+		for _, row := range rows {
+			gen := byRef[row.ref]
+			if err := archive.write(archiveLine{
+				Host:        gen.host,
+				ContainerID: gen.id,
+				Name:        gen.name,
+				Project:     gen.project,
+				Image:       gen.image,
+				TsNS:        row.pos.tsNS,
+				Stream:      streamName(row.stream),
+				Raw:         row.raw,
+			}); err != nil {
+				archive.close()
+				return written, err
+			}
+			written++
+		}
+		if len(rows) < exportChunk {
+			break
+		}
+		from, hasPos = rows[len(rows)-1].pos, true
+	}
+	return written, archive.close()
+}
+
+// Import stores the lines of a history archive. Each line is filed under the
+// generation it was exported from — its engine container ID on its host, under
+// its name — so it joins that logical container's timeline. A generation the
+// store does not know is created as removed; the next sync clears that if the
+// engine still runs it. Lines go through the same duplicate check as ingestion,
+// so importing an archive twice, or one that overlaps what the store already
+// holds, stores each line once.
+//
+// Lines are committed in batches as they are read: an archive that turns out to
+// be malformed part-way keeps the batches before the bad record, and the error
+// says where it stopped. Imported lines count against the retention caps like
+// any other.
+func (s *Store) Import(ctx context.Context, r io.Reader) (ImportResult, error) {
+	archive, err := openArchive(r)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer archive.close()
+
+	var (
+		result  ImportResult
+		batch   = make([]archiveLine, 0, importBatch)
+		created = make(map[genKey]bool) // generations this import created
+	)
+	for {
+		record, err := archive.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if flushErr := s.importLines(ctx, batch, created, &result); flushErr != nil {
+				return result, flushErr
+			}
+			return result, err
+		}
+
+		batch = append(batch, record)
+		if len(batch) == importBatch {
+			// Lines sharing the batch's oldest timestamp wait for the next batch,
+			// which holds the rest of them, so they are stored in one go and keep
+			// their order (see importLines).
+			split := len(batch) - 1
+			for split > 0 && batch[split-1].TsNS == batch[len(batch)-1].TsNS {
+				split--
+			}
+			if split == 0 {
+				split = len(batch)
+			}
+			if err := s.importLines(ctx, batch[:split], created, &result); err != nil {
+				return result, err
+			}
+			batch = append(batch[:0], batch[split:]...)
+		}
+	}
+	return result, s.importLines(ctx, batch, created, &result)
+}
+
+func (l archiveLine) validate() error {
+	switch {
+	case l.Host == "" || l.ContainerID == "" || l.Name == "":
+		return errors.New("host, containerId, and name are required")
+	case l.Stream != "stdout" && l.Stream != "stderr":
+		return fmt.Errorf("unknown stream %q", l.Stream)
+	case l.TsNS <= 0:
+		return errors.New("tsNs must be positive")
+	}
+	return nil
+}
+
+// importLines stores one batch in one transaction. It runs off the writer
+// goroutine, like DeleteContainer, so it reads the index states from the
+// database inside its transaction, whose write lock orders it against the
+// writer turning an index on or off. created carries the generations the import
+// created across batches, and gains the ones this batch creates once it commits.
+//
+// The batch is in archive order, newest first, and is stored in reverse: Query
+// breaks timestamp ties by rowid, so lines sharing a timestamp must be inserted
+// oldest first to come back in the order they were exported in.
+func (s *Store) importLines(ctx context.Context, batch []archiveLine, created map[genKey]bool, result *ImportResult) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	indexing, err := indexActive(ctx, tx)
+	if err != nil {
+		return err
+	}
+	indexedKeys, err := loadFieldKeys(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	type agg struct {
+		bytes   int64
+		created bool
+		oldest  int64
+		newest  int64
+	}
+	refs := make(map[genKey]int64)
+	aggs := make(map[int64]*agg)
+	fresh := make(map[genKey]bool) // generations this batch created
+	nowMS := time.Now().UnixMilli()
+	var counts ImportResult
+
+	for i := len(batch) - 1; i >= 0; i-- {
+		record := batch[i]
+		key := genKey{host: record.Host, id: record.ContainerID}
+		ref, ok := refs[key]
+		if !ok {
+			var isNew bool
+			ref, isNew, err = importGeneration(ctx, tx, key, record, nowMS)
+			if err != nil {
+				return err
+			}
+			refs[key] = ref
+			if isNew {
+				fresh[key] = true
+			}
+			aggs[ref] = &agg{created: isNew || created[key], oldest: record.TsNS, newest: record.TsNS}
+		}
+
+		entry := models.ParseLogLine(record.Raw, record.Stream)
+		l := line{tsNS: record.TsNS, stream: streamStdout, level: models.LevelSeverity(entry.Level), raw: record.Raw}
+		if record.Stream == "stderr" {
+			l.stream = streamStderr
+		}
+
+		counts.Lines++
+		rowid, inserted, err := insertLine(ctx, tx, ref, l)
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			counts.Duplicates++
+			continue
+		}
+		counts.Imported++
+		if indexing || len(indexedKeys) > 0 {
+			message := lineMessage(l)
+			if indexing {
+				if err := indexLine(ctx, tx, rowid, message); err != nil {
+					return err
+				}
+			}
+			if err := indexFields(ctx, tx, rowid, message, indexedKeys); err != nil {
+				return err
+			}
+		}
+
+		a := aggs[ref]
+		a.bytes += int64(len(l.raw))
+		a.oldest = min(a.oldest, l.tsNS)
+		a.newest = max(a.newest, l.tsNS)
+	}
+
+	// Watermarks are left alone: they mark how far the engine has been read,
+	// and an archive says nothing about that.
+	for ref, a := range aggs {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE containers SET stored_bytes = stored_bytes + ? WHERE id = ?", a.bytes, ref); err != nil {
+			return err
+		}
+		// A generation this import created is dated by its lines, not by the
+		// import, so the newest generation of a name stays the one whose metadata
+		// the store shows.
+		if a.created {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE containers
+				SET first_seen_ms = min(first_seen_ms, ?), last_seen_ms = max(last_seen_ms, ?)
+				WHERE id = ?`,
+				a.oldest/int64(time.Millisecond), a.newest/int64(time.Millisecond), ref); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for key := range fresh {
+		created[key] = true
+	}
+	result.Lines += counts.Lines
+	result.Imported += counts.Imported
+	result.Duplicates += counts.Duplicates
+	result.Generations += len(fresh)
+	return nil
+}
+
+// importGeneration resolves the generation row of an archived line, creating it
+// when the store does not know it. An existing row is left untouched: its
+// metadata is at least as current as the archive's, and renaming it would move
+// its whole timeline.
+func importGeneration(ctx context.Context, tx *sql.Tx, key genKey, record archiveLine, nowMS int64) (int64, bool, error) {
+	var ref int64
+	err := tx.QueryRowContext(ctx,
+		"SELECT id FROM containers WHERE host = ? AND container_id = ?", key.host, key.id).Scan(&ref)
+	if err == nil {
+		return ref, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, err
+	}
+
+	// Seen at the line's own time; importLines widens the span to every line
+	// the batch stores.
+	seenMS := record.TsNS / int64(time.Millisecond)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO containers (host, container_id, name, compose_project, image, first_seen_ms, last_seen_ms, removed_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		key.host, key.id, record.Name, record.Project, record.Image, seenMS, seenMS, nowMS,
+	).Scan(&ref)
+	return ref, err == nil, err
+}
@@ -0,0 +1,274 @@
+package logstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// exportArchive exports q from s and returns the archive.
+func exportArchive(t *testing.T, s *Store, q LogQuery) ([]byte, int64) {
+	t.Helper()
+	var buf bytes.Buffer
+	n, err := s.Export(context.Background(), q, &buf)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	return buf.Bytes(), n
+}
+
+func importArchive(t *testing.T, s *Store, archive []byte) ImportResult {
+	t.Helper()
+	result, err := s.Import(context.Background(), bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	return result
+}
+
+// entriesOf follows q to the end of history and flattens every entry, leaving
+// the cursors out: they carry rowids, which differ between stores.
+func entriesOf(t *testing.T, s *Store, q LogQuery) []string {
+	t.Helper()
+	var out []string
+	for _, line := range allPages(t, s, q) {
+		if !strings.HasPrefix(line, "cursor ") {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// zstdRecords compresses NDJSON records into an archive body.
+func zstdRecords(t *testing.T, records ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+	for _, record := range records {
+		_, _ = w.Write([]byte(record + "\n"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	orig := importBatch
+	importBatch = 500
+	t.Cleanup(func() { importBatch = orig })
+
+	src := newTestStore(t)
+	// More lines than one import batch, across twelve containers, with
+	// multi-line entries.
+	writeShop(t, src, 0, 3*importBatch+100)
+	// Lines sharing a timestamp come back in the order they were written.
+	tie := baseTime.Add(time.Hour)
+	writeProjectEntries(t, src, genKey{"local", "svc-00"}, "svc-00", "shop",
+		entryAt(tie, "stdout", "first of a tie"),
+		entryAt(tie, "stdout", "second of a tie"),
+		entryAt(tie, "stdout", "third of a tie"),
+	)
+	writeProjectEntries(t, src, genKey{"local", "db"}, "db", "storage",
+		entryAt(baseTime, "stdout", "checkpoint complete"),
+	)
+
+	archive, exported := exportArchive(t, src, LogQuery{Project: "shop"})
+	// writeShop stores two continuation lines in this range.
+	if want := int64(3*importBatch + 100 + 2 + 3); exported != want {
+		t.Fatalf("exported %d lines, want %d", exported, want)
+	}
+
+	dst := newTestStore(t)
+	result := importArchive(t, dst, archive)
+	if result.Lines != exported || result.Imported != exported || result.Duplicates != 0 || result.Generations != 12 {
+		t.Fatalf("first import = %+v, want all %d lines stored in 12 new generations", result, exported)
+	}
+
+	want := entriesOf(t, src, LogQuery{Project: "shop", Limit: 300})
+	if got := entriesOf(t, dst, LogQuery{Limit: 300}); !slices.Equal(got, want) {
+		t.Fatalf("the imported history differs from the exported one:\ngot  %q\nwant %q", got, want)
+	}
+
+	// Importing the same archive again stores nothing.
+	again := importArchive(t, dst, archive)
+	if again.Imported != 0 || again.Duplicates != exported || again.Generations != 0 {
+		t.Fatalf("second import = %+v, want every line a duplicate", again)
+	}
+	if n := countLines(t, dst); n != int(exported) {
+		t.Fatalf("the store holds %d lines after re-importing, want %d", n, exported)
+	}
+
+	containers, err := dst.ListContainers(context.Background())
+	if err != nil {
+		t.Fatalf("ListContainers: %v", err)
+	}
+	if len(containers) != 12 {
+		t.Fatalf("got %d stored containers, want the 12 of the shop project", len(containers))
+	}
+	for _, c := range containers {
+		if c.ComposeProject != "shop" || !c.Removed {
+			t.Fatalf("imported container %+v: want project shop, marked removed", c)
+		}
+	}
+}
+
+func TestExportSelectsContainersAndWindow(t *testing.T) {
+	src := newTestStore(t)
+	writeStatsFixture(t, src)
+
+	archive, n := exportArchive(t, src, LogQuery{
+		Container: "web",
+		Since:     baseTime.Add(30 * time.Second),
+		Until:     baseTime.Add(140 * time.Second),
+	})
+	if n != 3 {
+		t.Fatalf("exported %d lines, want the 3 web lines in the window", n)
+	}
+
+	dst := newTestStore(t)
+	importArchive(t, dst, archive)
+	want := []string{"web: WARN slow response", "web: INFO started", "web: ERROR upstream timeout"}
+	if got := entriesOf(t, dst, LogQuery{}); !slices.Equal(got, want) {
+		t.Fatalf("imported %q, want %q", got, want)
+	}
+}
+
+func TestImportKeepsGenerationIdentity(t *testing.T) {
+	src := newTestStore(t)
+	// web was rebuilt: two generations of one logical container.
+	writeEntries(t, src, genKey{"local", "aaa"}, "web",
+		entryAt(baseTime, "stdout", "old generation"),
+	)
+	writeEntries(t, src, genKey{"local", "bbb"}, "web",
+		entryAt(baseTime.Add(time.Minute), "stdout", "current generation"),
+		entryAt(baseTime.Add(2*time.Minute), "stdout", "current generation again"),
+	)
+	archive, _ := exportArchive(t, src, LogQuery{Container: "web"})
+
+	// The destination already runs bbb and holds one of its lines.
+	dst := newTestStore(t)
+	writeEntries(t, dst, genKey{"local", "bbb"}, "web",
+		entryAt(baseTime.Add(2*time.Minute), "stdout", "current generation again"),
+		entryAt(baseTime.Add(3*time.Minute), "stdout", "after the export"),
+	)
+
+	result := importArchive(t, dst, archive)
+	if result.Imported != 2 || result.Duplicates != 1 || result.Generations != 1 {
+		t.Fatalf("import = %+v, want 2 lines stored, 1 duplicate, and only aaa created", result)
+	}
+
+	want := []string{
+		"web: old generation",
+		"web: current generation",
+		"web: current generation again",
+		"web: after the export",
+	}
+	if got := entriesOf(t, dst, LogQuery{Container: "web"}); !slices.Equal(got, want) {
+		t.Fatalf("web's timeline = %q, want %q", got, want)
+	}
+
+	var generations int
+	if err := dst.db.QueryRow("SELECT count(*) FROM containers WHERE host = 'local' AND name = 'web'").Scan(&generations); err != nil {
+		t.Fatalf("count generations: %v", err)
+	}
+	if generations != 2 {
+		t.Fatalf("web has %d generations, want aaa and bbb", generations)
+	}
+
+	// The running generation is still the current one: the created aaa is dated
+	// by its lines, before bbb.
+	containers, err := dst.ListContainers(context.Background())
+	if err != nil {
+		t.Fatalf("ListContainers: %v", err)
+	}
+	if len(containers) != 1 || containers[0].Removed {
+		t.Fatalf("containers = %+v, want one web that is not removed", containers)
+	}
+}
+
+func TestImportRejectsInvalidArchives(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	header := `{"format":"logdeck-history","version":1,"exportedAt":"2026-07-01T12:00:00Z"}`
+	good := `{"host":"local","containerId":"aaa","name":"web","tsNs":1782907200000000000,"stream":"stdout","raw":"2026-07-01T12:00:00Z hello"}`
+
+	for name, body := range map[string][]byte{
+		"not zstd":        []byte("plain text"),
+		"empty":           zstdRecords(t),
+		"foreign header":  zstdRecords(t, `{"format":"something-else","version":1}`),
+		"future version":  zstdRecords(t, `{"format":"logdeck-history","version":2}`),
+		"missing name":    zstdRecords(t, header, `{"host":"local","containerId":"aaa","tsNs":1,"stream":"stdout","raw":"x"}`),
+		"unknown stream":  zstdRecords(t, header, strings.Replace(good, "stdout", "stdin", 1)),
+		"malformed JSON":  zstdRecords(t, header, `{"host":`),
+		"no timestamp":    zstdRecords(t, header, strings.Replace(good, "1782907200000000000", "0", 1)),
+		"truncated zstd":  zstdRecords(t, header, good)[:10],
+		"trailing record": zstdRecords(t, header, good, `[]`),
+	} {
+		if _, err := store.Import(ctx, bytes.NewReader(body)); !errors.Is(err, ErrInvalidArchive) {
+			t.Errorf("%s: got %v, want ErrInvalidArchive", name, err)
+		}
+	}
+
+	// The records before a bad one are stored, and the error says where it
+	// stopped.
+	store = newTestStore(t)
+	result, err := store.Import(ctx, bytes.NewReader(zstdRecords(t, header, good, `{"host":"local"}`)))
+	if !errors.Is(err, ErrInvalidArchive) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("got %v, want ErrInvalidArchive at line 2", err)
+	}
+	if result.Imported != 1 {
+		t.Fatalf("import = %+v, want the good line stored", result)
+	}
+}
+
+func TestImportMaintainsTheIndexes(t *testing.T) {
+	src := newTestStore(t)
+	writeOrders(t, src, 0, 1200)
+	writeShop(t, src, 1200, 1600)
+	archive, _ := exportArchive(t, src, LogQuery{})
+
+	dir := t.TempDir()
+	indexed, enabled := newIndexedStore(t, filepath.Join(dir, "fts.db"))
+	*enabled = true
+	buildIndex(t, indexed)
+	importArchive(t, indexed, archive)
+	var stored int
+	if err := indexed.db.QueryRow("SELECT count(*) FROM log_lines WHERE raw LIKE '%declined%'").Scan(&stored); err != nil {
+		t.Fatalf("count stored lines: %v", err)
+	}
+	if got := indexedCount(t, indexed, "declined"); stored == 0 || got != stored {
+		t.Fatalf("the full-text index holds %d rows for %d imported lines", got, stored)
+	}
+	q := LogQuery{Search: "declined"}
+	viaIndex := entriesOf(t, indexed, q)
+	*enabled = false
+	if scanned := entriesOf(t, indexed, q); !slices.Equal(viaIndex, scanned) {
+		t.Fatalf("the index disagrees with the scan after an import:\nindexed %q\nscanned %q", viaIndex, scanned)
+	}
+
+	fields, keys := newFieldStore(t, filepath.Join(dir, "fields.db"))
+	*keys = []string{"status"}
+	buildFields(t, fields)
+	importArchive(t, fields, archive)
+	if fieldRows(t, fields, "status") == 0 {
+		t.Fatal("the field index holds no rows for imported lines")
+	}
+	byIndex := entriesOf(t, fields, LogQuery{Filter: "status>=500"})
+	*keys = nil
+	buildFields(t, fields)
+	if scanned := entriesOf(t, fields, LogQuery{Filter: "status>=500"}); len(byIndex) == 0 || !slices.Equal(byIndex, scanned) {
+		t.Fatalf("the field index disagrees with the scan after an import:\nindexed %q\nscanned %q", byIndex, scanned)
+	}
+}
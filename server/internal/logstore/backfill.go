@@ -69,7 +69,7 @@ func (s *Store) backfill(ctx context.Context, engine Engine, info models.Contain
 	}
 
 	name := containerName(info)
-	project := composeProject(info.Labels)
+	project := models.ComposeProject(info.Labels)
 	opts := models.LogOptions{
 		Follow:     false,
 		Timestamps: true,
@@ -79,7 +79,12 @@ func (s *Store) backfill(ctx context.Context, engine Engine, info models.Contain
 		ShowStderr: true,
 	}
 
+	filter := s.ingestFilter().forContainer(key.host, name, info.Labels)
+	var open openEntry
 	tailErr := engine.TailContainerLogs(ctx, key.host, key.id, opts, func(entry models.LogEntry) {
+		if !filter.keeps(entry, &open) {
+			return
+		}
 		s.send(ctx, ingestMsg{
 			kind:    msgLine,
 			key:     key,
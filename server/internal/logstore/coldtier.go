@@ -0,0 +1,461 @@
+package logstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+)
+
+// The cold tier keeps what retention evicts. With archiving enabled, evictOldest
+// writes every span it is about to delete to segment files first — one per
+// generation and UTC day, under <host>/<name>/<yyyy>/<mm>/<dd>/ — and records
+// each segment in archive_segments inside the eviction's transaction, so a
+// segment is in the manifest exactly when its lines have left the database.
+//
+// A segment is a history archive (see archive.go) whose lines also carry their
+// rowid, so `history import` reads it as-is, and Query, which merges archived
+// rows into its keyset walk, gives them the same positions they had before they
+// were evicted. A generation with archived segments is never pruned, since a
+// query reaches its segments through it.
+
+// segmentCacheSize bounds how many decoded segments stay in memory. A page that
+// walks into the archive reads the same few segments round after round.
+const segmentCacheSize = 16
+
+// coldTarget stores segment files under slash-separated keys.
+type coldTarget interface {
+	put(ctx context.Context, key string, data []byte) error
+	get(ctx context.Context, key string) ([]byte, error)
+	remove(ctx context.Context, key string) error
+}
+
+// coldTarget resolves where segments live: the S3 bucket when one is
+// configured, otherwise the archive directory. Reads resolve it too, whether or
+// not archiving is enabled, so segments written before it was turned off stay
+// readable.
+func (s *Store) coldTarget(cfg config.ResolvedLogStoreArchive) coldTarget {
+	if cfg.S3.Configured() {
+		return newS3Target(cfg.S3)
+	}
+	return dirTarget{root: s.archiveDir(cfg)}
+}
+
+// archiveDir is the configured archive directory, by default "archive" next to
+// the database.
+func (s *Store) archiveDir(cfg config.ResolvedLogStoreArchive) string {
+	if cfg.Dir != "" {
+		return cfg.Dir
+	}
+	return filepath.Join(filepath.Dir(s.path), "archive")
+}
+
+// dirTarget stores segments as files under root.
+type dirTarget struct {
+	root string
+}
+
+// put writes through a temporary file and a rename, so a reader never sees a
+// partial segment.
+func (d dirTarget) put(_ context.Context, key string, data []byte) error {
+	name := filepath.Join(d.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".segment-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (d dirTarget) get(_ context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(d.root, filepath.FromSlash(key)))
+}
+
+func (d dirTarget) remove(_ context.Context, key string) error {
+	err := os.Remove(filepath.Join(d.root, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// evictedLine is one row retention is about to delete, as the cold tier keeps
+// it.
+type evictedLine struct {
+	rowid  int64
+	ref    int64
+	tsNS   int64
+	stream int
+	raw    string
+}
+
+// segmentKey names the file of one segment. The first line's position makes it
+// unique, and deterministic: an eviction that is retried after a failed commit
+// rewrites the same file rather than leaving an orphan beside it.
+func segmentKey(gen generation, day time.Time, first evictedLine) string {
+	return path.Join(
+		url.PathEscape(gen.host), url.PathEscape(gen.name), day.Format("2006/01/02"),
+		fmt.Sprintf("%s-%d-%d.ndjson.zst", url.PathEscape(gen.id), first.tsNS, first.rowid),
+	)
+}
+
+// archiveEvicted writes lines (oldest first, as evictOldest reads them) to the
+// cold tier and records their segments in tx, the eviction's transaction. An
+// error leaves the caller to roll the eviction back: lines are never deleted
+// before their segment is safely stored.
+func (s *Store) archiveEvicted(ctx context.Context, tx *sql.Tx, target coldTarget, lines []evictedLine) error {
+	refs := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, l := range lines {
+		if !seen[l.ref] {
+			seen[l.ref] = true
+			refs = append(refs, l.ref)
+		}
+	}
+	gens, err := loadGenerations(ctx, tx, refs)
+	if err != nil {
+		return err
+	}
+
+	type segmentID struct {
+		ref int64
+		day int64
+	}
+	var (
+		order    []segmentID
+		segments = make(map[segmentID][]evictedLine)
+	)
+	for _, l := range lines {
+		id := segmentID{ref: l.ref, day: l.tsNS / int64(24*time.Hour)}
+		if _, ok := segments[id]; !ok {
+			order = append(order, id)
+		}
+		segments[id] = append(segments[id], l)
+	}
+
+	for _, id := range order {
+		span := segments[id]
+		gen := gens[id.ref]
+		day := time.Unix(0, id.day*int64(24*time.Hour)).UTC()
+		key := segmentKey(gen, day, span[0])
+
+		// Newest first, like every history archive.
+		rows := make([]rawRow, len(span))
+		for i, l := range span {
+			rows[len(span)-1-i] = rawRow{pos: cursorPos{tsNS: l.tsNS, rowid: l.rowid}, ref: l.ref, stream: l.stream, raw: l.raw}
+		}
+		data, size, err := encodeSegment(gen, rows)
+		if err != nil {
+			return err
+		}
+		if err := target.put(ctx, key, data); err != nil {
+			return fmt.Errorf("write segment %s: %w", key, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO archive_segments (container_ref, first_ts_ns, last_ts_ns, lines, bytes, location)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (location) DO UPDATE SET
+			  first_ts_ns = excluded.first_ts_ns, last_ts_ns = excluded.last_ts_ns,
+			  lines = excluded.lines, bytes = excluded.bytes`,
+			id.ref, span[0].tsNS, span[len(span)-1].tsNS, len(span), size, key); err != nil {
+			return err
+		}
+	}
+	s.archived.Store(true)
+	return nil
+}
+
+// encodeSegment renders rows (newest first) of one generation as a segment,
+// and reports the size of their raw lines.
+func encodeSegment(gen generation, rows []rawRow) ([]byte, int64, error) {
+	var buf bytes.Buffer
+	archive, err := newArchiveWriter(&buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	var size int64
+	for _, row := range rows {
+		size += int64(len(row.raw))
+		if err := archive.write(archiveLine{
+			Host:        gen.host,
+			ContainerID: gen.id,
+			Name:        gen.name,
+			Project:     gen.project,
+			Image:       gen.image,
+			TsNS:        row.pos.tsNS,
+			Stream:      streamName(row.stream),
+			Raw:         row.raw,
+			Rowid:       row.pos.rowid,
+		}); err != nil {
+			archive.close()
+			return nil, 0, err
+		}
+	}
+	if err := archive.close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), size, nil
+}
+
+// loadGenerations reads the metadata of the given generation rows.
+func loadGenerations(ctx context.Context, tx *sql.Tx, refs []int64) (map[int64]generation, error) {
+	placeholders, args := refArgs(refs)
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+generationColumns+" FROM containers WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gens := make(map[int64]generation, len(refs))
+	for rows.Next() {
+		gen, err := scanGeneration(rows)
+		if err != nil {
+			return nil, err
+		}
+		gens[gen.ref] = gen
+	}
+	return gens, rows.Err()
+}
+
+// coldFloor is the keyset position just above the newest archived line of the
+// given generations: every archived row is older than it. ok is false when none
+// of them has an archived segment.
+func (s *Store) coldFloor(ctx context.Context, refs []int64) (floor cursorPos, ok bool, err error) {
+	if !s.archived.Load() {
+		return cursorPos{}, false, nil
+	}
+	placeholders, args := refArgs(refs)
+	var newest sql.NullInt64
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT MAX(last_ts_ns) FROM archive_segments WHERE container_ref IN ("+placeholders+")", args...,
+	).Scan(&newest); err != nil {
+		return cursorPos{}, false, err
+	}
+	if !newest.Valid {
+		return cursorPos{}, false, nil
+	}
+	return cursorPos{tsNS: newest.Int64 + 1, rowid: math.MinInt64}, true, nil
+}
+
+// coldRows returns up to limit archived rows of the given generations, newest
+// first: those in the query's window, strictly older than from (with hasPos),
+// and no older than floor (with hasFloor). Segments are opened newest first and
+// only until no unopened one can hold a row that would make the cut.
+func (s *Store) coldRows(ctx context.Context, refs []int64, q LogQuery, from cursorPos, hasPos bool, floor int64, hasFloor bool, limit int) ([]rawRow, error) {
+	placeholders, args := refArgs(refs)
+	where := []string{"container_ref IN (" + placeholders + ")"}
+	if hasPos {
+		where = append(where, "first_ts_ns <= ?")
+		args = append(args, from.tsNS)
+	}
+	if hasFloor {
+		where = append(where, "last_ts_ns >= ?")
+		args = append(args, floor)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "last_ts_ns >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "first_ts_ns <= ?")
+		args = append(args, q.Until.UnixNano())
+	}
+
+	type segment struct {
+		id       int64
+		ref      int64
+		lastTsNS int64
+		location string
+	}
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, container_ref, last_ts_ns, location FROM archive_segments WHERE "+
+			strings.Join(where, " AND ")+" ORDER BY last_ts_ns DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	var segments []segment
+	for rows.Next() {
+		var seg segment
+		if err := rows.Scan(&seg.id, &seg.ref, &seg.lastTsNS, &seg.location); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	target := s.coldTarget(s.limits().Archive)
+	var collected []rawRow
+	for i, seg := range segments {
+		// Segments are in descending order of their newest line, so once limit
+		// rows are in hand, a segment whose newest line is older than all of them
+		// cannot contribute, and neither can any after it.
+		if len(collected) >= limit {
+			sort.Slice(collected, func(a, b int) bool { return newerThan(collected[a].pos, collected[b].pos) })
+			collected = collected[:limit]
+			if segments[i].lastTsNS < collected[limit-1].pos.tsNS {
+				break
+			}
+		}
+
+		lines, err := s.segmentRows(ctx, target, seg.id, seg.ref, seg.location)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range lines {
+			switch {
+			case hasPos && !newerThan(from, row.pos):
+			case hasFloor && row.pos.tsNS < floor:
+			case !q.Since.IsZero() && row.pos.tsNS < q.Since.UnixNano():
+			case !q.Until.IsZero() && row.pos.tsNS > q.Until.UnixNano():
+			default:
+				collected = append(collected, row)
+			}
+		}
+	}
+
+	sort.Slice(collected, func(a, b int) bool { return newerThan(collected[a].pos, collected[b].pos) })
+	if len(collected) > limit {
+		collected = collected[:limit]
+	}
+	return collected, nil
+}
+
+// segmentRows reads one segment through the cache.
+func (s *Store) segmentRows(ctx context.Context, target coldTarget, id, ref int64, location string) ([]rawRow, error) {
+	if rows, ok := s.segments.get(id); ok {
+		return rows, nil
+	}
+
+	data, err := target.get(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("read archived segment %s: %w", location, err)
+	}
+	archive, err := openArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("read archived segment %s: %w", location, err)
+	}
+	defer archive.close()
+
+	var rows []rawRow
+	for {
+		record, err := archive.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archived segment %s: %w", location, err)
+		}
+		row := rawRow{pos: cursorPos{tsNS: record.TsNS, rowid: record.Rowid}, ref: ref, raw: record.Raw}
+		if record.Stream == "stderr" {
+			row.stream = streamStderr
+		}
+		rows = append(rows, row)
+	}
+	s.segments.put(id, rows)
+	return rows, nil
+}
+
+// removeSegments deletes segment files after the rows naming them are gone. A
+// file that cannot be removed is only logged by the caller: nothing refers to it
+// any more.
+func (s *Store) removeSegments(ctx context.Context, ids []int64, locations []string) error {
+	target := s.coldTarget(s.limits().Archive)
+	var errs []error
+	for i, location := range locations {
+		s.segments.drop(ids[i])
+		if err := target.remove(ctx, location); err != nil {
+			errs = append(errs, fmt.Errorf("remove segment %s: %w", location, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// segmentCache holds recently read segments, decoded. Segments never change
+// once written, so entries need no invalidation beyond removal.
+type segmentCache struct {
+	mu    sync.Mutex
+	order []int64 // least recently used first
+	rows  map[int64][]rawRow
+}
+
+func (c *segmentCache) get(id int64) ([]rawRow, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rows, ok := c.rows[id]
+	if ok {
+		c.touch(id)
+	}
+	return rows, ok
+}
+
+func (c *segmentCache) put(id int64, rows []rawRow) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rows == nil {
+		c.rows = make(map[int64][]rawRow)
+	}
+	if _, ok := c.rows[id]; !ok && len(c.order) >= segmentCacheSize {
+		delete(c.rows, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.rows[id] = rows
+	c.touch(id)
+}
+
+func (c *segmentCache) drop(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.rows, id)
+	c.remove(id)
+}
+
+// touch moves id to the most recently used end; c.mu must be held.
+func (c *segmentCache) touch(id int64) {
+	c.remove(id)
+	c.order = append(c.order, id)
+}
+
+func (c *segmentCache) remove(id int64) {
+	for i, cached := range c.order {
+		if cached == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package logstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+)
+
+// s3Timeout bounds one object request. Eviction waits on puts, so a stalled
+// endpoint must not hold the writer for long.
+const s3Timeout = 30 * time.Second
+
+// s3Target stores segments in an S3-compatible bucket (AWS S3, MinIO, R2, ...)
+// through path-style requests signed with AWS Signature Version 4. Only the
+// three object calls the cold tier makes are implemented, which keeps the
+// store free of an SDK dependency.
+type s3Target struct {
+	cfg    config.S3ArchiveConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+func newS3Target(cfg config.S3ArchiveConfig) *s3Target {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &s3Target{cfg: cfg, client: &http.Client{Timeout: s3Timeout}, now: time.Now}
+}
+
+func (t *s3Target) put(ctx context.Context, key string, data []byte) error {
+	_, err := t.do(ctx, http.MethodPut, key, data)
+	return err
+}
+
+func (t *s3Target) get(ctx context.Context, key string) ([]byte, error) {
+	return t.do(ctx, http.MethodGet, key, nil)
+}
+
+func (t *s3Target) remove(ctx context.Context, key string) error {
+	_, err := t.do(ctx, http.MethodDelete, key, nil)
+	return err
+}
+
+// objectURL is the path-style URL of key: <endpoint>/<bucket>/<prefix>/<key>.
+func (t *s3Target) objectURL(key string) (*url.URL, error) {
+	endpoint := t.cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint %q: %w", t.cfg.Endpoint, err)
+	}
+	u.Path = "/" + path.Join(strings.Trim(u.Path, "/"), t.cfg.Bucket, strings.Trim(t.cfg.Prefix, "/"), key)
+	// The signature covers the path as S3 encodes it, which escapes more than
+	// Go does by default.
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	u.RawPath = strings.Join(segments, "/")
+	return u, nil
+}
+
+func (t *s3Target) do(ctx context.Context, method, key string, body []byte) ([]byte, error) {
+	u, err := t.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = nil
+		req.ContentLength = 0
+	}
+	t.sign(req, body)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		// DELETE of a missing object already succeeds on S3 (204), but not on
+		// every compatible server.
+		if method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// sign adds the Signature Version 4 headers for a request carrying payload.
+// Every header already on the request is signed along with the host.
+func (t *s3Target) sign(req *http.Request, payload []byte) {
+	now := t.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + t.cfg.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+t.cfg.SecretAccessKey), day)
+	key = hmacSHA256(key, t.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery renders query parameters sorted and strictly percent-encoded.
+func canonicalQuery(values url.Values) string {
+	var pairs []string
+	for name, vals := range values {
+		for _, v := range vals {
+			pairs = append(pairs, awsEscape(name)+"="+awsEscape(v))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,270 @@
+package logstore
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// The field index (log_fields) holds, for each configured key, the value every
+// stored line carries for it (see models.LineFields). Like the full-text index
+// it only narrows a filtered query: Query still groups and filters every entry
+// itself, so an index that lags the configuration changes speed, never
+// results. Keys are indexed independently — adding one catches up only that
+// key, and removing one drops only its rows.
+
+// fieldKeys is the field index state: every indexed key mapped to its
+// catch-up mark, with the same meaning as a positive full-text index state,
+// and 0 once every stored line is covered.
+type fieldKeys map[string]int64
+
+// loadFieldKeys reads the persisted field index state. Like indexActive, a
+// write off the writer goroutine reads it inside its own transaction.
+func loadFieldKeys(ctx context.Context, db interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}) (fieldKeys, error) {
+	rows, err := db.QueryContext(ctx, "SELECT key, pending_below FROM log_field_keys")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(fieldKeys)
+	for rows.Next() {
+		var (
+			key   string
+			below int64
+		)
+		if err := rows.Scan(&key, &below); err != nil {
+			return nil, err
+		}
+		keys[key] = below
+	}
+	return keys, rows.Err()
+}
+
+// indexedFields returns the current field index state. Only the writer
+// replaces it, and never mutates a published map.
+func (s *Store) indexedFields() fieldKeys {
+	return *s.fields.Load()
+}
+
+// maintainFields brings the field index in line with the configured keys, on
+// the writer goroutine for the same reasons as maintainIndex: a configuration
+// change is applied first, and otherwise one chunk of catch-up runs per call.
+func (s *Store) maintainFields() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	configured := s.limits().IndexedFields
+	keys := s.indexedFields()
+
+	var err error
+	switch {
+	case !sameKeys(keys, configured):
+		err = s.updateFieldKeys(ctx, keys, configured)
+	case keys.pending():
+		err = s.catchUpFields(ctx, keys)
+	}
+	if err != nil {
+		log.Printf("logstore: field index maintenance failed: %v", err)
+	}
+}
+
+func sameKeys(keys fieldKeys, configured []string) bool {
+	if len(keys) != len(configured) {
+		return false
+	}
+	for _, key := range configured {
+		if _, ok := keys[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (keys fieldKeys) pending() bool {
+	for _, below := range keys {
+		if below > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// updateFieldKeys starts indexing every newly configured key and drops the rows
+// of every key no longer configured.
+func (s *Store) updateFieldKeys(ctx context.Context, keys fieldKeys, configured []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var below int64
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(rowid), 0) + 1 FROM log_lines").Scan(&below); err != nil {
+		return err
+	}
+
+	next := make(fieldKeys, len(configured))
+	for _, key := range configured {
+		if mark, ok := keys[key]; ok {
+			next[key] = mark
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO log_field_keys (key, pending_below) VALUES (?, ?)", key, below); err != nil {
+			return err
+		}
+		next[key] = below
+	}
+	for key := range keys {
+		if slices.Contains(configured, key) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM log_fields WHERE key = ?", key); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM log_field_keys WHERE key = ?", key); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.fields.Store(&next)
+	return nil
+}
+
+// catchUpFields indexes the newest indexChunk lines below the highest pending
+// mark, for every key still waiting on them. Keys added at different times
+// converge on one mark after the first chunk, so catch-up reads each line once
+// however many keys are pending.
+func (s *Store) catchUpFields(ctx context.Context, keys fieldKeys) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var top int64
+	for _, below := range keys {
+		top = max(top, below)
+	}
+	lines, err := linesBelow(ctx, tx, top)
+	if err != nil {
+		return err
+	}
+
+	for _, stored := range lines {
+		fields := models.LineFields(lineMessage(stored.line))
+		for key, below := range keys {
+			value, ok := fields[key]
+			if !ok || stored.rowid >= below {
+				continue
+			}
+			if err := indexField(ctx, tx, stored.rowid, key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	next := maps.Clone(keys)
+	for key, below := range next {
+		switch {
+		case below == 0:
+		case len(lines) < indexChunk:
+			next[key] = 0 // everything below the highest mark is indexed
+		default:
+			next[key] = min(below, lines[len(lines)-1].rowid)
+		}
+		if next[key] != keys[key] {
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE log_field_keys SET pending_below = ? WHERE key = ?", next[key], key); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.fields.Store(&next)
+	return nil
+}
+
+// indexFields adds one stored line's values for the indexed keys.
+func indexFields(ctx context.Context, tx *sql.Tx, rowid int64, message string, keys fieldKeys) error {
+	fields := models.LineFields(message)
+	for key := range keys {
+		if value, ok := fields[key]; ok {
+			if err := indexField(ctx, tx, rowid, key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// indexField stores one value, and its numeric reading when it has one — the
+// same reading numeric predicates compare with.
+func indexField(ctx context.Context, tx *sql.Tx, rowid int64, key, value string) error {
+	var num any
+	if number, ok := models.ParseFieldNumber(value); ok {
+		num = number
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO log_fields (line_rowid, key, value, num) VALUES (?, ?, ?, ?)", rowid, key, value, num)
+	return err
+}
+
+// fieldLookup picks the predicate of a field filter the index can answer: one
+// on a fully indexed key that a line without the key cannot satisfy. Equality
+// is preferred, as it is usually the most selective. It returns nil when no
+// predicate qualifies.
+func (s *Store) fieldLookup(m matcher) *indexLookup {
+	keys := s.indexedFields()
+
+	var chosen *models.FieldPredicate
+	for i := range m.fields.Predicates {
+		predicate := &m.fields.Predicates[i]
+		if below, ok := keys[predicate.Key]; !ok || below != 0 {
+			continue
+		}
+		if predicate.Op == models.FieldEq {
+			chosen = predicate
+			break
+		}
+		switch predicate.Op {
+		case models.FieldGreater, models.FieldGreaterEq, models.FieldLess, models.FieldLessEq:
+			if chosen == nil {
+				chosen = predicate
+			}
+		}
+	}
+	if chosen == nil {
+		return nil
+	}
+	predicate := *chosen
+
+	// A numeric value compares against num: "500" equals "500.0", and an ordering
+	// bound never matches a value that is not a number. Otherwise the comparison
+	// is on the text, which SQLite's binary collation orders like Go does.
+	column, operand := "value", any(predicate.Value)
+	if number, ok := predicate.Numeric(); ok {
+		column, operand = "num", number
+	}
+	return &indexLookup{
+		where: "rowid IN (SELECT line_rowid FROM log_fields WHERE key = ? AND " + column + " " + string(predicate.Op) + " ?)",
+		args:  []any{predicate.Key, operand},
+		candidate: func(row storedRow) bool {
+			return predicate.Matches(models.EntryFields(row.entry))
+		},
+	}
+}
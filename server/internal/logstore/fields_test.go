@@ -0,0 +1,313 @@
+package logstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// newFieldStore opens a store whose indexed field keys the test sets through
+// the returned pointer, the way the settings API sets them through the config
+// manager.
+func newFieldStore(t *testing.T, path string) (*Store, *[]string) {
+	t.Helper()
+	keys := new([]string)
+	store, err := Open(path, func() config.ResolvedLogStoreConfig {
+		limits := testLimits()
+		limits.IndexedFields = *keys
+		return limits
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, keys
+}
+
+// buildFields runs the writer's field index maintenance until the index
+// matches the configured keys and catch-up is complete.
+func buildFields(t *testing.T, s *Store) {
+	t.Helper()
+	for range 100 {
+		s.maintainFields()
+		keys := s.indexedFields()
+		if sameKeys(keys, s.limits().IndexedFields) && !keys.pending() {
+			return
+		}
+	}
+	t.Fatalf("the field index never finished catching up (state %v)", s.indexedFields())
+}
+
+// fieldRows reports how many index rows hold key.
+func fieldRows(t *testing.T, s *Store, key string) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow("SELECT count(*) FROM log_fields WHERE key = ?", key).Scan(&n); err != nil {
+		t.Fatalf("count field rows: %v", err)
+	}
+	return n
+}
+
+// writeOrders stores logfmt and JSON request lines numbered from..to-1 across
+// twelve containers of one compose project. Failures are rare, and some carry
+// their request id only on a "key: value" continuation line.
+func writeOrders(t *testing.T, s *Store, from, to int) {
+	t.Helper()
+	byContainer := make(map[int][]models.LogEntry)
+	for i := from; i < to; i++ {
+		c := i % 12
+		ts := baseTime.Add(time.Duration(i) * time.Millisecond)
+		switch {
+		case i%997 == 0:
+			byContainer[c] = append(byContainer[c],
+				entryAt(ts, "stderr", fmt.Sprintf(`level=error route=/checkout status=503 request_id=req-%05d msg="payment declined"`, i)))
+		case i%1500 == 7:
+			byContainer[c] = append(byContainer[c],
+				entryAt(ts, "stderr", "level=error unhandled exception route=/checkout status=500"),
+				entryAt(ts.Add(time.Nanosecond), "stderr", fmt.Sprintf("request_id: req-%05d", i)))
+		case i%50 == 3:
+			byContainer[c] = append(byContainer[c],
+				entryAt(ts, "stdout", fmt.Sprintf(`{"level":"warn","route":"/search","status":429,"request_id":"req-%05d","http":{"client":"bot"}}`, i)))
+		default:
+			byContainer[c] = append(byContainer[c],
+				entryAt(ts, "stdout", fmt.Sprintf("level=info route=/cart status=200 request_id=req-%05d ms=%d", i, i%700)))
+		}
+	}
+	for c, entries := range byContainer {
+		name := fmt.Sprintf("svc-%02d", c)
+		writeProjectEntries(t, s, genKey{"local", name}, name, "shop", entries...)
+	}
+}
+
+// TestFieldIndexAgreesWithTheScan is the field index's contract: indexing a key
+// changes how fast a field filter runs, never what it returns or how it pages.
+func TestFieldIndexAgreesWithTheScan(t *testing.T) {
+	smallIndexChunks(t)
+	store, keys := newFieldStore(t, filepath.Join(t.TempDir(), "logs.db"))
+
+	// Stored before the keys are indexed: these are indexed by catch-up, in more
+	// than one chunk.
+	writeOrders(t, store, 0, 1600)
+	*keys = []string{"status", "request_id", "route"}
+	buildFields(t, store)
+	// Stored after: these are indexed by the commit that writes them.
+	writeOrders(t, store, 1600, 2000)
+
+	queries := []LogQuery{
+		{Filter: "status>=500"},
+		{Project: "shop", Filter: "status=503", Limit: 4},
+		{Filter: `request_id="req-00007"`},
+		{Filter: "status>=500 AND route~checkout", Levels: []string{"ERROR"}},
+		{Container: "svc-03", Filter: "status<300 AND ms>650", Limit: 300},
+		{Filter: "status=429 AND http.client=bot", Limit: 25},
+		{Filter: "route=/nothing"},
+		{Filter: "status>=500", Search: "declined"},
+		{Filter: "status>500", Since: baseTime.Add(500 * time.Millisecond), Until: baseTime.Add(1600 * time.Millisecond)},
+	}
+	indexed := make([][]string, len(queries))
+	for i, q := range queries {
+		if store.fieldLookup(mustMatcher(t, q)) == nil {
+			t.Fatalf("query %+v would not use the field index", q)
+		}
+		indexed[i] = allPages(t, store, q)
+	}
+
+	*keys = nil
+	buildFields(t, store)
+	for i, q := range queries {
+		if store.fieldLookup(mustMatcher(t, q)) != nil {
+			t.Fatalf("query %+v still uses the field index after it was dropped", q)
+		}
+		if scanned := allPages(t, store, q); !slices.Equal(indexed[i], scanned) {
+			t.Fatalf("query %+v: the index changed the result\nindexed %q\nscanned %q", q, indexed[i], scanned)
+		}
+	}
+
+	// The request id of the multi-line entry lives only in its continuation line,
+	// and the entry must come back whole.
+	page, err := store.Query(context.Background(), LogQuery{Filter: `request_id="req-00007"`})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	want := []string{"level=error unhandled exception route=/checkout status=500\nrequest_id: req-00007"}
+	if got := messages(page.Entries); !slices.Equal(got, want) {
+		t.Fatalf("continuation-only match returned %q, want %q", got, want)
+	}
+}
+
+// TestQueryConsultsTheFieldIndex proves a filter on an indexed key goes through
+// the index: a line written behind the index's back is invisible to it, while
+// operators the index cannot answer, and keys it does not hold, still scan.
+func TestQueryConsultsTheFieldIndex(t *testing.T) {
+	store, keys := newFieldStore(t, filepath.Join(t.TempDir(), "logs.db"))
+	ctx := context.Background()
+
+	*keys = []string{"status"}
+	buildFields(t, store)
+	writeEntries(t, store, genKey{"local", "aaa"}, "web", entryAt(baseTime, "stdout", "level=error status=502 code=indexed"))
+
+	var ref int64
+	if err := store.db.QueryRow("SELECT id FROM containers WHERE name = 'web'").Scan(&ref); err != nil {
+		t.Fatalf("read ref: %v", err)
+	}
+	if _, err := store.db.Exec(
+		"INSERT INTO log_lines (container_ref, ts_ns, stream, level, raw) VALUES (?, ?, 0, 0, ?)",
+		ref, baseTime.Add(time.Second).UnixNano(), rawLine(baseTime.Add(time.Second), "level=error status=502 code=hidden"),
+	); err != nil {
+		t.Fatalf("write behind the index: %v", err)
+	}
+
+	filter := func(expr string) []string {
+		t.Helper()
+		page, err := store.Query(ctx, LogQuery{Container: "web", Filter: expr})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		return messages(page.Entries)
+	}
+
+	if got := filter("status=502"); !slices.Equal(got, []string{"level=error status=502 code=indexed"}) {
+		t.Fatalf("indexed filter returned %q, want only the indexed line", got)
+	}
+	if got := filter("status>=500"); len(got) != 1 {
+		t.Fatalf("indexed range filter returned %q, want only the indexed line", got)
+	}
+	if got := filter("status~50"); len(got) != 2 {
+		t.Fatalf("a contains filter returned %q, want both lines from the scan", got)
+	}
+	if got := filter("code!=nothing"); len(got) != 2 {
+		t.Fatalf("a filter on an unindexed key returned %q, want both lines from the scan", got)
+	}
+
+	if _, err := store.Query(ctx, LogQuery{Container: "web", Filter: "status>="}); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("a malformed filter returned %v, want ErrInvalidFilter", err)
+	}
+}
+
+// TestRetentionAndPurgeClearTheFieldIndex keeps the field index from pointing at
+// lines that are gone.
+func TestRetentionAndPurgeClearTheFieldIndex(t *testing.T) {
+	store, keys := newFieldStore(t, filepath.Join(t.TempDir(), "logs.db"))
+	ctx := context.Background()
+
+	*keys = []string{"user"}
+	buildFields(t, store)
+	writeEntries(t, store, genKey{"local", "aaa"}, "web",
+		entryAt(baseTime, "stdout", "login user=alice"),
+		entryAt(baseTime.Add(time.Second), "stdout", "login user=bob"),
+	)
+	writeEntries(t, store, genKey{"local", "bbb"}, "api",
+		entryAt(baseTime, "stdout", "token user=carol"),
+	)
+
+	var webRef int64
+	if err := store.db.QueryRow("SELECT id FROM containers WHERE name = 'web'").Scan(&webRef); err != nil {
+		t.Fatalf("read ref: %v", err)
+	}
+	if _, err := store.evictOldest(ctx, []int64{webRef}, 1); err != nil {
+		t.Fatalf("evictOldest: %v", err)
+	}
+	if got := fieldRows(t, store, "user"); got != 2 {
+		t.Fatalf("after evicting one line the index holds %d user rows, want 2", got)
+	}
+
+	if _, err := store.DeleteContainer(ctx, "local", "api"); err != nil {
+		t.Fatalf("DeleteContainer: %v", err)
+	}
+	var value string
+	if err := store.db.QueryRow("SELECT value FROM log_fields WHERE key = 'user'").Scan(&value); err != nil {
+		t.Fatalf("read the remaining row: %v", err)
+	}
+	if value != "bob" {
+		t.Fatalf("the remaining index row holds %q, want bob", value)
+	}
+}
+
+// TestFieldKeysAreIndexedIndependently covers the per-key lifecycle: adding a
+// key catches up only that key, removing one drops only its rows, and the state
+// survives a restart.
+func TestFieldKeysAreIndexedIndependently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+	store, keys := newFieldStore(t, path)
+
+	writeEntries(t, store, genKey{"local", "aaa"}, "web",
+		entryAt(baseTime, "stdout", `{"status":200,"user":"alice"}`),
+		entryAt(baseTime.Add(time.Second), "stdout", "status=404 user=bob"),
+	)
+	*keys = []string{"status"}
+	buildFields(t, store)
+	if got := fieldRows(t, store, "status"); got != 2 {
+		t.Fatalf("catch-up indexed %d status rows, want 2", got)
+	}
+
+	*keys = []string{"status", "user"}
+	store.maintainFields()
+	state := store.indexedFields()
+	if state["status"] != 0 || state["user"] <= 0 {
+		t.Fatalf("after adding a key the state is %v, want status complete and user pending", state)
+	}
+	buildFields(t, store)
+	if got := fieldRows(t, store, "user"); got != 2 {
+		t.Fatalf("catch-up indexed %d user rows, want 2", got)
+	}
+
+	*keys = []string{"user"}
+	buildFields(t, store)
+	if got := fieldRows(t, store, "status"); got != 0 {
+		t.Fatalf("dropping status left %d rows behind", got)
+	}
+	if got := fieldRows(t, store, "user"); got != 2 {
+		t.Fatalf("dropping status touched the user rows: %d left", got)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, _ := newFieldStore(t, path)
+	if got := reopened.indexedFields(); len(got) != 1 || got["user"] != 0 {
+		t.Fatalf("after a restart the field index state is %v, want user complete", got)
+	}
+}
+
+func TestMigrationFromV3AddsTheFieldIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("open raw: %v", err)
+	}
+	if _, err := db.Exec(schemaV1 + schemaV2 + schemaV3 + `
+		INSERT INTO containers (id, host, container_id, name, first_seen_ms, last_seen_ms)
+		VALUES (1, 'local', 'aaa', 'web', 1, 1);
+		INSERT INTO log_lines (container_ref, ts_ns, stream, level, raw) VALUES (1, 10, 0, 0, 'migrated status=418');
+		PRAGMA user_version = 3;`); err != nil {
+		t.Fatalf("write a v3 database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("close raw: %v", err)
+	}
+
+	store, keys := newFieldStore(t, path)
+	if got := store.indexedFields(); len(got) != 0 {
+		t.Fatalf("a migrated store starts with field index state %v, want none", got)
+	}
+
+	// Lines stored before the migration become filterable through catch-up.
+	*keys = []string{"status"}
+	buildFields(t, store)
+	page, err := store.Query(context.Background(), LogQuery{Container: "web", Filter: "status=418"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if got := messages(page.Entries); !slices.Equal(got, []string{"migrated status=418"}) {
+		t.Fatalf("indexed filter over migrated history returned %q", got)
+	}
+}
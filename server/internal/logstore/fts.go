@@ -0,0 +1,339 @@
+package logstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// The full-text index (log_lines_fts) is an optional trigram index over every
+// stored line's parsed, lowercased message. It only ever narrows a substring
+// search: Query still groups and filters every entry it returns exactly as it
+// would without the index, so a stale or partial index could cost speed but
+// never change a result. Every write to it happens in a transaction that also
+// changes log_lines, which is what keeps the two in step.
+
+// indexChunk bounds one catch-up transaction, which indexes lines that were
+// stored before the index was turned on. Tests lower it to cover several
+// chunks with a small fixture.
+var indexChunk = 5000
+
+const (
+	// minIndexedNeedle is the shortest search a trigram index can answer.
+	minIndexedNeedle = 3
+	// indexOff is the index state while no index is maintained. 0 means the
+	// index covers every stored line, and a positive value means lines with a
+	// rowid below it are still waiting for catch-up.
+	indexOff = -1
+	// indexStateKey is the store_meta row persisting the index state. The row
+	// is absent while the index is off.
+	indexStateKey = "fts_pending_below"
+)
+
+// loadIndexState reads the persisted index state.
+func loadIndexState(ctx context.Context, db *sql.DB) (int64, error) {
+	var below int64
+	err := db.QueryRowContext(ctx, "SELECT value FROM store_meta WHERE key = ?", indexStateKey).Scan(&below)
+	if errors.Is(err, sql.ErrNoRows) {
+		return indexOff, nil
+	}
+	return below, err
+}
+
+// indexActive reports, inside a write transaction, whether the index is being
+// maintained. Deletions read it from the database rather than from the writer's
+// in-memory state: DeleteContainer runs off the writer goroutine, and the write
+// lock its transaction holds is what orders it against the writer turning the
+// index on or off.
+func indexActive(ctx context.Context, tx *sql.Tx) (bool, error) {
+	var exists int
+	err := tx.QueryRowContext(ctx, "SELECT 1 FROM store_meta WHERE key = ?", indexStateKey).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// maintainIndex brings the index in line with the configured setting. It runs
+// on the writer goroutine, so turning the index on, catching up, and dropping
+// it never compete with ingestion for the write lock, and commit always sees
+// the state the last step left behind. Catch-up indexes one chunk per call,
+// newest lines first, so recent history becomes searchable soonest.
+func (s *Store) maintainIndex() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	enabled := s.limits().FullTextIndex
+	state := s.index.Load()
+
+	var err error
+	switch {
+	case enabled && state == indexOff:
+		err = s.startIndex(ctx)
+	case enabled && state > 0:
+		err = s.catchUpIndex(ctx, state)
+	case !enabled && state != indexOff:
+		err = s.dropIndex(ctx)
+	}
+	if err != nil {
+		log.Printf("logstore: full-text index maintenance failed: %v", err)
+	}
+}
+
+// startIndex turns the index on. From here commit indexes every line it
+// writes, and catch-up covers the ones already stored.
+func (s *Store) startIndex(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var below int64
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(rowid), 0) + 1 FROM log_lines").Scan(&below); err != nil {
+		return err
+	}
+	if err := setIndexState(ctx, tx, below); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.index.Store(below)
+	return nil
+}
+
+// catchUpIndex indexes the newest indexChunk lines below the catch-up mark.
+// Re-indexing a line commit already indexed is harmless, so a rowid SQLite
+// reused after a deletion needs no special care.
+func (s *Store) catchUpIndex(ctx context.Context, below int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	lines, err := linesBelow(ctx, tx, below)
+	if err != nil {
+		return err
+	}
+	for _, stored := range lines {
+		if err := indexLine(ctx, tx, stored.rowid, lineMessage(stored.line)); err != nil {
+			return err
+		}
+	}
+
+	next := int64(0) // everything below the mark is indexed
+	if len(lines) == indexChunk {
+		next = lines[len(lines)-1].rowid
+	}
+	if err := setIndexState(ctx, tx, next); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.index.Store(next)
+	return nil
+}
+
+// dropIndex turns the index off and empties it, giving its pages back to
+// SQLite for reuse.
+func (s *Store) dropIndex(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO log_lines_fts (log_lines_fts) VALUES ('delete-all')"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM store_meta WHERE key = ?", indexStateKey); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.index.Store(indexOff)
+	return nil
+}
+
+// storedLine is one stored line read back for indexing.
+type storedLine struct {
+	rowid int64
+	line  line
+}
+
+// linesBelow reads the newest indexChunk lines with a rowid below the given
+// catch-up mark, newest first.
+func linesBelow(ctx context.Context, tx *sql.Tx, below int64) ([]storedLine, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT rowid, stream, raw FROM log_lines WHERE rowid < ? ORDER BY rowid DESC LIMIT ?", below, indexChunk)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []storedLine
+	for rows.Next() {
+		var stored storedLine
+		if err := rows.Scan(&stored.rowid, &stored.line.stream, &stored.line.raw); err != nil {
+			return nil, err
+		}
+		lines = append(lines, stored)
+	}
+	return lines, rows.Err()
+}
+
+func setIndexState(ctx context.Context, tx *sql.Tx, below int64) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO store_meta (key, value) VALUES (?, ?)", indexStateKey, below)
+	return err
+}
+
+// indexLine adds one stored line's message to the index, lowercased the same
+// way the matcher lowercases it. The tokenizer is case-sensitive, so no second
+// notion of case folding can make the index disagree with the matcher.
+func indexLine(ctx context.Context, tx *sql.Tx, rowid int64, message string) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO log_lines_fts (rowid, message) VALUES (?, ?)", rowid, strings.ToLower(message))
+	return err
+}
+
+// lineMessage is the message Query matches a stored line against, which is
+// what both indexes are built from.
+func lineMessage(l line) string {
+	return models.ParseLogLine(l.raw, streamName(l.stream)).Message
+}
+
+// indexLookup is an index's answer to part of a query's filter: a condition on
+// log_lines.rowid selecting every row that may belong to a matching entry.
+// candidate reports whether an already-read row is one the condition selects.
+type indexLookup struct {
+	where     string
+	args      []any
+	candidate func(storedRow) bool
+}
+
+// indexLookup returns the lookup Query can narrow its scan with, or nil when
+// it has to read every row. A field predicate the field index covers is
+// preferred over a substring search, as it usually selects far fewer rows.
+func (s *Store) indexLookup(m matcher) *indexLookup {
+	if lookup := s.fieldLookup(m); lookup != nil {
+		return lookup
+	}
+	if needle := s.indexNeedle(m); needle != "" {
+		return &indexLookup{
+			where: "rowid IN (SELECT rowid FROM log_lines_fts WHERE log_lines_fts MATCH ?)",
+			// Matched as one quoted phrase, which a trigram index answers as a plain
+			// substring test.
+			args: []any{`"` + strings.ReplaceAll(needle, `"`, `""`) + `"`},
+			candidate: func(row storedRow) bool {
+				return strings.Contains(strings.ToLower(row.entry.Message), needle)
+			},
+		}
+	}
+	return nil
+}
+
+// indexNeedle returns the search Query can look up in the full-text index, or
+// "" when it cannot: no substring search, a regex, a needle too short for
+// trigrams, or an index that is off or still catching up. A needle holding a
+// newline can span two lines of a grouped entry, which a per-line index cannot
+// see.
+func (s *Store) indexNeedle(m matcher) string {
+	if m.needle == "" || utf8.RuneCountInString(m.needle) < minIndexedNeedle || strings.Contains(m.needle, "\n") {
+		return ""
+	}
+	if s.index.Load() != 0 || !s.limits().FullTextIndex {
+		return ""
+	}
+	return m.needle
+}
+
+// anyCandidate reports whether any of rows is one the lookup selects.
+func (l *indexLookup) anyCandidate(rows []storedRow) bool {
+	return slices.ContainsFunc(rows, l.candidate)
+}
+
+// seekMatch finds where a filtered scan can resume so it skips every row the
+// index proves cannot match. It looks up the newest row older than from that
+// the lookup selects, then walks forward over the continuation lines of that
+// row's entry: the scan resumes just above the entry's last row, which makes
+// that entry the newest one it reads.
+//
+// found is false when no older row is selected at all. skip is false when the
+// scan should just carry on from from: the entry reaches all the way back up to
+// it, or runs on for longer than one walk reads.
+func (s *Store) seekMatch(ctx context.Context, refs []int64, q LogQuery, lookup *indexLookup, from cursorPos, hasPos bool, byRef map[int64]generation) (resume cursorPos, skip, found bool, err error) {
+	statement, args := buildMatch(refs, q, lookup, from, hasPos)
+	candidates, err := s.readRows(ctx, statement, args, 1)
+	if err != nil || len(candidates) == 0 {
+		return cursorPos{}, false, false, err
+	}
+	candidate := rebuildRows(candidates, byRef)[0]
+
+	newer, err := s.readMerged(ctx, refs, indexedChunk, olderThan, func(refs []int64) (string, []any) {
+		return buildWalk(refs, q, candidate.pos, from, hasPos, indexedChunk)
+	}, byRef)
+	if err != nil {
+		return cursorPos{}, false, false, err
+	}
+
+	// The same fold groupRows applies, walked in the same oldest-first order.
+	// Other containers' lines may interleave the entry without ending it.
+	entry := candidate.entry
+	for _, row := range newer {
+		if row.entry.ContainerID != entry.ContainerID {
+			continue
+		}
+		if row.format.Fold(&entry, row.entry) {
+			continue
+		}
+		return row.pos, true, true, nil
+	}
+	return cursorPos{}, false, true, nil
+}
+
+// buildMatch renders the index lookup: the newest row of the query's window,
+// older than from, that the lookup selects.
+func buildMatch(refs []int64, q LogQuery, lookup *indexLookup, from cursorPos, hasPos bool) (string, []any) {
+	where, args := windowWhere(refs, q)
+	if hasPos {
+		where = append(where, "(ts_ns < ? OR (ts_ns = ? AND rowid < ?))")
+		args = append(args, from.tsNS, from.tsNS, from.rowid)
+	}
+	args = append(slices.Clone(lookup.args), args...)
+
+	statement := "SELECT rowid, container_ref, ts_ns, stream, raw FROM log_lines WHERE " +
+		lookup.where + " AND " +
+		strings.Join(where, " AND ") +
+		" ORDER BY ts_ns DESC, rowid DESC LIMIT 1"
+	return statement, args
+}
+
+// buildWalk renders an oldest-first read of the rows strictly newer than after
+// and, with a position, strictly older than from.
+func buildWalk(refs []int64, q LogQuery, after, from cursorPos, hasPos bool, limit int) (string, []any) {
+	where, args := windowWhere(refs, q)
+	where = append(where, "(ts_ns > ? OR (ts_ns = ? AND rowid > ?))")
+	args = append(args, after.tsNS, after.tsNS, after.rowid)
+	if hasPos {
+		where = append(where, "(ts_ns < ? OR (ts_ns = ? AND rowid < ?))")
+		args = append(args, from.tsNS, from.tsNS, from.rowid)
+	}
+	args = append(args, limit)
+
+	statement := "SELECT rowid, container_ref, ts_ns, stream, raw FROM log_lines WHERE " +
+		strings.Join(where, " AND ") +
+		" ORDER BY ts_ns, rowid LIMIT ?"
+	return statement, args
+}
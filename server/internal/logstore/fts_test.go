@@ -0,0 +1,359 @@
+package logstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// newIndexedStore opens a store whose full-text index switch the test flips
+// through the returned pointer, the way the settings API flips it through the
+// config manager.
+func newIndexedStore(t *testing.T, path string) (*Store, *bool) {
+	t.Helper()
+	enabled := new(bool)
+	store, err := Open(path, func() config.ResolvedLogStoreConfig {
+		limits := testLimits()
+		limits.FullTextIndex = *enabled
+		return limits
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, enabled
+}
+
+// buildIndex runs the writer's index maintenance until catch-up is complete.
+func buildIndex(t *testing.T, s *Store) {
+	t.Helper()
+	for range 100 {
+		s.maintainIndex()
+		if s.index.Load() == 0 {
+			return
+		}
+	}
+	t.Fatalf("the index never finished catching up (state %d)", s.index.Load())
+}
+
+// indexedCount reports how many rows the index holds for needle.
+func indexedCount(t *testing.T, s *Store, needle string) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(
+		"SELECT count(*) FROM log_lines_fts WHERE log_lines_fts MATCH ?", `"`+needle+`"`,
+	).Scan(&n); err != nil {
+		t.Fatalf("count indexed rows: %v", err)
+	}
+	return n
+}
+
+// smallIndexChunks lowers indexChunk for one test, so index catch-up runs in
+// several chunks over a fixture of a few thousand lines.
+func smallIndexChunks(t *testing.T) {
+	t.Helper()
+	orig := indexChunk
+	indexChunk = 300
+	t.Cleanup(func() { indexChunk = orig })
+}
+
+// allPages follows a query's cursor to the end of history and flattens every
+// page, cursor included, so two runs can be compared exactly.
+func allPages(t *testing.T, s *Store, q LogQuery) []string {
+	t.Helper()
+	var out []string
+	for range 1000 {
+		page, err := s.Query(context.Background(), q)
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		for _, entry := range page.Entries {
+			out = append(out, entry.ContainerName+": "+entry.Message)
+		}
+		out = append(out, "cursor "+page.NextCursor)
+		if page.NextCursor == "" {
+			return out
+		}
+		q.Cursor = page.NextCursor
+	}
+	t.Fatal("paging never reached the end of history")
+	return nil
+}
+
+// writeShop stores lines numbered from..to-1 spread across twelve containers of
+// one compose project — past mergeRefsAbove, so store-wide reads merge. Rare
+// lines and multi-line entries are sprinkled in at fixed positions.
+func writeShop(t *testing.T, s *Store, from, to int) {
+	t.Helper()
+	byContainer := make(map[int][]models.LogEntry)
+	for i := from; i < to; i++ {
+		c := i % 12
+		ts := baseTime.Add(time.Duration(i) * time.Millisecond)
+		switch {
+		case i%997 == 0:
+			byContainer[c] = append(byContainer[c],
+				entryAt(ts, "stderr", fmt.Sprintf("level=error payment ORDER-%d declined", i)))
+		case i%1500 == 7:
+			// The continuation is one nanosecond younger than its parent, so no other
+			// container's line can come between them.
+			byContainer[c] = append(byContainer[c],
+				entryAt(ts, "stderr", "level=error unhandled exception"),
+				entryAt(ts.Add(time.Nanosecond), "stderr", fmt.Sprintf("at com.example.Checkout.pay(Checkout.java:%d)", i)))
+		default:
+			byContainer[c] = append(byContainer[c],
+				entryAt(ts, "stdout", fmt.Sprintf("level=info request %05d served", i)))
+		}
+	}
+	for c, entries := range byContainer {
+		name := fmt.Sprintf("svc-%02d", c)
+		writeProjectEntries(t, s, genKey{"local", name}, name, "shop", entries...)
+	}
+}
+
+// TestFullTextIndexAgreesWithTheScan is the index's contract: switching it on
+// changes how fast a substring search runs, never what it returns or how it
+// pages.
+func TestFullTextIndexAgreesWithTheScan(t *testing.T) {
+	smallIndexChunks(t)
+	store, enabled := newIndexedStore(t, filepath.Join(t.TempDir(), "logs.db"))
+
+	// Stored before the index exists: these are indexed by catch-up, in more
+	// than one chunk.
+	writeShop(t, store, 0, 1600)
+	*enabled = true
+	buildIndex(t, store)
+	// Stored after: these are indexed by the commit that writes them.
+	writeShop(t, store, 1600, 2000)
+
+	queries := []LogQuery{
+		{Container: "svc-03", Search: "declined"},
+		{Project: "shop", Search: "DECLINED", Limit: 4},
+		{Search: "checkout.java", Levels: []string{"ERROR"}},
+		{Search: "checkout.java", Limit: 2},
+		{Search: "request 01", Limit: 300},
+		{Containers: []string{"svc-01", "svc-02"}, Search: "order-"},
+		{Search: "declined", Since: baseTime.Add(500 * time.Millisecond), Until: baseTime.Add(1600 * time.Millisecond)},
+		{Search: "no line says this"},
+		{Search: `say "hi"`},
+	}
+	for _, q := range queries {
+		*enabled = true
+		if store.indexNeedle(mustMatcher(t, q)) == "" {
+			t.Fatalf("query %+v would not use the index", q)
+		}
+		indexed := allPages(t, store, q)
+
+		*enabled = false
+		scanned := allPages(t, store, q)
+
+		if !slices.Equal(indexed, scanned) {
+			t.Fatalf("query %+v: the index changed the result\nindexed %q\nscanned %q", q, indexed, scanned)
+		}
+	}
+
+	// The multi-line entry whose needle lives only in its continuation line must
+	// come back whole.
+	*enabled = true
+	page, err := store.Query(context.Background(), LogQuery{Container: "svc-07", Search: "checkout.java:7)"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	want := []string{"level=error unhandled exception\nat com.example.Checkout.pay(Checkout.java:7)"}
+	if got := messages(page.Entries); !slices.Equal(got, want) {
+		t.Fatalf("continuation-only match returned %q, want %q", got, want)
+	}
+}
+
+func mustMatcher(t *testing.T, q LogQuery) matcher {
+	t.Helper()
+	m, err := newMatcher(q)
+	if err != nil {
+		t.Fatalf("newMatcher: %v", err)
+	}
+	return m
+}
+
+// TestQueryConsultsTheIndex proves a substring search goes through the index
+// rather than the scan: a line written behind the index's back is invisible to
+// an indexed search, and found again once the index is off. Regex and short
+// searches always scan.
+func TestQueryConsultsTheIndex(t *testing.T) {
+	store, enabled := newIndexedStore(t, filepath.Join(t.TempDir(), "logs.db"))
+	ctx := context.Background()
+	key := genKey{"local", "aaa"}
+
+	*enabled = true
+	buildIndex(t, store)
+	writeEntries(t, store, key, "web", entryAt(baseTime, "stdout", "level=info indexed needle"))
+
+	var ref int64
+	if err := store.db.QueryRow("SELECT id FROM containers WHERE name = 'web'").Scan(&ref); err != nil {
+		t.Fatalf("read ref: %v", err)
+	}
+	if _, err := store.db.Exec(
+		"INSERT INTO log_lines (container_ref, ts_ns, stream, level, raw) VALUES (?, ?, 0, 0, ?)",
+		ref, baseTime.Add(time.Second).UnixNano(), rawLine(baseTime.Add(time.Second), "level=info hidden needle"),
+	); err != nil {
+		t.Fatalf("write behind the index: %v", err)
+	}
+
+	search := func(q LogQuery) []string {
+		t.Helper()
+		q.Container = "web"
+		page, err := store.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		return messages(page.Entries)
+	}
+
+	if got := search(LogQuery{Search: "needle"}); !slices.Equal(got, []string{"level=info indexed needle"}) {
+		t.Fatalf("indexed search returned %q, want only the indexed line", got)
+	}
+	if got := search(LogQuery{Search: "n.edle", Regex: true}); len(got) != 2 {
+		t.Fatalf("regex search returned %q, want both lines from the scan", got)
+	}
+	if got := search(LogQuery{Search: "ne"}); len(got) != 2 {
+		t.Fatalf("a two-character search returned %q, want both lines from the scan", got)
+	}
+
+	*enabled = false
+	if got := search(LogQuery{Search: "needle"}); len(got) != 2 {
+		t.Fatalf("with the index switched off the search returned %q, want both lines", got)
+	}
+}
+
+// TestRetentionAndPurgeClearTheIndex keeps the index from pointing at lines
+// that are gone — rowids SQLite is free to hand to new lines.
+func TestRetentionAndPurgeClearTheIndex(t *testing.T) {
+	store, enabled := newIndexedStore(t, filepath.Join(t.TempDir(), "logs.db"))
+	ctx := context.Background()
+
+	*enabled = true
+	buildIndex(t, store)
+	writeEntries(t, store, genKey{"local", "aaa"}, "web",
+		entryAt(baseTime, "stdout", "alpha token one"),
+		entryAt(baseTime.Add(time.Second), "stdout", "alpha token two"),
+	)
+	writeEntries(t, store, genKey{"local", "bbb"}, "api",
+		entryAt(baseTime, "stdout", "beta token"),
+	)
+
+	var webRef int64
+	if err := store.db.QueryRow("SELECT id FROM containers WHERE name = 'web'").Scan(&webRef); err != nil {
+		t.Fatalf("read ref: %v", err)
+	}
+	if _, err := store.evictOldest(ctx, []int64{webRef}, 1); err != nil {
+		t.Fatalf("evictOldest: %v", err)
+	}
+	if got := indexedCount(t, store, "alpha token"); got != 1 {
+		t.Fatalf("after evicting one line the index holds %d alpha rows, want 1", got)
+	}
+
+	if _, err := store.DeleteContainer(ctx, "local", "api"); err != nil {
+		t.Fatalf("DeleteContainer: %v", err)
+	}
+	if got := indexedCount(t, store, "beta"); got != 0 {
+		t.Fatalf("after the purge the index still holds %d beta rows", got)
+	}
+	if got := indexedCount(t, store, "alpha"); got != 1 {
+		t.Fatalf("the purge touched another container's index rows: %d alpha rows, want 1", got)
+	}
+}
+
+// TestFullTextIndexSwitchesOffAndPersists covers the index's lifecycle across
+// the setting and across restarts.
+func TestFullTextIndexSwitchesOffAndPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+	store, enabled := newIndexedStore(t, path)
+
+	writeEntries(t, store, genKey{"local", "aaa"}, "web", entryAt(baseTime, "stdout", "kept token"))
+	*enabled = true
+	buildIndex(t, store)
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, enabled := newIndexedStore(t, path)
+	if got := reopened.index.Load(); got != 0 {
+		t.Fatalf("after a restart the index state is %d, want 0 (complete)", got)
+	}
+	if got := indexedCount(t, reopened, "kept"); got != 1 {
+		t.Fatalf("the index lost its rows across a restart: %d", got)
+	}
+
+	// Opened with the setting off: the first maintenance pass drops the index.
+	*enabled = false
+	reopened.maintainIndex()
+	if got := reopened.index.Load(); got != indexOff {
+		t.Fatalf("index state = %d after switching off, want %d", got, indexOff)
+	}
+	if got := indexedCount(t, reopened, "kept"); got != 0 {
+		t.Fatalf("switching the index off left %d rows behind", got)
+	}
+	var meta int
+	if err := reopened.db.QueryRow("SELECT count(*) FROM store_meta").Scan(&meta); err != nil {
+		t.Fatalf("read store_meta: %v", err)
+	}
+	if meta != 0 {
+		t.Fatalf("switching the index off left %d store_meta rows", meta)
+	}
+
+	// Stored lines are untouched, and new lines are not indexed while it is off.
+	writeEntries(t, reopened, genKey{"local", "aaa"}, "web", entryAt(baseTime.Add(time.Second), "stdout", "later token"))
+	if got := indexedCount(t, reopened, "token"); got != 0 {
+		t.Fatalf("a line written with the index off was indexed")
+	}
+	if got := storedMessages(t, reopened, "local", "web"); len(got) != 2 {
+		t.Fatalf("switching the index off touched stored lines: %q", got)
+	}
+}
+
+func TestMigrationFromV2AddsTheFullTextIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("open raw: %v", err)
+	}
+	if _, err := db.Exec(schemaV1 + schemaV2 + `
+		INSERT INTO containers (id, host, container_id, name, first_seen_ms, last_seen_ms)
+		VALUES (1, 'local', 'aaa', 'web', 1, 1);
+		INSERT INTO log_lines (container_ref, ts_ns, stream, level, raw) VALUES (1, 10, 0, 0, 'migrated line');
+		PRAGMA user_version = 2;`); err != nil {
+		t.Fatalf("write a v2 database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("close raw: %v", err)
+	}
+
+	store, enabled := newIndexedStore(t, path)
+	var version int
+	if err := store.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("read user_version: %v", err)
+	}
+	if version != schemaVersion {
+		t.Fatalf("user_version = %d, want %d", version, schemaVersion)
+	}
+	if got := store.index.Load(); got != indexOff {
+		t.Fatalf("a migrated store starts with index state %d, want off", got)
+	}
+
+	// Lines stored before the migration become searchable through catch-up.
+	*enabled = true
+	buildIndex(t, store)
+	page, err := store.Query(context.Background(), LogQuery{Container: "web", Search: "migrated"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if got := messages(page.Entries); !slices.Equal(got, []string{"migrated line"}) {
+		t.Fatalf("indexed search over migrated history returned %q", got)
+	}
+}
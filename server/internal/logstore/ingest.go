@@ -134,6 +134,9 @@ func (s *Store) writeLoop() {
 			}
 		case <-ticker.C:
 			flush()
+			s.maintainIndex()
+			s.maintainFields()
+			s.refreshFilter()
 		case <-s.retainCh:
 			flush()
 			retain()
@@ -207,6 +210,11 @@ func (s *Store) commit(batch []ingestMsg, refs map[genKey]int64) error {
 	fresh := make(map[genKey]int64) // ids this transaction discovered
 	nowMS := time.Now().UnixMilli()
 	insertedCount := int64(0)
+	levelCounts := make(map[lineCountKey]int64)
+	// Once an index is on, every new line is indexed in the transaction that
+	// stores it; lines stored before that are left to catch-up.
+	indexing := s.index.Load() != indexOff
+	indexedKeys := s.indexedFields()
 
 	for _, msg := range batch {
 		ref, ok := refs[msg.key]
@@ -231,7 +239,7 @@ func (s *Store) commit(batch []ingestMsg, refs map[genKey]int64) error {
 			continue
 		}
 
-		inserted, err := insertLine(ctx, tx, ref, msg.line)
+		rowid, inserted, err := insertLine(ctx, tx, ref, msg.line)
 		if err != nil {
 			return err
 		}
@@ -239,6 +247,18 @@ func (s *Store) commit(batch []ingestMsg, refs map[genKey]int64) error {
 			continue
 		}
 		insertedCount++
+		levelCounts[lineCountKey{msg.key.host, msg.name, msg.project, msg.line.level}]++
+		if indexing || len(indexedKeys) > 0 {
+			message := lineMessage(msg.line)
+			if indexing {
+				if err := indexLine(ctx, tx, rowid, message); err != nil {
+					return err
+				}
+			}
+			if err := indexFields(ctx, tx, rowid, message, indexedKeys); err != nil {
+				return err
+			}
+		}
 
 		a := aggs[ref]
 		if a == nil {
@@ -271,6 +291,7 @@ func (s *Store) commit(batch []ingestMsg, refs map[genKey]int64) error {
 		return err
 	}
 	s.committed.Add(insertedCount)
+	s.lines.add(levelCounts)
 
 	// The ids are real only now.
 	for key, ref := range fresh {
@@ -302,7 +323,8 @@ func upsertGeneration(ctx context.Context, tx *sql.Tx, key genKey, name, project
 }
 
 // insertLine stores one line unless an identical (ts_ns, stream, raw) row is
-// already stored for the generation, and reports whether a row was written.
+// already stored for the generation. It reports whether a row was written and,
+// if so, its rowid.
 //
 // Every insert is deduplicated, not just backfilled ones: live delivery and a
 // backfill re-read of the same window can reach the writer in either order
@@ -311,7 +333,7 @@ func upsertGeneration(ctx context.Context, tx *sql.Tx, key genKey, name, project
 // same B-tree the insert already touches. Note that this never drops a line by
 // timestamp alone — only a byte-identical line on the same stream in the same
 // nanosecond, which is the duplicate we are trying to avoid.
-func insertLine(ctx context.Context, tx *sql.Tx, ref int64, l line) (bool, error) {
+func insertLine(ctx context.Context, tx *sql.Tx, ref int64, l line) (int64, bool, error) {
 	result, err := tx.ExecContext(ctx, `
 		INSERT INTO log_lines (container_ref, ts_ns, stream, level, raw)
 		SELECT ?, ?, ?, ?, ?
@@ -322,11 +344,15 @@ func insertLine(ctx context.Context, tx *sql.Tx, ref int64, l line) (bool, error
 		ref, l.tsNS, l.stream, l.level, l.raw,
 		ref, l.tsNS, l.stream, l.raw)
 	if err != nil {
-		return false, err
+		return 0, false, err
 	}
 	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return 0, false, err
+	}
+	rowid, err := result.LastInsertId()
 	if err != nil {
-		return false, err
+		return 0, false, err
 	}
-	return rows > 0, nil
+	return rowid, true, nil
 }
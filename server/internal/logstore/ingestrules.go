@@ -0,0 +1,209 @@
+package logstore
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/logstream"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// ingestFilter is the compiled form of the configured ingestion rules. The
+// writer recompiles it when the rules change (see refreshFilter); the sink and
+// backfills only read it.
+type ingestFilter struct {
+	source []config.IngestionRule
+	rules  []ingestRule
+}
+
+type ingestRule struct {
+	index   int // position in the configured list, for reasons
+	spec    logstream.ContainerSpec
+	labels  []labelMatch
+	action  string
+	pattern *regexp.Regexp
+	percent int
+}
+
+// labelMatch is one "key" or "key=value" label selector; an empty value
+// matches any value of the key.
+type labelMatch struct {
+	key, value string
+}
+
+func compileIngestion(rules []config.IngestionRule) *ingestFilter {
+	filter := &ingestFilter{source: rules}
+	for i, rule := range rules {
+		compiled := ingestRule{
+			index:   i + 1,
+			spec:    logstream.ContainerSpec{Hosts: rule.Hosts, Containers: rule.Containers, Projects: rule.Projects},
+			action:  rule.Action,
+			percent: rule.SamplePercent,
+		}
+		for _, label := range rule.Labels {
+			key, value, _ := strings.Cut(label, "=")
+			compiled.labels = append(compiled.labels, labelMatch{key: key, value: value})
+		}
+		if rule.Action == config.IngestionDrop {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				log.Printf("logstore: ingestion rule %d has an invalid pattern, skipping it: %v", i+1, err)
+				continue
+			}
+			compiled.pattern = pattern
+		}
+		filter.rules = append(filter.rules, compiled)
+	}
+	return filter
+}
+
+// matches reports whether the rule targets a container. Labels widen the
+// names-or-projects dimension; a rule with neither targets every container on
+// its hosts.
+func (r ingestRule) matches(host, name string, labels map[string]string) bool {
+	if len(r.labels) == 0 {
+		return r.spec.Matches(host, name, labels)
+	}
+	if !(logstream.ContainerSpec{Hosts: r.spec.Hosts}).Matches(host, name, labels) {
+		return false
+	}
+	for _, want := range r.labels {
+		if value, ok := labels[want.key]; ok && (want.value == "" || value == want.value) {
+			return true
+		}
+	}
+	return (len(r.spec.Containers) > 0 || len(r.spec.Projects) > 0) && r.spec.Matches(host, name, labels)
+}
+
+// containerFilter is the combined effect of every rule that targets one
+// container. The zero value keeps everything.
+type containerFilter struct {
+	excluded bool
+	patterns []*regexp.Regexp
+	percent  int               // share of INFO-and-below entries kept; 100 keeps them all
+	format   models.LineFormat // groups lines into the entries sampling decides on
+	reason   string
+}
+
+// openEntry is the entry a container's lines are folding into, and whether
+// sampling kept it. The zero value has no entry open.
+type openEntry struct {
+	entry models.LogEntry
+	open  bool
+	kept  bool
+}
+
+// openEntries holds the open entry of each live-tailed container that
+// sampling applies to. Sink calls may come from several goroutines.
+type openEntries struct {
+	mu      sync.Mutex
+	entries map[genKey]*openEntry
+}
+
+// keeps applies filter to the generation's next live line, carrying the
+// generation's open entry from the previous one.
+func (o *openEntries) keeps(key genKey, filter containerFilter, entry models.LogEntry) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if filter.percent >= 100 {
+		delete(o.entries, key)
+		return filter.keeps(entry, nil)
+	}
+	open := o.entries[key]
+	if open == nil {
+		if o.entries == nil {
+			o.entries = make(map[genKey]*openEntry)
+		}
+		open = &openEntry{}
+		o.entries[key] = open
+	}
+	return filter.keeps(entry, open)
+}
+
+// forContainer works out what the rules do to one container's lines. A
+// container labelled logdeck.store=false is excluded whatever the rules say.
+func (f *ingestFilter) forContainer(host, name string, labels map[string]string) containerFilter {
+	policy := models.PolicyFromLabels(labels)
+	filter := containerFilter{percent: 100, format: policy.Format}
+	var reasons []string
+	if policy.NoStore {
+		filter.excluded = true
+		reasons = append(reasons, "excluded by label "+models.LabelStore+"=false")
+	}
+	for _, rule := range f.rules {
+		if !rule.matches(host, name, labels) {
+			continue
+		}
+		switch rule.action {
+		case config.IngestionExclude:
+			filter.excluded = true
+			reasons = append(reasons, fmt.Sprintf("excluded by ingestion rule %d", rule.index))
+		case config.IngestionDrop:
+			filter.patterns = append(filter.patterns, rule.pattern)
+			reasons = append(reasons, fmt.Sprintf("ingestion rule %d drops lines matching %q", rule.index, rule.pattern))
+		case config.IngestionSample:
+			filter.percent = min(filter.percent, rule.percent)
+			reasons = append(reasons, fmt.Sprintf("ingestion rule %d keeps %d%% of INFO-and-below entries", rule.index, rule.percent))
+		}
+	}
+	filter.reason = strings.Join(reasons, "; ")
+	return filter
+}
+
+// keeps reports whether the container's next line is stored. open carries
+// the entry being built from one line of the container to the next; nil
+// decides the line on its own.
+//
+// Sampling decides per entry: a line that continues the open entry is kept or
+// dropped with it, so a stored ERROR keeps its whole trace (continuation lines
+// are mostly UNKNOWN, below INFO) and a sampled-out entry leaves no stray
+// lines behind. It hashes the entry's first raw line rather than drawing at
+// random, so the live path and a backfill re-reading the same window make the
+// same choice: a sampled-out entry is not stored by the next gap heal either.
+func (c containerFilter) keeps(entry models.LogEntry, open *openEntry) bool {
+	if c.excluded {
+		return false
+	}
+	for _, pattern := range c.patterns {
+		if pattern.MatchString(entry.Message) {
+			return false
+		}
+	}
+	if c.percent >= 100 {
+		return true
+	}
+	if open != nil && open.open && c.format.Fold(&open.entry, entry) {
+		return open.kept
+	}
+	kept := models.LevelSeverity(entry.Level) > models.LevelSeverity(models.LogLevelInfo)
+	if !kept {
+		h := fnv.New32a()
+		h.Write([]byte(entry.Raw))
+		kept = int(h.Sum32()%100) < c.percent
+	}
+	if open != nil {
+		*open = openEntry{entry: entry, open: true, kept: kept}
+	}
+	return kept
+}
+
+// ingestFilter returns the current compiled ingestion rules.
+func (s *Store) ingestFilter() *ingestFilter {
+	return s.filter.Load()
+}
+
+// refreshFilter recompiles the ingestion rules if the configured ones have
+// changed since they were last compiled.
+func (s *Store) refreshFilter() {
+	rules := s.limits().IngestionRules
+	if current := s.filter.Load(); current != nil && reflect.DeepEqual(current.source, rules) {
+		return
+	}
+	s.filter.Store(compileIngestion(rules))
+}
@@ -0,0 +1,229 @@
+package logstore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/logstream"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+func linesOf(t *testing.T, s *Store, name string) []string {
+	t.Helper()
+	var out []string
+	rows, err := s.db.Query(`
+		SELECT l.raw FROM log_lines l JOIN containers c ON c.id = l.container_ref
+		WHERE c.name = ? ORDER BY l.ts_ns`, name)
+	if err != nil {
+		t.Fatalf("read lines: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		out = append(out, models.ParseLogLine(raw, "stdout").Message)
+	}
+	return out
+}
+
+func listed(t *testing.T, s *Store, name string) StoredContainer {
+	t.Helper()
+	containers, err := s.ListContainers(context.Background())
+	if err != nil {
+		t.Fatalf("ListContainers: %v", err)
+	}
+	for _, c := range containers {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("%s is not listed: %+v", name, containers)
+	return StoredContainer{}
+}
+
+// TestIngestionRulesFilterLiveAndBackfilledLines runs the noisy-sidecar setup
+// through the whole pipeline: an excluded pinger is neither tailed nor stored,
+// Traefik's health checks are dropped on both paths, and removing the rules
+// lets the pinger in.
+func TestIngestionRulesFilterLiveAndBackfilledLines(t *testing.T) {
+	var mu sync.Mutex
+	limits := testLimits()
+	limits.IngestionRules = []config.IngestionRule{
+		{Containers: []string{"pinger"}, Action: config.IngestionExclude},
+		{Labels: []string{"traefik.enable=true"}, Action: config.IngestionDrop, Pattern: `GET /ping\b`},
+	}
+	store, err := Open(filepath.Join(t.TempDir(), "logs.db"), func() config.ResolvedLogStoreConfig {
+		mu.Lock()
+		defer mu.Unlock()
+		return limits
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	traefik := containerInfo("local", "bbb", "traefik", baseTime)
+	traefik.Labels = map[string]string{"traefik.enable": "true"}
+	engine := newFakeEngine(containerInfo("local", "aaa", "pinger", baseTime), traefik)
+	engine.tail = func(_, id string, _ models.LogOptions, emit func(models.LogEntry)) error {
+		emit(entryAt(baseTime, "stdout", id+" GET /ping 200"))
+		emit(entryAt(baseTime.Add(time.Second), "stdout", id+" GET /orders 200"))
+		return nil
+	}
+	hub := &fakeHub{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store.start(ctx, hub, func() Engine { return engine })
+	defer func() {
+		cancel()
+		store.Wait()
+	}()
+
+	waitFor(t, "traefik's backfill", func() bool { return len(linesOf(t, store, "traefik")) == 1 })
+	hub.emit(logstream.Record{Host: "local", ContainerID: "aaa", ContainerName: "pinger",
+		Entry: entryAt(baseTime.Add(time.Minute), "stdout", "live ping")})
+	hub.emit(logstream.Record{Host: "local", ContainerID: "bbb", ContainerName: "traefik", Labels: traefik.Labels,
+		Entry: entryAt(baseTime.Add(time.Minute), "stdout", "GET /ping 200")})
+	hub.emit(logstream.Record{Host: "local", ContainerID: "bbb", ContainerName: "traefik", Labels: traefik.Labels,
+		Entry: entryAt(baseTime.Add(2*time.Minute), "stdout", "GET /cart 200")})
+	waitFor(t, "traefik's live line", func() bool { return len(linesOf(t, store, "traefik")) == 2 })
+
+	if got := linesOf(t, store, "traefik"); got[0] != "bbb GET /orders 200" || got[1] != "GET /cart 200" {
+		t.Fatalf("traefik stored %q, want the health checks dropped", got)
+	}
+	if got := linesOf(t, store, "pinger"); len(got) != 0 {
+		t.Fatalf("the excluded pinger stored %q", got)
+	}
+	if calls := engine.calls("aaa"); calls != 0 {
+		t.Fatalf("the excluded pinger was tailed %d times", calls)
+	}
+	if c := listed(t, store, "pinger"); !c.Filtered || c.FilteredReason != "excluded by ingestion rule 1" || c.Excluded {
+		t.Fatalf("pinger listing = %+v, want it filtered by rule 1", c)
+	}
+	if c := listed(t, store, "traefik"); !c.Filtered || !strings.Contains(c.FilteredReason, "ingestion rule 2 drops lines matching") {
+		t.Fatalf("traefik listing = %+v, want it filtered by rule 2", c)
+	}
+
+	mu.Lock()
+	limits.IngestionRules = nil
+	mu.Unlock()
+	store.sync(ctx, engine, newBackfillTracker(), make(chan backfillResult, 2))
+	waitFor(t, "the pinger's backfill", func() bool { return len(linesOf(t, store, "pinger")) == 2 })
+	if c := listed(t, store, "pinger"); c.Filtered || c.FilteredReason != "" {
+		t.Fatalf("pinger listing = %+v, want no filter once the rule is gone", c)
+	}
+}
+
+func TestSamplingKeepsWarningsAndIsDeterministic(t *testing.T) {
+	filter := compileIngestion([]config.IngestionRule{
+		{Projects: []string{"edge"}, Action: config.IngestionSample, SamplePercent: 50},
+		{Projects: []string{"edge"}, Action: config.IngestionSample, SamplePercent: 20},
+	}).forContainer("local", "proxy", map[string]string{"com.docker.compose.project": "edge"})
+
+	kept, warnings := 0, 0
+	for i := range 2000 {
+		entry := entryAt(baseTime.Add(time.Duration(i)*time.Millisecond), "stdout", fmt.Sprintf("INFO request %d", i))
+		if filter.keeps(entry, nil) {
+			kept++
+		}
+		if filter.keeps(entry, nil) != filter.keeps(entry, nil) {
+			t.Fatal("sampling the same line twice gave different answers")
+		}
+		if filter.keeps(entryAt(entry.Timestamp, "stderr", fmt.Sprintf("WARN slow request %d", i)), nil) {
+			warnings++
+		}
+	}
+	// The lowest rate wins: about 20% of 2000.
+	if kept < 300 || kept > 500 {
+		t.Fatalf("kept %d of 2000 INFO lines, want about 400", kept)
+	}
+	if warnings != 2000 {
+		t.Fatalf("kept %d of 2000 WARN lines, want every one", warnings)
+	}
+
+	// Sampling decides per entry: an error's UNKNOWN trace lines are kept with
+	// it, and a sampled-out INFO entry takes its continuation lines along.
+	var open openEntry
+	errorsKept, traceKept, strays := 0, 0, 0
+	for i := range 200 {
+		ts := baseTime.Add(time.Duration(i) * time.Second)
+		if filter.keeps(entryAt(ts, "stderr", fmt.Sprintf("ERROR request %d failed", i)), &open) {
+			errorsKept++
+		}
+		if filter.keeps(entryAt(ts.Add(time.Nanosecond), "stderr", fmt.Sprintf("request_id: req-%d", i)), &open) {
+			traceKept++
+		}
+		infoKept := filter.keeps(entryAt(ts.Add(time.Millisecond), "stdout", fmt.Sprintf("INFO request %d", i)), &open)
+		if filter.keeps(entryAt(ts.Add(time.Millisecond+time.Nanosecond), "stdout", fmt.Sprintf("status: %d", i)), &open) != infoKept {
+			strays++
+		}
+	}
+	if errorsKept != 200 || traceKept != 200 {
+		t.Fatalf("kept %d ERROR lines and %d of their trace lines, want all 200 of each", errorsKept, traceKept)
+	}
+	if strays != 0 {
+		t.Fatalf("%d INFO continuation lines were sampled apart from their entry", strays)
+	}
+
+	other := compileIngestion([]config.IngestionRule{
+		{Projects: []string{"edge"}, Action: config.IngestionSample, SamplePercent: 0},
+	}).forContainer("local", "web", nil)
+	if !other.keeps(entryAt(baseTime, "stdout", "INFO hello"), nil) || other.reason != "" {
+		t.Fatalf("a rule for another project filtered web: %+v", other)
+	}
+}
+
+func TestIngestionRuleLabelTargeting(t *testing.T) {
+	cases := []struct {
+		name   string
+		rule   config.IngestionRule
+		host   string
+		labels map[string]string
+		want   bool
+	}{
+		{"key only", config.IngestionRule{Labels: []string{"healthcheck"}}, "h1", map[string]string{"healthcheck": "x"}, true},
+		{"key and value", config.IngestionRule{Labels: []string{"tier=edge"}}, "h1", map[string]string{"tier": "edge"}, true},
+		{"value miss", config.IngestionRule{Labels: []string{"tier=edge"}}, "h1", map[string]string{"tier": "core"}, false},
+		{"host still applies", config.IngestionRule{Hosts: []string{"h2"}, Labels: []string{"tier"}}, "h1", map[string]string{"tier": "edge"}, false},
+		{"or with names", config.IngestionRule{Containers: []string{"web"}, Labels: []string{"tier"}}, "h1", nil, true},
+	}
+	for _, tc := range cases {
+		tc.rule.Action = config.IngestionExclude
+		got := compileIngestion([]config.IngestionRule{tc.rule}).forContainer(tc.host, "web", tc.labels).excluded
+		if got != tc.want {
+			t.Errorf("%s: excluded = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMigrationFromV5AddsTheFilterReason(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+	store, err := Open(path, testLimits)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	writeEntries(t, store, genKey{"local", "aaa"}, "web", entryAt(baseTime, "stdout", "kept"))
+	if _, err := store.db.Exec("ALTER TABLE containers DROP COLUMN filtered_reason; " + dropV7Columns + "PRAGMA user_version = 5"); err != nil {
+		t.Fatalf("write a v5 database: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := Open(path, testLimits)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if c := listed(t, reopened, "web"); c.Filtered {
+		t.Fatalf("a migrated container reads as filtered: %+v", c)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -53,13 +54,18 @@ func (s *Store) janitorLoop(ctx context.Context) {
 	}
 }
 
-// retain evicts oldest-first until every logical container is under the
-// per-container cap and the store as a whole is under the total cap.
+// retain first deletes what the retention rules have aged out, then evicts
+// oldest-first until every logical container is under the per-container cap
+// and the store as a whole is under the total cap.
 func (s *Store) retain(ctx context.Context) error {
 	limits := s.limits()
 	perCap := int64(limits.PerContainerMB) * bytesPerMB
 	totalCap := int64(limits.TotalMB) * bytesPerMB
 
+	if err := s.expire(ctx, limits.RetentionRules, time.Now()); err != nil {
+		return err
+	}
+
 	groups, err := s.loadGroups(ctx)
 	if err != nil {
 		return err
@@ -173,14 +179,18 @@ func (s *Store) oldestLine(ctx context.Context, refs []int64) (int64, bool, erro
 // evictOldest deletes the oldest lines of one logical container until it has
 // freed excess bytes, reading at most deleteChunk rows so one transaction can
 // never hold the write lock long enough to stall ingestion (the caller loops
-// if more is needed). It returns how many bytes it freed. The row deletion and
-// the stored_bytes adjustment share a transaction, and stored_bytes is updated
-// relative to its current value so it composes with concurrent ingestion.
+// if more is needed). It returns how many bytes it freed. The row deletion, the
+// matching index deletions, and the stored_bytes adjustment share a
+// transaction, and stored_bytes is updated relative to its current value so it
+// composes with concurrent ingestion.
 //
 // The transaction takes the write lock up front (_txlock=immediate on the DSN):
 // as a deferred read-then-write transaction it would fail with
 // SQLITE_BUSY_SNAPSHOT whenever a line was ingested between its SELECT and its
 // DELETE, so retention would stop working exactly when the store is busiest.
+//
+// With archiving enabled the span is first written to the cold tier and
+// recorded in archive_segments, in the same transaction (see coldtier.go).
 func (s *Store) evictOldest(ctx context.Context, refs []int64, excess int64) (int64, error) {
 	placeholders, args := refArgs(refs)
 
@@ -190,8 +200,15 @@ func (s *Store) evictOldest(ctx context.Context, refs []int64, excess int64) (in
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	// With archiving on, the span is read in full so it can be written to the
+	// cold tier before it is deleted.
+	archive := s.limits().Archive
+	columns := "rowid, container_ref, length(CAST(raw AS BLOB)), ts_ns, stream, ''"
+	if archive.Enabled {
+		columns = "rowid, container_ref, length(CAST(raw AS BLOB)), ts_ns, stream, raw"
+	}
 	rows, err := tx.QueryContext(ctx,
-		"SELECT rowid, container_ref, length(CAST(raw AS BLOB)) FROM log_lines"+
+		"SELECT "+columns+" FROM log_lines"+
 			" WHERE container_ref IN ("+placeholders+") ORDER BY ts_ns, rowid LIMIT ?",
 		append(args, deleteChunk)...)
 	if err != nil {
@@ -199,17 +216,25 @@ func (s *Store) evictOldest(ctx context.Context, refs []int64, excess int64) (in
 	}
 
 	var (
-		rowids []any
-		freed  int64
-		perRef = make(map[int64]int64)
+		rowids  []any
+		evicted []evictedLine
+		freed   int64
+		perRef  = make(map[int64]int64)
 	)
 	for rows.Next() {
-		var rowid, ref, size int64
-		if err := rows.Scan(&rowid, &ref, &size); err != nil {
+		var (
+			line evictedLine
+			size int64
+		)
+		if err := rows.Scan(&line.rowid, &line.ref, &size, &line.tsNS, &line.stream, &line.raw); err != nil {
 			rows.Close()
 			return 0, err
 		}
+		rowid, ref := line.rowid, line.ref
 		rowids = append(rowids, rowid)
+		if archive.Enabled {
+			evicted = append(evicted, line)
+		}
 		perRef[ref] += size
 		freed += size
 		if freed >= excess {
@@ -224,18 +249,17 @@ func (s *Store) evictOldest(ctx context.Context, refs []int64, excess int64) (in
 		return 0, nil
 	}
 
-	rowPlaceholders := strings.TrimSuffix(strings.Repeat("?,", len(rowids)), ",")
-	if _, err := tx.ExecContext(ctx,
-		"DELETE FROM log_lines WHERE rowid IN ("+rowPlaceholders+")", rowids...); err != nil {
-		return 0, err
-	}
-	for ref, size := range perRef {
-		if _, err := tx.ExecContext(ctx,
-			"UPDATE containers SET stored_bytes = max(0, stored_bytes - ?) WHERE id = ?", size, ref); err != nil {
-			return 0, err
+	if archive.Enabled {
+		// A span that cannot be archived is not evicted: the store stays over
+		// its cap until the cold tier is reachable again.
+		if err := s.archiveEvicted(ctx, tx, s.coldTarget(archive), evicted); err != nil {
+			return 0, fmt.Errorf("archive evicted lines: %w", err)
 		}
 	}
 
+	if err := deleteLines(ctx, tx, rowids, perRef); err != nil {
+		return 0, err
+	}
 	if err := tx.Commit(); err != nil {
 		return 0, err
 	}
@@ -248,15 +272,47 @@ func (s *Store) evictOldest(ctx context.Context, refs []int64, excess int64) (in
 	return freed, nil
 }
 
+// deleteLines deletes the given rows, their index entries, and their share of
+// each generation's stored_bytes (perRef) in tx.
+func deleteLines(ctx context.Context, tx *sql.Tx, rowids []any, perRef map[int64]int64) error {
+	rowPlaceholders := strings.TrimSuffix(strings.Repeat("?,", len(rowids)), ",")
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM log_lines WHERE rowid IN ("+rowPlaceholders+")", rowids...); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM log_fields WHERE line_rowid IN ("+rowPlaceholders+")", rowids...); err != nil {
+		return err
+	}
+	indexed, err := indexActive(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if indexed {
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM log_lines_fts WHERE rowid IN ("+rowPlaceholders+")", rowids...); err != nil {
+			return err
+		}
+	}
+	for ref, size := range perRef {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE containers SET stored_bytes = max(0, stored_bytes - ?) WHERE id = ?", size, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // pruneEmptyGenerations drops generation rows whose container is gone from the
 // engine and whose lines have all been evicted. A removed generation that
-// still has lines is kept — its history is part of the logical container's
-// timeline.
+// still has lines, stored or archived, is kept — its history is part of the
+// logical container's timeline.
 func (s *Store) pruneEmptyGenerations(ctx context.Context) error {
 	_, err := s.db.ExecContext(ctx, `
 		DELETE FROM containers
 		WHERE removed_ms IS NOT NULL
-		  AND NOT EXISTS (SELECT 1 FROM log_lines WHERE container_ref = containers.id)`)
+		  AND NOT EXISTS (SELECT 1 FROM log_lines WHERE container_ref = containers.id)
+		  AND NOT EXISTS (SELECT 1 FROM archive_segments WHERE container_ref = containers.id)`)
 	return err
 }
 
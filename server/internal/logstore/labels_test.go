@@ -0,0 +1,143 @@
+package logstore
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// dropV7Columns turns a current containers table back into a version 6 one.
+const dropV7Columns = "ALTER TABLE containers DROP COLUMN parser; " +
+	"ALTER TABLE containers DROP COLUMN multiline; " +
+	"ALTER TABLE containers DROP COLUMN retention_s; "
+
+// labelled records a generation the way sync does, with the given labels.
+func labelled(t *testing.T, s *Store, key genKey, name string, labels map[string]string) {
+	t.Helper()
+	info := containerInfo(key.host, key.id, name, baseTime)
+	info.Labels = labels
+	if _, err := s.upsertMeta(context.Background(), key, info, "", time.Now().UnixMilli()); err != nil {
+		t.Fatalf("upsertMeta: %v", err)
+	}
+}
+
+// TestRetentionLabelOverridesRules keeps an audit service's lines for the two
+// weeks its label asks for, while the rules keep everything else two days.
+func TestRetentionLabelOverridesRules(t *testing.T) {
+	limits := testLimits()
+	limits.RetentionRules = []config.RetentionRule{{MaxAgeHours: 48}}
+	store, err := Open(filepath.Join(t.TempDir(), "logs.db"), func() config.ResolvedLogStoreConfig { return limits })
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	labelled(t, store, genKey{"local", "aud"}, "audit", map[string]string{models.LabelRetention: "14d"})
+	writeEntries(t, store, genKey{"local", "aud"}, "audit",
+		entryAt(ago(20*24*time.Hour), "stdout", "INFO login 1"),
+		entryAt(ago(3*24*time.Hour), "stdout", "DEBUG login 2"),
+	)
+	labelled(t, store, genKey{"local", "web"}, "web", nil)
+	writeEntries(t, store, genKey{"local", "web"}, "web",
+		entryAt(ago(3*24*time.Hour), "stdout", "INFO request 1"),
+		entryAt(ago(time.Hour), "stdout", "INFO request 2"),
+	)
+
+	retain(t, store)
+
+	if got, want := storedMessages(t, store, "local", "audit"), []string{"DEBUG login 2"}; !slices.Equal(got, want) {
+		t.Fatalf("audit kept %q, want %q", got, want)
+	}
+	if got, want := storedMessages(t, store, "local", "web"), []string{"INFO request 2"}; !slices.Equal(got, want) {
+		t.Fatalf("web kept %q, want %q", got, want)
+	}
+
+	// The label needs no rules to take effect.
+	limits.RetentionRules = nil
+	if err := store.expire(context.Background(), nil, now.Add(12*24*time.Hour)); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if got := storedMessages(t, store, "local", "audit"); len(got) != 0 {
+		t.Fatalf("audit kept %q past its label's two weeks", got)
+	}
+}
+
+// TestFormatLabelsShapeStoredHistory reads a JSON-logging Java service back
+// from the store: the message comes from the line's msg key, and its stack
+// trace folds into the entry even though the trace lines classify as errors.
+func TestFormatLabelsShapeStoredHistory(t *testing.T) {
+	store := newTestStore(t)
+	key := genKey{"local", "pay"}
+	labelled(t, store, key, "payments", map[string]string{
+		models.LabelParser:    "json",
+		models.LabelMultiline: "java",
+	})
+	writeEntries(t, store, key, "payments",
+		entryAt(baseTime, "stdout", `{"level":"error","msg":"charge failed","order":7}`),
+		entryAt(baseTime.Add(time.Millisecond), "stdout", "java.lang.IllegalStateException: card declined"),
+		entryAt(baseTime.Add(2*time.Millisecond), "stdout", "Caused by: java.io.IOException: gateway failed"),
+		entryAt(baseTime.Add(time.Second), "stdout", `{"level":"info","msg":"charge ok","order":8}`),
+	)
+
+	page, err := store.Query(context.Background(), LogQuery{Host: "local", Container: "payments", Filter: "order=7"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(page.Entries) != 1 {
+		t.Fatalf("order=7 matched %d entries, want 1: %+v", len(page.Entries), page.Entries)
+	}
+	entry := page.Entries[0]
+	want := "charge failed\njava.lang.IllegalStateException: card declined\nCaused by: java.io.IOException: gateway failed"
+	if entry.Message != want || entry.Level != models.LogLevelError || entry.Fields["order"] != "7" {
+		t.Fatalf("entry = %+v, want the parsed message with its trace folded in", entry)
+	}
+}
+
+func TestStoreLabelExcludesAContainer(t *testing.T) {
+	filter := compileIngestion(nil).forContainer("local", "scratch", map[string]string{models.LabelStore: "false"})
+	if !filter.excluded || filter.reason != "excluded by label logdeck.store=false" {
+		t.Fatalf("filter = %+v, want the container excluded by its label", filter)
+	}
+	if kept := compileIngestion(nil).forContainer("local", "web", map[string]string{models.LabelStore: "true"}); kept.excluded {
+		t.Fatalf("logdeck.store=true excluded the container: %+v", kept)
+	}
+}
+
+func TestMigrationFromV6AddsTheLabelColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+	store, err := Open(path, testLimits)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	writeEntries(t, store, genKey{"local", "aaa"}, "web", entryAt(baseTime, "stdout", "kept"))
+	if _, err := store.db.Exec(dropV7Columns + "PRAGMA user_version = 6"); err != nil {
+		t.Fatalf("write a v6 database: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := Open(path, testLimits)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if got := storedMessages(t, reopened, "local", "web"); !slices.Equal(got, []string{"kept"}) {
+		t.Fatalf("migrated history = %q, want the line kept", got)
+	}
+	labelled(t, reopened, genKey{"local", "aaa"}, "web", map[string]string{models.LabelRetention: "36h"})
+	gens, err := reopened.allGenerations(context.Background())
+	if err != nil {
+		t.Fatalf("allGenerations: %v", err)
+	}
+	if len(gens) != 1 || gens[0].maxAge != 36*time.Hour {
+		t.Fatalf("generations = %+v, want web kept for 36h", gens)
+	}
+}
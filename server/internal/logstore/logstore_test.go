@@ -1451,7 +1451,7 @@ func TestEmptyNameNeverBlanksTheStoredName(t *testing.T) {
 
 	// The lifecycle path: a listing with no names and no image.
 	unnamed := models.ContainerInfo{ID: "aaa", Host: "local", Created: baseTime.Unix()}
-	if _, err := store.upsertMeta(ctx, key, unnamed, time.Now().UnixMilli()); err != nil {
+	if _, err := store.upsertMeta(ctx, key, unnamed, "", time.Now().UnixMilli()); err != nil {
 		t.Fatalf("upsertMeta: %v", err)
 	}
 
@@ -1623,3 +1623,192 @@ func TestRetentionHonorsCapLoweredAtRuntime(t *testing.T) {
 		t.Fatal("the oldest line survived; eviction must be oldest-first")
 	}
 }
+
+// writeProjectEntries is writeEntries for a generation that belongs to a
+// compose project.
+func writeProjectEntries(t *testing.T, s *Store, key genKey, name, project string, entries ...models.LogEntry) {
+	t.Helper()
+	batch := make([]ingestMsg, 0, len(entries))
+	for _, entry := range entries {
+		batch = append(batch, ingestMsg{
+			kind:    msgLine,
+			key:     key,
+			name:    name,
+			project: project,
+			line:    lineFromEntry(entry),
+		})
+	}
+	if err := s.commit(batch, map[genKey]int64{}); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+}
+
+func TestStoreWideQueryMergesContainersByTimestamp(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	writeProjectEntries(t, store, genKey{"local", "aaa"}, "api", "shop",
+		entryAt(baseTime, "stdout", "api started"),
+		entryAt(baseTime.Add(2*time.Second), "stdout", "api handled req-42"),
+	)
+	writeProjectEntries(t, store, genKey{"local", "bbb"}, "worker", "shop",
+		entryAt(baseTime.Add(time.Second), "stdout", "worker started"),
+		entryAt(baseTime.Add(3*time.Second), "stdout", "worker finished req-42"),
+	)
+	writeProjectEntries(t, store, genKey{"remote", "ccc"}, "api", "",
+		entryAt(baseTime.Add(4*time.Second), "stdout", "remote api handled req-42"),
+	)
+
+	for _, tt := range []struct {
+		name  string
+		query LogQuery
+		want  []string
+	}{
+		{
+			name:  "every container",
+			query: LogQuery{Search: "req-42"},
+			want:  []string{"api handled req-42", "worker finished req-42", "remote api handled req-42"},
+		},
+		{
+			name:  "by project",
+			query: LogQuery{Project: "shop"},
+			want:  []string{"api started", "worker started", "api handled req-42", "worker finished req-42"},
+		},
+		{
+			name:  "by host",
+			query: LogQuery{Host: "remote"},
+			want:  []string{"remote api handled req-42"},
+		},
+		{
+			name:  "by names",
+			query: LogQuery{Containers: []string{"worker", " ", "worker"}},
+			want:  []string{"worker started", "worker finished req-42"},
+		},
+		{
+			name:  "by names on one host",
+			query: LogQuery{Host: "local", Containers: []string{"api"}},
+			want:  []string{"api started", "api handled req-42"},
+		},
+		{
+			name:  "unknown project",
+			query: LogQuery{Project: "nope"},
+			want:  []string{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := store.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if got := messages(page.Entries); !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	page, err := store.Query(ctx, LogQuery{Search: "remote"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Host != "remote" || page.Entries[0].ContainerName != "api" {
+		t.Fatalf("a store-wide entry must say where it came from, got %+v", page.Entries)
+	}
+}
+
+// TestStoreWideQueryKeepsInterleavedEntriesWhole merges two containers whose
+// stack traces interleave: each trace must still come back as one entry, on a
+// single page and when a page boundary falls between a trace and its frame.
+func TestStoreWideQueryKeepsInterleavedEntriesWhole(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	writeProjectEntries(t, store, genKey{"local", "aaa"}, "api", "shop",
+		entryAt(baseTime, "stderr", "level=error api failed"),
+		entryAt(baseTime.Add(2*time.Second), "stderr", "at com.shop.Api.handle(Api.java:10)"),
+		entryAt(baseTime.Add(4*time.Second), "stdout", "level=info api recovered"),
+	)
+	writeProjectEntries(t, store, genKey{"local", "bbb"}, "worker", "shop",
+		entryAt(baseTime.Add(time.Second), "stderr", "level=error worker failed"),
+		entryAt(baseTime.Add(3*time.Second), "stderr", "at com.shop.Worker.poll(Worker.java:30)"),
+		entryAt(baseTime.Add(5*time.Second), "stdout", "level=info worker recovered"),
+	)
+
+	want := []string{
+		"level=error api failed\nat com.shop.Api.handle(Api.java:10)",
+		"level=error worker failed\nat com.shop.Worker.poll(Worker.java:30)",
+		"level=info api recovered",
+		"level=info worker recovered",
+	}
+
+	page, err := store.Query(ctx, LogQuery{Project: "shop"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if got := messages(page.Entries); !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// One entry per page puts chunk and page boundaries inside the traces.
+	var seen []string
+	cursor := ""
+	for range 10 {
+		page, err := store.Query(ctx, LogQuery{Project: "shop", Limit: 1, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		seen = append(messages(page.Entries), seen...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if !slices.Equal(seen, want) {
+		t.Fatalf("paged: got %q, want %q", seen, want)
+	}
+}
+
+// TestStoreWideQueryPagesAcrossManyGenerations drives the per-generation merge:
+// more generations than mergeRefsAbove, interleaved in time, paged with the
+// same cursor scheme as a single container.
+func TestStoreWideQueryPagesAcrossManyGenerations(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	const (
+		containers = mergeRefsAbove + 4
+		perEach    = 10
+		total      = containers * perEach
+	)
+	for c := range containers {
+		entries := make([]models.LogEntry, 0, perEach)
+		for i := range perEach {
+			// Line n of the merged timeline lands at second n.
+			n := i*containers + c
+			entries = append(entries, entryAt(baseTime.Add(time.Duration(n)*time.Second), "stdout", fmt.Sprintf("line %03d", n)))
+		}
+		writeEntries(t, store, genKey{"local", fmt.Sprintf("id-%02d", c)}, fmt.Sprintf("svc-%02d", c), entries...)
+	}
+
+	seen := make([]string, 0, total)
+	cursor := ""
+	for page := 0; page < 20; page++ {
+		got, err := store.Query(ctx, LogQuery{Limit: 25, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		seen = append(messages(got.Entries), seen...)
+		if got.NextCursor == "" {
+			break
+		}
+		cursor = got.NextCursor
+	}
+
+	if len(seen) != total {
+		t.Fatalf("paginated over %d entries, want %d", len(seen), total)
+	}
+	for n := range total {
+		if want := fmt.Sprintf("line %03d", n); seen[n] != want {
+			t.Fatalf("entry %d = %q, want %q (the merge lost, duplicated, or misordered a line)", n, seen[n], want)
+		}
+	}
+}
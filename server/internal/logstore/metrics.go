@@ -0,0 +1,75 @@
+package logstore
+
+import (
+	"sort"
+	"sync"
+)
+
+// LineCount is how many lines of one level the store has committed for one
+// container since it started. Like Committed it only grows: retention and
+// purges do not take lines back out of it.
+type LineCount struct {
+	Host      string
+	Container string
+	Project   string
+	Level     string
+	Lines     int64
+}
+
+type lineCountKey struct {
+	host, name, project string
+	level               int
+}
+
+// lineCounts accumulates LineCounts. The writer adds to it after each commit;
+// scrapes read it from other goroutines.
+type lineCounts struct {
+	mu     sync.Mutex
+	counts map[lineCountKey]int64
+}
+
+func (c *lineCounts) add(batch map[lineCountKey]int64) {
+	if len(batch) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[lineCountKey]int64)
+	}
+	for key, n := range batch {
+		c.counts[key] += n
+	}
+}
+
+// LineCounts reports the lines committed per container and level, ordered by
+// host, container, and level name.
+func (s *Store) LineCounts() []LineCount {
+	s.lines.mu.Lock()
+	counts := make([]LineCount, 0, len(s.lines.counts))
+	for key, n := range s.lines.counts {
+		counts = append(counts, LineCount{
+			Host:      key.host,
+			Container: key.name,
+			Project:   key.project,
+			Level:     statsLevels[key.level],
+			Lines:     n,
+		})
+	}
+	s.lines.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i], counts[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if a.Container != b.Container {
+			return a.Container < b.Container
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Level < b.Level
+	})
+	return counts
+}
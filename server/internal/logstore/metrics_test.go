@@ -0,0 +1,32 @@
+package logstore
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestLineCountsCountCommittedLinesPerLevel counts each stored line once, by
+// its own level; a duplicate the writer skips is not counted again.
+func TestLineCountsCountCommittedLinesPerLevel(t *testing.T) {
+	store := newTestStore(t)
+	writeEntries(t, store, genKey{"local", "aaa"}, "web",
+		entryAt(baseTime, "stdout", "INFO request 1"),
+		entryAt(baseTime.Add(time.Millisecond), "stdout", "INFO request 2"),
+		entryAt(baseTime.Add(2*time.Millisecond), "stderr", "ERROR upstream failed"),
+	)
+	writeEntries(t, store, genKey{"local", "aaa"}, "web", entryAt(baseTime, "stdout", "INFO request 1"))
+	writeEntries(t, store, genKey{"edge", "bbb"}, "db", entryAt(baseTime, "stdout", "checkpoint done"))
+
+	want := []LineCount{
+		{Host: "edge", Container: "db", Level: "UNKNOWN", Lines: 1},
+		{Host: "local", Container: "web", Level: "ERROR", Lines: 1},
+		{Host: "local", Container: "web", Level: "INFO", Lines: 2},
+	}
+	if got := store.LineCounts(); !slices.Equal(got, want) {
+		t.Fatalf("LineCounts = %+v, want %+v", got, want)
+	}
+	if got := store.Committed(); got != 4 {
+		t.Fatalf("Committed = %d, want 4", got)
+	}
+}
@@ -3,6 +3,7 @@ package logstore
 import (
 	"context"
 	"errors"
+	"log"
 	"os"
 )
 
@@ -11,7 +12,8 @@ import (
 var ErrContainerNotFound = errors.New("container not found")
 
 // DeleteContainer removes every generation of the logical container (host, name)
-// and all of its stored lines. Returns the number of lines deleted.
+// and all of its stored lines, archived ones included. Returns the number of
+// lines deleted.
 //
 // host is required: the same name can exist on several hosts and each one owns
 // its own history. The database file is not VACUUMed — like the janitor's
@@ -57,8 +59,27 @@ func (s *Store) DeleteContainer(ctx context.Context, host, name string) (int64,
 		return 0, ErrContainerNotFound
 	}
 
-	// Lines first: log_lines.container_ref references containers(id).
+	// The indexes are keyed by log_lines rowid, so they are cleared while the
+	// rows they point at can still be found.
 	placeholders, args := refArgs(refs)
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM log_fields WHERE line_rowid IN (SELECT rowid FROM log_lines WHERE container_ref IN ("+placeholders+"))",
+		args...); err != nil {
+		return 0, err
+	}
+	indexed, err := indexActive(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	if indexed {
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM log_lines_fts WHERE rowid IN (SELECT rowid FROM log_lines WHERE container_ref IN ("+placeholders+"))",
+			args...); err != nil {
+			return 0, err
+		}
+	}
+
+	// Lines next: log_lines.container_ref references containers(id).
 	result, err := tx.ExecContext(ctx,
 		"DELETE FROM log_lines WHERE container_ref IN ("+placeholders+")", args...)
 	if err != nil {
@@ -68,6 +89,41 @@ func (s *Store) DeleteContainer(ctx context.Context, host, name string) (int64,
 	if err != nil {
 		return 0, err
 	}
+
+	// Archived segments go with the generations that own them. Their files are
+	// removed once the deletion has committed: until then the rows still name
+	// them.
+	segments, err := tx.QueryContext(ctx,
+		"SELECT id, lines, location FROM archive_segments WHERE container_ref IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		segmentIDs []int64
+		locations  []string
+	)
+	for segments.Next() {
+		var (
+			id, lines int64
+			location  string
+		)
+		if err := segments.Scan(&id, &lines, &location); err != nil {
+			segments.Close()
+			return 0, err
+		}
+		segmentIDs = append(segmentIDs, id)
+		locations = append(locations, location)
+		deleted += lines
+	}
+	segments.Close()
+	if err := segments.Err(); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM archive_segments WHERE container_ref IN ("+placeholders+")", args...); err != nil {
+		return 0, err
+	}
+
 	if _, err := tx.ExecContext(ctx,
 		"DELETE FROM containers WHERE id IN ("+placeholders+")", args...); err != nil {
 		return 0, err
@@ -84,6 +140,9 @@ func (s *Store) DeleteContainer(ctx context.Context, host, name string) (int64,
 	if err := tx.Commit(); err != nil {
 		return 0, err
 	}
+	if err := s.removeSegments(ctx, segmentIDs, locations); err != nil {
+		log.Printf("logstore: purging %s/%s left archived segments behind: %v", host, name, err)
+	}
 	return deleted, nil
 }
 
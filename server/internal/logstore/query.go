@@ -1,7 +1,9 @@
 package logstore
 
 import (
+	"container/heap"
 	"context"
+	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -24,12 +26,26 @@ const (
 	// backwards until it holds a full page or history runs out; a page is never
 	// both empty and continuable.
 	scanChunk = 1000
+	// indexedChunk replaces scanChunk for a filter an index answers.
+	// Each round starts at a match, so a small chunk wastes little; a large one
+	// would read and parse rows the next jump skips anyway.
+	indexedChunk = 100
+	// mergeRefsAbove is the generation count past which a chunk is read one
+	// generation at a time and merged in Go. A store-wide query can span hundreds
+	// of generations, and a single IN (...) ORDER BY ts_ns over all of them makes
+	// SQLite sort every row in the window for every chunk; one index range per
+	// generation reads only the rows the chunk can actually use.
+	mergeRefsAbove = 8
 )
 
 // ErrInvalidCursor is returned when LogQuery.Cursor is not a cursor this store
 // produced.
 var ErrInvalidCursor = errors.New("invalid cursor")
 
+// ErrInvalidFilter wraps the parse error of a LogQuery.Filter that is not a
+// valid field filter.
+var ErrInvalidFilter = errors.New("invalid filter")
+
 // StoredContainer is one logical container (host, name) in the store —
 // every generation of that name collapsed into a single entry, including
 // containers the engine no longer knows about.
@@ -44,21 +60,31 @@ type StoredContainer struct {
 	Removed        bool      `json:"removed"`
 	Excluded       bool      `json:"excluded"`
 	ExcludedReason string    `json:"excludedReason,omitempty"`
+	// Filtered reports that ingestion rules exclude the container or skip
+	// some of its lines; FilteredReason says which rules and how.
+	Filtered       bool   `json:"filtered"`
+	FilteredReason string `json:"filteredReason,omitempty"`
 }
 
-// LogQuery selects stored lines for one logical container. Host is optional
-// (empty matches the name on any host); Since/Until, Levels, and Search are
-// all optional filters.
+// LogQuery selects stored lines. With Container set it reads one logical
+// container, and Host is optional (empty matches the name on any host). With
+// Container empty the query is store-wide: every stored container matching
+// Host, Project, and Containers (each optional, and combined with AND) is read
+// as one timeline merged by timestamp. Since/Until, Levels, Search, and Filter
+// are all optional filters in either mode.
 type LogQuery struct {
-	Host      string
-	Container string // logical container name
-	Since     time.Time
-	Until     time.Time
-	Levels    []string // level names, e.g. "ERROR"; empty = all levels
-	Search    string
-	Regex     bool // Search is an RE2 pattern rather than a substring
-	Limit     int  // clamped to [1, MaxQueryLimit]; 0 means DefaultQueryLimit
-	Cursor    string
+	Host       string
+	Container  string   // logical container name
+	Containers []string // store-wide: only these logical container names
+	Project    string   // store-wide: only containers of this compose project
+	Since      time.Time
+	Until      time.Time
+	Levels     []string // level names, e.g. "ERROR"; empty = all levels
+	Search     string
+	Regex      bool   // Search is an RE2 pattern rather than a substring
+	Filter     string // field predicates, e.g. `status>=500 AND path~"/api"`; see models.ParseFieldFilter
+	Limit      int    // clamped to [1, MaxQueryLimit]; 0 means DefaultQueryLimit
+	Cursor     string
 }
 
 // LogPage is one page of stored lines. Entries are ascending by timestamp;
@@ -80,7 +106,7 @@ func (s *Store) ListContainers(ctx context.Context) ([]StoredContainer, error) {
 
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, host, container_id, name, compose_project, image,
-		       first_seen_ms, removed_ms, stored_bytes, excluded_reason
+		       first_seen_ms, removed_ms, stored_bytes, excluded_reason, filtered_reason
 		FROM containers`)
 	if err != nil {
 		return nil, err
@@ -106,9 +132,10 @@ func (s *Store) ListContainers(ctx context.Context) ([]StoredContainer, error) {
 			removedMS   *int64
 			storedBytes int64
 			reason      string
+			filtered    string
 		)
 		if err := rows.Scan(&ref, &host, &containerID, &name, &project, &image,
-			&firstSeenMS, &removedMS, &storedBytes, &reason); err != nil {
+			&firstSeenMS, &removedMS, &storedBytes, &reason, &filtered); err != nil {
 			return nil, err
 		}
 
@@ -140,6 +167,8 @@ func (s *Store) ListContainers(ctx context.Context) ([]StoredContainer, error) {
 			entry.container.Removed = removedMS != nil
 			entry.container.Excluded = reason != ""
 			entry.container.ExcludedReason = reason
+			entry.container.Filtered = filtered != ""
+			entry.container.FilteredReason = filtered
 		}
 	}
 	if err := rows.Err(); err != nil {
@@ -164,10 +193,15 @@ type lineSpan struct {
 	newest time.Time
 }
 
-// lineSpans reports the stored time range of every generation that has lines.
+// lineSpans reports the time range of every generation that has lines, stored
+// or archived: both are part of the history a query can reach.
 func (s *Store) lineSpans(ctx context.Context) (map[int64]lineSpan, error) {
-	rows, err := s.db.QueryContext(ctx,
-		"SELECT container_ref, MIN(ts_ns), MAX(ts_ns) FROM log_lines GROUP BY container_ref")
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT container_ref, MIN(oldest), MAX(newest) FROM (
+		  SELECT container_ref, MIN(ts_ns) AS oldest, MAX(ts_ns) AS newest FROM log_lines GROUP BY container_ref
+		  UNION ALL
+		  SELECT container_ref, MIN(first_ts_ns), MAX(last_ts_ns) FROM archive_segments GROUP BY container_ref
+		) GROUP BY container_ref`)
 	if err != nil {
 		return nil, err
 	}
@@ -189,20 +223,46 @@ func (s *Store) lineSpans(ctx context.Context) (map[int64]lineSpan, error) {
 
 // generation is one stored container generation resolved for a query.
 type generation struct {
-	ref  int64
-	id   string
-	name string
+	ref     int64
+	host    string
+	id      string
+	name    string
+	project string
+	image   string
+	format  models.LineFormat // from its logdeck.parser and logdeck.multiline labels
+	maxAge  time.Duration     // from its logdeck.retention label; 0 if unset
 }
 
-// Query returns one page of stored lines for a logical container. Every
-// generation of the name is read as one timeline, which is what makes history
-// survive a container rebuild: the caller asks for "web" and gets the lines of
-// every engine container that has ever been called "web" on that host.
+// generationColumns are the containers columns scanGeneration reads.
+const generationColumns = "id, host, container_id, name, compose_project, image, parser, multiline, retention_s"
+
+// scanGeneration reads one generation row selected with generationColumns.
+func scanGeneration(rows *sql.Rows) (generation, error) {
+	var (
+		gen        generation
+		retentionS int64
+	)
+	err := rows.Scan(&gen.ref, &gen.host, &gen.id, &gen.name, &gen.project, &gen.image,
+		&gen.format.Parser, &gen.format.Multiline, &retentionS)
+	gen.maxAge = time.Duration(retentionS) * time.Second
+	return gen, err
+}
+
+// Query returns one page of stored lines for a logical container, or for every
+// container a store-wide query selects. Every generation of a name is read as
+// one timeline, which is what makes history survive a container rebuild: the
+// caller asks for "web" and gets the lines of every engine container that has
+// ever been called "web" on that host. A store-wide query is the same walk over
+// more generations, so its pages and cursors work exactly like a single
+// container's. Lines retention has archived (see coldtier.go) are part of the
+// same walk: a cursor that passes the oldest stored row continues into them.
 //
 // Rows are read unfiltered and only then grouped and filtered, which is the
-// order the live path uses. Filtering rows in SQL first would delete the
-// continuation lines of every multi-line entry — they classify as UNKNOWN — and
-// a level-filtered stack trace would come back as its first line with no body.
+// order the live path uses; the full-text and field indexes, when on, only
+// decide which rows a filtered query can skip. Filtering rows in SQL first
+// would delete the continuation lines of every multi-line entry — they
+// classify as UNKNOWN — and a level-filtered stack trace would come back as its
+// first line with no body.
 func (s *Store) Query(ctx context.Context, q LogQuery) (LogPage, error) {
 	limit := q.Limit
 	if limit <= 0 {
@@ -215,7 +275,7 @@ func (s *Store) Query(ctx context.Context, q LogQuery) (LogPage, error) {
 		return LogPage{}, err
 	}
 
-	generations, err := s.generations(ctx, q.Host, q.Container)
+	generations, err := s.generations(ctx, q)
 	if err != nil {
 		return LogPage{}, err
 	}
@@ -233,33 +293,79 @@ func (s *Store) Query(ctx context.Context, q LogQuery) (LogPage, error) {
 	var (
 		from   cursorPos
 		hasPos bool
+		// before is where the previous page's oldest entry began: this page
+		// holds only entries older than it. from may be newer, when an older
+		// entry's continuation lines interleave that page's.
+		before    cursorPos
+		hasBefore bool
 	)
 	if q.Cursor != "" {
-		tsNS, rowid, err := decodeCursor(q.Cursor)
+		before, from, err = decodeCursor(q.Cursor)
 		if err != nil {
 			return LogPage{}, err
 		}
-		from, hasPos = cursorPos{tsNS: tsNS, rowid: rowid}, true
+		hasPos, hasBefore = true, true
 	}
 
 	// One extra entry beyond the page is what proves an older page exists, and
 	// one more row covers the entry held back at the chunk boundary below, so an
 	// unfiltered query still settles in a single round.
 	chunk := limit + 2
-	if match.active() {
+	// With an index a filtered query need not read every row: the index shows
+	// where the next match may lie, and the scan skips straight to it, so a
+	// chunk only has to reach a little past the row it lands on.
+	lookup := s.indexLookup(match)
+	switch {
+	case lookup != nil:
+		chunk = max(chunk, indexedChunk)
+	case match.active():
 		chunk = max(chunk, scanChunk)
 	}
 
 	var (
 		entries []models.LogEntry // matched, oldest-first
-		anchors []cursorPos
-		// carry holds the rows of the oldest entry of the previous round. That
-		// entry can still grow — its parent line may be one row older than the
-		// chunk reached — so it is regrouped with the next, older chunk instead of
+		spans   []entrySpan
+		// carry holds the rows of the oldest entries of the previous round. Those
+		// can still grow — a parent line may be one row older than the chunk
+		// reached — so they are regrouped with the next, older chunk instead of
 		// being filtered while incomplete.
 		carry []storedRow
 	)
+	// The indexes cover only the database. Archived rows all sit below
+	// coldFloor, so the index may skip ahead only down to it; past it the rows
+	// are scanned.
+	var (
+		coldFloor cursorPos
+		hasCold   bool
+	)
+	if lookup != nil {
+		coldFloor, hasCold, err = s.coldFloor(ctx, refs)
+		if err != nil {
+			return LogPage{}, err
+		}
+	}
 	for {
+		// A carried entry the index would select may be about to match, so the
+		// scan just carries on past it.
+		if lookup != nil && !lookup.anyCandidate(carry) && (!hasCold || !hasPos || newerThan(from, coldFloor)) {
+			resume, skip, found, err := s.seekMatch(ctx, refs, q, lookup, from, hasPos, byRef)
+			if err != nil {
+				return LogPage{}, err
+			}
+			switch {
+			case hasCold && (!found || skip && !newerThan(resume, coldFloor)):
+				// No indexed match is left above the archive; scan on from it.
+				from, hasPos, carry = coldFloor, true, nil
+			case !found:
+				// Nothing older can match, the carried entry included.
+				return newPage(entries, spans, nil, limit), nil
+			case skip:
+				// The index selects none of the skipped rows, and so none of the
+				// carried entry's.
+				from, hasPos, carry = resume, true, nil
+			}
+		}
+
 		rows, err := s.scanRows(ctx, refs, q, from, hasPos, chunk, byRef)
 		if err != nil {
 			return LogPage{}, err
@@ -283,23 +389,31 @@ func (s *Store) Query(ctx context.Context, q LogQuery) (LogPage, error) {
 		// Each round groups only the rows it just read (plus the carried entry),
 		// so a filter that matches nothing for many rounds costs one grouping per
 		// row rather than one per row per round.
-		grouped, groupAnchors, open := groupRows(combined)
+		grouped, groupSpans, open, held := groupRows(combined, chunk)
 		carry = nil
 		if !exhausted && len(grouped) > 0 {
 			carry = combined[open:]
-			grouped, groupAnchors = grouped[1:], groupAnchors[1:]
+			grouped, groupSpans = grouped[held:], groupSpans[held:]
+		}
+		if hasBefore {
+			// Entries are oldest-first; those the previous page began are last.
+			shown := len(grouped)
+			for shown > 0 && !olderThan(groupSpans[shown-1].first, before) {
+				shown--
+			}
+			grouped, groupSpans = grouped[:shown], groupSpans[:shown]
 		}
 
-		matched, matchedAnchors := match.filter(grouped, groupAnchors)
+		matched, matchedSpans := match.filter(grouped, groupSpans)
 		// This round read strictly older rows than the last, and pages are
 		// oldest-first.
 		entries = append(matched, entries...)
-		anchors = append(matchedAnchors, anchors...)
+		spans = append(matchedSpans, spans...)
 
 		// Stop once the page is provably full, or once history runs out — never
 		// hand back a page that is empty but still carries a cursor.
 		if len(entries) > limit || exhausted {
-			return newPage(entries, anchors, limit), nil
+			return newPage(entries, spans, carry, limit), nil
 		}
 	}
 }
@@ -307,8 +421,11 @@ func (s *Store) Query(ctx context.Context, q LogQuery) (LogPage, error) {
 // newPage keeps the newest limit entries of the scanned window. Pages walk
 // backwards through history, so an entry older than the page is not truncated
 // data: it is the proof that another page exists, and its position is the
-// cursor the next page resumes from.
-func newPage(entries []models.LogEntry, anchors []cursorPos, limit int) LogPage {
+// cursor the next page resumes from. When several containers interleave, an
+// older entry can run on past that position, so the cursor also says how far up
+// the next page must read to see it whole: the newest row of any older entry,
+// or of the carried rows still waiting for their parent.
+func newPage(entries []models.LogEntry, spans []entrySpan, carry []storedRow, limit int) LogPage {
 	if len(entries) <= limit {
 		if entries == nil {
 			entries = []models.LogEntry{}
@@ -316,9 +433,27 @@ func newPage(entries []models.LogEntry, anchors []cursorPos, limit int) LogPage
 		return LogPage{Entries: entries}
 	}
 	cut := len(entries) - limit
+	before := spans[cut].first
+	reach := before
+	for _, span := range spans[:cut] {
+		if newerThan(span.last, reach) {
+			reach = span.last
+		}
+	}
+	for _, row := range carry {
+		if newerThan(row.pos, reach) {
+			reach = row.pos
+		}
+	}
+	// Reads are strictly older than from, so it sits just above the row it
+	// must reach.
+	from := before
+	if reach != before {
+		from = cursorPos{tsNS: reach.tsNS, rowid: reach.rowid + 1}
+	}
 	return LogPage{
 		Entries:    entries[cut:],
-		NextCursor: encodeCursor(anchors[cut].tsNS, anchors[cut].rowid),
+		NextCursor: encodeCursor(before, from),
 	}
 }
 
@@ -328,54 +463,246 @@ type cursorPos struct {
 	rowid int64
 }
 
+// entrySpan is where a grouped entry's first and newest rows sit.
+type entrySpan struct {
+	first cursorPos
+	last  cursorPos
+}
+
 // storedRow is one scanned row: the entry it parses to and where it sits.
 type storedRow struct {
-	entry models.LogEntry
-	pos   cursorPos
+	entry  models.LogEntry
+	pos    cursorPos
+	format models.LineFormat // its generation's, for grouping
 }
 
-// scanRows reads one chunk of rows, newest-first, strictly older than from.
+// scanRows reads one chunk of rows, newest-first, strictly older than from. Once
+// anything has been archived, the chunk also draws on the cold tier: archived
+// rows are merged in by position, so a cursor walks from the database into the
+// archive (and through any overlap between them) without a seam.
 func (s *Store) scanRows(ctx context.Context, refs []int64, q LogQuery, from cursorPos, hasPos bool, chunk int, byRef map[int64]generation) ([]storedRow, error) {
-	statement, args := buildSelect(refs, q, from, hasPos, chunk)
+	rows, err := s.readMergedRaw(ctx, refs, chunk, newerThan, func(refs []int64) (string, []any) {
+		return buildSelect(refs, q, from, hasPos, chunk)
+	})
+	if err != nil || !s.archived.Load() {
+		return rebuildRows(rows, byRef), err
+	}
+
+	// A full chunk already holds chunk rows newer than anything below its oldest
+	// one, so only archived rows at least that new can make the cut.
+	floor, hasFloor := int64(0), len(rows) == chunk
+	if hasFloor {
+		floor = rows[len(rows)-1].pos.tsNS
+	}
+	cold, err := s.coldRows(ctx, refs, q, from, hasPos, floor, hasFloor, chunk)
+	if err != nil {
+		return nil, err
+	}
+	if len(cold) > 0 {
+		rows = append(rows, cold...)
+		sort.Slice(rows, func(i, j int) bool { return newerThan(rows[i].pos, rows[j].pos) })
+		if len(rows) > chunk {
+			rows = rows[:chunk]
+		}
+	}
+	return rebuildRows(rows, byRef), nil
+}
+
+// readMerged runs one keyset read of at most limit rows over the given
+// generations. Past mergeRefsAbove generations the read is assembled from one
+// statement per generation instead; see mergeRefsAbove. before must order rows
+// the way the statement does.
+func (s *Store) readMerged(ctx context.Context, refs []int64, limit int, before func(a, b cursorPos) bool, build func(refs []int64) (string, []any), byRef map[int64]generation) ([]storedRow, error) {
+	rows, err := s.readMergedRaw(ctx, refs, limit, before, build)
+	return rebuildRows(rows, byRef), err
+}
+
+// readMergedRaw is readMerged without the parse, for callers that want the
+// stored lines themselves.
+func (s *Store) readMergedRaw(ctx context.Context, refs []int64, limit int, before func(a, b cursorPos) bool, build func(refs []int64) (string, []any)) ([]rawRow, error) {
+	if len(refs) <= mergeRefsAbove {
+		statement, args := build(refs)
+		return s.readRows(ctx, statement, args, limit)
+	}
+
+	// One cursor per generation, all on one connection so they read the same
+	// snapshot, merged a row at a time: SQLite steps each index range only as
+	// far as the merge consumes it, so the read touches about limit rows plus
+	// one per generation rather than limit per generation.
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
 
+	heads := &mergeHeads{before: before}
+	defer heads.close()
+	for _, ref := range refs {
+		statement, args := build([]int64{ref})
+		rows, err := conn.QueryContext(ctx, statement, args...)
+		if err != nil {
+			return nil, err
+		}
+		if err := heads.advance(mergeCursor{rows: rows}); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := make([]rawRow, 0, limit)
+	for len(merged) < limit && heads.Len() > 0 {
+		cursor := heap.Pop(heads).(mergeCursor)
+		merged = append(merged, cursor.head)
+		if err := heads.advance(cursor); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// mergeCursor is one generation's open read and the row it is positioned on.
+type mergeCursor struct {
+	rows *sql.Rows
+	head rawRow
+}
+
+// mergeHeads is a heap of open cursors ordered by their current rows, the
+// first by before on top. Exhausted cursors are closed and dropped.
+type mergeHeads struct {
+	cursors []mergeCursor
+	before  func(a, b cursorPos) bool
+}
+
+func (h *mergeHeads) Len() int { return len(h.cursors) }
+func (h *mergeHeads) Less(i, j int) bool {
+	return h.before(h.cursors[i].head.pos, h.cursors[j].head.pos)
+}
+func (h *mergeHeads) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+func (h *mergeHeads) Push(x any)    { h.cursors = append(h.cursors, x.(mergeCursor)) }
+func (h *mergeHeads) Pop() any {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
+
+// advance steps cursor to its next row and pushes it back, or closes it once
+// its read is done.
+func (h *mergeHeads) advance(cursor mergeCursor) error {
+	if !cursor.rows.Next() {
+		if err := cursor.rows.Err(); err != nil {
+			cursor.rows.Close()
+			return err
+		}
+		return cursor.rows.Close()
+	}
+	if err := scanRawRow(cursor.rows, &cursor.head); err != nil {
+		cursor.rows.Close()
+		return err
+	}
+	heap.Push(h, cursor)
+	return nil
+}
+
+// close closes every cursor still open.
+func (h *mergeHeads) close() {
+	for _, cursor := range h.cursors {
+		cursor.rows.Close()
+	}
+	h.cursors = nil
+}
+
+// newerThan orders keyset positions the way buildSelect does: ts_ns DESC, rowid
+// DESC.
+func newerThan(a, b cursorPos) bool {
+	if a.tsNS != b.tsNS {
+		return a.tsNS > b.tsNS
+	}
+	return a.rowid > b.rowid
+}
+
+// olderThan is the reverse order, the one buildWalk reads in.
+func olderThan(a, b cursorPos) bool {
+	return newerThan(b, a)
+}
+
+// rawRow is one row as read, before its line is parsed.
+type rawRow struct {
+	pos    cursorPos
+	ref    int64
+	stream int
+	raw    string
+}
+
+// readRows runs one statement selecting (rowid, container_ref, ts_ns, stream,
+// raw). sizeHint only presizes the result.
+func (s *Store) readRows(ctx context.Context, statement string, args []any, sizeHint int) ([]rawRow, error) {
 	rows, err := s.db.QueryContext(ctx, statement, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	scanned := make([]storedRow, 0, chunk)
+	scanned := make([]rawRow, 0, sizeHint)
 	for rows.Next() {
-		var (
-			rowid  int64
-			ref    int64
-			tsNS   int64
-			stream int
-			raw    string
-		)
-		if err := rows.Scan(&rowid, &ref, &tsNS, &stream, &raw); err != nil {
+		var row rawRow
+		if err := scanRawRow(rows, &row); err != nil {
 			return nil, err
 		}
-		scanned = append(scanned, storedRow{
-			entry: entryFromRow(tsNS, stream, raw, byRef[ref]),
-			pos:   cursorPos{tsNS: tsNS, rowid: rowid},
-		})
+		scanned = append(scanned, row)
 	}
 	return scanned, rows.Err()
 }
 
-// generations resolves a logical container name to its generation rows. An
-// empty host matches the name on every host.
-func (s *Store) generations(ctx context.Context, host, name string) ([]generation, error) {
-	if name == "" {
-		return nil, nil
+// scanRawRow scans the current row of a readRows statement.
+func scanRawRow(rows *sql.Rows, row *rawRow) error {
+	return rows.Scan(&row.pos.rowid, &row.ref, &row.pos.tsNS, &row.stream, &row.raw)
+}
+
+// rebuildRows parses read rows into entries.
+func rebuildRows(rows []rawRow, byRef map[int64]generation) []storedRow {
+	rebuilt := make([]storedRow, len(rows))
+	for i, row := range rows {
+		gen := byRef[row.ref]
+		rebuilt[i] = storedRow{
+			entry:  entryFromRow(row.pos.tsNS, row.stream, row.raw, gen),
+			pos:    row.pos,
+			format: gen.format,
+		}
 	}
+	return rebuilt
+}
 
-	statement := "SELECT id, container_id, name FROM containers WHERE name = ?"
-	args := []any{name}
-	if host != "" {
-		statement += " AND host = ?"
-		args = append(args, host)
+// generations resolves the query to the generation rows it reads: every
+// generation of the named logical container, or for a store-wide query every
+// generation matching its host, project, and name filters. An empty host
+// matches every host.
+func (s *Store) generations(ctx context.Context, q LogQuery) ([]generation, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if q.Container != "" {
+		where = append(where, "name = ?")
+		args = append(args, q.Container)
+	} else {
+		if names := compactNames(q.Containers); len(names) > 0 {
+			where = append(where, "name IN ("+strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")+")")
+			for _, name := range names {
+				args = append(args, name)
+			}
+		}
+		if q.Project != "" {
+			where = append(where, "compose_project = ?")
+			args = append(args, q.Project)
+		}
+	}
+	if q.Host != "" {
+		where = append(where, "host = ?")
+		args = append(args, q.Host)
+	}
+
+	statement := "SELECT " + generationColumns + " FROM containers"
+	if len(where) > 0 {
+		statement += " WHERE " + strings.Join(where, " AND ")
 	}
 
 	rows, err := s.db.QueryContext(ctx, statement, args...)
@@ -386,8 +713,8 @@ func (s *Store) generations(ctx context.Context, host, name string) ([]generatio
 
 	var generations []generation
 	for rows.Next() {
-		var gen generation
-		if err := rows.Scan(&gen.ref, &gen.id, &gen.name); err != nil {
+		gen, err := scanGeneration(rows)
+		if err != nil {
 			return nil, err
 		}
 		generations = append(generations, gen)
@@ -395,11 +722,41 @@ func (s *Store) generations(ctx context.Context, host, name string) ([]generatio
 	return generations, rows.Err()
 }
 
+// compactNames trims the names of a store-wide query and drops blanks and
+// duplicates, so "web, ,web" selects one container rather than matching an
+// empty name.
+func compactNames(names []string) []string {
+	compact := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(compact, name) {
+			compact = append(compact, name)
+		}
+	}
+	return compact
+}
+
 // buildSelect renders the keyset page query: newest-first over every generation
 // of the container, bounded by the time window and the cursor. Only filters that
 // cannot delete part of a multi-line entry live in SQL — level and search are
 // applied in Go, on grouped entries.
 func buildSelect(refs []int64, q LogQuery, from cursorPos, hasPos bool, chunk int) (string, []any) {
+	where, args := windowWhere(refs, q)
+	if hasPos {
+		where = append(where, "(ts_ns < ? OR (ts_ns = ? AND rowid < ?))")
+		args = append(args, from.tsNS, from.tsNS, from.rowid)
+	}
+	args = append(args, chunk)
+
+	statement := "SELECT rowid, container_ref, ts_ns, stream, raw FROM log_lines WHERE " +
+		strings.Join(where, " AND ") +
+		" ORDER BY ts_ns DESC, rowid DESC LIMIT ?"
+	return statement, args
+}
+
+// windowWhere renders the conditions every read of a query shares: its
+// generations and its time window.
+func windowWhere(refs []int64, q LogQuery) ([]string, []any) {
 	placeholders, args := refArgs(refs)
 	where := []string{"container_ref IN (" + placeholders + ")"}
 
@@ -411,26 +768,19 @@ func buildSelect(refs []int64, q LogQuery, from cursorPos, hasPos bool, chunk in
 		where = append(where, "ts_ns <= ?")
 		args = append(args, q.Until.UnixNano())
 	}
-	if hasPos {
-		where = append(where, "(ts_ns < ? OR (ts_ns = ? AND rowid < ?))")
-		args = append(args, from.tsNS, from.tsNS, from.rowid)
-	}
-	args = append(args, chunk)
-
-	statement := "SELECT rowid, container_ref, ts_ns, stream, raw FROM log_lines WHERE " +
-		strings.Join(where, " AND ") +
-		" ORDER BY ts_ns DESC, rowid DESC LIMIT ?"
-	return statement, args
+	return where, args
 }
 
-// matcher applies the level and search filters to grouped entries, with the
-// same semantics as the live view: case-insensitive, over the parsed message
-// rather than the raw line, so History finds what Live finds and a search for a
-// timestamp-like string cannot match the engine's timestamp prefix.
+// matcher applies the level, search, and field filters to grouped entries, with
+// the same semantics as the live view: case-insensitive, over the parsed
+// message rather than the raw line, so History finds what Live finds and a
+// search for a timestamp-like string cannot match the engine's timestamp
+// prefix.
 type matcher struct {
 	levels []int
 	needle string         // lowercased substring search
 	regex  *regexp.Regexp // case-insensitive pattern search
+	fields models.FieldFilter
 }
 
 func newMatcher(q LogQuery) (matcher, error) {
@@ -446,17 +796,25 @@ func newMatcher(q LogQuery) (matcher, error) {
 	default:
 		m.needle = strings.ToLower(q.Search)
 	}
+	fields, err := models.ParseFieldFilter(q.Filter)
+	if err != nil {
+		return matcher{}, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	m.fields = fields
 	return m, nil
 }
 
 func (m matcher) active() bool {
-	return len(m.levels) > 0 || m.needle != "" || m.regex != nil
+	return len(m.levels) > 0 || m.needle != "" || m.regex != nil || !m.fields.Empty()
 }
 
 func (m matcher) matches(entry models.LogEntry) bool {
 	if len(m.levels) > 0 && !slices.Contains(m.levels, models.LevelSeverity(entry.Level)) {
 		return false
 	}
+	if !m.fields.MatchesEntry(entry) {
+		return false
+	}
 	switch {
 	case m.regex != nil:
 		return m.regex.MatchString(entry.Message)
@@ -466,20 +824,20 @@ func (m matcher) matches(entry models.LogEntry) bool {
 	return true
 }
 
-// filter keeps the matching entries and their anchors, in order.
-func (m matcher) filter(entries []models.LogEntry, anchors []cursorPos) ([]models.LogEntry, []cursorPos) {
+// filter keeps the matching entries and their spans, in order.
+func (m matcher) filter(entries []models.LogEntry, spans []entrySpan) ([]models.LogEntry, []entrySpan) {
 	if !m.active() {
-		return entries, anchors
+		return entries, spans
 	}
 	keptEntries := make([]models.LogEntry, 0, len(entries))
-	keptAnchors := make([]cursorPos, 0, len(anchors))
+	keptSpans := make([]entrySpan, 0, len(spans))
 	for i, entry := range entries {
 		if m.matches(entry) {
 			keptEntries = append(keptEntries, entry)
-			keptAnchors = append(keptAnchors, anchors[i])
+			keptSpans = append(keptSpans, spans[i])
 		}
 	}
-	return keptEntries, keptAnchors
+	return keptEntries, keptSpans
 }
 
 // levelSeverities maps level names to the severities stored on each row,
@@ -500,80 +858,120 @@ func levelSeverities(levels []string) []int {
 }
 
 // entryFromRow rebuilds a log entry from a stored row. The raw line is parsed
-// by the very same function the live path uses, so message cleaning and level
-// classification cannot drift; only the timestamp is taken from the stored
+// by the very same function the live path uses, in the generation's format, so
+// message cleaning and level classification cannot drift; only the timestamp is taken from the stored
 // engine timestamp rather than re-derived, which keeps an app-embedded
 // timestamp inside the line from overriding it.
 func entryFromRow(tsNS int64, stream int, raw string, gen generation) models.LogEntry {
-	name := "stdout"
-	if stream == streamStderr {
-		name = "stderr"
-	}
-	entry := models.ParseLogLine(raw, name)
+	entry := gen.format.Parse(raw, streamName(stream))
 	entry.Timestamp = time.Unix(0, tsNS).UTC()
+	entry.Host = gen.host
 	entry.ContainerID = gen.id
 	entry.ContainerName = gen.name
 	return entry
 }
 
+// streamName maps a stored stream back to the name the engine reports.
+func streamName(stream int) string {
+	if stream == streamStderr {
+		return "stderr"
+	}
+	return "stdout"
+}
+
 // groupRows folds continuation lines into their parent entry exactly like the
-// live historical path, but only within a run of lines from the same
-// generation, so a rebuild boundary can never merge two containers' lines.
+// live historical path, but only within one container's lines: when several
+// containers are merged by time, each keeps its own open entry, so a line from
+// another container interleaving a stack trace does not split it, and a
+// rebuild boundary can never merge two containers' lines.
 //
 // Rows arrive newest-first; entries come back oldest-first, each paired with the
-// position of its *first* row. That is what the next page resumes from: a
-// continuation line is always newer than its parent, so resuming at the parent
-// keeps the entry whole rather than splitting its body across two pages.
+// positions of its first and newest rows. The first is what the next page
+// resumes from: a continuation line is always newer than its parent, so
+// resuming at the parent keeps the entry whole rather than splitting its body
+// across two pages.
 //
-// The third return is the index in rows at which the oldest entry begins. It is
-// the entry whose parent may lie beyond the scanned rows, so a caller that has
-// not reached the end of history hands those rows to the next, older chunk.
-func groupRows(rows []storedRow) ([]models.LogEntry, []cursorPos, int) {
+// The oldest entry of each container may have its parent beyond the scanned
+// rows. The third return is the index in rows from which those entries (and any
+// entry starting among their rows) begin, and the fourth how many entries they
+// are: a caller that has not reached the end of history hands those rows to the
+// next, older chunk instead. A container that has been silent for more than
+// maxCarry rows is taken to have finished its entry, so one that rarely logs
+// cannot hold the whole window back.
+func groupRows(rows []storedRow, maxCarry int) ([]models.LogEntry, []entrySpan, int, int) {
 	entries := make([]models.LogEntry, 0, len(rows))
-	anchors := make([]cursorPos, 0, len(rows))
-	open := len(rows) - 1 // the oldest row starts the oldest entry
+	spans := make([]entrySpan, 0, len(rows))
+	starts := make([]int, 0, len(rows)) // index in rows of each entry's first row
+	newest := make([]int, 0, len(rows)) // index in rows of each entry's newest row
+	first := make([]bool, 0, len(rows)) // the entry is its container's oldest
+	openEntry := make(map[string]int)   // container ID -> its open entry
 
 	for i := len(rows) - 1; i >= 0; i-- {
 		row := rows[i]
-		if last := len(entries) - 1; last >= 0 && entries[last].ContainerID == row.entry.ContainerID {
-			// The live grouper decides; a folded pair comes back as one entry.
-			if merged := models.GroupRelatedLogEntries([]models.LogEntry{entries[last], row.entry}); len(merged) == 1 {
-				entries[last] = merged[0]
-				if last == 0 {
-					open = i // a continuation line of the oldest entry
-				}
-				continue
-			}
+		last, seen := openEntry[row.entry.ContainerID]
+		// The live grouper decides, in the generation's format.
+		if seen && row.format.Fold(&entries[last], row.entry) {
+			newest[last] = i
+			spans[last].last = row.pos
+			continue
 		}
+		openEntry[row.entry.ContainerID] = len(entries)
 		entries = append(entries, row.entry)
-		anchors = append(anchors, row.pos)
+		spans = append(spans, entrySpan{first: row.pos, last: row.pos})
+		starts = append(starts, i)
+		newest = append(newest, i)
+		first = append(first, !seen)
+	}
+
+	// Entries are oldest-first, so the held ones are a prefix: an entry
+	// starting among the held rows must be held too.
+	open, held := len(rows)-1, 0 // the oldest row starts the oldest entry
+	for k := range entries {
+		switch {
+		case starts[k] >= open:
+			open = min(open, newest[k])
+		case first[k] && len(rows)-newest[k] <= maxCarry:
+			open = newest[k]
+		default:
+			continue
+		}
+		held = k + 1
 	}
-	return entries, anchors, open
+	return entries, spans, open, held
 }
 
-// encodeCursor renders the keyset position of the oldest entry on a page.
-// (ts_ns, rowid) is unique and immutable, so pages stay stable while new lines
-// are ingested.
-func encodeCursor(tsNS, rowid int64) string {
-	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", tsNS, rowid))
+// encodeCursor renders the keyset position of the oldest entry on a page, and,
+// when it differs, the position the next page reads from. (ts_ns, rowid) is
+// unique and immutable, so pages stay stable while new lines are ingested.
+func encodeCursor(before, from cursorPos) string {
+	if from == before {
+		return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", before.tsNS, before.rowid))
+	}
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d:%d:%d", before.tsNS, before.rowid, from.tsNS, from.rowid))
 }
 
-func decodeCursor(cursor string) (int64, int64, error) {
+func decodeCursor(cursor string) (before, from cursorPos, err error) {
 	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return 0, 0, ErrInvalidCursor
+		return cursorPos{}, cursorPos{}, ErrInvalidCursor
 	}
-	tsPart, rowPart, ok := strings.Cut(string(decoded), ":")
-	if !ok {
-		return 0, 0, ErrInvalidCursor
+	parts := strings.Split(string(decoded), ":")
+	if len(parts) != 2 && len(parts) != 4 {
+		return cursorPos{}, cursorPos{}, ErrInvalidCursor
 	}
-	tsNS, err := strconv.ParseInt(tsPart, 10, 64)
-	if err != nil {
-		return 0, 0, ErrInvalidCursor
+	values := make([]int64, len(parts))
+	for i, part := range parts {
+		if values[i], err = strconv.ParseInt(part, 10, 64); err != nil {
+			return cursorPos{}, cursorPos{}, ErrInvalidCursor
+		}
 	}
-	rowid, err := strconv.ParseInt(rowPart, 10, 64)
-	if err != nil {
-		return 0, 0, ErrInvalidCursor
+	before = cursorPos{tsNS: values[0], rowid: values[1]}
+	from = before
+	if len(values) == 4 {
+		from = cursorPos{tsNS: values[2], rowid: values[3]}
+		if olderThan(from, before) {
+			return cursorPos{}, cursorPos{}, ErrInvalidCursor
+		}
 	}
-	return tsNS, rowid, nil
+	return before, from, nil
 }
@@ -0,0 +1,472 @@
+package logstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/logstream"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// severities lists every level a stored row can carry, UNKNOWN (0) included.
+var severities = []int{0, 1, 2, 3, 4, 5, 6, 7}
+
+// retentionRule is a config.RetentionRule resolved for the janitor.
+type retentionRule struct {
+	spec       logstream.ContainerSpec
+	severities []int // nil matches every level
+	maxAge     time.Duration
+}
+
+// compileRetention resolves the configured rules. A rule naming a level the
+// store does not know is skipped rather than applied to every level, which is
+// what dropping the unknown name would amount to.
+func compileRetention(rules []config.RetentionRule) []retentionRule {
+	compiled := make([]retentionRule, 0, len(rules))
+	for i, rule := range rules {
+		c := retentionRule{
+			spec:   logstream.ContainerSpec{Hosts: rule.Hosts, Containers: rule.Containers, Projects: rule.Projects},
+			maxAge: time.Duration(rule.MaxAgeHours) * time.Hour,
+		}
+		if c.maxAge <= 0 {
+			continue
+		}
+		valid := true
+		for _, level := range rule.Levels {
+			if !KnownLevel(level) {
+				log.Printf("logstore: retention rule %d names unknown level %q, skipping it", i, level)
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			continue
+		}
+		if len(rule.Levels) > 0 {
+			c.severities = levelSeverities(rule.Levels)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// KnownLevel reports whether name is a level the store classifies lines into:
+// one of models' levels, UNKNOWN included. Case is ignored.
+func KnownLevel(name string) bool {
+	level := models.LogLevel(strings.ToUpper(strings.TrimSpace(name)))
+	return level == models.LogLevelUnknown || models.LevelSeverity(level) != 0
+}
+
+// ageLimit is one cutoff of a generation's policy: lines of the given
+// severities stored before cutoff (ts_ns) are expired.
+type ageLimit struct {
+	cutoff     int64
+	severities []int
+}
+
+// policyFor works out how long each level of a generation is kept: for every
+// level, the first rule that matches the generation and the level sets its
+// cutoff. Levels no rule reaches are kept until the size caps evict them.
+// Levels sharing a cutoff are returned together, so each limit is one
+// statement.
+//
+// A generation labelled logdeck.retention keeps every level for that long
+// instead: the label is the service's own policy, and overrides the rules.
+func policyFor(rules []retentionRule, gen generation, now time.Time) []ageLimit {
+	if gen.maxAge > 0 {
+		return []ageLimit{{cutoff: now.Add(-gen.maxAge).UnixNano(), severities: slices.Clone(severities)}}
+	}
+	cutoffs := make(map[int]int64, len(severities))
+	for _, rule := range rules {
+		if !rule.spec.MatchesProject(gen.host, gen.name, gen.project) {
+			continue
+		}
+		for _, severity := range severities {
+			if _, decided := cutoffs[severity]; decided {
+				continue
+			}
+			if rule.severities == nil || slices.Contains(rule.severities, severity) {
+				cutoffs[severity] = now.Add(-rule.maxAge).UnixNano()
+			}
+		}
+		if len(cutoffs) == len(severities) {
+			break
+		}
+	}
+
+	var limits []ageLimit
+	for _, severity := range severities {
+		cutoff, ok := cutoffs[severity]
+		if !ok {
+			continue
+		}
+		i := slices.IndexFunc(limits, func(l ageLimit) bool { return l.cutoff == cutoff })
+		if i < 0 {
+			limits = append(limits, ageLimit{cutoff: cutoff})
+			i = len(limits) - 1
+		}
+		limits[i].severities = append(limits[i].severities, severity)
+	}
+	return limits
+}
+
+// expire deletes every stored line the retention rules and labels have aged
+// out, and drops the same lines from archived segments: a rule is a promise
+// about how long a line is kept anywhere, not just in the database. Expired
+// lines are deleted outright, never archived. Like evictOldest, each
+// transaction covers at most deleteChunk rows.
+func (s *Store) expire(ctx context.Context, rules []config.RetentionRule, now time.Time) error {
+	compiled := compileRetention(rules)
+	gens, err := s.allGenerations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, gen := range gens {
+		limits := policyFor(compiled, gen, now)
+		for _, limit := range limits {
+			var (
+				after  cursorPos
+				hasPos bool
+			)
+			for {
+				read, last, err := s.expireChunk(ctx, gen, limit, after, hasPos)
+				if err != nil {
+					return err
+				}
+				if read < deleteChunk {
+					break
+				}
+				after, hasPos = last, true
+			}
+		}
+		if len(limits) > 0 && s.archived.Load() {
+			if err := s.expireSegments(ctx, gen, limits); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// allGenerations reads every generation row.
+func (s *Store) allGenerations(ctx context.Context) ([]generation, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+generationColumns+" FROM containers")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gens []generation
+	for rows.Next() {
+		gen, err := scanGeneration(rows)
+		if err != nil {
+			return nil, err
+		}
+		gens = append(gens, gen)
+	}
+	return gens, rows.Err()
+}
+
+// expireChunk deletes up to deleteChunk of one generation's entries that limit
+// has expired, oldest first and after the given position. It reports how many
+// rows it read and where the last one sits, so the next chunk resumes past the
+// rows it kept.
+//
+// A level-targeted limit expires whole entries, the way a query groups them:
+// an entry goes by the level and age of its first line, and takes the lines
+// that continue it along, whatever their own stored level. Continuation lines
+// are mostly stored as UNKNOWN, so deleting by each row's own level would
+// orphan a DEBUG entry's trace under a DEBUG limit, and cut an ERROR entry's
+// trace under an UNKNOWN one.
+func (s *Store) expireChunk(ctx context.Context, gen generation, limit ageLimit, after cursorPos, hasPos bool) (int, cursorPos, error) {
+	args := []any{gen.ref, limit.cutoff}
+	levelClause := ""
+	byEntry := len(limit.severities) < len(severities)
+	if byEntry {
+		levelClause = " AND level IN (" + strings.TrimSuffix(strings.Repeat("?,", len(limit.severities)), ",") + ")"
+		for _, severity := range limit.severities {
+			args = append(args, severity)
+		}
+	}
+	afterClause := ""
+	if hasPos {
+		afterClause = " AND (ts_ns > ? OR (ts_ns = ? AND rowid > ?))"
+		args = append(args, after.tsNS, after.tsNS, after.rowid)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, cursorPos{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	candidates, err := readExpiryRows(ctx, tx,
+		"SELECT "+expiryColumns+" FROM log_lines WHERE container_ref = ? AND ts_ns < ?"+
+			levelClause+afterClause+" ORDER BY ts_ns, rowid LIMIT ?", append(args, deleteChunk)...)
+	if err != nil || len(candidates) == 0 {
+		return 0, cursorPos{}, err
+	}
+
+	doomed := candidates
+	if byEntry {
+		if doomed, err = expiredEntryRows(ctx, tx, gen, candidates); err != nil {
+			return 0, cursorPos{}, err
+		}
+	}
+	var (
+		rowids []any
+		freed  int64
+	)
+	for _, row := range doomed {
+		rowids = append(rowids, row.pos.rowid)
+		freed += row.size
+	}
+	last := candidates[len(candidates)-1].pos
+	if len(rowids) == 0 {
+		return len(candidates), last, nil
+	}
+
+	if err := deleteLines(ctx, tx, rowids, map[int64]int64{gen.ref: freed}); err != nil {
+		return 0, cursorPos{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, cursorPos{}, err
+	}
+	s.evictions.Add(1)
+	return len(candidates), last, nil
+}
+
+// expiryRow is one stored row as expiry reads it.
+type expiryRow struct {
+	pos    cursorPos
+	stream int
+	level  int
+	raw    string
+	size   int64
+}
+
+// expiryColumns are the log_lines columns readExpiryRows scans.
+const expiryColumns = "rowid, ts_ns, stream, level, raw, length(CAST(raw AS BLOB))"
+
+// foldReach bounds how far expiry looks around a row for the rest of its
+// entry. Continuation lines sit right next to their parent, so a short reach
+// covers any real stack trace.
+const foldReach = 64
+
+func readExpiryRows(ctx context.Context, tx *sql.Tx, statement string, args ...any) ([]expiryRow, error) {
+	rows, err := tx.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var read []expiryRow
+	for rows.Next() {
+		var row expiryRow
+		if err := rows.Scan(&row.pos.rowid, &row.pos.tsNS, &row.stream, &row.level, &row.raw, &row.size); err != nil {
+			return nil, err
+		}
+		read = append(read, row)
+	}
+	return read, rows.Err()
+}
+
+// expiredEntryRows turns rows whose own level a limit covers, oldest first,
+// into the rows of the entries they start: a row that continues an older entry
+// is left to that entry, and each entry a row starts takes its continuation
+// lines along.
+func expiredEntryRows(ctx context.Context, tx *sql.Tx, gen generation, candidates []expiryRow) ([]expiryRow, error) {
+	var (
+		doomed  []expiryRow
+		claimed = make(map[int64]bool)
+	)
+	for _, row := range candidates {
+		if claimed[row.pos.rowid] {
+			continue
+		}
+		// Only an UNKNOWN line, or any line under a multiline preset, can
+		// continue another.
+		if row.level == 0 || gen.format.Multiline != "" {
+			continues, err := continuesEntry(ctx, tx, gen, row)
+			if err != nil {
+				return nil, err
+			}
+			if continues {
+				continue
+			}
+		}
+		entry, err := entryRowsFrom(ctx, tx, gen, row)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range entry {
+			claimed[member.pos.rowid] = true
+		}
+		doomed = append(doomed, entry...)
+	}
+	return doomed, nil
+}
+
+// continuesEntry reports whether row continues an older entry. It groups the
+// rows before it from the nearest one with a level, which starts an entry.
+func continuesEntry(ctx context.Context, tx *sql.Tx, gen generation, row expiryRow) (bool, error) {
+	older, err := readExpiryRows(ctx, tx,
+		"SELECT "+expiryColumns+" FROM log_lines WHERE container_ref = ? AND (ts_ns < ? OR (ts_ns = ? AND rowid < ?))"+
+			" ORDER BY ts_ns DESC, rowid DESC LIMIT ?", gen.ref, row.pos.tsNS, row.pos.tsNS, row.pos.rowid, foldReach)
+	if err != nil || len(older) == 0 {
+		return false, err
+	}
+	start := len(older) - 1
+	for i, prev := range older {
+		if prev.level != 0 {
+			start = i
+			break
+		}
+	}
+	open := entryFromRow(older[start].pos.tsNS, older[start].stream, older[start].raw, gen)
+	for i := start - 1; i >= 0; i-- {
+		next := entryFromRow(older[i].pos.tsNS, older[i].stream, older[i].raw, gen)
+		if !gen.format.Fold(&open, next) {
+			open = next
+		}
+	}
+	return gen.format.Continues(entryFromRow(row.pos.tsNS, row.stream, row.raw, gen), open), nil
+}
+
+// entryRowsFrom reads the entry row starts: row itself and the rows after it
+// that the generation's format folds into it.
+func entryRowsFrom(ctx context.Context, tx *sql.Tx, gen generation, row expiryRow) ([]expiryRow, error) {
+	newer, err := readExpiryRows(ctx, tx,
+		"SELECT "+expiryColumns+" FROM log_lines WHERE container_ref = ? AND (ts_ns > ? OR (ts_ns = ? AND rowid > ?))"+
+			" ORDER BY ts_ns, rowid LIMIT ?", gen.ref, row.pos.tsNS, row.pos.tsNS, row.pos.rowid, foldReach)
+	if err != nil {
+		return nil, err
+	}
+	entry := []expiryRow{row}
+	open := entryFromRow(row.pos.tsNS, row.stream, row.raw, gen)
+	for _, next := range newer {
+		// Without a multiline preset only an UNKNOWN line folds, so a
+		// levelled one ends the entry unparsed.
+		if next.level != 0 && gen.format.Multiline == "" {
+			break
+		}
+		if !gen.format.Fold(&open, entryFromRow(next.pos.tsNS, next.stream, next.raw, gen)) {
+			break
+		}
+		entry = append(entry, next)
+	}
+	return entry, nil
+}
+
+// expireSegments applies a generation's age limits to its archived segments. A
+// segment whose lines have all expired is removed; one with only some expired
+// is rewritten without them, under the same key.
+func (s *Store) expireSegments(ctx context.Context, gen generation, limits []ageLimit) error {
+	newest := limits[0].cutoff
+	for _, limit := range limits[1:] {
+		newest = max(newest, limit.cutoff)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, location FROM archive_segments WHERE container_ref = ? AND first_ts_ns < ?", gen.ref, newest)
+	if err != nil {
+		return err
+	}
+	type segment struct {
+		id       int64
+		location string
+	}
+	var segments []segment
+	for rows.Next() {
+		var seg segment
+		if err := rows.Scan(&seg.id, &seg.location); err != nil {
+			rows.Close()
+			return err
+		}
+		segments = append(segments, seg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	target := s.coldTarget(s.limits().Archive)
+	for _, seg := range segments {
+		lines, err := s.segmentRows(ctx, target, seg.id, gen.ref, seg.location)
+		if err != nil {
+			return err
+		}
+		dead := expiredRows(limits, gen, lines)
+		kept := make([]rawRow, 0, len(lines))
+		for i, row := range lines {
+			if !dead[i] {
+				kept = append(kept, row)
+			}
+		}
+		switch {
+		case len(kept) == len(lines):
+			continue
+		case len(kept) == 0:
+			if _, err := s.db.ExecContext(ctx, "DELETE FROM archive_segments WHERE id = ?", seg.id); err != nil {
+				return err
+			}
+			if err := s.removeSegments(ctx, []int64{seg.id}, []string{seg.location}); err != nil {
+				log.Printf("logstore: expired segment left behind: %v", err)
+			}
+		default:
+			data, size, err := encodeSegment(gen, kept)
+			if err != nil {
+				return err
+			}
+			if err := target.put(ctx, seg.location, data); err != nil {
+				return fmt.Errorf("rewrite segment %s: %w", seg.location, err)
+			}
+			s.segments.drop(seg.id)
+			if _, err := s.db.ExecContext(ctx,
+				"UPDATE archive_segments SET first_ts_ns = ?, last_ts_ns = ?, lines = ?, bytes = ? WHERE id = ?",
+				kept[len(kept)-1].pos.tsNS, kept[0].pos.tsNS, len(kept), size, seg.id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expiredRows reports which of a segment's rows, newest first, limits cover.
+// Archived rows carry no level, so each is classified again, the way it was
+// when stored, and grouped into entries the way a query groups them: a
+// continuation line goes with the entry it continues, whatever its own level.
+func expiredRows(limits []ageLimit, gen generation, rows []rawRow) []bool {
+	dead := make([]bool, len(rows))
+	var (
+		open   models.LogEntry
+		parent = -1
+	)
+	for i := len(rows) - 1; i >= 0; i-- {
+		entry := entryFromRow(rows[i].pos.tsNS, rows[i].stream, rows[i].raw, gen)
+		if parent >= 0 && gen.format.Fold(&open, entry) {
+			dead[i] = dead[parent]
+			continue
+		}
+		open, parent = entry, i
+		dead[i] = expired(limits, models.LevelSeverity(entry.Level), rows[i].pos.tsNS)
+	}
+	return dead
+}
+
+// expired reports whether one of limits covers an entry of the given severity
+// whose first line was stored at tsNS.
+func expired(limits []ageLimit, severity int, tsNS int64) bool {
+	for _, limit := range limits {
+		if slices.Contains(limit.severities, severity) {
+			return tsNS < limit.cutoff
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package logstore
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// TestRetentionRulesExpireByAgeAndLevel applies the compliance example: debug
+// lines never outlive a day, payments keeps two weeks, everything else two
+// days. Level rules expire whole entries, continuation lines included.
+func TestRetentionRulesExpireByAgeAndLevel(t *testing.T) {
+	limits := testLimits()
+	limits.RetentionRules = []config.RetentionRule{
+		{Levels: []string{"DEBUG"}, MaxAgeHours: 24},
+		{Containers: []string{"worker"}, Levels: []string{"UNKNOWN"}, MaxAgeHours: 24},
+		{Projects: []string{"payments"}, MaxAgeHours: 14 * 24},
+		{MaxAgeHours: 48},
+	}
+	store, err := Open(filepath.Join(t.TempDir(), "logs.db"), func() config.ResolvedLogStoreConfig { return limits })
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	writeProjectEntries(t, store, genKey{"local", "pay"}, "api", "payments",
+		entryAt(ago(20*24*time.Hour), "stdout", "INFO charge 1"),
+		entryAt(ago(10*24*time.Hour), "stdout", "INFO charge 2"),
+		entryAt(ago(30*time.Hour), "stdout", "DEBUG card token cached"),
+		entryAt(ago(time.Hour), "stdout", "DEBUG card token refreshed"),
+	)
+	writeProjectEntries(t, store, genKey{"local", "web"}, "web", "shop",
+		entryAt(ago(3*24*time.Hour), "stdout", "INFO request 1"),
+		entryAt(ago(30*time.Hour), "stdout", "INFO request 2"),
+		entryAt(ago(30*time.Hour).Add(time.Second), "stdout", "DEBUG cache miss"),
+		entryAt(ago(30*time.Hour).Add(time.Second+time.Nanosecond), "stdout", "key: cart-42"),
+		entryAt(ago(29*time.Hour), "stdout", "INFO request 2 served"),
+		entryAt(ago(time.Hour), "stderr", "ERROR request 3 failed"),
+	)
+	// The trace of a kept error is stored as UNKNOWN, like the bare line
+	// after it, but only the bare line is an UNKNOWN entry.
+	writeProjectEntries(t, store, genKey{"local", "wrk"}, "worker", "shop",
+		entryAt(ago(30*time.Hour), "stderr", "ERROR job 7 failed"),
+		entryAt(ago(30*time.Hour).Add(time.Nanosecond), "stderr", "job: 7"),
+		entryAt(ago(29*time.Hour), "stdout", "queue drained"),
+	)
+
+	retain(t, store)
+
+	if got, want := storedMessages(t, store, "local", "api"), []string{"INFO charge 2", "DEBUG card token refreshed"}; !slices.Equal(got, want) {
+		t.Fatalf("payments kept %q, want %q", got, want)
+	}
+	if got, want := storedMessages(t, store, "local", "web"), []string{"INFO request 2", "INFO request 2 served", "ERROR request 3 failed"}; !slices.Equal(got, want) {
+		t.Fatalf("web kept %q, want %q", got, want)
+	}
+	if got, want := storedMessages(t, store, "local", "worker"), []string{"ERROR job 7 failed\njob: 7"}; !slices.Equal(got, want) {
+		t.Fatalf("worker kept %q, want %q", got, want)
+	}
+
+	var stored int64
+	if err := store.db.QueryRow("SELECT SUM(stored_bytes) FROM containers").Scan(&stored); err != nil {
+		t.Fatalf("read stored_bytes: %v", err)
+	}
+	var actual int64
+	if err := store.db.QueryRow("SELECT SUM(length(CAST(raw AS BLOB))) FROM log_lines").Scan(&actual); err != nil {
+		t.Fatalf("sum raw: %v", err)
+	}
+	if stored != actual {
+		t.Fatalf("stored_bytes = %d after expiry, want the %d bytes still stored", stored, actual)
+	}
+}
+
+func TestPolicyFirstMatchingRuleWinsPerLevel(t *testing.T) {
+	now := baseTime
+	rules := compileRetention([]config.RetentionRule{
+		{Levels: []string{"debug", "trace"}, MaxAgeHours: 1},
+		{Containers: []string{"api"}, Levels: []string{"DEBUG", "ERROR"}, MaxAgeHours: 2},
+		{Projects: []string{"payments"}, MaxAgeHours: 3},
+		{Levels: []string{"VERBOSE"}, MaxAgeHours: 4}, // unknown level: skipped
+	})
+	if len(rules) != 3 {
+		t.Fatalf("compiled %d rules, want the unknown-level rule skipped", len(rules))
+	}
+	cutoff := func(hours int) int64 { return now.Add(-time.Duration(hours) * time.Hour).UnixNano() }
+	sev := func(level models.LogLevel) int { return models.LevelSeverity(level) }
+
+	got := policyFor(rules, generation{host: "local", name: "api", project: "payments"}, now)
+	want := []ageLimit{
+		{cutoff: cutoff(3), severities: []int{0, sev(models.LogLevelInfo), sev(models.LogLevelWarn), sev(models.LogLevelFatal), sev(models.LogLevelPanic)}},
+		{cutoff: cutoff(1), severities: []int{sev(models.LogLevelTrace), sev(models.LogLevelDebug)}},
+		{cutoff: cutoff(2), severities: []int{sev(models.LogLevelError)}},
+	}
+	for _, limit := range got {
+		slices.Sort(limit.severities)
+	}
+	for _, limit := range want {
+		slices.Sort(limit.severities)
+	}
+	slices.SortFunc(got, func(a, b ageLimit) int { return int(b.cutoff - a.cutoff) })
+	slices.SortFunc(want, func(a, b ageLimit) int { return int(b.cutoff - a.cutoff) })
+	if len(got) != len(want) {
+		t.Fatalf("policy = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].cutoff != want[i].cutoff || !slices.Equal(got[i].severities, want[i].severities) {
+			t.Fatalf("policy = %+v, want %+v", got, want)
+		}
+	}
+
+	// Only the level rule reaches a container outside payments; every other
+	// level is left to the size caps.
+	got = policyFor(rules, generation{host: "local", name: "web", project: "shop"}, now)
+	if len(got) != 1 || got[0].cutoff != cutoff(1) || len(got[0].severities) != 2 {
+		t.Fatalf("policy for web = %+v, want only trace and debug limited", got)
+	}
+}
+
+// TestExpiredRowsGroupArchivedEntries is the cold tier's side of whole-entry
+// expiry: archived rows carry no level, and a continuation line goes with the
+// entry it continues.
+func TestExpiredRowsGroupArchivedEntries(t *testing.T) {
+	cutoff := baseTime.Add(time.Hour)
+	limits := []ageLimit{{cutoff: cutoff.UnixNano(), severities: []int{models.LevelSeverity(models.LogLevelDebug)}}}
+	row := func(ts time.Time, message string) rawRow {
+		return rawRow{pos: cursorPos{tsNS: ts.UnixNano()}, raw: rawLine(ts, message)}
+	}
+	// Newest first, as a segment holds them.
+	rows := []rawRow{
+		row(baseTime.Add(2*time.Hour), "DEBUG cache hit"),
+		row(baseTime.Add(3*time.Second), "INFO request served"),
+		row(baseTime.Add(2*time.Second+time.Nanosecond), "key: cart-42"),
+		row(baseTime.Add(2*time.Second), "DEBUG cache miss"),
+		row(baseTime.Add(time.Second), "INFO request received"),
+	}
+	got := expiredRows(limits, generation{}, rows)
+	if want := []bool{false, false, true, true, false}; !slices.Equal(got, want) {
+		t.Fatalf("expired = %v, want %v: the old debug entry and its continuation", got, want)
+	}
+}
+
+func archivedLines(t *testing.T, s *Store) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow("SELECT COALESCE(SUM(lines), 0) FROM archive_segments").Scan(&n); err != nil {
+		t.Fatalf("sum archived lines: %v", err)
+	}
+	return n
+}
+
+// TestRetentionRulesReachArchivedSegments expires lines that eviction already
+// moved to the cold tier: a segment partly past the cutoff is rewritten, one
+// wholly past it is removed.
+func TestRetentionRulesReachArchivedSegments(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := newArchivingStore(t, filepath.Join(dir, "logs.db"))
+	writeLarge(t, store, genKey{"local", "aaa"}, "web", baseTime, 500)
+	retain(t, store)
+	archived := segmentCount(t, store)
+	if archived < 2 {
+		t.Fatalf("archived %d segments, want the evicted lines spread over days", archived)
+	}
+	total := archivedLines(t, store)
+
+	rules := []config.RetentionRule{{MaxAgeHours: 48}}
+	oldest := func() string {
+		// Pages run newest to oldest, each in chronological order, so the
+		// oldest line opens the last page.
+		pages := allPages(t, store, LogQuery{Container: "web", Limit: 125})
+		last := -1
+		for i := range len(pages) - 1 {
+			if strings.HasPrefix(pages[i], "cursor ") {
+				last = i
+			}
+		}
+		return pages[last+1]
+	}
+
+	// Lines 0-74 fall in the first day's segment, which keeps the rest.
+	if err := store.expire(context.Background(), rules, baseTime.Add(48*time.Hour+300*time.Minute)); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if got := oldest(); !strings.HasPrefix(got, "web: web line 00075 ") {
+		t.Fatalf("oldest line after expiry = %.40q, want line 00075", got)
+	}
+	if n := segmentCount(t, store); n != archived {
+		t.Fatalf("%d segments after a partial expiry, want the %d rewritten in place", n, archived)
+	}
+	if got := archivedLines(t, store); got != total-75 {
+		t.Fatalf("%d archived lines after expiry, want %d", got, total-75)
+	}
+
+	// The first day ends at line 179; all of its segment goes.
+	if err := store.expire(context.Background(), rules, baseTime.Add(48*time.Hour+800*time.Minute)); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if got := oldest(); !strings.HasPrefix(got, "web: web line 00200 ") {
+		t.Fatalf("oldest line after expiry = %.40q, want line 00200", got)
+	}
+	if n := segmentCount(t, store); n >= archived {
+		t.Fatalf("%d segments after the first day expired, want fewer than %d", n, archived)
+	}
+	if files := segmentFiles(t, filepath.Join(dir, "archive")); len(files) != segmentCount(t, store) {
+		t.Fatalf("%d segment files for %d manifest rows: %q", len(files), segmentCount(t, store), files)
+	}
+}
@@ -8,7 +8,7 @@ import (
 
 // schemaVersion is the current schema generation, tracked in PRAGMA
 // user_version. Bump it and add a migration step when the schema changes.
-const schemaVersion = 2
+const schemaVersion = 7
 
 // schemaV1 is the initial schema.
 //
@@ -74,6 +74,82 @@ ALTER TABLE log_lines_v2 RENAME TO log_lines;
 CREATE INDEX log_lines_container_ts ON log_lines(container_ref, ts_ns);
 `
 
+// schemaV3 adds the optional full-text index and a small key/value table for
+// store-level state. The index is contentless — it holds the trigrams of each
+// line's lowercased message keyed by the log_lines rowid, never the text itself
+// — and contentless_delete lets eviction drop rows from it by rowid. It starts
+// empty: the writer builds it only while the index is enabled (see fts.go), so
+// the migration itself costs nothing on a large store.
+const schemaV3 = `
+CREATE VIRTUAL TABLE log_lines_fts USING fts5(
+  message,
+  content = '',
+  contentless_delete = 1,
+  tokenize = 'trigram case_sensitive 1'
+);
+CREATE TABLE store_meta (
+  key   TEXT PRIMARY KEY,
+  value INTEGER NOT NULL
+);
+`
+
+// schemaV4 adds the structured-field index: one row per (line, key) for each
+// configured key a line carries, with the value as text and, when it parses as
+// a number, as a REAL so range predicates compare numerically. Like the
+// full-text index it starts empty and is filled by the writer (see fields.go).
+// log_field_keys records which keys are indexed and how far catch-up has got.
+const schemaV4 = `
+CREATE TABLE log_fields (
+  line_rowid INTEGER NOT NULL, -- log_lines.rowid
+  key        TEXT NOT NULL,
+  value      TEXT NOT NULL,
+  num        REAL,
+  PRIMARY KEY (line_rowid, key)
+) WITHOUT ROWID;
+CREATE INDEX log_fields_value ON log_fields(key, value);
+CREATE INDEX log_fields_num ON log_fields(key, num) WHERE num IS NOT NULL;
+CREATE TABLE log_field_keys (
+  key           TEXT PRIMARY KEY,
+  pending_below INTEGER NOT NULL -- 0 once every stored line is indexed
+);
+`
+
+// schemaV5 adds the cold tier's manifest: one row per archived segment, the
+// lines of one generation from one UTC day that retention wrote to a file
+// before evicting them (see coldtier.go). location is the file's key within the
+// archive target. The span columns are what lets a query find the segments a
+// page reaches without opening any file.
+const schemaV5 = `
+CREATE TABLE archive_segments (
+  id            INTEGER PRIMARY KEY,
+  container_ref INTEGER NOT NULL REFERENCES containers(id),
+  first_ts_ns   INTEGER NOT NULL,
+  last_ts_ns    INTEGER NOT NULL,
+  lines         INTEGER NOT NULL,
+  bytes         INTEGER NOT NULL, -- raw line bytes, counted like stored_bytes
+  location      TEXT NOT NULL UNIQUE
+);
+CREATE INDEX archive_segments_ref_ts ON archive_segments(container_ref, last_ts_ns);
+`
+
+// schemaV6 records, per generation, what the ingestion rules filter from it
+// (see ingestrules.go). Unlike excluded_reason it is rewritten on every sync,
+// so it clears as soon as no rule applies.
+const schemaV6 = `
+ALTER TABLE containers ADD COLUMN filtered_reason TEXT NOT NULL DEFAULT '';
+`
+
+// schemaV7 records the logdeck.* labels a generation was created with that
+// reading its history depends on: how its lines are parsed and grouped, and
+// how long they are kept (0 leaves that to the retention rules). Labels are
+// fixed when an engine container is created, so sync writes them once per
+// generation in effect.
+const schemaV7 = `
+ALTER TABLE containers ADD COLUMN parser TEXT NOT NULL DEFAULT '';
+ALTER TABLE containers ADD COLUMN multiline TEXT NOT NULL DEFAULT '';
+ALTER TABLE containers ADD COLUMN retention_s INTEGER NOT NULL DEFAULT 0;
+`
+
 // initSchema creates the schema on a fresh database and is a no-op on an
 // already-current one. Unknown (newer) versions are rejected rather than
 // silently downgraded. A fresh database walks the same migration path as an
@@ -107,6 +183,31 @@ func initSchema(ctx context.Context, db *sql.DB) error {
 			return fmt.Errorf("migrate schema to version 2: %w", err)
 		}
 	}
+	if version < 3 {
+		if _, err := tx.ExecContext(ctx, schemaV3); err != nil {
+			return fmt.Errorf("migrate schema to version 3: %w", err)
+		}
+	}
+	if version < 4 {
+		if _, err := tx.ExecContext(ctx, schemaV4); err != nil {
+			return fmt.Errorf("migrate schema to version 4: %w", err)
+		}
+	}
+	if version < 5 {
+		if _, err := tx.ExecContext(ctx, schemaV5); err != nil {
+			return fmt.Errorf("migrate schema to version 5: %w", err)
+		}
+	}
+	if version < 6 {
+		if _, err := tx.ExecContext(ctx, schemaV6); err != nil {
+			return fmt.Errorf("migrate schema to version 6: %w", err)
+		}
+	}
+	if version < 7 {
+		if _, err := tx.ExecContext(ctx, schemaV7); err != nil {
+			return fmt.Errorf("migrate schema to version 7: %w", err)
+		}
+	}
 
 	// PRAGMA does not accept bound parameters.
 	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
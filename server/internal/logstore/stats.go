@@ -0,0 +1,261 @@
+package logstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+const (
+	// DefaultStatsRange is the window a StatsQuery without Since covers.
+	DefaultStatsRange = 24 * time.Hour
+	// MaxStatsBuckets bounds the buckets one StatsQuery can produce — a day at
+	// one-minute resolution, with room for a window that is not aligned.
+	MaxStatsBuckets = 1500
+	// MinStatsInterval is the finest bucket width.
+	MinStatsInterval = time.Second
+	// defaultStatsBuckets is roughly how many buckets a StatsQuery without an
+	// Interval is split into.
+	defaultStatsBuckets = 100
+)
+
+// statsIntervals are the widths a StatsQuery without an Interval picks from:
+// the finest one that keeps the window within defaultStatsBuckets.
+var statsIntervals = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// ErrInvalidStats wraps the reason a StatsQuery's window or interval is
+// unusable.
+var ErrInvalidStats = errors.New("invalid stats query")
+
+// StatsQuery selects stored lines exactly like a LogQuery (Limit and Cursor are
+// ignored) and counts them in buckets of Interval. Until defaults to now, Since
+// to DefaultStatsRange before Until, and Interval to a width that splits the
+// window into about a hundred buckets.
+type StatsQuery struct {
+	LogQuery
+	Interval time.Duration
+}
+
+// Stats is a bucketed count of stored logs, per level. Without a search or
+// field filter it counts stored lines by their own level, read straight from the
+// level column; a continuation line of a multi-line entry classifies on its own
+// and counts as UNKNOWN. With one it counts matching entries, grouped exactly as
+// Query returns them, by the level of the entry.
+type Stats struct {
+	Since    time.Time
+	Until    time.Time
+	Interval time.Duration
+	Counting string // "lines" or "entries"
+	Total    int64
+	Levels   map[string]int64 // totals over the window
+	Buckets  []StatsBucket
+}
+
+// StatsBucket is the count of one interval, starting at Start. Every bucket of
+// the window is present, empty ones included, so a chart needs no gap filling.
+type StatsBucket struct {
+	Start  time.Time        `json:"start"`
+	Total  int64            `json:"total"`
+	Levels map[string]int64 `json:"levels"`
+}
+
+// statsLevels maps the stored severities back to level names.
+var statsLevels = func() map[int]string {
+	names := make(map[int]string)
+	for _, level := range []models.LogLevel{
+		models.LogLevelTrace, models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarn,
+		models.LogLevelError, models.LogLevelFatal, models.LogLevelPanic, models.LogLevelUnknown,
+	} {
+		names[models.LevelSeverity(level)] = string(level)
+	}
+	return names
+}()
+
+// Stats counts the logs a StatsQuery selects. Buckets are aligned to multiples
+// of the interval since the Unix epoch, so one-minute buckets start on the
+// minute whatever the window, and the first bucket may start before Since.
+func (s *Store) Stats(ctx context.Context, q StatsQuery) (Stats, error) {
+	if q.Until.IsZero() {
+		q.Until = time.Now()
+	}
+	if q.Since.IsZero() {
+		q.Since = q.Until.Add(-DefaultStatsRange)
+	}
+	if q.Until.Before(q.Since) {
+		return Stats{}, fmt.Errorf("%w: until is before since", ErrInvalidStats)
+	}
+	span := q.Until.Sub(q.Since)
+	if q.Interval == 0 {
+		q.Interval = statsInterval(span)
+	}
+	if q.Interval < MinStatsInterval {
+		return Stats{}, fmt.Errorf("%w: interval must be at least %s", ErrInvalidStats, MinStatsInterval)
+	}
+
+	interval := q.Interval.Nanoseconds()
+	start := q.Since.UnixNano() / interval * interval
+	count := (q.Until.UnixNano()-start)/interval + 1
+	if count > MaxStatsBuckets {
+		return Stats{}, fmt.Errorf("%w: an interval of %s splits this range into %d buckets, more than %d",
+			ErrInvalidStats, q.Interval, count, MaxStatsBuckets)
+	}
+
+	match, err := newMatcher(q.LogQuery)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{
+		Since:    q.Since.UTC(),
+		Until:    q.Until.UTC(),
+		Interval: q.Interval,
+		Counting: "lines",
+		Levels:   map[string]int64{},
+		Buckets:  make([]StatsBucket, count),
+	}
+	for i := range stats.Buckets {
+		stats.Buckets[i] = StatsBucket{
+			Start:  time.Unix(0, start+int64(i)*interval).UTC(),
+			Levels: map[string]int64{},
+		}
+	}
+	add := func(bucket int64, level string, n int64) {
+		if bucket < 0 || bucket >= count {
+			return
+		}
+		stats.Buckets[bucket].Levels[level] += n
+		stats.Buckets[bucket].Total += n
+		stats.Levels[level] += n
+		stats.Total += n
+	}
+
+	generations, err := s.generations(ctx, q.LogQuery)
+	if err != nil || len(generations) == 0 {
+		return stats, err
+	}
+
+	if match.needle == "" && match.regex == nil && match.fields.Empty() {
+		err = s.countLines(ctx, generations, q.LogQuery, match.levels, start, interval, add)
+	} else {
+		stats.Counting = "entries"
+		err = s.countEntries(ctx, generations, q.LogQuery, match, func(entry models.LogEntry) {
+			add((entry.Timestamp.UnixNano()-start)/interval, string(entry.Level), 1)
+		})
+	}
+	if err != nil {
+		return Stats{}, err
+	}
+	return stats, nil
+}
+
+// statsInterval picks the width of a StatsQuery without an Interval.
+func statsInterval(span time.Duration) time.Duration {
+	for _, interval := range statsIntervals {
+		if span/interval < defaultStatsBuckets {
+			return interval
+		}
+	}
+	day := 24 * time.Hour
+	return (span/defaultStatsBuckets/day + 1) * day
+}
+
+// countLines counts stored lines per bucket and level entirely in SQL. Each
+// generation is a range of log_lines_container_ts, so the count reads only the
+// index and the level column of the lines in the window.
+func (s *Store) countLines(ctx context.Context, generations []generation, q LogQuery, levels []int, start, interval int64, add func(bucket int64, level string, n int64)) error {
+	where, args := windowWhere(generationRefs(generations), q)
+	if len(levels) > 0 {
+		where = append(where, "level IN ("+strings.TrimSuffix(strings.Repeat("?,", len(levels)), ",")+")")
+		for _, level := range levels {
+			args = append(args, level)
+		}
+	}
+	args = append([]any{start, interval}, args...)
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT (ts_ns - ?) / ? AS bucket, level, COUNT(*) FROM log_lines WHERE "+
+			strings.Join(where, " AND ")+" GROUP BY bucket, level", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, n int64
+		var level int
+		if err := rows.Scan(&bucket, &level, &n); err != nil {
+			return err
+		}
+		add(bucket, statsLevels[level], n)
+	}
+	return rows.Err()
+}
+
+// countEntries streams the window oldest-first, one generation after another,
+// groups the lines into entries the way Query does, and hands every entry the
+// matcher keeps to count. An entry whose first line precedes Since is seen
+// without it, as its continuation lines would be on a page that starts there.
+func (s *Store) countEntries(ctx context.Context, generations []generation, q LogQuery, match matcher, count func(models.LogEntry)) error {
+	byRef := make(map[int64]generation, len(generations))
+	for _, gen := range generations {
+		byRef[gen.ref] = gen
+	}
+	where, args := windowWhere(generationRefs(generations), q)
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT container_ref, ts_ns, stream, raw FROM log_lines WHERE "+
+			strings.Join(where, " AND ")+" ORDER BY container_ref, ts_ns, rowid", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var (
+		open    models.LogEntry
+		openRef int64
+		hasOpen bool
+	)
+	flush := func() {
+		if hasOpen && match.matches(open) {
+			count(open)
+		}
+	}
+	for rows.Next() {
+		var (
+			ref, tsNS int64
+			stream    int
+			raw       string
+		)
+		if err := rows.Scan(&ref, &tsNS, &stream, &raw); err != nil {
+			return err
+		}
+		gen := byRef[ref]
+		entry := entryFromRow(tsNS, stream, raw, gen)
+		if hasOpen && ref == openRef && gen.format.Fold(&open, entry) {
+			continue
+		}
+		flush()
+		open, openRef, hasOpen = entry, ref, true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+func generationRefs(generations []generation) []int64 {
+	refs := make([]int64, len(generations))
+	for i, gen := range generations {
+		refs[i] = gen.ref
+	}
+	return refs
+}
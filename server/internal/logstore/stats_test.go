@@ -0,0 +1,177 @@
+package logstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// writeStatsFixture stores three minutes of "web" (across a rebuild) and one
+// line of "worker", both in the shop project, plus an unrelated "db".
+func writeStatsFixture(t *testing.T, s *Store) {
+	t.Helper()
+	at := func(seconds int) time.Time { return baseTime.Add(time.Duration(seconds) * time.Second) }
+
+	writeProjectEntries(t, s, genKey{"local", "aaa"}, "web", "shop",
+		entryAt(at(5), "stdout", "INFO started"),
+		entryAt(at(20), "stderr", "ERROR upstream timeout"),
+		entryAt(at(20).Add(time.Millisecond), "stderr", "    at fetch (client.js:10:5)"),
+		entryAt(at(40), "stdout", "WARN slow response"),
+	)
+	// The rebuilt generation continues the same timeline.
+	writeProjectEntries(t, s, genKey{"local", "bbb"}, "web", "shop",
+		entryAt(at(70), "stdout", "INFO started"),
+		entryAt(at(130), "stderr", "ERROR upstream timeout"),
+		entryAt(at(150), "stderr", "ERROR disk full"),
+	)
+	writeProjectEntries(t, s, genKey{"local", "ccc"}, "worker", "shop",
+		entryAt(at(75), "stderr", "ERROR job timeout"),
+	)
+	writeProjectEntries(t, s, genKey{"local", "ddd"}, "db", "storage",
+		entryAt(at(10), "stderr", "ERROR checkpoint timeout"),
+	)
+}
+
+// bucketLevels flattens stats to one level map per bucket.
+func bucketLevels(stats Stats) []map[string]int64 {
+	out := make([]map[string]int64, len(stats.Buckets))
+	for i, bucket := range stats.Buckets {
+		out[i] = bucket.Levels
+	}
+	return out
+}
+
+func TestStatsCountsStoredLinesPerLevel(t *testing.T) {
+	store := newTestStore(t)
+	writeStatsFixture(t, store)
+
+	stats, err := store.Stats(context.Background(), StatsQuery{
+		LogQuery: LogQuery{Container: "web", Since: baseTime, Until: baseTime.Add(179 * time.Second)},
+		Interval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+
+	if stats.Counting != "lines" {
+		t.Fatalf("Counting = %q, want lines", stats.Counting)
+	}
+	// The stack-trace line is stored on its own and classifies as UNKNOWN.
+	want := []map[string]int64{
+		{"INFO": 1, "ERROR": 1, "UNKNOWN": 1, "WARN": 1},
+		{"INFO": 1},
+		{"ERROR": 2},
+	}
+	if got := bucketLevels(stats); !reflect.DeepEqual(got, want) {
+		t.Fatalf("buckets = %v, want %v", got, want)
+	}
+	for i, bucket := range stats.Buckets {
+		if start := baseTime.Add(time.Duration(i) * time.Minute); !bucket.Start.Equal(start) {
+			t.Fatalf("bucket %d starts at %v, want %v", i, bucket.Start, start)
+		}
+	}
+	if stats.Total != 7 || stats.Levels["ERROR"] != 3 {
+		t.Fatalf("totals = %d %v, want 7 lines with 3 errors", stats.Total, stats.Levels)
+	}
+
+	// A level filter narrows the count in SQL; an empty bucket is still present.
+	errorsOnly, err := store.Stats(context.Background(), StatsQuery{
+		LogQuery: LogQuery{Project: "shop", Levels: []string{"ERROR"}, Since: baseTime, Until: baseTime.Add(179 * time.Second)},
+		Interval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	want = []map[string]int64{{"ERROR": 1}, {"ERROR": 1}, {"ERROR": 2}}
+	if got := bucketLevels(errorsOnly); !reflect.DeepEqual(got, want) {
+		t.Fatalf("project error buckets = %v, want %v", got, want)
+	}
+}
+
+func TestStatsCountsMatchingEntries(t *testing.T) {
+	store := newTestStore(t)
+	writeStatsFixture(t, store)
+
+	stats, err := store.Stats(context.Background(), StatsQuery{
+		LogQuery: LogQuery{Search: "timeout", Since: baseTime, Until: baseTime.Add(179 * time.Second)},
+		Interval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+
+	if stats.Counting != "entries" {
+		t.Fatalf("Counting = %q, want entries", stats.Counting)
+	}
+	// The multi-line timeout is one entry, and counts once, as an error.
+	want := []map[string]int64{{"ERROR": 2}, {"ERROR": 1}, {"ERROR": 1}}
+	if got := bucketLevels(stats); !reflect.DeepEqual(got, want) {
+		t.Fatalf("buckets = %v, want %v", got, want)
+	}
+
+	regex, err := store.Stats(context.Background(), StatsQuery{
+		LogQuery: LogQuery{Container: "web", Search: "start|disk", Regex: true, Since: baseTime, Until: baseTime.Add(179 * time.Second)},
+		Interval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	want = []map[string]int64{{"INFO": 1}, {"INFO": 1}, {"ERROR": 1}}
+	if got := bucketLevels(regex); !reflect.DeepEqual(got, want) {
+		t.Fatalf("regex buckets = %v, want %v", got, want)
+	}
+}
+
+func TestStatsDefaultsAndValidation(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	stats, err := store.Stats(ctx, StatsQuery{LogQuery: LogQuery{Until: baseTime}})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if !stats.Since.Equal(baseTime.Add(-DefaultStatsRange)) || stats.Interval != 15*time.Minute {
+		t.Fatalf("defaults = since %v, interval %v; want a day in 15m buckets", stats.Since, stats.Interval)
+	}
+	if len(stats.Buckets) != 97 || stats.Total != 0 {
+		t.Fatalf("an empty store gave %d buckets totalling %d, want 97 empty buckets", len(stats.Buckets), stats.Total)
+	}
+
+	for name, q := range map[string]StatsQuery{
+		"until before since": {LogQuery: LogQuery{Since: baseTime, Until: baseTime.Add(-time.Second)}},
+		"interval too fine":  {LogQuery: LogQuery{Until: baseTime}, Interval: 500 * time.Millisecond},
+		"too many buckets":   {LogQuery: LogQuery{Until: baseTime}, Interval: time.Second},
+	} {
+		if _, err := store.Stats(ctx, q); !errors.Is(err, ErrInvalidStats) {
+			t.Errorf("%s: got %v, want ErrInvalidStats", name, err)
+		}
+	}
+	if _, err := store.Stats(ctx, StatsQuery{LogQuery: LogQuery{Filter: "status>"}}); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("a malformed filter returned %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestStatsFieldFilterCountsEntries(t *testing.T) {
+	store := newTestStore(t)
+	writeEntries(t, store, genKey{"local", "aaa"}, "api",
+		entryAt(baseTime, "stdout", "level=info status=200"),
+		entryAt(baseTime.Add(time.Second), "stdout", "level=error status=502"),
+		entryAt(baseTime.Add(2*time.Second), "stdout", `{"level":"error","status":503}`),
+	)
+
+	stats, err := store.Stats(context.Background(), StatsQuery{
+		LogQuery: LogQuery{Container: "api", Filter: "status>=500", Since: baseTime, Until: baseTime.Add(time.Minute - time.Nanosecond)},
+		Interval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	want := []map[string]int64{{string(models.LogLevelError): 2}}
+	if got := bucketLevels(stats); !reflect.DeepEqual(got, want) {
+		t.Fatalf("buckets = %v, want %v", got, want)
+	}
+}
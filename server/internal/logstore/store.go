@@ -72,8 +72,9 @@ type DockerProvider interface {
 	Docker() *docker.MultiHostClient
 }
 
-// Limits supplies the retention caps, re-read on every janitor pass so config
-// changes take effect without a restart.
+// Limits supplies the retention caps and the index settings, re-read on every
+// janitor pass (and every writer tick, for the indexes) so config changes take
+// effect without a restart.
 type Limits func() config.ResolvedLogStoreConfig
 
 // Store owns the SQLite database, the ingestion pipeline, and retention.
@@ -95,8 +96,26 @@ type Store struct {
 	evictions atomic.Uint64
 	// committed counts log lines successfully inserted, cumulatively. Unlike the
 	// live row count it never decreases when retention evicts, so measurement can
-	// report true ingestion throughput. Maintained only for the stress harness.
+	// report true ingestion throughput.
 	committed atomic.Int64
+	// lines breaks committed down by container and level for the metrics
+	// endpoint.
+	lines lineCounts
+	// index is the full-text index state (see indexOff). Only the writer changes
+	// it; Query reads it to decide whether the index is complete enough to use.
+	index atomic.Int64
+	// fields is the field index state. Like index, only the writer replaces it.
+	fields atomic.Pointer[fieldKeys]
+	// archived reports whether any segment has ever been archived, so queries
+	// skip the cold tier entirely until retention has written to it.
+	archived atomic.Bool
+	// segments caches decoded cold-tier segments for queries.
+	segments segmentCache
+	// filter holds the compiled ingestion rules the sink and backfills apply.
+	filter atomic.Pointer[ingestFilter]
+	// sampled carries each live-tailed container's open entry from one sink
+	// call to the next, so sampling keeps or drops whole entries.
+	sampled openEntries
 
 	// mu guards resume, gaps, and invalidated, all read by goroutines other
 	// than the one that writes them.
@@ -150,6 +169,13 @@ func OpenFromConfig(manager *config.Manager) *Store {
 
 	log.Printf("Log persistence is ENABLED (%s, %d MB per container, %d MB total)",
 		path, limits.PerContainerMB, limits.TotalMB)
+	if limits.Archive.Enabled {
+		if limits.Archive.S3.Configured() {
+			log.Printf("Evicted logs are archived to s3 bucket %s at %s", limits.Archive.S3.Bucket, limits.Archive.S3.Endpoint)
+		} else {
+			log.Printf("Evicted logs are archived to %s", store.archiveDir(limits.Archive))
+		}
+	}
 	return store
 }
 
@@ -192,8 +218,23 @@ func Open(path string, limits Limits) (*Store, error) {
 		db.Close()
 		return nil, err
 	}
+	indexState, err := loadIndexState(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("read full-text index state: %w", err)
+	}
+	fieldState, err := loadFieldKeys(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("read field index state: %w", err)
+	}
+	var archived bool
+	if err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM archive_segments)").Scan(&archived); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("read archive manifest: %w", err)
+	}
 
-	return &Store{
+	store := &Store{
 		db:          db,
 		path:        path,
 		limits:      limits,
@@ -203,7 +244,12 @@ func Open(path string, limits Limits) (*Store, error) {
 		gaps:        make(map[genKey]int64),
 		invalidated: make(map[genKey]struct{}),
 		backfillSem: make(chan struct{}, maxConcurrentBackfills),
-	}, nil
+	}
+	store.index.Store(indexState)
+	store.fields.Store(&fieldState)
+	store.archived.Store(archived)
+	store.refreshFilter()
+	return store, nil
 }
 
 // Start runs the ingestion, lifecycle, and retention loops until ctx is
@@ -224,7 +270,7 @@ func (s *Store) start(ctx context.Context, hub Hub, source func() Engine) {
 	// The hub sink is a producer: it must stop before ingestCh is closed.
 	s.producers.Add(1)
 	unsubscribe := hub.Subscribe(
-		logstream.ContainerSpec{}, // all containers on all hosts
+		logstream.ContainerSpec{OptOutLabel: models.LabelStore}, // every container that has not opted out
 		models.LogOptions{Follow: true, Timestamps: true, Tail: "0", ShowStdout: true, ShowStderr: true},
 		s.sink,
 	)
@@ -272,13 +318,19 @@ func (s *Store) start(ctx context.Context, hub Hub, source func() Engine) {
 }
 
 // sink receives live records on the hub's delivery goroutine. It must never
-// block for long, so a full queue drops the record and bumps a counter.
+// block for long, so a full queue drops the record and bumps a counter. Lines
+// the ingestion rules filter out are skipped before they are queued.
 func (s *Store) sink(rec logstream.Record) {
+	key := genKey{host: rec.Host, id: rec.ContainerID}
+	filter := s.ingestFilter().forContainer(rec.Host, rec.ContainerName, rec.Labels)
+	if !s.sampled.keeps(key, filter, rec.Entry) {
+		return
+	}
 	msg := ingestMsg{
 		kind:    msgLine,
-		key:     genKey{host: rec.Host, id: rec.ContainerID},
+		key:     key,
 		name:    rec.ContainerName,
-		project: composeProject(rec.Labels),
+		project: models.ComposeProject(rec.Labels),
 	}
 	msg.line = lineFromEntry(rec.Entry)
 
@@ -382,15 +434,3 @@ func (s *Store) Wait() {
 func (s *Store) Close() error {
 	return s.db.Close()
 }
-
-// composeProject reads the compose project from container labels. Docker
-// Compose and recent podman-compose set the com.docker label; older
-// podman-compose releases only set the io.podman one.
-func composeProject(labels map[string]string) string {
-	for _, label := range []string{"com.docker.compose.project", "io.podman.compose.project"} {
-		if value := labels[label]; value != "" {
-			return value
-		}
-	}
-	return ""
-}
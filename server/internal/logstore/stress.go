@@ -2,23 +2,21 @@ package logstore
 
 import "context"
 
-// This file exposes a tiny, read-only measurement surface consumed ONLY by the
-// out-of-tree stress harness (server/cmd/logstore-stress). It adds no behavior
-// to the store and changes no write path: it just reads counters the pipeline
-// already maintains. It exists because those counters (s.drops) and the row
-// table (s.db) are unexported, and the harness must observe the REAL store, not
-// a copy. Do not use these methods in production code.
+// This file exposes a tiny, read-only measurement surface: it adds no behavior
+// to the store and changes no write path, it just reads counters the pipeline
+// already maintains. Drops and Committed feed the metrics endpoint as well as
+// the out-of-tree stress harness (server/cmd/logstore-stress); CountLines is
+// for the harness alone, which must observe the REAL store, not a copy.
 
 // Drops reports how many live records the ingest buffer has discarded because
-// the writer fell behind — the store's backpressure signal. Stress/measurement
-// use only.
+// the writer fell behind — the store's backpressure signal.
 func (s *Store) Drops() uint64 {
 	return s.drops.Load()
 }
 
 // Committed reports the cumulative number of log lines the writer has inserted.
 // It never decreases when retention evicts, so it measures true ingestion,
-// whereas CountLines reports only what currently survives. Stress use only.
+// whereas CountLines reports only what currently survives.
 func (s *Store) Committed() int64 {
 	return s.committed.Load()
 }
@@ -133,18 +133,23 @@ func (s *Store) sync(ctx context.Context, engine Engine, track *backfillTracker,
 
 	nowMS := time.Now().UnixMilli()
 	live := make(map[genKey]bool)
+	s.refreshFilter()
+	rules := s.ingestFilter()
 
 	for host, containers := range snapshot {
 		for _, info := range containers {
 			key := genKey{host: host, id: info.ID}
 			live[key] = true
 
-			excluded, err := s.upsertMeta(ctx, key, info, nowMS)
+			filter := rules.forContainer(host, containerName(info), info.Labels)
+			excluded, err := s.upsertMeta(ctx, key, info, filter.reason, nowMS)
 			if err != nil {
 				log.Printf("logstore: recording container %s/%s failed: %v", host, short(info.ID), err)
 				continue
 			}
-			if !track.schedule(s, key, excluded) {
+			// A rule exclusion is not recorded as done: once the rule goes, the
+			// generation is read like any other.
+			if filter.excluded || !track.schedule(s, key, excluded) {
 				continue
 			}
 			if ctx.Err() != nil {
@@ -161,28 +166,38 @@ func (s *Store) sync(ctx context.Context, engine Engine, track *backfillTracker,
 	}
 }
 
-// upsertMeta records the generation's engine metadata and reports whether the
-// generation is excluded from persistence. An empty incoming value never
-// overwrites a stored one: a listing that carries no names would otherwise
-// blank the name the logical container is keyed by, and an unnamed generation
-// is unresolvable.
-func (s *Store) upsertMeta(ctx context.Context, key genKey, info models.ContainerInfo, nowMS int64) (bool, error) {
+// upsertMeta records the generation's engine metadata, along with what the
+// ingestion rules currently filter from it, and reports whether the generation
+// is excluded from persistence because its logs cannot be read. An empty
+// incoming value never overwrites a stored one: a listing that carries no names
+// would otherwise blank the name the logical container is keyed by, and an
+// unnamed generation is unresolvable. The filter reason always does, since a
+// rule that no longer applies must stop being reported, and so do the
+// generation's logdeck.* labels, which the listing always carries in full.
+func (s *Store) upsertMeta(ctx context.Context, key genKey, info models.ContainerInfo, filtered string, nowMS int64) (bool, error) {
 	firstSeenMS := nowMS
 	if info.Created > 0 {
 		firstSeenMS = info.Created * 1000
 	}
+	policy := models.PolicyFromLabels(info.Labels)
 
 	if _, err := s.db.ExecContext(ctx, `
-		INSERT INTO containers (host, container_id, name, compose_project, image, first_seen_ms, last_seen_ms, removed_ms)
-		VALUES (?, ?, ?, ?, ?, ?, ?, NULL)
+		INSERT INTO containers (host, container_id, name, compose_project, image, first_seen_ms, last_seen_ms, removed_ms,
+		                        filtered_reason, parser, multiline, retention_s)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULL, ?, ?, ?, ?)
 		ON CONFLICT(host, container_id) DO UPDATE SET
 			name = CASE WHEN excluded.name != '' THEN excluded.name ELSE containers.name END,
 			compose_project = CASE WHEN excluded.compose_project != '' THEN excluded.compose_project ELSE containers.compose_project END,
 			image = CASE WHEN excluded.image != '' THEN excluded.image ELSE containers.image END,
 			first_seen_ms = min(containers.first_seen_ms, excluded.first_seen_ms),
 			last_seen_ms = excluded.last_seen_ms,
-			removed_ms = NULL`,
-		key.host, key.id, containerName(info), composeProject(info.Labels), info.Image, firstSeenMS, nowMS,
+			removed_ms = NULL,
+			filtered_reason = excluded.filtered_reason,
+			parser = excluded.parser,
+			multiline = excluded.multiline,
+			retention_s = excluded.retention_s`,
+		key.host, key.id, containerName(info), models.ComposeProject(info.Labels), info.Image, firstSeenMS, nowMS, filtered,
+		policy.Format.Parser, policy.Format.Multiline, int64(policy.Retention/time.Second),
 	); err != nil {
 		return false, err
 	}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"log"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -46,14 +47,9 @@ type ContainerSpec struct {
 	Hosts      []string
 	Containers []string // exact container names
 	Projects   []string // compose projects
-}
-
-// Docker Compose and recent podman-compose both set the com.docker label;
-// older podman-compose releases only set the io.podman one. Mirrors the
-// unexported helper in internal/docker/compose.go.
-var composeProjectLabels = []string{
-	"com.docker.compose.project",
-	"io.podman.compose.project",
+	// OptOutLabel, when set, names a label a container opts out with: one
+	// labelled OptOutLabel=false is never selected, whatever else matches.
+	OptOutLabel string
 }
 
 // Matches reports whether a container (identified by host, name without the
@@ -61,6 +57,28 @@ var composeProjectLabels = []string{
 // container/project dimension, which is an OR between exact names and compose
 // projects.
 func (s ContainerSpec) Matches(host, name string, labels map[string]string) bool {
+	if s.OptOutLabel != "" {
+		if in, err := strconv.ParseBool(strings.TrimSpace(labels[s.OptOutLabel])); err == nil && !in {
+			return false
+		}
+	}
+	return s.match(host, name, func(project string) bool {
+		for _, label := range models.ComposeProjectLabels {
+			if labels[label] == project {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchesProject is Matches for a container whose compose project is already
+// known, such as a stored generation that no longer has labels to read.
+func (s ContainerSpec) MatchesProject(host, name, project string) bool {
+	return s.match(host, name, func(p string) bool { return p == project })
+}
+
+func (s ContainerSpec) match(host, name string, inProject func(project string) bool) bool {
 	if len(s.Hosts) > 0 && !slices.Contains(s.Hosts, host) {
 		return false
 	}
@@ -71,10 +89,8 @@ func (s ContainerSpec) Matches(host, name string, labels map[string]string) bool
 		return true
 	}
 	for _, project := range s.Projects {
-		for _, label := range composeProjectLabels {
-			if labels[label] == project {
-				return true
-			}
+		if inProject(project) {
+			return true
 		}
 	}
 	return false
@@ -220,6 +220,28 @@ func TestSpecMatches(t *testing.T) {
 		if got := tc.spec.Matches(tc.host, tc.cname, tc.labels); got != tc.want {
 			t.Errorf("%s: Matches = %v, want %v", tc.name, got, tc.want)
 		}
+		if got := tc.spec.MatchesProject(tc.host, tc.cname, models.ComposeProject(tc.labels)); got != tc.want {
+			t.Errorf("%s: MatchesProject = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSpecOptOutLabel(t *testing.T) {
+	spec := ContainerSpec{OptOutLabel: "logdeck.store"}
+	cases := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{nil, true},
+		{map[string]string{"logdeck.store": "true"}, true},
+		{map[string]string{"logdeck.store": "maybe"}, true}, // unreadable: not an opt-out
+		{map[string]string{"logdeck.store": "false"}, false},
+		{map[string]string{"logdeck.store": " 0 "}, false},
+	}
+	for _, tc := range cases {
+		if got := spec.Matches("h1", "web", tc.labels); got != tc.want {
+			t.Errorf("labels %v: Matches = %v, want %v", tc.labels, got, tc.want)
+		}
 	}
 }
 
@@ -0,0 +1,112 @@
+// Package metrics renders samples in the Prometheus text exposition format
+// (version 0.0.4). It carries no registry and no collectors: the caller
+// gathers current values on every scrape and writes them family by family,
+// which keeps LogDeck free of a client library for one endpoint.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the Content-Type of a rendered exposition.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types a family can declare.
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+// Labels are one sample's label pairs. Empty values are dropped, the way
+// Prometheus treats them.
+type Labels map[string]string
+
+// Writer writes families to an underlying writer. The first write error is
+// kept and every later write is skipped, so a caller checks Err once.
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+// NewWriter returns a Writer that renders to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Err returns the first error a write returned.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Family starts a metric family: its HELP and TYPE lines. Every Sample that
+// follows, up to the next Family, belongs to it.
+func (w *Writer) Family(name, kind, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+}
+
+// Sample writes one sample of the current family.
+func (w *Writer) Sample(name string, labels Labels, value float64) {
+	w.printf("%s%s %s\n", name, renderLabels(labels), formatValue(value))
+}
+
+func (w *Writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+// renderLabels renders labels sorted by name, so a series reads the same on
+// every scrape.
+func renderLabels(labels Labels) string {
+	names := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
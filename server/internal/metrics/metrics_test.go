@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestWriterRendersTheTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Family("logdeck_store_bytes", Gauge, "Bytes of stored log lines.\nPer container.")
+	w.Sample("logdeck_store_bytes", Labels{"host": "local", "container": `we"b\1`, "project": ""}, 2048)
+	w.Sample("logdeck_store_bytes", nil, 0.5)
+	w.Family("logdeck_up", Counter, "Odd values.")
+	w.Sample("logdeck_up", Labels{"host": "a\nb"}, math.Inf(1))
+	w.Sample("logdeck_up", nil, math.NaN())
+	if err := w.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+
+	want := `# HELP logdeck_store_bytes Bytes of stored log lines.\nPer container.
+# TYPE logdeck_store_bytes gauge
+logdeck_store_bytes{container="we\"b\\1",host="local"} 2048
+logdeck_store_bytes 0.5
+# HELP logdeck_up Odd values.
+# TYPE logdeck_up counter
+logdeck_up{host="a\nb"} +Inf
+logdeck_up NaN
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("rendered\n%s\nwant\n%s", got, want)
+	}
+}
+
+type failingWriter struct{ writes int }
+
+func (f *failingWriter) Write([]byte) (int, error) {
+	f.writes++
+	return 0, errors.New("client went away")
+}
+
+func TestWriterStopsAtTheFirstError(t *testing.T) {
+	out := &failingWriter{}
+	w := NewWriter(out)
+	w.Family("a", Gauge, "a")
+	w.Sample("a", nil, 1)
+	w.Sample("a", nil, 2)
+	if w.Err() == nil || out.writes != 1 {
+		t.Fatalf("err = %v after %d writes, want the first error and no more writes", w.Err(), out.writes)
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // LevelSeverity returns the canonical numeric severity for a log level, used
 // by alert min-level matching and the persistence store. UNKNOWN is lowest so
 // unclassified lines never satisfy a min-level threshold; the named levels
@@ -25,21 +27,88 @@ func LevelSeverity(level LogLevel) int {
 	}
 }
 
-// Alert is one fired-alert history entry.
+// Alert states. An alert is an incident: it opens firing when its rule's
+// condition is met and resolves once the condition clears.
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert is one alert-history entry: an incident for one (rule, host,
+// container), from the time it fired until it resolved.
 type Alert struct {
 	ID            string          `json:"id"`
 	RuleID        string          `json:"ruleId"`
 	RuleName      string          `json:"ruleName"`
 	Type          string          `json:"type"`
+	Severity      string          `json:"severity,omitempty"` // "info" | "warning" | "critical"
 	Host          string          `json:"host"`
 	ContainerID   string          `json:"containerId"`
 	ContainerName string          `json:"containerName"`
+	Project       string          `json:"project,omitempty"` // compose project, when the container has one
 	Reason        string          `json:"reason"`
 	Sample        string          `json:"sample,omitempty"`
 	Count         int             `json:"count"`
 	Suppressed    int             `json:"suppressed"`
 	FiredAt       string          `json:"firedAt"`
 	Delivery      *DeliveryResult `json:"delivery,omitempty"`
+	// SuppressedBy names the silence or maintenance window that last kept a
+	// fire of the incident from being notified. Until a fire is notified,
+	// Delivery has status "suppressed-by-silence".
+	SuppressedBy string `json:"suppressedBy,omitempty"`
+	// Routing records which channels the incident was sent to and why; a
+	// resolution goes to the same channels.
+	Routing *AlertRouting `json:"routing,omitempty"`
+
+	State string `json:"state"`
+	// LastFiredAt and Repeats record the times the condition tripped again
+	// after the cooldown while the incident was still firing.
+	LastFiredAt string `json:"lastFiredAt,omitempty"`
+	Repeats     int    `json:"repeats,omitempty"`
+	// ResolvedAt, ResolveReason, and DurationSeconds are set once the
+	// incident resolves; ResolveDelivery is the resolved notification's
+	// outcome.
+	ResolvedAt      string          `json:"resolvedAt,omitempty"`
+	ResolveReason   string          `json:"resolveReason,omitempty"`
+	DurationSeconds int64           `json:"durationSeconds,omitempty"`
+	ResolveDelivery *DeliveryResult `json:"resolveDelivery,omitempty"`
+
+	// An acknowledged incident is not notified again when it repeats; its
+	// resolution still is.
+	AcknowledgedAt string `json:"acknowledgedAt,omitempty"`
+	AcknowledgedBy string `json:"acknowledgedBy,omitempty"`
+	// A silenced incident sends no notifications at all, repeats or
+	// resolution, until SilencedUntil or, when that is empty, until it
+	// resolves.
+	Silenced      bool   `json:"silenced,omitempty"`
+	SilencedUntil string `json:"silencedUntil,omitempty"`
+}
+
+// SilencedAt reports whether the incident's notifications are silenced at now.
+func (a Alert) SilencedAt(now time.Time) bool {
+	if !a.Silenced {
+		return false
+	}
+	if a.SilencedUntil == "" {
+		return true
+	}
+	until, err := time.Parse(time.RFC3339, a.SilencedUntil)
+	return err == nil && now.Before(until)
+}
+
+// AlertRouting is the routing decision for an incident: the channels chosen
+// and the reason, e.g. "route 1 (projects: staging)".
+type AlertRouting struct {
+	Channels []RoutedChannel `json:"channels"`
+	Reason   string          `json:"reason"`
+}
+
+// RoutedChannel identifies a channel an incident was routed to, with the
+// name and type it had at the time.
+type RoutedChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
 }
 
 // DeliveryResult records the outcome of one webhook delivery attempt.
@@ -48,3 +117,32 @@ type DeliveryResult struct {
 	HTTPStatus int    `json:"httpStatus,omitempty"`
 	Error      string `json:"error,omitempty"`
 }
+
+// ChannelHealth is a notification channel's recent delivery record.
+// ConsecutiveFailures resets on the next success. While RetryAt is in the
+// future the channel is backing off and its queued notifications wait.
+type ChannelHealth struct {
+	LastSuccessAt       string `json:"lastSuccessAt,omitempty"`
+	LastFailureAt       string `json:"lastFailureAt,omitempty"`
+	LastError           string `json:"lastError,omitempty"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	RetryAt             string `json:"retryAt,omitempty"`
+	// Pending counts notifications queued for the channel; DeadLettered
+	// those given up on at the delivery deadline.
+	Pending      int `json:"pending"`
+	DeadLettered int `json:"deadLettered"`
+}
+
+// NotificationPreview is what a channel sends for one alert once its
+// templates are applied. Title is empty for channel types without one.
+type NotificationPreview struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body"`
+}
+
+// ChannelTestResult is the outcome of a channel test: the delivery result
+// (status "skipped" for a dry run) and the rendered test notification.
+type ChannelTestResult struct {
+	DeliveryResult
+	Preview *NotificationPreview `json:"preview,omitempty"`
+}
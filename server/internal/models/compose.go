@@ -15,3 +15,14 @@ type ComposeActionResult struct {
 	Succeeded int                       `json:"succeeded"`
 	Failed    []ComposeContainerFailure `json:"failed"`
 }
+
+// ComposeDeployEvent is one line of a compose pull, up, or down progress
+// stream. The stream ends with a line whose Status is "done" or "error".
+type ComposeDeployEvent struct {
+	Service string `json:"service,omitempty"`
+	// Target is what the step acts on: a container, image, network, or volume.
+	Target string `json:"target,omitempty"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
@@ -13,6 +13,9 @@ type ContainerInfo struct {
 	Health  string            `json:"health,omitempty"`
 	Labels  map[string]string `json:"labels,omitempty"`
 	Host    string            `json:"host"`
+	// UpdateAvailable is set when the last image update check found that the
+	// container's image tag points to a newer image in its registry.
+	UpdateAvailable bool `json:"update_available,omitempty"`
 }
 
 // ContainerEvent represents a container lifecycle event streamed to the frontend
@@ -35,6 +38,7 @@ type LogOptions struct {
 	ShowStderr bool   `json:"show_stderr"`
 	Level      string `json:"level,omitempty"`
 	Search     string `json:"search,omitempty"`
+	Filter     string `json:"filter,omitempty"` // field predicates; see ParseFieldFilter
 }
 
 func DefaultLogOptions() LogOptions {
@@ -57,9 +61,54 @@ type ContainerStats struct {
 	MemoryPercent float64 `json:"memory_percent"`
 	MemoryUsed    uint64  `json:"memory_used"`
 	MemoryLimit   uint64  `json:"memory_limit"`
+	// Breakdown of the raw usage: page cache and anonymous (RSS) memory.
+	MemoryCache uint64 `json:"memory_cache"`
+	MemoryRSS   uint64 `json:"memory_rss"`
+	// I/O rates in bytes per second since the previous reading of the same
+	// container; 0 on the first reading.
+	NetworkRxPerSec  float64 `json:"network_rx_per_sec"`
+	NetworkTxPerSec  float64 `json:"network_tx_per_sec"`
+	BlockReadPerSec  float64 `json:"block_read_per_sec"`
+	BlockWritePerSec float64 `json:"block_write_per_sec"`
+	PIDs             uint64  `json:"pids"`
+}
+
+// ContainerStatsEvent is one line of the container stats stream: a fresh
+// reading, or, with Removed set, notice that the container stopped and its
+// last reading no longer applies.
+type ContainerStatsEvent struct {
+	ContainerStats
+	Removed bool `json:"removed,omitempty"`
 }
 
 // ContainerStatsResponse is the API response for container stats
 type ContainerStatsResponse struct {
 	Stats []ContainerStats `json:"stats"`
 }
+
+// ContainerStatsPoint is one point of a container's stats history: a raw
+// sample, or the average and peak of a rollup bucket starting at Timestamp.
+// I/O rates are averages in bytes per second.
+type ContainerStatsPoint struct {
+	Timestamp        int64   `json:"timestamp"` // unix milliseconds
+	Samples          int     `json:"samples"`
+	CPUPercent       float64 `json:"cpu_percent"`
+	CPUPercentMax    float64 `json:"cpu_percent_max"`
+	MemoryUsed       uint64  `json:"memory_used"`
+	MemoryUsedMax    uint64  `json:"memory_used_max"`
+	MemoryLimit      uint64  `json:"memory_limit"`
+	NetworkRxPerSec  float64 `json:"network_rx_per_sec"`
+	NetworkTxPerSec  float64 `json:"network_tx_per_sec"`
+	BlockReadPerSec  float64 `json:"block_read_per_sec"`
+	BlockWritePerSec float64 `json:"block_write_per_sec"`
+}
+
+// ContainerStatsHistoryResponse is the API response for a container's stats
+// history. Resolution is "raw", "1m", or "1h"; the newest points may be finer
+// than it when their buckets are not complete yet.
+type ContainerStatsHistoryResponse struct {
+	Host       string                `json:"host"`
+	Container  string                `json:"container"`
+	Resolution string                `json:"resolution"`
+	Points     []ContainerStatsPoint `json:"points"`
+}
@@ -0,0 +1,404 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// fieldKeyRegex is the shape of a field name, in a logfmt line and in a filter
+// expression alike. Dots and dashes are allowed so flattened JSON keys
+// ("http.status") and kebab-case keys can be named directly.
+var fieldKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// ValidFieldKey reports whether key can name a field.
+func ValidFieldKey(key string) bool {
+	return fieldKeyRegex.MatchString(key)
+}
+
+// ExtractFields reads the key/value pairs of a structured log message: the
+// scalar members of a JSON object, with nested objects flattened into dotted
+// keys, or the key=value pairs of a logfmt line. It returns nil when the
+// message carries no fields.
+func ExtractFields(message string) map[string]string {
+	message = strings.TrimSpace(message)
+	if strings.HasPrefix(message, "{") {
+		if fields, ok := extractJSONFields(message); ok {
+			return fields
+		}
+	}
+	return extractLogfmtFields(message)
+}
+
+// EntryFields returns every field of a (possibly grouped) entry: the fields a
+// structured parser attached to it, then the fields of each of its lines, a
+// later line overriding an earlier one. This is what a FieldFilter is
+// evaluated against.
+func EntryFields(entry LogEntry) map[string]string {
+	var fields map[string]string
+	if len(entry.Fields) > 0 {
+		fields = maps.Clone(entry.Fields)
+	}
+	for line := range strings.SplitSeq(entry.Message, "\n") {
+		for key, value := range LineFields(line) {
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// LineFields returns the fields of one line: its JSON or logfmt pairs, or, for
+// a line that has none, the "key: value" form grouping folds into an entry as
+// a continuation. An entry's fields are always drawn from its lines' fields,
+// which is what lets the log store index lines rather than entries.
+func LineFields(message string) map[string]string {
+	if fields := ExtractFields(message); fields != nil {
+		return fields
+	}
+	if key, value, ok := parseStructuredField(message); ok {
+		// `service: "api"` is how many pretty-printers render a string field.
+		if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+			value = unquoted
+		}
+		return map[string]string{key: value}
+	}
+	return nil
+}
+
+func extractJSONFields(message string) (map[string]string, bool) {
+	var payload map[string]any
+	decoder := json.NewDecoder(strings.NewReader(message))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, false
+	}
+	fields := make(map[string]string, len(payload))
+	flattenJSONFields(fields, "", payload)
+	if len(fields) == 0 {
+		return nil, true
+	}
+	return fields, true
+}
+
+// flattenJSONFields copies scalars into fields. Arrays and nulls carry no value
+// a filter could compare against, so they are skipped.
+func flattenJSONFields(fields map[string]string, prefix string, payload map[string]any) {
+	for key, value := range payload {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch typed := value.(type) {
+		case string:
+			fields[key] = typed
+		case json.Number:
+			fields[key] = typed.String()
+		case bool:
+			fields[key] = strconv.FormatBool(typed)
+		case map[string]any:
+			flattenJSONFields(fields, key, typed)
+		}
+	}
+}
+
+// extractLogfmtFields reads key=value pairs separated by whitespace. Values may
+// be double-quoted with Go-style escapes. Words that are not pairs — the free
+// text many loggers put around them — are skipped, not treated as errors.
+func extractLogfmtFields(message string) map[string]string {
+	var fields map[string]string
+	rest := message
+	for rest != "" {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		end := strings.IndexFunc(rest, func(r rune) bool { return r == '=' || unicode.IsSpace(r) })
+		if end <= 0 || rest[end] != '=' {
+			// Not a pair: skip the word.
+			if end < 0 {
+				break
+			}
+			rest = rest[max(end, 1):]
+			continue
+		}
+		key := rest[:end]
+		rest = rest[end+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, remaining, ok := cutQuoted(rest)
+			if !ok {
+				break // an unterminated quote swallows the rest of the line
+			}
+			value, rest = quoted, remaining
+		} else {
+			valueEnd := strings.IndexFunc(rest, unicode.IsSpace)
+			if valueEnd < 0 {
+				valueEnd = len(rest)
+			}
+			value, rest = rest[:valueEnd], rest[valueEnd:]
+		}
+
+		if !ValidFieldKey(key) {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// cutQuoted splits a leading double-quoted string off s, unescaping it.
+func cutQuoted(s string) (value, rest string, ok bool) {
+	escaped := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			unquoted, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return s[1:i], s[i+1:], true // keep the raw text rather than drop the pair
+			}
+			return unquoted, s[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// FieldOp is a comparison in a field predicate.
+type FieldOp string
+
+const (
+	FieldEq          FieldOp = "="
+	FieldNotEq       FieldOp = "!="
+	FieldGreater     FieldOp = ">"
+	FieldGreaterEq   FieldOp = ">="
+	FieldLess        FieldOp = "<"
+	FieldLessEq      FieldOp = "<="
+	FieldContains    FieldOp = "~"
+	FieldNotContains FieldOp = "!~"
+)
+
+// fieldOps is ordered so a two-character operator is tried before its
+// one-character prefix.
+var fieldOps = []FieldOp{
+	FieldNotEq, FieldGreaterEq, FieldLessEq, FieldNotContains,
+	FieldEq, FieldGreater, FieldLess, FieldContains,
+}
+
+// FieldPredicate is one "key op value" clause of a FieldFilter.
+type FieldPredicate struct {
+	Key   string
+	Op    FieldOp
+	Value string
+}
+
+// Numeric reports the predicate's value as a number, when it is one.
+func (p FieldPredicate) Numeric() (float64, bool) {
+	return ParseFieldNumber(p.Value)
+}
+
+// Matches evaluates the predicate against an entry's fields.
+//
+// = and != compare numerically when both sides are numbers ("500" equals
+// "500.0") and exactly otherwise. The ordering operators compare numerically
+// when the value is a number, and then a field that is not a number never
+// matches; a non-numeric value compares as a string. ~ and !~ test for a
+// case-insensitive substring. A missing field satisfies only != and !~.
+func (p FieldPredicate) Matches(fields map[string]string) bool {
+	actual, ok := fields[p.Key]
+	if !ok {
+		return p.Op == FieldNotEq || p.Op == FieldNotContains
+	}
+
+	switch p.Op {
+	case FieldContains:
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(p.Value))
+	case FieldNotContains:
+		return !strings.Contains(strings.ToLower(actual), strings.ToLower(p.Value))
+	case FieldEq, FieldNotEq:
+		equal := actual == p.Value
+		if want, ok := p.Numeric(); ok {
+			if got, ok := ParseFieldNumber(actual); ok {
+				equal = got == want
+			}
+		}
+		return equal == (p.Op == FieldEq)
+	}
+
+	var cmp int
+	if want, ok := p.Numeric(); ok {
+		got, ok := ParseFieldNumber(actual)
+		if !ok {
+			return false
+		}
+		switch {
+		case got < want:
+			cmp = -1
+		case got > want:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(actual, p.Value)
+	}
+
+	switch p.Op {
+	case FieldGreater:
+		return cmp > 0
+	case FieldGreaterEq:
+		return cmp >= 0
+	case FieldLess:
+		return cmp < 0
+	case FieldLessEq:
+		return cmp <= 0
+	}
+	return false
+}
+
+// ParseFieldNumber reads a field value as a finite number, the way numeric
+// predicates do; NaN and the infinities are treated as text so they cannot
+// make a comparison meaningless.
+func ParseFieldNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+// FieldFilter is a conjunction of field predicates, parsed from an expression
+// such as `status>=500 AND path~"/api"`. The zero value matches everything.
+type FieldFilter struct {
+	Predicates []FieldPredicate
+}
+
+// ParseFieldFilter parses clauses of the form key op value joined by AND
+// (case-insensitive). A value is either bare — running to the next AND — or
+// double-quoted with Go-style escapes, which it must be to contain " AND ".
+// The empty expression is the empty filter.
+func ParseFieldFilter(expr string) (FieldFilter, error) {
+	var filter FieldFilter
+	rest := strings.TrimSpace(expr)
+	for rest != "" {
+		predicate, remaining, err := parseFieldPredicate(rest)
+		if err != nil {
+			return FieldFilter{}, err
+		}
+		filter.Predicates = append(filter.Predicates, predicate)
+
+		rest = strings.TrimSpace(remaining)
+		if rest == "" {
+			break
+		}
+		connective := strings.IndexFunc(rest, unicode.IsSpace)
+		if connective < 0 {
+			connective = len(rest)
+		}
+		if !strings.EqualFold(rest[:connective], "AND") {
+			return FieldFilter{}, fmt.Errorf("expected AND before %q", rest)
+		}
+		if rest = strings.TrimSpace(rest[connective:]); rest == "" {
+			return FieldFilter{}, fmt.Errorf("expected a field comparison after AND")
+		}
+	}
+	return filter, nil
+}
+
+func parseFieldPredicate(s string) (FieldPredicate, string, error) {
+	keyEnd := strings.IndexFunc(s, func(r rune) bool {
+		return strings.ContainsRune("=!<>~", r) || unicode.IsSpace(r)
+	})
+	if keyEnd <= 0 {
+		return FieldPredicate{}, "", fmt.Errorf("expected a field comparison at %q", s)
+	}
+	key := s[:keyEnd]
+	if !ValidFieldKey(key) {
+		return FieldPredicate{}, "", fmt.Errorf("invalid field name %q", key)
+	}
+
+	rest := strings.TrimLeftFunc(s[keyEnd:], unicode.IsSpace)
+	var op FieldOp
+	for _, candidate := range fieldOps {
+		if strings.HasPrefix(rest, string(candidate)) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return FieldPredicate{}, "", fmt.Errorf("expected an operator after %q (one of = != > >= < <= ~ !~)", key)
+	}
+	rest = strings.TrimLeftFunc(rest[len(op):], unicode.IsSpace)
+
+	if strings.HasPrefix(rest, `"`) {
+		value, remaining, ok := cutQuoted(rest)
+		if !ok {
+			return FieldPredicate{}, "", fmt.Errorf("unterminated quote in the value of %q", key)
+		}
+		return FieldPredicate{Key: key, Op: op, Value: value}, remaining, nil
+	}
+
+	value, remaining := rest, ""
+	if i := indexWord(rest, "AND"); i >= 0 {
+		value, remaining = rest[:i], rest[i:]
+	}
+	value = strings.TrimSpace(value)
+	if indexWord(value, "OR") >= 0 {
+		return FieldPredicate{}, "", fmt.Errorf("OR is not supported in the value of %q (quote the value to match it literally)", key)
+	}
+	if value == "" {
+		return FieldPredicate{}, "", fmt.Errorf("missing value for %q (quote an empty value: \"\")", key)
+	}
+	return FieldPredicate{Key: key, Op: op, Value: value}, remaining, nil
+}
+
+// indexWord finds the next whitespace-delimited occurrence of the upper-case
+// word in s, ignoring case. The word may end s.
+func indexWord(s, word string) int {
+	upper := strings.ToUpper(s)
+	for from := 0; ; {
+		i := strings.Index(upper[from:], word)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		end := i + len(word)
+		before := i > 0 && unicode.IsSpace(rune(s[i-1]))
+		after := end == len(s) || unicode.IsSpace(rune(s[end]))
+		if before && after {
+			return i
+		}
+		from = end
+	}
+}
+
+// Empty reports whether the filter has no predicates.
+func (f FieldFilter) Empty() bool {
+	return len(f.Predicates) == 0
+}
+
+// Matches reports whether fields satisfy every predicate.
+func (f FieldFilter) Matches(fields map[string]string) bool {
+	for _, predicate := range f.Predicates {
+		if !predicate.Matches(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesEntry evaluates the filter against an entry's fields; see EntryFields.
+func (f FieldFilter) MatchesEntry(entry LogEntry) bool {
+	if f.Empty() {
+		return true
+	}
+	return f.Matches(EntryFields(entry))
+}
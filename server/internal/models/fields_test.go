@@ -0,0 +1,148 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    map[string]string
+	}{
+		{
+			name:    "json scalars and nested objects",
+			message: `{"level":"error","status":503,"ok":false,"http":{"path":"/api/orders","method":"POST"},"tags":["a"],"trace":null}`,
+			want: map[string]string{
+				"level":       "error",
+				"status":      "503",
+				"ok":          "false",
+				"http.path":   "/api/orders",
+				"http.method": "POST",
+			},
+		},
+		{
+			name:    "json keeps number text",
+			message: `{"latency":0.250,"id":12345678901234567890}`,
+			want:    map[string]string{"latency": "0.250", "id": "12345678901234567890"},
+		},
+		{
+			name:    "logfmt with quoted values and free text",
+			message: `request done level=info path="/api/a b" status=200 msg="said \"hi\""`,
+			want: map[string]string{
+				"level":  "info",
+				"path":   "/api/a b",
+				"status": "200",
+				"msg":    `said "hi"`,
+			},
+		},
+		{
+			name:    "malformed json falls back to logfmt",
+			message: `{"broken": status=500`,
+			want:    map[string]string{"status": "500"},
+		},
+		{
+			name:    "plain text has no fields",
+			message: "GET /api?x=1 took 3ms",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractFields(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ExtractFields(%q) = %v, want %v", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntryFieldsIncludesContinuationLines(t *testing.T) {
+	grouped := GroupRelatedLogEntries([]LogEntry{
+		ParseLogLine("2026-05-28T05:00:38Z ERROR request failed status=502", "stdout"),
+		ParseLogLine("2026-05-28T05:00:38Z request_id: abc-123", "stdout"),
+		ParseLogLine("2026-05-28T05:00:38Z user_id=7 region=eu", "stdout"),
+	})
+	if len(grouped) != 1 {
+		t.Fatalf("grouped into %d entries, want 1", len(grouped))
+	}
+
+	want := map[string]string{"status": "502", "request_id": "abc-123", "user_id": "7", "region": "eu"}
+	if got := EntryFields(grouped[0]); !reflect.DeepEqual(got, want) {
+		t.Fatalf("EntryFields = %v, want %v", got, want)
+	}
+}
+
+func TestParseFieldFilter(t *testing.T) {
+	filter, err := ParseFieldFilter(`status>=500 and path~"/api AND more"  AND user.id!=7 AND msg = "" AND region=eu west`)
+	if err != nil {
+		t.Fatalf("ParseFieldFilter: %v", err)
+	}
+	want := []FieldPredicate{
+		{Key: "status", Op: FieldGreaterEq, Value: "500"},
+		{Key: "path", Op: FieldContains, Value: "/api AND more"},
+		{Key: "user.id", Op: FieldNotEq, Value: "7"},
+		{Key: "msg", Op: FieldEq, Value: ""},
+		{Key: "region", Op: FieldEq, Value: "eu west"},
+	}
+	if !reflect.DeepEqual(filter.Predicates, want) {
+		t.Fatalf("Predicates = %+v, want %+v", filter.Predicates, want)
+	}
+
+	if filter, err := ParseFieldFilter("   "); err != nil || !filter.Empty() {
+		t.Fatalf("blank filter = %+v, %v; want the empty filter", filter, err)
+	}
+}
+
+func TestParseFieldFilterRejectsMalformedExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"status",
+		"status>=",
+		"=500",
+		"1status=5",
+		`path="/api`,
+		`path="/api" status=5`,
+		"status=5 AND",
+		"status=5 OR path=/",
+	} {
+		if _, err := ParseFieldFilter(expr); err == nil {
+			t.Errorf("ParseFieldFilter(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestFieldPredicateMatches(t *testing.T) {
+	fields := map[string]string{"status": "503", "path": "/API/orders", "latency": "0.5", "region": "eu"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"status>=500", true},
+		{"status>503", false},
+		{"status=503.0", true},
+		{"status<1000", true},
+		{"latency<1", true},
+		{"path~/api", true},
+		{"path!~orders", false},
+		{"region>=eu", true},
+		{"region<ab", false},
+		{"region>5", false}, // a numeric bound never matches a non-numeric field
+		{"missing=1", false},
+		{"missing!=1", true},
+		{"missing!~x", true},
+		{"missing>0", false},
+		{"status>=500 AND path~orders", true},
+		{"status>=500 AND region!=eu", false},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFieldFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseFieldFilter(%q): %v", tt.expr, err)
+		}
+		if got := filter.Matches(fields); got != tt.want {
+			t.Errorf("%q matched = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+// ImageUpdateStatus is the outcome of checking one running container's image
+// tag against its registry.
+type ImageUpdateStatus struct {
+	Host          string `json:"host"`
+	ContainerID   string `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	// Image is the reference the container was created from, e.g. nginx:latest.
+	Image   string `json:"image"`
+	ImageID string `json:"image_id"`
+	// RemoteDigest is the manifest digest the tag points to in the registry.
+	RemoteDigest    string `json:"remote_digest,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+	// Error explains why the image could not be checked, e.g. a locally built
+	// image or a registry that denied access. UpdateAvailable is false then.
+	Error     string `json:"error,omitempty"`
+	CheckedAt int64  `json:"checked_at"`
+}
+
+// ImageUpdatesResponse is the API response for the image update check.
+type ImageUpdatesResponse struct {
+	Enabled bool `json:"enabled"`
+	// LastCheck is when the last full check finished, in Unix milliseconds;
+	// zero before the first one.
+	LastCheck int64               `json:"last_check"`
+	Checking  bool                `json:"checking"`
+	Updates   []ImageUpdateStatus `json:"updates"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Container labels LogDeck reads to steer how it treats one container, so a
+// service's policy can live in its compose file next to its definition.
+const (
+	LabelStore     = "logdeck.store"     // "false" keeps the container out of the log store
+	LabelRetention = "logdeck.retention" // how long stored lines are kept, e.g. "7d" or "36h"
+	LabelParser    = "logdeck.parser"    // how lines are structured: "json" or "logfmt"
+	LabelMultiline = "logdeck.multiline" // which stack traces to fold: "java", "python", or "go"
+	LabelHide      = "logdeck.hide"      // "true" leaves the container out of the container list
+)
+
+// ComposeProjectLabels are the labels a compose project is read from. Docker
+// Compose and recent podman-compose both set the com.docker label; older
+// podman-compose releases only set the io.podman one.
+var ComposeProjectLabels = []string{
+	"com.docker.compose.project",
+	"io.podman.compose.project",
+}
+
+// ComposeProject returns the compose project a container belongs to, from its
+// labels, or "" for a standalone container.
+func ComposeProject(labels map[string]string) string {
+	for _, label := range ComposeProjectLabels {
+		if project := labels[label]; project != "" {
+			return project
+		}
+	}
+	return ""
+}
+
+// ContainerPolicy is what a container's logdeck.* labels ask for. The zero
+// value is the default behaviour; a label whose value cannot be read is
+// ignored rather than guessed at.
+type ContainerPolicy struct {
+	NoStore   bool
+	Retention time.Duration // 0 leaves the configured retention rules in charge
+	Format    LineFormat
+	Hidden    bool
+}
+
+// PolicyFromLabels reads a container's logdeck.* labels.
+func PolicyFromLabels(labels map[string]string) ContainerPolicy {
+	var policy ContainerPolicy
+	if store, err := strconv.ParseBool(strings.TrimSpace(labels[LabelStore])); err == nil {
+		policy.NoStore = !store
+	}
+	if hide, err := strconv.ParseBool(strings.TrimSpace(labels[LabelHide])); err == nil {
+		policy.Hidden = hide
+	}
+	if age, ok := ParseRetention(labels[LabelRetention]); ok {
+		policy.Retention = age
+	}
+	if parser := strings.ToLower(strings.TrimSpace(labels[LabelParser])); parser == ParserJSON || parser == ParserLogfmt {
+		policy.Format.Parser = parser
+	}
+	if preset := strings.ToLower(strings.TrimSpace(labels[LabelMultiline])); multilinePresets[preset] != nil {
+		policy.Format.Multiline = preset
+	}
+	return policy
+}
+
+// ParseRetention reads a retention period: a whole number of days ("7d") or
+// weeks ("2w"), or a Go duration ("36h", "90m"). A period that is not
+// positive, or too long to represent, is rejected.
+func ParseRetention(value string) (time.Duration, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, false
+	}
+	var age time.Duration
+	switch unit := value[len(value)-1]; unit {
+	case 'd', 'w':
+		n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		day := 24 * time.Hour
+		if unit == 'w' {
+			day *= 7
+		}
+		// Past this the multiplication wraps, possibly to a positive period.
+		if n > math.MaxInt64/int64(day) {
+			return 0, false
+		}
+		age = time.Duration(n) * day
+	default:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, false
+		}
+		age = parsed
+	}
+	if age <= 0 {
+		return 0, false
+	}
+	return age, true
+}
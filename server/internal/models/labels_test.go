@@ -0,0 +1,183 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicyFromLabels(t *testing.T) {
+	policy := PolicyFromLabels(map[string]string{
+		LabelStore:     "false",
+		LabelRetention: "7d",
+		LabelParser:    "JSON",
+		LabelMultiline: "java",
+		LabelHide:      "true",
+	})
+	want := ContainerPolicy{
+		NoStore:   true,
+		Retention: 7 * 24 * time.Hour,
+		Format:    LineFormat{Parser: ParserJSON, Multiline: MultilineJava},
+		Hidden:    true,
+	}
+	if policy != want {
+		t.Fatalf("policy = %+v, want %+v", policy, want)
+	}
+
+	unreadable := PolicyFromLabels(map[string]string{
+		LabelStore:     "nope",
+		LabelRetention: "forever",
+		LabelParser:    "xml",
+		LabelMultiline: "cobol",
+		LabelHide:      "",
+	})
+	if unreadable != (ContainerPolicy{}) {
+		t.Fatalf("unreadable labels gave %+v, want the default policy", unreadable)
+	}
+}
+
+func TestComposeProject(t *testing.T) {
+	cases := []struct {
+		labels map[string]string
+		want   string
+	}{
+		{map[string]string{"com.docker.compose.project": "shop"}, "shop"},
+		{map[string]string{"io.podman.compose.project": "shop"}, "shop"},
+		{map[string]string{"com.docker.compose.project": "", "io.podman.compose.project": "legacy"}, "legacy"},
+		{nil, ""},
+	}
+	for _, tc := range cases {
+		if got := ComposeProject(tc.labels); got != tc.want {
+			t.Errorf("ComposeProject(%v) = %q, want %q", tc.labels, got, tc.want)
+		}
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"7d", 7 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"36h", 36 * time.Hour, true},
+		{" 90m ", 90 * time.Minute, true},
+		{"106751d", 106751 * 24 * time.Hour, true},
+		{"106752d", 0, false},
+		{"1000000d", 0, false},
+		{"15250w", 15250 * 7 * 24 * time.Hour, true},
+		{"15251w", 0, false},
+		{"0d", 0, false},
+		{"-1h", 0, false},
+		{"d", 0, false},
+		{"", 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := ParseRetention(tc.value)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ParseRetention(%q) = %v, %v; want %v, %v", tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestLineFormatParsesStructuredLines(t *testing.T) {
+	json := LineFormat{Parser: ParserJSON}.Parse(`2026-05-28T05:00:38.367Z {"level":"warn","msg":"slow query","ms":812}`, "stdout")
+	if json.Level != LogLevelWarn || json.Message != "slow query" || json.Fields["ms"] != "812" {
+		t.Fatalf("json entry = %+v, want WARN \"slow query\" with its fields", json)
+	}
+
+	logfmt := LineFormat{Parser: ParserLogfmt}.Parse(`2026-05-28T05:00:38.367Z level=error msg="upstream failed" status=502`, "stderr")
+	if logfmt.Level != LogLevelError || logfmt.Message != "upstream failed" || logfmt.Fields["status"] != "502" {
+		t.Fatalf("logfmt entry = %+v, want ERROR \"upstream failed\" with its fields", logfmt)
+	}
+	if !(FieldFilter{Predicates: []FieldPredicate{{Key: "status", Op: FieldEq, Value: "502"}}}).MatchesEntry(logfmt) {
+		t.Fatal("a field filter does not see the parsed fields")
+	}
+
+	plain := LineFormat{Parser: ParserJSON}.Parse("2026-05-28T05:00:38.367Z ERROR not json at all", "stderr")
+	if want := ParseLogLine("2026-05-28T05:00:38.367Z ERROR not json at all", "stderr"); plain.Message != want.Message || plain.Level != want.Level {
+		t.Fatalf("a non-JSON line under the json parser = %+v, want %+v", plain, want)
+	}
+}
+
+func groupedMessages(format LineFormat, lines ...string) []string {
+	entries := make([]LogEntry, len(lines))
+	for i, line := range lines {
+		entries[i] = ParseLogLine("2026-05-28T05:00:38.367Z "+line, "stderr")
+	}
+	var messages []string
+	for _, entry := range format.Group(entries) {
+		messages = append(messages, strings.ReplaceAll(entry.Message, "\n", " | "))
+	}
+	return messages
+}
+
+func TestMultilinePresetsFoldStackTraces(t *testing.T) {
+	cases := []struct {
+		name   string
+		format LineFormat
+		lines  []string
+		want   []string
+	}{
+		{
+			name:   "java",
+			format: LineFormat{Multiline: MultilineJava},
+			lines: []string{
+				"INFO handling order 7",
+				"java.lang.IllegalStateException: order 7 failed",
+				"at com.shop.Orders.place(Orders.java:42)",
+				"Caused by: java.io.IOException: connection reset",
+				"... 12 more",
+				"INFO handling order 8",
+			},
+			want: []string{
+				"INFO handling order 7 | java.lang.IllegalStateException: order 7 failed | at com.shop.Orders.place(Orders.java:42) | Caused by: java.io.IOException: connection reset | ... 12 more",
+				"INFO handling order 8",
+			},
+		},
+		{
+			name:   "python",
+			format: LineFormat{Multiline: MultilinePython},
+			lines: []string{
+				"ERROR job failed",
+				"Traceback (most recent call last):",
+				`File "/app/jobs.py", line 12, in run`,
+				"total = compute(items)",
+				"ValueError: items is empty",
+				"INFO: next job",
+			},
+			want: []string{
+				`ERROR job failed | Traceback (most recent call last): | File "/app/jobs.py", line 12, in run | total = compute(items) | ValueError: items is empty`,
+				"INFO: next job",
+			},
+		},
+		{
+			name:   "go",
+			format: LineFormat{Multiline: MultilineGo},
+			lines: []string{
+				"panic: runtime error: index out of range",
+				"goroutine 1 [running]:",
+				"main.main()",
+				"/app/main.go:10 +0x1d",
+				"exit status 2",
+				"INFO server restarted",
+			},
+			want: []string{
+				"panic: runtime error: index out of range | goroutine 1 [running]: | main.main() | /app/main.go:10 +0x1d | exit status 2",
+				"INFO server restarted",
+			},
+		},
+	}
+	for _, tc := range cases {
+		got := groupedMessages(tc.format, tc.lines...)
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: grouped into\n%q\nwant\n%q", tc.name, got, tc.want)
+		}
+	}
+
+	// Without the preset the Java trace's classified lines stand alone.
+	if got := groupedMessages(LineFormat{}, "INFO handling order 7", "Caused by: java.io.IOException: connection failed"); len(got) != 2 {
+		t.Fatalf("the default format folded a classified line: %q", got)
+	}
+}
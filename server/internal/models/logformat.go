@@ -0,0 +1,157 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Parsers and multiline presets a LineFormat can name.
+const (
+	ParserJSON   = "json"
+	ParserLogfmt = "logfmt"
+
+	MultilineJava   = "java"
+	MultilinePython = "python"
+	MultilineGo     = "go"
+)
+
+// LineFormat is how one container's lines are read, as its logdeck.parser and
+// logdeck.multiline labels describe them. The zero value guesses line by line,
+// exactly like ParseLogLine and GroupRelatedLogEntries.
+type LineFormat struct {
+	Parser    string
+	Multiline string
+}
+
+// messageKeys are the keys a structured line carries its message under, in the
+// order they are tried.
+var messageKeys = []string{"msg", "message"}
+
+// structuredLevelKeys are the keys a structured line carries its level under.
+var structuredLevelKeys = []string{"level", "lvl", "levelname", "level_name", "severity", "severity_text", "severityText", "log.level"}
+
+// Parse parses one line. A structured parser takes the level and the message
+// from the line's own keys and attaches every key as a field; a line that is
+// not in the named format is parsed like any other.
+func (f LineFormat) Parse(logLine, stream string) LogEntry {
+	entry := ParseLogLine(logLine, stream)
+
+	var fields map[string]string
+	switch f.Parser {
+	case ParserJSON:
+		if strings.HasPrefix(entry.Message, "{") {
+			fields, _ = extractJSONFields(entry.Message)
+		}
+	case ParserLogfmt:
+		fields = extractLogfmtFields(entry.Message)
+	}
+	if len(fields) == 0 {
+		return entry
+	}
+
+	for _, key := range structuredLevelKeys {
+		if level, ok := normalizeLogLevel(fields[key]); ok {
+			entry.Level = level
+			break
+		}
+	}
+	for _, key := range messageKeys {
+		if message := strings.TrimSpace(fields[key]); message != "" {
+			entry.Message = message
+			break
+		}
+	}
+	entry.Fields = fields
+	return entry
+}
+
+// Group folds continuation lines into the entry before them, the way Fold
+// does for one line at a time.
+func (f LineFormat) Group(entries []LogEntry) []LogEntry {
+	grouped := make([]LogEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		if len(grouped) > 0 && f.Fold(&grouped[len(grouped)-1], entry) {
+			continue
+		}
+
+		grouped = append(grouped, entry)
+	}
+
+	return grouped
+}
+
+// Fold appends entry to open, the entry built so far, when it continues it,
+// and reports whether it did.
+func (f LineFormat) Fold(open *LogEntry, entry LogEntry) bool {
+	if !f.Continues(entry, *open) {
+		return false
+	}
+	appendContinuationLine(open, entry)
+	return true
+}
+
+// Continues reports whether entry continues previous. A multiline preset folds
+// its language's stack-trace lines whatever level they were classified as;
+// every format also folds what IsContinuationLogEntry does.
+func (f LineFormat) Continues(entry, previous LogEntry) bool {
+	if continues := multilinePresets[f.Multiline]; continues != nil {
+		message := strings.TrimSpace(entry.Message)
+		if message != "" && strings.TrimSpace(previous.Message) != "" && continues(message, previous.Message) {
+			return true
+		}
+	}
+	return IsContinuationLogEntry(entry, previous)
+}
+
+var (
+	javaFrameRegex     = regexp.MustCompile(`^(at \S|Caused by: |Suppressed: |\.\.\. \d+ (more|common frames omitted)$)`)
+	javaExceptionRegex = regexp.MustCompile(`^([\w$]+\.)+[\w$]*(Exception|Error|Throwable)(: |$)`)
+
+	pythonStartRegex     = regexp.MustCompile(`^(Traceback \(most recent call last\):|File "|During handling of the above exception|The above exception was the direct cause)`)
+	pythonCaretRegex     = regexp.MustCompile(`^[\^~]+$`)
+	pythonExceptionRegex = regexp.MustCompile(`^[A-Za-z_][\w.]*(: |$)`)
+
+	goTraceRegex = regexp.MustCompile(`^(goroutine \d+ \[|created by |\[signal |exit status \d+$)`)
+	goFrameRegex = regexp.MustCompile(`^(\S+\(.*\)|/\S+\.go:\d+( \+0x[0-9a-f]+)?)$`)
+)
+
+// multilinePresets decide, for a line of one language's stack trace, whether
+// it continues the entry built so far. message is trimmed; previous is the
+// whole message of the open entry, one line per folded line.
+var multilinePresets = map[string]func(message, previous string) bool{
+	MultilineJava: func(message, _ string) bool {
+		return javaFrameRegex.MatchString(message) || javaExceptionRegex.MatchString(message)
+	},
+	MultilinePython: func(message, previous string) bool {
+		if pythonStartRegex.MatchString(message) || pythonCaretRegex.MatchString(message) {
+			return true
+		}
+		// The source line under a frame, and the exception closing the
+		// trace, say nothing by themselves: they are known by what they
+		// follow.
+		last, beforeLast := lastLines(previous)
+		if strings.HasPrefix(last, `File "`) {
+			return true
+		}
+		return strings.HasPrefix(beforeLast, `File "`) && pythonExceptionRegex.MatchString(message)
+	},
+	MultilineGo: func(message, previous string) bool {
+		if goTraceRegex.MatchString(message) {
+			return true
+		}
+		// Frames only count inside a goroutine dump; elsewhere "name(args)"
+		// is as likely to be an ordinary line.
+		return strings.Contains(previous, "goroutine ") && goFrameRegex.MatchString(message)
+	},
+}
+
+// lastLines returns the last two lines of a message, trimmed.
+func lastLines(message string) (last, beforeLast string) {
+	lines := strings.Split(message, "\n")
+	last = strings.TrimSpace(lines[len(lines)-1])
+	if len(lines) > 1 {
+		beforeLast = strings.TrimSpace(lines[len(lines)-2])
+	}
+	return last, beforeLast
+}
@@ -17,10 +17,16 @@ type LogEntry struct {
 	Raw               string            `json:"raw"`    // Original log line
 	Fields            map[string]string `json:"fields,omitempty"`
 	ContinuationCount int               `json:"continuationCount,omitempty"`
+	// Set only on a live stream of a container with a logdeck.multiline
+	// label: the line belongs to the entry before it, and the client folds it
+	// there.
+	Continues bool `json:"continues,omitempty"`
 	// Set only on aggregated multi-container streams; omitempty keeps the
 	// single-container payload unchanged.
 	ContainerID   string `json:"containerId,omitempty"`
 	ContainerName string `json:"containerName,omitempty"`
+	// Set only on stored entries, where one query can span several hosts.
+	Host string `json:"host,omitempty"`
 }
 
 // LogLevel represents the severity of a log entry
@@ -321,18 +327,7 @@ func ParseLogLine(logLine string, stream string) LogEntry {
 // logical log event. Docker adds its own timestamp to every physical line, so
 // multi-line app logs can otherwise appear as separate UNKNOWN rows.
 func GroupRelatedLogEntries(entries []LogEntry) []LogEntry {
-	grouped := make([]LogEntry, 0, len(entries))
-
-	for _, entry := range entries {
-		if len(grouped) > 0 && IsContinuationLogEntry(entry, grouped[len(grouped)-1]) {
-			appendContinuationLine(&grouped[len(grouped)-1], entry)
-			continue
-		}
-
-		grouped = append(grouped, entry)
-	}
-
-	return grouped
+	return LineFormat{}.Group(entries)
 }
 
 func IsContinuationLogEntry(entry LogEntry, previous LogEntry) bool {
@@ -0,0 +1,143 @@
+package statsstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// Resolution names a query can ask for.
+const (
+	ResolutionRaw    = "raw"
+	ResolutionMinute = "1m"
+	ResolutionHour   = "1h"
+)
+
+// ErrInvalidResolution is returned for a resolution that is not one of the
+// Resolution names.
+var ErrInvalidResolution = errors.New("resolution must be raw, 1m, or 1h")
+
+// Automatic resolution picks raw samples up to maxRawSpan and minute buckets
+// up to maxMinuteSpan, which keeps a chart to a few thousand points at most.
+const (
+	maxRawSpan    = 2 * time.Hour
+	maxMinuteSpan = 2 * 24 * time.Hour
+)
+
+var resolutionSeconds = map[string]int{
+	ResolutionRaw:    resolutionRaw,
+	ResolutionMinute: resolutionMinute,
+	ResolutionHour:   resolutionHour,
+}
+
+// Query selects one container's stats history.
+type Query struct {
+	Host      string
+	Container string // container name
+	Since     time.Time
+	Until     time.Time // zero means now
+	// Resolution is one of the Resolution names; empty picks the finest one
+	// that still holds the whole range and keeps it chartable.
+	Resolution string
+}
+
+// History is a query's result, oldest point first.
+type History struct {
+	Resolution string
+	Points     []models.ContainerStatsPoint
+}
+
+// History reads a container's stats. Rollup buckets only exist once they are
+// complete, so the range past the newest bucket is filled in from the next
+// finer resolution: a week at hourly resolution still ends at the latest
+// sample.
+func (s *Store) History(ctx context.Context, q Query) (History, error) {
+	now := s.now()
+	if q.Until.IsZero() {
+		q.Until = now
+	}
+	if q.Resolution == "" {
+		q.Resolution = s.autoResolution(q.Since, q.Until, now)
+	}
+	resolution, ok := resolutionSeconds[q.Resolution]
+	if !ok {
+		return History{}, ErrInvalidResolution
+	}
+
+	history := History{Resolution: q.Resolution, Points: []models.ContainerStatsPoint{}}
+	from := q.Since.UnixMilli()
+	until := q.Until.UnixMilli()
+	// Walk from the requested resolution down to raw samples, each covering
+	// the range its coarser neighbour has not rolled up yet.
+	for _, res := range []int{resolutionHour, resolutionMinute, resolutionRaw} {
+		if res > resolution || from >= until {
+			continue
+		}
+		to := until
+		if res != resolutionRaw {
+			done, err := rollupDone(ctx, s.db, res)
+			if err != nil {
+				return History{}, err
+			}
+			to = min(until, done)
+		}
+		points, err := s.points(ctx, q.Host, q.Container, res, from, to)
+		if err != nil {
+			return History{}, err
+		}
+		history.Points = append(history.Points, points...)
+		from = max(from, to)
+	}
+	return history, nil
+}
+
+// autoResolution picks the finest resolution whose span limit and retention
+// period both cover the range.
+func (s *Store) autoResolution(since, until, now time.Time) string {
+	settings := s.settings()
+	span := until.Sub(since)
+	switch {
+	case span <= maxRawSpan && !since.Before(now.Add(-time.Duration(settings.RawHours)*time.Hour)):
+		return ResolutionRaw
+	case span <= maxMinuteSpan && !since.Before(now.Add(-time.Duration(settings.MinuteDays)*24*time.Hour)):
+		return ResolutionMinute
+	default:
+		return ResolutionHour
+	}
+}
+
+// points reads one resolution's rows in [fromMS, toMS). A bucket is included
+// when it starts in the range, so the range's start is aligned down to the
+// bucket holding it.
+func (s *Store) points(ctx context.Context, host, name string, resolution int, fromMS, toMS int64) ([]models.ContainerStatsPoint, error) {
+	if resolution > 0 {
+		width := int64(resolution) * 1000
+		fromMS = fromMS / width * width
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT ts_ms, samples, cpu_avg, cpu_max, mem_avg, mem_max, mem_limit,
+			net_rx, net_tx, blk_read, blk_write
+		FROM samples
+		WHERE host = ? AND name = ? AND resolution = ? AND ts_ms >= ? AND ts_ms < ?
+		ORDER BY ts_ms`, host, name, resolution, fromMS, toMS)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []models.ContainerStatsPoint
+	for rows.Next() {
+		var (
+			p                        models.ContainerStatsPoint
+			memAvg, memMax, memLimit int64
+		)
+		if err := rows.Scan(&p.Timestamp, &p.Samples, &p.CPUPercent, &p.CPUPercentMax, &memAvg, &memMax, &memLimit,
+			&p.NetworkRxPerSec, &p.NetworkTxPerSec, &p.BlockReadPerSec, &p.BlockWritePerSec); err != nil {
+			return nil, err
+		}
+		p.MemoryUsed, p.MemoryUsedMax, p.MemoryLimit = uint64(memAvg), uint64(memMax), uint64(memLimit)
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
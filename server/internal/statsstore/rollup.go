@@ -0,0 +1,112 @@
+package statsstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Resolutions, as stored in samples.resolution: bucket widths in seconds.
+const (
+	resolutionRaw    = 0
+	resolutionMinute = 60
+	resolutionHour   = 3600
+)
+
+// rollupLevels lists each rollup with the resolution it summarizes. Order
+// matters: the hour rollup reads minute buckets the minute rollup has just
+// written.
+var rollupLevels = []struct{ from, to int }{
+	{resolutionRaw, resolutionMinute},
+	{resolutionMinute, resolutionHour},
+}
+
+// maintain writes every bucket that has completed since the last pass, then
+// deletes each resolution past its retention period.
+func (s *Store) maintain(ctx context.Context, now time.Time) error {
+	for _, level := range rollupLevels {
+		if err := s.rollup(ctx, level.from, level.to, now); err != nil {
+			return err
+		}
+	}
+	return s.retain(ctx, now)
+}
+
+// rollup summarizes the from-resolution rows of every complete to-resolution
+// bucket that has not been written yet. Averages are weighted by the raw
+// samples each source row summarizes; peaks are the maximum of the peaks.
+func (s *Store) rollup(ctx context.Context, from, to int, now time.Time) error {
+	widthMS := int64(to) * 1000
+	// Only complete buckets: the one now falls in is still filling.
+	end := now.UnixMilli() / widthMS * widthMS
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	done, err := rollupDone(ctx, tx, to)
+	if err != nil {
+		return err
+	}
+	if done >= end {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO samples
+		(resolution, host, name, ts_ms, samples, cpu_avg, cpu_max, mem_avg, mem_max, mem_limit, net_rx, net_tx, blk_read, blk_write)
+		SELECT ?, host, name, ts_ms / ? * ? AS bucket, SUM(samples),
+			SUM(cpu_avg * samples) / SUM(samples), MAX(cpu_max),
+			SUM(mem_avg * samples) / SUM(samples), MAX(mem_max), MAX(mem_limit),
+			SUM(net_rx * samples) / SUM(samples), SUM(net_tx * samples) / SUM(samples),
+			SUM(blk_read * samples) / SUM(samples), SUM(blk_write * samples) / SUM(samples)
+		FROM samples
+		WHERE resolution = ? AND ts_ms >= ? AND ts_ms < ?
+		GROUP BY host, name, bucket`,
+		to, widthMS, widthMS, from, done, end); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO rollups (resolution, done_ms) VALUES (?, ?)", to, end); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryer is the slice of *sql.DB and *sql.Tx rollupDone reads through.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// rollupDone reports where the rollup to resolution stopped: 0 before its
+// first pass.
+func rollupDone(ctx context.Context, q queryer, resolution int) (int64, error) {
+	var done int64
+	err := q.QueryRowContext(ctx, "SELECT done_ms FROM rollups WHERE resolution = ?", resolution).Scan(&done)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return done, err
+}
+
+// retain deletes the rows of each resolution that are older than its
+// retention period.
+func (s *Store) retain(ctx context.Context, now time.Time) error {
+	settings := s.settings()
+	for _, r := range []struct {
+		resolution int
+		keep       time.Duration
+	}{
+		{resolutionRaw, time.Duration(settings.RawHours) * time.Hour},
+		{resolutionMinute, time.Duration(settings.MinuteDays) * 24 * time.Hour},
+		{resolutionHour, time.Duration(settings.HourDays) * 24 * time.Hour},
+	} {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM samples WHERE resolution = ? AND ts_ms < ?",
+			r.resolution, now.Add(-r.keep).UnixMilli()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
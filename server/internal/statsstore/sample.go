@@ -0,0 +1,130 @@
+package statsstore
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// sampleRow is one raw sample as stored.
+type sampleRow struct {
+	host, name string
+	sample     *docker.StatsSample
+	netRx      float64
+	netTx      float64
+	blkRead    float64
+	blkWrite   float64
+}
+
+// samplePass reads every running container's stats and stores them as raw
+// samples timestamped now. A container whose reading fails is skipped for
+// this pass; an unreachable host only costs its own containers.
+func (s *Store) samplePass(ctx context.Context, engine Engine, now time.Time) error {
+	listCtx, cancel := context.WithTimeout(ctx, listTimeout)
+	containersMap, _, err := engine.ListContainersAllHosts(listCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	type target struct{ host, id, name string }
+	var targets []target
+	for host, containers := range containersMap {
+		for _, c := range containers {
+			if c.State != "running" || len(c.Names) == 0 {
+				continue
+			}
+			targets = append(targets, target{host, c.ID, strings.TrimPrefix(c.Names[0], "/")})
+		}
+	}
+
+	samples := make([]*docker.StatsSample, len(targets))
+	sem := make(chan struct{}, maxConcurrentSamples)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			sampleCtx, cancel := context.WithTimeout(ctx, sampleTimeout)
+			defer cancel()
+			if sample, err := engine.SampleContainerStats(sampleCtx, t.host, t.id); err == nil {
+				samples[i] = sample
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	seen := make(map[genKey]*docker.StatsSample, len(targets))
+	rows := make([]sampleRow, 0, len(targets))
+	for i, t := range targets {
+		sample := samples[i]
+		if sample == nil {
+			continue
+		}
+		key := genKey{t.host, t.id}
+		seen[key] = sample
+		row := sampleRow{host: t.host, name: t.name, sample: sample}
+		if prev := s.prev[key]; prev != nil {
+			if elapsed := sample.ReadAt.Sub(prev.ReadAt).Seconds(); elapsed > 0 {
+				row.netRx = docker.CounterRate(prev.NetRxBytes, sample.NetRxBytes, elapsed)
+				row.netTx = docker.CounterRate(prev.NetTxBytes, sample.NetTxBytes, elapsed)
+				row.blkRead = docker.CounterRate(prev.BlockReadBytes, sample.BlockReadBytes, elapsed)
+				row.blkWrite = docker.CounterRate(prev.BlockWriteBytes, sample.BlockWriteBytes, elapsed)
+			}
+		}
+		rows = append(rows, row)
+	}
+	// Containers that stopped or failed to read start over: their next
+	// sample has nothing to be diffed against.
+	s.prev = seen
+
+	latest := make([]models.ContainerStats, 0, len(rows))
+	for _, r := range rows {
+		st := r.sample.ContainerStats
+		st.NetworkRxPerSec, st.NetworkTxPerSec = r.netRx, r.netTx
+		st.BlockReadPerSec, st.BlockWritePerSec = r.blkRead, r.blkWrite
+		latest = append(latest, st)
+	}
+	s.setLatest(latest)
+
+	return s.insertSamples(ctx, now, rows)
+}
+
+func (s *Store) insertSamples(ctx context.Context, now time.Time, rows []sampleRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO samples
+		(resolution, host, name, ts_ms, samples, cpu_avg, cpu_max, mem_avg, mem_max, mem_limit, net_rx, net_tx, blk_read, blk_write)
+		VALUES (0, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	ts := now.UnixMilli()
+	for _, r := range rows {
+		st := r.sample.ContainerStats
+		if _, err := stmt.ExecContext(ctx, r.host, r.name, ts,
+			st.CPUPercent, st.CPUPercent, int64(st.MemoryUsed), int64(st.MemoryUsed), int64(st.MemoryLimit),
+			r.netRx, r.netTx, r.blkRead, r.blkWrite); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
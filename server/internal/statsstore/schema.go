@@ -0,0 +1,82 @@
+package statsstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// schemaVersion is the current schema generation, tracked in PRAGMA
+// user_version. Bump it and add a migration step when the schema changes.
+const schemaVersion = 1
+
+// schemaV1 is the initial schema.
+//
+// One table holds every resolution: a raw sample is a row with resolution 0,
+// and a rollup bucket is a row whose resolution is the bucket width in
+// seconds, timestamped at the bucket's start. A row keeps the average and the
+// peak of CPU and memory, so a one-hour bucket still shows the spike that
+// preceded an OOM kill; I/O rates are averages. Rows are keyed by container
+// name rather than engine ID, so a recreated container keeps one timeline.
+const schemaV1 = `
+CREATE TABLE samples (
+  resolution INTEGER NOT NULL, -- bucket width in seconds, 0 for a raw sample
+  host       TEXT NOT NULL,
+  name       TEXT NOT NULL,
+  ts_ms      INTEGER NOT NULL,
+  samples    INTEGER NOT NULL, -- raw samples the row summarizes
+  cpu_avg    REAL NOT NULL,
+  cpu_max    REAL NOT NULL,
+  mem_avg    INTEGER NOT NULL,
+  mem_max    INTEGER NOT NULL,
+  mem_limit  INTEGER NOT NULL,
+  net_rx     REAL NOT NULL, -- bytes per second
+  net_tx     REAL NOT NULL,
+  blk_read   REAL NOT NULL,
+  blk_write  REAL NOT NULL,
+  PRIMARY KEY (host, name, resolution, ts_ms)
+) WITHOUT ROWID;
+CREATE INDEX samples_resolution_ts ON samples(resolution, ts_ms);
+
+-- rollups records, per rollup resolution, where the last pass stopped: every
+-- bucket starting before done_ms has been written.
+CREATE TABLE rollups (
+  resolution INTEGER PRIMARY KEY,
+  done_ms    INTEGER NOT NULL
+);
+`
+
+// initSchema creates the schema on a fresh database and is a no-op on an
+// already-current one. Unknown (newer) versions are rejected rather than
+// silently downgraded.
+func initSchema(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	switch {
+	case version == schemaVersion:
+		return nil
+	case version > schemaVersion:
+		return fmt.Errorf("stats history schema version %d is newer than supported version %d", version, schemaVersion)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if version < 1 {
+		if _, err := tx.ExecContext(ctx, schemaV1); err != nil {
+			return fmt.Errorf("create schema: %w", err)
+		}
+	}
+
+	// PRAGMA does not accept bound parameters.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+	return tx.Commit()
+}
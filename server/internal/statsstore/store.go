@@ -0,0 +1,212 @@
+// Package statsstore records container resource usage to a local SQLite
+// database so CPU, memory, network, and block I/O history survives page
+// reloads and logdeck restarts.
+//
+// A sampler reads every running container's stats on a fixed interval and
+// stores them as raw samples. A maintenance pass rolls raw samples up into
+// one-minute buckets and those into one-hour buckets, then deletes each
+// resolution once it is older than its retention period, so the last day is
+// kept at full resolution and the last quarter at hourly.
+package statsstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+
+	_ "modernc.org/sqlite" // pure-Go driver: every release build is CGO_ENABLED=0
+)
+
+const (
+	// listTimeout bounds one container listing; an unreachable host must not
+	// stall the sampler.
+	listTimeout = 15 * time.Second
+	// sampleTimeout bounds one container's stats reading.
+	sampleTimeout = 10 * time.Second
+	// maxConcurrentSamples bounds the stats requests in flight per pass.
+	maxConcurrentSamples = 8
+	// maintainInterval is how often rollups and retention run.
+	maintainInterval = time.Minute
+)
+
+// Engine is the slice of *docker.MultiHostClient the sampler needs.
+type Engine interface {
+	ListContainersAllHosts(ctx context.Context) (map[string][]models.ContainerInfo, []docker.HostError, error)
+	SampleContainerStats(ctx context.Context, host, containerID string) (*docker.StatsSample, error)
+}
+
+// DockerProvider yields the current Docker client set; reading through it on
+// every pass keeps the sampler correct across hot-swapped config.
+type DockerProvider interface {
+	Docker() *docker.MultiHostClient
+}
+
+// Settings supplies the sampling interval and retention periods, re-read on
+// every pass so config changes take effect without a restart.
+type Settings func() config.ResolvedStatsHistoryConfig
+
+// Store owns the SQLite database and the sampler.
+type Store struct {
+	db       *sql.DB
+	settings Settings
+	now      func() time.Time
+
+	// prev holds each container generation's previous sample, which the
+	// current one's I/O counters are diffed against. Only the sampler
+	// goroutine touches it.
+	prev map[genKey]*docker.StatsSample
+
+	// latest is the last pass's reading of every running container, as
+	// stored; it serves readers that want current stats without asking
+	// every engine again.
+	mu     sync.Mutex
+	latest []models.ContainerStats
+
+	workers sync.WaitGroup
+}
+
+// genKey identifies one engine container.
+type genKey struct {
+	host, id string
+}
+
+// DBPath returns the stats database path that sits next to the config file.
+func DBPath(configFilePath string) string {
+	return filepath.Join(filepath.Dir(configFilePath), "stats.db")
+}
+
+// OpenFromConfig prepares the stats history from the manager's settings. Like
+// the log store it never aborts startup: a disabled history creates no
+// database file, and an unusable path logs a warning. A nil return means "no
+// stats history".
+func OpenFromConfig(manager *config.Manager) *Store {
+	settings := manager.StatsHistory()
+	if !settings.Enabled {
+		log.Println("Container stats history is DISABLED")
+		return nil
+	}
+
+	path := DBPath(manager.ConfigFilePath())
+	store, err := Open(path, manager.StatsHistory)
+	if err != nil {
+		log.Printf("Warning: container stats history is DISABLED: %v", err)
+		return nil
+	}
+
+	log.Printf("Container stats history is ENABLED (%s, sampled every %ds)", path, settings.IntervalSeconds)
+	return store
+}
+
+// Open prepares the SQLite database at path and initializes the schema.
+func Open(path string, settings Settings) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("create stats history directory: %w", err)
+	}
+
+	dsn := fmt.Sprintf(
+		"file:%s?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)&_txlock=immediate",
+		path,
+	)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open stats history: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open stats history: %w", err)
+	}
+	if err := initSchema(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{
+		db:       db,
+		settings: settings,
+		now:      time.Now,
+		prev:     make(map[genKey]*docker.StatsSample),
+	}, nil
+}
+
+// Start runs the sampler until ctx is cancelled. Use Wait to block until it
+// has stopped.
+func (s *Store) Start(ctx context.Context, provider DockerProvider) {
+	s.start(ctx, func() Engine { return provider.Docker() })
+}
+
+// start is the injectable form of Start; tests supply fakes.
+func (s *Store) start(ctx context.Context, source func() Engine) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		s.sampleLoop(ctx, source)
+	}()
+}
+
+// sampleLoop takes a sample pass every interval and runs maintenance every
+// maintainInterval. The interval is re-read after every pass.
+func (s *Store) sampleLoop(ctx context.Context, source func() Engine) {
+	var lastMaintain time.Time
+	for {
+		settings := s.settings()
+		if settings.Enabled {
+			now := s.now()
+			if err := s.samplePass(ctx, source(), now); err != nil && ctx.Err() == nil {
+				log.Printf("statsstore: sampling failed: %v", err)
+			}
+			if now.Sub(lastMaintain) >= maintainInterval {
+				if err := s.maintain(ctx, now); err != nil && ctx.Err() == nil {
+					log.Printf("statsstore: rollup and retention failed: %v", err)
+				}
+				lastMaintain = now
+			}
+		} else {
+			s.setLatest(nil)
+		}
+
+		timer := time.NewTimer(time.Duration(settings.IntervalSeconds) * time.Second)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Latest returns the newest sample of every container that was running at the
+// last sample pass, with the I/O rates stored for it. It is empty while
+// sampling is off.
+func (s *Store) Latest() []models.ContainerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest
+}
+
+func (s *Store) setLatest(latest []models.ContainerStats) {
+	s.mu.Lock()
+	s.latest = latest
+	s.mu.Unlock()
+}
+
+// Wait blocks until the sampler has stopped.
+func (s *Store) Wait() {
+	s.workers.Wait()
+}
+
+// Close releases the database. Call it after Wait.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
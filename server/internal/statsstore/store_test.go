@@ -0,0 +1,229 @@
+package statsstore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/config"
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+var t0 = time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+
+func testSettings() config.ResolvedStatsHistoryConfig {
+	return config.ResolvedStatsHistoryConfig{
+		Enabled:         true,
+		IntervalSeconds: 15,
+		RawHours:        24,
+		MinuteDays:      7,
+		HourDays:        90,
+	}
+}
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "stats.db"), testSettings)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	store.now = func() time.Time { return t0 }
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// fakeEngine serves one host's containers and the next stats reading of each.
+type fakeEngine struct {
+	mu         sync.Mutex
+	containers []models.ContainerInfo
+	samples    map[string]*docker.StatsSample
+}
+
+func (f *fakeEngine) ListContainersAllHosts(context.Context) (map[string][]models.ContainerInfo, []docker.HostError, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return map[string][]models.ContainerInfo{"local": f.containers}, nil, nil
+}
+
+func (f *fakeEngine) SampleContainerStats(_ context.Context, host, id string) (*docker.StatsSample, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sample, ok := f.samples[id]
+	if !ok {
+		return nil, errors.New("no such container")
+	}
+	return sample, nil
+}
+
+func reading(cpu float64, mem, netRx, blkWrite uint64, at time.Time) *docker.StatsSample {
+	return &docker.StatsSample{
+		ContainerStats:  models.ContainerStats{CPUPercent: cpu, MemoryUsed: mem, MemoryLimit: 1000},
+		NetRxBytes:      netRx,
+		BlockWriteBytes: blkWrite,
+		ReadAt:          at,
+	}
+}
+
+// insert stores one raw sample for web directly.
+func insert(t *testing.T, s *Store, at time.Time, cpu float64, mem uint64) {
+	t.Helper()
+	row := sampleRow{host: "local", name: "web", sample: reading(cpu, mem, 0, 0, at)}
+	if err := s.insertSamples(context.Background(), at, []sampleRow{row}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func history(t *testing.T, s *Store, q Query) History {
+	t.Helper()
+	q.Host, q.Container = "local", "web"
+	h, err := s.History(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestSamplePassComputesIORates(t *testing.T) {
+	s := newTestStore(t)
+	engine := &fakeEngine{
+		containers: []models.ContainerInfo{
+			{ID: "c1", Names: []string{"/web"}, State: "running"},
+			{ID: "c2", Names: []string{"/old"}, State: "exited"},
+		},
+		samples: map[string]*docker.StatsSample{"c1": reading(10, 100, 1000, 500, t0)},
+	}
+	ctx := context.Background()
+	if err := s.samplePass(ctx, engine, t0); err != nil {
+		t.Fatal(err)
+	}
+	engine.samples["c1"] = reading(30, 300, 4000, 2500, t0.Add(10*time.Second))
+	if err := s.samplePass(ctx, engine, t0.Add(10*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	// The latest pass is kept as stored, rates included, for /metrics.
+	if latest := s.Latest(); len(latest) != 1 || latest[0].CPUPercent != 30 || latest[0].NetworkRxPerSec != 300 {
+		t.Errorf("latest = %+v, want web's second sample with its rate", latest)
+	}
+	// A restart resets the counters; that interval has no rate.
+	engine.samples["c1"] = reading(5, 50, 10, 10, t0.Add(20*time.Second))
+	if err := s.samplePass(ctx, engine, t0.Add(20*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	h := history(t, s, Query{Since: t0, Until: t0.Add(time.Minute)})
+	if h.Resolution != ResolutionRaw || len(h.Points) != 3 {
+		t.Fatalf("history = %s with %d points, want 3 raw samples", h.Resolution, len(h.Points))
+	}
+	if p := h.Points[0]; p.NetworkRxPerSec != 0 || p.CPUPercent != 10 || p.MemoryUsed != 100 {
+		t.Errorf("first sample = %+v, want no rate and the reading", p)
+	}
+	if p := h.Points[1]; p.NetworkRxPerSec != 300 || p.BlockWritePerSec != 200 {
+		t.Errorf("second sample = %+v, want 300 B/s received and 200 B/s written", p)
+	}
+	if p := h.Points[2]; p.NetworkRxPerSec != 0 || p.BlockWritePerSec != 0 {
+		t.Errorf("sample after a counter reset = %+v, want no rate", p)
+	}
+}
+
+func TestRollupAveragesAndKeepsPeaks(t *testing.T) {
+	s := newTestStore(t)
+	// Two minutes of samples, one spike in the first.
+	for i, cpu := range []float64{10, 90, 20, 40, 40, 40, 40, 40} {
+		insert(t, s, t0.Add(time.Duration(i)*15*time.Second), cpu, uint64(100*(i+1)))
+	}
+	now := t0.Add(2*time.Minute + 5*time.Second)
+	if err := s.maintain(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.points(context.Background(), "local", "web", resolutionMinute, 0, now.UnixMilli())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("minute buckets = %+v, want 2", got)
+	}
+	if b := got[0]; b.Timestamp != t0.UnixMilli() || b.Samples != 4 || b.CPUPercent != 40 || b.CPUPercentMax != 90 || b.MemoryUsed != 250 || b.MemoryUsedMax != 400 {
+		t.Errorf("first bucket = %+v, want 4 samples averaging 40%% CPU and 250 B with peaks 90%% and 400 B", b)
+	}
+
+	// A second pass inside the same minute writes nothing new, and the hour
+	// bucket waits for the hour to end.
+	if err := s.maintain(context.Background(), now.Add(10*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if hours, _ := s.points(context.Background(), "local", "web", resolutionHour, 0, now.Add(time.Hour).UnixMilli()); len(hours) != 0 {
+		t.Fatalf("hour buckets before the hour ended = %+v", hours)
+	}
+	if err := s.maintain(context.Background(), t0.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	hours, _ := s.points(context.Background(), "local", "web", resolutionHour, 0, t0.Add(2*time.Hour).UnixMilli())
+	if len(hours) != 1 || hours[0].Samples != 8 || hours[0].CPUPercentMax != 90 || hours[0].CPUPercent != 40 {
+		t.Fatalf("hour buckets = %+v, want one of 8 samples averaging 40%% with a 90%% peak", hours)
+	}
+}
+
+func TestRetentionPerResolution(t *testing.T) {
+	s := newTestStore(t)
+	insert(t, s, t0, 10, 100)
+	insert(t, s, t0.Add(2*24*time.Hour), 10, 100)
+	ctx := context.Background()
+	if err := s.maintain(ctx, t0.Add(2*24*time.Hour+time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first raw sample is past 24 hours, its rollups are not.
+	count := func(resolution int) int {
+		var n int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM samples WHERE resolution = ?", resolution).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if raw, minutes, hours := count(resolutionRaw), count(resolutionMinute), count(resolutionHour); raw != 1 || minutes != 2 || hours != 1 {
+		t.Fatalf("rows = %d raw, %d minute, %d hour; want 1, 2, 1", raw, minutes, hours)
+	}
+}
+
+func TestHistoryPicksResolutionAndFillsTheTail(t *testing.T) {
+	s := newTestStore(t)
+	start := t0.Add(-3 * time.Hour)
+	for at := start; at.Before(t0); at = at.Add(time.Minute) {
+		insert(t, s, at, 50, 100)
+	}
+	// Maintenance last ran at 11:30: hours up to 11:00 and minutes up to
+	// 11:30 are rolled up, the rest is raw.
+	if err := s.maintain(context.Background(), t0.Add(-30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if h := history(t, s, Query{Since: t0.Add(-time.Hour)}); h.Resolution != ResolutionRaw || len(h.Points) != 60 {
+		t.Errorf("last hour = %s with %d points, want 60 raw", h.Resolution, len(h.Points))
+	}
+
+	h := history(t, s, Query{Since: start, Resolution: ResolutionHour})
+	// 09:00 and 10:00 hourly, 11:00-11:29 by the minute, then raw samples.
+	if len(h.Points) != 2+30+30 {
+		t.Fatalf("hourly history has %d points, want 62", len(h.Points))
+	}
+	if h.Points[0].Samples != 60 || h.Points[2].Timestamp != t0.Add(-time.Hour).UnixMilli() {
+		t.Errorf("points = %+v..., want two full hours then minutes from 11:00", h.Points[:3])
+	}
+	for i := 1; i < len(h.Points); i++ {
+		if h.Points[i].Timestamp <= h.Points[i-1].Timestamp {
+			t.Fatalf("points out of order at %d", i)
+		}
+	}
+
+	if h := history(t, s, Query{Since: t0.Add(-10 * 24 * time.Hour)}); h.Resolution != ResolutionHour {
+		t.Errorf("ten days resolved to %s, want 1h", h.Resolution)
+	}
+	if _, err := s.History(context.Background(), Query{Resolution: "5m"}); !errors.Is(err, ErrInvalidResolution) {
+		t.Errorf("5m: err = %v, want ErrInvalidResolution", err)
+	}
+}
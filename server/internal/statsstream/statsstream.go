@@ -0,0 +1,396 @@
+// Package statsstream provides a shared hub that streams container stats to
+// any number of live viewers. Instead of one one-shot stats request per
+// container per viewer per poll, the hub keeps a single streaming stats
+// subscription per running container that some viewer wants, and fans each
+// reading out to every subscription whose filter selects it. Streams only
+// run while a subscription wants them, so an idle server costs the engine
+// nothing.
+package statsstream
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+const (
+	defaultListTimeout    = 15 * time.Second
+	defaultResyncInterval = 60 * time.Second
+	defaultRetryBaseDelay = 2 * time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// DockerProvider yields the current Docker client set; reading through it on
+// every use keeps the hub correct across hot-swapped config updates.
+type DockerProvider interface {
+	Docker() *docker.MultiHostClient
+}
+
+type containerKey struct {
+	host string
+	id   string
+}
+
+// listResult is a container snapshot posted back to the run loop by the
+// single-flight listing goroutine.
+type listResult struct {
+	snapshot map[string][]models.ContainerInfo
+	hostErrs []docker.HostError
+	err      error
+}
+
+// reading is one stats reading posted to the run loop by a stream goroutine.
+type reading struct {
+	key   containerKey
+	st    *stream
+	stats models.ContainerStats
+}
+
+// streamExit notifies the run loop that a stream goroutine has stopped.
+type streamExit struct {
+	key containerKey
+	st  *stream
+}
+
+// Hub owns the container stats streams shared by all subscriptions. The run
+// loop is the single owner of all subscription, stream, and reading state.
+type Hub struct {
+	source func() engineClient
+
+	reqCh        chan func()
+	pokeCh       chan struct{}
+	listCh       chan listResult
+	readingCh    chan reading
+	streamExitCh chan streamExit
+	stopped      chan struct{} // closed when the hub stops accepting requests
+	finished     chan struct{} // closed when Run has fully drained
+
+	listTimeout    time.Duration
+	resyncInterval time.Duration
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+
+	// State below is owned exclusively by the run loop goroutine.
+	runCtx       context.Context
+	subs         map[*Subscription]struct{}
+	running      map[containerKey]bool
+	streams      map[containerKey]*stream
+	latest       map[containerKey]models.ContainerStats
+	events       <-chan docker.EngineEvent
+	eventsCancel context.CancelFunc
+	eventsClient engineClient
+	listInFlight bool
+	listQueued   bool
+	streamWg     sync.WaitGroup
+}
+
+// New creates a hub that streams stats through the provider's current Docker
+// client set.
+func New(provider DockerProvider) *Hub {
+	return newHub(func() engineClient { return dockerAdapter{c: provider.Docker()} })
+}
+
+// newHub builds a hub over an arbitrary client source; tests inject fakes
+// here. source must return comparable values so client swaps are detectable.
+func newHub(source func() engineClient) *Hub {
+	return &Hub{
+		source:         source,
+		reqCh:          make(chan func()),
+		pokeCh:         make(chan struct{}, 1),
+		listCh:         make(chan listResult, 1),
+		readingCh:      make(chan reading, 64),
+		streamExitCh:   make(chan streamExit),
+		stopped:        make(chan struct{}),
+		finished:       make(chan struct{}),
+		listTimeout:    defaultListTimeout,
+		resyncInterval: defaultResyncInterval,
+		retryBaseDelay: defaultRetryBaseDelay,
+		retryMaxDelay:  defaultRetryMaxDelay,
+		subs:           make(map[*Subscription]struct{}),
+		running:        make(map[containerKey]bool),
+		streams:        make(map[containerKey]*stream),
+		latest:         make(map[containerKey]models.ContainerStats),
+	}
+}
+
+// do runs fn on the run loop goroutine. It returns false (without running fn)
+// once the hub has begun shutting down.
+func (h *Hub) do(fn func()) bool {
+	select {
+	case h.reqCh <- fn:
+		return true
+	case <-h.stopped:
+		return false
+	}
+}
+
+// Subscribe registers a subscription for the containers filter selects. The
+// latest known reading of each is waiting on it straight away; fresh ones
+// follow as the engine reports them (about once a second). Call Close when
+// done. Subscribe blocks until the hub's run loop is started; after shutdown
+// it returns a subscription that is already done.
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	sub := newSubscription(h, filter)
+	registered := h.do(func() {
+		h.subs[sub] = struct{}{}
+		for key, stats := range h.latest {
+			if filter.matches(key) {
+				sub.push(key, models.ContainerStatsEvent{ContainerStats: stats})
+			}
+		}
+		h.converge()
+		h.requestList()
+	})
+	if !registered {
+		sub.end()
+	}
+	return sub
+}
+
+// removeSub runs on the loop: it drops the subscription and stops the
+// streams nobody wants any more.
+func (h *Hub) removeSub(sub *Subscription) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	sub.end()
+	h.converge()
+}
+
+// Reconcile pokes the run loop to re-list containers and converge streams.
+// Non-blocking; pokes coalesce. Called on config reloads.
+func (h *Hub) Reconcile() {
+	select {
+	case h.pokeCh <- struct{}{}:
+	default:
+	}
+}
+
+// Run drives the hub until ctx is cancelled. It is typically invoked in its
+// own goroutine; use Wait to block until every stream has fully stopped.
+func (h *Hub) Run(ctx context.Context) {
+	defer close(h.finished)
+	h.runCtx = ctx
+
+	h.openEventStream(h.source())
+
+	ticker := time.NewTicker(h.resyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.shutdown()
+			return
+		case fn := <-h.reqCh:
+			fn()
+		case <-h.pokeCh:
+			h.requestList()
+		case <-ticker.C:
+			// Nobody is watching: the next subscription lists afresh.
+			if len(h.subs) > 0 {
+				h.requestList()
+			}
+		case ev, ok := <-h.events:
+			if !ok {
+				// All host watchers stopped (e.g. the client was closed
+				// after a hot swap). Re-list; handleList reopens the stream.
+				h.events = nil
+				h.requestList()
+				continue
+			}
+			h.handleEvent(ev)
+		case res := <-h.listCh:
+			h.handleList(res)
+		case r := <-h.readingCh:
+			h.handleReading(r)
+		case ex := <-h.streamExitCh:
+			if h.streams[ex.key] == ex.st {
+				delete(h.streams, ex.key)
+			}
+		}
+	}
+}
+
+// Wait blocks until the hub has shut down: Run returned and all streams have
+// stopped.
+func (h *Hub) Wait() {
+	<-h.finished
+}
+
+// openEventStream (re)opens the engine event stream on client under a child
+// context of the run context.
+func (h *Hub) openEventStream(client engineClient) {
+	ctx, cancel := context.WithCancel(h.runCtx)
+	h.eventsCancel = cancel
+	h.events = client.streamEvents(ctx)
+	h.eventsClient = client
+}
+
+// requestList starts a single-flight container listing; pokes arriving while
+// one is in flight coalesce into at most one follow-up listing.
+func (h *Hub) requestList() {
+	if h.listInFlight {
+		h.listQueued = true
+		return
+	}
+	h.listInFlight = true
+
+	client := h.source()
+	runCtx := h.runCtx
+	timeout := h.listTimeout
+	go func() {
+		ctx, cancel := context.WithTimeout(runCtx, timeout)
+		defer cancel()
+		snapshot, hostErrs, err := client.listContainers(ctx)
+		select {
+		case h.listCh <- listResult{snapshot: snapshot, hostErrs: hostErrs, err: err}:
+		case <-runCtx.Done():
+		}
+	}()
+}
+
+// handleEvent is the fast path: start streams a wanted container at once,
+// die and destroy stop its stream and tell subscribers it is gone.
+func (h *Hub) handleEvent(ev docker.EngineEvent) {
+	base, _, _ := strings.Cut(ev.Action, ": ")
+	key := containerKey{host: ev.Host, id: ev.ContainerID}
+	switch base {
+	case "start":
+		h.running[key] = true
+		h.converge()
+	case "die", "destroy":
+		h.forget(key)
+	}
+}
+
+// forget drops a container that is no longer running. Subscriptions that
+// had a reading of it are told it is gone.
+func (h *Hub) forget(key containerKey) {
+	delete(h.running, key)
+	_, hadReading := h.latest[key]
+	h.converge()
+	if !hadReading {
+		return
+	}
+	removed := models.ContainerStatsEvent{
+		ContainerStats: models.ContainerStats{ID: key.id, Host: key.host},
+		Removed:        true,
+	}
+	for sub := range h.subs {
+		if sub.filter.matches(key) {
+			sub.push(key, removed)
+		}
+	}
+}
+
+// handleList refreshes the running set from a fresh container snapshot.
+// Hosts that failed to list keep their containers untouched: a genuinely dead
+// host's streams exit on their own and are retried by the next resync.
+func (h *Hub) handleList(res listResult) {
+	h.listInFlight = false
+	defer func() {
+		if h.listQueued {
+			h.listQueued = false
+			h.requestList()
+		}
+	}()
+
+	// Hot-swap check: if the provider's client changed since the event stream
+	// was opened (or the stream closed), reopen it on the current client.
+	if current := h.source(); h.events == nil || current != h.eventsClient {
+		h.eventsCancel()
+		h.openEventStream(current)
+	}
+
+	if res.err != nil {
+		log.Printf("statsstream: container listing failed: %v", res.err)
+		return
+	}
+	for _, hostErr := range res.hostErrs {
+		log.Printf("statsstream: listing containers on host %s failed: %v", hostErr.HostName, hostErr.Err)
+	}
+
+	running := make(map[containerKey]bool)
+	for host, containers := range res.snapshot {
+		for _, ctr := range containers {
+			if ctr.State == "running" {
+				running[containerKey{host: host, id: ctr.ID}] = true
+			}
+		}
+	}
+	for key := range h.running {
+		if _, listed := res.snapshot[key.host]; listed && !running[key] {
+			h.forget(key)
+		}
+	}
+	for key := range running {
+		h.running[key] = true
+	}
+	h.converge()
+}
+
+// wanted reports whether any subscription selects the container.
+func (h *Hub) wanted(key containerKey) bool {
+	for sub := range h.subs {
+		if sub.filter.matches(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// converge stops the streams of containers that stopped or nobody wants and
+// starts one for every running container somebody does.
+func (h *Hub) converge() {
+	for key, st := range h.streams {
+		if h.running[key] && h.wanted(key) {
+			continue
+		}
+		st.cancel()
+		delete(h.streams, key)
+		// An unwatched reading would be stale by the time anyone asks.
+		delete(h.latest, key)
+	}
+	for key := range h.running {
+		if _, ok := h.streams[key]; !ok && h.wanted(key) {
+			h.spawnStream(key)
+		}
+	}
+}
+
+// handleReading records a container's newest reading and fans it out.
+// Readings from a stream that has since been replaced or stopped are dropped.
+func (h *Hub) handleReading(r reading) {
+	if h.streams[r.key] != r.st {
+		return
+	}
+	h.latest[r.key] = r.stats
+	ev := models.ContainerStatsEvent{ContainerStats: r.stats}
+	for sub := range h.subs {
+		if sub.filter.matches(r.key) {
+			sub.push(r.key, ev)
+		}
+	}
+}
+
+// shutdown drains the hub: reject new requests, cancel the event stream and
+// every stats stream, wait for them to stop, then end every subscription.
+func (h *Hub) shutdown() {
+	close(h.stopped)
+	h.eventsCancel()
+	for key, st := range h.streams {
+		st.cancel()
+		delete(h.streams, key)
+	}
+	h.streamWg.Wait()
+	for sub := range h.subs {
+		sub.end()
+	}
+}
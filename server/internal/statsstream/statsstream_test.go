@@ -0,0 +1,301 @@
+package statsstream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/docker"
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// fakeClient is a scripted engineClient: a canned container snapshot, a
+// pushable event channel, and stats streams that emit whatever the test sends
+// on the container's readings channel until cancelled.
+type fakeClient struct {
+	events chan docker.EngineEvent
+
+	mu       sync.Mutex
+	snapshot map[string][]models.ContainerInfo
+	readings map[containerKey]chan models.ContainerStats
+	active   map[containerKey]int
+	starts   map[containerKey]int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		events:   make(chan docker.EngineEvent, 16),
+		snapshot: map[string][]models.ContainerInfo{},
+		readings: map[containerKey]chan models.ContainerStats{},
+		active:   map[containerKey]int{},
+		starts:   map[containerKey]int{},
+	}
+}
+
+func (f *fakeClient) set(snapshot map[string][]models.ContainerInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.snapshot = snapshot
+}
+
+// readingsFor returns the channel the container's stream emits from.
+func (f *fakeClient) readingsFor(key containerKey) chan models.ContainerStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch, ok := f.readings[key]
+	if !ok {
+		ch = make(chan models.ContainerStats, 16)
+		f.readings[key] = ch
+	}
+	return ch
+}
+
+func (f *fakeClient) activeStreams(key containerKey) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active[key]
+}
+
+func (f *fakeClient) totalStarts(key containerKey) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.starts[key]
+}
+
+func (f *fakeClient) listContainers(context.Context) (map[string][]models.ContainerInfo, []docker.HostError, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.snapshot, nil, nil
+}
+
+func (f *fakeClient) streamEvents(ctx context.Context) <-chan docker.EngineEvent {
+	out := make(chan docker.EngineEvent)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case ev := <-f.events:
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (f *fakeClient) openStats(ctx context.Context, host, id string, emit func(models.ContainerStats)) error {
+	key := containerKey{host: host, id: id}
+	readings := f.readingsFor(key)
+	f.mu.Lock()
+	f.active[key]++
+	f.starts[key]++
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.active[key]--
+		f.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case stats := <-readings:
+			emit(stats)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func running(ids ...string) map[string][]models.ContainerInfo {
+	containers := make([]models.ContainerInfo, 0, len(ids))
+	for _, id := range ids {
+		containers = append(containers, models.ContainerInfo{ID: id, Names: []string{"/" + id}, State: "running"})
+	}
+	return map[string][]models.ContainerInfo{"local": containers}
+}
+
+func startHub(t *testing.T, client *fakeClient) *Hub {
+	t.Helper()
+	h := newHub(func() engineClient { return client })
+	h.retryBaseDelay = 10 * time.Millisecond
+	h.retryMaxDelay = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	go h.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		h.Wait()
+	})
+	return h
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// next waits for the subscription's next batch of events.
+func next(t *testing.T, sub *Subscription) []models.ContainerStatsEvent {
+	t.Helper()
+	select {
+	case <-sub.Ready():
+		return sub.Take()
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for stats")
+		return nil
+	}
+}
+
+func stats(id string, cpu float64) models.ContainerStats {
+	return models.ContainerStats{ID: id, Host: "local", CPUPercent: cpu}
+}
+
+func TestStreamsOnlyWhileSubscribedAndShareOneStream(t *testing.T) {
+	client := newFakeClient()
+	client.set(running("a", "b"))
+	h := startHub(t, client)
+	a, b := containerKey{"local", "a"}, containerKey{"local", "b"}
+
+	// Nothing is streamed until somebody watches.
+	time.Sleep(20 * time.Millisecond)
+	if client.totalStarts(a) != 0 {
+		t.Fatal("stream opened with no subscribers")
+	}
+
+	all := h.Subscribe(Filter{})
+	onlyA := h.Subscribe(Filter{Host: "local", ContainerID: "a"})
+	waitFor(t, "both streams", func() bool { return client.activeStreams(a) == 1 && client.activeStreams(b) == 1 })
+
+	client.readingsFor(a) <- stats("a", 10)
+	if got := next(t, onlyA); len(got) != 1 || got[0].ID != "a" || got[0].CPUPercent != 10 {
+		t.Fatalf("filtered subscription got %+v, want a's reading", got)
+	}
+	if got := next(t, all); len(got) != 1 || got[0].ID != "a" {
+		t.Fatalf("unfiltered subscription got %+v, want a's reading", got)
+	}
+	if client.totalStarts(a) != 1 {
+		t.Fatalf("a streamed %d times for two subscribers, want one shared stream", client.totalStarts(a))
+	}
+
+	// b is only wanted by the unfiltered subscription.
+	all.Close()
+	waitFor(t, "b's stream to stop", func() bool { return client.activeStreams(b) == 0 })
+	if client.activeStreams(a) != 1 {
+		t.Fatal("a's stream stopped while still watched")
+	}
+	onlyA.Close()
+	waitFor(t, "a's stream to stop", func() bool { return client.activeStreams(a) == 0 })
+}
+
+func TestNewSubscriptionGetsLatestReadings(t *testing.T) {
+	client := newFakeClient()
+	client.set(running("a", "b"))
+	h := startHub(t, client)
+
+	first := h.Subscribe(Filter{})
+	defer first.Close()
+	waitFor(t, "streams", func() bool {
+		return client.activeStreams(containerKey{"local", "a"}) == 1 && client.activeStreams(containerKey{"local", "b"}) == 1
+	})
+	client.readingsFor(containerKey{"local", "a"}) <- stats("a", 1)
+	client.readingsFor(containerKey{"local", "b"}) <- stats("b", 2)
+	waitFor(t, "both readings", func() bool {
+		first.mu.Lock()
+		defer first.mu.Unlock()
+		return len(first.pending) == 2
+	})
+
+	late := h.Subscribe(Filter{})
+	defer late.Close()
+	got := next(t, late)
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+		t.Fatalf("late subscriber got %+v, want the latest readings of a and b in order", got)
+	}
+}
+
+func TestReadingsCoalescePerContainer(t *testing.T) {
+	client := newFakeClient()
+	client.set(running("a"))
+	h := startHub(t, client)
+	key := containerKey{"local", "a"}
+
+	sub := h.Subscribe(Filter{})
+	defer sub.Close()
+	waitFor(t, "stream", func() bool { return client.activeStreams(key) == 1 })
+	for cpu := 1.0; cpu <= 3; cpu++ {
+		client.readingsFor(key) <- stats("a", cpu)
+	}
+	waitFor(t, "the last reading", func() bool {
+		sub.mu.Lock()
+		defer sub.mu.Unlock()
+		return sub.pending[key].CPUPercent == 3
+	})
+	if got := sub.Take(); len(got) != 1 || got[0].CPUPercent != 3 {
+		t.Fatalf("slow subscriber got %+v, want only the newest reading", got)
+	}
+}
+
+func TestDieEventRemovesContainer(t *testing.T) {
+	client := newFakeClient()
+	client.set(running("a"))
+	h := startHub(t, client)
+	key := containerKey{"local", "a"}
+
+	sub := h.Subscribe(Filter{})
+	defer sub.Close()
+	waitFor(t, "stream", func() bool { return client.activeStreams(key) == 1 })
+	client.readingsFor(key) <- stats("a", 5)
+	next(t, sub)
+
+	client.set(running())
+	client.events <- docker.EngineEvent{Host: "local", ContainerID: "a", Action: "die"}
+	got := next(t, sub)
+	if len(got) != 1 || !got[0].Removed || got[0].ID != "a" {
+		t.Fatalf("after die got %+v, want a removal", got)
+	}
+	waitFor(t, "the stream to stop", func() bool { return client.activeStreams(key) == 0 })
+
+	// A start event streams the container again without waiting for a resync.
+	client.events <- docker.EngineEvent{Host: "local", ContainerID: "a", Action: "start"}
+	waitFor(t, "the stream to restart", func() bool { return client.activeStreams(key) == 1 })
+}
+
+func TestShutdownEndsSubscriptions(t *testing.T) {
+	client := newFakeClient()
+	client.set(running("a"))
+	h := newHub(func() engineClient { return client })
+	ctx, cancel := context.WithCancel(context.Background())
+	go h.Run(ctx)
+
+	sub := h.Subscribe(Filter{})
+	waitFor(t, "stream", func() bool { return client.activeStreams(containerKey{"local", "a"}) == 1 })
+	cancel()
+	h.Wait()
+
+	select {
+	case <-sub.Done():
+	default:
+		t.Fatal("subscription still open after shutdown")
+	}
+	if client.activeStreams(containerKey{"local", "a"}) != 0 {
+		t.Fatal("stream still running after shutdown")
+	}
+	// Closing after shutdown must not block.
+	sub.Close()
+	if late := h.Subscribe(Filter{}); late == nil {
+		t.Fatal("Subscribe after shutdown returned nil")
+	} else {
+		<-late.Done()
+	}
+}
@@ -0,0 +1,71 @@
+package statsstream
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// maxStreamAttempts bounds how many times a stats stream is opened before
+// giving up until the next resync or start event respawns it.
+const maxStreamAttempts = 5
+
+// stream is the run loop's handle to one container's stats stream goroutine.
+type stream struct {
+	cancel context.CancelFunc
+}
+
+// spawnStream starts the stats stream for one container. Runs on the loop.
+// The Docker client is captured at spawn time: after a hot swap the old
+// client's close ends the stream and resync respawns it on the new client.
+func (h *Hub) spawnStream(key containerKey) {
+	client := h.source()
+	ctx, cancel := context.WithCancel(h.runCtx)
+	st := &stream{cancel: cancel}
+	h.streams[key] = st
+
+	h.streamWg.Add(1)
+	go func() {
+		defer h.streamWg.Done()
+		defer cancel()
+		h.runStream(ctx, client, key, st)
+		// Tell the loop this stream is gone so resync can respawn it.
+		// Skipped on shutdown, when the loop is no longer receiving.
+		select {
+		case h.streamExitCh <- streamExit{key: key, st: st}:
+		case <-h.runCtx.Done():
+		}
+	}()
+}
+
+// runStream keeps the stats stream open while desired, retrying with
+// exponential backoff when it ends prematurely. Returns when ctx is cancelled
+// (stream no longer desired) or after maxStreamAttempts.
+func (h *Hub) runStream(ctx context.Context, client engineClient, key containerKey, st *stream) {
+	emit := func(stats models.ContainerStats) {
+		select {
+		case h.readingCh <- reading{key: key, st: st, stats: stats}:
+		case <-ctx.Done():
+		}
+	}
+
+	delay := h.retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := client.openStats(ctx, key.host, key.id, emit)
+		if ctx.Err() != nil {
+			return
+		}
+		if attempt >= maxStreamAttempts {
+			log.Printf("statsstream: stats stream %s/%s gave up after %d attempts (last error: %v)", key.host, key.id, attempt, err)
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, h.retryMaxDelay)
+	}
+}
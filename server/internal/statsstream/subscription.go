@@ -0,0 +1,98 @@
+package statsstream
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/AmoabaKelvin/logdeck/internal/models"
+)
+
+// Filter selects the containers a subscription receives. Empty fields match
+// every container.
+type Filter struct {
+	Host        string
+	ContainerID string
+}
+
+func (f Filter) matches(key containerKey) bool {
+	return (f.Host == "" || f.Host == key.host) && (f.ContainerID == "" || f.ContainerID == key.id)
+}
+
+// Subscription receives the latest reading of every container its filter
+// selects. Readings coalesce per container: a subscriber that falls behind
+// gets each container's newest reading instead of a backlog, so a slow client
+// can never stall the hub or grow its memory.
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+
+	mu      sync.Mutex
+	pending map[containerKey]models.ContainerStatsEvent
+
+	ready   chan struct{} // holds a token while pending is non-empty
+	done    chan struct{} // closed when the subscription ends
+	endOnce sync.Once
+}
+
+func newSubscription(hub *Hub, filter Filter) *Subscription {
+	return &Subscription{
+		hub:     hub,
+		filter:  filter,
+		pending: make(map[containerKey]models.ContainerStatsEvent),
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// push records ev as the container's pending event, replacing any earlier one
+// the subscriber has not taken yet. It never blocks.
+func (s *Subscription) push(key containerKey, ev models.ContainerStatsEvent) {
+	s.mu.Lock()
+	s.pending[key] = ev
+	s.mu.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready is signalled when events are waiting to be taken.
+func (s *Subscription) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Done is closed when the subscription ends: after Close, or when the hub
+// shuts down.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Take returns the waiting events, at most one per container, ordered by
+// host and container ID.
+func (s *Subscription) Take() []models.ContainerStatsEvent {
+	s.mu.Lock()
+	events := make([]models.ContainerStatsEvent, 0, len(s.pending))
+	for key, ev := range s.pending {
+		events = append(events, ev)
+		delete(s.pending, key)
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(events, func(a, b models.ContainerStatsEvent) int {
+		return cmp.Or(cmp.Compare(a.Host, b.Host), cmp.Compare(a.ID, b.ID))
+	})
+	return events
+}
+
+// Close unsubscribes. Streams no other subscription wants are stopped.
+// Idempotent.
+func (s *Subscription) Close() {
+	s.hub.do(func() { s.hub.removeSub(s) })
+	s.end()
+}
+
+// end closes Done once.
+func (s *Subscription) end() {
+	s.endOnce.Do(func() { close(s.done) })
+}